@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/longhorn/backupstore"
+	// Although we don't use following drivers directly, we need to import them to register drivers.
+	_ "github.com/longhorn/backupstore/nfs" //nolint
+	_ "github.com/longhorn/backupstore/s3"  //nolint
+	"github.com/rancher/wrangler/v3/pkg/signals"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/settings"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+	"github.com/harvester/harvester/pkg/version"
+)
+
+// terminationLogPath is the default termination message path of a container.
+const terminationLogPath = "/dev/termination-log"
+
+var (
+	volumePath     string
+	exportPath     string
+	baseExportPath string
+	progressPath   string
+	blockSize      int64
+	exportPaths    []string
+	lhBackups      []string
+	mountPath      string
+	port           int
+	sourceURL      string
+	defaultKey     string
+	blockedCIDRs   []string
+
+	rootCmd = &cobra.Command{
+		Use:     datamover.BinaryName,
+		Short:   "Harvester Data Mover",
+		Long:    "Copies volume content between a PVC and the backup target for snapshot-export backups, or between two backup targets, serves the files of restored volumes and pushes exported vm images",
+		Version: fmt.Sprintf("%s (%s)", version.Version, version.GitCommit),
+		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+			logrus.SetOutput(os.Stdout)
+		},
+	}
+
+	uploadCmd = &cobra.Command{
+		Use:   datamover.CommandUpload,
+		Short: "Upload the volume to the backup target",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			driver, err := getBackupStoreDriver()
+			if err != nil {
+				return err
+			}
+			key, err := getEncryptionKeyFromEnv("")
+			if err != nil {
+				return err
+			}
+			_, err = datamover.Upload(cmd.Context(), driver, key, volumePath, exportPath, baseExportPath, blockSize)
+			return err
+		},
+	}
+
+	downloadCmd = &cobra.Command{
+		Use:   datamover.CommandDownload,
+		Short: "Download the volume from the backup target",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			driver, err := getBackupStoreDriver()
+			if err != nil {
+				return err
+			}
+			key, err := getEncryptionKeyFromEnv("")
+			if err != nil {
+				return err
+			}
+			return datamover.Download(cmd.Context(), driver, key, exportPath, volumePath, progressPath)
+		},
+	}
+
+	copyCmd = &cobra.Command{
+		Use:   datamover.CommandCopy,
+		Short: "Copy volume backups to the destination backup target",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var longhornBackups []datamover.LonghornBackup
+			for _, s := range lhBackups {
+				b, err := datamover.ParseLonghornBackup(s)
+				if err != nil {
+					return err
+				}
+				longhornBackups = append(longhornBackups, b)
+			}
+
+			src, err := getBackupStoreDriverFromEnv(datamover.EnvBackupTarget, "")
+			if err != nil {
+				return err
+			}
+			dst, err := getBackupStoreDriverFromEnv(datamover.EnvDestinationBackupTarget, datamover.DestinationEnvPrefix)
+			if err != nil {
+				return err
+			}
+			srcKey, err := getEncryptionKeyFromEnv("")
+			if err != nil {
+				return err
+			}
+			dstKey, err := getEncryptionKeyFromEnv(datamover.DestinationEnvPrefix)
+			if err != nil {
+				return err
+			}
+			_, err = datamover.Copy(cmd.Context(), src, dst, srcKey, dstKey, exportPaths, longhornBackups, progressPath)
+			return err
+		},
+	}
+
+	browseCmd = &cobra.Command{
+		Use:   datamover.CommandBrowse,
+		Short: "Mount the filesystems of the volume read-only and serve their files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return datamover.Browse(cmd.Context(), volumePath, mountPath, port, os.Getenv(datamover.EnvBrowseToken))
+		},
+	}
+
+	imageExportCmd = &cobra.Command{
+		Use:   export.CommandImageExport,
+		Short: "Push the image served by a vm image downloader to the export target",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runImageExport(cmd)
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{uploadCmd, downloadCmd} {
+		c.Flags().StringVar(&volumePath, "volume", "", "Path of the block device or disk image")
+		c.Flags().StringVar(&exportPath, "export-path", "", "Folder of the volume in the backup target")
+		c.Flags().StringVar(&progressPath, "progress-path", "", "File in the backup target to report progress to")
+		_ = c.MarkFlagRequired("volume")
+		_ = c.MarkFlagRequired("export-path")
+		rootCmd.AddCommand(c)
+	}
+	uploadCmd.Flags().StringVar(&baseExportPath, "base-export-path", "", "Previous export of the volume to upload the changed blocks against")
+	uploadCmd.Flags().Int64Var(&blockSize, "block-size", datamover.DefaultBlockSize, "Size of the blocks stored in the backup target")
+
+	copyCmd.Flags().StringArrayVar(&exportPaths, "export-path", nil, "Folder of a volume export to copy")
+	copyCmd.Flags().StringArrayVar(&lhBackups, "longhorn-backup", nil, "Longhorn backup to copy as <volume>/<backup>")
+	copyCmd.Flags().StringVar(&progressPath, "progress-path", "", "File in the destination backup target to report progress to")
+	rootCmd.AddCommand(copyCmd)
+
+	browseCmd.Flags().StringVar(&volumePath, "volume", "", "Path of the block device or disk image")
+	browseCmd.Flags().StringVar(&mountPath, "mount-path", "/browse", "Folder to mount the filesystems in")
+	browseCmd.Flags().IntVar(&port, "port", datamover.BrowsePort, "Port to serve the files on")
+	_ = browseCmd.MarkFlagRequired("volume")
+	rootCmd.AddCommand(browseCmd)
+
+	imageExportCmd.Flags().StringVar(&sourceURL, "source-url", "", "URL of the image served by the vm image downloader")
+	imageExportCmd.Flags().StringVar(&defaultKey, "default-key", "", "Object key of the s3 target if it sets none")
+	imageExportCmd.Flags().StringArrayVar(&blockedCIDRs, "blocked-cidr", nil, "Network the target must not resolve to, on top of the loopback, link-local and multicast ones")
+	_ = imageExportCmd.MarkFlagRequired("source-url")
+	rootCmd.AddCommand(imageExportCmd)
+}
+
+// runImageExport uploads the image through a client refusing the blocked networks and
+// reports the checksum and size in the termination message of the pod.
+func runImageExport(cmd *cobra.Command) error {
+	target := &harvesterv1.VirtualMachineImageExportTarget{}
+	if err := json.Unmarshal([]byte(os.Getenv(export.EnvExportTarget)), target); err != nil {
+		return fmt.Errorf("failed to decode export target: %w", err)
+	}
+	clusterNetworks, err := export.ParseCIDRs(blockedCIDRs)
+	if err != nil {
+		return err
+	}
+	blocked := append(export.DefaultBlockedNetworks(), clusterNetworks...)
+	if target.URL != "" {
+		if err := export.CheckURL(cmd.Context(), target.URL, blocked); err != nil {
+			return err
+		}
+	}
+	data, err := export.ReadSecretDir(export.SecretMountPath)
+	if err != nil {
+		return fmt.Errorf("failed to read export secret: %w", err)
+	}
+	uploader, err := export.NewUploader(target, data, defaultKey, export.NewHTTPClient(blocked))
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Exporting %s to %s", sourceURL, uploader.URL())
+	result, err := export.Run(cmd.Context(), export.NewSourceClient(), sourceURL, uploader)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Exported %d bytes to %s", result.Size, uploader.URL())
+
+	f, err := os.Create(terminationLogPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return export.WriteResult(f, result)
+}
+
+// getBackupStoreDriver connects to the backup target passed by the engine.
+// The S3 credentials are already in the environment through the credential secret.
+func getBackupStoreDriver() (backupstore.BackupStoreDriver, error) {
+	target, err := settings.DecodeBackupTarget(os.Getenv(datamover.EnvBackupTarget))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode backup target: %w", err)
+	}
+	if target.IsDefaultBackupTarget() {
+		return nil, fmt.Errorf("backup target is not set")
+	}
+	return backupstore.GetBackupStoreDriver(backuputil.ConstructEndpoint(target))
+}
+
+// getBackupStoreDriverFromEnv connects to the backup target in the environment
+// variable. Its S3 credentials are in the environment variables with the prefix,
+// so that the source and destination of a copy can be used side by side.
+func getBackupStoreDriverFromEnv(targetEnv, credentialPrefix string) (backupstore.BackupStoreDriver, error) {
+	target, err := settings.DecodeBackupTarget(os.Getenv(targetEnv))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode backup target %s: %w", targetEnv, err)
+	}
+	if target.IsDefaultBackupTarget() {
+		return nil, fmt.Errorf("backup target %s is not set", targetEnv)
+	}
+	var credentials map[string][]byte
+	if target.Type == settings.S3BackupType {
+		credentials = backuputil.GetCredentialsFromEnv(credentialPrefix)
+	}
+	return backuputil.GetBackupStoreDriverWithCredentials(target, credentials)
+}
+
+// getEncryptionKeyFromEnv returns the encryption key of the backup target in
+// the environment variable with the prefix, or nil if the target has none.
+func getEncryptionKeyFromEnv(prefix string) ([]byte, error) {
+	value := os.Getenv(prefix + datamover.EnvEncryptionKey)
+	if value == "" {
+		return nil, nil
+	}
+	return backuputil.ParseEncryptionKey([]byte(value))
+}
+
+func main() {
+	cobra.CheckErr(rootCmd.ExecuteContext(signals.SetupSignalContext()))
+}
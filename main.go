@@ -15,6 +15,7 @@ import (
 
 	"github.com/harvester/harvester/pkg/cmd"
 	"github.com/harvester/harvester/pkg/config"
+	"github.com/harvester/harvester/pkg/image/convert"
 	"github.com/harvester/harvester/pkg/server"
 )
 
@@ -73,6 +74,18 @@ func main() {
 			Destination: &options.RancherURL,
 			Hidden:      true,
 		},
+		cli.StringFlag{
+			Name:        "image-conversion-dir",
+			EnvVar:      "HARVESTER_IMAGE_CONVERSION_DIR",
+			Usage:       "Specify the directory images are staged in while they are converted, defaults to the temporary directory",
+			Destination: &options.ImageConversionDir,
+		},
+		cli.StringFlag{
+			Name:        "image-conversion-size-limit",
+			EnvVar:      "HARVESTER_IMAGE_CONVERSION_SIZE_LIMIT",
+			Usage:       "Specify the size of the image conversion directory, e.g. 200Gi",
+			Destination: &options.ImageConversionSizeLimit,
+		},
 	}
 
 	app := cmd.NewApp(name, "", flags, func(commonOptions *config.CommonOptions) error {
@@ -85,6 +98,10 @@ func main() {
 func run(commonOptions *config.CommonOptions, options config.Options) error {
 	ctx := signals.SetupSignalContext()
 
+	if err := convert.Configure(options.ImageConversionDir, options.ImageConversionSizeLimit); err != nil {
+		return err
+	}
+
 	kubeConfig, err := server.GetConfig(commonOptions.KubeConfig)
 	if err != nil {
 		return fmt.Errorf("failed to find kubeconfig: %v", err)
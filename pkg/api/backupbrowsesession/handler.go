@@ -0,0 +1,124 @@
+package backupbrowsesession
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	// linkPartitions lists the partitions of the volume and their filesystem
+	linkPartitions = "partitions"
+	// linkFiles lists a folder, or downloads a file, of a partition given by
+	// the `partition` and `path` query parameters
+	linkFiles = "files"
+)
+
+// proxiedHeaders are the headers of the helper pod response passed to the client.
+var proxiedHeaders = []string{
+	"Accept-Ranges",
+	"Content-Disposition",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"Last-Modified",
+}
+
+// LinkHandler proxies the requests to the helper pod of a ready session.
+// Accessing a link requires the permission to get the session. The helper
+// pod only accepts connections from the Harvester namespace, and requests
+// carrying the token of the session.
+type LinkHandler struct {
+	sessionCache ctlharvesterv1.BackupBrowseSessionCache
+	podCache     ctlcorev1.PodCache
+	secretCache  ctlcorev1.SecretCache
+	httpClient   *http.Client
+	port         int
+}
+
+func (h *LinkHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBody, error) {
+	req, rw := ctx.Req(), ctx.RespWriter()
+	vars := util.EncodeVars(mux.Vars(req))
+	namespace, name := vars["namespace"], vars["name"]
+
+	var helperURL url.URL
+	switch vars["link"] {
+	case linkPartitions:
+		helperURL.Path = datamover.BrowsePartitionsPath
+	case linkFiles:
+		helperURL.Path = datamover.BrowseFilesPath
+		query := url.Values{}
+		query.Set(datamover.BrowseQueryPartition, req.URL.Query().Get(datamover.BrowseQueryPartition))
+		query.Set(datamover.BrowseQueryPath, req.URL.Query().Get(datamover.BrowseQueryPath))
+		helperURL.RawQuery = query.Encode()
+	default:
+		return nil, apierror.NewAPIError(validation.InvalidAction, fmt.Sprintf("Unsupported GET action %s", vars["link"]))
+	}
+
+	session, err := h.sessionCache.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status.Phase != harvesterv1.BackupBrowseSessionPhaseReady {
+		return nil, apierror.NewAPIError(validation.InvalidState, fmt.Sprintf("backup browse session %s/%s is not ready", namespace, name))
+	}
+	// the helper pods run in the data mover namespace
+	pod, err := h.podCache.Get(datamover.Namespace, session.Status.PodName)
+	if err != nil {
+		return nil, err
+	}
+	if pod.Status.PodIP == "" {
+		return nil, apierror.NewAPIError(validation.InvalidState, fmt.Sprintf("helper pod %s/%s has no IP", pod.Namespace, pod.Name))
+	}
+	helperURL.Scheme = "http"
+	helperURL.Host = net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(h.port))
+	tokenSecret, err := h.secretCache.Get(datamover.Namespace, datamover.BrowseTokenSecretName(pod.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	// the URL points to the helper pod of the session, only the query comes from the request
+	helperReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, helperURL.String(), nil) //nolint:gosec // see comment above
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.ServerError, fmt.Sprintf("failed to create request to helper pod: %v", err))
+	}
+	helperReq.Header.Set("Authorization", "Bearer "+string(tokenSecret.Data[datamover.BrowseTokenKey]))
+	if rangeHeader := req.Header.Get("Range"); rangeHeader != "" {
+		helperReq.Header.Set("Range", rangeHeader)
+	}
+	resp, err := h.httpClient.Do(helperReq) //nolint:gosec // see comment above
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.ServerError, fmt.Sprintf("failed to send request to helper pod: %v", err))
+	}
+	defer resp.Body.Close()
+
+	// Instruct the framework to skip its automatic response handling, as the
+	// response of the helper pod, including its errors, is streamed as is.
+	ctx.SkipAutoResponse()
+	for _, header := range proxiedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			rw.Header().Set(header, value)
+		}
+	}
+	rw.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(rw, resp.Body); err != nil {
+		logrus.WithError(err).Warnf("failed to stream files of backup browse session %s/%s", namespace, name)
+		return nil, err
+	}
+	return nil, nil
+}
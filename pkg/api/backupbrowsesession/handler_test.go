@@ -0,0 +1,138 @@
+package backupbrowsesession
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+const (
+	testNamespace = "default"
+	testSession   = "session"
+	testPod       = "default-session-browse"
+	testToken     = "secret-token"
+)
+
+func TestLinkHandler(t *testing.T) {
+	readySession := &harvesterv1.BackupBrowseSession{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSession},
+		Status: harvesterv1.BackupBrowseSessionStatus{
+			Phase:   harvesterv1.BackupBrowseSessionPhaseReady,
+			PodName: testPod,
+		},
+	}
+	preparingSession := readySession.DeepCopy()
+	preparingSession.Status.Phase = harvesterv1.BackupBrowseSessionPhasePreparing
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: datamover.Namespace, Name: datamover.BrowseTokenSecretName(testPod)},
+		Data:       map[string][]byte{datamover.BrowseTokenKey: []byte(testToken)},
+	}
+
+	var testCases = []struct {
+		name           string
+		link           string
+		query          string
+		objects        []runtime.Object
+		expectedStatus int
+		expectedPath   string
+		expectedQuery  string
+	}{
+		{
+			name:           "partitions are proxied with the session token",
+			link:           linkPartitions,
+			objects:        []runtime.Object{readySession, tokenSecret},
+			expectedStatus: http.StatusOK,
+			expectedPath:   datamover.BrowsePartitionsPath,
+		},
+		{
+			name:           "only the partition and path of a files request are proxied",
+			link:           linkFiles,
+			query:          "partition=p1&path=%2Fetc%2Fhosts&other=x",
+			objects:        []runtime.Object{readySession, tokenSecret},
+			expectedStatus: http.StatusOK,
+			expectedPath:   datamover.BrowseFilesPath,
+			expectedQuery:  "partition=p1&path=%2Fetc%2Fhosts",
+		},
+		{
+			name:           "unknown link is rejected",
+			link:           "unknown",
+			objects:        []runtime.Object{readySession, tokenSecret},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "session not ready",
+			link:           linkPartitions,
+			objects:        []runtime.Object{preparingSession, tokenSecret},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "missing token secret",
+			link:           linkPartitions,
+			objects:        []runtime.Object{readySession},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var proxied *http.Request
+			helper := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				proxied = req
+				rw.Header().Set("Content-Type", "application/json")
+				rw.Header().Set("X-Internal", "leaked")
+				rw.WriteHeader(http.StatusOK)
+			}))
+			defer helper.Close()
+			host, port, err := net.SplitHostPort(helper.Listener.Addr().String())
+			require.NoError(t, err)
+			helperPort, err := strconv.Atoi(port)
+			require.NoError(t, err)
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: datamover.Namespace, Name: testPod},
+				Status:     corev1.PodStatus{PodIP: host},
+			}
+			clientset := fake.NewSimpleClientset(append(tc.objects, pod)...)
+			handler := harvesterServer.NewHandler(&LinkHandler{
+				sessionCache: fakeclients.BackupBrowseSessionCache(clientset.HarvesterhciV1beta1().BackupBrowseSessions),
+				podCache:     fakeclients.PodCache(clientset.CoreV1().Pods),
+				secretCache:  fakeclients.SecretCache(clientset.CoreV1().Secrets),
+				httpClient:   helper.Client(),
+				port:         helperPort,
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil)
+			req.Header.Set("Authorization", "Bearer user-token")
+			req = mux.SetURLVars(req, map[string]string{"namespace": testNamespace, "name": testSession, "link": tc.link})
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+
+			assert.Equal(t, tc.expectedStatus, rw.Code)
+			if tc.expectedPath == "" {
+				assert.Nil(t, proxied, "the helper pod must not be reached")
+				return
+			}
+			require.NotNil(t, proxied)
+			assert.Equal(t, tc.expectedPath, proxied.URL.Path)
+			assert.Equal(t, tc.expectedQuery, proxied.URL.RawQuery)
+			assert.Equal(t, "Bearer "+testToken, proxied.Header.Get("Authorization"))
+			assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+			assert.Empty(t, rw.Header().Get("X-Internal"))
+		})
+	}
+}
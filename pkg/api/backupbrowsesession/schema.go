@@ -0,0 +1,39 @@
+package backupbrowsesession
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/server"
+
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/config"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+)
+
+const (
+	backupBrowseSessionSchemaID = "harvesterhci.io.backupbrowsesession"
+)
+
+func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Options) error {
+	handler := harvesterServer.NewHandler(&LinkHandler{
+		sessionCache: scaled.HarvesterFactory.Harvesterhci().V1beta1().BackupBrowseSession().Cache(),
+		podCache:     scaled.CoreFactory.Core().V1().Pod().Cache(),
+		secretCache:  scaled.CoreFactory.Core().V1().Secret().Cache(),
+		httpClient:   &http.Client{},
+		port:         datamover.BrowsePort,
+	})
+
+	t := schema.Template{
+		ID: backupBrowseSessionSchemaID,
+		Customize: func(s *types.APISchema) {
+			s.LinkHandlers = map[string]http.Handler{
+				linkPartitions: handler,
+				linkFiles:      handler,
+			}
+		},
+	}
+	server.SchemaFactory.AddTemplate(t)
+	return nil
+}
@@ -3,7 +3,9 @@ package node
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 	"time"
@@ -21,10 +23,8 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
 
 	ctlnode "github.com/harvester/harvester/pkg/controller/master/node"
@@ -38,13 +38,13 @@ import (
 )
 
 const (
-	drainKey                     = "kubevirt.io/drain"
 	enableMaintenanceModeAction  = "enableMaintenanceMode"
 	disableMaintenanceModeAction = "disableMaintenanceMode"
 	cordonAction                 = "cordon"
 	uncordonAction               = "uncordon"
 	listUnhealthyVM              = "listUnhealthyVM"
 	maintenancePossible          = "maintenancePossible"
+	maintenancePlan              = "maintenancePlan"
 	powerAction                  = "powerAction"
 	powerActionPossible          = "powerActionPossible"
 	seederAddonName              = "harvester-seeder"
@@ -60,9 +60,10 @@ var (
 )
 
 func Formatter(request *types.APIRequest, resource *types.RawResource) {
-	resource.Actions = make(map[string]string, 3)
+	resource.Actions = make(map[string]string, 4)
 	resource.AddAction(request, listUnhealthyVM)
 	resource.AddAction(request, maintenancePossible)
+	resource.AddAction(request, maintenancePlan)
 	resource.AddAction(request, powerActionPossible)
 
 	if request.AccessControl.CanUpdate(request, resource.APIObject, resource.Schema) != nil {
@@ -96,8 +97,9 @@ type ActionHandler struct {
 	virtualMachineCache         ctlkubevirtv1.VirtualMachineCache
 	virtualMachineInstanceCache ctlkubevirtv1.VirtualMachineInstanceCache
 	addonCache                  harvesterctlv1beta1.AddonCache
+	vmGroupCache                harvesterctlv1beta1.VirtualMachineGroupCache
+	vmPlacementPolicyCache      harvesterctlv1beta1.VMPlacementPolicyCache
 	dynamicClient               dynamic.Interface
-	virtSubresourceRestClient   rest.Interface
 	ctx                         context.Context
 }
 
@@ -124,6 +126,8 @@ func (h ActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBod
 		return nil, h.listUnhealthyVM(rw, toUpdate)
 	case maintenancePossible:
 		return nil, h.maintenancePossible(toUpdate)
+	case maintenancePlan:
+		return nil, h.maintenancePlan(rw, req, toUpdate)
 	case powerActionPossible:
 		return nil, h.powerActionPossible(rw, name)
 	case powerAction:
@@ -204,13 +208,7 @@ func (h ActionHandler) enableMaintenanceMode(req *http.Request, node *corev1.Nod
 			return err
 		}
 
-		if nodeObj.Annotations == nil {
-			nodeObj.Annotations = make(map[string]string)
-		}
-		nodeObj.Annotations[drainhelper.DrainAnnotation] = "true"
-		if maintenanceInput.Force == "true" {
-			nodeObj.Annotations[drainhelper.ForcedDrain] = "true"
-		}
+		drainhelper.RequestMaintenanceMode(nodeObj, maintenanceInput.Force == "true")
 		_, err = h.nodeClient.Update(nodeObj)
 		return err
 	})
@@ -219,20 +217,7 @@ func (h ActionHandler) enableMaintenanceMode(req *http.Request, node *corev1.Nod
 type maintenanceModeUpdateFunc func(node *corev1.Node)
 
 func (h ActionHandler) disableMaintenanceMode(nodeName string) error {
-	disableMaintaenanceModeFunc := func(node *corev1.Node) {
-		node.Spec.Unschedulable = false
-		for i, taint := range node.Spec.Taints {
-			if taint.Key == drainKey {
-				node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
-				break
-			}
-		}
-		delete(node.Annotations, drainhelper.DrainAnnotation)
-		delete(node.Annotations, drainhelper.ForcedDrain)
-		delete(node.Annotations, ctlnode.MaintainStatusAnnotationKey)
-	}
-
-	err := h.retryMaintenanceModeUpdate(nodeName, disableMaintaenanceModeFunc, "disable")
+	err := h.retryMaintenanceModeUpdate(nodeName, drainhelper.DisableMaintenanceMode, "disable")
 	if err != nil {
 		return err
 	}
@@ -240,43 +225,7 @@ func (h ActionHandler) disableMaintenanceMode(nodeName string) error {
 	// Restart those VMs that have been labeled to be shut down before
 	// maintenance mode and that should be restarted when the maintenance
 	// mode has been disabled again.
-	node, err := h.nodeCache.Get(nodeName)
-	if err != nil {
-		return err
-	}
-	selector := labels.Set{util.LabelMaintainModeStrategy: util.MaintainModeStrategyShutdownAndRestartAfterDisable}.AsSelector()
-	vmList, err := h.virtualMachineCache.List(node.Namespace, selector)
-	if err != nil {
-		return fmt.Errorf("failed to list VMs with labels %s: %w", selector.String(), err)
-	}
-	for _, vm := range vmList {
-		// Make sure that this VM was shut down as part of the maintenance
-		// mode of the given node.
-		if vm.Annotations[util.AnnotationMaintainModeStrategyNodeName] != nodeName {
-			continue
-		}
-
-		logrus.WithFields(logrus.Fields{
-			"namespace":           vm.Namespace,
-			"virtualmachine_name": vm.Name,
-		}).Info("restarting the VM that was shut down in maintenance mode")
-
-		err := h.virtSubresourceRestClient.Put().Namespace(vm.Namespace).Resource("virtualmachines").SubResource("start").Name(vm.Name).Do(h.ctx).Error()
-		if err != nil {
-			return fmt.Errorf("failed to start VM %s/%s: %w", vm.Namespace, vm.Name, err)
-		}
-
-		// Remove the annotation that was previously set when the node
-		// went into maintenance mode.
-		vmCopy := vm.DeepCopy()
-		delete(vmCopy.Annotations, util.AnnotationMaintainModeStrategyNodeName)
-		_, err = h.virtualMachineClient.Update(vmCopy)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return ctlnode.RestartVMs(nodeName, util.MaintainModeStrategyShutdownAndRestartAfterDisable, h.virtualMachineClient, h.virtualMachineCache, h.vmGroupCache)
 }
 
 func (h ActionHandler) retryMaintenanceModeUpdate(nodeName string, updateFunc maintenanceModeUpdateFunc, actionName string) error {
@@ -305,7 +254,7 @@ func (h ActionHandler) retryMaintenanceModeUpdate(nodeName string, updateFunc ma
 }
 
 func (h ActionHandler) listUnhealthyVM(rw http.ResponseWriter, node *corev1.Node) error {
-	ndc := nodedrain.ActionHelper(h.nodeCache, h.virtualMachineInstanceCache, h.longhornVolumeCache, h.longhornReplicaCache)
+	ndc := nodedrain.ActionHelper(h.nodeCache, h.virtualMachineInstanceCache, h.longhornVolumeCache, h.longhornReplicaCache, h.vmGroupCache, h.vmPlacementPolicyCache)
 	nonMigrtableVMList, err := ndc.FindNonMigratableVMS(node)
 	if err != nil {
 		return err
@@ -322,6 +271,23 @@ func (h ActionHandler) listUnhealthyVM(rw http.ResponseWriter, node *corev1.Node
 	return json.NewEncoder(rw).Encode(&respObj)
 }
 
+// maintenancePlan returns what enabling maintenance mode would do to the VMs on the node without doing it.
+func (h ActionHandler) maintenancePlan(rw http.ResponseWriter, req *http.Request, node *corev1.Node) error {
+	var maintenanceInput MaintenanceModeInput
+	if err := json.NewDecoder(req.Body).Decode(&maintenanceInput); err != nil && !errors.Is(err, io.EOF) {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to decode request body: %v ", err))
+	}
+
+	ndc := nodedrain.ActionHelper(h.nodeCache, h.virtualMachineInstanceCache, h.longhornVolumeCache, h.longhornReplicaCache, h.vmGroupCache, h.vmPlacementPolicyCache)
+	plan, err := ndc.MaintenancePlan(node, maintenanceInput.Force == "true")
+	if err != nil {
+		return err
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	return json.NewEncoder(rw).Encode(plan)
+}
+
 func (h ActionHandler) maintenancePossible(node *corev1.Node) error {
 	return drainhelper.DrainPossible(h.nodeCache, node)
 }
@@ -8,12 +8,9 @@ import (
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server"
 	"github.com/rancher/wrangler/v3/pkg/schemas"
-	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/rest"
 
 	"github.com/harvester/harvester/pkg/config"
-	"github.com/harvester/harvester/pkg/generated/clientset/versioned/scheme"
 	harvesterServer "github.com/harvester/harvester/pkg/server/http"
 )
 
@@ -37,15 +34,6 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 		return fmt.Errorf("error creating dyanmic client: %v", err)
 	}
 
-	copyConfig := rest.CopyConfig(server.RESTConfig)
-	copyConfig.GroupVersion = &k8sschema.GroupVersion{Group: "subresources.kubevirt.io", Version: "v1"}
-	copyConfig.APIPath = "/apis"
-	copyConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
-	virtSubresourceClient, err := rest.RESTClientFor(copyConfig)
-	if err != nil {
-		return err
-	}
-
 	nodeHandler := harvesterServer.NewHandler(ActionHandler{
 		jobCache:                    scaled.Management.BatchFactory.Batch().V1().Job().Cache(),
 		nodeClient:                  scaled.Management.CoreFactory.Core().V1().Node(),
@@ -56,8 +44,9 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 		virtualMachineCache:         scaled.Management.VirtFactory.Kubevirt().V1().VirtualMachine().Cache(),
 		virtualMachineInstanceCache: scaled.Management.VirtFactory.Kubevirt().V1().VirtualMachineInstance().Cache(),
 		addonCache:                  scaled.Management.HarvesterFactory.Harvesterhci().V1beta1().Addon().Cache(),
+		vmGroupCache:                scaled.Management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup().Cache(),
+		vmPlacementPolicyCache:      scaled.Management.HarvesterFactory.Harvesterhci().V1beta1().VMPlacementPolicy().Cache(),
 		dynamicClient:               dynamicClient,
-		virtSubresourceRestClient:   virtSubresourceClient,
 		ctx:                         scaled.Ctx,
 	})
 
@@ -76,6 +65,9 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 				uncordonAction:               {},
 				listUnhealthyVM:              {},
 				maintenancePossible:          {},
+				maintenancePlan: {
+					Input: "maintenanceModeInput",
+				},
 				powerAction: {
 					Input: "powerActionInput",
 				},
@@ -90,6 +82,7 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 				uncordonAction:               nodeHandler,
 				listUnhealthyVM:              nodeHandler,
 				maintenancePossible:          nodeHandler,
+				maintenancePlan:              nodeHandler,
 				powerAction:                  nodeHandler,
 				powerActionPossible:          nodeHandler,
 				enableCPUManager:             nodeHandler,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/rancher/steve/pkg/server"
 
+	"github.com/harvester/harvester/pkg/api/backupbrowsesession"
 	"github.com/harvester/harvester/pkg/api/cluster"
 	"github.com/harvester/harvester/pkg/api/image"
 	"github.com/harvester/harvester/pkg/api/keypair"
@@ -12,6 +13,8 @@ import (
 	"github.com/harvester/harvester/pkg/api/node"
 	"github.com/harvester/harvester/pkg/api/upgradelog"
 	"github.com/harvester/harvester/pkg/api/vm"
+	"github.com/harvester/harvester/pkg/api/vmbackup"
+	"github.com/harvester/harvester/pkg/api/vmgroup"
 	"github.com/harvester/harvester/pkg/api/vmtemplate"
 	"github.com/harvester/harvester/pkg/api/volume"
 	"github.com/harvester/harvester/pkg/api/volumesnapshot"
@@ -42,5 +45,8 @@ func Setup(ctx context.Context, server *server.Server, _ *server.Controllers, op
 		volumesnapshot.RegisterSchema,
 		cluster.RegisterSchema,
 		namespace.RegisterSchema,
+		vmbackup.RegisterSchema,
+		backupbrowsesession.RegisterSchema,
+		vmgroup.RegisterSchema,
 	)
 }
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CanCreateResource checks whether the user may create the resource of the
+// group in the namespace. Actions creating objects with the Harvester service
+// account use it to not let users create what they couldn't themselves.
+func CanCreateResource(clientSet kubernetes.Interface, namespace string, userInfo user.Info, group, resource string) (bool, error) {
+	review, err := clientSet.AuthorizationV1().SubjectAccessReviews().Create(
+		context.TODO(),
+		&authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      "create",
+					Group:     group,
+					Resource:  resource,
+				},
+				User:   userInfo.GetName(),
+				Groups: userInfo.GetGroups(),
+				UID:    userInfo.GetUID(),
+			},
+		},
+		metav1.CreateOptions{},
+	)
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"namespace": namespace,
+			"user":      userInfo.GetName(),
+			"resource":  resource,
+		}).Error("Failed to check create permission")
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
@@ -28,7 +28,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/storage/names"
@@ -47,7 +46,6 @@ import (
 	volumeapi "github.com/harvester/harvester/pkg/api/volume"
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/builder"
-	nodecontroller "github.com/harvester/harvester/pkg/controller/master/node"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctlcniv1 "github.com/harvester/harvester/pkg/generated/controllers/k8s.cni.cncf.io/v1"
 	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
@@ -55,7 +53,7 @@ import (
 	harvesterServer "github.com/harvester/harvester/pkg/server/http"
 	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
-	"github.com/harvester/harvester/pkg/util/drainhelper"
+	"github.com/harvester/harvester/pkg/util/migration"
 	"github.com/harvester/harvester/pkg/util/virtualmachine"
 	"github.com/harvester/harvester/pkg/util/virtualmachineinstance"
 )
@@ -171,9 +169,15 @@ func (h *vmActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.Response
 		if input.FsFreezeDeadline != nil && input.FsFreezeDeadline.Duration < 0 {
 			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `fsFreezeDeadline` must not be negative")
 		}
+		if input.Type != "" && !input.Type.UsesRemoteBackupTarget() {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Parameter `type` must be %s or %s", harvesterv1.Backup, harvesterv1.SnapshotExport))
+		}
 
-		if err := h.checkBackupTargetConfigured(); err != nil {
-			return nil, err
+		// the webhook checks the named backup targets
+		if input.BackupTargetName == "" {
+			if err := h.checkBackupTargetConfigured(); err != nil {
+				return nil, err
+			}
 		}
 
 		if err := h.createVMBackup(name, namespace, input); err != nil {
@@ -698,169 +702,15 @@ func (h *vmActionHandler) findMigratableNodes(rw http.ResponseWriter, namespace,
 }
 
 func (h *vmActionHandler) findMigratableNodesByVMI(vmi *kubevirtv1.VirtualMachineInstance) ([]string, error) {
-	nodeFilter, err := h.getNodeSelectorRequirementFromVMI(vmi)
-	if err != nil {
-		return nil, err
-	}
-
-	nodes, err := h.nodeCache.List(nodeFilter)
-	if err != nil || len(nodes) == 0 {
-		return nil, err
-	}
-
-	// ignore the node where the VM is running
-	migratableNodes := make([]string, 0, len(nodes)-1)
-	for _, node := range nodes {
-		if vmi.Status.NodeName == node.Name {
-			continue
-		}
-
-		if isDrained(node) {
-			continue
-		}
-
-		migratableNodes = append(migratableNodes, node.Name)
-	}
-	return migratableNodes, nil
-}
-
-func isDrained(node *corev1.Node) bool {
-	if _, ok := node.Annotations[nodecontroller.MaintainStatusAnnotationKey]; ok {
-		return ok
-	}
-	if _, ok := node.Annotations[drainhelper.DrainAnnotation]; ok {
-		return ok
-	}
-	if node.Spec.Unschedulable {
-		return true
-	}
-	if node.Spec.Taints != nil {
-		for _, taint := range node.Spec.Taints {
-			if taint.Key == corev1.TaintNodeUnreachable || taint.Key == corev1.TaintNodeUnschedulable {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-func (h *vmActionHandler) getNodeSelectorRequirementFromVMI(vmi *kubevirtv1.VirtualMachineInstance) (labels.Selector, error) {
-	if vmi == nil {
-		return labels.Everything(), nil
-	}
-
-	vmiPod, err := h.getVMIPod(vmi)
-	if err != nil {
-		return labels.Nothing(), err
-	}
-
-	nodeFilter := labels.NewSelector()
-
-	for key, value := range vmiPod.Spec.NodeSelector {
-		if key == corev1.LabelHostname {
-			continue
-		}
-		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
-		if err != nil {
-			return nil, fmt.Errorf("failed to create requirement for %s=%s: %w", key, value, err)
-		}
-		nodeFilter = nodeFilter.Add(*requirement)
-	}
-
-	if isRequiredAffinityFilterPresent(vmiPod) {
-		required := vmiPod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
-		var err error
-		nodeFilter, err = addNodeAffinityFilters(nodeFilter, required.NodeSelectorTerms)
-		if err != nil {
-			return nil, err
-		}
-
-	}
-
-	return nodeFilter, nil
-}
-
-func isRequiredAffinityFilterPresent(pod *corev1.Pod) bool {
-	return pod.Spec.Affinity != nil &&
-		pod.Spec.Affinity.NodeAffinity != nil &&
-		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil
-}
-
-func addNodeAffinityFilters(nodeFilter labels.Selector, terms []corev1.NodeSelectorTerm) (labels.Selector, error) {
-	for _, term := range terms {
-		for _, expr := range term.MatchExpressions {
-			if expr.Key == corev1.LabelHostname {
-				continue
-			}
-
-			requirement, err := convertNodeSelectorRequirementToSelector(expr)
-			if err != nil {
-				return nil, err
-			}
-			if requirement != nil {
-				nodeFilter = nodeFilter.Add(*requirement)
-			}
-		}
-	}
-	return nodeFilter, nil
-}
-
-func convertNodeSelectorRequirementToSelector(req corev1.NodeSelectorRequirement) (*labels.Requirement, error) {
-	var op selection.Operator
-	switch req.Operator {
-	case corev1.NodeSelectorOpIn:
-		op = selection.In
-	case corev1.NodeSelectorOpNotIn:
-		op = selection.NotIn
-	case corev1.NodeSelectorOpExists:
-		op = selection.Exists
-	case corev1.NodeSelectorOpDoesNotExist:
-		op = selection.DoesNotExist
-	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
-		logrus.Debugf("Skipping unsupported node selector operator %s for key %s", req.Operator, req.Key)
-		return nil, nil
-	default:
-		logrus.Warnf("Unknown node selector operator %s for key %s", req.Operator, req.Key)
-		return nil, nil
-	}
-
-	requirement, err := labels.NewRequirement(req.Key, op, req.Values)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create requirement for %s %v %v: %w", req.Key, op, req.Values, err)
-	}
-	return requirement, nil
-}
-
-func (h *vmActionHandler) getVMIPod(vmi *kubevirtv1.VirtualMachineInstance) (*corev1.Pod, error) {
-	selector := labels.SelectorFromSet(labels.Set{
-		kubevirtv1.CreatedByLabel: string(vmi.UID),
-	})
-
-	vmiPods, err := h.podCache.List(vmi.Namespace, selector)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pods for VMI %s/%s: %w", vmi.Namespace, vmi.Name, err)
-	}
-
-	var activePods []*corev1.Pod
-	for _, pod := range vmiPods {
-		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
-			activePods = append(activePods, pod)
-		}
-	}
-
-	if len(activePods) == 0 {
-		return nil, fmt.Errorf("there are no active pods for VMI: %s/%s, migration target cannot be picked unless only 1 pod is active", vmi.Namespace, vmi.Name)
-	}
-	if len(activePods) > 1 {
-		return nil, fmt.Errorf("there are multiple active pods for VMI: %s/%s, migration target can be picked only when at most 1 pod is active", vmi.Namespace, vmi.Name)
-	}
-
-	return activePods[0], nil
+	return migration.FindMigratableNodes(vmi, h.podCache, h.nodeCache)
 }
 
 func (h *vmActionHandler) createVMBackup(vmName, vmNamespace string, input BackupInput) error {
 	apiGroup := kubevirtv1.SchemeGroupVersion.Group
+	backupType := input.Type
+	if backupType == "" {
+		backupType = harvesterv1.Backup
+	}
 	backup := &harvesterv1.VirtualMachineBackup{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      input.Name,
@@ -872,8 +722,10 @@ func (h *vmActionHandler) createVMBackup(vmName, vmNamespace string, input Backu
 				Kind:     kubevirtv1.VirtualMachineGroupVersionKind.Kind,
 				Name:     vmName,
 			},
-			Type:             harvesterv1.Backup,
+			Type:             backupType,
 			FsFreezeDeadline: input.FsFreezeDeadline,
+			Hooks:            input.Hooks,
+			BackupTargetName: input.BackupTargetName,
 		},
 	}
 
@@ -925,6 +777,10 @@ func buildVirtualMachineRestore(vmName, vmNamespace string, input RestoreInput)
 		restore.Spec.HaltAfterRestore = true
 	}
 
+	if len(input.Volumes) > 0 {
+		restore.Spec.Volumes = input.Volumes
+	}
+
 	return restore
 }
 
@@ -1617,6 +1617,19 @@ func TestBuildVirtualMachineRestore(t *testing.T) {
 				HaltAfterRestore: true,
 			},
 		},
+		{
+			name:        "builds partial restore with selected volumes",
+			vmName:      "vm-partial",
+			vmNamespace: "ns-partial",
+			input: RestoreInput{
+				Name:       "restore-partial",
+				BackupName: "backup-partial",
+				Volumes: []harvesterv1.VolumeRestoreSelection{
+					{VolumeName: "disk-0", Mode: harvesterv1.VolumeRestoreModeReplace},
+					{VolumeName: "disk-1", Mode: harvesterv1.VolumeRestoreModeHotplug, TargetVolumeName: "data"},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1630,6 +1643,7 @@ func TestBuildVirtualMachineRestore(t *testing.T) {
 			assert.Equal(t, tt.input.BackupName, got.Spec.VirtualMachineBackupName)
 			assert.Equal(t, tt.input.KeepMacAddress, got.Spec.KeepMacAddress)
 			assert.Equal(t, tt.input.HaltAfterRestore, got.Spec.HaltAfterRestore)
+			assert.Equal(t, tt.input.Volumes, got.Spec.Volumes)
 		})
 	}
 }
@@ -1765,8 +1779,9 @@ func TestCreateVMBackup(t *testing.T) {
 	vmNamespace := "default"
 
 	testCases := []struct {
-		name  string
-		input BackupInput
+		name         string
+		input        BackupInput
+		expectedType harvesterv1.BackupType
 	}{
 		{
 			name: "Create VM backup w/ FsFreezeDeadline (Infinite)",
@@ -1788,6 +1803,23 @@ func TestCreateVMBackup(t *testing.T) {
 				Name: "backup3",
 			},
 		},
+		{
+			name: "Create snapshot-export VM backup in a backup target",
+			input: BackupInput{
+				Name:             "backup4",
+				BackupTargetName: "nfs",
+				Type:             harvesterv1.SnapshotExport,
+			},
+			expectedType: harvesterv1.SnapshotExport,
+		},
+		{
+			// the webhook rejects backups of this type in a backup target
+			name: "Create VM backup in a backup target keeps the backup type",
+			input: BackupInput{
+				Name:             "backup5",
+				BackupTargetName: "nfs",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1805,7 +1837,12 @@ func TestCreateVMBackup(t *testing.T) {
 			assert.Equal(t, tc.input.Name, backup.Name)
 			assert.Equal(t, vmNamespace, backup.Namespace)
 			assert.Equal(t, vmName, backup.Spec.Source.Name)
-			assert.Equal(t, harvesterv1.Backup, backup.Spec.Type)
+			expectedType := tc.expectedType
+			if expectedType == "" {
+				expectedType = harvesterv1.Backup
+			}
+			assert.Equal(t, expectedType, backup.Spec.Type)
+			assert.Equal(t, tc.input.BackupTargetName, backup.Spec.BackupTargetName)
 			assert.Equal(t, tc.input.FsFreezeDeadline, backup.Spec.FsFreezeDeadline)
 		})
 	}
@@ -4,6 +4,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/rancher/wrangler/v3/pkg/condition"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 )
 
 var (
@@ -20,8 +22,12 @@ type EjectCdRomVolumeActionInput struct {
 }
 
 type BackupInput struct {
-	Name             string           `json:"name"`
-	FsFreezeDeadline *metav1.Duration `json:"fsFreezeDeadline,omitempty"`
+	Name             string                   `json:"name"`
+	FsFreezeDeadline *metav1.Duration         `json:"fsFreezeDeadline,omitempty"`
+	Hooks            *harvesterv1.BackupHooks `json:"hooks,omitempty"`
+	BackupTargetName string                   `json:"backupTargetName,omitempty"`
+	// Type defaults to backup, only snapshot-export backups can be stored in a named backup target
+	Type harvesterv1.BackupType `json:"type,omitempty"`
 }
 
 type RestoreInput struct {
@@ -29,6 +35,8 @@ type RestoreInput struct {
 	BackupName       string `json:"backupName"`
 	KeepMacAddress   bool   `json:"keepMacAddress,omitempty"`
 	HaltAfterRestore bool   `json:"haltAfterRestore,omitempty"`
+	// Volumes restores only the selected volumes into the VM
+	Volumes []harvesterv1.VolumeRestoreSelection `json:"volumes,omitempty"`
 }
 
 type MigrateInput struct {
@@ -0,0 +1,28 @@
+package vmbackup
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	actionBrowse = "browse"
+)
+
+func Formatter(request *types.APIRequest, resource *types.RawResource) {
+	resource.Actions = make(map[string]string, 1)
+	if request.AccessControl.CanUpdate(request, resource.APIObject, resource.Schema) != nil {
+		return
+	}
+
+	vmBackup := &harvesterv1.VirtualMachineBackup{}
+	if err := convert.ToObj(resource.APIObject.Data(), vmBackup); err != nil {
+		return
+	}
+
+	if vmBackup.DeletionTimestamp == nil && vmBackup.Status.ReadyToUse != nil && *vmBackup.Status.ReadyToUse {
+		resource.AddAction(request, actionBrowse)
+	}
+}
@@ -0,0 +1,68 @@
+package vmbackup
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const browseSessionSuffix = "browse"
+
+type ActionHandler struct {
+	browseSessions ctlharvesterv1.BackupBrowseSessionClient
+	vmBackupCache  ctlharvesterv1.VirtualMachineBackupCache
+}
+
+func (h *ActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBody, error) {
+	r := ctx.Req()
+
+	vars := util.EncodeVars(mux.Vars(r))
+	action := vars["action"]
+	vmBackupName := vars["name"]
+	vmBackupNamespace := vars["namespace"]
+
+	switch action {
+	case actionBrowse:
+		var input BrowseInput
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to decode request body: %v", err))
+		}
+		if input.VolumeName == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `volumeName` is required")
+		}
+		return h.browse(vmBackupNamespace, vmBackupName, input)
+	default:
+		return nil, apierror.NewAPIError(validation.InvalidAction, "Unsupported action")
+	}
+}
+
+// browse starts a session serving the files of a volume of the backup, and
+// returns it. Its files can be browsed once the session is ready.
+func (h *ActionHandler) browse(namespace, vmBackupName string, input BrowseInput) (*harvesterv1.BackupBrowseSession, error) {
+	if _, err := h.vmBackupCache.Get(namespace, vmBackupName); err != nil {
+		return nil, err
+	}
+
+	session := &harvesterv1.BackupBrowseSession{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name.SafeConcatName(vmBackupName, browseSessionSuffix) + "-",
+			Namespace:    namespace,
+		},
+		Spec: harvesterv1.BackupBrowseSessionSpec{
+			VMBackupName: vmBackupName,
+			VolumeName:   input.VolumeName,
+			TTL:          input.TTL,
+		},
+	}
+	return h.browseSessions.Create(session)
+}
@@ -0,0 +1,10 @@
+package vmbackup
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type BrowseInput struct {
+	VolumeName string           `json:"volumeName"`
+	TTL        *metav1.Duration `json:"ttl,omitempty"`
+}
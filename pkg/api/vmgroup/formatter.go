@@ -0,0 +1,32 @@
+package vmgroup
+
+import (
+	"github.com/rancher/apiserver/pkg/types"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	actionStart   = "start"
+	actionStop    = "stop"
+	actionRestart = "restart"
+)
+
+func formatter(request *types.APIRequest, resource *types.RawResource) {
+	resource.Actions = make(map[string]string, 3)
+	if request.AccessControl.CanUpdate(request, resource.APIObject, resource.Schema) != nil {
+		return
+	}
+
+	phase := harvesterv1.VirtualMachineGroupPhase(resource.APIObject.Data().String("status", "phase"))
+	if isInProgress(phase) {
+		return
+	}
+	resource.AddAction(request, actionStart)
+	resource.AddAction(request, actionStop)
+	resource.AddAction(request, actionRestart)
+}
+
+func isInProgress(phase harvesterv1.VirtualMachineGroupPhase) bool {
+	return phase == harvesterv1.VirtualMachineGroupPhaseStopping || phase == harvesterv1.VirtualMachineGroupPhaseStarting
+}
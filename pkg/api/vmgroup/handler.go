@@ -0,0 +1,63 @@
+package vmgroup
+
+import (
+	"fmt"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// ActionHandler requests an action on a group, the action is run by the VM group controller.
+type ActionHandler struct {
+	groupCache  ctlharvesterv1.VirtualMachineGroupCache
+	groupClient ctlharvesterv1.VirtualMachineGroupClient
+}
+
+func (h *ActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBody, error) {
+	vars := util.EncodeVars(mux.Vars(ctx.Req()))
+	namespace, name := vars["namespace"], vars["name"]
+
+	var action harvesterv1.VirtualMachineGroupAction
+	switch vars["action"] {
+	case actionStart:
+		action = harvesterv1.VirtualMachineGroupActionStart
+	case actionStop:
+		action = harvesterv1.VirtualMachineGroupActionStop
+	case actionRestart:
+		action = harvesterv1.VirtualMachineGroupActionRestart
+	default:
+		return nil, apierror.NewAPIError(validation.InvalidAction, fmt.Sprintf("Unsupported action %s", vars["action"]))
+	}
+	return nil, h.requestAction(namespace, name, action)
+}
+
+func (h *ActionHandler) requestAction(namespace, name string, action harvesterv1.VirtualMachineGroupAction) error {
+	group, err := h.groupCache.Get(namespace, name)
+	if err != nil {
+		return err
+	}
+	if isInProgress(group.Status.Phase) {
+		return apierror.NewAPIError(validation.InvalidState,
+			fmt.Sprintf("VM group %s/%s is running the %s action", namespace, name, group.Status.Action))
+	}
+
+	now := metav1.Now()
+	toUpdate := group.DeepCopy()
+	toUpdate.Status = harvesterv1.VirtualMachineGroupStatus{
+		Action:    action,
+		Phase:     harvesterv1.VirtualMachineGroupPhaseStopping,
+		StartTime: &now,
+	}
+	if action == harvesterv1.VirtualMachineGroupActionStart {
+		toUpdate.Status.Phase = harvesterv1.VirtualMachineGroupPhaseStarting
+	}
+	_, err = h.groupClient.UpdateStatus(toUpdate)
+	return err
+}
@@ -0,0 +1,44 @@
+package vmgroup
+
+import (
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/steve/pkg/schema"
+	"github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
+
+	"github.com/harvester/harvester/pkg/config"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+)
+
+const (
+	vmGroupSchemaID = "harvesterhci.io.virtualmachinegroup"
+)
+
+func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Options) error {
+	groups := scaled.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup()
+	handler := harvesterServer.NewHandler(&ActionHandler{
+		groupCache:  groups.Cache(),
+		groupClient: groups,
+	})
+
+	t := schema.Template{
+		ID:        vmGroupSchemaID,
+		Formatter: formatter,
+		Customize: func(s *types.APISchema) {
+			s.ResourceActions = map[string]schemas.Action{
+				actionStart:   {},
+				actionStop:    {},
+				actionRestart: {},
+			}
+			s.ActionHandlers = map[string]http.Handler{
+				actionStart:   handler,
+				actionStop:    handler,
+				actionRestart: handler,
+			}
+		},
+	}
+	server.SchemaFactory.AddTemplate(t)
+	return nil
+}
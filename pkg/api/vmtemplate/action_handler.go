@@ -0,0 +1,355 @@
+package vmtemplate
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/mux"
+	"github.com/rancher/apiserver/pkg/apierror"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/kubernetes"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	apiutil "github.com/harvester/harvester/pkg/api/util"
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/builder"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlcniv1 "github.com/harvester/harvester/pkg/generated/controllers/k8s.cni.cncf.io/v1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/ref"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/vmtemplate"
+)
+
+type ActionHandler struct {
+	templateVersionCache ctlharvesterv1.VirtualMachineTemplateVersionCache
+	vmClient             ctlkubevirtv1.VirtualMachineClient
+	secretCache          ctlcorev1.SecretCache
+	secretClient         ctlcorev1.SecretClient
+	nadCache             ctlcniv1.NetworkAttachmentDefinitionCache
+	clientSet            kubernetes.Interface
+}
+
+func (h *ActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBody, error) {
+	req := ctx.Req()
+	vars := util.EncodeVars(mux.Vars(req))
+	action := vars["action"]
+	namespace := vars["namespace"]
+	name := vars["name"]
+
+	switch action {
+	case actionInstantiate:
+		var input InstantiateInput
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to decode request body: %v", err))
+		}
+		if input.Name == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `name` is required")
+		}
+		user, ok := request.UserFrom(req.Context())
+		if !ok {
+			return nil, apierror.NewAPIError(validation.Unauthorized, "failed to get user from request")
+		}
+		return h.instantiate(user, namespace, name, input)
+	case actionDiff:
+		var input DiffInput
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to decode request body: %v", err))
+		}
+		if input.VersionID == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `versionId` is required")
+		}
+		return h.diff(namespace, name, input.VersionID)
+	default:
+		return nil, apierror.NewAPIError(validation.InvalidAction, "Unsupported action")
+	}
+}
+
+// instantiate creates a VM from the template version with the given parameters. The PVCs are
+// created from the volume claim templates of the VM, and the secrets of the template version are
+// copied to the VM with the cloud-init parameters rendered. As they're created with the Harvester
+// service account, the user has to be allowed to create them.
+func (h *ActionHandler) instantiate(user user.Info, namespace, name string, input InstantiateInput) (*kubevirtv1.VirtualMachine, error) {
+	tv, err := h.templateVersionCache.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := vmtemplate.ResolveParameters(tv.Spec.Parameters, input.Parameters)
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if err := h.checkNetworks(tv.Spec.Parameters, values); err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+
+	vm, err := newVMFromTemplateVersion(tv, input)
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if err := vmtemplate.ApplyParameters(vm, tv.Spec.Parameters, values); err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, err.Error())
+	}
+	if err := renameVolumeClaimTemplates(vm); err != nil {
+		return nil, err
+	}
+	renderInlineCloudInit(vm, tv.Spec.Parameters, values)
+	secretNameMap, cloudInitSecrets := renameSecrets(vm)
+	if err := h.checkPermissions(user, vm, len(secretNameMap) > 0); err != nil {
+		return nil, err
+	}
+
+	if vm, err = h.vmClient.Create(vm); err != nil {
+		return nil, fmt.Errorf("cannot create VM %s/%s from template version %s/%s, err: %w", namespace, input.Name, namespace, name, err)
+	}
+
+	for oldSecretName, newSecretName := range secretNameMap {
+		if err := h.copySecret(vm, tv, oldSecretName, newSecretName, cloudInitSecrets[oldSecretName], values); err != nil {
+			logger := logrus.WithError(err).WithFields(logrus.Fields{
+				"namespace":       namespace,
+				"templateVersion": name,
+				"vm":              vm.Name,
+			})
+			logger.Error("Failed to copy template version secret")
+			// the copied secrets are owned by the VM, so they're removed with it
+			if err := h.vmClient.Delete(vm.Namespace, vm.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				logger.WithError(err).Error("Failed to delete VM after failing to copy template version secret")
+			}
+			return nil, err
+		}
+	}
+	return vm, nil
+}
+
+// checkPermissions checks the user may create the VM, its secrets and the PVCs of its volume claim
+// templates, and may update the resource quota if the VM skips the resource quota auto scaling.
+func (h *ActionHandler) checkPermissions(user user.Info, vm *kubevirtv1.VirtualMachine, hasSecrets bool) error {
+	type access struct {
+		group    string
+		resource string
+	}
+	required := []access{{group: kubevirtv1.SchemeGroupVersion.Group, resource: "virtualmachines"}}
+	if hasSecrets {
+		required = append(required, access{group: corev1.GroupName, resource: "secrets"})
+	}
+	if vm.Annotations[util.AnnotationVolumeClaimTemplates] != "" {
+		required = append(required, access{group: corev1.GroupName, resource: "persistentvolumeclaims"})
+	}
+
+	for _, a := range required {
+		if ok, err := apiutil.CanCreateResource(h.clientSet, vm.Namespace, user, a.group, a.resource); err != nil {
+			return apierror.NewAPIError(validation.ServerError, fmt.Sprintf("Failed to check permission: %v", err))
+		} else if !ok {
+			return apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("User does not have permission to create %s in namespace %s", a.resource, vm.Namespace))
+		}
+	}
+
+	if _, ok := vm.Annotations[util.AnnotationSkipResourceQuotaAutoScaling]; ok {
+		if ok, err := apiutil.CanUpdateResourceQuota(h.clientSet, vm.Namespace, user.GetName()); err != nil {
+			return apierror.NewAPIError(validation.ServerError, fmt.Sprintf("Failed to check permission: %v", err))
+		} else if !ok {
+			return apierror.NewAPIError(validation.PermissionDenied, "User does not have permission to update resource quota")
+		}
+	}
+	return nil
+}
+
+func (h *ActionHandler) checkNetworks(params []harvesterv1.VirtualMachineTemplateParameter, values map[string]string) error {
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok || p.Type != harvesterv1.TemplateParameterNetwork {
+			continue
+		}
+		nadNamespace, nadName := ref.Parse(value)
+		if _, err := h.nadCache.Get(nadNamespace, nadName); err != nil {
+			return fmt.Errorf("failed to get network %s of parameter %s: %w", value, p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (h *ActionHandler) copySecret(vm *kubevirtv1.VirtualMachine, tv *harvesterv1.VirtualMachineTemplateVersion, oldSecretName, newSecretName string, cloudInit bool, values map[string]string) error {
+	secret, err := h.secretCache.Get(vm.Namespace, oldSecretName)
+	if err != nil {
+		return fmt.Errorf("cannot get secret %s/%s, err: %w", vm.Namespace, oldSecretName, err)
+	}
+
+	data := make(map[string][]byte, len(secret.Data))
+	for key, value := range secret.Data {
+		if cloudInit {
+			value = []byte(vmtemplate.RenderCloudInit(string(value), tv.Spec.Parameters, values))
+		}
+		data[key] = value
+	}
+
+	newSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: vm.Namespace,
+			Name:      newSecretName,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: kubevirtv1.SchemeGroupVersion.String(),
+					Kind:       kubevirtv1.VirtualMachineGroupVersionKind.Kind,
+					Name:       vm.Name,
+					UID:        vm.UID,
+				},
+			},
+		},
+		Data: data,
+		Type: secret.Type,
+	}
+	if _, err := h.secretClient.Create(newSecret); err != nil {
+		return fmt.Errorf("cannot create a new secret from %s/%s, err: %w", vm.Namespace, oldSecretName, err)
+	}
+	return nil
+}
+
+// diff compares the template version with another version of the same template.
+func (h *ActionHandler) diff(namespace, name, versionID string) (*DiffOutput, error) {
+	from, err := h.templateVersionCache.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	toNamespace, toName := ref.Parse(versionID)
+	to, err := h.templateVersionCache.Get(toNamespace, toName)
+	if err != nil {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to get template version %s: %v", versionID, err))
+	}
+	if from.Spec.TemplateID != to.Spec.TemplateID {
+		return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Template version %s isn't a version of template %s", versionID, from.Spec.TemplateID))
+	}
+
+	changes, err := vmtemplate.DiffVersions(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &DiffOutput{
+		From:    ref.Construct(from.Namespace, from.Name),
+		To:      ref.Construct(to.Namespace, to.Name),
+		Changes: changes,
+	}, nil
+}
+
+func newVMFromTemplateVersion(tv *harvesterv1.VirtualMachineTemplateVersion, input InstantiateInput) (*kubevirtv1.VirtualMachine, error) {
+	source := tv.Spec.VM.DeepCopy()
+	if source.Spec.Template == nil {
+		return nil, fmt.Errorf("template version %s/%s has no VM template", tv.Namespace, tv.Name)
+	}
+
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        input.Name,
+			Namespace:   tv.Namespace,
+			Labels:      source.ObjectMeta.Labels,
+			Annotations: source.ObjectMeta.Annotations,
+		},
+		Spec: source.Spec,
+	}
+	if vm.Annotations == nil {
+		vm.Annotations = map[string]string{}
+	}
+	if vm.Spec.Template.ObjectMeta.Labels == nil {
+		vm.Spec.Template.ObjectMeta.Labels = map[string]string{}
+	}
+
+	if input.RunStrategy != "" {
+		runStrategy := kubevirtv1.VirtualMachineRunStrategy(input.RunStrategy)
+		vm.Spec.RunStrategy = &runStrategy
+	}
+	vm.Spec.Template.Spec.Hostname = vm.Name
+	vm.Spec.Template.ObjectMeta.Labels[builder.LabelKeyVirtualMachineName] = vm.Name
+	for i := range vm.Spec.Template.Spec.Domain.Devices.Interfaces {
+		vm.Spec.Template.Spec.Domain.Devices.Interfaces[i].MacAddress = ""
+	}
+	return vm, nil
+}
+
+// renameVolumeClaimTemplates gives the volume claim templates of the template version the names of
+// the VM, so the VM controller creates dedicated PVCs for it.
+func renameVolumeClaimTemplates(vm *kubevirtv1.VirtualMachine) error {
+	entries, err := util.UnmarshalVolumeClaimTemplates(vm.Annotations[util.AnnotationVolumeClaimTemplates])
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	claimNameMap := map[string]string{}
+	for i, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		newName := names.SimpleNameGenerator.GenerateName(fmt.Sprintf("%s-%s-", vm.Name, volume.Name))
+		claimNameMap[volume.PersistentVolumeClaim.ClaimName] = newName
+		vm.Spec.Template.Spec.Volumes[i].PersistentVolumeClaim.ClaimName = newName
+	}
+	for i := range entries {
+		if newName, ok := claimNameMap[entries[i].Name]; ok {
+			entries[i].Name = newName
+		}
+	}
+
+	data, err := util.MarshalVolumeClaimTemplates(entries)
+	if err != nil {
+		return err
+	}
+	vm.Annotations[util.AnnotationVolumeClaimTemplates] = data
+	return nil
+}
+
+func renderInlineCloudInit(vm *kubevirtv1.VirtualMachine, params []harvesterv1.VirtualMachineTemplateParameter, values map[string]string) {
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.CloudInitNoCloud == nil {
+			continue
+		}
+		volume.CloudInitNoCloud.UserData = vmtemplate.RenderCloudInit(volume.CloudInitNoCloud.UserData, params, values)
+		volume.CloudInitNoCloud.NetworkData = vmtemplate.RenderCloudInit(volume.CloudInitNoCloud.NetworkData, params, values)
+	}
+}
+
+// renameSecrets points the VM to the copies of the secrets of the template version. It returns the
+// new names of the secrets and the secrets which hold cloud-init data.
+func renameSecrets(vm *kubevirtv1.VirtualMachine) (map[string]string, map[string]bool) {
+	secretNameMap := map[string]string{}
+	cloudInitSecrets := map[string]bool{}
+	rename := func(name string) string {
+		if _, ok := secretNameMap[name]; !ok {
+			secretNameMap[name] = names.SimpleNameGenerator.GenerateName(fmt.Sprintf("%s-", vm.Name))
+		}
+		return secretNameMap[name]
+	}
+
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.CloudInitNoCloud == nil {
+			continue
+		}
+		if secretRef := volume.CloudInitNoCloud.UserDataSecretRef; secretRef != nil {
+			cloudInitSecrets[secretRef.Name] = true
+			secretRef.Name = rename(secretRef.Name)
+		}
+		if secretRef := volume.CloudInitNoCloud.NetworkDataSecretRef; secretRef != nil {
+			cloudInitSecrets[secretRef.Name] = true
+			secretRef.Name = rename(secretRef.Name)
+		}
+	}
+	for _, credential := range vm.Spec.Template.Spec.AccessCredentials {
+		if sshPublicKey := credential.SSHPublicKey; sshPublicKey != nil && sshPublicKey.Source.Secret != nil {
+			sshPublicKey.Source.Secret.SecretName = rename(sshPublicKey.Source.Secret.SecretName)
+		}
+		if userPassword := credential.UserPassword; userPassword != nil && userPassword.Source.Secret != nil {
+			userPassword.Source.Secret.SecretName = rename(userPassword.Source.Secret.SecretName)
+		}
+	}
+	return secretNameMap, cloudInitSecrets
+}
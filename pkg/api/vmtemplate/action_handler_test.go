@@ -0,0 +1,165 @@
+package vmtemplate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/user"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/builder"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func TestNewVMFromTemplateVersion(t *testing.T) {
+	tv := &harvesterv1.VirtualMachineTemplateVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "template-a", Namespace: "default"},
+		Spec: harvesterv1.VirtualMachineTemplateVersionSpec{
+			VM: harvesterv1.VirtualMachineSourceSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						util.AnnotationVolumeClaimTemplates: `[{"metadata":{"name":"templateversion-template-a-disk-0"}}]`,
+					},
+				},
+				Spec: kubevirtv1.VirtualMachineSpec{
+					Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+						Spec: kubevirtv1.VirtualMachineInstanceSpec{
+							Domain: kubevirtv1.DomainSpec{
+								Devices: kubevirtv1.Devices{
+									Interfaces: []kubevirtv1.Interface{{Name: "nic-1", MacAddress: "52:54:00:00:00:01"}},
+								},
+							},
+							Volumes: []kubevirtv1.Volume{
+								{
+									Name: "disk-0",
+									VolumeSource: kubevirtv1.VolumeSource{
+										PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+											PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "templateversion-template-a-disk-0"},
+										},
+									},
+								},
+								{
+									Name: "cloudinitdisk",
+									VolumeSource: kubevirtv1.VolumeSource{
+										CloudInitNoCloud: &kubevirtv1.CloudInitNoCloudSource{
+											UserDataSecretRef:    &corev1.LocalObjectReference{Name: "templateversion-template-a-cloudinitdisk-userdata"},
+											NetworkDataSecretRef: &corev1.LocalObjectReference{Name: "templateversion-template-a-cloudinitdisk-networkdata"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	vm, err := newVMFromTemplateVersion(tv, InstantiateInput{Name: "vm1", RunStrategy: string(kubevirtv1.RunStrategyHalted)})
+	assert.NoError(t, err)
+	assert.Equal(t, "default", vm.Namespace)
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, *vm.Spec.RunStrategy)
+	assert.Equal(t, "vm1", vm.Spec.Template.Spec.Hostname)
+	assert.Equal(t, "vm1", vm.Spec.Template.ObjectMeta.Labels[builder.LabelKeyVirtualMachineName])
+	assert.Empty(t, vm.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress)
+
+	assert.NoError(t, renameVolumeClaimTemplates(vm))
+	claimName := vm.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName
+	assert.True(t, strings.HasPrefix(claimName, "vm1-disk-0-"))
+	entries, err := util.UnmarshalVolumeClaimTemplates(vm.Annotations[util.AnnotationVolumeClaimTemplates])
+	assert.NoError(t, err)
+	assert.Equal(t, claimName, entries[0].Name)
+
+	secretNameMap, cloudInitSecrets := renameSecrets(vm)
+	assert.Len(t, secretNameMap, 2)
+	assert.True(t, cloudInitSecrets["templateversion-template-a-cloudinitdisk-userdata"])
+	assert.Equal(t, secretNameMap["templateversion-template-a-cloudinitdisk-userdata"],
+		vm.Spec.Template.Spec.Volumes[1].CloudInitNoCloud.UserDataSecretRef.Name)
+
+	// the template version isn't changed
+	assert.Equal(t, "templateversion-template-a-disk-0", tv.Spec.VM.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "52:54:00:00:00:01", tv.Spec.VM.Spec.Template.Spec.Domain.Devices.Interfaces[0].MacAddress)
+}
+
+func TestCheckPermissions(t *testing.T) {
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vm1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				util.AnnotationVolumeClaimTemplates: `[{"metadata":{"name":"vm1-disk-0"}}]`,
+			},
+		},
+	}
+	skipQuotaVM := vm.DeepCopy()
+	skipQuotaVM.Annotations[util.AnnotationSkipResourceQuotaAutoScaling] = "true"
+
+	tests := []struct {
+		name       string
+		vm         *kubevirtv1.VirtualMachine
+		hasSecrets bool
+		denied     string
+		expectErr  bool
+	}{
+		{
+			name:       "all allowed",
+			vm:         vm,
+			hasSecrets: true,
+		},
+		{
+			name:      "vm creation denied",
+			vm:        vm,
+			denied:    "virtualmachines",
+			expectErr: true,
+		},
+		{
+			name:       "secret creation denied",
+			vm:         vm,
+			hasSecrets: true,
+			denied:     "secrets",
+			expectErr:  true,
+		},
+		{
+			name:   "secret creation isn't needed without secrets",
+			vm:     vm,
+			denied: "secrets",
+		},
+		{
+			name:      "pvc creation denied",
+			vm:        vm,
+			denied:    "persistentvolumeclaims",
+			expectErr: true,
+		},
+		{
+			name:      "resource quota update denied when skipping the auto scaling",
+			vm:        skipQuotaVM,
+			denied:    harvesterv1.ResourceQuotaResourceName,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		clientSet := k8sfake.NewSimpleClientset()
+		clientSet.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			review.Status.Allowed = review.Spec.ResourceAttributes.Resource != tc.denied
+			return true, review, nil
+		})
+		h := &ActionHandler{clientSet: clientSet}
+
+		err := h.checkPermissions(&user.DefaultInfo{Name: "alice"}, tc.vm, tc.hasSecrets)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
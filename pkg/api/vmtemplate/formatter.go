@@ -4,10 +4,26 @@ import (
 	"github.com/rancher/apiserver/pkg/types"
 )
 
+const (
+	actionInstantiate = "instantiate"
+	actionDiff        = "diff"
+
+	vmSchemaID = "kubevirt.io.virtualmachine"
+)
+
 func formatter(request *types.APIRequest, resource *types.RawResource) {
 	resource.Links["versions"] = request.URLBuilder.Link(resource.Schema, resource.ID, "versions")
 }
 
-func versionFormatter(_ *types.APIRequest, resource *types.RawResource) {
+func versionFormatter(request *types.APIRequest, resource *types.RawResource) {
 	delete(resource.Links, "update")
+
+	resource.Actions = make(map[string]string, 2)
+	resource.AddAction(request, actionDiff)
+
+	vmSchema := request.Schemas.LookupSchema(vmSchemaID)
+	if vmSchema == nil || request.AccessControl.CanCreate(request, vmSchema) != nil {
+		return
+	}
+	resource.AddAction(request, actionInstantiate)
 }
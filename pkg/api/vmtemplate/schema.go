@@ -1,11 +1,15 @@
 package vmtemplate
 
 import (
+	"net/http"
+
 	"github.com/rancher/apiserver/pkg/types"
 	"github.com/rancher/steve/pkg/schema"
 	"github.com/rancher/steve/pkg/server"
+	"github.com/rancher/wrangler/v3/pkg/schemas"
 
 	"github.com/harvester/harvester/pkg/config"
+	harvesterServer "github.com/harvester/harvester/pkg/server/http"
 )
 
 const (
@@ -19,6 +23,19 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 		templateVersionCache: templateVersionCache,
 	}
 
+	server.BaseSchemas.MustImportAndCustomize(InstantiateInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(DiffInput{}, nil)
+	secrets := scaled.CoreFactory.Core().V1().Secret()
+	actionHandler := &ActionHandler{
+		templateVersionCache: templateVersionCache,
+		vmClient:             scaled.VirtFactory.Kubevirt().V1().VirtualMachine(),
+		secretCache:          secrets.Cache(),
+		secretClient:         secrets,
+		nadCache:             scaled.CniFactory.K8s().V1().NetworkAttachmentDefinition().Cache(),
+		clientSet:            scaled.Management.ClientSet,
+	}
+	handler := harvesterServer.NewHandler(actionHandler)
+
 	t := []schema.Template{
 		{
 			ID:        templateSchemaID,
@@ -30,6 +47,20 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 		{
 			ID:        templateVersionSchemaID,
 			Formatter: versionFormatter,
+			Customize: func(apiSchema *types.APISchema) {
+				apiSchema.ResourceActions = map[string]schemas.Action{
+					actionInstantiate: {
+						Input: "instantiateInput",
+					},
+					actionDiff: {
+						Input: "diffInput",
+					},
+				}
+				apiSchema.ActionHandlers = map[string]http.Handler{
+					actionInstantiate: handler,
+					actionDiff:        handler,
+				}
+			},
 		},
 	}
 
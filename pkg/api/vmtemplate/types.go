@@ -0,0 +1,21 @@
+package vmtemplate
+
+import (
+	"github.com/harvester/harvester/pkg/util/vmtemplate"
+)
+
+type InstantiateInput struct {
+	Name        string            `json:"name"`
+	RunStrategy string            `json:"runStrategy,omitempty"`
+	Parameters  map[string]string `json:"parameters,omitempty"`
+}
+
+type DiffInput struct {
+	VersionID string `json:"versionId"`
+}
+
+type DiffOutput struct {
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Changes []vmtemplate.Change `json:"changes"`
+}
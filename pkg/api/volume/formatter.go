@@ -5,6 +5,8 @@ import (
 	"github.com/rancher/wrangler/v3/pkg/data/convert"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
 	"github.com/harvester/harvester/pkg/ref"
@@ -18,6 +20,7 @@ const (
 	actionClone         = "clone"
 	actionSnapshot      = "snapshot"
 	actionDataMigration = "dataMigration"
+	actionRekey         = "rekey"
 )
 
 type volFormatter struct {
@@ -55,9 +58,47 @@ func (f *volFormatter) Formatter(request *types.APIRequest, resource *types.RawR
 		resource.AddAction(request, actionSnapshot)
 	}
 
-	if vms, err := f.vmCache.GetByIndex(indexeresutil.VMByPVCIndex, ref.Construct(pvc.Namespace, pvc.Name)); err == nil && len(vms) == 0 {
+	vms, err := f.vmCache.GetByIndex(indexeresutil.VMByPVCIndex, ref.Construct(pvc.Namespace, pvc.Name))
+	if err != nil {
+		return
+	}
+	if len(vms) == 0 {
 		resource.AddAction(request, actionDataMigration)
 	}
+
+	if IsEncrypted(pvc, f.scCache) && !IsRekeying(pvc) && allVMsStopped(vms) {
+		resource.AddAction(request, actionRekey)
+	}
+}
+
+// IsEncrypted returns true if the volume is provisioned by an encrypted Longhorn storage class.
+func IsEncrypted(pvc *corev1.PersistentVolumeClaim, scCache ctlstoragev1.StorageClassCache) bool {
+	if pvc.Spec.StorageClassName == nil {
+		return false
+	}
+	sc, err := scCache.Get(*pvc.Spec.StorageClassName)
+	if err != nil {
+		return false
+	}
+	return isEncryptedStorageClass(sc)
+}
+
+func isEncryptedStorageClass(sc *storagev1.StorageClass) bool {
+	return sc.Provisioner == util.CSIProvisionerLonghorn && sc.Parameters[util.LonghornOptionEncrypted] == "true"
+}
+
+// IsRekeying returns true if the data of the volume is being re-encrypted with a new secret.
+func IsRekeying(pvc *corev1.PersistentVolumeClaim) bool {
+	return pvc.Annotations[util.AnnotationVolumeRekeyStatus] == util.VolumeRekeyInProgress
+}
+
+func allVMsStopped(vms []*kubevirtv1.VirtualMachine) bool {
+	for _, vm := range vms {
+		if vm.Status.Created {
+			return false
+		}
+	}
+	return true
 }
 
 func IsResizing(pvc *corev1.PersistentVolumeClaim, scCache ctlstoragev1.StorageClassCache) bool {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -106,6 +107,18 @@ func (h *ActionHandler) Do(ctx *harvesterServer.Ctx) (harvesterServer.ResponseBo
 			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `targetStorageClassName` is required")
 		}
 		return nil, h.dataMigration(req.Context(), pvcNamespace, pvcName, input)
+	case actionRekey:
+		var input RekeyVolumeInput
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("Failed to decode request body: %v", err))
+		}
+		if input.TargetVolumeName == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `targetVolumeName` is required")
+		}
+		if input.TargetStorageClassName == "" {
+			return nil, apierror.NewAPIError(validation.InvalidBodyContent, "Parameter `targetStorageClassName` is required")
+		}
+		return nil, h.rekey(req.Context(), pvcNamespace, pvcName, input)
 	default:
 		return nil, apierror.NewAPIError(validation.InvalidAction, "Unsupported action")
 	}
@@ -558,3 +571,139 @@ func isCDIVolume(sc *storagev1.StorageClass) bool {
 	}
 	return false
 }
+
+// rekey copies the data of the volume into a new volume of an encrypted storage class with another secret,
+// the VMs are switched to the new volume and the volume is deleted by the volume rekey controller once the
+// copy is done. The VMs can't be started meanwhile.
+func (h *ActionHandler) rekey(_ context.Context, pvcNamespace, pvcName string, input RekeyVolumeInput) error {
+	pvc, err := h.validateRekey(pvcNamespace, pvcName, input)
+	if err != nil {
+		return err
+	}
+
+	keyVersion := getKeyVersion(pvc)
+	dv := &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      input.TargetVolumeName,
+			Namespace: pvcNamespace,
+			Annotations: map[string]string{
+				util.AnnotationVolumeRekeySource:    pvcName,
+				util.AnnotationEncryptionKeyVersion: strconv.Itoa(keyVersion + 1),
+			},
+		},
+		Spec: cdiv1.DataVolumeSpec{
+			Source: &cdiv1.DataVolumeSource{
+				PVC: &cdiv1.DataVolumeSourcePVC{
+					Name:      pvcName,
+					Namespace: pvcNamespace,
+				},
+			},
+			Storage: &cdiv1.StorageSpec{
+				AccessModes:      pvc.Spec.AccessModes,
+				VolumeMode:       pvc.Spec.VolumeMode,
+				StorageClassName: &input.TargetStorageClassName,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: pvc.Spec.Resources.Requests,
+				},
+			},
+		},
+	}
+
+	if _, err := h.dataVolumes.Create(dv); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"namespace":  pvcNamespace,
+			"name":       pvcName,
+			"targetName": input.TargetVolumeName,
+			"apiVersion": "cdi.kubevirt.io/v1beta1",
+			"kind":       "DataVolume",
+		}).WithError(err).Error("failed to create DataVolume for volume rekey")
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pvc, err := h.pvcs.Get(pvcNamespace, pvcName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		toUpdate := pvc.DeepCopy()
+		if toUpdate.Annotations == nil {
+			toUpdate.Annotations = map[string]string{}
+		}
+		toUpdate.Annotations[util.AnnotationEncryptionKeyVersion] = strconv.Itoa(keyVersion)
+		toUpdate.Annotations[util.AnnotationVolumeRekeyStatus] = util.VolumeRekeyInProgress
+		toUpdate.Annotations[util.AnnotationVolumeRekeyTarget] = input.TargetVolumeName
+		toUpdate.Annotations[util.AnnotationVolumeRekeyProgress] = "0.0%"
+		delete(toUpdate.Annotations, util.AnnotationVolumeRekeyMessage)
+		_, err = h.pvcs.Update(toUpdate)
+		return err
+	})
+}
+
+func (h *ActionHandler) validateRekey(pvcNamespace, pvcName string, input RekeyVolumeInput) (*corev1.PersistentVolumeClaim, error) {
+	pvc, err := h.pvcCache.Get(pvcNamespace, pvcName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source PVC %s/%s: %v", pvcNamespace, pvcName, err)
+	}
+	if IsRekeying(pvc) {
+		return nil, fmt.Errorf("PVC %s/%s is already being rekeyed to %s", pvcNamespace, pvcName, pvc.Annotations[util.AnnotationVolumeRekeyTarget])
+	}
+
+	if pvc.Spec.StorageClassName == nil {
+		return nil, fmt.Errorf("PVC %s/%s has no StorageClass", pvcNamespace, pvcName)
+	}
+	sourceSC, err := h.scCache.Get(*pvc.Spec.StorageClassName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source StorageClass %s: %v", *pvc.Spec.StorageClassName, err)
+	}
+	if !isEncryptedStorageClass(sourceSC) {
+		return nil, fmt.Errorf("PVC %s/%s is not encrypted", pvcNamespace, pvcName)
+	}
+
+	targetSC, err := h.scCache.Get(input.TargetStorageClassName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target StorageClass %s: %v", input.TargetStorageClassName, err)
+	}
+	if !isEncryptedStorageClass(targetSC) {
+		return nil, fmt.Errorf("target StorageClass %s is not encrypted", input.TargetStorageClassName)
+	}
+	if sourceSC.Parameters[util.CSINodePublishSecretNameKey] == targetSC.Parameters[util.CSINodePublishSecretNameKey] &&
+		sourceSC.Parameters[util.CSINodePublishSecretNamespaceKey] == targetSC.Parameters[util.CSINodePublishSecretNamespaceKey] {
+		return nil, fmt.Errorf("target StorageClass %s uses the secret PVC %s/%s is encrypted with", input.TargetStorageClassName, pvcNamespace, pvcName)
+	}
+
+	if _, err := h.pvcCache.Get(pvcNamespace, input.TargetVolumeName); err == nil {
+		return nil, fmt.Errorf("PVC %s/%s already exists", pvcNamespace, input.TargetVolumeName)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to check existing PVC %s/%s: %v", pvcNamespace, input.TargetVolumeName, err)
+	}
+
+	if _, err := h.dataVolumes.Get(pvcNamespace, input.TargetVolumeName, metav1.GetOptions{}); err == nil {
+		return nil, fmt.Errorf("DataVolume %s/%s already exists", pvcNamespace, input.TargetVolumeName)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to check existing DataVolume %s/%s: %v", pvcNamespace, input.TargetVolumeName, err)
+	}
+
+	// the data is re-encrypted while the VMs are stopped
+	vms, err := h.vmCache.GetByIndex(indexeresutil.VMByPVCIndex, ref.Construct(pvcNamespace, pvcName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VMs using PVC %s/%s: %v", pvcNamespace, pvcName, err)
+	}
+	for _, vm := range vms {
+		if vm.Status.Created {
+			return nil, fmt.Errorf("VM %s/%s using PVC %s/%s must be stopped", vm.Namespace, vm.Name, pvcNamespace, pvcName)
+		}
+	}
+	if err := h.assertPVCNotInUse(pvcNamespace, pvcName); err != nil {
+		return nil, fmt.Errorf("PVC %s/%s is currently in use: %v", pvcNamespace, pvcName, err)
+	}
+
+	return pvc, nil
+}
+
+// getKeyVersion returns the key version of an encrypted volume, volumes which were never rekeyed are on the first version.
+func getKeyVersion(pvc *corev1.PersistentVolumeClaim) int {
+	if version, err := strconv.Atoi(pvc.Annotations[util.AnnotationEncryptionKeyVersion]); err == nil && version > 0 {
+		return version
+	}
+	return 1
+}
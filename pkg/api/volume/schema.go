@@ -22,6 +22,7 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 	server.BaseSchemas.MustImportAndCustomize(CloneVolumeInput{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(SnapshotVolumeInput{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(DataMigrationInput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(RekeyVolumeInput{}, nil)
 	actionHandler := &ActionHandler{
 		images:      scaled.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImage(),
 		pods:        scaled.CoreFactory.Core().V1().Pod().Cache(),
@@ -62,6 +63,9 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 				actionDataMigration: {
 					Input: "dataMigrationInput",
 				},
+				actionRekey: {
+					Input: "rekeyVolumeInput",
+				},
 			}
 			s.ActionHandlers = map[string]http.Handler{
 				actionExport:        handler,
@@ -69,6 +73,7 @@ func RegisterSchema(scaled *config.Scaled, server *server.Server, _ config.Optio
 				actionClone:         handler,
 				actionSnapshot:      handler,
 				actionDataMigration: handler,
+				actionRekey:         handler,
 			}
 		},
 		Formatter: formatter.Formatter,
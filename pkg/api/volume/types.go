@@ -18,3 +18,8 @@ type DataMigrationInput struct {
 	TargetVolumeName       string `json:"targetVolumeName"`
 	TargetStorageClassName string `json:"targetStorageClassName"`
 }
+
+type RekeyVolumeInput struct {
+	TargetVolumeName       string `json:"targetVolumeName"`
+	TargetStorageClassName string `json:"targetStorageClassName"`
+}
@@ -45,15 +45,19 @@ type BackupType string
 const (
 	Backup   BackupType = "backup"
 	Snapshot BackupType = "snapshot"
+	// SnapshotExport takes an in-cluster CSI VolumeSnapshot and exports its
+	// content to the backup target with a Job-based data mover. It works for
+	// any CSI driver that supports VolumeSnapshots, not only Longhorn.
+	SnapshotExport BackupType = "snapshot-export"
 )
 
 // UsesRemoteBackupTarget reports whether this backup type persists data to the
 // configured remote BackupTarget. Snapshot stays in-cluster; Backup pushes to
-// S3 via the Longhorn-native engine. Kept as a predicate so third-party engines
-// can be plugged in later without rewriting call sites.
+// S3 via the Longhorn-native engine; SnapshotExport pushes through the data
+// mover Jobs of the export engine.
 func (b BackupType) UsesRemoteBackupTarget() bool {
 	switch b {
-	case Backup:
+	case Backup, SnapshotExport:
 		return true
 	}
 	return false
@@ -61,11 +65,14 @@ func (b BackupType) UsesRemoteBackupTarget() bool {
 
 // OwnsExternalState reports whether this backup type holds remote state that
 // no other controller will garbage-collect, requiring the engine's ForceDelete
-// to run on every VMBackup removal. The built-in types don't qualify (native
-// Backup defers to Longhorn's own backup CRs), so this currently always
-// returns false. Kept as an extension point for third-party engines that push
-// to storage outside of any K8s resource lifecycle.
+// to run on every VMBackup removal. Native Backup defers to Longhorn's own
+// backup CRs, while the data written by SnapshotExport lives outside of any
+// K8s resource lifecycle.
 func (b BackupType) OwnsExternalState() bool {
+	switch b {
+	case SnapshotExport:
+		return true
+	}
 	return false
 }
 
@@ -91,7 +98,7 @@ type VirtualMachineBackupSpec struct {
 	Source corev1.TypedLocalObjectReference `json:"source"`
 
 	// +kubebuilder:default:="backup"
-	// +kubebuilder:validation:Enum=backup;snapshot
+	// +kubebuilder:validation:Enum=backup;snapshot;snapshot-export
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.type is immutable"
 	Type BackupType `json:"type,omitempty" default:"backup"`
@@ -101,6 +108,101 @@ type VirtualMachineBackupSpec struct {
 	// before it is automatically unfrozen during snapshot or backup creation.
 	// A value of 0 means there is no deadline.
 	FsFreezeDeadline *metav1.Duration `json:"fsFreezeDeadline,omitempty"`
+
+	// +optional
+	// Hooks are commands run inside the guest through the qemu-guest-agent
+	// around the volume snapshots, e.g. to flush database buffers.
+	Hooks *BackupHooks `json:"hooks,omitempty"`
+
+	// +optional
+	// BackupTargetName is the name of the BackupTarget to store the backup in.
+	// The backup-target setting is used if it's empty.
+	BackupTargetName string `json:"backupTargetName,omitempty"`
+}
+
+// HookFailurePolicy defines what to do with the backup when a hook fails
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyAbort fails the backup without taking any volume snapshot
+	HookFailurePolicyAbort HookFailurePolicy = "abort"
+
+	// HookFailurePolicyContinue records the failure and goes on with the backup
+	HookFailurePolicyContinue HookFailurePolicy = "continue"
+)
+
+type HookPhase string
+
+const (
+	HookPhasePreSnapshot  HookPhase = "preSnapshot"
+	HookPhasePostSnapshot HookPhase = "postSnapshot"
+)
+
+type HookStatus string
+
+const (
+	HookStatusSucceeded HookStatus = "succeeded"
+	HookStatusFailed    HookStatus = "failed"
+	// HookStatusSkipped is set when the VM isn't running, its volumes are consistent anyway.
+	HookStatusSkipped HookStatus = "skipped"
+	// HookStatusRunning is set while the hook runs in the background.
+	HookStatusRunning HookStatus = "running"
+)
+
+type BackupHooks struct {
+	// +optional
+	// PreSnapshot hooks run in order before the volume snapshots are taken.
+	PreSnapshot []BackupHook `json:"preSnapshot,omitempty"`
+
+	// +optional
+	// PostSnapshot hooks run in order once all volume snapshots are taken, or
+	// once a pre-snapshot hook aborted the backup.
+	PostSnapshot []BackupHook `json:"postSnapshot,omitempty"`
+}
+
+// BackupHook is a command run inside the guest through the qemu-guest-agent
+type BackupHook struct {
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	// Command is the executable and its arguments, it isn't run in a shell.
+	Command []string `json:"command"`
+
+	// +optional
+	// Timeout defaults to 30 seconds.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// +optional
+	// +kubebuilder:default:="abort"
+	// +kubebuilder:validation:Enum=abort;continue
+	OnFailure HookFailurePolicy `json:"onFailure,omitempty"`
+}
+
+// HookResult records a single execution of a hook
+type HookResult struct {
+	Name string `json:"name"`
+
+	Phase HookPhase `json:"phase"`
+
+	Status HookStatus `json:"status"`
+
+	// +optional
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	// +optional
+	// Output is the end of the combined stdout and stderr of the command.
+	Output string `json:"output,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 // VirtualMachineBackupStatus is the status for a VirtualMachineBackup resource
@@ -112,7 +214,7 @@ type VirtualMachineBackupStatus struct {
 	CreationTime *metav1.Time `json:"creationTime,omitempty"`
 
 	// +optional
-	BackupTarget *BackupTarget `json:"backupTarget,omitempty"`
+	BackupTarget *BackupTargetLocation `json:"backupTarget,omitempty"`
 
 	// +optional
 	CSIDriverVolumeSnapshotClassNames map[string]string `json:"csiDriverVolumeSnapshotClassNames,omitempty"`
@@ -126,6 +228,13 @@ type VirtualMachineBackupStatus struct {
 	// +optional
 	SecretBackups []SecretBackup `json:"secretBackups,omitempty"`
 
+	// +optional
+	HookResults []HookResult `json:"hookResults,omitempty"`
+
+	// +optional
+	// Verification is the result of the last BackupVerification of the backup.
+	Verification *BackupVerificationResult `json:"verification,omitempty"`
+
 	// +optional
 	Progress int `json:"progress,omitempty"`
 
@@ -139,8 +248,10 @@ type VirtualMachineBackupStatus struct {
 	Conditions []Condition `json:"conditions,omitempty"`
 }
 
-// BackupTarget is where VM Backup stores
-type BackupTarget struct {
+// BackupTargetLocation is where VM Backup stores
+type BackupTargetLocation struct {
+	// Name of the BackupTarget, it's empty for the backup-target setting.
+	Name         string `json:"name,omitempty"`
 	Endpoint     string `json:"endpoint,omitempty"`
 	BucketName   string `json:"bucketName,omitempty"`
 	BucketRegion string `json:"bucketRegion,omitempty"`
@@ -182,6 +293,11 @@ type VolumeBackup struct {
 	// +optional
 	Progress int `json:"progress,omitempty"`
 
+	// TransferredBytes is the amount of data actually uploaded to the backup
+	// target, which is lower than VolumeSize for incremental backups.
+	// +optional
+	TransferredBytes int64 `json:"transferredBytes,omitempty"`
+
 	// +optional
 	ReadyToUse *bool `json:"readyToUse,omitempty"`
 
@@ -251,6 +367,39 @@ type VirtualMachineRestoreSpec struct {
 	// HaltAfterRestore defines whether the VM should remain halted after the restore is complete.
 	// If false (default), the VM will be started after a successful restore.
 	HaltAfterRestore bool `json:"haltAfterRestore,omitempty"`
+
+	// +optional
+	// Volumes restricts the restore to the listed volumes of the backup. Only
+	// works when NewVM is false, the other disks and the spec of the existing
+	// VM are left untouched.
+	Volumes []VolumeRestoreSelection `json:"volumes,omitempty"`
+}
+
+type VolumeRestoreMode string
+
+const (
+	// VolumeRestoreModeReplace swaps the PVC of an existing disk of the stopped VM
+	VolumeRestoreModeReplace VolumeRestoreMode = "replace"
+	// VolumeRestoreModeHotplug attaches the restored PVC as a new hotplug disk
+	VolumeRestoreModeHotplug VolumeRestoreMode = "hotplug"
+)
+
+// VolumeRestoreSelection selects a volume of the backup for a partial restore
+type VolumeRestoreSelection struct {
+	// +kubebuilder:validation:Required
+	// VolumeName is the name of the volume in the backup.
+	VolumeName string `json:"volumeName"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=replace;hotplug
+	Mode VolumeRestoreMode `json:"mode"`
+
+	// +optional
+	// TargetVolumeName is the volume of the VM the restored PVC is attached as.
+	// In replace mode, it's the existing volume to replace and defaults to
+	// VolumeName. In hotplug mode, it's the name of the new disk and defaults
+	// to VolumeName with a "-restored" suffix.
+	TargetVolumeName string `json:"targetVolumeName,omitempty"`
 }
 
 // VirtualMachineRestoreStatus is the spec for a VirtualMachineRestore resource
@@ -0,0 +1,79 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BackupBrowseSessionConditionReady is true once the files of the volume can be browsed
+	BackupBrowseSessionConditionReady condition.Cond = "Ready"
+)
+
+type BackupBrowseSessionPhase string
+
+const (
+	BackupBrowseSessionPhasePreparing BackupBrowseSessionPhase = "Preparing"
+	BackupBrowseSessionPhaseReady     BackupBrowseSessionPhase = "Ready"
+	BackupBrowseSessionPhaseFailed    BackupBrowseSessionPhase = "Failed"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=bbs;bbses,scope=Namespaced
+// +kubebuilder:printcolumn:name="SOURCE",type=string,JSONPath=`.spec.vmBackupName`
+// +kubebuilder:printcolumn:name="VOLUME",type=string,JSONPath=`.spec.volumeName`
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="EXPIRATION",type=date,JSONPath=`.status.expirationTime`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BackupBrowseSession restores one volume backup of a VirtualMachineBackup of
+// the same namespace into a temporary PVC, and mounts its filesystems read-only
+// in a helper pod, so single files can be browsed and downloaded through the
+// API server. The session and everything it created are removed after the TTL.
+type BackupBrowseSession struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupBrowseSessionSpec   `json:"spec"`
+	Status BackupBrowseSessionStatus `json:"status,omitempty"`
+}
+
+type BackupBrowseSessionSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.vmBackupName is immutable"
+	VMBackupName string `json:"vmBackupName"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.volumeName is immutable"
+	// VolumeName is the name of the volume in the VM, as in the volume backups.
+	VolumeName string `json:"volumeName"`
+
+	// +optional
+	// TTL is how long the session lives from its creation. It defaults to one hour
+	// and can't exceed 24 hours.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+type BackupBrowseSessionStatus struct {
+	// +optional
+	Phase BackupBrowseSessionPhase `json:"phase,omitempty"`
+
+	// +optional
+	// PVCName is the temporary PVC in harvester-system the volume backup is restored into.
+	PVCName string `json:"pvcName,omitempty"`
+
+	// +optional
+	// PodName is the helper pod in harvester-system serving the files.
+	PodName string `json:"podName,omitempty"`
+
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+
+	// +optional
+	// Message explains why the session failed.
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -0,0 +1,86 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BackupCopyConditionReady is true once the copy is complete in the destination backup target
+	BackupCopyConditionReady condition.Cond = "Ready"
+
+	// BackupCopyConditionProgressing is true while the data mover copies the volumes
+	BackupCopyConditionProgressing condition.Cond = "InProgress"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=vmbackupcopy;vmbackupcopies,scope=Namespaced
+// +kubebuilder:printcolumn:name="SOURCE",type=string,JSONPath=`.spec.vmBackupName`
+// +kubebuilder:printcolumn:name="TARGET",type=string,JSONPath=`.spec.backupTargetName`
+// +kubebuilder:printcolumn:name="READY_TO_USE",type=boolean,JSONPath=`.status.readyToUse`
+// +kubebuilder:printcolumn:name="PROGRESS",type=integer,JSONPath=`.status.progress`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:printcolumn:name="ERROR",type=string,JSONPath=`.status.error.message`
+
+// VirtualMachineBackupCopy copies a VirtualMachineBackup of the same namespace,
+// its volume backups and metadata, into another backup target. The copy lives
+// on independently of the source VirtualMachineBackup, and is removed from the
+// destination backup target with the VirtualMachineBackupCopy.
+type VirtualMachineBackupCopy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualMachineBackupCopySpec   `json:"spec"`
+	Status VirtualMachineBackupCopyStatus `json:"status,omitempty"`
+}
+
+type VirtualMachineBackupCopySpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.vmBackupName is immutable"
+	VMBackupName string `json:"vmBackupName"`
+
+	// +optional
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.backupTargetName is immutable"
+	// BackupTargetName is the name of the BackupTarget to copy the backup to.
+	// The backup-target setting is used if it's empty.
+	BackupTargetName string `json:"backupTargetName,omitempty"`
+}
+
+type VirtualMachineBackupCopyStatus struct {
+	// +optional
+	// SourceBackupTarget is where the copied VirtualMachineBackup is stored.
+	SourceBackupTarget *BackupTargetLocation `json:"sourceBackupTarget,omitempty"`
+
+	// +optional
+	// BackupTarget is where the copy is stored.
+	BackupTarget *BackupTargetLocation `json:"backupTarget,omitempty"`
+
+	// +optional
+	// Type of the copied VirtualMachineBackup.
+	Type BackupType `json:"type,omitempty"`
+
+	// +optional
+	// VolumeBackupNames are the copied volume backups.
+	VolumeBackupNames []string `json:"volumeBackupNames,omitempty"`
+
+	// +optional
+	Progress int `json:"progress,omitempty"`
+
+	// +optional
+	// TransferredBytes is the amount of data written to the destination backup
+	// target, the data already in it is not copied again.
+	TransferredBytes int64 `json:"transferredBytes,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// +optional
+	ReadyToUse *bool `json:"readyToUse,omitempty"`
+
+	// +optional
+	Error *Error `json:"error,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -0,0 +1,83 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// BackupTargetConditionAvailable is true if the backup target can be connected
+	BackupTargetConditionAvailable condition.Cond = "Available"
+)
+
+// +enum
+type BackupTargetType string
+
+const (
+	BackupTargetTypeS3  BackupTargetType = "s3"
+	BackupTargetTypeNFS BackupTargetType = "nfs"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="TYPE",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="ENDPOINT",type=string,JSONPath=`.spec.endpoint`
+// +kubebuilder:printcolumn:name="BUCKET",type=string,JSONPath=`.spec.bucketName`
+// +kubebuilder:printcolumn:name="AVAILABLE",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BackupTarget is a named S3 or NFS server VM backups can be stored in,
+// in addition to the one of the backup-target setting.
+type BackupTarget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupTargetSpec   `json:"spec"`
+	Status BackupTargetStatus `json:"status,omitempty"`
+}
+
+type BackupTargetSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=s3;nfs
+	Type BackupTargetType `json:"type"`
+
+	// +optional
+	// Endpoint is the NFS server path, or the S3 endpoint if it isn't AWS.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// +optional
+	BucketName string `json:"bucketName,omitempty"`
+
+	// +optional
+	BucketRegion string `json:"bucketRegion,omitempty"`
+
+	// +optional
+	VirtualHostedStyle bool `json:"virtualHostedStyle,omitempty"`
+
+	// +optional
+	// CredentialSecret is the name of a secret in the harvester-system namespace
+	// with the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optional AWS_CERT of an S3 target.
+	CredentialSecret string `json:"credentialSecret,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// RefreshIntervalInSeconds is how often the target is checked and the VM
+	// backups in it are synced. 0 means they are only synced on changes.
+	RefreshIntervalInSeconds int64 `json:"refreshIntervalInSeconds,omitempty"`
+
+	// +optional
+	// EncryptionKeySecret is the name of a secret in the harvester-system
+	// namespace with the key to encrypt the metadata and the volume exports
+	// in the target, 32 random bytes encoded in base64.
+	EncryptionKeySecret string `json:"encryptionKeySecret,omitempty"`
+}
+
+type BackupTargetStatus struct {
+	// +optional
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -0,0 +1,102 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	// BackupVerificationConditionCompleted is true once the verification passed or failed
+	BackupVerificationConditionCompleted condition.Cond = "Completed"
+)
+
+type BackupVerificationPhase string
+
+const (
+	BackupVerificationPhaseRestoring BackupVerificationPhase = "Restoring"
+	BackupVerificationPhaseBooting   BackupVerificationPhase = "Booting"
+	BackupVerificationPhasePassed    BackupVerificationPhase = "Passed"
+	BackupVerificationPhaseFailed    BackupVerificationPhase = "Failed"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=bverification;bverifications,scope=Namespaced
+// +kubebuilder:printcolumn:name="SOURCE",type=string,JSONPath=`.spec.vmBackupName`
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="SANDBOX",type=string,JSONPath=`.status.sandboxNamespace`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:printcolumn:name="MESSAGE",type=string,JSONPath=`.status.message`
+
+// BackupVerification restores a VirtualMachineBackup of the same namespace as
+// a new VM in a throwaway sandbox namespace, boots it without any NIC and
+// waits for it to be ready. The result is recorded in the status of the
+// VirtualMachineBackup, the sandbox namespace is removed once it's known.
+type BackupVerification struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupVerificationSpec   `json:"spec"`
+	Status BackupVerificationStatus `json:"status,omitempty"`
+}
+
+type BackupVerificationSpec struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec.vmBackupName is immutable"
+	VMBackupName string `json:"vmBackupName"`
+
+	BackupVerificationTemplate `json:",inline"`
+}
+
+// BackupVerificationTemplate is how a restored VM is checked.
+type BackupVerificationTemplate struct {
+	// +optional
+	// ReadinessProbe replaces the default check, which waits for the guest
+	// agent to connect. The VM has no NIC, so only exec and guest agent ping
+	// probes are supported.
+	ReadinessProbe *kubevirtv1.Probe `json:"readinessProbe,omitempty"`
+
+	// +optional
+	// Timeout is how long the verification may take from its creation,
+	// including the restore. It defaults to 30 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type BackupVerificationStatus struct {
+	// +optional
+	Phase BackupVerificationPhase `json:"phase,omitempty"`
+
+	// +optional
+	// SandboxNamespace is the namespace the backup is restored in.
+	SandboxNamespace string `json:"sandboxNamespace,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// +optional
+	// Message explains why the verification failed.
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// BackupVerificationResult is the outcome of the last verification of a
+// VirtualMachineBackup.
+type BackupVerificationResult struct {
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Passed bool `json:"passed"`
+
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -12,6 +12,7 @@ var (
 	ImageRetryLimitExceeded condition.Cond = "RetryLimitExceeded"
 	BackingImageMissing     condition.Cond = "BackingImageMissing"
 	MetadataReady           condition.Cond = "MetadataReady"
+	ImageConverted          condition.Cond = "Converted"
 )
 
 // +genclient
@@ -44,7 +45,7 @@ type VirtualMachineImageSpec struct {
 	DisplayName string `json:"displayName"`
 
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=download;upload;export-from-volume;restore;clone
+	// +kubebuilder:validation:Enum=download;upload;export-from-volume;restore;clone;registry;remote
 	SourceType VirtualMachineImageSourceType `json:"sourceType"`
 
 	// +optional
@@ -56,9 +57,26 @@ type VirtualMachineImageSpec struct {
 	// +optional
 	URL string `json:"url"`
 
+	// The checksum of the image file, either a bare SHA-512 digest or a digest prefixed with its
+	// algorithm, e.g. sha256:<hex> or sha512:<hex>. The checksum of a remote image is the one of its raw disk.
 	// +optional
 	Checksum string `json:"checksum"`
 
+	// The detached signature the downloaded image file is verified with.
+	// +optional
+	Signature *VirtualMachineImageSignature `json:"signature,omitempty"`
+
+	// The image on a peer Harvester cluster the image is imported from, required by the remote source type.
+	// +optional
+	Remote *VirtualMachineImageRemoteSource `json:"remote,omitempty"`
+
+	// The name of the secret in the image namespace holding the credentials a registry image is
+	// pulled with, under the accessKeyId and secretKey keys. Without it, the image is pulled with
+	// the credentials of the containerd-registry setting, the cdi backend lets the container
+	// runtime of the node pull it then, as its importer runs in the image namespace.
+	// +optional
+	RegistryCredentialSecretName string `json:"registryCredentialSecretName,omitempty"`
+
 	// +optional
 	StorageClassParameters map[string]string `json:"storageClassParameters"`
 
@@ -76,11 +94,70 @@ type VirtualMachineImageSpec struct {
 	// +optional
 	// +kubebuilder:validation:Optional
 	TargetStorageClassName string `json:"targetStorageClassName,omitempty"`
+
+	// The format of the downloaded or uploaded image file. The image is converted to
+	// the target format before it is imported if the two formats differ.
+	// +optional
+	// +kubebuilder:validation:Enum=raw;qcow2;vmdk;vhdx;vpc
+	SourceFormat VirtualMachineImageFormat `json:"sourceFormat,omitempty"`
+
+	// The format the image is converted to, defaults to raw.
+	// +optional
+	// +kubebuilder:validation:Enum=raw;qcow2
+	TargetFormat VirtualMachineImageFormat `json:"targetFormat,omitempty"`
+}
+
+// +enum
+type VirtualMachineImageFormat string
+
+const (
+	VirtualMachineImageFormatRaw   VirtualMachineImageFormat = "raw"
+	VirtualMachineImageFormatQCOW2 VirtualMachineImageFormat = "qcow2"
+	VirtualMachineImageFormatVMDK  VirtualMachineImageFormat = "vmdk"
+	VirtualMachineImageFormatVHDX  VirtualMachineImageFormat = "vhdx"
+	// VirtualMachineImageFormatVPC is the Hyper-V VHD format, named after the qemu-img driver
+	VirtualMachineImageFormatVPC VirtualMachineImageFormat = "vpc"
+)
+
+type VirtualMachineImageSignature struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=cosign;gpg
+	Type VirtualMachineImageSignatureType `json:"type"`
+
+	// The URL of the detached signature of the image file.
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// The name of the secret in the image namespace holding the public key under the publicKey key.
+	// +kubebuilder:validation:Required
+	PublicKeySecretName string `json:"publicKeySecretName"`
 }
 
+type VirtualMachineImageRemoteSource struct {
+	// The name of the secret in the image namespace holding the kubeconfig of the peer cluster under the kubeconfig key.
+	// +kubebuilder:validation:Required
+	KubeconfigSecretName string `json:"kubeconfigSecretName"`
+
+	// +kubebuilder:validation:Required
+	ImageNamespace string `json:"imageNamespace"`
+
+	// +kubebuilder:validation:Required
+	ImageName string `json:"imageName"`
+}
+
+// +enum
+type VirtualMachineImageSignatureType string
+
+const (
+	// VirtualMachineImageSignatureTypeCosign is a base64 encoded signature made by cosign sign-blob
+	VirtualMachineImageSignatureTypeCosign VirtualMachineImageSignatureType = "cosign"
+	// VirtualMachineImageSignatureTypeGPG is a binary or armored signature made by gpg --detach-sign
+	VirtualMachineImageSignatureTypeGPG VirtualMachineImageSignatureType = "gpg"
+)
+
 type VirtualMachineImageSecurityParameters struct {
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=encrypt;decrypt
+	// +kubebuilder:validation:Enum=encrypt;decrypt;rekey
 	CryptoOperation VirtualMachineImageCryptoOperationType `json:"cryptoOperation"`
 
 	// +kubebuilder:validation:Required
@@ -99,6 +176,8 @@ const (
 	VirtualMachineImageSourceTypeExportVolume VirtualMachineImageSourceType = "export-from-volume"
 	VirtualMachineImageSourceTypeRestore      VirtualMachineImageSourceType = "restore"
 	VirtualMachineImageSourceTypeClone        VirtualMachineImageSourceType = "clone"
+	VirtualMachineImageSourceTypeRegistry     VirtualMachineImageSourceType = "registry"
+	VirtualMachineImageSourceTypeRemote       VirtualMachineImageSourceType = "remote"
 )
 
 type VirtualMachineImageCryptoOperationType string
@@ -106,6 +185,9 @@ type VirtualMachineImageCryptoOperationType string
 const (
 	VirtualMachineImageCryptoOperationTypeEncrypt VirtualMachineImageCryptoOperationType = "encrypt"
 	VirtualMachineImageCryptoOperationTypeDecrypt VirtualMachineImageCryptoOperationType = "decrypt"
+	// VirtualMachineImageCryptoOperationTypeRekey re-encrypts an encrypted source image with the
+	// secret of the storage class parameters of the image.
+	VirtualMachineImageCryptoOperationTypeRekey VirtualMachineImageCryptoOperationType = "rekey"
 )
 
 // +enum
@@ -120,6 +202,14 @@ type VirtualMachineImageStatus struct {
 	// +optional
 	AppliedURL string `json:"appliedUrl,omitempty"`
 
+	// The manifest digest the registry reference resolved to when the image was pulled.
+	// +optional
+	ResolvedDigest string `json:"resolvedDigest,omitempty"`
+
+	// The SHA-512 checksum of the raw disk streamed from a peer cluster.
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
 	// +optional
 	Progress int `json:"progress,omitempty"`
 
@@ -133,7 +223,7 @@ type VirtualMachineImageStatus struct {
 	StorageClassName string `json:"storageClassName,omitempty"`
 
 	// +optional
-	BackupTarget *BackupTarget `json:"backupTarget,omitempty"`
+	BackupTarget *BackupTargetLocation `json:"backupTarget,omitempty"`
 
 	// +optional
 	// +kubebuilder:default:=0
@@ -151,6 +241,41 @@ type VirtualMachineImageStatus struct {
 	// +optional
 	// +kubebuilder:validation:Optional
 	TargetStorageClassName string `json:"targetStorageClassName,omitempty"`
+
+	// The number of volumes, VMs and template versions using the image.
+	// +optional
+	ReferenceCount int `json:"referenceCount,omitempty"`
+
+	// The volumes, VMs and template versions using the image.
+	// +optional
+	Consumers []VirtualMachineImageConsumer `json:"consumers,omitempty"`
+
+	// The last time the consumers of the image changed, which is the last time the image
+	// was in use once it has no consumers left.
+	// +optional
+	LastUsedTime *metav1.Time `json:"lastUsedTime,omitempty"`
+
+	// The secret and key version an encrypted image is encrypted with.
+	// +optional
+	Encryption *VirtualMachineImageEncryptionStatus `json:"encryption,omitempty"`
+}
+
+type VirtualMachineImageEncryptionStatus struct {
+	SecretName string `json:"secretName"`
+
+	SecretNamespace string `json:"secretNamespace"`
+
+	// The version of the key, which is 1 for an encrypted image and increases with every rekey.
+	KeyVersion int `json:"keyVersion"`
+}
+
+type VirtualMachineImageConsumer struct {
+	// The kind of the consumer, PersistentVolumeClaim, VirtualMachine or VirtualMachineTemplateVersion.
+	Kind string `json:"kind"`
+
+	Namespace string `json:"namespace"`
+
+	Name string `json:"name"`
 }
 
 type Condition struct {
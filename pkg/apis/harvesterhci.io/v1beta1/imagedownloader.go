@@ -14,6 +14,27 @@ var (
 
 	DownloaderCondsReconciling DownloaderCondsType = "Reconciling"
 	DownloaderCondsReady       DownloaderCondsType = "Ready"
+	DownloaderCondsExported    DownloaderCondsType = "Exported"
+)
+
+const (
+	ImageDownloaderCompressTypeQCOW2 = "qcow2"
+	ImageDownloaderCompressTypeRaw   = "raw"
+)
+
+type ImageExportTargetType string
+
+const (
+	ImageExportTargetTypeS3   ImageExportTargetType = "s3"
+	ImageExportTargetTypeHTTP ImageExportTargetType = "http"
+)
+
+type ImageExportPhase string
+
+const (
+	ImageExportPhaseInProgress ImageExportPhase = "InProgress"
+	ImageExportPhaseCompleted  ImageExportPhase = "Completed"
+	ImageExportPhaseFailed     ImageExportPhase = "Failed"
 )
 
 // +genclient
@@ -22,6 +43,7 @@ var (
 // +kubebuilder:printcolumn:name="VMImage",type=string,JSONPath=`.spec.imageName`
 // +kubebuilder:printcolumn:name="CompressType",type="string",JSONPath=`.spec.compressType`
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.status`
+// +kubebuilder:printcolumn:name="Export",type="string",JSONPath=`.status.export.phase`
 // +kubebuilder:subresource:status
 
 type VirtualMachineImageDownloader struct {
@@ -37,11 +59,44 @@ type VirtualMachineImageDownloaderSpec struct {
 	// +kubebuilder:validation:Required
 	ImageName string `json:"imageName"`
 
-	// compress type of the vm image
-	// +kubebuilder:validation:Enum:=qcow2
+	// compress type of the vm image, raw keeps the image uncompressed
+	// +kubebuilder:validation:Enum:=qcow2;raw
 	// +kubebuilder:default:=qcow2
 	// +optional
 	CompressType string `json:"compressType,omitempty"`
+
+	// push the vm image to an upload target once it is ready, instead of only serving it in the cluster
+	// +optional
+	Export *VirtualMachineImageExportTarget `json:"export,omitempty"`
+}
+
+type VirtualMachineImageExportTarget struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum:=s3;http
+	Type ImageExportTargetType `json:"type"`
+
+	// the object URL the image is PUT to for the http type,
+	// or the S3 endpoint for the s3 type, which defaults to AWS
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// the bucket of the s3 type
+	// +optional
+	Bucket string `json:"bucket,omitempty"`
+
+	// the region of the s3 type
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// the object key of the s3 type, defaults to <namespace>/<image name>.<compress type>
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// the secret in the same namespace holding the credentials. The s3 type reads
+	// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and optionally AWS_CERT, the http type reads
+	// username and password for basic authentication or token for bearer authentication
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
 }
 
 type VirtualMachineImageDownloaderStatus struct {
@@ -58,6 +113,38 @@ type VirtualMachineImageDownloaderStatus struct {
 	// the url of the vm image
 	// +optional
 	DownloadURL string `json:"downloadUrl,omitempty"`
+
+	// the progress of the export to the upload target
+	// +optional
+	Export *VirtualMachineImageExportStatus `json:"export,omitempty"`
+}
+
+type VirtualMachineImageExportStatus struct {
+	// +optional
+	Phase ImageExportPhase `json:"phase,omitempty"`
+
+	// +optional
+	Progress int `json:"progress,omitempty"`
+
+	// +optional
+	UploadedBytes int64 `json:"uploadedBytes,omitempty"`
+
+	// +optional
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+
+	// the sha256 checksum of the uploaded image
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// where the image is exported to
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
 }
 
 type VirtualMachineImageDownloaderCondition struct {
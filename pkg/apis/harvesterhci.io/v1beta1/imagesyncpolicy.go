@@ -0,0 +1,108 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ImageSyncPolicyConditionSynced is true once every selected image of the source cluster is mirrored
+	ImageSyncPolicyConditionSynced condition.Cond = "Synced"
+)
+
+type ImageSyncState string
+
+const (
+	ImageSyncStateSyncing   ImageSyncState = "Syncing"
+	ImageSyncStateInSync    ImageSyncState = "InSync"
+	ImageSyncStateOutOfSync ImageSyncState = "OutOfSync"
+	ImageSyncStateFailed    ImageSyncState = "Failed"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=isp;isps,scope=Namespaced
+// +kubebuilder:printcolumn:name="SOURCE-NAMESPACE",type=string,JSONPath=`.spec.sourceNamespace`
+// +kubebuilder:printcolumn:name="IMAGES",type=integer,JSONPath=`.status.imageCount`
+// +kubebuilder:printcolumn:name="LAST-SYNC",type=date,JSONPath=`.status.lastSyncTime`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+
+// ImageSyncPolicy mirrors the label-selected VM images of a namespace on a peer
+// Harvester cluster into the namespace of the policy. Every source image gets a
+// replica of the same name with the remote source type, replicas are replaced
+// when their source image is recreated or its checksum changes.
+type ImageSyncPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageSyncPolicySpec   `json:"spec"`
+	Status ImageSyncPolicyStatus `json:"status,omitempty"`
+}
+
+type ImageSyncPolicySpec struct {
+	// The name of the secret in the policy namespace holding the kubeconfig of the source cluster under the kubeconfig key.
+	// +kubebuilder:validation:Required
+	KubeconfigSecretName string `json:"kubeconfigSecretName"`
+
+	// +kubebuilder:validation:Required
+	SourceNamespace string `json:"sourceNamespace"`
+
+	// Selects the images of the source namespace to mirror, all images if empty.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// The backend of the replicas, defaults to the backend of the source image.
+	// +optional
+	// +kubebuilder:validation:Enum=backingimage;cdi
+	Backend VMIBackend `json:"backend,omitempty"`
+
+	// The storage class the replicas store their data volume in, required by the cdi backend.
+	// +optional
+	TargetStorageClassName string `json:"targetStorageClassName,omitempty"`
+
+	// How often the source cluster is checked for changes, at least 1 minute, defaults to 10 minutes.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Delete the replicas whose source image is gone or no longer selected.
+	// +optional
+	Prune bool `json:"prune,omitempty"`
+}
+
+type ImageSyncPolicyStatus struct {
+	// The last time the images of the source cluster were compared with their replicas.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// The generation of the policy the last sync was made with.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	ImageCount int `json:"imageCount,omitempty"`
+
+	// +optional
+	Images []ImageSyncStatus `json:"images,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+type ImageSyncStatus struct {
+	// The name of the source image and of its replica.
+	Name string `json:"name"`
+
+	State ImageSyncState `json:"state"`
+
+	// The checksum of the source image, if it is known.
+	// +optional
+	SourceChecksum string `json:"sourceChecksum,omitempty"`
+
+	// The checksum of the replica, recorded once it is imported.
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,135 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type NodeMaintenanceCampaignPhase string
+
+const (
+	NodeMaintenanceCampaignPhaseRunning   NodeMaintenanceCampaignPhase = "Running"
+	NodeMaintenanceCampaignPhaseSucceeded NodeMaintenanceCampaignPhase = "Succeeded"
+	NodeMaintenanceCampaignPhaseFailed    NodeMaintenanceCampaignPhase = "Failed"
+)
+
+type NodeMaintenanceState string
+
+const (
+	NodeMaintenanceStatePending NodeMaintenanceState = "Pending"
+	// NodeMaintenanceStatePreHook runs the pre hook job before maintenance mode is enabled
+	NodeMaintenanceStatePreHook NodeMaintenanceState = "PreHook"
+	// NodeMaintenanceStateEntering waits for the node to be drained
+	NodeMaintenanceStateEntering NodeMaintenanceState = "EnteringMaintenance"
+	// NodeMaintenanceStatePostHook runs the post hook job while the node is in maintenance mode
+	NodeMaintenanceStatePostHook NodeMaintenanceState = "PostHook"
+	// NodeMaintenanceStateExiting disables maintenance mode
+	NodeMaintenanceStateExiting   NodeMaintenanceState = "ExitingMaintenance"
+	NodeMaintenanceStateCompleted NodeMaintenanceState = "Completed"
+	NodeMaintenanceStateFailed    NodeMaintenanceState = "Failed"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=nmc;nmcs,scope=Cluster
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="CONCURRENCY",type=integer,JSONPath=`.spec.concurrency`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:printcolumn:name="MESSAGE",type=string,JSONPath=`.status.message`
+// +kubebuilder:subresource:status
+
+// NodeMaintenanceCampaign puts the nodes into maintenance mode one after another. For each node the
+// pre hook job runs, maintenance mode is enabled, the post hook job runs while the node is drained and
+// maintenance mode is disabled again. No further node is started once a node failed.
+type NodeMaintenanceCampaign struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeMaintenanceCampaignSpec   `json:"spec"`
+	Status NodeMaintenanceCampaignStatus `json:"status,omitempty"`
+}
+
+type NodeMaintenanceCampaignSpec struct {
+	// +optional
+	// Nodes are put into maintenance mode in the listed order, before the nodes
+	// matching the node selector.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// +optional
+	// NodeSelector selects further nodes, they are put into maintenance mode in
+	// the order of their names.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// +optional
+	// Concurrency is the number of nodes in maintenance at the same time.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Concurrency int `json:"concurrency,omitempty"`
+
+	// +optional
+	// Force shuts down the VMs which can't be migrated, as the force option of
+	// the enableMaintenanceMode action does.
+	Force bool `json:"force,omitempty"`
+
+	// +optional
+	// PreHook runs on the node before maintenance mode is enabled.
+	PreHook *NodeMaintenanceHook `json:"preHook,omitempty"`
+
+	// +optional
+	// PostHook runs on the node once it is in maintenance mode, maintenance mode
+	// is disabled after it succeeded.
+	PostHook *NodeMaintenanceHook `json:"postHook,omitempty"`
+}
+
+// NodeMaintenanceHook is a job which runs on the node, the name of the node is in the NODE_NAME
+// environment variable. The job runs as an unprivileged pod unless it requests host access.
+type NodeMaintenanceHook struct {
+	Image string `json:"image"`
+
+	// +optional
+	// HostAccess runs the job privileged in the PID namespace of the host with
+	// the root filesystem of the node mounted at /host.
+	HostAccess bool `json:"hostAccess,omitempty"`
+
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// +optional
+	// Timeout of the job, it defaults to 1 hour.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type NodeMaintenanceCampaignStatus struct {
+	// +optional
+	Phase NodeMaintenanceCampaignPhase `json:"phase,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Nodes []NodeMaintenanceStatus `json:"nodes,omitempty"`
+}
+
+type NodeMaintenanceStatus struct {
+	Name string `json:"name"`
+
+	State NodeMaintenanceState `json:"state"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
@@ -0,0 +1,139 @@
+package v1beta1
+
+import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RebalancePolicyConditionBalanced is true when the scores of the nodes are within the threshold
+	RebalancePolicyConditionBalanced condition.Cond = "Balanced"
+)
+
+type RebalanceMovePhase string
+
+const (
+	RebalanceMovePlanned   RebalanceMovePhase = "Planned"
+	RebalanceMoveMigrating RebalanceMovePhase = "Migrating"
+	RebalanceMoveSucceeded RebalanceMovePhase = "Succeeded"
+	RebalanceMoveFailed    RebalanceMovePhase = "Failed"
+)
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=rbp;rbps,scope=Cluster
+// +kubebuilder:printcolumn:name="PAUSED",type=boolean,JSONPath=`.spec.paused`
+// +kubebuilder:printcolumn:name="THRESHOLD",type=integer,JSONPath=`.spec.threshold`
+// +kubebuilder:printcolumn:name="BALANCED",type=string,JSONPath=`.status.conditions[?(@.type=="Balanced")].status`
+// +kubebuilder:printcolumn:name="LAST-RUN",type=date,JSONPath=`.status.lastRunTime`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+
+// RebalancePolicy live migrates VMs from the nodes with the most allocated CPU and memory to the
+// ones with the least, until the scores of the nodes are within the threshold. The allocation is
+// the sum of the requests of the pods of a node, which are already reduced by the overcommit
+// setting for the VMs. Nothing is migrated unless a policy is created, and only one policy is allowed.
+type RebalancePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RebalancePolicySpec   `json:"spec"`
+	Status RebalancePolicyStatus `json:"status,omitempty"`
+}
+
+type RebalancePolicySpec struct {
+	// Stops planning new migrations, the running ones are still tracked.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Selects the nodes to balance, all nodes if empty.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// The allowed difference in percentage points between the scores of the most and the least
+	// allocated nodes.
+	// +kubebuilder:default:=20
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Threshold int `json:"threshold"`
+
+	// The weight of the allocated CPU in the score of a node.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	CPUWeight int `json:"cpuWeight"`
+
+	// The weight of the allocated memory in the score of a node.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	MemoryWeight int `json:"memoryWeight"`
+
+	// The maximum number of migrations the policy runs at the same time.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	MaxConcurrentMigrations int `json:"maxConcurrentMigrations"`
+
+	// How often the nodes are scored, defaults to 5 minutes.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+type RebalancePolicyStatus struct {
+	// The last time the nodes were scored.
+	// +optional
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+
+	// The generation of the policy the last run was made with.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	Nodes []RebalanceNodeScore `json:"nodes,omitempty"`
+
+	// The moves of the last run which are waiting or migrating.
+	// +optional
+	PlannedMoves []RebalanceMove `json:"plannedMoves,omitempty"`
+
+	// The latest finished moves, the newest last.
+	// +optional
+	ExecutedMoves []RebalanceMove `json:"executedMoves,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+type RebalanceNodeScore struct {
+	Node string `json:"node"`
+
+	// The percentage of the allocatable CPU which is requested.
+	CPU int `json:"cpu"`
+
+	// The percentage of the allocatable memory which is requested.
+	Memory int `json:"memory"`
+
+	// The weighted average of the CPU and memory percentages.
+	Score int `json:"score"`
+}
+
+type RebalanceMove struct {
+	// The VM in the form of <namespace>/<name>.
+	VM string `json:"vm"`
+
+	SourceNode string `json:"sourceNode"`
+
+	TargetNode string `json:"targetNode"`
+
+	Phase RebalanceMovePhase `json:"phase"`
+
+	// The name of the VirtualMachineInstanceMigration in the namespace of the VM.
+	// +optional
+	Migration string `json:"migration,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The time the move entered its phase.
+	// +optional
+	Time *metav1.Time `json:"time,omitempty"`
+}
@@ -7,6 +7,16 @@ import (
 
 var BackupSuspend condition.Cond = "BackupSuspend"
 
+// RetentionTier is a period of a grandfather-father-son retention policy.
+type RetentionTier string
+
+const (
+	RetentionTierHourly  RetentionTier = "hourly"
+	RetentionTierDaily   RetentionTier = "daily"
+	RetentionTierWeekly  RetentionTier = "weekly"
+	RetentionTierMonthly RetentionTier = "monthly"
+)
+
 type VolumeBackupInfo struct {
 	// +optional
 	Name *string `json:"name,omitempty"`
@@ -29,6 +39,28 @@ type VMBackupInfo struct {
 
 	// +optional
 	Error *Error `json:"error,omitempty"`
+
+	// +optional
+	// RetentionTiers are the tiers of the retention policy the backup is kept for.
+	RetentionTiers []RetentionTier `json:"retentionTiers,omitempty"`
+
+	// +optional
+	// Verification is the result of the last BackupVerification of the backup.
+	Verification *BackupVerificationResult `json:"verification,omitempty"`
+}
+
+type VMBackupCopyInfo struct {
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	VMBackupName string `json:"vmBackupName,omitempty"`
+
+	// +optional
+	ReadyToUse *bool `json:"readyToUse,omitempty"`
+
+	// +optional
+	Error *Error `json:"error,omitempty"`
 }
 
 // +genclient
@@ -58,6 +90,8 @@ type ScheduleVMBackupSpec struct {
 	// +kubebuilder:default:=8
 	// +kubebuilder:validation:Maximum=250
 	// +kubebuilder:validation:Minimum=2
+	// Retain is how many of the latest backups are kept, it's ignored when
+	// the retention policy is set.
 	Retain int `json:"retain"`
 
 	// +kubebuilder:validation:Required
@@ -71,12 +105,65 @@ type ScheduleVMBackupSpec struct {
 
 	// +kubebuilder:validation:Required
 	VMBackupSpec VirtualMachineBackupSpec `json:"vmbackup"`
+
+	// +optional
+	// Copy copies every successful backup into another backup target.
+	Copy *ScheduleVMBackupCopy `json:"copy,omitempty"`
+
+	// +optional
+	// RetentionPolicy keeps the backups by tiers from their creation time
+	// instead of the `retain` latest ones. The `maxFailure` failed backups
+	// should be fewer than the backups kept by all tiers.
+	RetentionPolicy *ScheduleVMBackupRetentionPolicy `json:"retentionPolicy,omitempty"`
+
+	// +optional
+	// Verification verifies every successful backup with a BackupVerification.
+	Verification *BackupVerificationTemplate `json:"verification,omitempty"`
+}
+
+// ScheduleVMBackupRetentionPolicy keeps the latest backup of each of the last
+// N hours, days, ISO weeks and months having backups. A backup kept by
+// several tiers is counted by each of them.
+type ScheduleVMBackupRetentionPolicy struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Hourly int `json:"hourly,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Daily int `json:"daily,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Weekly int `json:"weekly,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Monthly int `json:"monthly,omitempty"`
+}
+
+type ScheduleVMBackupCopy struct {
+	// +optional
+	// BackupTargetName is the name of the BackupTarget to copy the backups to.
+	// The backup-target setting is used if it's empty.
+	BackupTargetName string `json:"backupTargetName,omitempty"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:default:=8
+	// +kubebuilder:validation:Maximum=250
+	// +kubebuilder:validation:Minimum=1
+	// Retain is how many copies are kept, independently of the backups kept by the schedule.
+	Retain int `json:"retain"`
 }
 
 type ScheduleVMBackupStatus struct {
 	// +optional
 	VMBackupInfo []VMBackupInfo `json:"vmbackupInfo,omitempty"`
 
+	// +optional
+	// VMBackupCopyInfo is the state of the copies made by the schedule.
+	VMBackupCopyInfo []VMBackupCopyInfo `json:"vmbackupCopyInfo,omitempty"`
+
 	// +optional
 	Failure int `json:"failure,omitempty"`
 
@@ -0,0 +1,76 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type VolumeRemoteBackupInfo struct {
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Success bool `json:"success,omitempty"`
+
+	// +optional
+	// Error is the message of the last error of the backup.
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=svrbackup;svrbackups,scope=Namespaced
+// +kubebuilder:printcolumn:name="Cron",type=string,JSONPath=`.spec.cron`
+// +kubebuilder:printcolumn:name="Retain",type=integer,JSONPath=`.spec.retain`
+// +kubebuilder:printcolumn:name="MaxFailure",type=integer,JSONPath=`.spec.maxFailure`
+// +kubebuilder:printcolumn:name="SpecSuspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.volumeRemoteBackup.type`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.volumeRemoteBackup.source`
+// +kubebuilder:printcolumn:name="Suspended",type=string,JSONPath=`.status.suspended`
+// +kubebuilder:printcolumn:name="Failure",type=integer,JSONPath=`.status.failure`
+
+// ScheduleVolumeRemoteBackup creates VolumeRemoteBackups of a PVC on a cron schedule, it covers
+// the volumes which aren't attached to any VM and aren't backed up by a ScheduleVMBackup.
+type ScheduleVolumeRemoteBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduleVolumeRemoteBackupSpec   `json:"spec"`
+	Status ScheduleVolumeRemoteBackupStatus `json:"status,omitempty"`
+}
+
+type ScheduleVolumeRemoteBackupSpec struct {
+	// +kubebuilder:validation:Required
+	Cron string `json:"cron"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:default:=8
+	// +kubebuilder:validation:Maximum=250
+	// +kubebuilder:validation:Minimum=1
+	Retain int `json:"retain"`
+
+	// +kubebuilder:validation:Required
+	// +kubebuilder:default:=4
+	// +kubebuilder:validation:Minimum=2
+	MaxFailure int `json:"maxFailure"`
+
+	// +optional
+	// +kubebuilder:default:=false
+	Suspend bool `json:"suspend"`
+
+	// +kubebuilder:validation:Required
+	VolumeRemoteBackupSpec VolumeRemoteBackupSpec `json:"volumeRemoteBackup"`
+}
+
+type ScheduleVolumeRemoteBackupStatus struct {
+	// +optional
+	VolumeRemoteBackupInfo []VolumeRemoteBackupInfo `json:"volumeRemoteBackupInfo,omitempty"`
+
+	// +optional
+	Failure int `json:"failure,omitempty"`
+
+	// +optional
+	Suspended bool `json:"suspended,omitempty"`
+
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
@@ -76,6 +76,63 @@ type VirtualMachineTemplateVersionSpec struct {
 
 	// +optional
 	VM VirtualMachineSourceSpec `json:"vm,omitempty"`
+
+	// Parameters declares the inputs of the template version, they are resolved and applied to
+	// the VM when the version is instantiated.
+	// +optional
+	Parameters []VirtualMachineTemplateParameter `json:"parameters,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=cpu;memory;diskSize;network;cloudInit
+type VirtualMachineTemplateParameterType string
+
+const (
+	// TemplateParameterCPU sets the CPU cores and limit of the VM.
+	TemplateParameterCPU VirtualMachineTemplateParameterType = "cpu"
+	// TemplateParameterMemory sets the memory limit of the VM.
+	TemplateParameterMemory VirtualMachineTemplateParameterType = "memory"
+	// TemplateParameterDiskSize sets the size of the disk named in the target.
+	TemplateParameterDiskSize VirtualMachineTemplateParameterType = "diskSize"
+	// TemplateParameterNetwork sets the multus network `<namespace>/<name>` of the network named in the target.
+	TemplateParameterNetwork VirtualMachineTemplateParameterType = "network"
+	// TemplateParameterCloudInit replaces the `${name}` references in the cloud-init user and network data.
+	// Its values are substituted as is, so they must not contain line breaks or other control characters.
+	TemplateParameterCloudInit VirtualMachineTemplateParameterType = "cloudInit"
+)
+
+type VirtualMachineTemplateParameter struct {
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Required
+	Type VirtualMachineTemplateParameterType `json:"type"`
+
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Target is the name of the volume of a diskSize parameter, or the name of the network of a
+	// network parameter.
+	// +optional
+	Target string `json:"target,omitempty"`
+
+	// Default is used when the parameter isn't given on instantiation.
+	// +optional
+	Default string `json:"default,omitempty"`
+
+	// +optional
+	Required bool `json:"required,omitempty"`
+
+	// Min and Max bound the value of cpu, memory and diskSize parameters.
+	// +optional
+	Min string `json:"min,omitempty"`
+
+	// +optional
+	Max string `json:"max,omitempty"`
+
+	// Enum lists the allowed values.
+	// +optional
+	Enum []string `json:"enum,omitempty"`
 }
 
 type VirtualMachineSourceSpec struct {
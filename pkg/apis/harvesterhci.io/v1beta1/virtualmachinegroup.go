@@ -0,0 +1,134 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type VirtualMachineGroupAction string
+
+const (
+	VirtualMachineGroupActionStart   VirtualMachineGroupAction = "start"
+	VirtualMachineGroupActionStop    VirtualMachineGroupAction = "stop"
+	VirtualMachineGroupActionRestart VirtualMachineGroupAction = "restart"
+)
+
+type VirtualMachineGroupPhase string
+
+const (
+	VirtualMachineGroupPhaseStopping  VirtualMachineGroupPhase = "Stopping"
+	VirtualMachineGroupPhaseStarting  VirtualMachineGroupPhase = "Starting"
+	VirtualMachineGroupPhaseSucceeded VirtualMachineGroupPhase = "Succeeded"
+	VirtualMachineGroupPhaseFailed    VirtualMachineGroupPhase = "Failed"
+)
+
+type VirtualMachineGroupReadinessGateType string
+
+const (
+	VirtualMachineGroupReadinessGuestAgent VirtualMachineGroupReadinessGateType = "GuestAgentConnected"
+	VirtualMachineGroupReadinessTCPPort    VirtualMachineGroupReadinessGateType = "TCPPort"
+)
+
+type VirtualMachineGroupMemberState string
+
+const (
+	VirtualMachineGroupMemberStopped  VirtualMachineGroupMemberState = "Stopped"
+	VirtualMachineGroupMemberStopping VirtualMachineGroupMemberState = "Stopping"
+	VirtualMachineGroupMemberWaiting  VirtualMachineGroupMemberState = "Waiting"
+	VirtualMachineGroupMemberStarting VirtualMachineGroupMemberState = "Starting"
+	VirtualMachineGroupMemberReady    VirtualMachineGroupMemberState = "Ready"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=vmgroup;vmgroups,scope=Namespaced
+// +kubebuilder:printcolumn:name="ACTION",type=string,JSONPath=`.status.action`
+// +kubebuilder:printcolumn:name="PHASE",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:printcolumn:name="MESSAGE",type=string,JSONPath=`.status.message`
+// +kubebuilder:subresource:status
+
+// VirtualMachineGroup starts the VMs of the same namespace in the order of their boot order and
+// stops them in the reverse order. Members with the same boot order are started and stopped
+// together, a member is only started once the members with a lower boot order are ready.
+type VirtualMachineGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualMachineGroupSpec   `json:"spec"`
+	Status VirtualMachineGroupStatus `json:"status,omitempty"`
+}
+
+type VirtualMachineGroupSpec struct {
+	// +kubebuilder:validation:MinItems=1
+	Members []VirtualMachineGroupMember `json:"members"`
+
+	// +optional
+	// Timeout is how long a member may take to become ready or to stop, it
+	// defaults to 10 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type VirtualMachineGroupMember struct {
+	// Name of the VM in the namespace of the group.
+	Name string `json:"name"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	BootOrder int `json:"bootOrder,omitempty"`
+
+	// +optional
+	// StartDelay is waited after the members with a lower boot order are
+	// ready before the member is started.
+	StartDelay *metav1.Duration `json:"startDelay,omitempty"`
+
+	// +optional
+	// ReadinessGates have to pass in addition to the ready condition of the
+	// VMI before the member is ready. To wait for a service of the guest,
+	// configure a readinessProbe on the VM, the VMI is only ready once it
+	// passes.
+	ReadinessGates []VirtualMachineGroupReadinessGate `json:"readinessGates,omitempty"`
+}
+
+type VirtualMachineGroupReadinessGate struct {
+	// +kubebuilder:validation:Enum=GuestAgentConnected;TCPPort
+	Type VirtualMachineGroupReadinessGateType `json:"type"`
+
+	// +optional
+	// Port is the TCP port which has to accept connections on the masquerade
+	// pod network of the VM, only used by the TCPPort gate. It's probed from
+	// the network namespace of the virt-launcher pod of the VM.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+}
+
+type VirtualMachineGroupStatus struct {
+	// +optional
+	// Action is the last action requested on the group.
+	Action VirtualMachineGroupAction `json:"action,omitempty"`
+
+	// +optional
+	Phase VirtualMachineGroupPhase `json:"phase,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// +optional
+	Members []VirtualMachineGroupMemberStatus `json:"members,omitempty"`
+}
+
+type VirtualMachineGroupMemberStatus struct {
+	Name string `json:"name"`
+
+	// +optional
+	State VirtualMachineGroupMemberState `json:"state,omitempty"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,71 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type VMPlacementPolicyType string
+
+const (
+	// VMPlacementPolicyAntiAffinity keeps the members apart from each other in the topology.
+	VMPlacementPolicyAntiAffinity VMPlacementPolicyType = "AntiAffinity"
+	// VMPlacementPolicyAffinity keeps the members together in the topology.
+	VMPlacementPolicyAffinity VMPlacementPolicyType = "Affinity"
+	// VMPlacementPolicySpread spreads the members evenly across the topology.
+	VMPlacementPolicySpread VMPlacementPolicyType = "Spread"
+)
+
+type VMPlacementPolicyEnforcement string
+
+const (
+	// VMPlacementPolicyHard doesn't schedule a member if the policy can't be met.
+	VMPlacementPolicyHard VMPlacementPolicyEnforcement = "Hard"
+	// VMPlacementPolicySoft schedules a member anyway if the policy can't be met.
+	VMPlacementPolicySoft VMPlacementPolicyEnforcement = "Soft"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=vmpp;vmpps,scope=Namespaced
+// +kubebuilder:printcolumn:name="TYPE",type=string,JSONPath=`.spec.type`
+// +kubebuilder:printcolumn:name="ENFORCEMENT",type=string,JSONPath=`.spec.enforcement`
+// +kubebuilder:printcolumn:name="TOPOLOGY_KEY",type=string,JSONPath=`.spec.topologyKey`
+// +kubebuilder:printcolumn:name="AGE",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VMPlacementPolicy places the VMs of the same namespace which are labeled with
+// harvesterhci.io/placementPolicy=<policy name>. The VM mutator translates the policy
+// into the pod affinity terms or topology spread constraints of the member VMs.
+type VMPlacementPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec VMPlacementPolicySpec `json:"spec"`
+}
+
+type VMPlacementPolicySpec struct {
+	// +kubebuilder:validation:Enum=AntiAffinity;Affinity;Spread
+	Type VMPlacementPolicyType `json:"type"`
+
+	// +optional
+	// +kubebuilder:default=Soft
+	// +kubebuilder:validation:Enum=Hard;Soft
+	Enforcement VMPlacementPolicyEnforcement `json:"enforcement,omitempty"`
+
+	// +optional
+	// TopologyKey is the node label the policy applies to, it defaults to
+	// kubernetes.io/hostname for AntiAffinity and Affinity, and to
+	// topology.kubernetes.io/zone for Spread.
+	TopologyKey string `json:"topologyKey,omitempty"`
+
+	// +optional
+	// Weight of the preferred terms of a Soft AntiAffinity or Affinity policy, it defaults to 100.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight,omitempty"`
+
+	// +optional
+	// MaxSkew is the maximum difference of the number of members between the
+	// topology domains of a Spread policy, it defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+}
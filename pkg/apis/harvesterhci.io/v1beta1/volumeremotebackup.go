@@ -2,6 +2,7 @@ package v1beta1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,6 +11,9 @@ type VolumeRemoteBackupType string
 
 const (
 	VolumeRemoteBackupLH VolumeRemoteBackupType = "lh"
+	// VolumeRemoteBackupCSI backs up the volume with the backup VolumeSnapshotClass of its CSI driver,
+	// for the volumes which are not provisioned by Longhorn.
+	VolumeRemoteBackupCSI VolumeRemoteBackupType = "csi"
 )
 
 // +genclient
@@ -31,7 +35,7 @@ type VolumeRemoteBackup struct {
 
 type VolumeRemoteBackupSpec struct {
 	// +kubebuilder:default=lh
-	// +kubebuilder:validation:Enum=lh
+	// +kubebuilder:validation:Enum=lh;csi
 	// +kubebuilder:validation:Required
 	Type VolumeRemoteBackupType `json:"type"`
 
@@ -61,6 +65,9 @@ type VolumeRemoteRestoreType string
 
 const (
 	VolumeRemoteRestoreLH VolumeRemoteRestoreType = "lh"
+	// VolumeRemoteRestoreCSI restores a csi VolumeRemoteBackup from a pre-provisioned
+	// VolumeSnapshotContent which retains the backup snapshot.
+	VolumeRemoteRestoreCSI VolumeRemoteRestoreType = "csi"
 )
 
 // +genclient
@@ -80,12 +87,32 @@ type VolumeRemoteRestore struct {
 
 type VolumeRemoteRestoreSpec struct {
 	// +kubebuilder:default=lh
-	// +kubebuilder:validation:Enum=lh
+	// +kubebuilder:validation:Enum=lh;csi
 	// +kubebuilder:validation:Required
 	Type VolumeRemoteRestoreType `json:"type"`
 
 	// +kubebuilder:validation:Required
 	From string `json:"from"`
+
+	// +optional
+	// TargetNamespace is the namespace the PVC is restored into, it's the namespace of the restore
+	// if empty. A PVC restored into another namespace isn't owned by the restore and is kept when
+	// the restore is deleted.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// +optional
+	// TargetPVCName is the name of the restored PVC, it's the name of the restore if empty.
+	TargetPVCName string `json:"targetPVCName,omitempty"`
+
+	// +optional
+	// StorageClassName is the storage class of the restored PVC, it must be provisioned by the CSI
+	// driver of the backup. It's the storage class of the source PVC if empty.
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// +optional
+	// Size is the requested storage of the restored PVC, it can't be smaller than the one of the
+	// source PVC. It's the size of the source PVC if empty.
+	Size *resource.Quantity `json:"size,omitempty"`
 }
 
 type VolumeRemoteRestoreStatus struct {
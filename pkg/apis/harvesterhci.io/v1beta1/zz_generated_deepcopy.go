@@ -25,6 +25,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	types "k8s.io/apimachinery/pkg/types"
+	corev1 "kubevirt.io/api/core/v1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -142,101 +143,170 @@ func (in *Archive) DeepCopy() *Archive {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *BackupTarget) DeepCopyInto(out *BackupTarget) {
+func (in *BackupBrowseSession) DeepCopyInto(out *BackupBrowseSession) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTarget.
-func (in *BackupTarget) DeepCopy() *BackupTarget {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupBrowseSession.
+func (in *BackupBrowseSession) DeepCopy() *BackupBrowseSession {
 	if in == nil {
 		return nil
 	}
-	out := new(BackupTarget)
+	out := new(BackupBrowseSession)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupBrowseSession) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Condition) DeepCopyInto(out *Condition) {
+func (in *BackupBrowseSessionList) DeepCopyInto(out *BackupBrowseSessionList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BackupBrowseSession, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
-func (in *Condition) DeepCopy() *Condition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupBrowseSessionList.
+func (in *BackupBrowseSessionList) DeepCopy() *BackupBrowseSessionList {
 	if in == nil {
 		return nil
 	}
-	out := new(Condition)
+	out := new(BackupBrowseSessionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupBrowseSessionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupBrowseSessionSpec) DeepCopyInto(out *BackupBrowseSessionSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupBrowseSessionSpec.
+func (in *BackupBrowseSessionSpec) DeepCopy() *BackupBrowseSessionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupBrowseSessionSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Error) DeepCopyInto(out *Error) {
+func (in *BackupBrowseSessionStatus) DeepCopyInto(out *BackupBrowseSessionStatus) {
 	*out = *in
-	if in.Time != nil {
-		in, out := &in.Time, &out.Time
+	if in.ExpirationTime != nil {
+		in, out := &in.ExpirationTime, &out.ExpirationTime
 		*out = (*in).DeepCopy()
 	}
-	if in.Message != nil {
-		in, out := &in.Message, &out.Message
-		*out = new(string)
-		**out = **in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Error.
-func (in *Error) DeepCopy() *Error {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupBrowseSessionStatus.
+func (in *BackupBrowseSessionStatus) DeepCopy() *BackupBrowseSessionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Error)
+	out := new(BackupBrowseSessionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ErrorResponse) DeepCopyInto(out *ErrorResponse) {
+func (in *BackupHook) DeepCopyInto(out *BackupHook) {
 	*out = *in
-	if in.Errors != nil {
-		in, out := &in.Errors, &out.Errors
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrorResponse.
-func (in *ErrorResponse) DeepCopy() *ErrorResponse {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupHook.
+func (in *BackupHook) DeepCopy() *BackupHook {
 	if in == nil {
 		return nil
 	}
-	out := new(ErrorResponse)
+	out := new(BackupHook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyGenInput) DeepCopyInto(out *KeyGenInput) {
+func (in *BackupHooks) DeepCopyInto(out *BackupHooks) {
 	*out = *in
+	if in.PreSnapshot != nil {
+		in, out := &in.PreSnapshot, &out.PreSnapshot
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PostSnapshot != nil {
+		in, out := &in.PostSnapshot, &out.PostSnapshot
+		*out = make([]BackupHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyGenInput.
-func (in *KeyGenInput) DeepCopy() *KeyGenInput {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupHooks.
+func (in *BackupHooks) DeepCopy() *BackupHooks {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyGenInput)
+	out := new(BackupHooks)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyPair) DeepCopyInto(out *KeyPair) {
+func (in *BackupTarget) DeepCopyInto(out *BackupTarget) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -245,18 +315,18 @@ func (in *KeyPair) DeepCopyInto(out *KeyPair) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPair.
-func (in *KeyPair) DeepCopy() *KeyPair {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTarget.
+func (in *BackupTarget) DeepCopy() *BackupTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyPair)
+	out := new(BackupTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KeyPair) DeepCopyObject() runtime.Object {
+func (in *BackupTarget) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -264,13 +334,13 @@ func (in *KeyPair) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyPairList) DeepCopyInto(out *KeyPairList) {
+func (in *BackupTargetList) DeepCopyInto(out *BackupTargetList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]KeyPair, len(*in))
+		*out = make([]BackupTarget, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -278,18 +348,18 @@ func (in *KeyPairList) DeepCopyInto(out *KeyPairList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairList.
-func (in *KeyPairList) DeepCopy() *KeyPairList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTargetList.
+func (in *BackupTargetList) DeepCopy() *BackupTargetList {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyPairList)
+	out := new(BackupTargetList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *KeyPairList) DeepCopyObject() runtime.Object {
+func (in *BackupTargetList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -297,96 +367,84 @@ func (in *KeyPairList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyPairSpec) DeepCopyInto(out *KeyPairSpec) {
+func (in *BackupTargetLocation) DeepCopyInto(out *BackupTargetLocation) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairSpec.
-func (in *KeyPairSpec) DeepCopy() *KeyPairSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTargetLocation.
+func (in *BackupTargetLocation) DeepCopy() *BackupTargetLocation {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyPairSpec)
+	out := new(BackupTargetLocation)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeyPairStatus) DeepCopyInto(out *KeyPairStatus) {
+func (in *BackupTargetSpec) DeepCopyInto(out *BackupTargetSpec) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		copy(*out, *in)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairStatus.
-func (in *KeyPairStatus) DeepCopy() *KeyPairStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTargetSpec.
+func (in *BackupTargetSpec) DeepCopy() *BackupTargetSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(KeyPairStatus)
+	out := new(BackupTargetSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NodeUpgradeStatus) DeepCopyInto(out *NodeUpgradeStatus) {
+func (in *BackupTargetStatus) DeepCopyInto(out *BackupTargetStatus) {
 	*out = *in
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeUpgradeStatus.
-func (in *NodeUpgradeStatus) DeepCopy() *NodeUpgradeStatus {
-	if in == nil {
-		return nil
+	if in.LastSyncedTime != nil {
+		in, out := &in.LastSyncedTime, &out.LastSyncedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
 	}
-	out := new(NodeUpgradeStatus)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PersistentVolumeClaimSourceSpec) DeepCopyInto(out *PersistentVolumeClaimSourceSpec) {
-	*out = *in
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimSourceSpec.
-func (in *PersistentVolumeClaimSourceSpec) DeepCopy() *PersistentVolumeClaimSourceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTargetStatus.
+func (in *BackupTargetStatus) DeepCopy() *BackupTargetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PersistentVolumeClaimSourceSpec)
+	out := new(BackupTargetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Preference) DeepCopyInto(out *Preference) {
+func (in *BackupVerification) DeepCopyInto(out *BackupVerification) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Preference.
-func (in *Preference) DeepCopy() *Preference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerification.
+func (in *BackupVerification) DeepCopy() *BackupVerification {
 	if in == nil {
 		return nil
 	}
-	out := new(Preference)
+	out := new(BackupVerification)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Preference) DeepCopyObject() runtime.Object {
+func (in *BackupVerification) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -394,13 +452,13 @@ func (in *Preference) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PreferenceList) DeepCopyInto(out *PreferenceList) {
+func (in *BackupVerificationList) DeepCopyInto(out *BackupVerificationList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Preference, len(*in))
+		*out = make([]BackupVerification, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -408,18 +466,18 @@ func (in *PreferenceList) DeepCopyInto(out *PreferenceList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreferenceList.
-func (in *PreferenceList) DeepCopy() *PreferenceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationList.
+func (in *BackupVerificationList) DeepCopy() *BackupVerificationList {
 	if in == nil {
 		return nil
 	}
-	out := new(PreferenceList)
+	out := new(BackupVerificationList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PreferenceList) DeepCopyObject() runtime.Object {
+func (in *BackupVerificationList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -427,258 +485,210 @@ func (in *PreferenceList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceQuota) DeepCopyInto(out *ResourceQuota) {
+func (in *BackupVerificationResult) DeepCopyInto(out *BackupVerificationResult) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuota.
-func (in *ResourceQuota) DeepCopy() *ResourceQuota {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationResult.
+func (in *BackupVerificationResult) DeepCopy() *BackupVerificationResult {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceQuota)
+	out := new(BackupVerificationResult)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ResourceQuota) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupVerificationSpec) DeepCopyInto(out *BackupVerificationSpec) {
+	*out = *in
+	in.BackupVerificationTemplate.DeepCopyInto(&out.BackupVerificationTemplate)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationSpec.
+func (in *BackupVerificationSpec) DeepCopy() *BackupVerificationSpec {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(BackupVerificationSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceQuotaList) DeepCopyInto(out *ResourceQuotaList) {
+func (in *BackupVerificationStatus) DeepCopyInto(out *BackupVerificationStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ResourceQuota, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaList.
-func (in *ResourceQuotaList) DeepCopy() *ResourceQuotaList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationStatus.
+func (in *BackupVerificationStatus) DeepCopy() *BackupVerificationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceQuotaList)
+	out := new(BackupVerificationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ResourceQuotaList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceQuotaSpec) DeepCopyInto(out *ResourceQuotaSpec) {
+func (in *BackupVerificationTemplate) DeepCopyInto(out *BackupVerificationTemplate) {
 	*out = *in
-	in.SnapshotLimit.DeepCopyInto(&out.SnapshotLimit)
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(corev1.Probe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaSpec.
-func (in *ResourceQuotaSpec) DeepCopy() *ResourceQuotaSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupVerificationTemplate.
+func (in *BackupVerificationTemplate) DeepCopy() *BackupVerificationTemplate {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceQuotaSpec)
+	out := new(BackupVerificationTemplate)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceQuotaStatus) DeepCopyInto(out *ResourceQuotaStatus) {
+func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
-	in.SnapshotLimitStatus.DeepCopyInto(&out.SnapshotLimitStatus)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaStatus.
-func (in *ResourceQuotaStatus) DeepCopy() *ResourceQuotaStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceQuotaStatus)
+	out := new(Condition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ScheduleVMBackup) DeepCopyInto(out *ScheduleVMBackup) {
+func (in *Error) DeepCopyInto(out *Error) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+	if in.Message != nil {
+		in, out := &in.Message, &out.Message
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackup.
-func (in *ScheduleVMBackup) DeepCopy() *ScheduleVMBackup {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Error.
+func (in *Error) DeepCopy() *Error {
 	if in == nil {
 		return nil
 	}
-	out := new(ScheduleVMBackup)
+	out := new(Error)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ScheduleVMBackup) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ScheduleVMBackupList) DeepCopyInto(out *ScheduleVMBackupList) {
+func (in *ErrorResponse) DeepCopyInto(out *ErrorResponse) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ScheduleVMBackup, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupList.
-func (in *ScheduleVMBackupList) DeepCopy() *ScheduleVMBackupList {
-	if in == nil {
-		return nil
-	}
-	out := new(ScheduleVMBackupList)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ScheduleVMBackupList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	return nil
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ScheduleVMBackupSpec) DeepCopyInto(out *ScheduleVMBackupSpec) {
-	*out = *in
-	in.VMBackupSpec.DeepCopyInto(&out.VMBackupSpec)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupSpec.
-func (in *ScheduleVMBackupSpec) DeepCopy() *ScheduleVMBackupSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ErrorResponse.
+func (in *ErrorResponse) DeepCopy() *ErrorResponse {
 	if in == nil {
 		return nil
 	}
-	out := new(ScheduleVMBackupSpec)
+	out := new(ErrorResponse)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ScheduleVMBackupStatus) DeepCopyInto(out *ScheduleVMBackupStatus) {
+func (in *HookResult) DeepCopyInto(out *HookResult) {
 	*out = *in
-	if in.VMBackupInfo != nil {
-		in, out := &in.VMBackupInfo, &out.VMBackupInfo
-		*out = make([]VMBackupInfo, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		copy(*out, *in)
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int)
+		**out = **in
 	}
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupStatus.
-func (in *ScheduleVMBackupStatus) DeepCopy() *ScheduleVMBackupStatus {
-	if in == nil {
-		return nil
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
 	}
-	out := new(ScheduleVMBackupStatus)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretBackup) DeepCopyInto(out *SecretBackup) {
-	*out = *in
-	if in.Data != nil {
-		in, out := &in.Data, &out.Data
-		*out = make(map[string][]byte, len(*in))
-		for key, val := range *in {
-			var outVal []byte
-			if val == nil {
-				(*out)[key] = nil
-			} else {
-				in, out := &val, &outVal
-				*out = make([]byte, len(*in))
-				copy(*out, *in)
-			}
-			(*out)[key] = outVal
-		}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretBackup.
-func (in *SecretBackup) DeepCopy() *SecretBackup {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookResult.
+func (in *HookResult) DeepCopy() *HookResult {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretBackup)
+	out := new(HookResult)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Setting) DeepCopyInto(out *Setting) {
+func (in *ImageSyncPolicy) DeepCopyInto(out *ImageSyncPolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Setting.
-func (in *Setting) DeepCopy() *Setting {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncPolicy.
+func (in *ImageSyncPolicy) DeepCopy() *ImageSyncPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(Setting)
+	out := new(ImageSyncPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Setting) DeepCopyObject() runtime.Object {
+func (in *ImageSyncPolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -686,13 +696,13 @@ func (in *Setting) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SettingList) DeepCopyInto(out *SettingList) {
+func (in *ImageSyncPolicyList) DeepCopyInto(out *ImageSyncPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Setting, len(*in))
+		*out = make([]ImageSyncPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -700,18 +710,18 @@ func (in *SettingList) DeepCopyInto(out *SettingList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingList.
-func (in *SettingList) DeepCopy() *SettingList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncPolicyList.
+func (in *ImageSyncPolicyList) DeepCopy() *ImageSyncPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(SettingList)
+	out := new(ImageSyncPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SettingList) DeepCopyObject() runtime.Object {
+func (in *ImageSyncPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -719,8 +729,43 @@ func (in *SettingList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SettingStatus) DeepCopyInto(out *SettingStatus) {
+func (in *ImageSyncPolicySpec) DeepCopyInto(out *ImageSyncPolicySpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncPolicySpec.
+func (in *ImageSyncPolicySpec) DeepCopy() *ImageSyncPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSyncPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSyncPolicyStatus) DeepCopyInto(out *ImageSyncPolicyStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageSyncStatus, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]Condition, len(*in))
@@ -729,84 +774,70 @@ func (in *SettingStatus) DeepCopyInto(out *SettingStatus) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingStatus.
-func (in *SettingStatus) DeepCopy() *SettingStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncPolicyStatus.
+func (in *ImageSyncPolicyStatus) DeepCopy() *ImageSyncPolicyStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SettingStatus)
+	out := new(ImageSyncPolicyStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SnapshotLimit) DeepCopyInto(out *SnapshotLimit) {
+func (in *ImageSyncStatus) DeepCopyInto(out *ImageSyncStatus) {
 	*out = *in
-	if in.VMTotalSnapshotSizeQuota != nil {
-		in, out := &in.VMTotalSnapshotSizeQuota, &out.VMTotalSnapshotSizeQuota
-		*out = make(map[string]int64, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotLimit.
-func (in *SnapshotLimit) DeepCopy() *SnapshotLimit {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSyncStatus.
+func (in *ImageSyncStatus) DeepCopy() *ImageSyncStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SnapshotLimit)
+	out := new(ImageSyncStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SnapshotLimitStatus) DeepCopyInto(out *SnapshotLimitStatus) {
+func (in *KeyGenInput) DeepCopyInto(out *KeyGenInput) {
 	*out = *in
-	if in.VMTotalSnapshotSizeUsage != nil {
-		in, out := &in.VMTotalSnapshotSizeUsage, &out.VMTotalSnapshotSizeUsage
-		*out = make(map[string]int64, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotLimitStatus.
-func (in *SnapshotLimitStatus) DeepCopy() *SnapshotLimitStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyGenInput.
+func (in *KeyGenInput) DeepCopy() *KeyGenInput {
 	if in == nil {
 		return nil
 	}
-	out := new(SnapshotLimitStatus)
+	out := new(KeyGenInput)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SupportBundle) DeepCopyInto(out *SupportBundle) {
+func (in *KeyPair) DeepCopyInto(out *KeyPair) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	out.Spec = in.Spec
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundle.
-func (in *SupportBundle) DeepCopy() *SupportBundle {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPair.
+func (in *KeyPair) DeepCopy() *KeyPair {
 	if in == nil {
 		return nil
 	}
-	out := new(SupportBundle)
+	out := new(KeyPair)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SupportBundle) DeepCopyObject() runtime.Object {
+func (in *KeyPair) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -814,13 +845,13 @@ func (in *SupportBundle) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SupportBundleList) DeepCopyInto(out *SupportBundleList) {
+func (in *KeyPairList) DeepCopyInto(out *KeyPairList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]SupportBundle, len(*in))
+		*out = make([]KeyPair, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -828,18 +859,18 @@ func (in *SupportBundleList) DeepCopyInto(out *SupportBundleList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleList.
-func (in *SupportBundleList) DeepCopy() *SupportBundleList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairList.
+func (in *KeyPairList) DeepCopy() *KeyPairList {
 	if in == nil {
 		return nil
 	}
-	out := new(SupportBundleList)
+	out := new(KeyPairList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SupportBundleList) DeepCopyObject() runtime.Object {
+func (in *KeyPairList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -847,33 +878,23 @@ func (in *SupportBundleList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SupportBundleSpec) DeepCopyInto(out *SupportBundleSpec) {
+func (in *KeyPairSpec) DeepCopyInto(out *KeyPairSpec) {
 	*out = *in
-	if in.ExtraCollectionNamespaces != nil {
-		in, out := &in.ExtraCollectionNamespaces, &out.ExtraCollectionNamespaces
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
-	if in.Timeout != nil {
-		in, out := &in.Timeout, &out.Timeout
-		*out = new(int)
-		**out = **in
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleSpec.
-func (in *SupportBundleSpec) DeepCopy() *SupportBundleSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairSpec.
+func (in *KeyPairSpec) DeepCopy() *KeyPairSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(SupportBundleSpec)
+	out := new(KeyPairSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SupportBundleStatus) DeepCopyInto(out *SupportBundleStatus) {
+func (in *KeyPairStatus) DeepCopyInto(out *KeyPairStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -883,18 +904,18 @@ func (in *SupportBundleStatus) DeepCopyInto(out *SupportBundleStatus) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleStatus.
-func (in *SupportBundleStatus) DeepCopy() *SupportBundleStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyPairStatus.
+func (in *KeyPairStatus) DeepCopy() *KeyPairStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SupportBundleStatus)
+	out := new(KeyPairStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Upgrade) DeepCopyInto(out *Upgrade) {
+func (in *NodeMaintenanceCampaign) DeepCopyInto(out *NodeMaintenanceCampaign) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -903,18 +924,18 @@ func (in *Upgrade) DeepCopyInto(out *Upgrade) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Upgrade.
-func (in *Upgrade) DeepCopy() *Upgrade {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceCampaign.
+func (in *NodeMaintenanceCampaign) DeepCopy() *NodeMaintenanceCampaign {
 	if in == nil {
 		return nil
 	}
-	out := new(Upgrade)
+	out := new(NodeMaintenanceCampaign)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Upgrade) DeepCopyObject() runtime.Object {
+func (in *NodeMaintenanceCampaign) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -922,13 +943,13 @@ func (in *Upgrade) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeList) DeepCopyInto(out *UpgradeList) {
+func (in *NodeMaintenanceCampaignList) DeepCopyInto(out *NodeMaintenanceCampaignList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Upgrade, len(*in))
+		*out = make([]NodeMaintenanceCampaign, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -936,18 +957,18 @@ func (in *UpgradeList) DeepCopyInto(out *UpgradeList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeList.
-func (in *UpgradeList) DeepCopy() *UpgradeList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceCampaignList.
+func (in *NodeMaintenanceCampaignList) DeepCopy() *NodeMaintenanceCampaignList {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeList)
+	out := new(NodeMaintenanceCampaignList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *UpgradeList) DeepCopyObject() runtime.Object {
+func (in *NodeMaintenanceCampaignList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -955,41 +976,55 @@ func (in *UpgradeList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeLog) DeepCopyInto(out *UpgradeLog) {
+func (in *NodeMaintenanceCampaignSpec) DeepCopyInto(out *NodeMaintenanceCampaignSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreHook != nil {
+		in, out := &in.PreHook, &out.PreHook
+		*out = new(NodeMaintenanceHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostHook != nil {
+		in, out := &in.PostHook, &out.PostHook
+		*out = new(NodeMaintenanceHook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLog.
-func (in *UpgradeLog) DeepCopy() *UpgradeLog {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceCampaignSpec.
+func (in *NodeMaintenanceCampaignSpec) DeepCopy() *NodeMaintenanceCampaignSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeLog)
+	out := new(NodeMaintenanceCampaignSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *UpgradeLog) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeLogList) DeepCopyInto(out *UpgradeLogList) {
+func (in *NodeMaintenanceCampaignStatus) DeepCopyInto(out *NodeMaintenanceCampaignStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]UpgradeLog, len(*in))
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeMaintenanceStatus, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -997,18 +1032,125 @@ func (in *UpgradeLogList) DeepCopyInto(out *UpgradeLogList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogList.
-func (in *UpgradeLogList) DeepCopy() *UpgradeLogList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceCampaignStatus.
+func (in *NodeMaintenanceCampaignStatus) DeepCopy() *NodeMaintenanceCampaignStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeLogList)
+	out := new(NodeMaintenanceCampaignStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMaintenanceHook) DeepCopyInto(out *NodeMaintenanceHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceHook.
+func (in *NodeMaintenanceHook) DeepCopy() *NodeMaintenanceHook {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMaintenanceHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMaintenanceStatus) DeepCopyInto(out *NodeMaintenanceStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceStatus.
+func (in *NodeMaintenanceStatus) DeepCopy() *NodeMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeUpgradeStatus) DeepCopyInto(out *NodeUpgradeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeUpgradeStatus.
+func (in *NodeUpgradeStatus) DeepCopy() *NodeUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeClaimSourceSpec) DeepCopyInto(out *PersistentVolumeClaimSourceSpec) {
+	*out = *in
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeClaimSourceSpec.
+func (in *PersistentVolumeClaimSourceSpec) DeepCopy() *PersistentVolumeClaimSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeClaimSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Preference) DeepCopyInto(out *Preference) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Preference.
+func (in *Preference) DeepCopy() *Preference {
+	if in == nil {
+		return nil
+	}
+	out := new(Preference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *UpgradeLogList) DeepCopyObject() runtime.Object {
+func (in *Preference) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1016,152 +1158,227 @@ func (in *UpgradeLogList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeLogSpec) DeepCopyInto(out *UpgradeLogSpec) {
+func (in *PreferenceList) DeepCopyInto(out *PreferenceList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Preference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogSpec.
-func (in *UpgradeLogSpec) DeepCopy() *UpgradeLogSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreferenceList.
+func (in *PreferenceList) DeepCopy() *PreferenceList {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeLogSpec)
+	out := new(PreferenceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PreferenceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeLogStatus) DeepCopyInto(out *UpgradeLogStatus) {
+func (in *RebalanceMove) DeepCopyInto(out *RebalanceMove) {
 	*out = *in
-	if in.Archives != nil {
-		in, out := &in.Archives, &out.Archives
-		*out = make(map[string]Archive, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		copy(*out, *in)
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogStatus.
-func (in *UpgradeLogStatus) DeepCopy() *UpgradeLogStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalanceMove.
+func (in *RebalanceMove) DeepCopy() *RebalanceMove {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeLogStatus)
+	out := new(RebalanceMove)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeSpec) DeepCopyInto(out *UpgradeSpec) {
+func (in *RebalanceNodeScore) DeepCopyInto(out *RebalanceNodeScore) {
 	*out = *in
-	if in.UpgradeImage != nil {
-		in, out := &in.UpgradeImage, &out.UpgradeImage
-		*out = new(string)
-		**out = **in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalanceNodeScore.
+func (in *RebalanceNodeScore) DeepCopy() *RebalanceNodeScore {
+	if in == nil {
+		return nil
 	}
+	out := new(RebalanceNodeScore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalancePolicy) DeepCopyInto(out *RebalancePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSpec.
-func (in *UpgradeSpec) DeepCopy() *UpgradeSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancePolicy.
+func (in *RebalancePolicy) DeepCopy() *RebalancePolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeSpec)
+	out := new(RebalancePolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RebalancePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+func (in *RebalancePolicyList) DeepCopyInto(out *RebalancePolicyList) {
 	*out = *in
-	if in.NodeStatuses != nil {
-		in, out := &in.NodeStatuses, &out.NodeStatuses
-		*out = make(map[string]NodeUpgradeStatus, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RebalancePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
-		copy(*out, *in)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancePolicyList.
+func (in *RebalancePolicyList) DeepCopy() *RebalancePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(RebalancePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RebalancePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalancePolicySpec) DeepCopyInto(out *RebalancePolicySpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
-func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancePolicySpec.
+func (in *RebalancePolicySpec) DeepCopy() *RebalancePolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(UpgradeStatus)
+	out := new(RebalancePolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VMBackupInfo) DeepCopyInto(out *VMBackupInfo) {
+func (in *RebalancePolicyStatus) DeepCopyInto(out *RebalancePolicyStatus) {
 	*out = *in
-	if in.VolumeBackupInfo != nil {
-		in, out := &in.VolumeBackupInfo, &out.VolumeBackupInfo
-		*out = make([]VolumeBackupInfo, len(*in))
+	if in.LastRunTime != nil {
+		in, out := &in.LastRunTime, &out.LastRunTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]RebalanceNodeScore, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlannedMoves != nil {
+		in, out := &in.PlannedMoves, &out.PlannedMoves
+		*out = make([]RebalanceMove, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.ReadyToUse != nil {
-		in, out := &in.ReadyToUse, &out.ReadyToUse
-		*out = new(bool)
-		**out = **in
+	if in.ExecutedMoves != nil {
+		in, out := &in.ExecutedMoves, &out.ExecutedMoves
+		*out = make([]RebalanceMove, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	if in.Error != nil {
-		in, out := &in.Error, &out.Error
-		*out = new(Error)
-		(*in).DeepCopyInto(*out)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMBackupInfo.
-func (in *VMBackupInfo) DeepCopy() *VMBackupInfo {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancePolicyStatus.
+func (in *RebalancePolicyStatus) DeepCopy() *RebalancePolicyStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VMBackupInfo)
+	out := new(RebalancePolicyStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Version) DeepCopyInto(out *Version) {
+func (in *ResourceQuota) DeepCopyInto(out *ResourceQuota) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Version.
-func (in *Version) DeepCopy() *Version {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuota.
+func (in *ResourceQuota) DeepCopy() *ResourceQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(Version)
+	out := new(ResourceQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Version) DeepCopyObject() runtime.Object {
+func (in *ResourceQuota) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1169,13 +1386,13 @@ func (in *Version) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VersionList) DeepCopyInto(out *VersionList) {
+func (in *ResourceQuotaList) DeepCopyInto(out *ResourceQuotaList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Version, len(*in))
+		*out = make([]ResourceQuota, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1183,12 +1400,996 @@ func (in *VersionList) DeepCopyInto(out *VersionList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionList.
-func (in *VersionList) DeepCopy() *VersionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaList.
+func (in *ResourceQuotaList) DeepCopy() *ResourceQuotaList {
 	if in == nil {
 		return nil
 	}
-	out := new(VersionList)
+	out := new(ResourceQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ResourceQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceQuotaSpec) DeepCopyInto(out *ResourceQuotaSpec) {
+	*out = *in
+	in.SnapshotLimit.DeepCopyInto(&out.SnapshotLimit)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaSpec.
+func (in *ResourceQuotaSpec) DeepCopy() *ResourceQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceQuotaStatus) DeepCopyInto(out *ResourceQuotaStatus) {
+	*out = *in
+	in.SnapshotLimitStatus.DeepCopyInto(&out.SnapshotLimitStatus)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuotaStatus.
+func (in *ResourceQuotaStatus) DeepCopy() *ResourceQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackup) DeepCopyInto(out *ScheduleVMBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackup.
+func (in *ScheduleVMBackup) DeepCopy() *ScheduleVMBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScheduleVMBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackupCopy) DeepCopyInto(out *ScheduleVMBackupCopy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupCopy.
+func (in *ScheduleVMBackupCopy) DeepCopy() *ScheduleVMBackupCopy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackupCopy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackupList) DeepCopyInto(out *ScheduleVMBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ScheduleVMBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupList.
+func (in *ScheduleVMBackupList) DeepCopy() *ScheduleVMBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScheduleVMBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackupRetentionPolicy) DeepCopyInto(out *ScheduleVMBackupRetentionPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupRetentionPolicy.
+func (in *ScheduleVMBackupRetentionPolicy) DeepCopy() *ScheduleVMBackupRetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackupRetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackupSpec) DeepCopyInto(out *ScheduleVMBackupSpec) {
+	*out = *in
+	in.VMBackupSpec.DeepCopyInto(&out.VMBackupSpec)
+	if in.Copy != nil {
+		in, out := &in.Copy, &out.Copy
+		*out = new(ScheduleVMBackupCopy)
+		**out = **in
+	}
+	if in.RetentionPolicy != nil {
+		in, out := &in.RetentionPolicy, &out.RetentionPolicy
+		*out = new(ScheduleVMBackupRetentionPolicy)
+		**out = **in
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupVerificationTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupSpec.
+func (in *ScheduleVMBackupSpec) DeepCopy() *ScheduleVMBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVMBackupStatus) DeepCopyInto(out *ScheduleVMBackupStatus) {
+	*out = *in
+	if in.VMBackupInfo != nil {
+		in, out := &in.VMBackupInfo, &out.VMBackupInfo
+		*out = make([]VMBackupInfo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VMBackupCopyInfo != nil {
+		in, out := &in.VMBackupCopyInfo, &out.VMBackupCopyInfo
+		*out = make([]VMBackupCopyInfo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVMBackupStatus.
+func (in *ScheduleVMBackupStatus) DeepCopy() *ScheduleVMBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVMBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVolumeRemoteBackup) DeepCopyInto(out *ScheduleVolumeRemoteBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVolumeRemoteBackup.
+func (in *ScheduleVolumeRemoteBackup) DeepCopy() *ScheduleVolumeRemoteBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVolumeRemoteBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScheduleVolumeRemoteBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVolumeRemoteBackupList) DeepCopyInto(out *ScheduleVolumeRemoteBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ScheduleVolumeRemoteBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVolumeRemoteBackupList.
+func (in *ScheduleVolumeRemoteBackupList) DeepCopy() *ScheduleVolumeRemoteBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVolumeRemoteBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ScheduleVolumeRemoteBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVolumeRemoteBackupSpec) DeepCopyInto(out *ScheduleVolumeRemoteBackupSpec) {
+	*out = *in
+	out.VolumeRemoteBackupSpec = in.VolumeRemoteBackupSpec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVolumeRemoteBackupSpec.
+func (in *ScheduleVolumeRemoteBackupSpec) DeepCopy() *ScheduleVolumeRemoteBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVolumeRemoteBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleVolumeRemoteBackupStatus) DeepCopyInto(out *ScheduleVolumeRemoteBackupStatus) {
+	*out = *in
+	if in.VolumeRemoteBackupInfo != nil {
+		in, out := &in.VolumeRemoteBackupInfo, &out.VolumeRemoteBackupInfo
+		*out = make([]VolumeRemoteBackupInfo, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleVolumeRemoteBackupStatus.
+func (in *ScheduleVolumeRemoteBackupStatus) DeepCopy() *ScheduleVolumeRemoteBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleVolumeRemoteBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretBackup) DeepCopyInto(out *SecretBackup) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string][]byte, len(*in))
+		for key, val := range *in {
+			var outVal []byte
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]byte, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretBackup.
+func (in *SecretBackup) DeepCopy() *SecretBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Setting) DeepCopyInto(out *Setting) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Setting.
+func (in *Setting) DeepCopy() *Setting {
+	if in == nil {
+		return nil
+	}
+	out := new(Setting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Setting) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SettingList) DeepCopyInto(out *SettingList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Setting, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingList.
+func (in *SettingList) DeepCopy() *SettingList {
+	if in == nil {
+		return nil
+	}
+	out := new(SettingList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SettingList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SettingStatus) DeepCopyInto(out *SettingStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SettingStatus.
+func (in *SettingStatus) DeepCopy() *SettingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SettingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotLimit) DeepCopyInto(out *SnapshotLimit) {
+	*out = *in
+	if in.VMTotalSnapshotSizeQuota != nil {
+		in, out := &in.VMTotalSnapshotSizeQuota, &out.VMTotalSnapshotSizeQuota
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotLimit.
+func (in *SnapshotLimit) DeepCopy() *SnapshotLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotLimitStatus) DeepCopyInto(out *SnapshotLimitStatus) {
+	*out = *in
+	if in.VMTotalSnapshotSizeUsage != nil {
+		in, out := &in.VMTotalSnapshotSizeUsage, &out.VMTotalSnapshotSizeUsage
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotLimitStatus.
+func (in *SnapshotLimitStatus) DeepCopy() *SnapshotLimitStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotLimitStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupportBundle) DeepCopyInto(out *SupportBundle) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundle.
+func (in *SupportBundle) DeepCopy() *SupportBundle {
+	if in == nil {
+		return nil
+	}
+	out := new(SupportBundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SupportBundle) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupportBundleList) DeepCopyInto(out *SupportBundleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SupportBundle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleList.
+func (in *SupportBundleList) DeepCopy() *SupportBundleList {
+	if in == nil {
+		return nil
+	}
+	out := new(SupportBundleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SupportBundleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupportBundleSpec) DeepCopyInto(out *SupportBundleSpec) {
+	*out = *in
+	if in.ExtraCollectionNamespaces != nil {
+		in, out := &in.ExtraCollectionNamespaces, &out.ExtraCollectionNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(int)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleSpec.
+func (in *SupportBundleSpec) DeepCopy() *SupportBundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SupportBundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SupportBundleStatus) DeepCopyInto(out *SupportBundleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SupportBundleStatus.
+func (in *SupportBundleStatus) DeepCopy() *SupportBundleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SupportBundleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Upgrade) DeepCopyInto(out *Upgrade) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Upgrade.
+func (in *Upgrade) DeepCopy() *Upgrade {
+	if in == nil {
+		return nil
+	}
+	out := new(Upgrade)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Upgrade) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeList) DeepCopyInto(out *UpgradeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Upgrade, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeList.
+func (in *UpgradeList) DeepCopy() *UpgradeList {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpgradeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeLog) DeepCopyInto(out *UpgradeLog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLog.
+func (in *UpgradeLog) DeepCopy() *UpgradeLog {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeLog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpgradeLog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeLogList) DeepCopyInto(out *UpgradeLogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]UpgradeLog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogList.
+func (in *UpgradeLogList) DeepCopy() *UpgradeLogList {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeLogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *UpgradeLogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeLogSpec) DeepCopyInto(out *UpgradeLogSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogSpec.
+func (in *UpgradeLogSpec) DeepCopy() *UpgradeLogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeLogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeLogStatus) DeepCopyInto(out *UpgradeLogStatus) {
+	*out = *in
+	if in.Archives != nil {
+		in, out := &in.Archives, &out.Archives
+		*out = make(map[string]Archive, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeLogStatus.
+func (in *UpgradeLogStatus) DeepCopy() *UpgradeLogStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeLogStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeSpec) DeepCopyInto(out *UpgradeSpec) {
+	*out = *in
+	if in.UpgradeImage != nil {
+		in, out := &in.UpgradeImage, &out.UpgradeImage
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSpec.
+func (in *UpgradeSpec) DeepCopy() *UpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.NodeStatuses != nil {
+		in, out := &in.NodeStatuses, &out.NodeStatuses
+		*out = make(map[string]NodeUpgradeStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMBackupCopyInfo) DeepCopyInto(out *VMBackupCopyInfo) {
+	*out = *in
+	if in.ReadyToUse != nil {
+		in, out := &in.ReadyToUse, &out.ReadyToUse
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		*out = new(Error)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMBackupCopyInfo.
+func (in *VMBackupCopyInfo) DeepCopy() *VMBackupCopyInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(VMBackupCopyInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMBackupInfo) DeepCopyInto(out *VMBackupInfo) {
+	*out = *in
+	if in.VolumeBackupInfo != nil {
+		in, out := &in.VolumeBackupInfo, &out.VolumeBackupInfo
+		*out = make([]VolumeBackupInfo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReadyToUse != nil {
+		in, out := &in.ReadyToUse, &out.ReadyToUse
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		*out = new(Error)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetentionTiers != nil {
+		in, out := &in.RetentionTiers, &out.RetentionTiers
+		*out = make([]RetentionTier, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupVerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMBackupInfo.
+func (in *VMBackupInfo) DeepCopy() *VMBackupInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(VMBackupInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPlacementPolicy) DeepCopyInto(out *VMPlacementPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPlacementPolicy.
+func (in *VMPlacementPolicy) DeepCopy() *VMPlacementPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPlacementPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VMPlacementPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPlacementPolicyList) DeepCopyInto(out *VMPlacementPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VMPlacementPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPlacementPolicyList.
+func (in *VMPlacementPolicyList) DeepCopy() *VMPlacementPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPlacementPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VMPlacementPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPlacementPolicySpec) DeepCopyInto(out *VMPlacementPolicySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPlacementPolicySpec.
+func (in *VMPlacementPolicySpec) DeepCopy() *VMPlacementPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPlacementPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Version) DeepCopyInto(out *Version) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Version.
+func (in *Version) DeepCopy() *Version {
+	if in == nil {
+		return nil
+	}
+	out := new(Version)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Version) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionList) DeepCopyInto(out *VersionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Version, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionList.
+func (in *VersionList) DeepCopy() *VersionList {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionList)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1212,18 +2413,316 @@ func (in *VersionSpec) DeepCopyInto(out *VersionSpec) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionSpec.
-func (in *VersionSpec) DeepCopy() *VersionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionSpec.
+func (in *VersionSpec) DeepCopy() *VersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackup) DeepCopyInto(out *VirtualMachineBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackup.
+func (in *VirtualMachineBackup) DeepCopy() *VirtualMachineBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualMachineBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupCopy) DeepCopyInto(out *VirtualMachineBackupCopy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupCopy.
+func (in *VirtualMachineBackupCopy) DeepCopy() *VirtualMachineBackupCopy {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupCopy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualMachineBackupCopy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupCopyList) DeepCopyInto(out *VirtualMachineBackupCopyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VirtualMachineBackupCopy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupCopyList.
+func (in *VirtualMachineBackupCopyList) DeepCopy() *VirtualMachineBackupCopyList {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupCopyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualMachineBackupCopyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupCopySpec) DeepCopyInto(out *VirtualMachineBackupCopySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupCopySpec.
+func (in *VirtualMachineBackupCopySpec) DeepCopy() *VirtualMachineBackupCopySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupCopySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupCopyStatus) DeepCopyInto(out *VirtualMachineBackupCopyStatus) {
+	*out = *in
+	if in.SourceBackupTarget != nil {
+		in, out := &in.SourceBackupTarget, &out.SourceBackupTarget
+		*out = new(BackupTargetLocation)
+		**out = **in
+	}
+	if in.BackupTarget != nil {
+		in, out := &in.BackupTarget, &out.BackupTarget
+		*out = new(BackupTargetLocation)
+		**out = **in
+	}
+	if in.VolumeBackupNames != nil {
+		in, out := &in.VolumeBackupNames, &out.VolumeBackupNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ReadyToUse != nil {
+		in, out := &in.ReadyToUse, &out.ReadyToUse
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		*out = new(Error)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupCopyStatus.
+func (in *VirtualMachineBackupCopyStatus) DeepCopy() *VirtualMachineBackupCopyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupCopyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupList) DeepCopyInto(out *VirtualMachineBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VirtualMachineBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupList.
+func (in *VirtualMachineBackupList) DeepCopy() *VirtualMachineBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VirtualMachineBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupSpec) DeepCopyInto(out *VirtualMachineBackupSpec) {
+	*out = *in
+	in.Source.DeepCopyInto(&out.Source)
+	if in.FsFreezeDeadline != nil {
+		in, out := &in.FsFreezeDeadline, &out.FsFreezeDeadline
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(BackupHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupSpec.
+func (in *VirtualMachineBackupSpec) DeepCopy() *VirtualMachineBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineBackupStatus) DeepCopyInto(out *VirtualMachineBackupStatus) {
+	*out = *in
+	if in.SourceUID != nil {
+		in, out := &in.SourceUID, &out.SourceUID
+		*out = new(types.UID)
+		**out = **in
+	}
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.BackupTarget != nil {
+		in, out := &in.BackupTarget, &out.BackupTarget
+		*out = new(BackupTargetLocation)
+		**out = **in
+	}
+	if in.CSIDriverVolumeSnapshotClassNames != nil {
+		in, out := &in.CSIDriverVolumeSnapshotClassNames, &out.CSIDriverVolumeSnapshotClassNames
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SourceSpec != nil {
+		in, out := &in.SourceSpec, &out.SourceSpec
+		*out = new(VirtualMachineSourceSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeBackups != nil {
+		in, out := &in.VolumeBackups, &out.VolumeBackups
+		*out = make([]VolumeBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SecretBackups != nil {
+		in, out := &in.SecretBackups, &out.SecretBackups
+		*out = make([]SecretBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HookResults != nil {
+		in, out := &in.HookResults, &out.HookResults
+		*out = make([]HookResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(BackupVerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadyToUse != nil {
+		in, out := &in.ReadyToUse, &out.ReadyToUse
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		*out = new(Error)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupStatus.
+func (in *VirtualMachineBackupStatus) DeepCopy() *VirtualMachineBackupStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VersionSpec)
+	out := new(VirtualMachineBackupStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VirtualMachineBackup) DeepCopyInto(out *VirtualMachineBackup) {
+func (in *VirtualMachineGroup) DeepCopyInto(out *VirtualMachineGroup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -1232,18 +2731,18 @@ func (in *VirtualMachineBackup) DeepCopyInto(out *VirtualMachineBackup) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackup.
-func (in *VirtualMachineBackup) DeepCopy() *VirtualMachineBackup {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroup.
+func (in *VirtualMachineGroup) DeepCopy() *VirtualMachineGroup {
 	if in == nil {
 		return nil
 	}
-	out := new(VirtualMachineBackup)
+	out := new(VirtualMachineGroup)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VirtualMachineBackup) DeepCopyObject() runtime.Object {
+func (in *VirtualMachineGroup) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1251,13 +2750,13 @@ func (in *VirtualMachineBackup) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VirtualMachineBackupList) DeepCopyInto(out *VirtualMachineBackupList) {
+func (in *VirtualMachineGroupList) DeepCopyInto(out *VirtualMachineGroupList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]VirtualMachineBackup, len(*in))
+		*out = make([]VirtualMachineGroup, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -1265,18 +2764,18 @@ func (in *VirtualMachineBackupList) DeepCopyInto(out *VirtualMachineBackupList)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupList.
-func (in *VirtualMachineBackupList) DeepCopy() *VirtualMachineBackupList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupList.
+func (in *VirtualMachineGroupList) DeepCopy() *VirtualMachineGroupList {
 	if in == nil {
 		return nil
 	}
-	out := new(VirtualMachineBackupList)
+	out := new(VirtualMachineGroupList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VirtualMachineBackupList) DeepCopyObject() runtime.Object {
+func (in *VirtualMachineGroupList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -1284,94 +2783,116 @@ func (in *VirtualMachineBackupList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VirtualMachineBackupSpec) DeepCopyInto(out *VirtualMachineBackupSpec) {
+func (in *VirtualMachineGroupMember) DeepCopyInto(out *VirtualMachineGroupMember) {
 	*out = *in
-	in.Source.DeepCopyInto(&out.Source)
-	if in.FsFreezeDeadline != nil {
-		in, out := &in.FsFreezeDeadline, &out.FsFreezeDeadline
+	if in.StartDelay != nil {
+		in, out := &in.StartDelay, &out.StartDelay
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.ReadinessGates != nil {
+		in, out := &in.ReadinessGates, &out.ReadinessGates
+		*out = make([]VirtualMachineGroupReadinessGate, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupSpec.
-func (in *VirtualMachineBackupSpec) DeepCopy() *VirtualMachineBackupSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupMember.
+func (in *VirtualMachineGroupMember) DeepCopy() *VirtualMachineGroupMember {
 	if in == nil {
 		return nil
 	}
-	out := new(VirtualMachineBackupSpec)
+	out := new(VirtualMachineGroupMember)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VirtualMachineBackupStatus) DeepCopyInto(out *VirtualMachineBackupStatus) {
+func (in *VirtualMachineGroupMemberStatus) DeepCopyInto(out *VirtualMachineGroupMemberStatus) {
 	*out = *in
-	if in.SourceUID != nil {
-		in, out := &in.SourceUID, &out.SourceUID
-		*out = new(types.UID)
-		**out = **in
-	}
-	if in.CreationTime != nil {
-		in, out := &in.CreationTime, &out.CreationTime
-		*out = (*in).DeepCopy()
-	}
-	if in.BackupTarget != nil {
-		in, out := &in.BackupTarget, &out.BackupTarget
-		*out = new(BackupTarget)
-		**out = **in
-	}
-	if in.CSIDriverVolumeSnapshotClassNames != nil {
-		in, out := &in.CSIDriverVolumeSnapshotClassNames, &out.CSIDriverVolumeSnapshotClassNames
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.SourceSpec != nil {
-		in, out := &in.SourceSpec, &out.SourceSpec
-		*out = new(VirtualMachineSourceSpec)
-		(*in).DeepCopyInto(*out)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupMemberStatus.
+func (in *VirtualMachineGroupMemberStatus) DeepCopy() *VirtualMachineGroupMemberStatus {
+	if in == nil {
+		return nil
 	}
-	if in.VolumeBackups != nil {
-		in, out := &in.VolumeBackups, &out.VolumeBackups
-		*out = make([]VolumeBackup, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	out := new(VirtualMachineGroupMemberStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineGroupReadinessGate) DeepCopyInto(out *VirtualMachineGroupReadinessGate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupReadinessGate.
+func (in *VirtualMachineGroupReadinessGate) DeepCopy() *VirtualMachineGroupReadinessGate {
+	if in == nil {
+		return nil
 	}
-	if in.SecretBackups != nil {
-		in, out := &in.SecretBackups, &out.SecretBackups
-		*out = make([]SecretBackup, len(*in))
+	out := new(VirtualMachineGroupReadinessGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineGroupSpec) DeepCopyInto(out *VirtualMachineGroupSpec) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]VirtualMachineGroupMember, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.ReadyToUse != nil {
-		in, out := &in.ReadyToUse, &out.ReadyToUse
-		*out = new(bool)
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
 		**out = **in
 	}
-	if in.Error != nil {
-		in, out := &in.Error, &out.Error
-		*out = new(Error)
-		(*in).DeepCopyInto(*out)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupSpec.
+func (in *VirtualMachineGroupSpec) DeepCopy() *VirtualMachineGroupSpec {
+	if in == nil {
+		return nil
 	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
+	out := new(VirtualMachineGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineGroupStatus) DeepCopyInto(out *VirtualMachineGroupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]VirtualMachineGroupMemberStatus, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineBackupStatus.
-func (in *VirtualMachineBackupStatus) DeepCopy() *VirtualMachineBackupStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineGroupStatus.
+func (in *VirtualMachineGroupStatus) DeepCopy() *VirtualMachineGroupStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VirtualMachineBackupStatus)
+	out := new(VirtualMachineGroupStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -1404,12 +2925,28 @@ func (in *VirtualMachineImage) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageConsumer) DeepCopyInto(out *VirtualMachineImageConsumer) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageConsumer.
+func (in *VirtualMachineImageConsumer) DeepCopy() *VirtualMachineImageConsumer {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageConsumer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineImageDownloader) DeepCopyInto(out *VirtualMachineImageDownloader) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -1485,6 +3022,11 @@ func (in *VirtualMachineImageDownloaderList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineImageDownloaderSpec) DeepCopyInto(out *VirtualMachineImageDownloaderSpec) {
 	*out = *in
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(VirtualMachineImageExportTarget)
+		**out = **in
+	}
 	return
 }
 
@@ -1508,6 +3050,11 @@ func (in *VirtualMachineImageDownloaderStatus) DeepCopyInto(out *VirtualMachineI
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Export != nil {
+		in, out := &in.Export, &out.Export
+		*out = new(VirtualMachineImageExportStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1521,6 +3068,58 @@ func (in *VirtualMachineImageDownloaderStatus) DeepCopy() *VirtualMachineImageDo
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageEncryptionStatus) DeepCopyInto(out *VirtualMachineImageEncryptionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageEncryptionStatus.
+func (in *VirtualMachineImageEncryptionStatus) DeepCopy() *VirtualMachineImageEncryptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageEncryptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageExportStatus) DeepCopyInto(out *VirtualMachineImageExportStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageExportStatus.
+func (in *VirtualMachineImageExportStatus) DeepCopy() *VirtualMachineImageExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageExportTarget) DeepCopyInto(out *VirtualMachineImageExportTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageExportTarget.
+func (in *VirtualMachineImageExportTarget) DeepCopy() *VirtualMachineImageExportTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageExportTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineImageList) DeepCopyInto(out *VirtualMachineImageList) {
 	*out = *in
@@ -1554,6 +3153,22 @@ func (in *VirtualMachineImageList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageRemoteSource) DeepCopyInto(out *VirtualMachineImageRemoteSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageRemoteSource.
+func (in *VirtualMachineImageRemoteSource) DeepCopy() *VirtualMachineImageRemoteSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageRemoteSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineImageSecurityParameters) DeepCopyInto(out *VirtualMachineImageSecurityParameters) {
 	*out = *in
@@ -1570,9 +3185,35 @@ func (in *VirtualMachineImageSecurityParameters) DeepCopy() *VirtualMachineImage
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineImageSignature) DeepCopyInto(out *VirtualMachineImageSignature) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineImageSignature.
+func (in *VirtualMachineImageSignature) DeepCopy() *VirtualMachineImageSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineImageSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineImageSpec) DeepCopyInto(out *VirtualMachineImageSpec) {
 	*out = *in
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(VirtualMachineImageSignature)
+		**out = **in
+	}
+	if in.Remote != nil {
+		in, out := &in.Remote, &out.Remote
+		*out = new(VirtualMachineImageRemoteSource)
+		**out = **in
+	}
 	if in.StorageClassParameters != nil {
 		in, out := &in.StorageClassParameters, &out.StorageClassParameters
 		*out = make(map[string]string, len(*in))
@@ -1603,7 +3244,7 @@ func (in *VirtualMachineImageStatus) DeepCopyInto(out *VirtualMachineImageStatus
 	*out = *in
 	if in.BackupTarget != nil {
 		in, out := &in.BackupTarget, &out.BackupTarget
-		*out = new(BackupTarget)
+		*out = new(BackupTargetLocation)
 		**out = **in
 	}
 	if in.Conditions != nil {
@@ -1611,6 +3252,20 @@ func (in *VirtualMachineImageStatus) DeepCopyInto(out *VirtualMachineImageStatus
 		*out = make([]Condition, len(*in))
 		copy(*out, *in)
 	}
+	if in.Consumers != nil {
+		in, out := &in.Consumers, &out.Consumers
+		*out = make([]VirtualMachineImageConsumer, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastUsedTime != nil {
+		in, out := &in.LastUsedTime, &out.LastUsedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Encryption != nil {
+		in, out := &in.Encryption, &out.Encryption
+		*out = new(VirtualMachineImageEncryptionStatus)
+		**out = **in
+	}
 	return
 }
 
@@ -1689,6 +3344,11 @@ func (in *VirtualMachineRestoreList) DeepCopyObject() runtime.Object {
 func (in *VirtualMachineRestoreSpec) DeepCopyInto(out *VirtualMachineRestoreSpec) {
 	*out = *in
 	in.Target.DeepCopyInto(&out.Target)
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeRestoreSelection, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1828,6 +3488,27 @@ func (in *VirtualMachineTemplateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualMachineTemplateParameter) DeepCopyInto(out *VirtualMachineTemplateParameter) {
+	*out = *in
+	if in.Enum != nil {
+		in, out := &in.Enum, &out.Enum
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualMachineTemplateParameter.
+func (in *VirtualMachineTemplateParameter) DeepCopy() *VirtualMachineTemplateParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualMachineTemplateParameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VirtualMachineTemplateSpec) DeepCopyInto(out *VirtualMachineTemplateSpec) {
 	*out = *in
@@ -1930,6 +3611,13 @@ func (in *VirtualMachineTemplateVersionSpec) DeepCopyInto(out *VirtualMachineTem
 		copy(*out, *in)
 	}
 	in.VM.DeepCopyInto(&out.VM)
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]VirtualMachineTemplateParameter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -2064,6 +3752,22 @@ func (in *VolumeRemoteBackup) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeRemoteBackupInfo) DeepCopyInto(out *VolumeRemoteBackupInfo) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeRemoteBackupInfo.
+func (in *VolumeRemoteBackupInfo) DeepCopy() *VolumeRemoteBackupInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeRemoteBackupInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeRemoteBackupList) DeepCopyInto(out *VolumeRemoteBackupList) {
 	*out = *in
@@ -2140,7 +3844,7 @@ func (in *VolumeRemoteRestore) DeepCopyInto(out *VolumeRemoteRestore) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -2199,6 +3903,11 @@ func (in *VolumeRemoteRestoreList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeRemoteRestoreSpec) DeepCopyInto(out *VolumeRemoteRestoreSpec) {
 	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	return
 }
 
@@ -2254,3 +3963,19 @@ func (in *VolumeRestore) DeepCopy() *VolumeRestore {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeRestoreSelection) DeepCopyInto(out *VolumeRestoreSelection) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeRestoreSelection.
+func (in *VolumeRestoreSelection) DeepCopy() *VolumeRestoreSelection {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeRestoreSelection)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -315,6 +315,23 @@ func NewVolumeRemoteRestore(namespace, name string, obj VolumeRemoteRestore) *Vo
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// ScheduleVolumeRemoteBackupList is a list of ScheduleVolumeRemoteBackup resources
+type ScheduleVolumeRemoteBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ScheduleVolumeRemoteBackup `json:"items"`
+}
+
+func NewScheduleVolumeRemoteBackup(namespace, name string, obj ScheduleVolumeRemoteBackup) *ScheduleVolumeRemoteBackup {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ScheduleVolumeRemoteBackup").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // VirtualMachineImageDownloaderList is a list of VirtualMachineImageDownloader resources
 type VirtualMachineImageDownloaderList struct {
 	metav1.TypeMeta `json:",inline"`
@@ -329,3 +346,156 @@ func NewVirtualMachineImageDownloader(namespace, name string, obj VirtualMachine
 	obj.Namespace = namespace
 	return &obj
 }
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BackupTargetList is a list of BackupTarget resources
+type BackupTargetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []BackupTarget `json:"items"`
+}
+
+func NewBackupTarget(namespace, name string, obj BackupTarget) *BackupTarget {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("BackupTarget").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VirtualMachineBackupCopyList is a list of VirtualMachineBackupCopy resources
+type VirtualMachineBackupCopyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []VirtualMachineBackupCopy `json:"items"`
+}
+
+func NewVirtualMachineBackupCopy(namespace, name string, obj VirtualMachineBackupCopy) *VirtualMachineBackupCopy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("VirtualMachineBackupCopy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BackupVerificationList is a list of BackupVerification resources
+type BackupVerificationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []BackupVerification `json:"items"`
+}
+
+func NewBackupVerification(namespace, name string, obj BackupVerification) *BackupVerification {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("BackupVerification").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BackupBrowseSessionList is a list of BackupBrowseSession resources
+type BackupBrowseSessionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []BackupBrowseSession `json:"items"`
+}
+
+func NewBackupBrowseSession(namespace, name string, obj BackupBrowseSession) *BackupBrowseSession {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("BackupBrowseSession").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ImageSyncPolicyList is a list of ImageSyncPolicy resources
+type ImageSyncPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ImageSyncPolicy `json:"items"`
+}
+
+func NewImageSyncPolicy(namespace, name string, obj ImageSyncPolicy) *ImageSyncPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ImageSyncPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RebalancePolicyList is a list of RebalancePolicy resources
+type RebalancePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []RebalancePolicy `json:"items"`
+}
+
+func NewRebalancePolicy(namespace, name string, obj RebalancePolicy) *RebalancePolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("RebalancePolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VirtualMachineGroupList is a list of VirtualMachineGroup resources
+type VirtualMachineGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []VirtualMachineGroup `json:"items"`
+}
+
+func NewVirtualMachineGroup(namespace, name string, obj VirtualMachineGroup) *VirtualMachineGroup {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("VirtualMachineGroup").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VMPlacementPolicyList is a list of VMPlacementPolicy resources
+type VMPlacementPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []VMPlacementPolicy `json:"items"`
+}
+
+func NewVMPlacementPolicy(namespace, name string, obj VMPlacementPolicy) *VMPlacementPolicy {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("VMPlacementPolicy").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeMaintenanceCampaignList is a list of NodeMaintenanceCampaign resources
+type NodeMaintenanceCampaignList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []NodeMaintenanceCampaign `json:"items"`
+}
+
+func NewNodeMaintenanceCampaign(namespace, name string, obj NodeMaintenanceCampaign) *NodeMaintenanceCampaign {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("NodeMaintenanceCampaign").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
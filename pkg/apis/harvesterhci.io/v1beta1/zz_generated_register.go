@@ -29,16 +29,26 @@ import (
 
 var (
 	AddonResourceName                         = "addons"
+	BackupBrowseSessionResourceName           = "backupbrowsesessions"
+	BackupTargetResourceName                  = "backuptargets"
+	BackupVerificationResourceName            = "backupverifications"
+	ImageSyncPolicyResourceName               = "imagesyncpolicies"
 	KeyPairResourceName                       = "keypairs"
+	NodeMaintenanceCampaignResourceName       = "nodemaintenancecampaigns"
 	PreferenceResourceName                    = "preferences"
+	RebalancePolicyResourceName               = "rebalancepolicies"
 	ResourceQuotaResourceName                 = "resourcequotas"
 	ScheduleVMBackupResourceName              = "schedulevmbackups"
+	ScheduleVolumeRemoteBackupResourceName    = "schedulevolumeremotebackups"
 	SettingResourceName                       = "settings"
 	SupportBundleResourceName                 = "supportbundles"
 	UpgradeResourceName                       = "upgrades"
 	UpgradeLogResourceName                    = "upgradelogs"
+	VMPlacementPolicyResourceName             = "vmplacementpolicies"
 	VersionResourceName                       = "versions"
 	VirtualMachineBackupResourceName          = "virtualmachinebackups"
+	VirtualMachineBackupCopyResourceName      = "virtualmachinebackupcopies"
+	VirtualMachineGroupResourceName           = "virtualmachinegroups"
 	VirtualMachineImageResourceName           = "virtualmachineimages"
 	VirtualMachineImageDownloaderResourceName = "virtualmachineimagedownloaders"
 	VirtualMachineRestoreResourceName         = "virtualmachinerestores"
@@ -71,14 +81,28 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Addon{},
 		&AddonList{},
+		&BackupBrowseSession{},
+		&BackupBrowseSessionList{},
+		&BackupTarget{},
+		&BackupTargetList{},
+		&BackupVerification{},
+		&BackupVerificationList{},
+		&ImageSyncPolicy{},
+		&ImageSyncPolicyList{},
 		&KeyPair{},
 		&KeyPairList{},
+		&NodeMaintenanceCampaign{},
+		&NodeMaintenanceCampaignList{},
 		&Preference{},
 		&PreferenceList{},
+		&RebalancePolicy{},
+		&RebalancePolicyList{},
 		&ResourceQuota{},
 		&ResourceQuotaList{},
 		&ScheduleVMBackup{},
 		&ScheduleVMBackupList{},
+		&ScheduleVolumeRemoteBackup{},
+		&ScheduleVolumeRemoteBackupList{},
 		&Setting{},
 		&SettingList{},
 		&SupportBundle{},
@@ -87,10 +111,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&UpgradeList{},
 		&UpgradeLog{},
 		&UpgradeLogList{},
+		&VMPlacementPolicy{},
+		&VMPlacementPolicyList{},
 		&Version{},
 		&VersionList{},
 		&VirtualMachineBackup{},
 		&VirtualMachineBackupList{},
+		&VirtualMachineBackupCopy{},
+		&VirtualMachineBackupCopyList{},
+		&VirtualMachineGroup{},
+		&VirtualMachineGroupList{},
 		&VirtualMachineImage{},
 		&VirtualMachineImageList{},
 		&VirtualMachineImageDownloader{},
@@ -26,6 +26,7 @@ import (
 	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
 	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
 )
 
 const (
@@ -67,6 +68,7 @@ type VMBackupOperator interface {
 	SetVolBackupCreationTime(vb *harvesterv1.VolumeBackup, t *metav1.Time) error
 	SetVolBackupError(vb *harvesterv1.VolumeBackup, err *snapshotv1.VolumeSnapshotError) error
 	SetVolBackupProgress(vb *harvesterv1.VolumeBackup, p int) error
+	SetVolBackupTransferredBytes(vb *harvesterv1.VolumeBackup, n int64) error
 	SetVolBackupLHBackupName(vb *harvesterv1.VolumeBackup, name string) error
 
 	// VMBackup operations that need K8s deps and so can't live on the
@@ -102,6 +104,9 @@ type VMBackupOperator interface {
 	// Initialization Methods
 	InitVMBackup(old *harvesterv1.VirtualMachineBackup, vm *kubevirtv1.VirtualMachine) error
 
+	// GetCurrentBackupTarget returns the backup target the VMBackup is stored in, as it's configured now.
+	GetCurrentBackupTarget(vmb *harvesterv1.VirtualMachineBackup) (*settings.BackupTarget, error)
+
 	// Filesystem Freeze Support
 	TryFreezeFS(ctx context.Context, vmb *harvesterv1.VirtualMachineBackup) error
 }
@@ -136,6 +141,7 @@ type VMBackupReader interface {
 	GetVolBackupReadyToUse(vb *harvesterv1.VolumeBackup) bool
 	GetVolBackupSize(vb *harvesterv1.VolumeBackup) int64
 	GetVolBackupProgress(vb *harvesterv1.VolumeBackup) int
+	GetVolBackupTransferredBytes(vb *harvesterv1.VolumeBackup) int64
 	GetVolBackupLHBackupName(vb *harvesterv1.VolumeBackup) *string
 	GetVolBackupSCName(vb *harvesterv1.VolumeBackup) *string
 	GetVolBackupError(vb *harvesterv1.VolumeBackup) *harvesterv1.Error
@@ -157,8 +163,9 @@ type VMBackupReader interface {
 	GetAnnotations(vmb *harvesterv1.VirtualMachineBackup) map[string]string
 	GetCSIDriverVSCNames(vmb *harvesterv1.VirtualMachineBackup) map[string]string
 	GetType(vmb *harvesterv1.VirtualMachineBackup) harvesterv1.BackupType
-	GetBackupTarget(vmb *harvesterv1.VirtualMachineBackup) *harvesterv1.BackupTarget
+	GetBackupTarget(vmb *harvesterv1.VirtualMachineBackup) *harvesterv1.BackupTargetLocation
 	GetFsFreezeDeadline(vmb *harvesterv1.VirtualMachineBackup) *metav1.Duration
+	GetBackupTargetName(vmb *harvesterv1.VirtualMachineBackup) string
 }
 
 type vmbackupReader struct{}
@@ -194,7 +201,7 @@ func (c *vmbackupReader) IsTargetConsistent(vmb *harvesterv1.VirtualMachineBacku
 	if bt == nil || target == nil {
 		return false
 	}
-	return bt.Endpoint == target.Endpoint && bt.BucketName == target.BucketName && bt.BucketRegion == target.BucketRegion
+	return bt.Name == target.Name && bt.Endpoint == target.Endpoint && bt.BucketName == target.BucketName && bt.BucketRegion == target.BucketRegion
 }
 
 func (c *vmbackupReader) IsTransitToNonReady(vmb *harvesterv1.VirtualMachineBackup) bool {
@@ -310,6 +317,13 @@ func (a *vmbackupReader) GetVolBackupProgress(vb *harvesterv1.VolumeBackup) int
 	return vb.Progress
 }
 
+func (a *vmbackupReader) GetVolBackupTransferredBytes(vb *harvesterv1.VolumeBackup) int64 {
+	if vb == nil {
+		return 0
+	}
+	return vb.TransferredBytes
+}
+
 func (a *vmbackupReader) GetVolBackupLHBackupName(vb *harvesterv1.VolumeBackup) *string {
 	if vb == nil {
 		return nil
@@ -406,7 +420,7 @@ func (a *vmbackupReader) GetType(vmb *harvesterv1.VirtualMachineBackup) harveste
 	return vmb.Spec.Type
 }
 
-func (a *vmbackupReader) GetBackupTarget(vmb *harvesterv1.VirtualMachineBackup) *harvesterv1.BackupTarget {
+func (a *vmbackupReader) GetBackupTarget(vmb *harvesterv1.VirtualMachineBackup) *harvesterv1.BackupTargetLocation {
 	return vmb.Status.BackupTarget
 }
 
@@ -417,6 +431,10 @@ func (a *vmbackupReader) GetFsFreezeDeadline(vmb *harvesterv1.VirtualMachineBack
 	return vmb.Spec.FsFreezeDeadline
 }
 
+func (a *vmbackupReader) GetBackupTargetName(vmb *harvesterv1.VirtualMachineBackup) string {
+	return vmb.Spec.BackupTargetName
+}
+
 type vmbackupOperator struct {
 	// Embedded so existing callers (which depend on the full VMBackupOperator
 	// interface) get the Is*/Get* methods for free — no behaviour change.
@@ -430,6 +448,7 @@ type vmbackupOperator struct {
 	pvcCache     ctlcorev1.PersistentVolumeClaimCache
 	pvCache      ctlcorev1.PersistentVolumeCache
 	secretCache  ctlcorev1.SecretCache
+	btCache      ctlharvesterv1.BackupTargetCache
 
 	virtSubresourceRestClient rest.Interface
 }
@@ -489,6 +508,11 @@ func (b *VMBackupOperatorBuilder) WithSecretCache(c ctlcorev1.SecretCache) *VMBa
 	return b
 }
 
+func (b *VMBackupOperatorBuilder) WithBackupTargetCache(c ctlharvesterv1.BackupTargetCache) *VMBackupOperatorBuilder {
+	b.op.btCache = c
+	return b
+}
+
 func (b *VMBackupOperatorBuilder) WithVirtSubresourceRestClient(c rest.Interface) *VMBackupOperatorBuilder {
 	b.op.virtSubresourceRestClient = c
 	return b
@@ -558,6 +582,14 @@ func (vmbo *vmbackupOperator) SetVolBackupProgress(vb *harvesterv1.VolumeBackup,
 	return nil
 }
 
+func (vmbo *vmbackupOperator) SetVolBackupTransferredBytes(vb *harvesterv1.VolumeBackup, n int64) error {
+	if vb == nil {
+		return errors.New(errVolumeBackupNil)
+	}
+	vb.TransferredBytes = n
+	return nil
+}
+
 func (vmbo *vmbackupOperator) SetVolBackupLHBackupName(vb *harvesterv1.VolumeBackup, name string) error {
 	if vb == nil {
 		return errors.New(errVolumeBackupNil)
@@ -710,10 +742,11 @@ func getVSCName(backupType harvesterv1.BackupType, driverInfo settings.CSIDriver
 	var vscName string
 
 	switch {
+	case backupType == harvesterv1.Snapshot, backupType == harvesterv1.SnapshotExport:
+		// SnapshotExport moves the data by itself, it only needs an in-cluster snapshot.
+		vscName = driverInfo.VolumeSnapshotClassName
 	case backupType.UsesRemoteBackupTarget():
 		vscName = driverInfo.BackupVolumeSnapshotClassName
-	case backupType == harvesterv1.Snapshot:
-		vscName = driverInfo.VolumeSnapshotClassName
 	default:
 		return "", fmt.Errorf("unsupported backup type %q for CSI driver %q", backupType, csiDriverName)
 	}
@@ -792,7 +825,7 @@ func (vmbo *vmbackupOperator) ConfigureBackupTargetOnStatus(old *harvesterv1.Vir
 	}
 
 	newVMb := old.DeepCopy()
-	newVMb.Status.BackupTarget = &harvesterv1.BackupTarget{
+	newVMb.Status.BackupTarget = &harvesterv1.BackupTargetLocation{
 		Endpoint:     newVMb.Annotations[backupTargetAnnotation],
 		BucketName:   newVMb.Annotations[backupBucketNameAnnotation],
 		BucketRegion: newVMb.Annotations[backupBucketRegionAnnotation],
@@ -1163,19 +1196,19 @@ func (vmbo *vmbackupOperator) initVSCNames(vmb *harvesterv1.VirtualMachineBackup
 }
 
 func (vmbo *vmbackupOperator) initBackupTarget(vmb *harvesterv1.VirtualMachineBackup) (*harvesterv1.VirtualMachineBackup, error) {
-	target, err := settings.DecodeBackupTarget(settings.BackupTargetSet.Get())
+	target, err := vmbo.GetCurrentBackupTarget(vmb)
 	if err != nil {
 		return nil, err
 	}
 
-	vmb.Status.BackupTarget = &harvesterv1.BackupTarget{
-		Endpoint:     target.Endpoint,
-		BucketName:   target.BucketName,
-		BucketRegion: target.BucketRegion,
-	}
+	vmb.Status.BackupTarget = backuputil.GetBackupTargetLocation(target)
 	return vmb, nil
 }
 
+func (vmbo *vmbackupOperator) GetCurrentBackupTarget(vmb *harvesterv1.VirtualMachineBackup) (*settings.BackupTarget, error) {
+	return backuputil.GetBackupTarget(vmbo.btCache, vmbo.GetBackupTargetName(vmb))
+}
+
 func (vmbo *vmbackupOperator) InitVMBackup(old *harvesterv1.VirtualMachineBackup, vm *kubevirtv1.VirtualMachine) error {
 	newVMb := old.DeepCopy()
 	newVMb = vmbo.initStatus(newVMb, vm)
@@ -0,0 +1,300 @@
+package datamover
+
+// The browse command serves the files of a restored volume for the backup
+// browse sessions. It mounts every filesystem it knows of read-only, through
+// loop devices at the offset of their partition, and serves:
+//
+//	GET /partitions                          the partitions and their filesystem
+//	GET /files?partition=<name>&path=<path>  a folder listing, or the file content
+//	GET /healthz                             whether the server is up
+//
+// Every request but the health check has to carry the session token as
+// `Authorization: Bearer <token>`.
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	BrowsePartitionsPath = "/partitions"
+	BrowseFilesPath      = "/files"
+	BrowseHealthPath     = "/healthz"
+
+	BrowseQueryPartition = "partition"
+	BrowseQueryPath      = "path"
+
+	browseShutdownTimeout = 10 * time.Second
+)
+
+// mountOptions are the options a filesystem is mounted with on top of
+// read-only, so that mounting never replays a journal.
+var mountOptions = map[string]string{
+	"ext2":    "",
+	"ext3":    "noload",
+	"ext4":    "noload",
+	"xfs":     "norecovery",
+	"btrfs":   "",
+	"vfat":    "",
+	"ntfs":    "",
+	"iso9660": "",
+}
+
+// FileInfo is an entry of a folder listing.
+type FileInfo struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    fs.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	IsDir   bool        `json:"isDir"`
+}
+
+type browseServer struct {
+	partitions []Partition
+	// roots of the mounted partitions, by name
+	roots map[string]*os.Root
+}
+
+// Browse mounts the filesystems of the volume under mountPath and serves
+// them on the port to the clients presenting the token until the context is done.
+func Browse(ctx context.Context, volumePath, mountPath string, port int, token string) error {
+	if token == "" {
+		return fmt.Errorf("the browse token is required")
+	}
+	partitions, err := detectVolumePartitions(volumePath)
+	if err != nil {
+		return err
+	}
+
+	s := &browseServer{partitions: partitions, roots: map[string]*os.Root{}}
+	defer s.unmountAll(mountPath)
+	for i := range s.partitions {
+		p := &s.partitions[i]
+		if err := s.mount(volumePath, mountPath, p); err != nil {
+			p.Error = err.Error()
+			logrus.WithError(err).WithField("partition", p.Name).Warn("failed to mount partition")
+		}
+	}
+
+	server := &http.Server{
+		Addr:              net.JoinHostPort("", strconv.Itoa(port)),
+		Handler:           s.handler(token),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+	logrus.Infof("serving the files of %s on port %d", volumePath, port)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), browseShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+func (s *browseServer) handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(BrowsePartitionsPath, s.servePartitions)
+	mux.HandleFunc(BrowseFilesPath, s.serveFiles)
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == BrowseHealthPath {
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+		if !validBrowseToken(req, token) {
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(rw, req)
+	})
+}
+
+func validBrowseToken(req *http.Request, token string) bool {
+	presented, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
+
+func detectVolumePartitions(volumePath string) ([]Partition, error) {
+	f, err := os.Open(volumePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Stat doesn't return the size of block devices
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := DetectPartitions(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the partition table of %s: %w", volumePath, err)
+	}
+	return partitions, nil
+}
+
+// mount mounts the filesystem of the partition read-only on a loop device.
+func (s *browseServer) mount(volumePath, mountPath string, p *Partition) error {
+	extra, ok := mountOptions[p.Filesystem]
+	if !ok {
+		return nil
+	}
+	if p.Error != "" {
+		return errors.New(p.Error)
+	}
+
+	target := filepath.Join(mountPath, p.Name)
+	if err := os.MkdirAll(target, 0o700); err != nil {
+		return err
+	}
+	options := fmt.Sprintf("ro,loop,offset=%d,sizelimit=%d", p.Offset, p.Size)
+	if extra != "" {
+		options += "," + extra
+	}
+	out, err := exec.Command("mount", "-t", p.Filesystem, "-o", options, volumePath, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to mount %s filesystem: %s", p.Filesystem, out)
+	}
+	p.Mounted = true
+
+	root, err := os.OpenRoot(target)
+	if err != nil {
+		return err
+	}
+	s.roots[p.Name] = root
+	return nil
+}
+
+func (s *browseServer) unmountAll(mountPath string) {
+	for _, p := range s.partitions {
+		if !p.Mounted {
+			continue
+		}
+		if root := s.roots[p.Name]; root != nil {
+			_ = root.Close()
+		}
+		target := filepath.Join(mountPath, p.Name)
+		if out, err := exec.Command("umount", target).CombinedOutput(); err != nil {
+			logrus.Warnf("failed to unmount %s: %s", target, out)
+		}
+	}
+}
+
+func (s *browseServer) servePartitions(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSONResponse(rw, s.partitions)
+}
+
+// serveFiles lists a folder or streams a file of a mounted partition. The
+// path is resolved within the root of the partition, so neither `..` nor
+// symbolic links can escape it.
+func (s *browseServer) serveFiles(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	root, ok := s.roots[query.Get(BrowseQueryPartition)]
+	if !ok {
+		http.Error(rw, fmt.Sprintf("partition %q isn't mounted", query.Get(BrowseQueryPartition)), http.StatusBadRequest)
+		return
+	}
+
+	name := cleanBrowsePath(query.Get(BrowseQueryPath))
+	f, err := root.Open(name)
+	if err != nil {
+		writeFileError(rw, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeFileError(rw, err)
+		return
+	}
+
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			http.Error(rw, fmt.Sprintf("%s isn't a regular file", name), http.StatusBadRequest)
+			return
+		}
+		rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", info.Name()))
+		rw.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(rw, req, info.Name(), info.ModTime(), f)
+		return
+	}
+
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		writeFileError(rw, err)
+		return
+	}
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, FileInfo{
+			Name:    entry.Name(),
+			Size:    info.Size(),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+			IsDir:   entry.IsDir(),
+		})
+	}
+	writeJSONResponse(rw, files)
+}
+
+// cleanBrowsePath turns a path of the partition into a path relative to its root.
+func cleanBrowsePath(p string) string {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return "."
+	}
+	return p[1:]
+}
+
+func writeFileError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+	case errors.Is(err, fs.ErrPermission):
+		http.Error(rw, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeJSONResponse(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		logrus.WithError(err).Warn("failed to write response")
+	}
+}
@@ -0,0 +1,164 @@
+package datamover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowseHandlerAuthentication(t *testing.T) {
+	const token = "token"
+	handler := (&browseServer{}).handler(token)
+
+	var testCases = []struct {
+		name           string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{
+			name:           "health check needs no token",
+			path:           BrowseHealthPath,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			path:           BrowsePartitionsPath,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "wrong token",
+			path:           BrowseFilesPath,
+			authorization:  "Bearer wrong",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "token without bearer scheme",
+			path:           BrowsePartitionsPath,
+			authorization:  token,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "valid token",
+			path:           BrowsePartitionsPath,
+			authorization:  "Bearer " + token,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, req)
+			assert.Equal(t, tc.expectedStatus, rw.Code)
+		})
+	}
+}
+
+// TestBrowseMountsPartitions mounts a partition of a disk image the way the
+// helper pod does, so it needs root and loop devices.
+func TestBrowseMountsPartitions(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("mounting needs root")
+	}
+	if _, err := os.Stat("/dev/loop-control"); err != nil {
+		t.Skip("no loop devices")
+	}
+	mkfs, err := exec.LookPath("mkfs.ext4")
+	if err != nil {
+		t.Skip("mkfs.ext4 not found")
+	}
+
+	const (
+		token           = "token"
+		partitionOffset = 1 << 20
+		partitionSize   = 8 << 20
+	)
+	dir := t.TempDir()
+	content := filepath.Join(dir, "content")
+	require.NoError(t, os.MkdirAll(filepath.Join(content, "etc"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(content, "etc", "hostname"), []byte("vm\n"), 0o644))
+
+	// an MBR disk with an ext4 partition at 1MiB
+	fsImage := filepath.Join(dir, "fs.img")
+	require.NoError(t, os.WriteFile(fsImage, nil, 0o600))
+	require.NoError(t, os.Truncate(fsImage, partitionSize))
+	out, err := exec.Command(mkfs, "-q", "-d", content, fsImage).CombinedOutput()
+	require.NoError(t, err, string(out))
+	fsData, err := os.ReadFile(fsImage)
+	require.NoError(t, err)
+	disk := make([]byte, partitionOffset+partitionSize)
+	writeMBREntry(disk, 0, 0, 0x83, partitionOffset/sectorSize, partitionSize/sectorSize)
+	copy(disk[partitionOffset:], fsData)
+	diskPath := filepath.Join(dir, "disk.img")
+	require.NoError(t, os.WriteFile(diskPath, disk, 0o600))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	mountPath := filepath.Join(dir, "mnt")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Browse(ctx, diskPath, mountPath, port, token)
+	}()
+
+	baseURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	get := func(path string, query url.Values) []byte {
+		req, err := http.NewRequest(http.MethodGet, baseURL+path+"?"+query.Encode(), nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+		return body
+	}
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(baseURL + BrowseHealthPath)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 10*time.Second, 50*time.Millisecond)
+
+	var partitions []Partition
+	require.NoError(t, json.Unmarshal(get(BrowsePartitionsPath, nil), &partitions))
+	require.Len(t, partitions, 1)
+	assert.Equal(t, Partition{Name: "p1", Number: 1, Offset: partitionOffset, Size: partitionSize, Filesystem: "ext4", Mounted: true}, partitions[0])
+
+	var files []FileInfo
+	require.NoError(t, json.Unmarshal(get(BrowseFilesPath, url.Values{BrowseQueryPartition: {"p1"}, BrowseQueryPath: {"/etc"}}), &files))
+	require.Len(t, files, 1)
+	assert.Equal(t, "hostname", files[0].Name)
+	assert.Equal(t, []byte("vm\n"), get(BrowseFilesPath, url.Values{BrowseQueryPartition: {"p1"}, BrowseQueryPath: {"/etc/hostname"}}))
+
+	cancel()
+	require.NoError(t, <-errCh)
+	entries, err := os.ReadDir(filepath.Join(mountPath, "p1"))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the partition must be unmounted")
+}
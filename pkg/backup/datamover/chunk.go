@@ -0,0 +1,96 @@
+package datamover
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	chunkKeyInfo   = "harvester volume chunks"
+	chunkIDKeyInfo = "harvester volume chunk ids"
+)
+
+// chunkCodec turns blocks into the chunks of the pool. Without an encryption
+// key, a chunk is the block itself, addressed by its SHA-256 checksum. With
+// one, the chunk is the block encrypted with AES-256-GCM, addressed by its
+// HMAC-SHA256, so the pool reveals neither the content of the blocks nor
+// which well-known blocks it holds. The checksum is authenticated along with
+// the block, so a chunk can't be swapped for another one.
+type chunkCodec struct {
+	idKey []byte
+	key   []byte
+}
+
+func newChunkCodec(key []byte) (*chunkCodec, error) {
+	if key == nil {
+		return &chunkCodec{}, nil
+	}
+	chunkKey, err := backuputil.DeriveKey(key, chunkKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	idKey, err := backuputil.DeriveKey(key, chunkIDKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkCodec{idKey: idKey, key: chunkKey}, nil
+}
+
+// sameKey reports whether the chunks of both codecs are the same.
+func (c *chunkCodec) sameKey(other *chunkCodec) bool {
+	return bytes.Equal(c.idKey, other.idKey)
+}
+
+func (c *chunkCodec) checksum(data []byte) string {
+	if c.idKey == nil {
+		return checksum(data)
+	}
+	mac := hmac.New(sha256.New, c.idKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *chunkCodec) seal(sum string, data []byte) ([]byte, error) {
+	if c.key == nil {
+		return data, nil
+	}
+	gcm, err := backuputil.NewGCM(c.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(data)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, []byte(sum)), nil
+}
+
+// open returns the data of the chunk of the block, and fails if the chunk
+// isn't the one the block refers to.
+func (c *chunkCodec) open(block Block, chunk []byte) ([]byte, error) {
+	data := chunk
+	if c.key != nil {
+		gcm, err := backuputil.NewGCM(c.key)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) < gcm.NonceSize() {
+			return nil, fmt.Errorf("block at offset %d is corrupted", block.Offset)
+		}
+		nonce, sealed := chunk[:gcm.NonceSize()], chunk[gcm.NonceSize():]
+		if data, err = gcm.Open(nil, nonce, sealed, []byte(block.Checksum)); err != nil {
+			return nil, fmt.Errorf("block at offset %d is corrupted or encrypted with another key", block.Offset)
+		}
+	}
+	if int64(len(data)) != block.Length || c.checksum(data) != block.Checksum {
+		return nil, fmt.Errorf("block at offset %d is corrupted", block.Offset)
+	}
+	return data, nil
+}
@@ -0,0 +1,333 @@
+package datamover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/longhorn/backupstore"
+	lhutil "github.com/longhorn/backupstore/util"
+	"github.com/sirupsen/logrus"
+)
+
+// LonghornBackup refers to a backup of a Longhorn volume in the backup target.
+type LonghornBackup struct {
+	VolumeName string
+	BackupName string
+}
+
+// String returns the form ParseLonghornBackup accepts.
+func (b LonghornBackup) String() string {
+	return b.VolumeName + "/" + b.BackupName
+}
+
+func ParseLonghornBackup(s string) (LonghornBackup, error) {
+	volumeName, backupName, ok := strings.Cut(s, "/")
+	if !ok || volumeName == "" || backupName == "" {
+		return LonghornBackup{}, fmt.Errorf("invalid Longhorn backup %q, expected <volume>/<backup>", s)
+	}
+	return LonghornBackup{VolumeName: volumeName, BackupName: backupName}, nil
+}
+
+// The layout Longhorn uses for the backups of a volume, see getVolumePath and
+// getBlockFilePath of the backupstore package.
+func getLonghornVolumePath(volumeName string) string {
+	sum := lhutil.GetChecksum([]byte(volumeName))
+	return filepath.Join(backupstore.GetBackupstoreBase(), backupstore.VOLUME_DIRECTORY, sum[0:2], sum[2:4], volumeName)
+}
+
+func getLonghornBackupConfigPath(b LonghornBackup) string {
+	return filepath.Join(getLonghornVolumePath(b.VolumeName), backupstore.BACKUP_DIRECTORY,
+		backupstore.BACKUP_CONFIG_PREFIX+b.BackupName+backupstore.CFG_SUFFIX)
+}
+
+func getLonghornBlockPath(volumeName, sum string) string {
+	return filepath.Join(getLonghornVolumePath(volumeName), backupstore.BLOCKS_DIRECTORY,
+		sum[0:2], sum[2:4], sum+backupstore.BLK_SUFFIX)
+}
+
+// copyBlock is a file holding volume data. Block is only set for export
+// chunks, Longhorn stores its blocks compressed. The checksum of the block is
+// updated when its chunk is encrypted with another key in the destination,
+// sum keeps the one of the chunk in the source.
+type copyBlock struct {
+	path   string
+	length int64
+	block  *Block
+	sum    string
+}
+
+// copyConfig is a file describing the blocks, copied after all of them. The
+// manifest of an export is written encrypted with the key of the destination.
+type copyConfig struct {
+	path       string
+	data       []byte
+	exportPath string
+	manifest   *Manifest
+	// keep an existing file, e.g. the volume.cfg of a volume with other backups
+	keepExisting bool
+}
+
+type copyPlan struct {
+	blocks  []copyBlock
+	configs []copyConfig
+	total   int64
+}
+
+// Copy copies the exports and the Longhorn backups, including the blocks
+// they refer to, from src to dst. Blocks already in dst are not copied again,
+// so copying successive backups of a volume only transfers what changed. The
+// manifests and backup configs are written after the blocks, so an
+// interrupted copy is simply restarted. The exports are decrypted with srcKey
+// and encrypted with dstKey, the keys of the backup targets, if any.
+func Copy(ctx context.Context, src, dst backupstore.BackupStoreDriver, srcKey, dstKey []byte, exportPaths []string, longhornBackups []LonghornBackup, progressPath string) (*Progress, error) {
+	srcChunks, err := newChunkCodec(srcKey)
+	if err != nil {
+		return nil, err
+	}
+	dstChunks, err := newChunkCodec(dstKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &copyPlan{}
+	for _, exportPath := range exportPaths {
+		if err := plan.addExport(src, srcKey, exportPath); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range longhornBackups {
+		if err := plan.addLonghornBackup(src, b); err != nil {
+			return nil, err
+		}
+	}
+
+	// Mark the exports as being uploaded, so CollectGarbage in dst doesn't
+	// remove the copied chunks before the manifests referring to them exist.
+	var markers []string
+	for _, exportPath := range exportPaths {
+		if ManifestExists(dst, exportPath) {
+			continue
+		}
+		marker := GetProgressPath(exportPath)
+		if err := writeJSON(dst, marker, &Progress{UpdatedAt: time.Now()}); err != nil {
+			return nil, fmt.Errorf("failed to mark export %s as being copied: %w", exportPath, err)
+		}
+		markers = append(markers, marker)
+	}
+
+	reporter := newProgressReporter(dst, progressPath, plan.total)
+	reporter.markers = markers
+	transfer := func(block copyBlock) (int64, error) {
+		if block.block != nil && !srcChunks.sameKey(dstChunks) {
+			return copyChunk(src, dst, srcChunks, dstChunks, block)
+		}
+		return copyFile(src, dst, srcChunks, block)
+	}
+	var exportBlocks []copyBlock
+	for _, block := range plan.blocks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		transferred, err := transfer(block)
+		if err != nil {
+			return nil, err
+		}
+		reporter.add(block.length, transferred)
+		if block.block != nil {
+			exportBlocks = append(exportBlocks, block)
+		}
+	}
+
+	// Put back the chunks a garbage collection in dst removed meanwhile.
+	err = ensureChunks(ctx, dst, reporter, len(exportBlocks), func(i int) error {
+		if dst.FileExists(getChunkPath(exportBlocks[i].block.Checksum)) {
+			return nil
+		}
+		transferred, err := transfer(exportBlocks[i])
+		reporter.add(0, transferred)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range plan.configs {
+		if config.manifest != nil {
+			if err := writeManifest(dst, dstKey, config.exportPath, config.manifest); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", config.path, err)
+			}
+			continue
+		}
+		if config.keepExisting && dst.FileExists(config.path) {
+			continue
+		}
+		if err := dst.Write(config.path, bytes.NewReader(config.data)); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", config.path, err)
+		}
+	}
+	reporter.flush()
+
+	logrus.WithFields(logrus.Fields{
+		"exports":          len(exportPaths),
+		"longhornBackups":  len(longhornBackups),
+		"size":             plan.total,
+		"transferredBytes": reporter.progress.TransferredBytes,
+	}).Info("backups copied")
+	return &reporter.progress, nil
+}
+
+// copyFile copies a file holding volume data as is, and returns how many
+// bytes were transferred. An export chunk is checked before it's copied.
+func copyFile(src, dst backupstore.BackupStoreDriver, chunks *chunkCodec, block copyBlock) (int64, error) {
+	if dst.FileExists(block.path) {
+		return 0, nil
+	}
+	data, err := readFile(src, block.path)
+	if err != nil {
+		return 0, err
+	}
+	if block.block != nil {
+		if _, err := chunks.open(*block.block, data); err != nil {
+			return 0, fmt.Errorf("block %s is corrupted: %w", block.path, err)
+		}
+	}
+	if err := dst.Write(block.path, bytes.NewReader(data)); err != nil {
+		return 0, fmt.Errorf("failed to write block %s: %w", block.path, err)
+	}
+	return int64(len(data)), nil
+}
+
+// copyChunk copies an export chunk between backup targets with different
+// keys. The chunk is decrypted, and stored in dst under the checksum of the
+// key of dst, which is recorded in the block.
+func copyChunk(src, dst backupstore.BackupStoreDriver, srcChunks, dstChunks *chunkCodec, block copyBlock) (int64, error) {
+	srcBlock := *block.block
+	srcBlock.Checksum = block.sum
+	data, err := readBlock(src, srcChunks, srcBlock)
+	if err != nil {
+		return 0, err
+	}
+	sum := dstChunks.checksum(data)
+	block.block.Checksum = sum
+	if dst.FileExists(getChunkPath(sum)) {
+		return 0, nil
+	}
+	chunk, err := dstChunks.seal(sum, data)
+	if err != nil {
+		return 0, err
+	}
+	if err := dst.Write(getChunkPath(sum), bytes.NewReader(chunk)); err != nil {
+		return 0, fmt.Errorf("failed to write block %s: %w", getChunkPath(sum), err)
+	}
+	return int64(len(chunk)), nil
+}
+
+func (p *copyPlan) addExport(src backupstore.BackupStoreDriver, key []byte, exportPath string) error {
+	manifest, err := LoadManifest(src, key, exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest of %s: %w", exportPath, err)
+	}
+
+	for i := range manifest.Blocks {
+		block := &manifest.Blocks[i]
+		p.blocks = append(p.blocks, copyBlock{
+			path:   getChunkPath(block.Checksum),
+			length: block.Length,
+			block:  block,
+			sum:    block.Checksum,
+		})
+		p.total += block.Length
+	}
+	p.configs = append(p.configs, copyConfig{
+		path:       GetManifestPath(exportPath),
+		exportPath: exportPath,
+		manifest:   manifest,
+	})
+	return nil
+}
+
+func (p *copyPlan) addLonghornBackup(src backupstore.BackupStoreDriver, b LonghornBackup) error {
+	configPath := getLonghornBackupConfigPath(b)
+	data, err := readFile(src, configPath)
+	if err != nil {
+		return err
+	}
+	backup := &backupstore.Backup{}
+	if err := json.Unmarshal(data, backup); err != nil {
+		return fmt.Errorf("failed to decode Longhorn backup %s: %w", b, err)
+	}
+	if backup.SingleFile.FilePath != "" {
+		return fmt.Errorf("backup %s is a single file backup, not a volume backup", b)
+	}
+	blockSize, err := backup.GetBlockSize()
+	if err != nil {
+		return fmt.Errorf("failed to get block size of Longhorn backup %s: %w", b, err)
+	}
+
+	for _, block := range backup.Blocks {
+		p.blocks = append(p.blocks, copyBlock{
+			path:   getLonghornBlockPath(b.VolumeName, block.BlockChecksum),
+			length: blockSize,
+		})
+		p.total += blockSize
+	}
+
+	volumeConfigPath := filepath.Join(getLonghornVolumePath(b.VolumeName), backupstore.VOLUME_CONFIG_FILE)
+	volumeConfig, err := readFile(src, volumeConfigPath)
+	if err != nil {
+		return err
+	}
+	p.configs = append(p.configs,
+		copyConfig{path: configPath, data: data},
+		copyConfig{path: volumeConfigPath, data: volumeConfig, keepExisting: true},
+	)
+	return nil
+}
+
+// LonghornBackupExists reports whether the Longhorn backup is in the backup
+// target of the driver.
+func LonghornBackupExists(driver backupstore.BackupStoreDriver, b LonghornBackup) bool {
+	return driver.FileExists(getLonghornBackupConfigPath(b))
+}
+
+// RemoveLonghornBackup removes a copied Longhorn backup. The blocks may be
+// shared with other backups of the volume, so they are only removed with the
+// volume once it has no backup left.
+func RemoveLonghornBackup(driver backupstore.BackupStoreDriver, b LonghornBackup) error {
+	if err := driver.Remove(getLonghornBackupConfigPath(b)); err != nil {
+		return fmt.Errorf("failed to remove Longhorn backup %s: %w", b, err)
+	}
+
+	volumePath := getLonghornVolumePath(b.VolumeName)
+	backups, err := driver.List(filepath.Join(volumePath, backupstore.BACKUP_DIRECTORY))
+	if err != nil {
+		return fmt.Errorf("failed to list backups of volume %s: %w", b.VolumeName, err)
+	}
+	if len(backups) > 0 {
+		return nil
+	}
+	if err := driver.Remove(volumePath); err != nil {
+		return fmt.Errorf("failed to remove volume %s: %w", b.VolumeName, err)
+	}
+	return nil
+}
+
+func readFile(driver backupstore.BackupStoreDriver, filePath string) ([]byte, error) {
+	rc, err := driver.Read(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return data, nil
+}
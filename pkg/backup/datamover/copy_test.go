@@ -0,0 +1,135 @@
+package datamover
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/longhorn/backupstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyExports(t *testing.T) {
+	const (
+		blockSize = 8
+		first     = "harvester/volumeexports/default/vmb-1/vb-1"
+		second    = "harvester/volumeexports/default/vmb-2/vb-2"
+	)
+
+	src := newMemoryDriver()
+	data := []byte("aaaaaaaabbbbbbbbcccccccc")
+	_, err := Upload(context.Background(), src, nil, writeSource(t, data), first, "", blockSize)
+	require.NoError(t, err)
+	copy(data[blockSize:], "BBBBBBBB")
+	_, err = Upload(context.Background(), src, nil, writeSource(t, data), second, first, blockSize)
+	require.NoError(t, err)
+
+	dst := newMemoryDriver()
+	progress, err := Copy(context.Background(), src, dst, nil, nil, []string{first}, nil, "progress.json")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3*blockSize), progress.TransferredBytes)
+	assert.True(t, ManifestExists(dst, first))
+
+	// Only the block which changed is copied with the second backup.
+	progress, err = Copy(context.Background(), src, dst, nil, nil, []string{second}, nil, "progress.json")
+	require.NoError(t, err)
+	assert.Equal(t, int64(blockSize), progress.TransferredBytes)
+	assert.Equal(t, int64(100), progress.Percentage())
+	assert.Equal(t, 4, dst.chunkCount())
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, Download(context.Background(), dst, nil, second, target, ""))
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+}
+
+func TestCopyExportsWithAnotherKey(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb-1/vb-1"
+	data := []byte("aaaaaaaabbbbbbbb")
+	srcKey, dstKey := newTestEncryptionKey(t), newTestEncryptionKey(t)
+
+	src := newMemoryDriver()
+	manifest, err := Upload(context.Background(), src, srcKey, writeSource(t, data), exportPath, "", 8)
+	require.NoError(t, err)
+
+	// the chunks are encrypted again with the key of the destination
+	dst := newMemoryDriver()
+	_, err = Copy(context.Background(), src, dst, srcKey, dstKey, []string{exportPath}, nil, "")
+	require.NoError(t, err)
+	copied, err := LoadManifest(dst, dstKey, exportPath)
+	require.NoError(t, err)
+	require.Len(t, copied.Blocks, 2)
+	assert.NotEqual(t, manifest.Blocks[0].Checksum, copied.Blocks[0].Checksum)
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, Download(context.Background(), dst, dstKey, exportPath, target, ""))
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+
+	// and decrypted for a destination without key
+	plain := newMemoryDriver()
+	_, err = Copy(context.Background(), src, plain, srcKey, nil, []string{exportPath}, nil, "")
+	require.NoError(t, err)
+	require.NoError(t, Download(context.Background(), plain, nil, exportPath, target, ""))
+}
+
+func TestCopyCorruptedExport(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb-1/vb-1"
+
+	src := newMemoryDriver()
+	manifest, err := Upload(context.Background(), src, nil, writeSource(t, []byte("some volume data")), exportPath, "", 8)
+	require.NoError(t, err)
+	src.files[getChunkPath(manifest.Blocks[0].Checksum)] = []byte("tampered")
+
+	dst := newMemoryDriver()
+	_, err = Copy(context.Background(), src, dst, nil, nil, []string{exportPath}, nil, "")
+	assert.ErrorContains(t, err, "corrupted")
+	assert.False(t, ManifestExists(dst, exportPath), "an incomplete copy must not have a manifest")
+	_, err = CollectGarbage(dst, nil)
+	assert.ErrorIs(t, err, ErrUploadInProgress)
+}
+
+func TestCopyLonghornBackup(t *testing.T) {
+	b, err := ParseLonghornBackup("pvc-1/backup-1")
+	require.NoError(t, err)
+
+	src := newMemoryDriver()
+	backup := &backupstore.Backup{
+		Name:       b.BackupName,
+		VolumeName: b.VolumeName,
+		Blocks: []backupstore.BlockMapping{
+			{Offset: 0, BlockChecksum: "0123456789"},
+			{Offset: 2 << 20, BlockChecksum: "abcdef0123"},
+		},
+	}
+	config, err := json.Marshal(backup)
+	require.NoError(t, err)
+	src.files[getLonghornBackupConfigPath(b)] = config
+	src.files[filepath.Join(getLonghornVolumePath(b.VolumeName), backupstore.VOLUME_CONFIG_FILE)] = []byte(`{"Name":"pvc-1"}`)
+	for _, block := range backup.Blocks {
+		src.files[getLonghornBlockPath(b.VolumeName, block.BlockChecksum)] = []byte("compressed")
+	}
+
+	dst := newMemoryDriver()
+	volumeConfigPath := filepath.Join(getLonghornVolumePath(b.VolumeName), backupstore.VOLUME_CONFIG_FILE)
+	dst.files[volumeConfigPath] = []byte(`{"Name":"pvc-1","LastBackupName":"backup-0"}`)
+
+	progress, err := Copy(context.Background(), src, dst, nil, nil, nil, []LonghornBackup{b}, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2*len("compressed")), progress.TransferredBytes)
+	assert.Equal(t, config, dst.files[getLonghornBackupConfigPath(b)])
+	// the volume already has backups in the destination, its config is kept
+	assert.Equal(t, `{"Name":"pvc-1","LastBackupName":"backup-0"}`, string(dst.files[volumeConfigPath]))
+
+	// the blocks go with the volume once its last backup is removed
+	require.NoError(t, RemoveLonghornBackup(dst, b))
+	assert.Empty(t, dst.files)
+
+	_, err = ParseLonghornBackup("pvc-1")
+	assert.Error(t, err)
+}
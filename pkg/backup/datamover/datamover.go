@@ -0,0 +1,504 @@
+package datamover
+
+// The data mover copies the raw content of a volume between a block device
+// (or a KubeVirt disk.img file) and the backup target. It runs inside the
+// Jobs created by the snapshot-export backup and restore engines, so it only
+// depends on a backupstore driver and never talks to the Kubernetes API.
+//
+// Layout of a single exported volume in the backup target:
+//
+//	<export path>/export.cfg      manifest, written last to mark completion
+//	<export path>/progress.json   progress of the upload
+//	<export path>/restores/<id>   progress of the running restores
+//
+// The non-zero blocks of all exports are stored once, addressed by their
+// checksum, in a pool shared by the whole backup target:
+//
+//	harvester/volumechunks/<first 2 hex digits>/<checksum>
+//
+// So an export only uploads the blocks that are not in the pool yet, and
+// successive backups of the same volume only upload what changed. A running
+// CollectGarbage marks the pool with harvester/volumechunks-gc/<id>.json.
+//
+// If the backup target has an encryption key, the manifests are encrypted
+// like the other metadata, and the chunks as described by chunkCodec.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/longhorn/backupstore"
+	"github.com/sirupsen/logrus"
+	kubevirtutil "kubevirt.io/kubevirt/pkg/util"
+
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	ManifestFileName = "export.cfg"
+	ProgressFileName = "progress.json"
+	// Every restore of an export reports its progress to its own file.
+	restoresFolderName = "restores"
+
+	// DefaultBlockSize keeps every object well below the single PUT limit of
+	// S3 compatible stores while bounding the number of objects per volume.
+	DefaultBlockSize int64 = 4 << 20
+
+	progressReportInterval = 5 * time.Second
+	// An upload which didn't report progress for that long lost its data
+	// mover, CollectGarbage doesn't wait for it anymore. Likewise, uploads
+	// don't wait for a CollectGarbage that quiet.
+	staleUploadTimeout = 30 * time.Minute
+)
+
+// Manifest describes an exported volume. Blocks that are entirely zero are
+// not stored and don't show up in Blocks.
+type Manifest struct {
+	Size      int64   `json:"size"`
+	BlockSize int64   `json:"blockSize"`
+	Blocks    []Block `json:"blocks,omitempty"`
+	// BaseExportPath is the export the unchanged blocks were compared against.
+	BaseExportPath string `json:"baseExportPath,omitempty"`
+	// TransferredBytes only counts the blocks that were not in the pool yet.
+	TransferredBytes int64     `json:"transferredBytes"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// Block refers to the chunk holding the data at Offset by its checksum.
+type Block struct {
+	Offset   int64  `json:"offset"`
+	Length   int64  `json:"length"`
+	Checksum string `json:"checksum"`
+}
+
+// Progress is periodically written by a running data mover so the engines
+// can report how far a transfer got.
+type Progress struct {
+	TotalBytes       int64     `json:"totalBytes"`
+	ProcessedBytes   int64     `json:"processedBytes"`
+	TransferredBytes int64     `json:"transferredBytes"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Percentage returns the processed ratio bounded between 0 and 100.
+func (p *Progress) Percentage() int64 {
+	if p == nil || p.TotalBytes <= 0 {
+		return 0
+	}
+	percentage := p.ProcessedBytes * 100 / p.TotalBytes
+	if percentage > 100 {
+		return 100
+	}
+	return percentage
+}
+
+func GetManifestPath(exportPath string) string {
+	return filepath.Join(exportPath, ManifestFileName)
+}
+
+func GetProgressPath(exportPath string) string {
+	return filepath.Join(exportPath, ProgressFileName)
+}
+
+// GetRestoreProgressPath returns the progress file of a single restore of the export at exportPath.
+func GetRestoreProgressPath(exportPath, restoreID string) string {
+	return filepath.Join(exportPath, restoresFolderName, fmt.Sprintf("%s.json", restoreID))
+}
+
+func getChunkPath(sum string) string {
+	return filepath.Join(backuputil.VolumeChunkFolderPath, sum[:2], sum)
+}
+
+// ManifestExists reports whether the export at exportPath has completed.
+func ManifestExists(driver backupstore.BackupStoreDriver, exportPath string) bool {
+	return driver.FileExists(GetManifestPath(exportPath))
+}
+
+// LoadManifest reads the manifest of the export, and decrypts it with the
+// encryption key of the backup target, if any.
+func LoadManifest(driver backupstore.BackupStoreDriver, key []byte, exportPath string) (*Manifest, error) {
+	data, err := backuputil.ReadMetadata(driver, GetManifestPath(exportPath), key)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeManifest(driver backupstore.BackupStoreDriver, key []byte, exportPath string, manifest *Manifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return backuputil.WriteMetadata(driver, GetManifestPath(exportPath), key, data)
+}
+
+// LoadProgress returns nil without error if the data mover hasn't reported
+// any progress yet.
+func LoadProgress(driver backupstore.BackupStoreDriver, progressPath string) (*Progress, error) {
+	if !driver.FileExists(progressPath) {
+		return nil, nil
+	}
+	progress := &Progress{}
+	if err := readJSON(driver, progressPath, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+// Remove deletes the manifest and the progress files of the export. The
+// chunks it refers to are left to CollectGarbage, as other exports may share them.
+func Remove(driver backupstore.BackupStoreDriver, exportPath string) error {
+	return driver.Remove(exportPath)
+}
+
+// Upload copies the content of source to exportPath. Blocks already in the
+// chunk pool are not uploaded again. If baseExportPath is set, blocks that
+// didn't change since that export are not even looked up in the pool. The
+// manifest is written after all blocks, so an interrupted upload is simply
+// restarted, and only uploads what the interrupted one didn't. The blocks
+// and the manifest are encrypted with the key, if any. The chunks a garbage
+// collection removed during the upload are uploaded again before the manifest.
+func Upload(ctx context.Context, driver backupstore.BackupStoreDriver, key []byte, source, exportPath, baseExportPath string, blockSize int64) (*Manifest, error) {
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("invalid block size %d", blockSize)
+	}
+	chunks, err := newChunkCodec(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source %s: %w", source, err)
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size of source %s: %w", source, err)
+	}
+
+	manifest := &Manifest{
+		Size:      size,
+		BlockSize: blockSize,
+	}
+	baseBlocks := loadBaseBlocks(driver, key, baseExportPath, blockSize)
+	if baseBlocks != nil {
+		manifest.BaseExportPath = baseExportPath
+	}
+	reporter := newProgressReporter(driver, GetProgressPath(exportPath), size)
+	buf := make([]byte, blockSize)
+
+	for offset := int64(0); offset < size; offset += blockSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read source %s at offset %d: %w", source, offset, err)
+		}
+		data := buf[:n]
+
+		if isZero(data) {
+			reporter.add(int64(n), 0)
+			continue
+		}
+
+		sum := chunks.checksum(data)
+		manifest.Blocks = append(manifest.Blocks, Block{
+			Offset:   offset,
+			Length:   int64(n),
+			Checksum: sum,
+		})
+		if baseBlocks[offset] == sum || driver.FileExists(getChunkPath(sum)) {
+			reporter.add(int64(n), 0)
+			continue
+		}
+
+		if err := writeChunk(driver, chunks, sum, data, offset); err != nil {
+			return nil, err
+		}
+		reporter.add(int64(n), int64(n))
+	}
+
+	// Put back the chunks a garbage collection removed meanwhile.
+	err = ensureChunks(ctx, driver, reporter, len(manifest.Blocks), func(i int) error {
+		block := manifest.Blocks[i]
+		if driver.FileExists(getChunkPath(block.Checksum)) {
+			return nil
+		}
+		data := buf[:block.Length]
+		if _, err := f.ReadAt(data, block.Offset); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read source %s at offset %d: %w", source, block.Offset, err)
+		}
+		if chunks.checksum(data) != block.Checksum {
+			return fmt.Errorf("source %s changed at offset %d during the upload", source, block.Offset)
+		}
+		if err := writeChunk(driver, chunks, block.Checksum, data, block.Offset); err != nil {
+			return err
+		}
+		reporter.add(0, block.Length)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest.TransferredBytes = reporter.progress.TransferredBytes
+	manifest.CreatedAt = time.Now().UTC()
+	if err := writeManifest(driver, key, exportPath, manifest); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	reporter.flush()
+
+	logrus.WithFields(logrus.Fields{
+		"exportPath":       exportPath,
+		"baseExportPath":   manifest.BaseExportPath,
+		"size":             size,
+		"transferredBytes": manifest.TransferredBytes,
+	}).Info("volume uploaded")
+	return manifest, nil
+}
+
+func writeChunk(driver backupstore.BackupStoreDriver, chunks *chunkCodec, sum string, data []byte, offset int64) error {
+	chunk, err := chunks.seal(sum, data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt block at offset %d: %w", offset, err)
+	}
+	if err := driver.Write(getChunkPath(sum), bytes.NewReader(chunk)); err != nil {
+		return fmt.Errorf("failed to write block at offset %d: %w", offset, err)
+	}
+	return nil
+}
+
+// loadBaseBlocks returns the checksums of the base export blocks by offset.
+// The base is only an optimization, so it is ignored if it can't be used.
+func loadBaseBlocks(driver backupstore.BackupStoreDriver, key []byte, baseExportPath string, blockSize int64) map[int64]string {
+	if baseExportPath == "" {
+		return nil
+	}
+	base, err := LoadManifest(driver, key, baseExportPath)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to load base export %s, uploading without it", baseExportPath)
+		return nil
+	}
+	if base.BlockSize != blockSize {
+		logrus.Warnf("block size of base export %s differs, uploading without it", baseExportPath)
+		return nil
+	}
+
+	blocks := make(map[int64]string, len(base.Blocks))
+	for _, block := range base.Blocks {
+		blocks[block.Offset] = block.Checksum
+	}
+	return blocks
+}
+
+// Download restores the export at exportPath into target. Regions that were
+// not stored are zeroed unless the target is a regular file, which is
+// truncated to the volume size and therefore already sparse.
+func Download(ctx context.Context, driver backupstore.BackupStoreDriver, key []byte, exportPath, target, progressPath string) error {
+	manifest, err := LoadManifest(driver, key, exportPath)
+	if err != nil {
+		return fmt.Errorf("failed to load manifest of %s: %w", exportPath, err)
+	}
+	chunks, err := newChunkCodec(key)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE, 0660)
+	if err != nil {
+		return fmt.Errorf("failed to open target %s: %w", target, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat target %s: %w", target, err)
+	}
+	zeroGaps := !info.Mode().IsRegular()
+	if !zeroGaps {
+		if err := f.Truncate(manifest.Size); err != nil {
+			return fmt.Errorf("failed to resize target %s: %w", target, err)
+		}
+		// virt-launcher runs as the non-root qemu user and must be able to open the disk image.
+		if os.Geteuid() == 0 {
+			if err := f.Chown(kubevirtutil.NonRootUID, kubevirtutil.NonRootUID); err != nil {
+				return fmt.Errorf("failed to change owner of target %s: %w", target, err)
+			}
+		}
+	} else if capacity, err := f.Seek(0, io.SeekEnd); err == nil && capacity < manifest.Size {
+		return fmt.Errorf("target %s is smaller than the exported volume (%d < %d)", target, capacity, manifest.Size)
+	}
+
+	reporter := newProgressReporter(driver, progressPath, manifest.Size)
+	zeros := make([]byte, manifest.BlockSize)
+	next := int64(0)
+	for _, block := range manifest.Blocks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if zeroGaps {
+			if err := writeZeros(f, zeros, next, block.Offset); err != nil {
+				return err
+			}
+		}
+		reporter.add(block.Offset-next, 0)
+
+		data, err := readBlock(driver, chunks, block)
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(data, block.Offset); err != nil {
+			return fmt.Errorf("failed to write target %s at offset %d: %w", target, block.Offset, err)
+		}
+		reporter.add(block.Length, block.Length)
+		next = block.Offset + block.Length
+	}
+	if zeroGaps {
+		if err := writeZeros(f, zeros, next, manifest.Size); err != nil {
+			return err
+		}
+	}
+	reporter.add(manifest.Size-next, 0)
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync target %s: %w", target, err)
+	}
+	reporter.flush()
+	return nil
+}
+
+func readBlock(driver backupstore.BackupStoreDriver, chunks *chunkCodec, block Block) ([]byte, error) {
+	if len(block.Checksum) != hex.EncodedLen(sha256.Size) {
+		return nil, fmt.Errorf("block at offset %d is corrupted", block.Offset)
+	}
+	rc, err := driver.Read(getChunkPath(block.Checksum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block at offset %d: %w", block.Offset, err)
+	}
+	defer rc.Close()
+
+	chunk, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block at offset %d: %w", block.Offset, err)
+	}
+	return chunks.open(block, chunk)
+}
+
+func writeZeros(f *os.File, zeros []byte, from, to int64) error {
+	for offset := from; offset < to; {
+		n := min(int64(len(zeros)), to-offset)
+		if _, err := f.WriteAt(zeros[:n], offset); err != nil {
+			return fmt.Errorf("failed to zero target at offset %d: %w", offset, err)
+		}
+		offset += n
+	}
+	return nil
+}
+
+func isZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func readJSON(driver backupstore.BackupStoreDriver, filePath string, v interface{}) error {
+	rc, err := driver.Read(filePath)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(rc).Decode(v)
+}
+
+func writeJSON(driver backupstore.BackupStoreDriver, filePath string, v interface{}) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return driver.Write(filePath, bytes.NewReader(j))
+}
+
+// progressReporter throttles progress writes, they are best effort and must
+// never fail the transfer. The markers are refreshed along with the progress,
+// so CollectGarbage knows the transfer writing to them is still alive.
+type progressReporter struct {
+	driver     backupstore.BackupStoreDriver
+	path       string
+	markers    []string
+	progress   Progress
+	lastReport time.Time
+}
+
+func newProgressReporter(driver backupstore.BackupStoreDriver, path string, total int64) *progressReporter {
+	return &progressReporter{
+		driver:   driver,
+		path:     path,
+		progress: Progress{TotalBytes: total},
+	}
+}
+
+func (r *progressReporter) add(processed, transferred int64) {
+	r.progress.ProcessedBytes += processed
+	r.progress.TransferredBytes += transferred
+	if time.Since(r.lastReport) >= progressReportInterval {
+		r.flush()
+	}
+}
+
+// refresh flushes like add, and reports whether the reporter was quiet for
+// staleUploadTimeout, so the others may have taken its transfer for dead.
+func (r *progressReporter) refresh() bool {
+	quiet := time.Since(r.lastReport)
+	stale := !r.lastReport.IsZero() && quiet > staleUploadTimeout
+	if quiet >= progressReportInterval {
+		r.flush()
+	}
+	return stale
+}
+
+// flush returns the errors along with logging them, for the callers which
+// rely on the progress being reported.
+func (r *progressReporter) flush() error {
+	r.lastReport = time.Now()
+	r.progress.UpdatedAt = r.lastReport
+	var flushErr error
+	for _, marker := range r.markers {
+		if err := writeJSON(r.driver, marker, &Progress{UpdatedAt: r.lastReport}); err != nil {
+			logrus.WithError(err).Warnf("failed to refresh %s", marker)
+			flushErr = errors.Join(flushErr, fmt.Errorf("failed to refresh %s: %w", marker, err))
+		}
+	}
+	if r.path == "" {
+		return flushErr
+	}
+	if err := writeJSON(r.driver, r.path, r.progress); err != nil {
+		logrus.WithError(err).Warnf("failed to report progress to %s", r.path)
+		flushErr = errors.Join(flushErr, fmt.Errorf("failed to report progress to %s: %w", r.path, err))
+	}
+	return flushErr
+}
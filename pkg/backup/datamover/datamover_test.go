@@ -0,0 +1,415 @@
+package datamover
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+// memoryDriver is an in-memory backupstore.BackupStoreDriver.
+type memoryDriver struct {
+	files map[string][]byte
+}
+
+func newMemoryDriver() *memoryDriver {
+	return &memoryDriver{files: map[string][]byte{}}
+}
+
+func (d *memoryDriver) Kind() string   { return "memory" }
+func (d *memoryDriver) GetURL() string { return "memory://" }
+
+func (d *memoryDriver) FileExists(filePath string) bool {
+	_, ok := d.files[filePath]
+	return ok
+}
+
+func (d *memoryDriver) FileSize(filePath string) int64 {
+	return int64(len(d.files[filePath]))
+}
+
+func (d *memoryDriver) FileTime(_ string) time.Time {
+	return time.Time{}
+}
+
+func (d *memoryDriver) Remove(path string) error {
+	for name := range d.files {
+		if name == path || strings.HasPrefix(name, path+"/") {
+			delete(d.files, name)
+		}
+	}
+	return nil
+}
+
+func (d *memoryDriver) Read(src string) (io.ReadCloser, error) {
+	data, ok := d.files[src]
+	if !ok {
+		return nil, fmt.Errorf("file %s not found", src)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (d *memoryDriver) Write(dst string, rs io.ReadSeeker) error {
+	data, err := io.ReadAll(rs)
+	if err != nil {
+		return err
+	}
+	d.files[dst] = data
+	return nil
+}
+
+// List returns the files and folders right below path.
+func (d *memoryDriver) List(path string) ([]string, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	children := map[string]struct{}{}
+	for name := range d.files {
+		if rest, ok := strings.CutPrefix(name, prefix); ok {
+			children[strings.SplitN(rest, "/", 2)[0]] = struct{}{}
+		}
+	}
+	var result []string
+	for child := range children {
+		result = append(result, child)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (d *memoryDriver) Upload(_, _ string) error   { return nil }
+func (d *memoryDriver) Download(_, _ string) error { return nil }
+
+func (d *memoryDriver) chunkCount() int {
+	count := 0
+	for name := range d.files {
+		if strings.HasPrefix(name, "harvester/volumechunks/") {
+			count++
+		}
+	}
+	return count
+}
+
+func writeSource(t *testing.T, data []byte) string {
+	t.Helper()
+	source := filepath.Join(t.TempDir(), "source.img")
+	require.NoError(t, os.WriteFile(source, data, 0600))
+	return source
+}
+
+func TestUploadDownload(t *testing.T) {
+	const (
+		blockSize  = 16
+		exportPath = "harvester/volumeexports/default/vmb/vb"
+	)
+
+	// 5 blocks, the second and the fourth are zero and the last one is partial.
+	data := make([]byte, 4*blockSize+5)
+	copy(data[0:], bytes.Repeat([]byte{'a'}, blockSize))
+	copy(data[2*blockSize:], bytes.Repeat([]byte{'c'}, blockSize))
+	copy(data[4*blockSize:], []byte("tail!"))
+
+	driver := newMemoryDriver()
+	manifest, err := Upload(context.Background(), driver, nil, writeSource(t, data), exportPath, "", blockSize)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(len(data)), manifest.Size)
+	assert.Len(t, manifest.Blocks, 3, "zero blocks must not be stored")
+	assert.Equal(t, int64(2*blockSize+5), manifest.TransferredBytes)
+	assert.Equal(t, 3, driver.chunkCount())
+	assert.True(t, ManifestExists(driver, exportPath))
+
+	progress, err := LoadProgress(driver, GetProgressPath(exportPath))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), progress.ProcessedBytes)
+	assert.Equal(t, int64(2*blockSize+5), progress.TransferredBytes)
+	assert.Equal(t, int64(100), progress.Percentage())
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	restoreProgressPath := GetRestoreProgressPath(exportPath, "restore")
+	require.NoError(t, Download(context.Background(), driver, nil, exportPath, target, restoreProgressPath))
+
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+
+	restoreProgress, err := LoadProgress(driver, restoreProgressPath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), restoreProgress.Percentage())
+
+	require.NoError(t, Remove(driver, exportPath))
+	assert.False(t, ManifestExists(driver, exportPath))
+
+	removed, err := CollectGarbage(driver, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.Empty(t, driver.files)
+}
+
+func newTestEncryptionKey(t *testing.T) []byte {
+	t.Helper()
+	secretValue := make([]byte, 32)
+	_, err := rand.Read(secretValue)
+	require.NoError(t, err)
+	key, err := backuputil.ParseEncryptionKey([]byte(base64.StdEncoding.EncodeToString(secretValue)))
+	require.NoError(t, err)
+	return key
+}
+
+func TestEncryptedUploadDownload(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb/vb"
+	data := []byte("aaaaaaaabbbbbbbbcccc")
+	key := newTestEncryptionKey(t)
+
+	driver := newMemoryDriver()
+	manifest, err := Upload(context.Background(), driver, key, writeSource(t, data), exportPath, "", 8)
+	require.NoError(t, err)
+	require.Len(t, manifest.Blocks, 3)
+	assert.NotEqual(t, checksum(data[:8]), manifest.Blocks[0].Checksum, "chunks must not be addressed by the checksum of their content")
+	for filePath, content := range driver.files {
+		assert.NotContains(t, string(content), "aaaaaaaa", filePath)
+		assert.NotContains(t, string(content), manifest.Blocks[0].Checksum+`"`, filePath)
+	}
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, Download(context.Background(), driver, key, exportPath, target, ""))
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+
+	// the export can't be read without the key or with another one
+	assert.Error(t, Download(context.Background(), driver, nil, exportPath, target, ""))
+	assert.Error(t, Download(context.Background(), driver, newTestEncryptionKey(t), exportPath, target, ""))
+
+	// a chunk can't be swapped for another one
+	driver.files[getChunkPath(manifest.Blocks[0].Checksum)] = driver.files[getChunkPath(manifest.Blocks[1].Checksum)]
+	assert.ErrorContains(t, Download(context.Background(), driver, key, exportPath, target, ""), "corrupted")
+}
+
+func TestIncrementalUpload(t *testing.T) {
+	const (
+		blockSize = 8
+		first     = "harvester/volumeexports/default/vmb-1/vb-1"
+		second    = "harvester/volumeexports/default/vmb-2/vb-2"
+		other     = "harvester/volumeexports/default/vmb-3/vb-3"
+	)
+
+	data := []byte("aaaaaaaabbbbbbbbccccccccdddddddd")
+	driver := newMemoryDriver()
+	_, err := Upload(context.Background(), driver, nil, writeSource(t, data), first, "", blockSize)
+	require.NoError(t, err)
+
+	// Change a single block, only that one is uploaded.
+	copy(data[blockSize:], "BBBBBBBB")
+	source := writeSource(t, data)
+	manifest, err := Upload(context.Background(), driver, nil, source, second, first, blockSize)
+	require.NoError(t, err)
+	assert.Equal(t, first, manifest.BaseExportPath)
+	assert.Equal(t, int64(blockSize), manifest.TransferredBytes)
+	assert.Equal(t, 5, driver.chunkCount())
+
+	progress, err := LoadProgress(driver, GetProgressPath(second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), progress.ProcessedBytes)
+	assert.Equal(t, int64(blockSize), progress.TransferredBytes)
+
+	// Identical data is deduplicated even without a base.
+	manifest, err = Upload(context.Background(), driver, nil, source, other, "", blockSize)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.BaseExportPath)
+	assert.Zero(t, manifest.TransferredBytes)
+
+	// Removing the base export keeps the chunks the others still refer to.
+	require.NoError(t, Remove(driver, first))
+	removed, err := CollectGarbage(driver, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, Download(context.Background(), driver, nil, second, target, ""))
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+}
+
+func TestIncrementalUploadWithUnusableBase(t *testing.T) {
+	data := []byte("aaaaaaaabbbbbbbb")
+	driver := newMemoryDriver()
+	_, err := Upload(context.Background(), driver, nil, writeSource(t, data), "base", "", 8)
+	require.NoError(t, err)
+
+	// The base is ignored when its blocks can't be compared, the pool still deduplicates.
+	manifest, err := Upload(context.Background(), driver, nil, writeSource(t, data), "export", "base", 4)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.BaseExportPath)
+	// "aaaa" and "bbbb" are both stored once.
+	assert.Equal(t, int64(8), manifest.TransferredBytes)
+
+	manifest, err = Upload(context.Background(), driver, nil, writeSource(t, data), "again", "missing", 8)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.BaseExportPath)
+	assert.Zero(t, manifest.TransferredBytes)
+}
+
+func TestCollectGarbageDuringUpload(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb/vb"
+
+	driver := newMemoryDriver()
+	_, err := Upload(context.Background(), driver, nil, writeSource(t, []byte("some volume data")), exportPath, "", 8)
+	require.NoError(t, err)
+	require.NoError(t, Remove(driver, exportPath))
+
+	// An upload which didn't write its manifest yet may rely on any chunk.
+	require.NoError(t, writeJSON(driver, GetProgressPath("harvester/volumeexports/default/other/vb"), &Progress{}))
+	_, err = CollectGarbage(driver, nil)
+	assert.ErrorIs(t, err, ErrUploadInProgress)
+	assert.Equal(t, 2, driver.chunkCount())
+}
+
+func TestCollectGarbageWithStaleUpload(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb/vb"
+
+	driver := newMemoryDriver()
+	_, err := Upload(context.Background(), driver, nil, writeSource(t, []byte("some volume data")), exportPath, "", 8)
+	require.NoError(t, err)
+	require.NoError(t, Remove(driver, exportPath))
+
+	// An upload which stopped reporting progress lost its data mover, it doesn't block the collection.
+	require.NoError(t, writeJSON(driver, GetProgressPath("harvester/volumeexports/default/other/vb"), &Progress{
+		UpdatedAt: time.Now().Add(-staleUploadTimeout - time.Minute),
+	}))
+	removed, err := CollectGarbage(driver, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Zero(t, driver.chunkCount())
+	running, err := isGarbageCollectionRunning(driver)
+	require.NoError(t, err)
+	assert.False(t, running)
+}
+
+// collectingDriver runs CollectGarbage right after the first chunk lookup,
+// before the upload reported any progress.
+type collectingDriver struct {
+	*memoryDriver
+	collected int
+}
+
+func (d *collectingDriver) FileExists(filePath string) bool {
+	exists := d.memoryDriver.FileExists(filePath)
+	if exists && d.collected == 0 && strings.HasPrefix(filePath, backuputil.VolumeChunkFolderPath) {
+		d.collected, _ = CollectGarbage(d.memoryDriver, nil)
+	}
+	return exists
+}
+
+func TestUploadRestoresCollectedChunks(t *testing.T) {
+	const exportPath = "harvester/volumeexports/default/vmb/vb"
+	data := []byte("some volume data")
+
+	driver := &collectingDriver{memoryDriver: newMemoryDriver()}
+	_, err := Upload(context.Background(), driver.memoryDriver, nil, writeSource(t, data), "harvester/volumeexports/default/old/vb", "", 8)
+	require.NoError(t, err)
+	require.NoError(t, Remove(driver.memoryDriver, "harvester/volumeexports/default/old/vb"))
+
+	// the chunks the upload skipped are removed, and uploaded again
+	_, err = Upload(context.Background(), driver, nil, writeSource(t, data), exportPath, "", 8)
+	require.NoError(t, err)
+	assert.Equal(t, 2, driver.collected)
+	assert.Equal(t, 2, driver.chunkCount())
+
+	target := filepath.Join(t.TempDir(), "disk.img")
+	require.NoError(t, Download(context.Background(), driver.memoryDriver, nil, exportPath, target, ""))
+	restored, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, data, restored)
+}
+
+func TestUploadWaitsForGarbageCollection(t *testing.T) {
+	const marker = garbageCollectionFolderPath + "gc.json"
+
+	driver := newMemoryDriver()
+	require.NoError(t, writeJSON(driver, marker, &Progress{UpdatedAt: time.Now()}))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := Upload(ctx, driver, nil, writeSource(t, []byte("some volume data")), "export", "", 8)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, ManifestExists(driver, "export"))
+
+	// a collection which stopped refreshing its marker lost its controller
+	require.NoError(t, writeJSON(driver, marker, &Progress{UpdatedAt: time.Now().Add(-staleUploadTimeout - time.Minute)}))
+	_, err = Upload(context.Background(), driver, nil, writeSource(t, []byte("some volume data")), "export", "", 8)
+	require.NoError(t, err)
+}
+
+func TestIsUploadStale(t *testing.T) {
+	const progressPath = "export/progress.json"
+	now := time.Now()
+
+	driver := newMemoryDriver()
+	require.NoError(t, writeJSON(driver, progressPath, &Progress{UpdatedAt: now.Add(-time.Minute)}))
+	assert.False(t, isUploadStale(driver, progressPath, now))
+	assert.True(t, isUploadStale(driver, progressPath, now.Add(staleUploadTimeout)))
+
+	// without an update time, the progress counts as alive
+	require.NoError(t, writeJSON(driver, progressPath, &Progress{}))
+	assert.False(t, isUploadStale(driver, progressPath, now.Add(staleUploadTimeout)))
+}
+
+func TestUploadReportsUpdateTime(t *testing.T) {
+	const exportPath = "export"
+
+	driver := newMemoryDriver()
+	_, err := Upload(context.Background(), driver, nil, writeSource(t, []byte("some volume data")), exportPath, "", 8)
+	require.NoError(t, err)
+	progress, err := LoadProgress(driver, GetProgressPath(exportPath))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), progress.UpdatedAt, time.Minute)
+}
+
+func TestDownloadCorruptedBlock(t *testing.T) {
+	const exportPath = "export"
+
+	driver := newMemoryDriver()
+	manifest, err := Upload(context.Background(), driver, nil, writeSource(t, []byte("some volume data")), exportPath, "", 8)
+	require.NoError(t, err)
+
+	driver.files[getChunkPath(manifest.Blocks[1].Checksum)] = []byte("tampered")
+
+	err = Download(context.Background(), driver, nil, exportPath, filepath.Join(t.TempDir(), "disk.img"), "")
+	assert.ErrorContains(t, err, "corrupted")
+}
+
+func TestUploadInvalidBlockSize(t *testing.T) {
+	_, err := Upload(context.Background(), newMemoryDriver(), nil, writeSource(t, []byte("data")), "export", "", 0)
+	assert.Error(t, err)
+}
+
+func TestProgressPercentage(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress *Progress
+		expected int64
+	}{
+		{name: "nil progress", progress: nil, expected: 0},
+		{name: "empty volume", progress: &Progress{}, expected: 0},
+		{name: "half", progress: &Progress{TotalBytes: 200, ProcessedBytes: 100}, expected: 50},
+		{name: "bounded", progress: &Progress{TotalBytes: 100, ProcessedBytes: 150}, expected: 100},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.progress.Percentage())
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package datamover
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/longhorn/backupstore"
+	"github.com/sirupsen/logrus"
+
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+// Every running CollectGarbage marks the backup target with its own file in
+// that folder, refreshed like the progress of an upload.
+const garbageCollectionFolderPath = "harvester/volumechunks-gc/"
+
+// ErrUploadInProgress is returned by CollectGarbage while an export is being
+// uploaded, the garbage is collected by a later call.
+var ErrUploadInProgress = errors.New("volume export upload in progress")
+
+// CollectGarbage removes the chunks no export refers to anymore and returns
+// how many were removed. A running upload may skip chunks that only removed
+// exports referred to so far, so nothing is removed while an upload runs.
+// The manifests are decrypted with the encryption key of the backup target, if any.
+//
+// An upload which started after the last check, or which went quiet for
+// staleUploadTimeout, may still rely on the removed chunks. So the collection
+// marks the backup target while it removes chunks, and ensureChunks makes the
+// uploads wait for it and put back what it removed before writing their
+// manifests.
+func CollectGarbage(driver backupstore.BackupStoreDriver, key []byte) (int, error) {
+	exportPaths, uploading, err := listExports(driver)
+	if err != nil {
+		return 0, err
+	}
+	if uploading {
+		return 0, ErrUploadInProgress
+	}
+
+	referenced := map[string]struct{}{}
+	for _, exportPath := range exportPaths {
+		manifest, err := LoadManifest(driver, key, exportPath)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load manifest of %s: %w", exportPath, err)
+		}
+		for _, block := range manifest.Blocks {
+			referenced[block.Checksum] = struct{}{}
+		}
+	}
+
+	var garbage []string
+	prefixes, err := driver.List(backuputil.VolumeChunkFolderPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	for _, prefix := range prefixes {
+		sums, err := driver.List(filepath.Join(backuputil.VolumeChunkFolderPath, prefix))
+		if err != nil {
+			return 0, fmt.Errorf("failed to list chunks: %w", err)
+		}
+		for _, sum := range sums {
+			if _, ok := referenced[sum]; !ok {
+				garbage = append(garbage, filepath.Join(backuputil.VolumeChunkFolderPath, prefix, sum))
+			}
+		}
+	}
+	if len(garbage) == 0 {
+		return 0, nil
+	}
+
+	// Mark the collection before checking the uploads again, so an upload
+	// which isn't seen by the check sees the marker.
+	marker := filepath.Join(garbageCollectionFolderPath, rand.Text()+".json")
+	reporter := newProgressReporter(driver, marker, int64(len(garbage)))
+	if err := reporter.flush(); err != nil {
+		return 0, fmt.Errorf("failed to mark the garbage collection: %w", err)
+	}
+	defer func() {
+		if err := driver.Remove(marker); err != nil {
+			logrus.WithError(err).Warnf("failed to remove %s", marker)
+		}
+	}()
+
+	// Listing takes a while on large targets, check again right before removing.
+	if _, uploading, err = listExports(driver); err != nil {
+		return 0, err
+	} else if uploading {
+		return 0, ErrUploadInProgress
+	}
+
+	for i, chunkPath := range garbage {
+		// Uploads don't wait for a collection which went quiet for that long.
+		if reporter.refresh() {
+			return i, fmt.Errorf("garbage collection stalled after removing %d chunks, stopping", i)
+		}
+		if err := driver.Remove(chunkPath); err != nil {
+			return i, fmt.Errorf("failed to remove chunk %s: %w", chunkPath, err)
+		}
+		reporter.add(1, 0)
+	}
+	logrus.Infof("removed %d unreferenced volume export chunks", len(garbage))
+	return len(garbage), nil
+}
+
+// listExports returns the completed exports of the backup target, and
+// whether any other export is still being uploaded. An upload whose data
+// mover stopped reporting progress is orphaned, it doesn't count.
+func listExports(driver backupstore.BackupStoreDriver) ([]string, bool, error) {
+	var exportPaths []string
+	uploading := false
+
+	// Exports are stored as <namespace>/<VMBackup>/<volume backup>.
+	err := walkFolders(driver, backuputil.VolumeExportFolderPath, 3, func(exportPath string) {
+		switch {
+		case ManifestExists(driver, exportPath):
+			exportPaths = append(exportPaths, exportPath)
+		case driver.FileExists(GetProgressPath(exportPath)):
+			if isUploadStale(driver, GetProgressPath(exportPath), time.Now()) {
+				logrus.Infof("ignoring orphaned volume export %s, its upload stopped reporting progress", exportPath)
+				return
+			}
+			uploading = true
+		}
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list volume exports: %w", err)
+	}
+	return exportPaths, uploading, nil
+}
+
+// ensureChunks is called by a transfer right before it writes its manifests.
+// put writes the chunk of the block i unless it exists. CollectGarbage may
+// have removed chunks the transfer skipped or wrote, if it checked for uploads
+// before the transfer reported any progress, or while the transfer was quiet
+// for staleUploadTimeout. So the running collections are waited for, then all
+// the chunks are put again, which starts over if the transfer went quiet
+// meanwhile. A collection starting later sees the transfer and removes nothing.
+func ensureChunks(ctx context.Context, driver backupstore.BackupStoreDriver, reporter *progressReporter, count int, put func(i int) error) error {
+	for {
+		if err := waitForGarbageCollection(ctx, driver, reporter); err != nil {
+			return err
+		}
+		stale := false
+		for i := 0; i < count; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := put(i); err != nil {
+				return err
+			}
+			stale = reporter.refresh() || stale
+		}
+		if !stale {
+			return nil
+		}
+		logrus.Warn("transfer stopped reporting progress while checking its chunks, checking them again")
+	}
+}
+
+// waitForGarbageCollection returns once no CollectGarbage is running in the
+// backup target. The progress of the transfer is reported first, so a
+// collection which isn't seen yet sees the transfer.
+func waitForGarbageCollection(ctx context.Context, driver backupstore.BackupStoreDriver, reporter *progressReporter) error {
+	for {
+		if err := reporter.flush(); err != nil {
+			return err
+		}
+		running, err := isGarbageCollectionRunning(driver)
+		if err != nil {
+			return err
+		}
+		if !running {
+			return nil
+		}
+		logrus.Info("waiting for the garbage collection of the volume export chunks")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(progressReportInterval):
+		}
+	}
+}
+
+// isGarbageCollectionRunning ignores the collections which stopped
+// refreshing their marker, like listExports the stale uploads.
+func isGarbageCollectionRunning(driver backupstore.BackupStoreDriver) (bool, error) {
+	names, err := driver.List(garbageCollectionFolderPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to list garbage collections: %w", err)
+	}
+	now := time.Now()
+	for _, name := range names {
+		marker := filepath.Join(garbageCollectionFolderPath, name)
+		if driver.FileExists(marker) && !isUploadStale(driver, marker, now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isUploadStale reports whether the progress at progressPath wasn't updated
+// for staleUploadTimeout. Progress written before it recorded its update time
+// falls back to the modification time of the file, and counts as alive if
+// the driver doesn't know it either.
+func isUploadStale(driver backupstore.BackupStoreDriver, progressPath string, now time.Time) bool {
+	var updatedAt time.Time
+	if progress, err := LoadProgress(driver, progressPath); err == nil && progress != nil {
+		updatedAt = progress.UpdatedAt
+	}
+	if updatedAt.IsZero() {
+		updatedAt = driver.FileTime(progressPath)
+	}
+	return !updatedAt.IsZero() && now.Sub(updatedAt) > staleUploadTimeout
+}
+
+func walkFolders(driver backupstore.BackupStoreDriver, folder string, depth int, fn func(string)) error {
+	if depth == 0 {
+		fn(folder)
+		return nil
+	}
+	names, err := driver.List(folder)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := walkFolders(driver, filepath.Join(folder, name), depth-1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
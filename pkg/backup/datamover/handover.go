@@ -0,0 +1,79 @@
+package datamover
+
+import (
+	"fmt"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The data mover downloads into a staging PVC in Namespace, because the Jobs
+// don't run in user namespaces. Once the download is complete, the PV is
+// handed over to the PVC of the user:
+//  1. RetainPV keeps the PV when the staging PVC is deleted.
+//  2. The PVC of the user is created with the PV as volume name, and the
+//     staging PVC is deleted.
+//  3. ClaimPV binds the released PV to the PVC of the user.
+//  4. RestoreReclaimPolicy puts the original reclaim policy back once bound.
+//
+// annotationReclaimPolicy keeps the original reclaim policy meanwhile.
+const annotationReclaimPolicy = "harvesterhci.io/datamover-reclaim-policy"
+
+// RetainPV sets the Retain reclaim policy on the PV of a staging PVC, and
+// records the original one.
+func RetainPV(pvClient ctlcorev1.PersistentVolumeClient, pv *corev1.PersistentVolume) error {
+	if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return nil
+	}
+
+	pvCpy := pv.DeepCopy()
+	if pvCpy.Annotations == nil {
+		pvCpy.Annotations = map[string]string{}
+	}
+	pvCpy.Annotations[annotationReclaimPolicy] = string(pv.Spec.PersistentVolumeReclaimPolicy)
+	pvCpy.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	if _, err := pvClient.Update(pvCpy); err != nil {
+		return fmt.Errorf("failed to retain PV %s: %w", pv.Name, err)
+	}
+	return nil
+}
+
+// ClaimPV binds the PV released by its staging PVC to the PVC. It must only
+// be called once the staging PVC is gone.
+func ClaimPV(pvClient ctlcorev1.PersistentVolumeClient, pv *corev1.PersistentVolume, pvc *corev1.PersistentVolumeClaim) error {
+	if ref := pv.Spec.ClaimRef; ref != nil && ref.Namespace == pvc.Namespace && ref.Name == pvc.Name {
+		return nil
+	}
+	if ref := pv.Spec.ClaimRef; ref != nil && ref.Namespace != Namespace {
+		return fmt.Errorf("PV %s is claimed by %s/%s", pv.Name, ref.Namespace, ref.Name)
+	}
+
+	pvCpy := pv.DeepCopy()
+	pvCpy.Spec.ClaimRef = &corev1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Namespace:  pvc.Namespace,
+		Name:       pvc.Name,
+		UID:        pvc.UID,
+	}
+	if _, err := pvClient.Update(pvCpy); err != nil {
+		return fmt.Errorf("failed to bind PV %s to PVC %s/%s: %w", pv.Name, pvc.Namespace, pvc.Name, err)
+	}
+	return nil
+}
+
+// RestoreReclaimPolicy puts back the reclaim policy RetainPV recorded.
+func RestoreReclaimPolicy(pvClient ctlcorev1.PersistentVolumeClient, pv *corev1.PersistentVolume) error {
+	policy, ok := pv.Annotations[annotationReclaimPolicy]
+	if !ok {
+		return nil
+	}
+
+	pvCpy := pv.DeepCopy()
+	delete(pvCpy.Annotations, annotationReclaimPolicy)
+	pvCpy.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimPolicy(policy)
+	if _, err := pvClient.Update(pvCpy); err != nil {
+		return fmt.Errorf("failed to restore the reclaim policy of PV %s: %w", pv.Name, err)
+	}
+	return nil
+}
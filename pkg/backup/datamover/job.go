@@ -0,0 +1,580 @@
+package datamover
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+	utilHelm "github.com/harvester/harvester/pkg/util/helm"
+)
+
+const (
+	// BinaryName is the data mover binary shipped in the Harvester image.
+	BinaryName = "harvester-datamover"
+
+	CommandUpload   = "upload"
+	CommandDownload = "download"
+	CommandCopy     = "copy"
+	CommandBrowse   = "browse"
+
+	// BrowsePort is the port the browse helper pod serves the files on.
+	BrowsePort = 8080
+	// EnvBrowseToken carries the token the browse helper requires on every
+	// request but the health check. It's injected from the token secret of
+	// the session, which the API server reads to proxy the requests.
+	EnvBrowseToken = "BROWSE_TOKEN"
+	// BrowseTokenKey is the key of the token in the token secret.
+	BrowseTokenKey = "token"
+	// BrowseMaxTTL is the longest a browse session may live.
+	BrowseMaxTTL = 24 * time.Hour
+
+	// EnvBackupTarget carries the JSON encoded backup target without its
+	// credentials, which are injected from the credential secret instead.
+	EnvBackupTarget = "BACKUP_TARGET"
+	// EnvDestinationBackupTarget is the backup target a copy Job writes to.
+	// Its credentials are injected with DestinationEnvPrefix.
+	EnvDestinationBackupTarget = "DESTINATION_BACKUP_TARGET"
+	DestinationEnvPrefix       = "DESTINATION_"
+	// EnvEncryptionKey carries the encryption key of the backup target, it's
+	// injected from the encryption key secret which is already in Namespace.
+	// The one of the destination of a copy has the DestinationEnvPrefix.
+	EnvEncryptionKey = "BACKUP_ENCRYPTION_KEY"
+
+	// LabelVMBackup, LabelVMRestore and LabelVMBackupCopy point a data mover
+	// Job back to the object it works for, so the controllers can enqueue it on Job changes.
+	LabelVMBackup     = "harvesterhci.io/datamover-vmbackup"
+	LabelVMRestore    = "harvesterhci.io/datamover-vmrestore"
+	LabelVMBackupCopy = "harvesterhci.io/datamover-vmbackupcopy"
+	// LabelBackupBrowseSession points the Jobs and the helper pod of a
+	// browse session back to the session.
+	LabelBackupBrowseSession = "harvesterhci.io/datamover-backupbrowsesession"
+	// LabelNamespace records the namespace of the object a data mover
+	// resource works for, because they all live in Namespace.
+	LabelNamespace = "harvesterhci.io/datamover-namespace"
+
+	containerName    = "datamover"
+	volumeName       = "volume"
+	volumeDevicePath = "/dev/harvester-volume"
+	volumeMountPath  = "/volume"
+	// the browse helper mounts the filesystems of the volume below browseMountPath
+	browseMountPath   = "/browse"
+	browseTokenSuffix = "browse-token"
+	browseTokenBytes  = 32
+	// KubeVirt stores the disk of a filesystem mode PVC in this file.
+	diskImageFileName = "disk.img"
+
+	jobBackoffLimit = 3
+
+	credentialSecretPrefix = "harvester-datamover-credentials"
+)
+
+// Namespace is where the data mover Jobs and everything they use run,
+// whatever the namespace of the backed up or restored volumes. The backup
+// target credentials thus never leave the system namespace, and the Jobs
+// which mount NFS targets by themselves never run privileged in user namespaces.
+const Namespace = util.HarvesterSystemNamespaceName
+
+// ResourceName returns the name of a data mover resource working for the
+// object in namespace. The namespace is part of the name, because the
+// resources of all namespaces share Namespace.
+func ResourceName(namespace, objectName, suffix string) string {
+	return name.SafeConcatName(namespace, objectName, suffix)
+}
+
+// JobOptions describes a data mover Job working on a single PVC in Namespace.
+type JobOptions struct {
+	Name   string
+	Labels map[string]string
+	Image  settings.Image
+	Target *settings.BackupTarget
+	// CredentialSecretName refers a secret created by EnsureCredentialSecret.
+	CredentialSecretName string
+	PVCName              string
+	VolumeMode           *corev1.PersistentVolumeMode
+	Command              string
+	ExportPath           string
+	// BaseExportPath is the previous export of the volume an upload is incremental to.
+	BaseExportPath string
+	ProgressPath   string
+}
+
+// CopyJobOptions describes a data mover Job in Namespace copying backups
+// between two backup targets.
+type CopyJobOptions struct {
+	Name        string
+	Labels      map[string]string
+	Image       settings.Image
+	Source      *settings.BackupTarget
+	Destination *settings.BackupTarget
+	// SourceCredentialSecretName and DestinationCredentialSecretName refer
+	// secrets created by EnsureCredentialSecret.
+	SourceCredentialSecretName      string
+	DestinationCredentialSecretName string
+	ExportPaths                     []string
+	LonghornBackups                 []LonghornBackup
+	ProgressPath                    string
+}
+
+// GetImage returns the Harvester image, which ships the data mover binary.
+func GetImage(clientset kubernetes.Interface) (settings.Image, error) {
+	return utilHelm.FetchImageFromHelmValues(
+		clientset,
+		util.FleetLocalNamespaceName,
+		util.HarvesterChartReleaseName,
+		[]string{"containers", "apiserver", "image"},
+	)
+}
+
+// GetVolumePath returns where the volume content is exposed inside the data mover container.
+func GetVolumePath(volumeMode *corev1.PersistentVolumeMode) string {
+	if volumeMode != nil && *volumeMode == corev1.PersistentVolumeBlock {
+		return volumeDevicePath
+	}
+	return filepath.Join(volumeMountPath, diskImageFileName)
+}
+
+// EnsureCredentialSecret keeps a copy of the backup target credentials in
+// Namespace for the data mover Jobs, and refreshes it when they change. The
+// copy is shared by all the Jobs of the backup target. It returns an empty
+// name if the backup target doesn't need any credentials.
+func EnsureCredentialSecret(
+	secretCache ctlcorev1.SecretCache,
+	secretClient ctlcorev1.SecretClient,
+	target *settings.BackupTarget,
+) (string, error) {
+	if target.Type != settings.S3BackupType {
+		return "", nil
+	}
+
+	credentials, err := backuputil.GetBackupTargetCredentials(secretCache, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to get backup target credentials: %w", err)
+	}
+
+	secretName := credentialSecretName(target)
+	secret, err := secretCache.Get(Namespace, secretName)
+	if apierrors.IsNotFound(err) {
+		secret = buildCredentialSecret(secretName, credentials)
+		if _, err := secretClient.Create(secret); err != nil && !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create credential secret %s/%s: %w", Namespace, secretName, err)
+		}
+		return secretName, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if reflect.DeepEqual(secret.Data, credentials) {
+		return secretName, nil
+	}
+	secretCpy := secret.DeepCopy()
+	secretCpy.Data = credentials
+	if _, err := secretClient.Update(secretCpy); err != nil {
+		return "", fmt.Errorf("failed to update credential secret %s/%s: %w", Namespace, secretName, err)
+	}
+	return secretName, nil
+}
+
+// credentialSecretName returns the name of the credential copy of a
+// BackupTarget, or of the backup-target setting which has no name.
+func credentialSecretName(target *settings.BackupTarget) string {
+	if target.Name == "" {
+		return credentialSecretPrefix
+	}
+	return name.SafeConcatName(credentialSecretPrefix, target.Name)
+}
+
+func buildCredentialSecret(name string, credentials map[string][]byte) *corev1.Secret {
+	data := map[string][]byte{}
+	for k, v := range credentials {
+		data[k] = v
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels: map[string]string{
+				util.LabelGeneratedBy: util.ValueGeneratedByHarvester,
+			},
+		},
+		Data: data,
+	}
+}
+
+func BuildJob(opts JobOptions) (*batchv1.Job, error) {
+	if opts.Target == nil {
+		return nil, fmt.Errorf("backup target is required for data mover job %s/%s", Namespace, opts.Name)
+	}
+
+	targetJSON, err := encodeTarget(opts.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		opts.Command,
+		"--volume", GetVolumePath(opts.VolumeMode),
+		"--export-path", opts.ExportPath,
+	}
+	if opts.BaseExportPath != "" {
+		args = append(args, "--base-export-path", opts.BaseExportPath)
+	}
+	if opts.ProgressPath != "" {
+		args = append(args, "--progress-path", opts.ProgressPath)
+	}
+
+	container := corev1.Container{
+		Name:            containerName,
+		Image:           opts.Image.ImageName(),
+		ImagePullPolicy: opts.Image.GetImagePullPolicy(),
+		Command:         []string{BinaryName},
+		Args:            args,
+		Env: appendEncryptionKeyEnv([]corev1.EnvVar{{
+			Name:  EnvBackupTarget,
+			Value: targetJSON,
+		}}, opts.Target, ""),
+		SecurityContext: &corev1.SecurityContext{
+			// The backupstore NFS driver mounts the export by itself, which is
+			// why the Jobs only run in Namespace.
+			Privileged: ptr.To(opts.Target.Type == settings.NFSBackupType),
+		},
+	}
+	if opts.CredentialSecretName != "" {
+		container.EnvFrom = []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: opts.CredentialSecretName},
+			},
+		}}
+	}
+	if opts.VolumeMode != nil && *opts.VolumeMode == corev1.PersistentVolumeBlock {
+		container.VolumeDevices = []corev1.VolumeDevice{{
+			Name:       volumeName,
+			DevicePath: volumeDevicePath,
+		}}
+	} else {
+		container.VolumeMounts = []corev1.VolumeMount{{
+			Name:      volumeName,
+			MountPath: volumeMountPath,
+		}}
+	}
+
+	return buildJob(opts.Name, opts.Labels, container, []corev1.Volume{{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: opts.PVCName,
+			},
+		},
+	}}), nil
+}
+
+// BuildCopyJob builds a Job copying the exports and Longhorn backups from
+// the source to the destination backup target.
+func BuildCopyJob(opts CopyJobOptions) (*batchv1.Job, error) {
+	if opts.Source == nil || opts.Destination == nil {
+		return nil, fmt.Errorf("source and destination backup targets are required for data mover job %s/%s", Namespace, opts.Name)
+	}
+
+	sourceJSON, err := encodeTarget(opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	destinationJSON, err := encodeTarget(opts.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{CommandCopy}
+	for _, exportPath := range opts.ExportPaths {
+		args = append(args, "--export-path", exportPath)
+	}
+	for _, b := range opts.LonghornBackups {
+		args = append(args, "--longhorn-backup", b.String())
+	}
+	if opts.ProgressPath != "" {
+		args = append(args, "--progress-path", opts.ProgressPath)
+	}
+
+	container := corev1.Container{
+		Name:            containerName,
+		Image:           opts.Image.ImageName(),
+		ImagePullPolicy: opts.Image.GetImagePullPolicy(),
+		Command:         []string{BinaryName},
+		Args:            args,
+		Env: []corev1.EnvVar{
+			{Name: EnvBackupTarget, Value: sourceJSON},
+			{Name: EnvDestinationBackupTarget, Value: destinationJSON},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: ptr.To(opts.Source.Type == settings.NFSBackupType || opts.Destination.Type == settings.NFSBackupType),
+		},
+	}
+	container.Env = appendEncryptionKeyEnv(container.Env, opts.Source, "")
+	container.Env = appendEncryptionKeyEnv(container.Env, opts.Destination, DestinationEnvPrefix)
+	if opts.SourceCredentialSecretName != "" {
+		container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: opts.SourceCredentialSecretName},
+			},
+		})
+	}
+	if opts.DestinationCredentialSecretName != "" {
+		container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+			Prefix: DestinationEnvPrefix,
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: opts.DestinationCredentialSecretName},
+			},
+		})
+	}
+
+	return buildJob(opts.Name, opts.Labels, container, nil), nil
+}
+
+// appendEncryptionKeyEnv adds the encryption key of the backup target, if any,
+// as EnvEncryptionKey with the prefix.
+func appendEncryptionKeyEnv(env []corev1.EnvVar, target *settings.BackupTarget, prefix string) []corev1.EnvVar {
+	if target.EncryptionKeySecret == "" {
+		return env
+	}
+	return append(env, corev1.EnvVar{
+		Name: prefix + EnvEncryptionKey,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: target.EncryptionKeySecret},
+				Key:                  backuputil.EncryptionKeySecretKey,
+			},
+		},
+	})
+}
+
+// BrowsePodOptions describes the helper pod in Namespace serving the files of a PVC.
+type BrowsePodOptions struct {
+	Name       string
+	Labels     map[string]string
+	Image      settings.Image
+	PVCName    string
+	VolumeMode *corev1.PersistentVolumeMode
+	// TokenSecretName refers a secret in Namespace created by BuildBrowseTokenSecret.
+	TokenSecretName string
+}
+
+// BrowseTokenSecretName returns the name of the token secret of a browse helper pod.
+func BrowseTokenSecretName(podName string) string {
+	return name.SafeConcatName(podName, browseTokenSuffix)
+}
+
+// BuildBrowseTokenSecret builds the secret in Namespace holding a random
+// token the browse helper requires, so only the API server can fetch files from it.
+func BuildBrowseTokenSecret(name string, labels map[string]string) (*corev1.Secret, error) {
+	token := make([]byte, browseTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate browse token: %w", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels:    generatedLabels(labels),
+		},
+		Data: map[string][]byte{
+			BrowseTokenKey: []byte(hex.EncodeToString(token)),
+		},
+	}, nil
+}
+
+// BuildBrowseNetworkPolicy builds the policy in Namespace only letting the
+// pods of the Harvester namespace, where the API server runs, reach the
+// helper pod selected by the labels.
+func BuildBrowseNetworkPolicy(name string, podLabels map[string]string, harvesterNamespace string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels:    generatedLabels(podLabels),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{corev1.LabelMetadataName: harvesterNamespace},
+					},
+				}},
+				Ports: []networkingv1.NetworkPolicyPort{{
+					Protocol: ptr.To(corev1.ProtocolTCP),
+					Port:     ptr.To(intstr.FromInt32(BrowsePort)),
+				}},
+			}},
+		},
+	}
+}
+
+// BuildBrowsePod builds the pod mounting the filesystems of the PVC
+// read-only and serving them on BrowsePort. It runs privileged, which is why
+// it only runs in Namespace: mounting the partitions needs the loop devices
+// of the host, and the default AppArmor and seccomp profiles deny mount.
+func BuildBrowsePod(opts BrowsePodOptions) *corev1.Pod {
+	labels := generatedLabels(opts.Labels)
+
+	container := corev1.Container{
+		Name:            containerName,
+		Image:           opts.Image.ImageName(),
+		ImagePullPolicy: opts.Image.GetImagePullPolicy(),
+		Command:         []string{BinaryName},
+		Args: []string{
+			CommandBrowse,
+			"--volume", GetVolumePath(opts.VolumeMode),
+			"--mount-path", browseMountPath,
+			"--port", strconv.Itoa(BrowsePort),
+		},
+		Env: []corev1.EnvVar{{
+			Name: EnvBrowseToken,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: opts.TokenSecretName},
+					Key:                  BrowseTokenKey,
+				},
+			},
+		}},
+		Ports: []corev1.ContainerPort{{
+			Name:          "http",
+			ContainerPort: BrowsePort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: BrowseHealthPath,
+					Port: intstr.FromInt32(BrowsePort),
+				},
+			},
+		},
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: ptr.To(true),
+		},
+	}
+	if opts.VolumeMode != nil && *opts.VolumeMode == corev1.PersistentVolumeBlock {
+		container.VolumeDevices = []corev1.VolumeDevice{{
+			Name:       volumeName,
+			DevicePath: volumeDevicePath,
+		}}
+	} else {
+		container.VolumeMounts = []corev1.VolumeMount{{
+			Name:      volumeName,
+			MountPath: volumeMountPath,
+			ReadOnly:  true,
+		}}
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      opts.Name,
+			Namespace: Namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:                corev1.RestartPolicyNever,
+			AutomountServiceAccountToken: ptr.To(false),
+			Containers:                   []corev1.Container{container},
+			Volumes: []corev1.Volume{{
+				Name: volumeName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: opts.PVCName,
+						ReadOnly:  true,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// encodeTarget returns the JSON encoded backup target. Never hand the
+// credentials over in plain text, they come from the credential secret.
+func encodeTarget(t *settings.BackupTarget) (string, error) {
+	target := *t
+	target.AccessKeyID = ""
+	target.SecretAccessKey = ""
+	targetJSON, err := json.Marshal(target)
+	if err != nil {
+		return "", err
+	}
+	return string(targetJSON), nil
+}
+
+// buildJob builds a Job in Namespace. It has no owner, because owner
+// references can't cross namespaces, the labels point back to it instead.
+func buildJob(name string, jobLabels map[string]string, container corev1.Container, volumes []corev1.Volume) *batchv1.Job {
+	labels := generatedLabels(jobLabels)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.To(int32(jobBackoffLimit)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					// The data mover never talks to the Kubernetes API.
+					AutomountServiceAccountToken: ptr.To(false),
+					Containers:                   []corev1.Container{container},
+					Volumes:                      volumes,
+				},
+			},
+		},
+	}
+}
+
+// generatedLabels returns the labels on top of the generated-by label of
+// the data mover resources.
+func generatedLabels(labels map[string]string) map[string]string {
+	generated := map[string]string{
+		util.LabelGeneratedBy: util.ValueGeneratedByHarvester,
+	}
+	for k, v := range labels {
+		generated[k] = v
+	}
+	return generated
+}
+
+// IsJobFinished returns whether the Job completed or failed, and the failure
+// message in the latter case.
+func IsJobFinished(job *batchv1.Job) (finished bool, failure string) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, ""
+		case batchv1.JobFailed:
+			return true, fmt.Sprintf("data mover job %s/%s failed: %s", job.Namespace, job.Name, c.Message)
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,217 @@
+package datamover
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestBuildBrowsePod(t *testing.T) {
+	var testCases = []struct {
+		name       string
+		volumeMode *corev1.PersistentVolumeMode
+		block      bool
+	}{
+		{
+			name:       "filesystem pvc is mounted read-only",
+			volumeMode: ptr.To(corev1.PersistentVolumeFilesystem),
+		},
+		{
+			name:       "block pvc is attached as a device",
+			volumeMode: ptr.To(corev1.PersistentVolumeBlock),
+			block:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := BuildBrowsePod(BrowsePodOptions{
+				Name:            "default-session-browse",
+				Labels:          map[string]string{LabelBackupBrowseSession: "session", LabelNamespace: "default"},
+				Image:           settings.Image{Repository: "rancher/harvester", Tag: "master"},
+				PVCName:         "default-session-browse",
+				VolumeMode:      tc.volumeMode,
+				TokenSecretName: "default-session-browse-token",
+			})
+
+			assert.Equal(t, Namespace, pod.Namespace)
+			assert.Equal(t, "session", pod.Labels[LabelBackupBrowseSession])
+			assert.Equal(t, "default", pod.Labels[LabelNamespace])
+			assert.False(t, *pod.Spec.AutomountServiceAccountToken)
+			require.Len(t, pod.Spec.Volumes, 1)
+			assert.True(t, pod.Spec.Volumes[0].PersistentVolumeClaim.ReadOnly)
+
+			require.Len(t, pod.Spec.Containers, 1)
+			container := pod.Spec.Containers[0]
+			assert.Equal(t, "rancher/harvester:master", container.Image)
+
+			// mounting through loop devices needs them and no AppArmor or seccomp confinement
+			assert.True(t, *container.SecurityContext.Privileged)
+
+			require.Len(t, container.Env, 1)
+			assert.Equal(t, EnvBrowseToken, container.Env[0].Name)
+			assert.Equal(t, "default-session-browse-token", container.Env[0].ValueFrom.SecretKeyRef.Name)
+			assert.Equal(t, BrowseTokenKey, container.Env[0].ValueFrom.SecretKeyRef.Key)
+			assert.Equal(t, BrowseHealthPath, container.ReadinessProbe.HTTPGet.Path)
+
+			if tc.block {
+				assert.Empty(t, container.VolumeMounts)
+				require.Len(t, container.VolumeDevices, 1)
+				assert.Contains(t, container.Args, volumeDevicePath)
+			} else {
+				assert.Empty(t, container.VolumeDevices)
+				require.Len(t, container.VolumeMounts, 1)
+				assert.True(t, container.VolumeMounts[0].ReadOnly)
+			}
+		})
+	}
+}
+
+func TestBuildBrowseTokenSecret(t *testing.T) {
+	first, err := BuildBrowseTokenSecret("token", nil)
+	require.NoError(t, err)
+	second, err := BuildBrowseTokenSecret("token", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, Namespace, first.Namespace)
+
+	assert.Len(t, first.Data[BrowseTokenKey], 2*browseTokenBytes)
+	assert.NotEqual(t, first.Data[BrowseTokenKey], second.Data[BrowseTokenKey])
+}
+
+func TestBuildBrowseNetworkPolicy(t *testing.T) {
+	podLabels := map[string]string{LabelBackupBrowseSession: "session", LabelNamespace: "default"}
+	policy := BuildBrowseNetworkPolicy("default-session-browse", podLabels, "harvester-system")
+
+	assert.Equal(t, Namespace, policy.Namespace)
+	assert.Equal(t, podLabels, policy.Spec.PodSelector.MatchLabels)
+	require.Len(t, policy.Spec.Ingress, 1)
+	require.Len(t, policy.Spec.Ingress[0].From, 1)
+	assert.Nil(t, policy.Spec.Ingress[0].From[0].PodSelector)
+	assert.Equal(t, map[string]string{corev1.LabelMetadataName: "harvester-system"}, policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels)
+	require.Len(t, policy.Spec.Ingress[0].Ports, 1)
+	assert.Equal(t, int32(BrowsePort), policy.Spec.Ingress[0].Ports[0].Port.IntVal)
+}
+
+func TestBuildJob(t *testing.T) {
+	var testCases = []struct {
+		name                string
+		targetType          settings.TargetType
+		encryptionKeySecret string
+		privileged          bool
+	}{
+		{
+			name:                "s3 job is not privileged",
+			targetType:          settings.S3BackupType,
+			encryptionKeySecret: "backup-encryption-key",
+		},
+		{
+			name:       "nfs job is privileged to mount the export",
+			targetType: settings.NFSBackupType,
+			privileged: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, err := BuildJob(JobOptions{
+				Name:                 "default-backup-export",
+				Labels:               map[string]string{LabelVMBackup: "backup", LabelNamespace: "default"},
+				Image:                settings.Image{Repository: "rancher/harvester", Tag: "master"},
+				Target:               &settings.BackupTarget{Type: tc.targetType, SecretAccessKey: "topsecret", EncryptionKeySecret: tc.encryptionKeySecret},
+				CredentialSecretName: "credentials",
+				PVCName:              "default-backup-export",
+				Command:              CommandUpload,
+				ExportPath:           "export",
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, Namespace, job.Namespace)
+			assert.Empty(t, job.OwnerReferences)
+			assert.Equal(t, "default", job.Labels[LabelNamespace])
+			assert.False(t, *job.Spec.Template.Spec.AutomountServiceAccountToken)
+
+			container := job.Spec.Template.Spec.Containers[0]
+			assert.Equal(t, tc.privileged, *container.SecurityContext.Privileged)
+			assert.NotContains(t, container.Env[0].Value, "topsecret")
+			require.Len(t, container.EnvFrom, 1)
+			assert.Equal(t, "credentials", container.EnvFrom[0].SecretRef.Name)
+
+			if tc.encryptionKeySecret == "" {
+				assert.Len(t, container.Env, 1)
+				return
+			}
+			require.Len(t, container.Env, 2)
+			assert.Equal(t, EnvEncryptionKey, container.Env[1].Name)
+			assert.Equal(t, tc.encryptionKeySecret, container.Env[1].ValueFrom.SecretKeyRef.Name)
+		})
+	}
+}
+
+func TestEnsureCredentialSecret(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "target-credentials", Namespace: Namespace},
+		Data:       map[string][]byte{util.AWSAccessKey: []byte("key"), util.AWSSecretKey: []byte("secret")},
+	})
+	secretCache := fakeclients.SecretCache(clientset.CoreV1().Secrets)
+	secretClient := fakeclients.SecretClient(clientset.CoreV1().Secrets)
+	target := &settings.BackupTarget{Name: "offsite", Type: settings.S3BackupType, Endpoint: "https://s3", CredentialSecret: "target-credentials"}
+
+	secretName, err := EnsureCredentialSecret(secretCache, secretClient, target)
+	require.NoError(t, err)
+	secret, err := secretCache.Get(Namespace, secretName)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), secret.Data[util.AWSSecretKey])
+
+	// rotated credentials are copied again
+	source, err := secretCache.Get(Namespace, "target-credentials")
+	require.NoError(t, err)
+	source = source.DeepCopy()
+	source.Data[util.AWSSecretKey] = []byte("rotated")
+	_, err = secretClient.Update(source)
+	require.NoError(t, err)
+
+	_, err = EnsureCredentialSecret(secretCache, secretClient, target)
+	require.NoError(t, err)
+	secret, err = secretCache.Get(Namespace, secretName)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("rotated"), secret.Data[util.AWSSecretKey])
+
+	secretName, err = EnsureCredentialSecret(secretCache, secretClient, &settings.BackupTarget{Type: settings.NFSBackupType})
+	require.NoError(t, err)
+	assert.Empty(t, secretName)
+}
+
+func TestBuildCopyJob(t *testing.T) {
+	job, err := BuildCopyJob(CopyJobOptions{
+		Name:                            "default-copy-copy",
+		Labels:                          map[string]string{LabelVMBackupCopy: "copy", LabelNamespace: "default"},
+		Image:                           settings.Image{Repository: "rancher/harvester", Tag: "master"},
+		Source:                          &settings.BackupTarget{Type: settings.S3BackupType},
+		Destination:                     &settings.BackupTarget{Name: "local", Type: settings.NFSBackupType},
+		SourceCredentialSecretName:      "source-credentials",
+		DestinationCredentialSecretName: "destination-credentials",
+		ExportPaths:                     []string{"export"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Namespace, job.Namespace)
+	assert.Empty(t, job.OwnerReferences)
+	assert.Equal(t, "default", job.Labels[LabelNamespace])
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.True(t, *container.SecurityContext.Privileged)
+	require.Len(t, container.EnvFrom, 2)
+	assert.Equal(t, "source-credentials", container.EnvFrom[0].SecretRef.Name)
+	assert.Equal(t, DestinationEnvPrefix, container.EnvFrom[1].Prefix)
+	assert.Equal(t, "destination-credentials", container.EnvFrom[1].SecretRef.Name)
+}
@@ -0,0 +1,259 @@
+package datamover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	sectorSize = 512
+
+	mbrSignatureOffset  = 510
+	mbrPartitionOffset  = 446
+	mbrPartitionSize    = 16
+	mbrTypeGPTProtected = 0xee
+
+	gptSignature = "EFI PART"
+
+	// WholeDiskPartitionName names the filesystem of a volume without partition table.
+	WholeDiskPartitionName = "disk"
+)
+
+var mbrExtendedTypes = map[byte]bool{0x05: true, 0x0f: true, 0x85: true}
+
+// Partition is a partition of a volume, or the whole volume if it holds a
+// filesystem without partition table.
+type Partition struct {
+	// Name is p<number>, or WholeDiskPartitionName
+	Name       string `json:"name"`
+	Number     int    `json:"number"`
+	Offset     int64  `json:"offset"`
+	Size       int64  `json:"size"`
+	Filesystem string `json:"filesystem,omitempty"`
+	Mounted    bool   `json:"mounted"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DetectPartitions reads the GPT or MBR partition table of the volume and
+// probes the filesystem of each partition. Logical partitions of an MBR
+// extended partition are numbered from 5, like Linux does.
+func DetectPartitions(r io.ReaderAt, size int64) ([]Partition, error) {
+	if fs := ProbeFilesystem(r, 0); fs != "" {
+		return []Partition{{Name: WholeDiskPartitionName, Size: size, Filesystem: fs}}, nil
+	}
+
+	partitions, err := readGPT(r)
+	if errors.Is(err, errNoPartitionTable) {
+		partitions, err = readMBR(r)
+	}
+	if errors.Is(err, errNoPartitionTable) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for i := range partitions {
+		p := &partitions[i]
+		p.Name = fmt.Sprintf("p%d", p.Number)
+		if p.Offset+p.Size > size {
+			p.Error = "partition exceeds the volume"
+			continue
+		}
+		p.Filesystem = ProbeFilesystem(io.NewSectionReader(r, p.Offset, p.Size), 0)
+	}
+	return partitions, nil
+}
+
+var errNoPartitionTable = errors.New("no partition table")
+
+// readGPT reads the primary GPT. Checksums aren't verified, the volume is
+// only ever mounted read-only.
+func readGPT(r io.ReaderAt) ([]Partition, error) {
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, sectorSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errNoPartitionTable
+		}
+		return nil, err
+	}
+	if string(header[:8]) != gptSignature {
+		return nil, errNoPartitionTable
+	}
+
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:80]))
+	count := binary.LittleEndian.Uint32(header[80:84])
+	entrySize := binary.LittleEndian.Uint32(header[84:88])
+	if entrySize < 128 || count > 1024 {
+		return nil, fmt.Errorf("invalid GPT header: %d entries of %d bytes", count, entrySize)
+	}
+
+	entries := make([]byte, int64(count)*int64(entrySize))
+	if _, err := r.ReadAt(entries, entriesLBA*sectorSize); err != nil {
+		return nil, fmt.Errorf("failed to read GPT entries: %w", err)
+	}
+
+	var partitions []Partition
+	for i := uint32(0); i < count; i++ {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		if bytes.Equal(entry[:16], make([]byte, 16)) {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(entry[32:40]))
+		last := int64(binary.LittleEndian.Uint64(entry[40:48]))
+		if last < first {
+			continue
+		}
+		partitions = append(partitions, Partition{
+			Number: int(i) + 1,
+			Offset: first * sectorSize,
+			Size:   (last - first + 1) * sectorSize,
+		})
+	}
+	return partitions, nil
+}
+
+type mbrEntry struct {
+	partType byte
+	start    int64
+	sectors  int64
+}
+
+func readMBRSector(r io.ReaderAt, offset int64) ([4]mbrEntry, error) {
+	var entries [4]mbrEntry
+	sector := make([]byte, sectorSize)
+	if _, err := r.ReadAt(sector, offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			return entries, errNoPartitionTable
+		}
+		return entries, err
+	}
+	if sector[mbrSignatureOffset] != 0x55 || sector[mbrSignatureOffset+1] != 0xaa {
+		return entries, errNoPartitionTable
+	}
+	for i := range entries {
+		e := sector[mbrPartitionOffset+i*mbrPartitionSize : mbrPartitionOffset+(i+1)*mbrPartitionSize]
+		entries[i] = mbrEntry{
+			partType: e[4],
+			start:    int64(binary.LittleEndian.Uint32(e[8:12])),
+			sectors:  int64(binary.LittleEndian.Uint32(e[12:16])),
+		}
+	}
+	return entries, nil
+}
+
+func readMBR(r io.ReaderAt) ([]Partition, error) {
+	entries, err := readMBRSector(r, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var partitions []Partition
+	for i, e := range entries {
+		switch {
+		case e.partType == 0 || e.sectors == 0:
+		case e.partType == mbrTypeGPTProtected:
+			return nil, fmt.Errorf("protective MBR without GPT")
+		case mbrExtendedTypes[e.partType]:
+			logical, err := readLogicalPartitions(r, e.start)
+			if err != nil {
+				return nil, err
+			}
+			partitions = append(partitions, logical...)
+		default:
+			partitions = append(partitions, Partition{
+				Number: i + 1,
+				Offset: e.start * sectorSize,
+				Size:   e.sectors * sectorSize,
+			})
+		}
+	}
+	return partitions, nil
+}
+
+// readLogicalPartitions follows the chain of extended boot records. The
+// logical partition is relative to its EBR, the next EBR to the extended
+// partition.
+func readLogicalPartitions(r io.ReaderAt, extendedStart int64) ([]Partition, error) {
+	const maxLogicalPartitions = 128
+
+	var partitions []Partition
+	ebr := extendedStart
+	for number := 5; number < 5+maxLogicalPartitions; number++ {
+		entries, err := readMBRSector(r, ebr*sectorSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read extended boot record at sector %d: %w", ebr, err)
+		}
+		if entries[0].sectors > 0 {
+			partitions = append(partitions, Partition{
+				Number: number,
+				Offset: (ebr + entries[0].start) * sectorSize,
+				Size:   entries[0].sectors * sectorSize,
+			})
+		}
+		if entries[1].sectors == 0 {
+			return partitions, nil
+		}
+		ebr = extendedStart + entries[1].start
+	}
+	return partitions, nil
+}
+
+type filesystemMagic struct {
+	filesystem string
+	offset     int64
+	magic      string
+}
+
+var filesystemMagics = []filesystemMagic{
+	{filesystem: "xfs", offset: 0, magic: "XFSB"},
+	{filesystem: "ntfs", offset: 3, magic: "NTFS    "},
+	{filesystem: "vfat", offset: 82, magic: "FAT32   "},
+	{filesystem: "vfat", offset: 54, magic: "FAT16   "},
+	{filesystem: "vfat", offset: 54, magic: "FAT12   "},
+	{filesystem: "LVM2_member", offset: 512 + 24, magic: "LVM2 001"},
+	{filesystem: "swap", offset: 4096 - 10, magic: "SWAPSPACE2"},
+	{filesystem: "iso9660", offset: 0x8001, magic: "CD001"},
+	{filesystem: "btrfs", offset: 0x10040, magic: "_BHRfS_M"},
+}
+
+const (
+	extSuperblockOffset   = 1024
+	extMagic              = 0xef53
+	extCompatHasJournal   = 0x4
+	extIncompatExtents    = 0x40
+	extIncompatFlexBg     = 0x200
+	extIncompatFeature64b = 0x80
+)
+
+// ProbeFilesystem returns the type of the filesystem at the offset, as
+// reported by blkid, or an empty string if it isn't known.
+func ProbeFilesystem(r io.ReaderAt, offset int64) string {
+	superblock := make([]byte, 104)
+	if _, err := r.ReadAt(superblock, offset+extSuperblockOffset); err == nil &&
+		binary.LittleEndian.Uint16(superblock[56:58]) == extMagic {
+		compat := binary.LittleEndian.Uint32(superblock[92:96])
+		incompat := binary.LittleEndian.Uint32(superblock[96:100])
+		switch {
+		case incompat&(extIncompatExtents|extIncompatFlexBg|extIncompatFeature64b) != 0:
+			return "ext4"
+		case compat&extCompatHasJournal != 0:
+			return "ext3"
+		default:
+			return "ext2"
+		}
+	}
+
+	for _, m := range filesystemMagics {
+		buf := make([]byte, len(m.magic))
+		if _, err := r.ReadAt(buf, offset+m.offset); err != nil {
+			continue
+		}
+		if string(buf) == m.magic {
+			return m.filesystem
+		}
+	}
+	return ""
+}
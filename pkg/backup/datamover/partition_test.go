@@ -0,0 +1,112 @@
+package datamover
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDiskSize = 4 << 20
+
+func writeExt4(disk []byte, offset int64) {
+	sb := disk[offset+extSuperblockOffset:]
+	binary.LittleEndian.PutUint16(sb[56:58], extMagic)
+	binary.LittleEndian.PutUint32(sb[92:96], extCompatHasJournal)
+	binary.LittleEndian.PutUint32(sb[96:100], extIncompatExtents)
+}
+
+func writeMBREntry(disk []byte, sector int64, index int, partType byte, start, sectors uint32) {
+	e := disk[sector*sectorSize+mbrPartitionOffset+int64(index)*mbrPartitionSize:]
+	e[4] = partType
+	binary.LittleEndian.PutUint32(e[8:12], start)
+	binary.LittleEndian.PutUint32(e[12:16], sectors)
+	disk[sector*sectorSize+mbrSignatureOffset] = 0x55
+	disk[sector*sectorSize+mbrSignatureOffset+1] = 0xaa
+}
+
+func TestDetectPartitions(t *testing.T) {
+	t.Run("whole disk filesystem", func(t *testing.T) {
+		disk := make([]byte, testDiskSize)
+		writeExt4(disk, 0)
+
+		partitions, err := DetectPartitions(bytes.NewReader(disk), testDiskSize)
+		require.NoError(t, err)
+		assert.Equal(t, []Partition{{Name: WholeDiskPartitionName, Size: testDiskSize, Filesystem: "ext4"}}, partitions)
+	})
+
+	t.Run("empty disk", func(t *testing.T) {
+		partitions, err := DetectPartitions(bytes.NewReader(make([]byte, testDiskSize)), testDiskSize)
+		require.NoError(t, err)
+		assert.Empty(t, partitions)
+	})
+
+	t.Run("MBR with logical partitions", func(t *testing.T) {
+		disk := make([]byte, testDiskSize)
+		// p1 at sector 2048, extended partition at sector 4096 holding p5 and p6
+		writeMBREntry(disk, 0, 0, 0x83, 2048, 1024)
+		writeMBREntry(disk, 0, 1, 0x05, 4096, 2048)
+		writeMBREntry(disk, 4096, 0, 0x83, 64, 512)
+		writeMBREntry(disk, 4096, 1, 0x05, 1024, 1024)
+		writeMBREntry(disk, 5120, 0, 0x82, 64, 512)
+		copy(disk[2048*sectorSize+3:], "NTFS    ")
+		writeExt4(disk, 4160*sectorSize)
+
+		partitions, err := DetectPartitions(bytes.NewReader(disk), testDiskSize)
+		require.NoError(t, err)
+		assert.Equal(t, []Partition{
+			{Name: "p1", Number: 1, Offset: 2048 * sectorSize, Size: 1024 * sectorSize, Filesystem: "ntfs"},
+			{Name: "p5", Number: 5, Offset: 4160 * sectorSize, Size: 512 * sectorSize, Filesystem: "ext4"},
+			{Name: "p6", Number: 6, Offset: 5184 * sectorSize, Size: 512 * sectorSize},
+		}, partitions)
+	})
+
+	t.Run("GPT", func(t *testing.T) {
+		disk := make([]byte, testDiskSize)
+		writeMBREntry(disk, 0, 0, mbrTypeGPTProtected, 1, testDiskSize/sectorSize-1)
+		header := disk[sectorSize:]
+		copy(header, gptSignature)
+		binary.LittleEndian.PutUint64(header[72:80], 2)
+		binary.LittleEndian.PutUint32(header[80:84], 128)
+		binary.LittleEndian.PutUint32(header[84:88], 128)
+		// the second entry is unused
+		for i, lba := range map[int][2]uint64{0: {2048, 4095}, 2: {4096, 8191}} {
+			entry := disk[2*sectorSize+i*128:]
+			entry[0] = 1
+			binary.LittleEndian.PutUint64(entry[32:40], lba[0])
+			binary.LittleEndian.PutUint64(entry[40:48], lba[1])
+		}
+		copy(disk[4096*sectorSize:], "XFSB")
+
+		partitions, err := DetectPartitions(bytes.NewReader(disk), testDiskSize)
+		require.NoError(t, err)
+		assert.Equal(t, []Partition{
+			{Name: "p1", Number: 1, Offset: 2048 * sectorSize, Size: 2048 * sectorSize},
+			{Name: "p3", Number: 3, Offset: 4096 * sectorSize, Size: 4096 * sectorSize, Filesystem: "xfs"},
+		}, partitions)
+	})
+
+	t.Run("partition exceeding the volume", func(t *testing.T) {
+		disk := make([]byte, testDiskSize)
+		writeMBREntry(disk, 0, 0, 0x83, 2048, testDiskSize/sectorSize)
+
+		partitions, err := DetectPartitions(bytes.NewReader(disk), testDiskSize)
+		require.NoError(t, err)
+		require.Len(t, partitions, 1)
+		assert.NotEmpty(t, partitions[0].Error)
+	})
+}
+
+func TestCleanBrowsePath(t *testing.T) {
+	for p, expected := range map[string]string{
+		"":                  ".",
+		"/":                 ".",
+		"etc/hosts":         "etc/hosts",
+		"/etc/../etc/fstab": "etc/fstab",
+		"../../etc/passwd":  "etc/passwd",
+	} {
+		assert.Equal(t, expected, cleanBrowsePath(p), p)
+	}
+}
@@ -27,3 +27,10 @@ type BackupEngine interface {
 	// extra watchers should implement this as a no-op.
 	RegisterWatchers(ctx context.Context, enqueue func(namespace, name string))
 }
+
+// VolumeReleaser is implemented by the engines which change the volumes of a
+// VMBackup while backing them up. ReleaseVolumes undoes the changes when the
+// VMBackup is removed, whether or not its backup is done.
+type VolumeReleaser interface {
+	ReleaseVolumes(vmb *harvesterv1.VirtualMachineBackup) error
+}
@@ -0,0 +1,618 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/longhorn/backupstore"
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/backup/engine"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
+	"github.com/harvester/harvester/pkg/restore/pvchelper"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	backupProgressComplete = 100
+	exportJobWatcherName   = "snapshot-export-job-watcher"
+	exportSuffix           = "export"
+)
+
+// ExportEngine backs up volumes of any CSI driver. It takes an in-cluster
+// VolumeSnapshot, provisions a temporary PVC from it and runs a data mover
+// Job that copies the PVC content to the backup target. Once the export is
+// complete, the temporary resources including the VolumeSnapshot are removed,
+// so the backup only lives in the backup target.
+//
+// The data mover runs in datamover.Namespace, so the backup target
+// credentials stay there. It reaches the snapshot taken in the namespace of
+// the VM through a pre-provisioned VolumeSnapshotContent sharing its handle,
+// and a VolumeSnapshot and PVC in datamover.Namespace bound to it.
+//
+// Exports are incremental: the data mover compares the volume against the
+// export of the previous backup of the same VM and only uploads the blocks
+// which are not in the backup target yet.
+type ExportEngine struct {
+	vmbo          common.VMBackupOperator
+	vsHelper      *common.VolumeSnapshotHelper
+	vsClient      ctlsnapshotv1.VolumeSnapshotClient
+	vscClient     ctlsnapshotv1.VolumeSnapshotContentClient
+	pvcCache      ctlcorev1.PersistentVolumeClaimCache
+	pvcClient     ctlcorev1.PersistentVolumeClaimClient
+	secretCache   ctlcorev1.SecretCache
+	secretClient  ctlcorev1.SecretClient
+	vmbCache      ctlharvesterv1.VirtualMachineBackupCache
+	jobCache      ctlbatchv1.JobCache
+	jobController ctlbatchv1.JobController
+	clientset     kubernetes.Interface
+}
+
+func GetBackupEngine(
+	vmbo common.VMBackupOperator,
+	vsCache ctlsnapshotv1.VolumeSnapshotCache,
+	vsClient ctlsnapshotv1.VolumeSnapshotClient,
+	vscCache ctlsnapshotv1.VolumeSnapshotContentCache,
+	vscClient ctlsnapshotv1.VolumeSnapshotContentClient,
+	pvcCache ctlcorev1.PersistentVolumeClaimCache,
+	pvcClient ctlcorev1.PersistentVolumeClaimClient,
+	scCache ctlstoragev1.StorageClassCache,
+	secretCache ctlcorev1.SecretCache,
+	secretClient ctlcorev1.SecretClient,
+	vmbCache ctlharvesterv1.VirtualMachineBackupCache,
+	jobController ctlbatchv1.JobController,
+	clientset kubernetes.Interface,
+) engine.BackupEngine {
+	return &ExportEngine{
+		vmbo:          vmbo,
+		vsHelper:      common.NewVolumeSnapshotHelper(vsCache, vsClient, vscCache, vscClient, vmbo, pvcCache, scCache),
+		vsClient:      vsClient,
+		vscClient:     vscClient,
+		pvcCache:      pvcCache,
+		pvcClient:     pvcClient,
+		secretCache:   secretCache,
+		secretClient:  secretClient,
+		vmbCache:      vmbCache,
+		jobCache:      jobController.Cache(),
+		jobController: jobController,
+		clientset:     clientset,
+	}
+}
+
+// exportResourceName names the VolumeSnapshotContent, and the VolumeSnapshot,
+// PVC and Job in datamover.Namespace exporting a volume backup.
+func exportResourceName(namespace, vbName string) string {
+	return datamover.ResourceName(namespace, vbName, exportSuffix)
+}
+
+func (ee *ExportEngine) exportPath(vmb *harvesterv1.VirtualMachineBackup, vbName string) string {
+	return backuputil.GetVolumeExportPath(ee.vmbo.GetNamespace(vmb), ee.vmbo.GetName(vmb), vbName)
+}
+
+// getBackupTarget returns the current backup target, and fails if it isn't
+// the one the VMBackup was created for.
+func (ee *ExportEngine) getBackupTarget(vmb *harvesterv1.VirtualMachineBackup) (*settings.BackupTarget, error) {
+	target, err := ee.vmbo.GetCurrentBackupTarget(vmb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup target: %w", err)
+	}
+	if target.IsDefaultBackupTarget() {
+		return nil, fmt.Errorf("backup target is not set")
+	}
+	if !ee.vmbo.IsTargetConsistent(vmb, target) {
+		return nil, fmt.Errorf("backup target has changed since VMBackup %s/%s was created",
+			ee.vmbo.GetNamespace(vmb), ee.vmbo.GetName(vmb))
+	}
+	return target, nil
+}
+
+func (ee *ExportEngine) getBackupStoreDriver(vmb *harvesterv1.VirtualMachineBackup) (backupstore.BackupStoreDriver, error) {
+	target, err := ee.getBackupTarget(vmb)
+	if err != nil {
+		return nil, err
+	}
+	return backuputil.GetBackupStoreDriver(ee.secretCache, target)
+}
+
+func (ee *ExportEngine) getJob(namespace, name string) (*batchv1.Job, error) {
+	job, err := ee.jobCache.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return job, err
+}
+
+func (ee *ExportEngine) Reconcile(
+	vmb *harvesterv1.VirtualMachineBackup,
+	volIndex int,
+	vsClassMap map[string]snapshotv1.VolumeSnapshotClass,
+) error {
+	logrus.Debugf("ExportEngine Reconcile called for VMBackup %s/%s volume index %d",
+		ee.vmbo.GetNamespace(vmb), ee.vmbo.GetName(vmb), volIndex)
+
+	vb := ee.vmbo.GetVolBackup(vmb, volIndex)
+	if vb == nil {
+		return fmt.Errorf("volume backup at index %d not found", volIndex)
+	}
+	if ee.vmbo.GetVolBackupReadyToUse(vb) {
+		return nil
+	}
+
+	vbName := ee.vmbo.GetVolBackupName(vb)
+	if vbName == nil {
+		return fmt.Errorf("%w for VMBackup %s/%s at index %d",
+			common.ErrVolumeBackupNameNil, ee.vmbo.GetNamespace(vmb), ee.vmbo.GetName(vmb), volIndex)
+	}
+	namespace := ee.vmbo.GetNamespace(vmb)
+
+	vs, err := ee.vsHelper.GetVolumeSnapshot(namespace, *vbName)
+	if err != nil {
+		return err
+	}
+	if err := ee.vsHelper.CheckSnapshotDeletionStatus(vs, vmb, vb); err != nil {
+		return err
+	}
+
+	job, err := ee.getJob(datamover.Namespace, exportResourceName(namespace, *vbName))
+	if err != nil {
+		return err
+	}
+
+	if job != nil {
+		return ee.reconcileJob(vmb, vb, job)
+	}
+
+	if vs == nil {
+		// The export may already be complete, either because the temporary
+		// resources were cleaned up before the status was persisted, or because
+		// the VMBackup is recovered from the backup target.
+		bsDriver, err := ee.getBackupStoreDriver(vmb)
+		if err != nil {
+			return err
+		}
+		if datamover.ManifestExists(bsDriver, ee.exportPath(vmb, *vbName)) {
+			return ee.completeExport(vmb, vb, bsDriver)
+		}
+
+		_, err = ee.ensureVolumeSnapshotExists(vmb, vb, vsClassMap)
+		return err
+	}
+
+	if vs.Status == nil || !ptr.Deref(vs.Status.ReadyToUse, false) {
+		// Only surface the snapshot error, the volume backup is ready after the export.
+		if vs.Status != nil {
+			return ee.vmbo.SetVolBackupError(vb, vs.Status.Error)
+		}
+		return nil
+	}
+
+	exportVS, err := ee.ensureExportSnapshot(vmb, vs)
+	if err != nil {
+		return err
+	}
+	if exportVS.Status == nil || !ptr.Deref(exportVS.Status.ReadyToUse, false) {
+		return engine.ErrRetryLater
+	}
+
+	if err := ee.createExportJob(vmb, vb, exportVS); err != nil {
+		return err
+	}
+	return engine.ErrRetryLater
+}
+
+// ensureExportSnapshot makes the snapshot of the VM namespace available in
+// datamover.Namespace. The pre-provisioned VolumeSnapshotContent retains the
+// snapshot, it still belongs to the VolumeSnapshot of the VM namespace.
+func (ee *ExportEngine) ensureExportSnapshot(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vs *snapshotv1.VolumeSnapshot,
+) (*snapshotv1.VolumeSnapshot, error) {
+	resourceName := exportResourceName(vs.Namespace, vs.Name)
+	exportVS, err := ee.vsHelper.GetVolumeSnapshot(datamover.Namespace, resourceName)
+	if err != nil || exportVS != nil {
+		return exportVS, err
+	}
+
+	contentName := ptr.Deref(vs.Status.BoundVolumeSnapshotContentName, "")
+	if contentName == "" {
+		return nil, engine.ErrRetryLater
+	}
+	vsc, err := ee.vsHelper.GetVolumeSnapshotContent(contentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VolumeSnapshotContent %s: %w", contentName, err)
+	}
+	if vsc.Status == nil || ptr.Deref(vsc.Status.SnapshotHandle, "") == "" {
+		return nil, engine.ErrRetryLater
+	}
+
+	exportVSC := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   resourceName,
+			Labels: ee.exportLabels(vmb),
+		},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain,
+			Driver:         vsc.Spec.Driver,
+			Source: snapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: vsc.Status.SnapshotHandle,
+			},
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Name:      resourceName,
+				Namespace: datamover.Namespace,
+			},
+			VolumeSnapshotClassName: vsc.Spec.VolumeSnapshotClassName,
+		},
+	}
+	if _, err := ee.vsHelper.CreateVolumeSnapshotContent(exportVSC); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create VolumeSnapshotContent %s: %w", resourceName, err)
+	}
+
+	exportVS = &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resourceName,
+			Namespace: datamover.Namespace,
+			Labels:    ee.exportLabels(vmb),
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: ptr.To(resourceName),
+			},
+			VolumeSnapshotClassName: vsc.Spec.VolumeSnapshotClassName,
+		},
+	}
+	if _, err := ee.vsHelper.CreateVolumeSnapshot(exportVS); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create VolumeSnapshot %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	return nil, engine.ErrRetryLater
+}
+
+// exportLabels point the resources in datamover.Namespace back to the
+// VMBackup, owner references can't cross namespaces.
+func (ee *ExportEngine) exportLabels(vmb *harvesterv1.VirtualMachineBackup) map[string]string {
+	return map[string]string{
+		util.LabelGeneratedBy:    util.ValueGeneratedByHarvester,
+		datamover.LabelVMBackup:  ee.vmbo.GetName(vmb),
+		datamover.LabelNamespace: ee.vmbo.GetNamespace(vmb),
+	}
+}
+
+// ensureVolumeSnapshotExists creates a new volume snapshot if it doesn't exist
+func (ee *ExportEngine) ensureVolumeSnapshotExists(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	vsClassMap map[string]snapshotv1.VolumeSnapshotClass,
+) (*snapshotv1.VolumeSnapshot, error) {
+	csiDriver := ee.vmbo.GetVolBackupCSIDriver(vb)
+	vsClass, exists := vsClassMap[csiDriver]
+	if !exists {
+		return nil, fmt.Errorf("VolumeSnapshotClass not found for CSI driver %s", csiDriver)
+	}
+
+	if err := ee.vsHelper.TryFreezeFS(context.Background(), vmb); err != nil {
+		return nil, err
+	}
+
+	return ee.vsHelper.CreateVolumeSnapshotFromPVC(vmb, vb, &vsClass, ee.vsHelper.BuildOwnerReference(vmb))
+}
+
+// createExportJob provisions the PVC from the snapshot in
+// datamover.Namespace, and the data mover Job uploading the PVC content.
+func (ee *ExportEngine) createExportJob(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	exportVS *snapshotv1.VolumeSnapshot,
+) error {
+	target, err := ee.getBackupTarget(vmb)
+	if err != nil {
+		return err
+	}
+
+	vbName := *ee.vmbo.GetVolBackupName(vb)
+	pvcSpec := ee.vmbo.GetVolBackupPVCSpec(vb)
+
+	if err := ee.ensureExportPVC(vmb, exportVS.Name, pvcSpec); err != nil {
+		return err
+	}
+
+	secretName, err := datamover.EnsureCredentialSecret(ee.secretCache, ee.secretClient, target)
+	if err != nil {
+		return err
+	}
+
+	image, err := datamover.GetImage(ee.clientset)
+	if err != nil {
+		return fmt.Errorf("failed to get data mover image: %w", err)
+	}
+
+	job, err := datamover.BuildJob(datamover.JobOptions{
+		Name:                 exportVS.Name,
+		Labels:               ee.exportLabels(vmb),
+		Image:                image,
+		Target:               target,
+		CredentialSecretName: secretName,
+		PVCName:              exportVS.Name,
+		VolumeMode:           pvcSpec.VolumeMode,
+		Command:              datamover.CommandUpload,
+		ExportPath:           ee.exportPath(vmb, vbName),
+		BaseExportPath:       ee.findBaseExportPath(vmb, vb),
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(ee.vsHelper.GetLogFields(vmb, vb)).WithField("job", job.Name).Info("creating data mover job to export volume snapshot")
+	if _, err := ee.jobController.Create(job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create data mover job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+	return nil
+}
+
+// ensureExportPVC provisions the PVC of the data mover from the VolumeSnapshot
+// of the same name in datamover.Namespace.
+func (ee *ExportEngine) ensureExportPVC(
+	vmb *harvesterv1.VirtualMachineBackup,
+	pvcName string,
+	pvcSpec corev1.PersistentVolumeClaimSpec,
+) error {
+	if _, err := ee.pvcCache.Get(datamover.Namespace, pvcName); err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	pvc := pvchelper.BuildPVCFromSnapshot(datamover.Namespace, pvcName, pvcName, ee.exportLabels(vmb), nil, pvcSpec)
+	if _, err := ee.pvcClient.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create export PVC %s/%s: %w", datamover.Namespace, pvcName, err)
+	}
+	return nil
+}
+
+func (ee *ExportEngine) reconcileJob(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	job *batchv1.Job,
+) error {
+	finished, failure := datamover.IsJobFinished(job)
+	if failure != "" {
+		return ee.vmbo.SetVolBackupError(vb, &snapshotv1.VolumeSnapshotError{
+			Message: ptr.To(failure),
+			Time:    ptr.To(metav1.Now()),
+		})
+	}
+
+	bsDriver, err := ee.getBackupStoreDriver(vmb)
+	if err != nil {
+		return err
+	}
+
+	if finished {
+		return ee.completeExport(vmb, vb, bsDriver)
+	}
+
+	exportPath := ee.exportPath(vmb, *ee.vmbo.GetVolBackupName(vb))
+	progress, err := datamover.LoadProgress(bsDriver, datamover.GetProgressPath(exportPath))
+	if err != nil {
+		logrus.WithError(err).WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Warn("failed to load data mover progress")
+		return engine.ErrRetryLater
+	}
+	if progress == nil {
+		return engine.ErrRetryLater
+	}
+	if err := ee.vmbo.SetVolBackupProgress(vb, int(progress.Percentage())); err != nil {
+		return err
+	}
+	if err := ee.vmbo.SetVolBackupTransferredBytes(vb, progress.TransferredBytes); err != nil {
+		return err
+	}
+	return engine.ErrRetryLater
+}
+
+// findBaseExportPath returns the export of the same volume in the latest
+// ready snapshot-export VMBackup of the same source on the same backup
+// target, or an empty path if there is none.
+func (ee *ExportEngine) findBaseExportPath(vmb *harvesterv1.VirtualMachineBackup, vb *harvesterv1.VolumeBackup) string {
+	vmbs, err := ee.vmbCache.List(ee.vmbo.GetNamespace(vmb), labels.Everything())
+	if err != nil {
+		logrus.WithError(err).WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Warn("failed to list VMBackups, exporting without base")
+		return ""
+	}
+
+	var (
+		basePath string
+		baseTime *metav1.Time
+	)
+	for _, candidate := range vmbs {
+		if candidate.UID == vmb.UID ||
+			candidate.DeletionTimestamp != nil ||
+			candidate.Spec.Type != harvesterv1.SnapshotExport ||
+			candidate.Spec.Source.Kind != vmb.Spec.Source.Kind ||
+			candidate.Spec.Source.Name != vmb.Spec.Source.Name ||
+			!ee.vmbo.IsReady(candidate) ||
+			candidate.Status.BackupTarget == nil || vmb.Status.BackupTarget == nil ||
+			*candidate.Status.BackupTarget != *vmb.Status.BackupTarget {
+			continue
+		}
+		if baseTime != nil && !baseTime.Before(candidate.Status.CreationTime) {
+			continue
+		}
+		for i := range candidate.Status.VolumeBackups {
+			candidateVB := &candidate.Status.VolumeBackups[i]
+			if candidateVB.VolumeName == vb.VolumeName && candidateVB.Name != nil && ee.vmbo.GetVolBackupReadyToUse(candidateVB) {
+				basePath = ee.exportPath(candidate, *candidateVB.Name)
+				baseTime = candidate.Status.CreationTime
+				break
+			}
+		}
+	}
+	return basePath
+}
+
+// completeExport marks the volume backup ready from the export manifest and
+// removes the temporary resources used to export it.
+func (ee *ExportEngine) completeExport(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	bsDriver backupstore.BackupStoreDriver,
+) error {
+	vbName := *ee.vmbo.GetVolBackupName(vb)
+	target, err := ee.getBackupTarget(vmb)
+	if err != nil {
+		return err
+	}
+	key, err := backuputil.GetEncryptionKey(ee.secretCache, target)
+	if err != nil {
+		return err
+	}
+	manifest, err := datamover.LoadManifest(bsDriver, key, ee.exportPath(vmb, vbName))
+	if err != nil {
+		return fmt.Errorf("failed to load export manifest of volume backup %s: %w", vbName, err)
+	}
+
+	if err := ee.deleteExportResources(ee.vmbo.GetNamespace(vmb), vbName); err != nil {
+		return err
+	}
+
+	logrus.WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Info("volume snapshot exported to the backup target")
+	if err := ee.vmbo.SetVolBackupReadyToUse(vb, ptr.To(true)); err != nil {
+		return err
+	}
+	if err := ee.vmbo.SetVolBackupCreationTime(vb, ptr.To(metav1.NewTime(manifest.CreatedAt))); err != nil {
+		return err
+	}
+	if err := ee.vmbo.SetVolBackupError(vb, nil); err != nil {
+		return err
+	}
+	if err := ee.vmbo.SetVolBackupTransferredBytes(vb, manifest.TransferredBytes); err != nil {
+		return err
+	}
+	return ee.vmbo.SetVolBackupProgress(vb, backupProgressComplete)
+}
+
+// deleteExportResources removes the Job, the PVC, the VolumeSnapshot and the
+// VolumeSnapshotContent in datamover.Namespace and the VolumeSnapshot of a
+// single volume backup. The pre-provisioned VolumeSnapshotContent retains the
+// snapshot, only the VolumeSnapshot of the VM namespace goes through the
+// regular deletion, so the CSI driver frees the snapshot on the storage side.
+func (ee *ExportEngine) deleteExportResources(namespace, vbName string) error {
+	resourceName := exportResourceName(namespace, vbName)
+
+	err := ee.jobController.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete data mover job %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+
+	if err := ee.pvcClient.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete export PVC %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+
+	if err := ee.vsClient.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete VolumeSnapshot %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+
+	if err := ee.vscClient.Delete(resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete VolumeSnapshotContent %s: %w", resourceName, err)
+	}
+
+	if err := ee.vsClient.Delete(namespace, vbName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete VolumeSnapshot %s/%s: %w", namespace, vbName, err)
+	}
+	return nil
+}
+
+func (ee *ExportEngine) UpdateProgress(vb *harvesterv1.VolumeBackup) (int64, error) {
+	if vb == nil {
+		return 0, nil
+	}
+
+	if ee.vmbo.GetVolBackupReadyToUse(vb) {
+		return backupProgressComplete, nil
+	}
+
+	// Reconcile refreshes the progress and the transferred bytes from the data
+	// mover while the Job runs.
+	return int64(ee.vmbo.GetVolBackupProgress(vb)), nil
+}
+
+// ForceDelete removes the temporary export resources and the exported data.
+// The data is only removed if the current backup target is still the one the
+// VMBackup was exported to. The chunks no other export refers to are
+// collected once the last volume of the VMBackup is removed.
+func (ee *ExportEngine) ForceDelete(vmb *harvesterv1.VirtualMachineBackup, volIndex int) error {
+	vb := ee.vmbo.GetVolBackup(vmb, volIndex)
+	vbName := ee.vmbo.GetVolBackupName(vb)
+	if vbName == nil {
+		return fmt.Errorf("%w for VMBackup %s/%s at index %d",
+			common.ErrVolumeBackupNameNil, ee.vmbo.GetNamespace(vmb), ee.vmbo.GetName(vmb), volIndex)
+	}
+
+	if err := ee.deleteExportResources(ee.vmbo.GetNamespace(vmb), *vbName); err != nil {
+		return err
+	}
+
+	target, err := ee.vmbo.GetCurrentBackupTarget(vmb)
+	if err != nil {
+		return fmt.Errorf("failed to get backup target: %w", err)
+	}
+	if target.IsDefaultBackupTarget() || !ee.vmbo.IsTargetConsistent(vmb, target) {
+		return nil
+	}
+
+	bsDriver, err := backuputil.GetBackupStoreDriver(ee.secretCache, target)
+	if err != nil {
+		return err
+	}
+
+	key, err := backuputil.GetEncryptionKey(ee.secretCache, target)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Info("removing volume export from the backup target")
+	if err := datamover.Remove(bsDriver, ee.exportPath(vmb, *vbName)); err != nil {
+		return err
+	}
+
+	if volIndex != len(ee.vmbo.GetVolBackups(vmb))-1 {
+		return nil
+	}
+	// Leftover chunks only waste space, they must not block the VMBackup deletion.
+	if _, err := datamover.CollectGarbage(bsDriver, key); errors.Is(err, datamover.ErrUploadInProgress) {
+		logrus.WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Info("skip collecting volume export chunks while an upload is running")
+	} else if err != nil {
+		logrus.WithError(err).WithFields(ee.vsHelper.GetLogFields(vmb, vb)).Warn("failed to collect volume export chunks")
+	}
+	return nil
+}
+
+// RegisterWatchers maps data mover Job changes back to the VMBackup through
+// the labels set on the Job, so the export progress and completion are
+// reconciled without waiting for a requeue.
+func (ee *ExportEngine) RegisterWatchers(ctx context.Context, enqueueVMBackup func(namespace, name string)) {
+	ee.jobController.OnChange(ctx, exportJobWatcherName, func(_ string, job *batchv1.Job) (*batchv1.Job, error) {
+		if job == nil || job.DeletionTimestamp != nil {
+			return nil, nil
+		}
+		if vmbName, ok := job.Labels[datamover.LabelVMBackup]; ok && job.Namespace == datamover.Namespace {
+			enqueueVMBackup(job.Labels[datamover.LabelNamespace], vmbName)
+		}
+		return nil, nil
+	})
+}
@@ -2,10 +2,11 @@ package longhorn
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
-	"github.com/longhorn/backupstore"
 	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
@@ -13,10 +14,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/utils/ptr"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/backup/datamover"
 	"github.com/harvester/harvester/pkg/backup/engine"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctllonghornv2 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
@@ -32,6 +35,12 @@ const (
 	backupProgressComplete = 100
 	lhBackupWatcherName    = "longhorn-backup-watcher"
 	vmBackupKindName       = "VirtualMachineBackup"
+
+	// Longhorn backs a volume up to the backup target of the volume, so a
+	// VMBackup locks the volume while it points the volume to its target, and
+	// puts the original target back once its backup is done.
+	annotationBackupTargetLock     = "harvesterhci.io/backup-target-lock"
+	annotationOriginalBackupTarget = "harvesterhci.io/original-backup-target"
 )
 
 var vmBackupKind = harvesterv1.SchemeGroupVersion.WithKind(vmBackupKindName)
@@ -41,8 +50,11 @@ type LonghornEngine struct {
 	vsHelper           *common.VolumeSnapshotHelper
 	pvcCache           ctlcorev1.PersistentVolumeClaimCache
 	scCache            ctlstoragev1.StorageClassCache
+	secretCache        ctlcorev1.SecretCache
 	lhbackupCache      ctllonghornv2.BackupCache
 	lhbackupController ctllonghornv2.BackupController
+	lhvolumeCache      ctllonghornv2.VolumeCache
+	lhvolumes          ctllonghornv2.VolumeClient
 	vmbCache           ctlharvesterv1.VirtualMachineBackupCache
 }
 
@@ -54,8 +66,11 @@ func GetBackupEngine(
 	vscClient ctlsnapshotv1.VolumeSnapshotContentClient,
 	pvcCache ctlcorev1.PersistentVolumeClaimCache,
 	scCache ctlstoragev1.StorageClassCache,
+	secretCache ctlcorev1.SecretCache,
 	lhbackupCache ctllonghornv2.BackupCache,
 	lhbackupController ctllonghornv2.BackupController,
+	lhvolumeCache ctllonghornv2.VolumeCache,
+	lhvolumes ctllonghornv2.VolumeClient,
 	vmbCache ctlharvesterv1.VirtualMachineBackupCache,
 ) engine.BackupEngine {
 	return &LonghornEngine{
@@ -63,8 +78,11 @@ func GetBackupEngine(
 		vsHelper:           common.NewVolumeSnapshotHelper(vsCache, vsClient, vscCache, vscClient, vmbo, pvcCache, scCache),
 		pvcCache:           pvcCache,
 		scCache:            scCache,
+		secretCache:        secretCache,
 		lhbackupCache:      lhbackupCache,
 		lhbackupController: lhbackupController,
+		lhvolumeCache:      lhvolumeCache,
+		lhvolumes:          lhvolumes,
 		vmbCache:           vmbCache,
 	}
 }
@@ -268,31 +286,27 @@ func (le *LonghornEngine) checkLHBackup(name string) (string, error) {
 }
 
 func (le *LonghornEngine) checkVolInBackupTarget(vmb *harvesterv1.VirtualMachineBackup, vb *harvesterv1.VolumeBackup, t *settings.BackupTarget) (string, error) {
-	// backupstore looks up by Longhorn volume name, which is the PV name.
-	pvName := le.vmbo.GetVolBackupPVName(vb)
-	vols, err := backupstore.List(pvName, backuputil.ConstructEndpoint(t), false)
+	bsDriver, err := backuputil.GetBackupStoreDriver(le.secretCache, t)
 	if err != nil {
 		// The backup target may be offline. In this case, we don't want to trigger reconciliation.
 		return err.Error(), nil
 	}
 
-	handleMissing := func(msg string) (string, error) {
-		logrus.WithFields(le.getLogFields(vmb, vb)).Warn(msg + ", change the VMBackup to not ready")
-		if err := le.vmbo.SetVolBackupReadyToUse(vb, ptr.To(false)); err != nil {
-			return "", fmt.Errorf("failed to set volume backup ready to use: %w", err)
-		}
-		return msg, nil
+	// backupstore looks up by Longhorn volume name, which is the PV name.
+	lhBackup := datamover.LonghornBackup{
+		VolumeName: le.vmbo.GetVolBackupPVName(vb),
+		BackupName: *le.vmbo.GetVolBackupLHBackupName(vb),
 	}
-
-	if vols[pvName] == nil {
-		return handleMissing(fmt.Sprintf("cannot find volume %s in the backup target", pvName))
+	if datamover.LonghornBackupExists(bsDriver, lhBackup) {
+		return "", nil
 	}
 
-	lhBackupName := *le.vmbo.GetVolBackupLHBackupName(vb)
-	if vols[pvName].Backups[lhBackupName] == nil {
-		return handleMissing(fmt.Sprintf("cannot find longhorn backup %s in the backup target", lhBackupName))
+	msg := fmt.Sprintf("cannot find longhorn backup %s in the backup target", lhBackup)
+	logrus.WithFields(le.getLogFields(vmb, vb)).Warn(msg + ", change the VMBackup to not ready")
+	if err := le.vmbo.SetVolBackupReadyToUse(vb, ptr.To(false)); err != nil {
+		return "", fmt.Errorf("failed to set volume backup ready to use: %w", err)
 	}
-	return "", nil
+	return msg, nil
 }
 
 func (le *LonghornEngine) shouldSkipVSUpdate(vmb *harvesterv1.VirtualMachineBackup, vb *harvesterv1.VolumeBackup) (bool, error) {
@@ -321,10 +335,9 @@ func (le *LonghornEngine) shouldSkipVSUpdate(vmb *harvesterv1.VirtualMachineBack
 		return true, nil
 	}
 
-	tValue := settings.BackupTargetSet.Get()
-	t, err := settings.DecodeBackupTarget(tValue)
+	t, err := le.vmbo.GetCurrentBackupTarget(vmb)
 	if err != nil {
-		logrus.WithError(err).WithFields(le.getLogFields(vmb, vb)).Warnf("failed to decode backup target %s", tValue)
+		logrus.WithError(err).WithFields(le.getLogFields(vmb, vb)).Warnf("failed to get backup target %s", le.vmbo.GetBackupTargetName(vmb))
 		return true, err
 	}
 
@@ -365,7 +378,13 @@ func (le *LonghornEngine) Reconcile(
 	}
 
 	if vs != nil {
-		return le.updateVolumeBackupFromSnapshot(vmb, vb, vs)
+		if err := le.updateVolumeBackupFromSnapshot(vmb, vb, vs); err != nil {
+			return err
+		}
+		if vs.Status != nil && (ptr.Deref(vs.Status.ReadyToUse, false) || vs.Status.Error != nil) {
+			return le.releaseVolumeBackupTarget(vmb, le.vmbo.GetVolBackupPVName(vb))
+		}
+		return nil
 	}
 
 	_, err = le.ensureVolumeSnapshotExists(vmb, vb, vsClassMap)
@@ -392,6 +411,10 @@ func (le *LonghornEngine) ensureVolumeSnapshotExists(
 		return le.createVSFromLHBackup(vmb, vb, &vsClass)
 	}
 
+	if err := le.ensureVolumeBackupTarget(vmb, vb); err != nil {
+		return nil, err
+	}
+
 	// Fresh-backup path: freeze the source filesystem before snapshotting for
 	// crash-consistency.
 	if err := le.vsHelper.TryFreezeFS(context.Background(), vmb); err != nil {
@@ -400,6 +423,134 @@ func (le *LonghornEngine) ensureVolumeSnapshotExists(
 	return le.createVSFromPVC(vmb, vb, &vsClass)
 }
 
+// ensureVolumeBackupTarget locks the Longhorn volume for the VMBackup and
+// points it to the Longhorn BackupTarget of the VMBackup. The lock serializes
+// the backups of the volume, as another VMBackup switching the target would
+// send a running backup to the wrong target. The volume update fails on a
+// conflict, so two VMBackups can't both take the lock from a stale cache.
+// The target isn't switched either while a backup taken by Longhorn itself,
+// e.g. by a recurring job, is in progress.
+func (le *LonghornEngine) ensureVolumeBackupTarget(vmb *harvesterv1.VirtualMachineBackup, vb *harvesterv1.VolumeBackup) error {
+	volumeName := le.vmbo.GetVolBackupPVName(vb)
+	volume, err := le.lhvolumeCache.Get(util.LonghornSystemNamespaceName, volumeName)
+	if err != nil {
+		return fmt.Errorf("failed to get Longhorn volume %s: %w", volumeName, err)
+	}
+
+	lockKey := getBackupTargetLockKey(vmb)
+	holder := volume.Annotations[annotationBackupTargetLock]
+	if holder == lockKey {
+		return nil
+	}
+	if holder != "" && le.isBackupTargetLockHeld(holder, volumeName) {
+		logrus.WithFields(le.getLogFields(vmb, vb)).Infof("waiting for VMBackup %s to release the backup target of the volume", holder)
+		return engine.ErrRetryLater
+	}
+
+	backupTargetName := backuputil.GetLonghornBackupTargetName(le.vmbo.GetBackupTargetName(vmb))
+	if volume.Spec.BackupTargetName != backupTargetName {
+		lhBackups, err := le.lhbackupCache.List(util.LonghornSystemNamespaceName, labels.Everything())
+		if err != nil {
+			return err
+		}
+		for _, lhBackup := range lhBackups {
+			if lhBackup.Status.VolumeName != volumeName {
+				continue
+			}
+			switch lhBackup.Status.State {
+			case lhv1beta2.BackupStateNew, lhv1beta2.BackupStatePending, lhv1beta2.BackupStateInProgress:
+				logrus.WithFields(le.getLogFields(vmb, vb)).Infof("waiting for longhorn backup %s to switch the backup target of the volume", lhBackup.Name)
+				return engine.ErrRetryLater
+			}
+		}
+	}
+
+	volumeCpy := volume.DeepCopy()
+	if volumeCpy.Annotations == nil {
+		volumeCpy.Annotations = map[string]string{}
+	}
+	// a stale lock already recorded the original target
+	if holder == "" {
+		volumeCpy.Annotations[annotationOriginalBackupTarget] = volume.Spec.BackupTargetName
+	}
+	volumeCpy.Annotations[annotationBackupTargetLock] = lockKey
+	volumeCpy.Spec.BackupTargetName = backupTargetName
+	if _, err := le.lhvolumes.Update(volumeCpy); err != nil {
+		return fmt.Errorf("failed to set backup target of Longhorn volume %s: %w", volumeName, err)
+	}
+	return nil
+}
+
+// releaseVolumeBackupTarget puts the original backup target of the Longhorn
+// volume back, if the VMBackup holds its lock.
+func (le *LonghornEngine) releaseVolumeBackupTarget(vmb *harvesterv1.VirtualMachineBackup, volumeName string) error {
+	volume, err := le.lhvolumeCache.Get(util.LonghornSystemNamespaceName, volumeName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get Longhorn volume %s: %w", volumeName, err)
+	}
+	if volume.Annotations[annotationBackupTargetLock] != getBackupTargetLockKey(vmb) {
+		return nil
+	}
+
+	volumeCpy := volume.DeepCopy()
+	volumeCpy.Spec.BackupTargetName = volume.Annotations[annotationOriginalBackupTarget]
+	delete(volumeCpy.Annotations, annotationBackupTargetLock)
+	delete(volumeCpy.Annotations, annotationOriginalBackupTarget)
+	if _, err := le.lhvolumes.Update(volumeCpy); err != nil {
+		return fmt.Errorf("failed to restore backup target of Longhorn volume %s: %w", volumeName, err)
+	}
+	return nil
+}
+
+// isBackupTargetLockHeld reports whether the VMBackup holding the lock of the
+// volume still backs it up. A VMBackup that is gone or whose backup of the
+// volume is done doesn't, e.g. if it was removed before releasing the lock.
+func (le *LonghornEngine) isBackupTargetLockHeld(holder, volumeName string) bool {
+	namespace, name, ok := strings.Cut(holder, "/")
+	if !ok {
+		return false
+	}
+	vmb, err := le.vmbCache.Get(namespace, name)
+	if err != nil || le.vmbo.GetDeletionTimestamp(vmb) != nil || le.vmbo.IsReady(vmb) {
+		return false
+	}
+	for _, vb := range le.vmbo.GetVolBackups(vmb) {
+		if le.vmbo.GetVolBackupPVName(&vb) != volumeName {
+			continue
+		}
+		return !le.vmbo.GetVolBackupReadyToUse(&vb) && le.vmbo.GetVolBackupError(&vb) == nil
+	}
+	return false
+}
+
+// ReleaseVolumes releases the backup target locks the VMBackup holds, so a
+// VMBackup removed in the middle of its backup doesn't leave the volumes
+// pointing to its target.
+func (le *LonghornEngine) ReleaseVolumes(vmb *harvesterv1.VirtualMachineBackup) error {
+	var errs []error
+	for _, vb := range le.vmbo.GetVolBackups(vmb) {
+		errs = append(errs, le.releaseVolumeBackupTarget(vmb, le.vmbo.GetVolBackupPVName(&vb)))
+	}
+	return errors.Join(errs...)
+}
+
+func getBackupTargetLockKey(vmb *harvesterv1.VirtualMachineBackup) string {
+	return vmb.Namespace + "/" + vmb.Name
+}
+
+// isLHBackupDone reports whether Longhorn is done with the backup, so the
+// backup target of its volume can be switched again.
+func isLHBackupDone(lhBackup *lhv1beta2.Backup) bool {
+	switch lhBackup.Status.State {
+	case lhv1beta2.BackupStateCompleted, lhv1beta2.BackupStateError, lhv1beta2.BackupStateUnknown:
+		return true
+	}
+	return false
+}
+
 // updateVolumeBackupFromSnapshot updates the volume backup status from the snapshot,
 // checking if the update should be skipped first
 func (le *LonghornEngine) updateVolumeBackupFromSnapshot(
@@ -467,7 +618,7 @@ func (le *LonghornEngine) ForceDelete(vmb *harvesterv1.VirtualMachineBackup, vol
 		return fmt.Errorf("failed to force delete orphan VolumeSnapshotContents: %w", err)
 	}
 
-	return nil
+	return le.releaseVolumeBackupTarget(vmb, le.vmbo.GetVolBackupPVName(vb))
 }
 
 // RegisterWatchers wires up a Longhorn-Backup → VMBackup event mapping so
@@ -494,6 +645,12 @@ func (le *LonghornEngine) onLHBackupChanged(
 		return nil, err
 	}
 
+	if isLHBackupDone(lhBackup) {
+		if err := le.releaseVolumeBackupTarget(vmb, lhBackup.Status.VolumeName); err != nil {
+			return nil, err
+		}
+	}
+
 	if le.vmbo.GetBackupTarget(vmb) == nil {
 		return nil, nil
 	}
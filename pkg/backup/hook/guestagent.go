@@ -0,0 +1,165 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	computeContainerName = "compute"
+	statusPollInterval   = time.Second
+
+	// virshScript sends the agent command $1 to the domain $0. virt-launcher
+	// runs libvirt in session mode unless it runs as root.
+	virshScript = `uri=qemu:///system; [ "$(id -u)" = 0 ] || uri='qemu+unix:///session?socket=/var/run/libvirt/virtqemud-sock'; exec virsh -c "$uri" qemu-agent-command "$0" "$1"`
+)
+
+// GuestAgentExecutor runs commands with the guest-exec command of the
+// qemu-guest-agent. KubeVirt has no subresource for it, so the agent commands
+// are sent with virsh from the compute container of the virt-launcher pod.
+type GuestAgentExecutor struct {
+	clientset  kubernetes.Interface
+	restConfig *rest.Config
+}
+
+func NewGuestAgentExecutor(clientset kubernetes.Interface, restConfig *rest.Config) *GuestAgentExecutor {
+	return &GuestAgentExecutor{
+		clientset:  clientset,
+		restConfig: restConfig,
+	}
+}
+
+type guestExecResponse struct {
+	Return struct {
+		PID int `json:"pid"`
+	} `json:"return"`
+}
+
+type guestExecStatusResponse struct {
+	Return struct {
+		Exited   bool   `json:"exited"`
+		ExitCode int    `json:"exitcode"`
+		OutData  string `json:"out-data"`
+		ErrData  string `json:"err-data"`
+	} `json:"return"`
+}
+
+// Exec starts the command in the guest and polls its status until it exits
+// or ctx is done. The guest agent can't kill a command, so a timed out
+// command keeps running in the guest.
+func (e *GuestAgentExecutor) Exec(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, command []string) (*ExecResult, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("command is empty")
+	}
+
+	pod, err := e.getLauncherPod(ctx, vmi)
+	if err != nil {
+		return nil, err
+	}
+	domain := fmt.Sprintf("%s_%s", vmi.Namespace, vmi.Name)
+
+	started := &guestExecResponse{}
+	if err := e.agentCommand(ctx, pod, domain, map[string]interface{}{
+		"execute": "guest-exec",
+		"arguments": map[string]interface{}{
+			"path":           command[0],
+			"arg":            command[1:],
+			"capture-output": true,
+		},
+	}, started); err != nil {
+		return nil, fmt.Errorf("failed to start command in the guest: %w", err)
+	}
+
+	for {
+		status := &guestExecStatusResponse{}
+		if err := e.agentCommand(ctx, pod, domain, map[string]interface{}{
+			"execute":   "guest-exec-status",
+			"arguments": map[string]interface{}{"pid": started.Return.PID},
+		}, status); err != nil {
+			return nil, fmt.Errorf("failed to get status of command %d in the guest: %w", started.Return.PID, err)
+		}
+		if status.Return.Exited {
+			return &ExecResult{
+				ExitCode: status.Return.ExitCode,
+				Output:   decodeOutput(status.Return.OutData) + decodeOutput(status.Return.ErrData),
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("command %d in the guest did not exit in time: %w", started.Return.PID, ctx.Err())
+		case <-time.After(statusPollInterval):
+		}
+	}
+}
+
+func (e *GuestAgentExecutor) getLauncherPod(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) (*corev1.Pod, error) {
+	pods, err := e.clientset.CoreV1().Pods(vmi.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{kubevirtv1.CreatedByLabel: string(vmi.UID)}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods for VMI %s/%s: %w", vmi.Namespace, vmi.Name, err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("there is no running pod for VMI %s/%s", vmi.Namespace, vmi.Name)
+}
+
+func (e *GuestAgentExecutor) agentCommand(ctx context.Context, pod *corev1.Pod, domain string, request, response interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: computeContainerName,
+			Command:   []string{"sh", "-c", virshScript, domain, string(payload)},
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(e.restConfig, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return json.Unmarshal(stdout.Bytes(), response)
+}
+
+func decodeOutput(data string) string {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return data
+	}
+	return string(decoded)
+}
@@ -0,0 +1,210 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	DefaultTimeout = 30 * time.Second
+	// MaxTimeout bounds a single hook, the volume snapshots wait for the hooks to finish.
+	MaxTimeout = 10 * time.Minute
+
+	// Only keep the end of the output, it usually tells why a command failed.
+	maxOutputLength = 1024
+)
+
+// ExecResult is the outcome of a command that exited in the guest.
+type ExecResult struct {
+	ExitCode int
+	Output   string
+}
+
+// Executor runs a command inside the guest of a running VMI and waits for it to exit.
+type Executor interface {
+	Exec(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance, command []string) (*ExecResult, error)
+}
+
+// GetHooks returns the hooks of the given phase.
+func GetHooks(hooks *harvesterv1.BackupHooks, phase harvesterv1.HookPhase) []harvesterv1.BackupHook {
+	if hooks == nil {
+		return nil
+	}
+	switch phase {
+	case harvesterv1.HookPhasePreSnapshot:
+		return hooks.PreSnapshot
+	case harvesterv1.HookPhasePostSnapshot:
+		return hooks.PostSnapshot
+	}
+	return nil
+}
+
+// IsPhaseDone reports whether the hooks of the phase already ran. All results
+// of a phase are recorded at once, so a single finished one is enough.
+func IsPhaseDone(hooks *harvesterv1.BackupHooks, results []harvesterv1.HookResult, phase harvesterv1.HookPhase) bool {
+	if len(GetHooks(hooks, phase)) == 0 {
+		return true
+	}
+	for _, result := range results {
+		if result.Phase == phase && result.Status != harvesterv1.HookStatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPhaseRunning reports whether the hooks of the phase were started and
+// haven't finished yet.
+func IsPhaseRunning(results []harvesterv1.HookResult, phase harvesterv1.HookPhase) bool {
+	for _, result := range results {
+		if result.Phase == phase && result.Status == harvesterv1.HookStatusRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAbortingResult returns the result of the failed pre-snapshot hook which
+// aborted the backup, or nil if the backup can go on.
+func GetAbortingResult(hooks *harvesterv1.BackupHooks, results []harvesterv1.HookResult) *harvesterv1.HookResult {
+	for i, result := range results {
+		if result.Phase != harvesterv1.HookPhasePreSnapshot || result.Status != harvesterv1.HookStatusFailed {
+			continue
+		}
+		for _, h := range GetHooks(hooks, harvesterv1.HookPhasePreSnapshot) {
+			if h.Name == result.Name && getFailurePolicy(h) == harvesterv1.HookFailurePolicyAbort {
+				return &results[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Run runs the hooks in order. It stops at the first failed hook whose
+// failure policy is abort, and reports the backup as aborted.
+func Run(
+	ctx context.Context,
+	executor Executor,
+	vmi *kubevirtv1.VirtualMachineInstance,
+	phase harvesterv1.HookPhase,
+	hooks []harvesterv1.BackupHook,
+) (results []harvesterv1.HookResult, aborted bool) {
+	for _, h := range hooks {
+		result := run(ctx, executor, vmi, phase, h)
+		results = append(results, result)
+		if result.Status == harvesterv1.HookStatusFailed && getFailurePolicy(h) == harvesterv1.HookFailurePolicyAbort {
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// Start records the hooks as running, until the results of the run replace them.
+func Start(phase harvesterv1.HookPhase, hooks []harvesterv1.BackupHook) []harvesterv1.HookResult {
+	now := metav1.Now()
+	results := make([]harvesterv1.HookResult, 0, len(hooks))
+	for _, h := range hooks {
+		results = append(results, harvesterv1.HookResult{
+			Name:      h.Name,
+			Phase:     phase,
+			Status:    harvesterv1.HookStatusRunning,
+			StartTime: &now,
+		})
+	}
+	return results
+}
+
+// Interrupt records the running hooks as failed, their run was lost because
+// the controller restarted. The guest agent can't tell how they ended.
+func Interrupt(results []harvesterv1.HookResult, phase harvesterv1.HookPhase) []harvesterv1.HookResult {
+	now := metav1.Now()
+	var interrupted []harvesterv1.HookResult
+	for _, result := range results {
+		if result.Phase != phase || result.Status != harvesterv1.HookStatusRunning {
+			continue
+		}
+		result.Status = harvesterv1.HookStatusFailed
+		result.Message = "hook was interrupted by a controller restart"
+		result.CompletionTime = &now
+		interrupted = append(interrupted, result)
+	}
+	return interrupted
+}
+
+// Skip records the hooks as skipped with the given reason.
+func Skip(phase harvesterv1.HookPhase, hooks []harvesterv1.BackupHook, message string) []harvesterv1.HookResult {
+	now := metav1.Now()
+	results := make([]harvesterv1.HookResult, 0, len(hooks))
+	for _, h := range hooks {
+		results = append(results, harvesterv1.HookResult{
+			Name:           h.Name,
+			Phase:          phase,
+			Status:         harvesterv1.HookStatusSkipped,
+			Message:        message,
+			StartTime:      &now,
+			CompletionTime: &now,
+		})
+	}
+	return results
+}
+
+func run(
+	ctx context.Context,
+	executor Executor,
+	vmi *kubevirtv1.VirtualMachineInstance,
+	phase harvesterv1.HookPhase,
+	h harvesterv1.BackupHook,
+) harvesterv1.HookResult {
+	result := harvesterv1.HookResult{
+		Name:      h.Name,
+		Phase:     phase,
+		StartTime: ptr.To(metav1.Now()),
+	}
+
+	timeout := DefaultTimeout
+	if h.Timeout != nil {
+		timeout = h.Timeout.Duration
+	}
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	execResult, err := executor.Exec(execCtx, vmi, h.Command)
+	result.CompletionTime = ptr.To(metav1.Now())
+
+	switch {
+	case err != nil:
+		result.Status = harvesterv1.HookStatusFailed
+		result.Message = err.Error()
+	case execResult.ExitCode != 0:
+		result.Status = harvesterv1.HookStatusFailed
+		result.ExitCode = ptr.To(execResult.ExitCode)
+		result.Output = truncateOutput(execResult.Output)
+		result.Message = fmt.Sprintf("command exited with code %d", execResult.ExitCode)
+	default:
+		result.Status = harvesterv1.HookStatusSucceeded
+		result.ExitCode = ptr.To(execResult.ExitCode)
+		result.Output = truncateOutput(execResult.Output)
+	}
+	return result
+}
+
+func getFailurePolicy(h harvesterv1.BackupHook) harvesterv1.HookFailurePolicy {
+	if h.OnFailure == "" {
+		return harvesterv1.HookFailurePolicyAbort
+	}
+	return h.OnFailure
+}
+
+func truncateOutput(output string) string {
+	if len(output) <= maxOutputLength {
+		return output
+	}
+	return output[len(output)-maxOutputLength:]
+}
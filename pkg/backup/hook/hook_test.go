@@ -0,0 +1,163 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+// fakeExecutor returns the result configured for the first element of the command.
+type fakeExecutor struct {
+	results map[string]*ExecResult
+	errors  map[string]error
+	delay   time.Duration
+	ran     []string
+}
+
+func (e *fakeExecutor) Exec(ctx context.Context, _ *kubevirtv1.VirtualMachineInstance, command []string) (*ExecResult, error) {
+	e.ran = append(e.ran, command[0])
+	if e.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(e.delay):
+		}
+	}
+	if err, ok := e.errors[command[0]]; ok {
+		return nil, err
+	}
+	if result, ok := e.results[command[0]]; ok {
+		return result, nil
+	}
+	return &ExecResult{}, nil
+}
+
+func TestRun(t *testing.T) {
+	var testCases = []struct {
+		name          string
+		hooks         []harvesterv1.BackupHook
+		executor      *fakeExecutor
+		expectRan     []string
+		expectStatus  []harvesterv1.HookStatus
+		expectAborted bool
+	}{
+		{
+			name: "all hooks succeed",
+			hooks: []harvesterv1.BackupHook{
+				{Name: "a", Command: []string{"a"}},
+				{Name: "b", Command: []string{"b"}},
+			},
+			executor:     &fakeExecutor{},
+			expectRan:    []string{"a", "b"},
+			expectStatus: []harvesterv1.HookStatus{harvesterv1.HookStatusSucceeded, harvesterv1.HookStatusSucceeded},
+		},
+		{
+			name: "failed hook aborts by default",
+			hooks: []harvesterv1.BackupHook{
+				{Name: "a", Command: []string{"a"}},
+				{Name: "b", Command: []string{"b"}},
+			},
+			executor: &fakeExecutor{
+				results: map[string]*ExecResult{"a": {ExitCode: 1}},
+			},
+			expectRan:     []string{"a"},
+			expectStatus:  []harvesterv1.HookStatus{harvesterv1.HookStatusFailed},
+			expectAborted: true,
+		},
+		{
+			name: "failed hook continues",
+			hooks: []harvesterv1.BackupHook{
+				{Name: "a", Command: []string{"a"}, OnFailure: harvesterv1.HookFailurePolicyContinue},
+				{Name: "b", Command: []string{"b"}},
+			},
+			executor: &fakeExecutor{
+				errors: map[string]error{"a": errors.New("guest agent is not connected")},
+			},
+			expectRan:    []string{"a", "b"},
+			expectStatus: []harvesterv1.HookStatus{harvesterv1.HookStatusFailed, harvesterv1.HookStatusSucceeded},
+		},
+		{
+			name: "hook times out",
+			hooks: []harvesterv1.BackupHook{
+				{Name: "a", Command: []string{"a"}, Timeout: &metav1.Duration{Duration: 10 * time.Millisecond}},
+			},
+			executor:      &fakeExecutor{delay: time.Second},
+			expectRan:     []string{"a"},
+			expectStatus:  []harvesterv1.HookStatus{harvesterv1.HookStatusFailed},
+			expectAborted: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		results, aborted := Run(context.Background(), tc.executor, &kubevirtv1.VirtualMachineInstance{}, harvesterv1.HookPhasePreSnapshot, tc.hooks)
+		assert.Equal(t, tc.expectAborted, aborted, tc.name)
+		assert.Equal(t, tc.expectRan, tc.executor.ran, tc.name)
+		var status []harvesterv1.HookStatus
+		for _, result := range results {
+			assert.Equal(t, harvesterv1.HookPhasePreSnapshot, result.Phase, tc.name)
+			assert.NotNil(t, result.StartTime, tc.name)
+			assert.NotNil(t, result.CompletionTime, tc.name)
+			status = append(status, result.Status)
+		}
+		assert.Equal(t, tc.expectStatus, status, tc.name)
+	}
+}
+
+func TestRunTruncatesOutput(t *testing.T) {
+	output := strings.Repeat("a", maxOutputLength) + "error"
+	executor := &fakeExecutor{
+		results: map[string]*ExecResult{"a": {ExitCode: 2, Output: output}},
+	}
+	hooks := []harvesterv1.BackupHook{{Name: "a", Command: []string{"a"}}}
+
+	results, _ := Run(context.Background(), executor, &kubevirtv1.VirtualMachineInstance{}, harvesterv1.HookPhasePreSnapshot, hooks)
+	assert.Len(t, results, 1)
+	assert.Len(t, results[0].Output, maxOutputLength)
+	assert.True(t, strings.HasSuffix(results[0].Output, "error"))
+	assert.Equal(t, 2, *results[0].ExitCode)
+}
+
+func TestIsPhaseDone(t *testing.T) {
+	hooks := &harvesterv1.BackupHooks{
+		PreSnapshot: []harvesterv1.BackupHook{{Name: "a", Command: []string{"a"}}},
+	}
+	results := []harvesterv1.HookResult{{Name: "a", Phase: harvesterv1.HookPhasePreSnapshot}}
+
+	assert.False(t, IsPhaseDone(hooks, nil, harvesterv1.HookPhasePreSnapshot))
+	assert.True(t, IsPhaseDone(hooks, results, harvesterv1.HookPhasePreSnapshot))
+	// no post-snapshot hooks, nothing to wait for
+	assert.True(t, IsPhaseDone(hooks, nil, harvesterv1.HookPhasePostSnapshot))
+	assert.True(t, IsPhaseDone(nil, nil, harvesterv1.HookPhasePreSnapshot))
+}
+
+func TestGetAbortingResult(t *testing.T) {
+	hooks := &harvesterv1.BackupHooks{
+		PreSnapshot: []harvesterv1.BackupHook{
+			{Name: "abort", Command: []string{"a"}},
+			{Name: "continue", Command: []string{"c"}, OnFailure: harvesterv1.HookFailurePolicyContinue},
+		},
+		PostSnapshot: []harvesterv1.BackupHook{
+			{Name: "abort", Command: []string{"a"}},
+		},
+	}
+
+	assert.Nil(t, GetAbortingResult(hooks, []harvesterv1.HookResult{
+		{Name: "abort", Phase: harvesterv1.HookPhasePreSnapshot, Status: harvesterv1.HookStatusSucceeded},
+		{Name: "continue", Phase: harvesterv1.HookPhasePreSnapshot, Status: harvesterv1.HookStatusFailed},
+		{Name: "abort", Phase: harvesterv1.HookPhasePostSnapshot, Status: harvesterv1.HookStatusFailed},
+	}))
+
+	result := GetAbortingResult(hooks, []harvesterv1.HookResult{
+		{Name: "abort", Phase: harvesterv1.HookPhasePreSnapshot, Status: harvesterv1.HookStatusFailed},
+	})
+	assert.NotNil(t, result)
+	assert.Equal(t, "abort", result.Name)
+}
@@ -0,0 +1,74 @@
+package hook
+
+import (
+	"sync"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+// Runner runs hooks in the background, so a slow guest doesn't hold a
+// controller worker. Runs only live in memory, the controller records a
+// running status for them and gets notified once they finish.
+type Runner struct {
+	mu   sync.Mutex
+	runs map[string]*backgroundRun
+}
+
+type backgroundRun struct {
+	done    bool
+	results []harvesterv1.HookResult
+}
+
+func NewRunner() *Runner {
+	return &Runner{
+		runs: map[string]*backgroundRun{},
+	}
+}
+
+// Start runs fn in the background under the key, unless a run with the same
+// key exists. onDone is called once the results can be collected.
+func (r *Runner) Start(key string, fn func() []harvesterv1.HookResult, onDone func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.runs[key]; ok {
+		return
+	}
+
+	current := &backgroundRun{}
+	r.runs[key] = current
+	go func() {
+		results := fn()
+
+		r.mu.Lock()
+		current.done = true
+		current.results = results
+		r.mu.Unlock()
+
+		onDone()
+	}()
+}
+
+// Collect returns the results of the finished run under the key. The run is
+// kept until Forget is called, so the results can be collected again if
+// recording them fails. running is true while the run hasn't finished, found
+// is false if there is no run under the key.
+func (r *Runner) Collect(key string) (results []harvesterv1.HookResult, running bool, found bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, ok := r.runs[key]
+	if !ok {
+		return nil, false, false
+	}
+	if !current.done {
+		return nil, true, true
+	}
+	return current.results, false, true
+}
+
+// Forget drops the run under the key once its results are recorded. A run which is still going keeps
+// running in the background, but its results are never collected.
+func (r *Runner) Forget(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, key)
+}
@@ -0,0 +1,74 @@
+package hook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func TestRunner(t *testing.T) {
+	runner := NewRunner()
+	release := make(chan struct{})
+	done := make(chan struct{}, 1)
+	fn := func() []harvesterv1.HookResult {
+		<-release
+		return []harvesterv1.HookResult{{Name: "a", Status: harvesterv1.HookStatusSucceeded}}
+	}
+
+	_, running, found := runner.Collect("key")
+	assert.False(t, running)
+	assert.False(t, found)
+
+	runner.Start("key", fn, func() { done <- struct{}{} })
+	// a second start doesn't replace the run
+	runner.Start("key", func() []harvesterv1.HookResult { return nil }, func() { done <- struct{}{} })
+	_, running, found = runner.Collect("key")
+	assert.True(t, running)
+	assert.True(t, found)
+
+	close(release)
+	<-done
+	results, running, found := runner.Collect("key")
+	assert.False(t, running)
+	assert.True(t, found)
+	assert.Equal(t, []harvesterv1.HookResult{{Name: "a", Status: harvesterv1.HookStatusSucceeded}}, results)
+
+	// the results are kept until the run is forgotten
+	results, _, found = runner.Collect("key")
+	assert.True(t, found)
+	assert.Len(t, results, 1)
+	runner.Forget("key")
+	_, _, found = runner.Collect("key")
+	assert.False(t, found)
+}
+
+func TestRunnerForget(t *testing.T) {
+	runner := NewRunner()
+	done := make(chan struct{}, 1)
+	runner.Start("key", func() []harvesterv1.HookResult { return nil }, func() { done <- struct{}{} })
+	<-done
+
+	runner.Forget("key")
+	_, _, found := runner.Collect("key")
+	assert.False(t, found)
+}
+
+func TestStartAndInterrupt(t *testing.T) {
+	hooks := &harvesterv1.BackupHooks{
+		PreSnapshot: []harvesterv1.BackupHook{{Name: "a", Command: []string{"a"}}},
+	}
+	results := Start(harvesterv1.HookPhasePreSnapshot, hooks.PreSnapshot)
+
+	assert.False(t, IsPhaseDone(hooks, results, harvesterv1.HookPhasePreSnapshot))
+	assert.True(t, IsPhaseRunning(results, harvesterv1.HookPhasePreSnapshot))
+	assert.False(t, IsPhaseRunning(results, harvesterv1.HookPhasePostSnapshot))
+
+	interrupted := Interrupt(results, harvesterv1.HookPhasePreSnapshot)
+	assert.Len(t, interrupted, 1)
+	assert.Equal(t, harvesterv1.HookStatusFailed, interrupted[0].Status)
+	assert.NotNil(t, interrupted[0].CompletionTime)
+	assert.True(t, IsPhaseDone(hooks, interrupted, harvesterv1.HookPhasePreSnapshot))
+	assert.NotNil(t, GetAbortingResult(hooks, interrupted))
+}
@@ -59,7 +59,17 @@ func main() {
 					harvesterv1.ScheduleVMBackup{},
 					harvesterv1.VolumeRemoteBackup{},
 					harvesterv1.VolumeRemoteRestore{},
+					harvesterv1.ScheduleVolumeRemoteBackup{},
 					harvesterv1.VirtualMachineImageDownloader{},
+					harvesterv1.BackupTarget{},
+					harvesterv1.VirtualMachineBackupCopy{},
+					harvesterv1.BackupVerification{},
+					harvesterv1.BackupBrowseSession{},
+					harvesterv1.ImageSyncPolicy{},
+					harvesterv1.RebalancePolicy{},
+					harvesterv1.VirtualMachineGroup{},
+					harvesterv1.VMPlacementPolicy{},
+					harvesterv1.NodeMaintenanceCampaign{},
 				},
 				GenerateTypes:   true,
 				GenerateClients: true,
@@ -59,6 +59,9 @@ type Options struct {
 	RancherEmbedded bool
 	RancherURL      string
 	HCIMode         bool
+
+	ImageConversionDir       string
+	ImageConversionSizeLimit string
 }
 
 type Scaled struct {
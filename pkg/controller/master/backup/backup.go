@@ -6,9 +6,9 @@ package backup
 // 1. support VM live & offline backup to the supported backupTarget(i.e, nfs_v4 or s3 storage server).
 // 2. restore a backup to a new VM or replacing it with the existing VM is supported.
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"time"
@@ -23,16 +23,20 @@ import (
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/backup/common"
 	"github.com/harvester/harvester/pkg/backup/engine"
+	"github.com/harvester/harvester/pkg/backup/engine/export"
 	"github.com/harvester/harvester/pkg/backup/engine/longhorn"
 	"github.com/harvester/harvester/pkg/backup/engine/snapshot"
+	"github.com/harvester/harvester/pkg/backup/hook"
 	"github.com/harvester/harvester/pkg/config"
 	"github.com/harvester/harvester/pkg/generated/clientset/versioned/scheme"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
@@ -70,7 +74,7 @@ func RegisterBackup(ctx context.Context, management *config.Management, _ config
 	vmbo := newBackupOperator(controllers, restClient)
 
 	// Initialize backup engines
-	engines := newBackupEngines(controllers, vmbo)
+	engines := newBackupEngines(controllers, vmbo, management.ClientSet)
 
 	// Let each engine wire up its own informer event handlers (e.g. Job
 	// watchers) so engine-owned resource changes feed back into VMBackup
@@ -80,7 +84,8 @@ func RegisterBackup(ctx context.Context, management *config.Management, _ config
 	}
 
 	// Create and configure handler
-	handler := newBackupHandler(controllers, vmbo, engines)
+	hookExecutor := hook.NewGuestAgentExecutor(management.ClientSet, management.RestConfig)
+	handler := newBackupHandler(controllers, vmbo, engines, hookExecutor)
 
 	// Register event handlers
 	registerBackupEventHandlers(ctx, controllers, handler)
@@ -103,6 +108,7 @@ type backupControllerSet struct {
 	vscs           ctlsnapshotv1.VolumeSnapshotContentController
 	vsClasses      ctlsnapshotv1.VolumeSnapshotClassController
 	jobs           ctlbatchv1.JobController
+	backupTargets  ctlharvesterv1.BackupTargetController
 }
 
 // getBackupControllers extracts all required controllers from management
@@ -121,6 +127,7 @@ func getBackupControllers(management *config.Management) *backupControllerSet {
 		vscs:           management.SnapshotFactory.Snapshot().V1().VolumeSnapshotContent(),
 		vsClasses:      management.SnapshotFactory.Snapshot().V1().VolumeSnapshotClass(),
 		jobs:           management.BatchFactory.Batch().V1().Job(),
+		backupTargets:  management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget(),
 	}
 }
 
@@ -151,6 +158,7 @@ func newBackupOperator(
 		WithPVCCache(controllers.pvcs.Cache()).
 		WithPVCache(controllers.pvs.Cache()).
 		WithSecretCache(controllers.secrets.Cache()).
+		WithBackupTargetCache(controllers.backupTargets.Cache()).
 		WithVirtSubresourceRestClient(restClient).
 		Build()
 }
@@ -159,6 +167,7 @@ func newBackupOperator(
 func newBackupEngines(
 	controllers *backupControllerSet,
 	vmbo common.VMBackupOperator,
+	clientset kubernetes.Interface,
 ) map[harvesterv1.BackupType]engine.BackupEngine {
 	return map[harvesterv1.BackupType]engine.BackupEngine{
 		harvesterv1.Snapshot: snapshot.GetBackupEngine(
@@ -176,10 +185,28 @@ func newBackupEngines(
 			controllers.vscs,
 			controllers.pvcs.Cache(),
 			controllers.storageClasses.Cache(),
+			controllers.secrets.Cache(),
 			controllers.lhbackups.Cache(),
 			controllers.lhbackups,
+			controllers.volumes.Cache(),
+			controllers.volumes,
 			controllers.vmbs.Cache(),
 		),
+		harvesterv1.SnapshotExport: export.GetBackupEngine(
+			vmbo,
+			controllers.vss.Cache(),
+			controllers.vss,
+			controllers.vscs.Cache(),
+			controllers.vscs,
+			controllers.pvcs.Cache(),
+			controllers.pvcs,
+			controllers.storageClasses.Cache(),
+			controllers.secrets.Cache(),
+			controllers.secrets,
+			controllers.vmbs.Cache(),
+			controllers.jobs,
+			clientset,
+		),
 	}
 }
 
@@ -188,15 +215,19 @@ func newBackupHandler(
 	controllers *backupControllerSet,
 	vmbo common.VMBackupOperator,
 	engines map[harvesterv1.BackupType]engine.BackupEngine,
+	hookExecutor hook.Executor,
 ) *Handler {
 	return &Handler{
 		vmbCache:      controllers.vmbs.Cache(),
 		vmbController: controllers.vmbs,
+		vmiCache:      controllers.vmis.Cache(),
 		secretCache:   controllers.secrets.Cache(),
 		vsCache:       controllers.vss.Cache(),
 		vscCache:      controllers.vscs.Cache(),
 		vmbo:          vmbo,
 		engines:       engines,
+		hookExecutor:  hookExecutor,
+		hookRunner:    hook.NewRunner(),
 	}
 }
 
@@ -210,11 +241,14 @@ func registerBackupEventHandlers(ctx context.Context, controllers *backupControl
 type Handler struct {
 	vmbCache      ctlharvesterv1.VirtualMachineBackupCache
 	vmbController ctlharvesterv1.VirtualMachineBackupController
+	vmiCache      ctlkubevirtv1.VirtualMachineInstanceCache
 	secretCache   ctlcorev1.SecretCache
 	vsCache       ctlsnapshotv1.VolumeSnapshotCache
 	vscCache      ctlsnapshotv1.VolumeSnapshotContentCache
 	vmbo          common.VMBackupOperator
 	engines       map[harvesterv1.BackupType]engine.BackupEngine
+	hookExecutor  hook.Executor
+	hookRunner    *hook.Runner
 }
 
 // getBackupEngine selects the appropriate backup engine based on backup type
@@ -232,6 +266,9 @@ func (h *Handler) OnBackupChange(_ string, vmb *harvesterv1.VirtualMachineBackup
 	}
 
 	if h.vmbo.IsReady(vmb) {
+		if ran, err := h.reconcilePostSnapshotHooks(vmb); ran || err != nil {
+			return nil, err
+		}
 		return nil, h.handleBackupReady(vmb)
 	}
 
@@ -254,6 +291,14 @@ func (h *Handler) OnBackupChange(_ string, vmb *harvesterv1.VirtualMachineBackup
 
 	// TODO, make sure status is initialized, and "Lock" the source VM by adding a finalizer and setting snapshotInProgress in status
 
+	// run the hooks around the volume snapshots
+	if proceed, err := h.reconcilePreSnapshotHooks(vmb); !proceed || err != nil {
+		return nil, err
+	}
+	if ran, err := h.reconcilePostSnapshotHooks(vmb); ran || err != nil {
+		return nil, err
+	}
+
 	_, csiVSClassMap, err := h.vmbo.BuildCSIDriverMap(vmb)
 	if err != nil {
 		return nil, h.vmbo.UpdateError(vmb, err)
@@ -277,15 +322,33 @@ func (h *Handler) OnBackupChange(_ string, vmb *harvesterv1.VirtualMachineBackup
 // 1. Delete backup metadata from the backup target if it's not the default target
 // 2. Delete volume backups if the backup target has changed
 func (h *Handler) OnBackupRemove(_ string, vmb *harvesterv1.VirtualMachineBackup) (*harvesterv1.VirtualMachineBackup, error) {
+	if vmb != nil {
+		h.hookRunner.Forget(hookRunKey(vmb, harvesterv1.HookPhasePreSnapshot))
+		h.hookRunner.Forget(hookRunKey(vmb, harvesterv1.HookPhasePostSnapshot))
+		if releaser, ok := h.getBackupEngine(vmb).(engine.VolumeReleaser); ok {
+			if err := releaser.ReleaseVolumes(vmb); err != nil {
+				return nil, fmt.Errorf("failed to release volumes: %w", err)
+			}
+		}
+	}
+
 	// Skip if backup is nil or doesn't have status/target information
 	if vmb == nil || h.vmbo.GetStatus(vmb) == nil || h.vmbo.GetBackupTarget(vmb) == nil {
 		return nil, nil
 	}
 
-	// Get the current backup target configuration
-	currentTarget, err := settings.DecodeBackupTarget(settings.BackupTargetSet.Get())
+	// Get the current configuration of the backup target the VMBackup is stored in
+	currentTarget, err := h.vmbo.GetCurrentBackupTarget(vmb)
+	if apierrors.IsNotFound(err) {
+		logrus.WithFields(logrus.Fields{
+			"namespace":    vmb.Namespace,
+			"name":         vmb.Name,
+			"backupTarget": h.vmbo.GetBackupTargetName(vmb),
+		}).Warn("skip cleaning up the backup target, because it's removed")
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode backup target: %w", err)
+		return nil, fmt.Errorf("failed to get backup target: %w", err)
 	}
 
 	// Delete metadata from backup target if it's configured (not default)
@@ -299,8 +362,8 @@ func (h *Handler) OnBackupRemove(_ string, vmb *harvesterv1.VirtualMachineBackup
 	// 1) Target changed since creation — orphaned vol backups against the old
 	//    target need cleanup regardless of type.
 	// 2) The backup type owns remote state nothing else GCs for us — the
-	//    engine must always get a chance to clean up. Currently always false
-	//    for built-in types; reserved for future third-party engines.
+	//    engine must always get a chance to clean up, e.g. the data exported
+	//    by SnapshotExport.
 	if !h.vmbo.IsTargetConsistent(vmb, currentTarget) || h.vmbo.GetType(vmb).OwnsExternalState() {
 		if err := h.forceDeleteVolBackups(vmb); err != nil {
 			return nil, fmt.Errorf("failed to delete volume backups: %w", err)
@@ -319,6 +382,7 @@ func (h *Handler) CreateVolumeBackups(
 ) error {
 	// Create a copy to track status changes during the loop
 	vmbCpy := vmb.DeepCopy()
+	retryLater := false
 
 	vbs := h.vmbo.GetVolBackups(vmbCpy)
 	for index, vb := range vbs {
@@ -331,12 +395,11 @@ func (h *Handler) CreateVolumeBackups(
 		backupEngine := h.getBackupEngine(vmbCpy)
 		err := backupEngine.Reconcile(vmbCpy, index, csiVSClassMap)
 
-		// Handle retry case - engine needs more time to complete the operation
+		// Handle retry case - engine needs more time to complete the operation.
+		// Keep reconciling the other volumes so they progress in parallel.
 		if err == engine.ErrRetryLater {
-			namespace := h.vmbo.GetNamespace(vmbCpy)
-			name := h.vmbo.GetName(vmbCpy)
-			h.vmbController.EnqueueAfter(namespace, name, engineRetryDelay)
-			return nil
+			retryLater = true
+			continue
 		}
 
 		if err != nil {
@@ -344,15 +407,22 @@ func (h *Handler) CreateVolumeBackups(
 		}
 	}
 
-	// Update the VM backup status if any changes were made
-	_, err := h.vmbo.UpdateByStatus(vmb, vmbCpy)
-	return err
+	// Update the VM backup status if any changes were made, engines may have
+	// refreshed the progress of volumes that are still in flight.
+	if _, err := h.vmbo.UpdateByStatus(vmb, vmbCpy); err != nil {
+		return err
+	}
+
+	if retryLater {
+		h.vmbController.EnqueueAfter(h.vmbo.GetNamespace(vmbCpy), h.vmbo.GetName(vmbCpy), engineRetryDelay)
+	}
+	return nil
 }
 
 func (h *Handler) deleteVMBackupMetadata(vmb *harvesterv1.VirtualMachineBackup, target *settings.BackupTarget) error {
 	var err error
 	if target == nil {
-		if target, err = settings.DecodeBackupTarget(settings.BackupTargetSet.Get()); err != nil {
+		if target, err = h.vmbo.GetCurrentBackupTarget(vmb); err != nil {
 			return err
 		}
 	}
@@ -387,7 +457,7 @@ func (h *Handler) uploadVMBackupMetadata(vmb *harvesterv1.VirtualMachineBackup)
 		return fmt.Errorf("no backup target in vmbackup.status")
 	}
 
-	target, err := settings.DecodeBackupTarget(settings.BackupTargetSet.Get())
+	target, err := h.vmbo.GetCurrentBackupTarget(vmb)
 	if err != nil {
 		return err
 	}
@@ -407,6 +477,11 @@ func (h *Handler) uploadVMBackupMetadata(vmb *harvesterv1.VirtualMachineBackup)
 		return err
 	}
 
+	key, err := backuputil.GetEncryptionKey(h.secretCache, target)
+	if err != nil {
+		return err
+	}
+
 	vmBackupMetadata := &VirtualMachineBackupMetadata{
 		Name:          h.vmbo.GetName(vmb),
 		Namespace:     h.vmbo.GetNamespace(vmb),
@@ -428,21 +503,22 @@ func (h *Handler) uploadVMBackupMetadata(vmb *harvesterv1.VirtualMachineBackup)
 
 	// Decide whether the metadata file needs to be (re)written. Skip the write
 	// if the remote already holds an identical payload — typical for a backup
-	// recovered from the target.
+	// recovered from the target. A payload which isn't encrypted the way the
+	// backup target requires, or with another key, is rewritten.
 	needsUpload := true
 	if bsDriver.FileExists(destURL) {
-		remote, err := loadBackupMetadataInBackupTarget(destURL, bsDriver)
-		if err != nil {
+		remote, err := loadBackupMetadataInBackupTarget(destURL, bsDriver, key)
+		if err != nil && !errors.Is(err, backuputil.ErrMetadataDecryption) {
 			return err
 		}
-		if reflect.DeepEqual(vmBackupMetadata, remote) {
+		if err == nil && reflect.DeepEqual(vmBackupMetadata, remote) {
 			needsUpload = false
 		}
 	}
 
 	if needsUpload {
 		logrus.Debugf("upload vm backup metadata %s/%s to backup target %s", vmb.Namespace, vmb.Name, target.Type)
-		if err := bsDriver.Write(destURL, bytes.NewReader(j)); err != nil {
+		if err := backuputil.WriteMetadata(bsDriver, destURL, key, j); err != nil {
 			return err
 		}
 	}
@@ -1,9 +1,9 @@
 package backup
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"reflect"
 
 	// Although we don't use following drivers directly, we need to import them to register drivers.
@@ -112,6 +112,11 @@ func (h *backupBackingImageHandler) OnBackupBackingImageChange(_ string, backupB
 		return nil, err
 	}
 
+	key, err := backuputil.GetEncryptionKey(h.secretCache, target)
+	if err != nil {
+		return nil, err
+	}
+
 	vmImageMetadata := &VirtualMachineImageMetadata{
 		Name:                   vmImage.Name,
 		Namespace:              vmImage.Namespace,
@@ -133,22 +138,24 @@ func (h *backupBackingImageHandler) OnBackupBackingImageChange(_ string, backupB
 	shouldUpload := true
 	destPath := backuputil.GetVMImageMetadataFilePath(vmImage.Namespace, vmImage.Name)
 	if bsDriver.FileExists(destPath) {
-		if remoteVMImageMetadata, err := loadVMImageMetadataInBackupTarget(destPath, bsDriver); err != nil {
+		remoteVMImageMetadata, err := loadVMImageMetadataInBackupTarget(destPath, bsDriver, key)
+		if err != nil && !errors.Is(err, backuputil.ErrMetadataDecryption) {
 			return nil, err
-		} else if reflect.DeepEqual(vmImageMetadata, remoteVMImageMetadata) {
+		}
+		if err == nil && reflect.DeepEqual(vmImageMetadata, remoteVMImageMetadata) {
 			shouldUpload = false
 		}
 	}
 
 	if shouldUpload {
 		logrus.Debugf("upload vm image metadata %s/%s to backup target %s", vmImage.Namespace, vmImage.Name, target.Type)
-		if err := bsDriver.Write(destPath, bytes.NewReader(data)); err != nil {
+		if err := backuputil.WriteMetadata(bsDriver, destPath, key, data); err != nil {
 			return nil, err
 		}
 
 		vmImageCopy := vmImage.DeepCopy()
 		harvesterv1.MetadataReady.True(vmImageCopy)
-		vmImageCopy.Status.BackupTarget = &harvesterv1.BackupTarget{
+		vmImageCopy.Status.BackupTarget = &harvesterv1.BackupTargetLocation{
 			Endpoint:     target.Endpoint,
 			BucketName:   target.BucketName,
 			BucketRegion: target.BucketRegion,
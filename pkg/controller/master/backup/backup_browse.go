@@ -0,0 +1,576 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/config"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
+	"github.com/harvester/harvester/pkg/restore/pvchelper"
+	"github.com/harvester/harvester/pkg/settings"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	backupBrowseControllerName = "harvester-backup-browse-controller"
+
+	backupBrowseSuffix = "browse"
+	// backupBrowseRestoredAnnotation is set on the PVC of a snapshot-export
+	// backup once the data mover filled it.
+	backupBrowseRestoredAnnotation = "harvesterhci.io/datamover-restored"
+
+	// a preparing session is checked every backupBrowsePollInterval
+	backupBrowsePollInterval = 5 * time.Second
+	defaultBackupBrowseTTL   = time.Hour
+)
+
+// RegisterBackupBrowse registers the controller restoring volume backups for
+// browse sessions
+func RegisterBackupBrowse(ctx context.Context, management *config.Management, options config.Options) error {
+	sessions := management.HarvesterFactory.Harvesterhci().V1beta1().BackupBrowseSession()
+	vmBackups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup()
+	backupTargets := management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget()
+	pvcs := management.CoreFactory.Core().V1().PersistentVolumeClaim()
+	pods := management.CoreFactory.Core().V1().Pod()
+	secrets := management.CoreFactory.Core().V1().Secret()
+	jobs := management.BatchFactory.Batch().V1().Job()
+	vss := management.SnapshotFactory.Snapshot().V1().VolumeSnapshot()
+	vscs := management.SnapshotFactory.Snapshot().V1().VolumeSnapshotContent()
+
+	handler := &backupBrowseHandler{
+		sessions:          sessions,
+		vmBackupCache:     vmBackups.Cache(),
+		backupTargetCache: backupTargets.Cache(),
+		pvcs:              pvcs,
+		pvcCache:          pvcs.Cache(),
+		pods:              pods,
+		podCache:          pods.Cache(),
+		secrets:           secrets,
+		secretCache:       secrets.Cache(),
+		jobs:              jobs,
+		jobCache:          jobs.Cache(),
+		vss:               vss,
+		vsCache:           vss.Cache(),
+		vscs:              vscs,
+		vscCache:          vscs.Cache(),
+		clientset:         management.ClientSet,
+		namespace:         options.Namespace,
+	}
+
+	sessions.OnChange(ctx, backupBrowseControllerName, handler.OnBackupBrowseSessionChange)
+	sessions.OnRemove(ctx, backupBrowseControllerName, handler.OnBackupBrowseSessionRemove)
+	return nil
+}
+
+type backupBrowseHandler struct {
+	sessions          ctlharvesterv1.BackupBrowseSessionController
+	vmBackupCache     ctlharvesterv1.VirtualMachineBackupCache
+	backupTargetCache ctlharvesterv1.BackupTargetCache
+	pvcs              ctlcorev1.PersistentVolumeClaimClient
+	pvcCache          ctlcorev1.PersistentVolumeClaimCache
+	pods              ctlcorev1.PodClient
+	podCache          ctlcorev1.PodCache
+	secrets           ctlcorev1.SecretClient
+	secretCache       ctlcorev1.SecretCache
+	jobs              ctlbatchv1.JobClient
+	jobCache          ctlbatchv1.JobCache
+	vss               ctlsnapshotv1.VolumeSnapshotClient
+	vsCache           ctlsnapshotv1.VolumeSnapshotCache
+	vscs              ctlsnapshotv1.VolumeSnapshotContentClient
+	vscCache          ctlsnapshotv1.VolumeSnapshotContentCache
+	clientset         kubernetes.Interface
+	// namespace is where Harvester runs, the only one allowed to reach the helper pods
+	namespace string
+}
+
+// OnBackupBrowseSessionChange restores the volume backup into a PVC, starts
+// the helper pod serving its files and deletes the session once it expires.
+// The PVC, the pod, its token secret and network policy live in
+// datamover.Namespace, because the helper pod runs privileged to mount the
+// filesystems of the volume.
+func (h *backupBrowseHandler) OnBackupBrowseSessionChange(_ string, session *harvesterv1.BackupBrowseSession) (*harvesterv1.BackupBrowseSession, error) {
+	if session == nil || session.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	expiration := getBackupBrowseExpiration(session)
+	if !time.Now().Before(expiration) {
+		logrus.WithFields(getBackupBrowseLogFields(session)).Info("backup browse session expired")
+		if err := h.sessions.Delete(session.Namespace, session.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return nil, nil
+	}
+	h.sessions.EnqueueAfter(session.Namespace, session.Name, time.Until(expiration))
+
+	switch session.Status.Phase {
+	case "":
+		sessionCpy := session.DeepCopy()
+		sessionCpy.Status.Phase = harvesterv1.BackupBrowseSessionPhasePreparing
+		sessionCpy.Status.PVCName = getBackupBrowseResourceName(session)
+		sessionCpy.Status.ExpirationTime = ptr.To(metav1.NewTime(expiration))
+		harvesterv1.BackupBrowseSessionConditionReady.False(sessionCpy)
+		return h.updateStatus(session, sessionCpy)
+	case harvesterv1.BackupBrowseSessionPhasePreparing:
+		return h.prepare(session)
+	case harvesterv1.BackupBrowseSessionPhaseReady:
+		return h.checkPod(session)
+	}
+	return nil, nil
+}
+
+// prepare restores the volume backup into the PVC of the session and starts
+// the helper pod on it.
+func (h *backupBrowseHandler) prepare(session *harvesterv1.BackupBrowseSession) (*harvesterv1.BackupBrowseSession, error) {
+	vmBackup, err := h.vmBackupCache.Get(session.Namespace, session.Spec.VMBackupName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return h.fail(session, fmt.Sprintf("vm backup %s/%s not found", session.Namespace, session.Spec.VMBackupName))
+		}
+		return nil, err
+	}
+	if vmBackup.Status.Error != nil {
+		return h.fail(session, fmt.Sprintf("vm backup %s/%s failed", vmBackup.Namespace, vmBackup.Name))
+	}
+	if vmBackup.Status.ReadyToUse == nil || !*vmBackup.Status.ReadyToUse {
+		h.sessions.EnqueueAfter(session.Namespace, session.Name, backupBrowsePollInterval)
+		return nil, nil
+	}
+	vb := getVolumeBackupByVolumeName(vmBackup, session.Spec.VolumeName)
+	if vb == nil || vb.Name == nil {
+		return h.fail(session, fmt.Sprintf("volume %s not found in vm backup %s/%s", session.Spec.VolumeName, vmBackup.Namespace, vmBackup.Name))
+	}
+
+	var pvc *corev1.PersistentVolumeClaim
+	var failure string
+	if vmBackup.Spec.Type == harvesterv1.SnapshotExport {
+		pvc, failure, err = h.downloadExport(session, vmBackup, vb)
+	} else {
+		pvc, failure, err = h.restoreSnapshot(session, vmBackup, vb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return h.fail(session, failure)
+	}
+	if pvc == nil {
+		h.sessions.EnqueueAfter(session.Namespace, session.Name, backupBrowsePollInterval)
+		return nil, nil
+	}
+
+	podName := getBackupBrowseResourceName(session)
+	pod, err := h.podCache.Get(datamover.Namespace, podName)
+	if apierrors.IsNotFound(err) {
+		if err := h.createPod(session, pvc, podName); err != nil {
+			return nil, err
+		}
+		h.sessions.EnqueueAfter(session.Namespace, session.Name, backupBrowsePollInterval)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return h.fail(session, fmt.Sprintf("helper pod %s/%s exited", pod.Namespace, pod.Name))
+	}
+	if !isPodReady(pod) {
+		h.sessions.EnqueueAfter(session.Namespace, session.Name, backupBrowsePollInterval)
+		return nil, nil
+	}
+
+	logrus.WithFields(getBackupBrowseLogFields(session)).Info("backup browse session is ready")
+	sessionCpy := session.DeepCopy()
+	sessionCpy.Status.Phase = harvesterv1.BackupBrowseSessionPhaseReady
+	sessionCpy.Status.PodName = pod.Name
+	harvesterv1.BackupBrowseSessionConditionReady.True(sessionCpy)
+	harvesterv1.BackupBrowseSessionConditionReady.Message(sessionCpy, "")
+	return h.updateStatus(session, sessionCpy)
+}
+
+// getBackupBrowseResourceName names the resources in datamover.Namespace
+// working for the session: the PVC, the VolumeSnapshot and VolumeSnapshotContent
+// it's restored from, the data mover Job downloading it, and the helper pod.
+func getBackupBrowseResourceName(session *harvesterv1.BackupBrowseSession) string {
+	return datamover.ResourceName(session.Namespace, session.Name, backupBrowseSuffix)
+}
+
+func getBackupBrowseLabels(session *harvesterv1.BackupBrowseSession) map[string]string {
+	return map[string]string{
+		datamover.LabelBackupBrowseSession: session.Name,
+		datamover.LabelNamespace:           session.Namespace,
+	}
+}
+
+// restoreSnapshot restores the Longhorn backup into a PVC in
+// datamover.Namespace. A VolumeSnapshot can't be restored in another
+// namespace, so it's done from a VolumeSnapshot bound to a copy of the
+// VolumeSnapshotContent of the backup. It returns the PVC once it exists,
+// or why the restore can't succeed.
+func (h *backupBrowseHandler) restoreSnapshot(
+	session *harvesterv1.BackupBrowseSession,
+	vmBackup *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+) (*corev1.PersistentVolumeClaim, string, error) {
+	resourceName := getBackupBrowseResourceName(session)
+	pvc, err := h.pvcCache.Get(datamover.Namespace, resourceName)
+	if err == nil {
+		return pvc, "", nil
+	} else if !apierrors.IsNotFound(err) {
+		return nil, "", err
+	}
+
+	if _, err := h.vsCache.Get(datamover.Namespace, resourceName); apierrors.IsNotFound(err) {
+		failure, err := h.createVolumeSnapshot(session, vmBackup, vb)
+		return nil, failure, err
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	pvc = pvchelper.BuildPVCFromSnapshot(datamover.Namespace, resourceName, resourceName, getBackupBrowseLabels(session), nil, vb.PersistentVolumeClaim.Spec)
+	logrus.WithFields(getBackupBrowseLogFields(session)).WithField("pvc", pvc.Name).Info("restoring volume backup for backup browse session")
+	if _, err := h.pvcs.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, "", fmt.Errorf("failed to create pvc %s/%s: %w", pvc.Namespace, pvc.Name, err)
+	}
+	return nil, "", nil
+}
+
+// createVolumeSnapshot creates the VolumeSnapshot in datamover.Namespace and
+// its VolumeSnapshotContent referring the same backup as the VolumeSnapshot
+// of the volume backup. The content is retained when it's deleted, so the
+// backup is kept.
+func (h *backupBrowseHandler) createVolumeSnapshot(
+	session *harvesterv1.BackupBrowseSession,
+	vmBackup *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+) (string, error) {
+	vs, err := h.vsCache.Get(vmBackup.Namespace, *vb.Name)
+	if apierrors.IsNotFound(err) {
+		return fmt.Sprintf("volume snapshot %s/%s not found", vmBackup.Namespace, *vb.Name), nil
+	} else if err != nil {
+		return "", err
+	}
+	if vs.Status == nil || vs.Status.BoundVolumeSnapshotContentName == nil {
+		return "", nil
+	}
+	vsc, err := h.vscCache.Get(*vs.Status.BoundVolumeSnapshotContentName)
+	if err != nil {
+		return "", err
+	}
+	snapshotHandle := vsc.Spec.Source.SnapshotHandle
+	if vsc.Status != nil && vsc.Status.SnapshotHandle != nil {
+		snapshotHandle = vsc.Status.SnapshotHandle
+	}
+	if snapshotHandle == nil {
+		return "", nil
+	}
+
+	resourceName := getBackupBrowseResourceName(session)
+	labels := getBackupBrowseLabels(session)
+	content := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   resourceName,
+			Labels: labels,
+		},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			Driver:         vsc.Spec.Driver,
+			DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain,
+			Source: snapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: ptr.To(*snapshotHandle),
+			},
+			VolumeSnapshotClassName: vsc.Spec.VolumeSnapshotClassName,
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Name:      resourceName,
+				Namespace: datamover.Namespace,
+			},
+		},
+	}
+	if _, err := h.vscs.Create(content); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create volume snapshot content %s: %w", content.Name, err)
+	}
+
+	snapshot := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      resourceName,
+			Namespace: datamover.Namespace,
+			Labels:    labels,
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: ptr.To(content.Name),
+			},
+			VolumeSnapshotClassName: vsc.Spec.VolumeSnapshotClassName,
+		},
+	}
+	if _, err := h.vss.Create(snapshot); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create volume snapshot %s/%s: %w", snapshot.Namespace, snapshot.Name, err)
+	}
+	return "", nil
+}
+
+// downloadExport runs the data mover Job filling the PVC of the session in
+// datamover.Namespace with the exported volume. It returns the PVC once it's
+// filled, or why the download can't succeed.
+func (h *backupBrowseHandler) downloadExport(
+	session *harvesterv1.BackupBrowseSession,
+	vmBackup *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+) (*corev1.PersistentVolumeClaim, string, error) {
+	resourceName := getBackupBrowseResourceName(session)
+	labels := getBackupBrowseLabels(session)
+	pvc, err := h.pvcCache.Get(datamover.Namespace, resourceName)
+	if apierrors.IsNotFound(err) {
+		pvc = pvchelper.BuildEmptyPVC(datamover.Namespace, resourceName, labels, nil, vb.PersistentVolumeClaim.Spec)
+		logrus.WithFields(getBackupBrowseLogFields(session)).WithField("pvc", pvc.Name).Info("creating pvc for backup browse session")
+		if _, err := h.pvcs.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+			return nil, "", fmt.Errorf("failed to create pvc %s/%s: %w", pvc.Namespace, pvc.Name, err)
+		}
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	if pvc.Annotations[backupBrowseRestoredAnnotation] == strconv.FormatBool(true) {
+		return pvc, "", nil
+	}
+
+	job, err := h.jobCache.Get(datamover.Namespace, resourceName)
+	if apierrors.IsNotFound(err) {
+		target, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackup.Spec.BackupTargetName)
+		if err != nil {
+			return nil, "", err
+		}
+		if target.IsDefaultBackupTarget() {
+			return nil, "backup target is not set", nil
+		}
+		if !backuputil.IsBackupTargetSame(vmBackup.Status.BackupTarget, target) {
+			return nil, fmt.Sprintf("vm backup %s/%s is not in the current backup target", vmBackup.Namespace, vmBackup.Name), nil
+		}
+		return nil, "", h.createDownloadJob(session, vmBackup, vb, pvc, target, labels)
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	finished, failure := datamover.IsJobFinished(job)
+	if failure != "" || !finished {
+		return nil, failure, nil
+	}
+
+	pvcCpy := pvc.DeepCopy()
+	if pvcCpy.Annotations == nil {
+		pvcCpy.Annotations = map[string]string{}
+	}
+	pvcCpy.Annotations[backupBrowseRestoredAnnotation] = strconv.FormatBool(true)
+	pvc, err = h.pvcs.Update(pvcCpy)
+	if err != nil {
+		return nil, "", err
+	}
+	// the helper pod can't mount the PVC until the Job releases it
+	if err := h.jobs.Delete(job.Namespace, job.Name, &metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, "", fmt.Errorf("failed to delete data mover job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+	return pvc, "", nil
+}
+
+func (h *backupBrowseHandler) createDownloadJob(
+	session *harvesterv1.BackupBrowseSession,
+	vmBackup *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	staging *corev1.PersistentVolumeClaim,
+	target *settings.BackupTarget,
+	labels map[string]string,
+) error {
+	secretName, err := datamover.EnsureCredentialSecret(h.secretCache, h.secrets, target)
+	if err != nil {
+		return err
+	}
+	image, err := datamover.GetImage(h.clientset)
+	if err != nil {
+		return fmt.Errorf("failed to get data mover image: %w", err)
+	}
+
+	job, err := datamover.BuildJob(datamover.JobOptions{
+		Name:                 staging.Name,
+		Labels:               labels,
+		Image:                image,
+		Target:               target,
+		CredentialSecretName: secretName,
+		PVCName:              staging.Name,
+		VolumeMode:           staging.Spec.VolumeMode,
+		Command:              datamover.CommandDownload,
+		ExportPath:           backuputil.GetVolumeExportPath(vmBackup.Namespace, vmBackup.Name, *vb.Name),
+	})
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(getBackupBrowseLogFields(session)).WithField("job", job.Name).Info("creating data mover job to download volume export")
+	if _, err := h.jobs.Create(job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create data mover job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+	return nil
+}
+
+// OnBackupBrowseSessionRemove removes the resources of the session, they
+// aren't garbage collected with it from datamover.Namespace.
+func (h *backupBrowseHandler) OnBackupBrowseSessionRemove(_ string, session *harvesterv1.BackupBrowseSession) (*harvesterv1.BackupBrowseSession, error) {
+	if session == nil {
+		return nil, nil
+	}
+
+	resourceName := getBackupBrowseResourceName(session)
+	if err := h.pods.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete helper pod %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	if err := h.clientset.NetworkingV1().NetworkPolicies(datamover.Namespace).Delete(context.TODO(), resourceName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete network policy %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	tokenSecretName := datamover.BrowseTokenSecretName(resourceName)
+	if err := h.secrets.Delete(datamover.Namespace, tokenSecretName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete token secret %s/%s: %w", datamover.Namespace, tokenSecretName, err)
+	}
+	if err := h.jobs.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete data mover job %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	if err := h.pvcs.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete pvc %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	if err := h.vss.Delete(datamover.Namespace, resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete volume snapshot %s/%s: %w", datamover.Namespace, resourceName, err)
+	}
+	// the content is retained, deleting it leaves the backup alone
+	if err := h.vscs.Delete(resourceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete volume snapshot content %s: %w", resourceName, err)
+	}
+	return session, nil
+}
+
+// createPod starts the helper pod once its token secret and the network
+// policy isolating it exist.
+func (h *backupBrowseHandler) createPod(session *harvesterv1.BackupBrowseSession, pvc *corev1.PersistentVolumeClaim, podName string) error {
+	labels := getBackupBrowseLabels(session)
+
+	tokenSecretName := datamover.BrowseTokenSecretName(podName)
+	if _, err := h.secretCache.Get(datamover.Namespace, tokenSecretName); apierrors.IsNotFound(err) {
+		secret, err := datamover.BuildBrowseTokenSecret(tokenSecretName, labels)
+		if err != nil {
+			return err
+		}
+		if _, err := h.secrets.Create(secret); err != nil && !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create token secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	policy := datamover.BuildBrowseNetworkPolicy(podName, labels, h.namespace)
+	if _, err := h.clientset.NetworkingV1().NetworkPolicies(policy.Namespace).Create(context.TODO(), policy, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create network policy %s/%s: %w", policy.Namespace, policy.Name, err)
+	}
+
+	image, err := datamover.GetImage(h.clientset)
+	if err != nil {
+		return fmt.Errorf("failed to get data mover image: %w", err)
+	}
+	pod := datamover.BuildBrowsePod(datamover.BrowsePodOptions{
+		Name:            podName,
+		Labels:          labels,
+		Image:           image,
+		PVCName:         pvc.Name,
+		VolumeMode:      pvc.Spec.VolumeMode,
+		TokenSecretName: tokenSecretName,
+	})
+	logrus.WithFields(getBackupBrowseLogFields(session)).WithField("pod", pod.Name).Info("creating backup browse helper pod")
+	if _, err := h.pods.Create(pod); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create helper pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
+
+// checkPod fails the session if its helper pod went away before it expired.
+func (h *backupBrowseHandler) checkPod(session *harvesterv1.BackupBrowseSession) (*harvesterv1.BackupBrowseSession, error) {
+	pod, err := h.podCache.Get(datamover.Namespace, session.Status.PodName)
+	if apierrors.IsNotFound(err) {
+		return h.fail(session, fmt.Sprintf("helper pod %s/%s is gone", datamover.Namespace, session.Status.PodName))
+	} else if err != nil {
+		return nil, err
+	}
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return h.fail(session, fmt.Sprintf("helper pod %s/%s exited", pod.Namespace, pod.Name))
+	}
+	h.sessions.EnqueueAfter(session.Namespace, session.Name, backupBrowsePollInterval)
+	return nil, nil
+}
+
+// fail records why the session failed. A failed session is kept until it
+// expires, so the reason can be read.
+func (h *backupBrowseHandler) fail(session *harvesterv1.BackupBrowseSession, message string) (*harvesterv1.BackupBrowseSession, error) {
+	logrus.WithFields(getBackupBrowseLogFields(session)).WithField("reason", message).Info("backup browse session failed")
+	sessionCpy := session.DeepCopy()
+	sessionCpy.Status.Phase = harvesterv1.BackupBrowseSessionPhaseFailed
+	sessionCpy.Status.Message = message
+	harvesterv1.BackupBrowseSessionConditionReady.False(sessionCpy)
+	harvesterv1.BackupBrowseSessionConditionReady.Message(sessionCpy, message)
+	return h.updateStatus(session, sessionCpy)
+}
+
+func (h *backupBrowseHandler) updateStatus(session, sessionCpy *harvesterv1.BackupBrowseSession) (*harvesterv1.BackupBrowseSession, error) {
+	if reflect.DeepEqual(session.Status, sessionCpy.Status) {
+		return session, nil
+	}
+	return h.sessions.Update(sessionCpy)
+}
+
+func getBackupBrowseExpiration(session *harvesterv1.BackupBrowseSession) time.Time {
+	ttl := defaultBackupBrowseTTL
+	if session.Spec.TTL != nil {
+		ttl = session.Spec.TTL.Duration
+	}
+	// the webhook rejects longer TTLs, cap the sessions created before it did
+	ttl = min(ttl, datamover.BrowseMaxTTL)
+	return session.CreationTimestamp.Add(ttl)
+}
+
+func getVolumeBackupByVolumeName(vmBackup *harvesterv1.VirtualMachineBackup, volumeName string) *harvesterv1.VolumeBackup {
+	for i := range vmBackup.Status.VolumeBackups {
+		if vmBackup.Status.VolumeBackups[i].VolumeName == volumeName {
+			return &vmBackup.Status.VolumeBackups[i]
+		}
+	}
+	return nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func getBackupBrowseLogFields(session *harvesterv1.BackupBrowseSession) logrus.Fields {
+	return logrus.Fields{
+		"namespace":    session.Namespace,
+		"name":         session.Name,
+		"vmBackupName": session.Spec.VMBackupName,
+		"volumeName":   session.Spec.VolumeName,
+	}
+}
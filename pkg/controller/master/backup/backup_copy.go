@@ -0,0 +1,554 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/longhorn/backupstore"
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/config"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	backupCopyControllerName = "harvester-vm-backup-copy-controller"
+	backupCopyJobWatcherName = "vm-backup-copy-job-watcher"
+
+	backupCopyJobSuffix = "copy"
+
+	// the progress of a running copy is refreshed every backupCopyProgressInterval
+	backupCopyProgressInterval = 5 * time.Second
+	backupCopyComplete         = 100
+)
+
+// RegisterBackupCopy registers the controller copying VM backups into other backup targets
+func RegisterBackupCopy(ctx context.Context, management *config.Management, _ config.Options) error {
+	vmBackupCopies := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackupCopy()
+	vmBackups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup()
+	backupTargets := management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget()
+	secrets := management.CoreFactory.Core().V1().Secret()
+	jobs := management.BatchFactory.Batch().V1().Job()
+
+	handler := &backupCopyHandler{
+		vmBackupCopies:    vmBackupCopies,
+		vmBackupCopyCache: vmBackupCopies.Cache(),
+		vmBackupCache:     vmBackups.Cache(),
+		backupTargetCache: backupTargets.Cache(),
+		secrets:           secrets,
+		secretCache:       secrets.Cache(),
+		jobs:              jobs,
+		jobCache:          jobs.Cache(),
+		clientset:         management.ClientSet,
+	}
+
+	vmBackupCopies.OnChange(ctx, backupCopyControllerName, handler.OnBackupCopyChange)
+	vmBackupCopies.OnRemove(ctx, backupCopyControllerName, handler.OnBackupCopyRemove)
+	vmBackups.OnChange(ctx, backupCopyControllerName, handler.OnVMBackupChange)
+	jobs.OnChange(ctx, backupCopyJobWatcherName, handler.OnJobChange)
+	return nil
+}
+
+type backupCopyHandler struct {
+	vmBackupCopies    ctlharvesterv1.VirtualMachineBackupCopyController
+	vmBackupCopyCache ctlharvesterv1.VirtualMachineBackupCopyCache
+	vmBackupCache     ctlharvesterv1.VirtualMachineBackupCache
+	backupTargetCache ctlharvesterv1.BackupTargetCache
+	secrets           ctlcorev1.SecretClient
+	secretCache       ctlcorev1.SecretCache
+	jobs              ctlbatchv1.JobClient
+	jobCache          ctlbatchv1.JobCache
+	clientset         kubernetes.Interface
+}
+
+// OnBackupCopyChange resolves the backup targets of a copy, runs the data
+// mover Job copying the volume backups and copies the metadata once it's done.
+func (h *backupCopyHandler) OnBackupCopyChange(_ string, vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	if vmBackupCopy == nil || vmBackupCopy.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	// a failed copy isn't retried, it's deleted and created again
+	if vmBackupCopy.Status.Error != nil || (vmBackupCopy.Status.ReadyToUse != nil && *vmBackupCopy.Status.ReadyToUse) {
+		return nil, nil
+	}
+
+	if vmBackupCopy.Status.BackupTarget == nil {
+		return h.initBackupCopy(vmBackupCopy)
+	}
+
+	source, destination, err := h.getBackupTargets(vmBackupCopy)
+	if err != nil {
+		return h.setError(vmBackupCopy, err)
+	}
+
+	job, err := h.jobCache.Get(datamover.Namespace, backupCopyJobName(vmBackupCopy))
+	if apierrors.IsNotFound(err) {
+		return h.createCopyJob(vmBackupCopy, source, destination)
+	} else if err != nil {
+		return nil, err
+	}
+
+	finished, failure := datamover.IsJobFinished(job)
+	if failure != "" {
+		return h.setError(vmBackupCopy, errors.New(failure))
+	}
+
+	dstDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, destination)
+	if err != nil {
+		return nil, err
+	}
+	progressPath := backuputil.GetVMBackupCopyProgressPath(vmBackupCopy.Namespace, vmBackupCopy.Name)
+	progress, err := datamover.LoadProgress(dstDriver, progressPath)
+	if err != nil {
+		logrus.WithError(err).WithFields(getBackupCopyLogFields(vmBackupCopy)).Warn("failed to load data mover progress")
+	}
+
+	if !finished {
+		h.vmBackupCopies.EnqueueAfter(vmBackupCopy.Namespace, vmBackupCopy.Name, backupCopyProgressInterval)
+		if progress == nil {
+			return nil, nil
+		}
+		vmBackupCopyCpy := vmBackupCopy.DeepCopy()
+		vmBackupCopyCpy.Status.Progress = int(progress.Percentage())
+		vmBackupCopyCpy.Status.TransferredBytes = progress.TransferredBytes
+		return h.updateStatus(vmBackupCopy, vmBackupCopyCpy)
+	}
+
+	if err := h.copyMetadata(vmBackupCopy, source, destination); err != nil {
+		return h.setError(vmBackupCopy, err)
+	}
+	if err := h.deleteCopyResources(vmBackupCopy); err != nil {
+		return nil, err
+	}
+	if progress != nil {
+		if err := dstDriver.Remove(progressPath); err != nil {
+			logrus.WithError(err).WithFields(getBackupCopyLogFields(vmBackupCopy)).Warn("failed to remove data mover progress")
+		}
+	}
+
+	logrus.WithFields(getBackupCopyLogFields(vmBackupCopy)).Info("vm backup copied to the backup target")
+	vmBackupCopyCpy := vmBackupCopy.DeepCopy()
+	vmBackupCopyCpy.Status.ReadyToUse = ptr.To(true)
+	vmBackupCopyCpy.Status.Progress = backupCopyComplete
+	if progress != nil {
+		vmBackupCopyCpy.Status.TransferredBytes = progress.TransferredBytes
+	}
+	vmBackupCopyCpy.Status.CompletionTime = ptr.To(metav1.Now())
+	vmBackupCopyCpy.Status.Error = nil
+	harvesterv1.BackupCopyConditionProgressing.False(vmBackupCopyCpy)
+	harvesterv1.BackupCopyConditionReady.True(vmBackupCopyCpy)
+	harvesterv1.BackupCopyConditionReady.Message(vmBackupCopyCpy, "")
+	return h.updateStatus(vmBackupCopy, vmBackupCopyCpy)
+}
+
+// initBackupCopy records what is copied where. The source VMBackup is only
+// needed until the data mover Job exists, the copy doesn't depend on it later.
+func (h *backupCopyHandler) initBackupCopy(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	vmBackup, err := h.vmBackupCache.Get(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return h.setError(vmBackupCopy, fmt.Errorf("vm backup %s/%s not found", vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName))
+		}
+		return nil, err
+	}
+	if !vmBackup.Spec.Type.UsesRemoteBackupTarget() {
+		return h.setError(vmBackupCopy, fmt.Errorf("vm backup %s/%s of type %s isn't stored in a backup target", vmBackup.Namespace, vmBackup.Name, vmBackup.Spec.Type))
+	}
+	if vmBackup.Status.ReadyToUse == nil || !*vmBackup.Status.ReadyToUse {
+		// OnVMBackupChange enqueues the copy once the VMBackup is ready
+		return nil, nil
+	}
+
+	source, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackup.Spec.BackupTargetName)
+	if err != nil {
+		return nil, err
+	}
+	if !backuputil.IsBackupTargetSame(vmBackup.Status.BackupTarget, source) {
+		return h.setError(vmBackupCopy, fmt.Errorf("backup target of vm backup %s/%s has changed since it was created", vmBackup.Namespace, vmBackup.Name))
+	}
+	destination, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackupCopy.Spec.BackupTargetName)
+	if err != nil {
+		return nil, err
+	}
+	if destination.IsDefaultBackupTarget() {
+		return h.setError(vmBackupCopy, fmt.Errorf("backup target is not set"))
+	}
+	if backuputil.IsBackupTargetSame(vmBackup.Status.BackupTarget, destination) {
+		return h.setError(vmBackupCopy, fmt.Errorf("vm backup %s/%s is already stored in backup target %s", vmBackup.Namespace, vmBackup.Name, destination.Endpoint))
+	}
+
+	vmBackupCopyCpy := vmBackupCopy.DeepCopy()
+	vmBackupCopyCpy.Status.SourceBackupTarget = backuputil.GetBackupTargetLocation(source)
+	vmBackupCopyCpy.Status.BackupTarget = backuputil.GetBackupTargetLocation(destination)
+	vmBackupCopyCpy.Status.Type = vmBackup.Spec.Type
+	vmBackupCopyCpy.Status.VolumeBackupNames = nil
+	for _, vb := range vmBackup.Status.VolumeBackups {
+		if vb.Name != nil {
+			vmBackupCopyCpy.Status.VolumeBackupNames = append(vmBackupCopyCpy.Status.VolumeBackupNames, *vb.Name)
+		}
+	}
+	vmBackupCopyCpy.Status.ReadyToUse = ptr.To(false)
+	vmBackupCopyCpy.Status.Error = nil
+	return h.updateStatus(vmBackupCopy, vmBackupCopyCpy)
+}
+
+// getBackupTargets returns the source and destination backup targets, and
+// fails if any of them isn't the one recorded in the status anymore.
+func (h *backupCopyHandler) getBackupTargets(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) (*settings.BackupTarget, *settings.BackupTarget, error) {
+	source, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackupCopy.Status.SourceBackupTarget.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !backuputil.IsBackupTargetSame(vmBackupCopy.Status.SourceBackupTarget, source) {
+		return nil, nil, fmt.Errorf("source backup target has changed since the copy was created")
+	}
+	destination, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackupCopy.Status.BackupTarget.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !backuputil.IsBackupTargetSame(vmBackupCopy.Status.BackupTarget, destination) {
+		return nil, nil, fmt.Errorf("backup target has changed since the copy was created")
+	}
+	return source, destination, nil
+}
+
+func (h *backupCopyHandler) createCopyJob(
+	vmBackupCopy *harvesterv1.VirtualMachineBackupCopy,
+	source, destination *settings.BackupTarget,
+) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	vmBackup, err := h.vmBackupCache.Get(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return h.setError(vmBackupCopy, fmt.Errorf("vm backup %s/%s not found", vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName))
+		}
+		return nil, err
+	}
+
+	// Don't copy any data if the destination holds another VMBackup with the same name.
+	if _, err := h.loadDestinationMetadata(vmBackupCopy, source, destination); err != nil {
+		return h.setError(vmBackupCopy, err)
+	}
+
+	// The Job runs in datamover.Namespace with the credential secrets, so it
+	// can't be owned by the copy. The labels point back to it instead.
+	opts := datamover.CopyJobOptions{
+		Name: backupCopyJobName(vmBackupCopy),
+		Labels: map[string]string{
+			datamover.LabelVMBackupCopy: vmBackupCopy.Name,
+			datamover.LabelNamespace:    vmBackupCopy.Namespace,
+		},
+		Source:       source,
+		Destination:  destination,
+		ProgressPath: backuputil.GetVMBackupCopyProgressPath(vmBackupCopy.Namespace, vmBackupCopy.Name),
+	}
+	for _, vb := range vmBackup.Status.VolumeBackups {
+		if vb.Name == nil {
+			continue
+		}
+		switch vmBackup.Spec.Type {
+		case harvesterv1.SnapshotExport:
+			opts.ExportPaths = append(opts.ExportPaths, backuputil.GetVolumeExportPath(vmBackup.Namespace, vmBackup.Name, *vb.Name))
+		case harvesterv1.Backup:
+			if vb.LonghornBackupName == nil {
+				return h.setError(vmBackupCopy, fmt.Errorf("volume backup %s has no Longhorn backup", *vb.Name))
+			}
+			opts.LonghornBackups = append(opts.LonghornBackups, datamover.LonghornBackup{
+				VolumeName: vb.PersistentVolumeClaim.Spec.VolumeName,
+				BackupName: *vb.LonghornBackupName,
+			})
+		}
+	}
+
+	if opts.SourceCredentialSecretName, err = datamover.EnsureCredentialSecret(h.secretCache, h.secrets, source); err != nil {
+		return nil, err
+	}
+	if opts.DestinationCredentialSecretName, err = datamover.EnsureCredentialSecret(h.secretCache, h.secrets, destination); err != nil {
+		return nil, err
+	}
+	if opts.Image, err = datamover.GetImage(h.clientset); err != nil {
+		return nil, fmt.Errorf("failed to get data mover image: %w", err)
+	}
+
+	job, err := datamover.BuildCopyJob(opts)
+	if err != nil {
+		return nil, err
+	}
+	logrus.WithFields(getBackupCopyLogFields(vmBackupCopy)).WithField("job", job.Name).Info("creating data mover job to copy vm backup")
+	if _, err := h.jobs.Create(job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create data mover job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	vmBackupCopyCpy := vmBackupCopy.DeepCopy()
+	harvesterv1.BackupCopyConditionProgressing.True(vmBackupCopyCpy)
+	harvesterv1.BackupCopyConditionProgressing.Message(vmBackupCopyCpy, "")
+	return h.updateStatus(vmBackupCopy, vmBackupCopyCpy)
+}
+
+// loadDestinationMetadata returns the metadata of the copied VMBackup in the
+// source backup target. It fails if the destination already holds other
+// metadata for a VMBackup with the same name.
+func (h *backupCopyHandler) loadDestinationMetadata(
+	vmBackupCopy *harvesterv1.VirtualMachineBackupCopy,
+	source, destination *settings.BackupTarget,
+) ([]byte, error) {
+	srcDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, source)
+	if err != nil {
+		return nil, err
+	}
+	srcKey, err := backuputil.GetEncryptionKey(h.secretCache, source)
+	if err != nil {
+		return nil, err
+	}
+	filePath := getVMBackupMetadataFilePath(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	data, err := backuputil.ReadMetadata(srcDriver, filePath, srcKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vm backup metadata: %w", err)
+	}
+
+	dstDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, destination)
+	if err != nil {
+		return nil, err
+	}
+	if !dstDriver.FileExists(filePath) {
+		return data, nil
+	}
+	dstKey, err := backuputil.GetEncryptionKey(h.secretCache, destination)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := backuputil.ReadMetadata(dstDriver, filePath, dstKey)
+	if err != nil || !bytes.Equal(existing, data) {
+		return nil, fmt.Errorf("another vm backup %s/%s already exists in the backup target", vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	}
+	return data, nil
+}
+
+// copyMetadata writes the metadata to the destination, encrypted with its
+// own key. It's written last, so the destination only lists the VMBackup once
+// all its volume backups are there.
+func (h *backupCopyHandler) copyMetadata(
+	vmBackupCopy *harvesterv1.VirtualMachineBackupCopy,
+	source, destination *settings.BackupTarget,
+) error {
+	data, err := h.loadDestinationMetadata(vmBackupCopy, source, destination)
+	if err != nil {
+		return err
+	}
+
+	dstDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, destination)
+	if err != nil {
+		return err
+	}
+	dstKey, err := backuputil.GetEncryptionKey(h.secretCache, destination)
+	if err != nil {
+		return err
+	}
+	filePath := getVMBackupMetadataFilePath(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	return backuputil.WriteMetadata(dstDriver, filePath, dstKey, data)
+}
+
+// deleteCopyResources removes the Job of the copy. The credential secrets
+// are shared by all the data mover Jobs of the backup targets.
+func (h *backupCopyHandler) deleteCopyResources(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) error {
+	jobName := backupCopyJobName(vmBackupCopy)
+	err := h.jobs.Delete(datamover.Namespace, jobName, &metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete data mover job %s/%s: %w", datamover.Namespace, jobName, err)
+	}
+	return nil
+}
+
+// OnBackupCopyRemove stops the data mover Job and removes the copy from the
+// destination backup target, unless the destination isn't the one it was
+// copied to anymore, or a VMBackup recovered from the destination uses it.
+func (h *backupCopyHandler) OnBackupCopyRemove(_ string, vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	if vmBackupCopy == nil {
+		return nil, nil
+	}
+	if err := h.deleteCopyResources(vmBackupCopy); err != nil {
+		return nil, err
+	}
+	if vmBackupCopy.Status.BackupTarget == nil {
+		return nil, nil
+	}
+
+	logFields := getBackupCopyLogFields(vmBackupCopy)
+	destination, err := backuputil.GetBackupTarget(h.backupTargetCache, vmBackupCopy.Status.BackupTarget.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logrus.WithFields(logFields).Info("skip removing vm backup copy, the backup target is gone")
+			return nil, nil
+		}
+		return nil, err
+	}
+	if destination.IsDefaultBackupTarget() || !backuputil.IsBackupTargetSame(vmBackupCopy.Status.BackupTarget, destination) {
+		logrus.WithFields(logFields).Info("skip removing vm backup copy, the backup target has changed")
+		return nil, nil
+	}
+
+	vmBackup, err := h.vmBackupCache.Get(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && backuputil.IsBackupTargetSame(vmBackup.Status.BackupTarget, destination) {
+		logrus.WithFields(logFields).Info("skip removing vm backup copy, it's used by the vm backup")
+		return nil, nil
+	}
+
+	dstDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, destination)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.removeCopiedData(vmBackupCopy, destination, dstDriver); err != nil {
+		return nil, err
+	}
+	logrus.WithFields(logFields).Info("vm backup copy removed from the backup target")
+	return nil, nil
+}
+
+// removeCopiedData removes the metadata first, so the destination doesn't
+// list a VMBackup with missing volume backups.
+func (h *backupCopyHandler) removeCopiedData(
+	vmBackupCopy *harvesterv1.VirtualMachineBackupCopy,
+	destination *settings.BackupTarget,
+	dstDriver backupstore.BackupStoreDriver,
+) error {
+	metadataPath := getVMBackupMetadataFilePath(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	dstKey, err := backuputil.GetEncryptionKey(h.secretCache, destination)
+	if err != nil {
+		return err
+	}
+
+	// The source VMBackup may be gone, the copied Longhorn backups are looked
+	// up in the copied metadata.
+	var longhornBackups []datamover.LonghornBackup
+	if vmBackupCopy.Status.Type == harvesterv1.Backup && dstDriver.FileExists(metadataPath) {
+		metadata, err := loadBackupMetadataInBackupTarget(metadataPath, dstDriver, dstKey)
+		if err != nil {
+			return err
+		}
+		for _, vb := range metadata.VolumeBackups {
+			if vb.LonghornBackupName != nil {
+				longhornBackups = append(longhornBackups, datamover.LonghornBackup{
+					VolumeName: vb.PersistentVolumeClaim.Spec.VolumeName,
+					BackupName: *vb.LonghornBackupName,
+				})
+			}
+		}
+	}
+
+	for _, filePath := range []string{
+		metadataPath,
+		backuputil.GetVMBackupCopyProgressPath(vmBackupCopy.Namespace, vmBackupCopy.Name),
+	} {
+		if !dstDriver.FileExists(filePath) {
+			continue
+		}
+		if err := dstDriver.Remove(filePath); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range longhornBackups {
+		if err := datamover.RemoveLonghornBackup(dstDriver, b); err != nil {
+			return err
+		}
+	}
+
+	if vmBackupCopy.Status.Type != harvesterv1.SnapshotExport {
+		return nil
+	}
+	for _, vbName := range vmBackupCopy.Status.VolumeBackupNames {
+		if err := datamover.Remove(dstDriver, backuputil.GetVolumeExportPath(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName, vbName)); err != nil {
+			return err
+		}
+	}
+	// Leftover chunks only waste space, they must not block the deletion.
+	if _, err := datamover.CollectGarbage(dstDriver, dstKey); errors.Is(err, datamover.ErrUploadInProgress) {
+		logrus.WithFields(getBackupCopyLogFields(vmBackupCopy)).Info("skip collecting volume export chunks while an upload is running")
+	} else if err != nil {
+		logrus.WithError(err).WithFields(getBackupCopyLogFields(vmBackupCopy)).Warn("failed to collect volume export chunks")
+	}
+	return nil
+}
+
+// OnVMBackupChange enqueues the copies waiting for the VMBackup to be ready.
+func (h *backupCopyHandler) OnVMBackupChange(_ string, vmBackup *harvesterv1.VirtualMachineBackup) (*harvesterv1.VirtualMachineBackup, error) {
+	if vmBackup == nil || vmBackup.DeletionTimestamp != nil ||
+		vmBackup.Status.ReadyToUse == nil || !*vmBackup.Status.ReadyToUse {
+		return nil, nil
+	}
+
+	vmBackupCopies, err := h.vmBackupCopyCache.List(vmBackup.Namespace, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, vmBackupCopy := range vmBackupCopies {
+		if vmBackupCopy.Spec.VMBackupName == vmBackup.Name && vmBackupCopy.Status.BackupTarget == nil {
+			h.vmBackupCopies.Enqueue(vmBackupCopy.Namespace, vmBackupCopy.Name)
+		}
+	}
+	return nil, nil
+}
+
+// OnJobChange enqueues the copy a data mover Job works for.
+func (h *backupCopyHandler) OnJobChange(_ string, job *batchv1.Job) (*batchv1.Job, error) {
+	if job == nil || job.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	if vmBackupCopyName, ok := job.Labels[datamover.LabelVMBackupCopy]; ok && job.Namespace == datamover.Namespace {
+		h.vmBackupCopies.Enqueue(job.Labels[datamover.LabelNamespace], vmBackupCopyName)
+	}
+	return nil, nil
+}
+
+func (h *backupCopyHandler) setError(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy, err error) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	logrus.WithError(err).WithFields(getBackupCopyLogFields(vmBackupCopy)).Error("failed to copy vm backup")
+	vmBackupCopyCpy := vmBackupCopy.DeepCopy()
+	vmBackupCopyCpy.Status.ReadyToUse = ptr.To(false)
+	vmBackupCopyCpy.Status.Error = &harvesterv1.Error{
+		Time:    ptr.To(metav1.Now()),
+		Message: ptr.To(err.Error()),
+	}
+	harvesterv1.BackupCopyConditionProgressing.False(vmBackupCopyCpy)
+	harvesterv1.BackupCopyConditionReady.False(vmBackupCopyCpy)
+	harvesterv1.BackupCopyConditionReady.Message(vmBackupCopyCpy, err.Error())
+	return h.updateStatus(vmBackupCopy, vmBackupCopyCpy)
+}
+
+func (h *backupCopyHandler) updateStatus(vmBackupCopy, vmBackupCopyCpy *harvesterv1.VirtualMachineBackupCopy) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	if reflect.DeepEqual(vmBackupCopy.Status, vmBackupCopyCpy.Status) {
+		return vmBackupCopy, nil
+	}
+	return h.vmBackupCopies.Update(vmBackupCopyCpy)
+}
+
+func backupCopyJobName(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) string {
+	return datamover.ResourceName(vmBackupCopy.Namespace, vmBackupCopy.Name, backupCopyJobSuffix)
+}
+
+func getBackupCopyLogFields(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) logrus.Fields {
+	return logrus.Fields{
+		"namespace": vmBackupCopy.Namespace,
+		"name":      vmBackupCopy.Name,
+		"vmBackup":  vmBackupCopy.Spec.VMBackupName,
+	}
+}
@@ -0,0 +1,223 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/hook"
+)
+
+// reconcilePreSnapshotHooks runs the pre-snapshot hooks once, before any
+// volume snapshot is taken. The hooks run in the background and the VMBackup
+// is requeued once they finish. It returns false while the backup must not go
+// on, either because the hooks are running or their results are being
+// recorded, or because a hook aborted the backup.
+func (h *Handler) reconcilePreSnapshotHooks(vmb *harvesterv1.VirtualMachineBackup) (bool, error) {
+	hooks := h.vmbo.GetSpec(vmb).Hooks
+	results := vmb.Status.HookResults
+	if !needsHooks(vmb) {
+		return true, nil
+	}
+
+	if hook.IsPhaseDone(hooks, results, harvesterv1.HookPhasePreSnapshot) {
+		if aborting := hook.GetAbortingResult(hooks, results); aborting != nil {
+			return false, h.vmbo.UpdateError(vmb, fmt.Errorf("pre-snapshot hook %s failed: %s", aborting.Name, aborting.Message))
+		}
+		return true, nil
+	}
+
+	newResults, running, found := h.hookRunner.Collect(hookRunKey(vmb, harvesterv1.HookPhasePreSnapshot))
+	if running {
+		return false, nil
+	}
+	if found {
+		return false, h.recordHookRun(vmb, harvesterv1.HookPhasePreSnapshot, newResults)
+	}
+
+	// The hooks were started before the controller restarted, their run is lost.
+	interrupted := hook.Interrupt(results, harvesterv1.HookPhasePreSnapshot)
+
+	vmi, skipReason, err := h.getHookVMI(vmb)
+	if err != nil {
+		return false, err
+	}
+
+	if skipReason != "" {
+		newResults = interrupted
+		if len(newResults) == 0 {
+			newResults = hook.Skip(harvesterv1.HookPhasePreSnapshot, hooks.PreSnapshot, skipReason)
+		}
+		newResults = append(newResults, hook.Skip(harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot, skipReason)...)
+		return false, h.updateHookResults(vmb, newResults)
+	}
+
+	if len(interrupted) > 0 {
+		if hook.GetAbortingResult(hooks, interrupted) == nil {
+			return false, h.updateHookResults(vmb, interrupted)
+		}
+		// Resume whatever the interrupted hooks paused, no snapshot will be taken.
+		return false, h.startHooks(vmb, harvesterv1.HookPhasePreSnapshot, hook.Start(harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot), func() []harvesterv1.HookResult {
+			postResults, _ := hook.Run(context.Background(), h.hookExecutor, vmi, harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot)
+			return append(interrupted, postResults...)
+		})
+	}
+
+	return false, h.startHooks(vmb, harvesterv1.HookPhasePreSnapshot, hook.Start(harvesterv1.HookPhasePreSnapshot, hooks.PreSnapshot), func() []harvesterv1.HookResult {
+		preResults, aborted := hook.Run(context.Background(), h.hookExecutor, vmi, harvesterv1.HookPhasePreSnapshot, hooks.PreSnapshot)
+		// Resume whatever the previous hooks paused, no snapshot will be taken.
+		if aborted {
+			postResults, _ := hook.Run(context.Background(), h.hookExecutor, vmi, harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot)
+			preResults = append(preResults, postResults...)
+		}
+		return preResults
+	})
+}
+
+// reconcilePostSnapshotHooks runs the post-snapshot hooks once all volume
+// snapshots are taken. It returns true while the hooks run in the background
+// or their results are being recorded, the VMBackup is requeued for both.
+// Post-snapshot hooks resume the guest, so an interrupted run starts over.
+func (h *Handler) reconcilePostSnapshotHooks(vmb *harvesterv1.VirtualMachineBackup) (bool, error) {
+	hooks := h.vmbo.GetSpec(vmb).Hooks
+	results := vmb.Status.HookResults
+	if !needsHooks(vmb) || hook.IsPhaseDone(hooks, results, harvesterv1.HookPhasePostSnapshot) {
+		return false, nil
+	}
+	if !hook.IsPhaseDone(hooks, results, harvesterv1.HookPhasePreSnapshot) || !h.areSnapshotsTaken(vmb) {
+		return false, nil
+	}
+
+	newResults, running, found := h.hookRunner.Collect(hookRunKey(vmb, harvesterv1.HookPhasePostSnapshot))
+	if running {
+		return true, nil
+	}
+	if found {
+		return true, h.recordHookRun(vmb, harvesterv1.HookPhasePostSnapshot, newResults)
+	}
+
+	vmi, skipReason, err := h.getHookVMI(vmb)
+	if err != nil {
+		return false, err
+	}
+
+	if skipReason != "" {
+		return true, h.updateHookResults(vmb, hook.Skip(harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot, skipReason))
+	}
+	return true, h.startHooks(vmb, harvesterv1.HookPhasePostSnapshot, hook.Start(harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot), func() []harvesterv1.HookResult {
+		postResults, _ := hook.Run(context.Background(), h.hookExecutor, vmi, harvesterv1.HookPhasePostSnapshot, hooks.PostSnapshot)
+		return postResults
+	})
+}
+
+// startHooks runs fn in the background and records the running hooks. The run
+// is started first, the recorded status triggers a reconcile which must find it.
+func (h *Handler) startHooks(
+	vmb *harvesterv1.VirtualMachineBackup,
+	phase harvesterv1.HookPhase,
+	running []harvesterv1.HookResult,
+	fn func() []harvesterv1.HookResult,
+) error {
+	namespace, name := vmb.Namespace, vmb.Name
+	h.hookRunner.Start(hookRunKey(vmb, phase), fn, func() {
+		h.vmbController.Enqueue(namespace, name)
+	})
+	return h.updateHookResults(vmb, running)
+}
+
+// recordHookRun records the results of a finished run and forgets the run
+// once they are stored. A failed update keeps the run for the retry, its
+// results would be lost otherwise and the hooks taken as interrupted.
+func (h *Handler) recordHookRun(vmb *harvesterv1.VirtualMachineBackup, phase harvesterv1.HookPhase, results []harvesterv1.HookResult) error {
+	if err := h.updateHookResults(vmb, results); err != nil {
+		return err
+	}
+	h.hookRunner.Forget(hookRunKey(vmb, phase))
+	return nil
+}
+
+func hookRunKey(vmb *harvesterv1.VirtualMachineBackup, phase harvesterv1.HookPhase) string {
+	return fmt.Sprintf("%s/%s", vmb.UID, phase)
+}
+
+// needsHooks reports whether the VMBackup has hooks to run. VMBackups synced
+// from the backup target have no source UID, their hooks ran on the cluster
+// which created them.
+func needsHooks(vmb *harvesterv1.VirtualMachineBackup) bool {
+	hooks := vmb.Spec.Hooks
+	if hooks == nil || (len(hooks.PreSnapshot) == 0 && len(hooks.PostSnapshot) == 0) {
+		return false
+	}
+	return vmb.Status.SourceUID != nil
+}
+
+// getHookVMI returns the VMI to run the hooks in, or why the hooks are skipped.
+// A stopped VM doesn't need any hook, its volumes are consistent already.
+func (h *Handler) getHookVMI(vmb *harvesterv1.VirtualMachineBackup) (*kubevirtv1.VirtualMachineInstance, string, error) {
+	vmi, err := h.vmiCache.Get(h.vmbo.GetNamespace(vmb), h.vmbo.GetSourceName(vmb))
+	if apierrors.IsNotFound(err) {
+		return nil, "VM is not running", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if !vmi.IsRunning() {
+		return nil, "VM is not running", nil
+	}
+	return vmi, "", nil
+}
+
+// areSnapshotsTaken reports whether every volume snapshot has been cut by the
+// CSI driver. Failed volume backups count as well, as nothing waits for them.
+func (h *Handler) areSnapshotsTaken(vmb *harvesterv1.VirtualMachineBackup) bool {
+	for _, vb := range h.vmbo.GetVolBackups(vmb) {
+		if h.vmbo.GetVolBackupReadyToUse(&vb) || h.vmbo.GetVolBackupError(&vb) != nil {
+			continue
+		}
+		vbName := h.vmbo.GetVolBackupName(&vb)
+		if vbName == nil {
+			return false
+		}
+		vs, err := h.vsCache.Get(h.vmbo.GetNamespace(vmb), *vbName)
+		if err != nil || vs.Status == nil || vs.Status.CreationTime == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// updateHookResults records the results, they replace the recorded results
+// of the same phases.
+func (h *Handler) updateHookResults(vmb *harvesterv1.VirtualMachineBackup, results []harvesterv1.HookResult) error {
+	phases := map[harvesterv1.HookPhase]bool{}
+	for _, result := range results {
+		phases[result.Phase] = true
+		if result.Status == harvesterv1.HookStatusRunning {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"namespace": vmb.Namespace,
+			"name":      vmb.Name,
+			"hook":      result.Name,
+			"phase":     result.Phase,
+			"status":    result.Status,
+			"exitCode":  ptr.Deref(result.ExitCode, -1),
+		}).Info("backup hook finished")
+	}
+
+	vmbCpy := vmb.DeepCopy()
+	vmbCpy.Status.HookResults = nil
+	for _, result := range vmb.Status.HookResults {
+		if !phases[result.Phase] {
+			vmbCpy.Status.HookResults = append(vmbCpy.Status.HookResults, result)
+		}
+	}
+	vmbCpy.Status.HookResults = append(vmbCpy.Status.HookResults, results...)
+	_, err := h.vmbo.UpdateByStatus(vmb, vmbCpy)
+	return err
+}
@@ -0,0 +1,209 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/backup/hook"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+// blockingExecutor runs every command once release is closed, commands
+// listed in failures exit with code 1.
+type blockingExecutor struct {
+	release  chan struct{}
+	failures map[string]bool
+}
+
+func (e *blockingExecutor) Exec(_ context.Context, _ *kubevirtv1.VirtualMachineInstance, command []string) (*hook.ExecResult, error) {
+	<-e.release
+	if e.failures[command[0]] {
+		return &hook.ExecResult{ExitCode: 1}, nil
+	}
+	return &hook.ExecResult{}, nil
+}
+
+// enqueueController records the enqueued VMBackups.
+type enqueueController struct {
+	ctlharvesterv1.VirtualMachineBackupController
+	enqueued chan string
+}
+
+func (c *enqueueController) Enqueue(namespace, name string) {
+	c.enqueued <- namespace + "/" + name
+}
+
+func newTestHookVMBackup() *harvesterv1.VirtualMachineBackup {
+	return &harvesterv1.VirtualMachineBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default", UID: "backup-uid"},
+		Spec: harvesterv1.VirtualMachineBackupSpec{
+			Type:   harvesterv1.Backup,
+			Source: corev1.TypedLocalObjectReference{Name: "vm"},
+			Hooks: &harvesterv1.BackupHooks{
+				PreSnapshot:  []harvesterv1.BackupHook{{Name: "freeze", Command: []string{"freeze"}}},
+				PostSnapshot: []harvesterv1.BackupHook{{Name: "thaw", Command: []string{"thaw"}}},
+			},
+		},
+		Status: harvesterv1.VirtualMachineBackupStatus{
+			SourceUID: ptr.To(types.UID("vm-uid")),
+		},
+	}
+}
+
+func newTestHookHandler(executor hook.Executor, vmb *harvesterv1.VirtualMachineBackup) (*Handler, *enqueueController, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(vmb, &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default"},
+		Status:     kubevirtv1.VirtualMachineInstanceStatus{Phase: kubevirtv1.Running},
+	})
+	controller := &enqueueController{enqueued: make(chan string, 1)}
+	vmbo := common.NewVMBackupOperatorBuilder().
+		WithClient(fakeclients.VMBackupClient(clientset.HarvesterhciV1beta1().VirtualMachineBackups)).
+		WithCache(fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups)).
+		Build()
+	return &Handler{
+		vmbController: controller,
+		vmiCache:      fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+		vsCache:       fakeclients.VolumeSnapshotCache(clientset.SnapshotV1().VolumeSnapshots),
+		vmbo:          vmbo,
+		hookExecutor:  executor,
+		hookRunner:    hook.NewRunner(),
+	}, controller, clientset
+}
+
+func getTestHookVMBackup(t *testing.T, clientset *fake.Clientset) *harvesterv1.VirtualMachineBackup {
+	vmb, err := clientset.HarvesterhciV1beta1().VirtualMachineBackups("default").Get(context.TODO(), "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	return vmb
+}
+
+func TestReconcilePreSnapshotHooksRunInBackground(t *testing.T) {
+	executor := &blockingExecutor{release: make(chan struct{})}
+	h, controller, clientset := newTestHookHandler(executor, newTestHookVMBackup())
+
+	// the hooks are started and recorded as running, the reconcile doesn't wait for them
+	proceed, err := h.reconcilePreSnapshotHooks(newTestHookVMBackup())
+	require.NoError(t, err)
+	assert.False(t, proceed)
+	vmb := getTestHookVMBackup(t, clientset)
+	require.Len(t, vmb.Status.HookResults, 1)
+	assert.Equal(t, harvesterv1.HookStatusRunning, vmb.Status.HookResults[0].Status)
+
+	proceed, err = h.reconcilePreSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.False(t, proceed)
+
+	// the finished run requeues the VMBackup and its results replace the running ones
+	close(executor.release)
+	assert.Equal(t, "default/backup", <-controller.enqueued)
+	proceed, err = h.reconcilePreSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.False(t, proceed)
+	vmb = getTestHookVMBackup(t, clientset)
+	require.Len(t, vmb.Status.HookResults, 1)
+	assert.Equal(t, harvesterv1.HookPhasePreSnapshot, vmb.Status.HookResults[0].Phase)
+	assert.Equal(t, harvesterv1.HookStatusSucceeded, vmb.Status.HookResults[0].Status)
+
+	proceed, err = h.reconcilePreSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.True(t, proceed)
+}
+
+func TestReconcilePreSnapshotHooksInterrupted(t *testing.T) {
+	vmb := newTestHookVMBackup()
+	vmb.Status.HookResults = hook.Start(harvesterv1.HookPhasePreSnapshot, vmb.Spec.Hooks.PreSnapshot)
+	executor := &blockingExecutor{release: make(chan struct{})}
+	close(executor.release)
+	h, controller, clientset := newTestHookHandler(executor, vmb)
+
+	// the lost run aborts the backup, the post-snapshot hooks resume the guest
+	proceed, err := h.reconcilePreSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.False(t, proceed)
+	<-controller.enqueued
+
+	proceed, err = h.reconcilePreSnapshotHooks(getTestHookVMBackup(t, clientset))
+	require.NoError(t, err)
+	assert.False(t, proceed)
+	vmb = getTestHookVMBackup(t, clientset)
+	require.Len(t, vmb.Status.HookResults, 2)
+	assert.Equal(t, harvesterv1.HookStatusFailed, vmb.Status.HookResults[0].Status)
+	assert.Contains(t, vmb.Status.HookResults[0].Message, "interrupted")
+	assert.Equal(t, harvesterv1.HookPhasePostSnapshot, vmb.Status.HookResults[1].Phase)
+	assert.Equal(t, harvesterv1.HookStatusSucceeded, vmb.Status.HookResults[1].Status)
+}
+
+func TestReconcilePostSnapshotHooksRunInBackground(t *testing.T) {
+	vmb := newTestHookVMBackup()
+	vmb.Status.HookResults = []harvesterv1.HookResult{
+		{Name: "freeze", Phase: harvesterv1.HookPhasePreSnapshot, Status: harvesterv1.HookStatusSucceeded},
+	}
+	executor := &blockingExecutor{release: make(chan struct{}), failures: map[string]bool{"thaw": true}}
+	h, controller, clientset := newTestHookHandler(executor, vmb)
+
+	ran, err := h.reconcilePostSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	vmb = getTestHookVMBackup(t, clientset)
+	assert.True(t, hook.IsPhaseRunning(vmb.Status.HookResults, harvesterv1.HookPhasePostSnapshot))
+
+	close(executor.release)
+	<-controller.enqueued
+	ran, err = h.reconcilePostSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	vmb = getTestHookVMBackup(t, clientset)
+	require.Len(t, vmb.Status.HookResults, 2)
+	assert.Equal(t, harvesterv1.HookStatusFailed, vmb.Status.HookResults[1].Status)
+
+	ran, err = h.reconcilePostSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.False(t, ran)
+}
+
+func TestReconcilePreSnapshotHooksRecordFailure(t *testing.T) {
+	executor := &blockingExecutor{release: make(chan struct{})}
+	h, controller, clientset := newTestHookHandler(executor, newTestHookVMBackup())
+
+	_, err := h.reconcilePreSnapshotHooks(newTestHookVMBackup())
+	require.NoError(t, err)
+	close(executor.release)
+	<-controller.enqueued
+
+	// the results of the run survive a failed status update
+	vmb := getTestHookVMBackup(t, clientset)
+	failUpdate := true
+	clientset.PrependReactor("update", "virtualmachinebackups", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failUpdate {
+			failUpdate = false
+			return true, nil, errors.New("conflict")
+		}
+		return false, nil, nil
+	})
+	_, err = h.reconcilePreSnapshotHooks(vmb)
+	require.Error(t, err)
+
+	proceed, err := h.reconcilePreSnapshotHooks(vmb)
+	require.NoError(t, err)
+	assert.False(t, proceed)
+	vmb = getTestHookVMBackup(t, clientset)
+	require.Len(t, vmb.Status.HookResults, 1)
+	assert.Equal(t, harvesterv1.HookStatusSucceeded, vmb.Status.HookResults[0].Status)
+
+	_, _, found := h.hookRunner.Collect(hookRunKey(vmb, harvesterv1.HookPhasePreSnapshot))
+	assert.False(t, found)
+}
@@ -1,9 +1,9 @@
 package backup
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"path/filepath"
@@ -27,6 +27,7 @@ import (
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/backup/datamover"
 	"github.com/harvester/harvester/pkg/config"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctllonghornv1 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
@@ -72,6 +73,7 @@ type MetadataHandler struct {
 	vmImages                        ctlharvesterv1.VirtualMachineImageClient
 	vmImageCache                    ctlharvesterv1.VirtualMachineImageCache
 	storageClassCache               ctlstoragev1.StorageClassCache
+	backupTargets                   ctlharvesterv1.BackupTargetController
 	vmbo                            common.VMBackupOperator
 }
 
@@ -86,12 +88,14 @@ func RegisterBackupMetadata(ctx context.Context, management *config.Management,
 	longhornBackupBackingImages := management.LonghornFactory.Longhorn().V1beta2().BackupBackingImage()
 	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
 	storageClass := management.StorageFactory.Storage().V1().StorageClass()
+	backupTargets := management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget()
 
 	vmbo := common.NewVMBackupOperatorBuilder().
 		WithClient(vmBackups).
 		WithCache(vmBackups.Cache()).
 		WithVMCache(vms.Cache()).
 		WithSecretCache(secrets.Cache()).
+		WithBackupTargetCache(backupTargets.Cache()).
 		Build()
 
 	backupMetadataController := &MetadataHandler{
@@ -106,10 +110,12 @@ func RegisterBackupMetadata(ctx context.Context, management *config.Management,
 		vmImages:                        vmImages,
 		vmImageCache:                    vmImages.Cache(),
 		storageClassCache:               storageClass.Cache(),
+		backupTargets:                   backupTargets,
 		vmbo:                            vmbo,
 	}
 
 	settings.OnChange(ctx, backupMetadataControllerName, backupMetadataController.OnBackupTargetChange)
+	backupTargets.OnChange(ctx, backupTargetMetadataControllerName, backupMetadataController.OnBackupTargetResourceChange)
 	return nil
 }
 
@@ -237,6 +243,10 @@ func (h *MetadataHandler) syncVMImage(target *settings.BackupTarget) error {
 	if err != nil {
 		return err
 	}
+	key, err := backuputil.GetEncryptionKey(h.secretCache, target)
+	if err != nil {
+		return err
+	}
 
 	namespaceFolders, err := bsDriver.List(filepath.Join(backuputil.VMImageMetadataFolderPath))
 	if err != nil {
@@ -249,7 +259,12 @@ func (h *MetadataHandler) syncVMImage(target *settings.BackupTarget) error {
 			return err
 		}
 		for _, fileName := range fileNames {
-			imageMetadata, err := loadVMImageMetadataInBackupTarget(filepath.Join(backuputil.VMImageMetadataFolderPath, namespaceFolder, fileName), bsDriver)
+			filePath := filepath.Join(backuputil.VMImageMetadataFolderPath, namespaceFolder, fileName)
+			imageMetadata, err := loadVMImageMetadataInBackupTarget(filePath, bsDriver, key)
+			if errors.Is(err, backuputil.ErrMetadataDecryption) {
+				logrus.WithError(err).WithField("filePath", filePath).Warn("skip creating vm image, because the metadata can't be decrypted")
+				continue
+			}
 			if err != nil {
 				return err
 			}
@@ -369,6 +384,10 @@ func (h *MetadataHandler) syncVMBackup(target *settings.BackupTarget) error {
 	if err != nil {
 		return err
 	}
+	key, err := backuputil.GetEncryptionKey(h.secretCache, target)
+	if err != nil {
+		return err
+	}
 
 	fileNames, err := bsDriver.List(filepath.Join(vmBackupMetadataFolderPath))
 	if err != nil {
@@ -386,7 +405,7 @@ func (h *MetadataHandler) syncVMBackup(target *settings.BackupTarget) error {
 		namespaceFolderSet[filePath] = true
 	}
 
-	if err = h.moveFilePaths(requiredMovingFilePaths, bsDriver, namespaceFolderSet); err != nil {
+	if err = h.moveFilePaths(requiredMovingFilePaths, bsDriver, key, namespaceFolderSet); err != nil {
 		return err
 	}
 
@@ -405,7 +424,7 @@ func (h *MetadataHandler) syncVMBackup(target *settings.BackupTarget) error {
 		}
 	}
 
-	return h.loadBackupMetadataAndCreateVMBackup(target, vmbackupMetadataFilePaths, bsDriver)
+	return h.loadBackupMetadataAndCreateVMBackup(target, vmbackupMetadataFilePaths, bsDriver, key)
 }
 
 func (h *MetadataHandler) createVMBackupIfNotExist(backupMetadata VirtualMachineBackupMetadata, target *settings.BackupTarget) error {
@@ -432,19 +451,18 @@ func (h *MetadataHandler) createVMBackupIfNotExist(backupMetadata VirtualMachine
 	if err := h.createNamespaceIfNotExist(backupMetadata.Namespace); err != nil {
 		return err
 	}
+	// The backup target may have another name in the cluster which created the backup.
+	spec := backupMetadata.BackupSpec
+	spec.BackupTargetName = target.Name
 	if _, err := h.vmBackups.Create(&harvesterv1.VirtualMachineBackup{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      backupMetadata.Name,
 			Namespace: backupMetadata.Namespace,
 		},
-		Spec: backupMetadata.BackupSpec,
+		Spec: spec,
 		Status: harvesterv1.VirtualMachineBackupStatus{
-			ReadyToUse: ptr.To(false),
-			BackupTarget: &harvesterv1.BackupTarget{
-				Endpoint:     target.Endpoint,
-				BucketName:   target.BucketName,
-				BucketRegion: target.BucketRegion,
-			},
+			ReadyToUse:    ptr.To(false),
+			BackupTarget:  backuputil.GetBackupTargetLocation(target),
 			SourceSpec:    backupMetadata.VMSourceSpec,
 			VolumeBackups: backupMetadata.VolumeBackups,
 			SecretBackups: backupMetadata.SecretBackups,
@@ -474,9 +492,14 @@ func (h *MetadataHandler) createNamespaceIfNotExist(namespace string) error {
 	return err
 }
 
-func (h *MetadataHandler) loadBackupMetadataAndCreateVMBackup(target *settings.BackupTarget, filePaths []string, bsDriver backupstore.BackupStoreDriver) error {
+func (h *MetadataHandler) loadBackupMetadataAndCreateVMBackup(target *settings.BackupTarget, filePaths []string, bsDriver backupstore.BackupStoreDriver, key []byte) error {
 	for _, filePath := range filePaths {
-		backupMetadata, err := loadBackupMetadataInBackupTarget(filePath, bsDriver)
+		backupMetadata, err := loadBackupMetadataInBackupTarget(filePath, bsDriver, key)
+		if errors.Is(err, backuputil.ErrMetadataDecryption) {
+			// The backup may be written by another cluster with a different key.
+			logrus.WithError(err).WithField("filePath", filePath).Warn("skip creating vm backup, because the metadata can't be decrypted")
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -486,7 +509,11 @@ func (h *MetadataHandler) loadBackupMetadataAndCreateVMBackup(target *settings.B
 		if !h.checkDependentStorageClassExist(backupMetadata) {
 			continue
 		}
-		if !h.checkDependentLonghornBackupExist(target, backupMetadata) {
+		if backupMetadata.BackupSpec.Type == harvesterv1.SnapshotExport {
+			if !h.checkDependentVolumeExportExist(backupMetadata, bsDriver) {
+				continue
+			}
+		} else if !h.checkDependentLonghornBackupExist(target, backupMetadata, bsDriver) {
 			continue
 		}
 		if err := h.createVMBackupIfNotExist(*backupMetadata, target); err != nil {
@@ -520,7 +547,7 @@ func (h *MetadataHandler) checkDependentStorageClassExist(backupMetadata *Virtua
 	return true
 }
 
-func (h *MetadataHandler) checkDependentLonghornBackupExist(target *settings.BackupTarget, backupMetadata *VirtualMachineBackupMetadata) bool {
+func (h *MetadataHandler) checkDependentLonghornBackupExist(target *settings.BackupTarget, backupMetadata *VirtualMachineBackupMetadata, bsDriver backupstore.BackupStoreDriver) bool {
 	for _, vb := range backupMetadata.VolumeBackups {
 		if vb.LonghornBackupName == nil {
 			logrus.WithFields(logrus.Fields{
@@ -533,16 +560,8 @@ func (h *MetadataHandler) checkDependentLonghornBackupExist(target *settings.Bac
 
 		volumeName := vb.PersistentVolumeClaim.Spec.VolumeName
 		// check whether data is in the backup target
-		volumes, err := backupstore.List(volumeName, backuputil.ConstructEndpoint(target), false)
-		if err != nil || volumes[volumeName] == nil {
-			logrus.WithError(err).WithFields(logrus.Fields{
-				"namespace": backupMetadata.Namespace,
-				"name":      backupMetadata.Name,
-				"volume":    volumeName,
-			}).Warn("skip creating vm backup, because the volume is not found in the backup target")
-			return false
-		}
-		if volumes[volumeName].Backups[*vb.LonghornBackupName] == nil {
+		lhBackup := datamover.LonghornBackup{VolumeName: volumeName, BackupName: *vb.LonghornBackupName}
+		if !datamover.LonghornBackupExists(bsDriver, lhBackup) {
 			logrus.WithFields(logrus.Fields{
 				"namespace":      backupMetadata.Namespace,
 				"name":           backupMetadata.Name,
@@ -567,14 +586,52 @@ func (h *MetadataHandler) checkDependentLonghornBackupExist(target *settings.Bac
 				"longhornBackup": *vb.LonghornBackupName,
 			}).Warn("skip creating vm backup, because the longhorn backup is being deleted")
 			return false
+		} else if backup.Status.BackupTargetName != "" && backup.Status.BackupTargetName != backuputil.GetLonghornBackupTargetName(target.Name) {
+			logrus.WithFields(logrus.Fields{
+				"namespace":      backupMetadata.Namespace,
+				"name":           backupMetadata.Name,
+				"longhornBackup": *vb.LonghornBackupName,
+				"backupTarget":   backup.Status.BackupTargetName,
+			}).Warn("skip creating vm backup, because the longhorn backup is in another backup target")
+			return false
+		}
+	}
+	return true
+}
+
+// checkDependentVolumeExportExist makes sure every volume of a snapshot-export backup
+// was completely written to the backup target before the VMBackup is recreated from it.
+func (h *MetadataHandler) checkDependentVolumeExportExist(backupMetadata *VirtualMachineBackupMetadata, bsDriver backupstore.BackupStoreDriver) bool {
+	for _, vb := range backupMetadata.VolumeBackups {
+		if vb.Name == nil {
+			logrus.WithFields(logrus.Fields{
+				"namespace": backupMetadata.Namespace,
+				"name":      backupMetadata.Name,
+				"volume":    vb.VolumeName,
+			}).Warn("skip creating vm backup, because the volume backup has no name")
+			return false
+		}
+
+		exportPath := backuputil.GetVolumeExportPath(backupMetadata.Namespace, backupMetadata.Name, *vb.Name)
+		if !datamover.ManifestExists(bsDriver, exportPath) {
+			logrus.WithFields(logrus.Fields{
+				"namespace":    backupMetadata.Namespace,
+				"name":         backupMetadata.Name,
+				"volumeBackup": *vb.Name,
+			}).Warn("skip creating vm backup, because the volume export is not found in the backup target")
+			return false
 		}
 	}
 	return true
 }
 
-func (h *MetadataHandler) moveFilePaths(filePaths []string, bsDriver backupstore.BackupStoreDriver, namespaceFolderSet map[string]bool) error {
+func (h *MetadataHandler) moveFilePaths(filePaths []string, bsDriver backupstore.BackupStoreDriver, key []byte, namespaceFolderSet map[string]bool) error {
 	for _, filePath := range filePaths {
-		backupMetadata, err := loadBackupMetadataInBackupTarget(filePath, bsDriver)
+		backupMetadata, err := loadBackupMetadataInBackupTarget(filePath, bsDriver, key)
+		if errors.Is(err, backuputil.ErrMetadataDecryption) {
+			logrus.WithError(err).WithField("filePath", filePath).Warn("skip moving vm backup metadata, because it can't be decrypted")
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -591,7 +648,7 @@ func (h *MetadataHandler) moveFilePaths(filePaths []string, bsDriver backupstore
 
 		newFilePath := getVMBackupMetadataFilePath(backupMetadata.Namespace, backupMetadata.Name)
 		logrus.Infof("move vm backup metadata %s/%s from %s to %s", backupMetadata.Namespace, backupMetadata.Name, filePath, newFilePath)
-		if err = bsDriver.Write(newFilePath, bytes.NewReader(j)); err != nil {
+		if err = backuputil.WriteMetadata(bsDriver, newFilePath, key, j); err != nil {
 			return err
 		}
 		if err = bsDriver.Remove(filePath); err != nil {
@@ -601,37 +658,29 @@ func (h *MetadataHandler) moveFilePaths(filePaths []string, bsDriver backupstore
 	return nil
 }
 
-func loadVMImageMetadataInBackupTarget(filePath string, bsDriver backupstore.BackupStoreDriver) (*VirtualMachineImageMetadata, error) {
-	if !bsDriver.FileExists(filePath) {
-		return nil, fmt.Errorf("cannot find %v in backupstore", filePath)
-	}
-
-	rc, err := bsDriver.Read(filePath)
+// loadVMImageMetadataInBackupTarget reads the VM image metadata file and decrypts it with the key.
+func loadVMImageMetadataInBackupTarget(filePath string, bsDriver backupstore.BackupStoreDriver, key []byte) (*VirtualMachineImageMetadata, error) {
+	data, err := backuputil.ReadMetadata(bsDriver, filePath, key)
 	if err != nil {
 		return nil, err
 	}
-	defer rc.Close()
 
 	imageMetadata := &VirtualMachineImageMetadata{}
-	if err := json.NewDecoder(rc).Decode(imageMetadata); err != nil {
+	if err := json.Unmarshal(data, imageMetadata); err != nil {
 		return nil, err
 	}
 	return imageMetadata, nil
 }
 
-func loadBackupMetadataInBackupTarget(filePath string, bsDriver backupstore.BackupStoreDriver) (*VirtualMachineBackupMetadata, error) {
-	if !bsDriver.FileExists(filePath) {
-		return nil, fmt.Errorf("cannot find %v in backupstore", filePath)
-	}
-
-	rc, err := bsDriver.Read(filePath)
+// loadBackupMetadataInBackupTarget reads the VM backup metadata file and decrypts it with the key.
+func loadBackupMetadataInBackupTarget(filePath string, bsDriver backupstore.BackupStoreDriver, key []byte) (*VirtualMachineBackupMetadata, error) {
+	data, err := backuputil.ReadMetadata(bsDriver, filePath, key)
 	if err != nil {
 		return nil, err
 	}
-	defer rc.Close()
 
 	backupMetadata := &VirtualMachineBackupMetadata{}
-	if err := json.NewDecoder(rc).Decode(backupMetadata); err != nil {
+	if err := json.Unmarshal(data, backupMetadata); err != nil {
 		return nil, err
 	}
 	return backupMetadata, nil
@@ -651,6 +700,9 @@ func (h *MetadataHandler) checkReadyVMBackup(vmbs []*harvesterv1.VirtualMachineB
 		if h.vmbo.GetType(vmb) == harvesterv1.Snapshot || !h.vmbo.IsReady(vmb) {
 			continue
 		}
+		if h.vmbo.GetBackupTargetName(vmb) != target.Name {
+			continue
+		}
 
 		if err := h.validateAndUpdateVMBackup(vmb, target); err != nil {
 			return err
@@ -707,6 +759,10 @@ func (h *MetadataHandler) checkReadyVMBackupVolume(
 	vb *harvesterv1.VolumeBackup,
 	target *settings.BackupTarget,
 ) (string, error) {
+	if h.vmbo.GetType(vmb) == harvesterv1.SnapshotExport {
+		return h.checkVolumeExportInBackupTarget(vmb, vb, target)
+	}
+
 	// Skip backup target checks if no Longhorn backup exists
 	if h.vmbo.GetVolBackupLHBackupName(vb) == nil {
 		return "", nil
@@ -815,30 +871,21 @@ func (h *MetadataHandler) checkVolumeInBackupTarget(
 	vb *harvesterv1.VolumeBackup,
 	target *settings.BackupTarget,
 ) (string, error) {
-	// backupstore looks up by Longhorn volume name, which is the PV name.
+	// Longhorn stores the backups by volume name, which is the PV name.
 	pvName := h.vmbo.GetVolBackupPVName(vb)
 	lhBackupName := h.vmbo.GetVolBackupLHBackupName(vb)
 
-	volumes, err := backupstore.List(pvName, backuputil.ConstructEndpoint(target), false)
+	bsDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, target)
 	if err != nil {
 		// The backup target may be offline. In this case, we don't want to trigger reconciliation.
 		return err.Error(), nil
 	}
 
-	if volumes[pvName] == nil {
-		return h.markVolumeNotReady(
-			vmb, vb,
-			"cannot find volume in the backup target for a ready VMBackup, change the VMBackup to not ready",
-			fmt.Sprintf("cannot find volume %s in the backup target", pvName),
-			logrus.Fields{"volume": pvName},
-		)
-	}
-
-	if volumes[pvName].Backups[*lhBackupName] == nil {
+	if !datamover.LonghornBackupExists(bsDriver, datamover.LonghornBackup{VolumeName: pvName, BackupName: *lhBackupName}) {
 		return h.markVolumeNotReady(
 			vmb, vb,
 			"cannot find Longhorn backup in the backup target for a ready VMBackup, change the VMBackup to not ready",
-			fmt.Sprintf("cannot find longhorn backup %s in the backup target", *lhBackupName),
+			fmt.Sprintf("cannot find longhorn backup %s of volume %s in the backup target", *lhBackupName, pvName),
 			logrus.Fields{
 				"pvName":         pvName,
 				"longhornBackup": *lhBackupName,
@@ -848,3 +895,32 @@ func (h *MetadataHandler) checkVolumeInBackupTarget(
 
 	return "", nil
 }
+
+func (h *MetadataHandler) checkVolumeExportInBackupTarget(
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	target *settings.BackupTarget,
+) (string, error) {
+	vbName := h.vmbo.GetVolBackupName(vb)
+	if vbName == nil {
+		return "", nil
+	}
+
+	bsDriver, err := backuputil.GetBackupStoreDriver(h.secretCache, target)
+	if err != nil {
+		// The backup target may be offline. In this case, we don't want to trigger reconciliation.
+		return err.Error(), nil
+	}
+
+	exportPath := backuputil.GetVolumeExportPath(h.vmbo.GetNamespace(vmb), h.vmbo.GetName(vmb), *vbName)
+	if datamover.ManifestExists(bsDriver, exportPath) {
+		return "", nil
+	}
+
+	return h.markVolumeNotReady(
+		vmb, vb,
+		"cannot find volume export in the backup target for a ready VMBackup, change the VMBackup to not ready",
+		fmt.Sprintf("cannot find volume export %s in the backup target", *vbName),
+		logrus.Fields{"volumeBackup": *vbName},
+	)
+}
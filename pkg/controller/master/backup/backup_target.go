@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	longhorntypes "github.com/longhorn/longhorn-manager/types"
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -26,7 +28,9 @@ import (
 )
 
 const (
-	backupTargetControllerName = "harvester-backup-target-controller"
+	backupTargetControllerName           = "harvester-backup-target-controller"
+	longhornBackupTargetControllerName   = "harvester-longhorn-backup-target-controller"
+	longhornBackupTargetSecretNamePrefix = "harvester-backup-target"
 )
 
 // RegisterBackupTarget register the setting controller and reconsile longhorn setting when backup target changed
@@ -34,6 +38,7 @@ func RegisterBackupTarget(ctx context.Context, management *config.Management, _
 	settings := management.HarvesterFactory.Harvesterhci().V1beta1().Setting()
 	secrets := management.CoreFactory.Core().V1().Secret()
 	lhBackupTargets := management.LonghornFactory.Longhorn().V1beta2().BackupTarget()
+	backupTargets := management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget()
 
 	backupTargetController := &TargetHandler{
 		ctx:                 ctx,
@@ -45,6 +50,8 @@ func RegisterBackupTarget(ctx context.Context, management *config.Management, _
 	}
 
 	settings.OnChange(ctx, backupTargetControllerName, backupTargetController.OnBackupTargetChange)
+	backupTargets.OnChange(ctx, longhornBackupTargetControllerName, backupTargetController.OnBackupTargetResourceChange)
+	backupTargets.OnRemove(ctx, longhornBackupTargetControllerName, backupTargetController.OnBackupTargetResourceRemove)
 	return nil
 }
 
@@ -136,6 +143,136 @@ func (h *TargetHandler) OnBackupTargetChange(_ string, setting *harvesterv1.Sett
 	return setting, nil
 }
 
+// OnBackupTargetResourceChange syncs a BackupTarget resource to a Longhorn
+// BackupTarget of its own, so Longhorn backups can be stored in it.
+func (h *TargetHandler) OnBackupTargetResourceChange(_ string, bt *harvesterv1.BackupTarget) (*harvesterv1.BackupTarget, error) {
+	if bt == nil || bt.DeletionTimestamp != nil {
+		return bt, nil
+	}
+
+	target := backuputil.ToSettingBackupTarget(bt)
+	lhBackupTarget := &lhv1beta2.BackupTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backuputil.GetLonghornBackupTargetName(bt.Name),
+			Namespace: util.LonghornSystemNamespaceName,
+		},
+		Spec: lhv1beta2.BackupTargetSpec{
+			BackupTargetURL: backuputil.ConstructEndpoint(target),
+			PollInterval: metav1.Duration{
+				Duration: time.Duration(target.RefreshIntervalInSeconds) * time.Second,
+			},
+		},
+	}
+
+	if target.Type == settings.S3BackupType {
+		secretName, err := h.updateLonghornBackupTargetResourceSecret(target)
+		if err != nil {
+			return bt, err
+		}
+		lhBackupTarget.Spec.CredentialSecret = secretName
+	} else if err := h.removeLonghornBackupTargetResourceSecret(bt.Name); err != nil {
+		return bt, err
+	}
+
+	existing, err := h.lhBackupTargetCache.Get(lhBackupTarget.Namespace, lhBackupTarget.Name)
+	if apierrors.IsNotFound(err) {
+		_, err = h.lhBackupTargets.Create(lhBackupTarget)
+		return bt, err
+	}
+	if err != nil {
+		return bt, err
+	}
+
+	existingCpy := existing.DeepCopy()
+	existingCpy.Spec.BackupTargetURL = lhBackupTarget.Spec.BackupTargetURL
+	existingCpy.Spec.CredentialSecret = lhBackupTarget.Spec.CredentialSecret
+	existingCpy.Spec.PollInterval = lhBackupTarget.Spec.PollInterval
+	if reflect.DeepEqual(existing, existingCpy) {
+		return bt, nil
+	}
+	_, err = h.lhBackupTargets.Update(existingCpy)
+	return bt, err
+}
+
+// OnBackupTargetResourceRemove removes the Longhorn BackupTarget of a
+// BackupTarget resource. The webhook only lets unused BackupTargets go, and
+// Longhorn leaves the backups in the target alone.
+func (h *TargetHandler) OnBackupTargetResourceRemove(_ string, bt *harvesterv1.BackupTarget) (*harvesterv1.BackupTarget, error) {
+	if bt == nil {
+		return bt, nil
+	}
+
+	err := h.lhBackupTargets.Delete(util.LonghornSystemNamespaceName, backuputil.GetLonghornBackupTargetName(bt.Name), &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return bt, err
+	}
+	return bt, h.removeLonghornBackupTargetResourceSecret(bt.Name)
+}
+
+func getLonghornBackupTargetResourceSecretName(backupTargetName string) string {
+	return name.SafeConcatName(longhornBackupTargetSecretNamePrefix, backupTargetName)
+}
+
+// updateLonghornBackupTargetResourceSecret copies the credentials of an S3
+// BackupTarget resource to longhorn-system, where Longhorn reads them from.
+func (h *TargetHandler) updateLonghornBackupTargetResourceSecret(target *settings.BackupTarget) (string, error) {
+	credentials, err := backuputil.GetBackupTargetCredentials(h.secretCache, target)
+	if err != nil {
+		return "", err
+	}
+	targetCpy := *target
+	targetCpy.AccessKeyID = string(credentials[util.AWSAccessKey])
+	targetCpy.SecretAccessKey = string(credentials[util.AWSSecretKey])
+	targetCpy.Cert = string(credentials[util.AWSCERT])
+	backupSecretData, err := getBackupSecretData(&targetCpy)
+	if err != nil {
+		return "", err
+	}
+
+	secretName := getLonghornBackupTargetResourceSecretName(target.Name)
+	secret, err := h.secretCache.Get(util.LonghornSystemNamespaceName, secretName)
+	if apierrors.IsNotFound(err) {
+		newSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: util.LonghornSystemNamespaceName,
+			},
+			StringData: backupSecretData,
+		}
+		_, err = h.secrets.Create(newSecret)
+		return secretName, err
+	}
+	if err != nil {
+		return "", err
+	}
+
+	secretCpy := secret.DeepCopy()
+	secretCpy.Data = map[string][]byte{}
+	for k, v := range backupSecretData {
+		secretCpy.Data[k] = []byte(v)
+	}
+	if reflect.DeepEqual(secret.Data, secretCpy.Data) {
+		return secretName, nil
+	}
+	_, err = h.secrets.Update(secretCpy)
+	return secretName, err
+}
+
+func (h *TargetHandler) removeLonghornBackupTargetResourceSecret(backupTargetName string) error {
+	secretName := getLonghornBackupTargetResourceSecretName(backupTargetName)
+	if _, err := h.secretCache.Get(util.LonghornSystemNamespaceName, secretName); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err := h.secrets.Delete(util.LonghornSystemNamespaceName, secretName, &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 func (h *TargetHandler) reUpdateBackupTargetSettingSecret(setting *harvesterv1.Setting, target *settings.BackupTarget) (*harvesterv1.Setting, error) {
 	// only do a second update when s3 with credentials
 	if target.Type != settings.S3BackupType {
@@ -0,0 +1,101 @@
+package backup
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	backupTargetMetadataControllerName = "harvester-backup-target-metadata-controller"
+	// unavailable backup targets are checked again after backupTargetRetryInterval
+	backupTargetRetryInterval = 30 * time.Second
+)
+
+// OnBackupTargetResourceChange checks the connection to a BackupTarget and
+// syncs the VM backups in it, like OnBackupTargetChange does for the
+// backup-target setting.
+func (h *MetadataHandler) OnBackupTargetResourceChange(_ string, bt *harvesterv1.BackupTarget) (*harvesterv1.BackupTarget, error) {
+	if bt == nil || bt.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	spec, err := json.Marshal(bt.Spec)
+	if err != nil {
+		return bt, err
+	}
+	specHash, err := getBackupTargetHash(string(spec))
+	if err != nil {
+		return bt, err
+	}
+	if !h.shouldRefreshBackupTarget(bt, specHash) {
+		return bt, nil
+	}
+
+	target := backuputil.ToSettingBackupTarget(bt)
+	contextLogger := logrus.WithFields(logrus.Fields{
+		"backupTarget":    bt.Name,
+		"target.type":     target.Type,
+		"target.endpoint": target.Endpoint,
+	})
+
+	btCpy := bt.DeepCopy()
+	if btCpy.Annotations == nil {
+		btCpy.Annotations = map[string]string{}
+	}
+	btCpy.Annotations[util.AnnotationHash] = specHash
+	btCpy.Status.LastSyncedTime = &metav1.Time{Time: time.Now()}
+
+	contextLogger.Info("start syncing vm backup metadata...")
+	if err := h.syncVMBackupInBackupTarget(target); err != nil {
+		contextLogger.WithError(err).Error("can't sync vm backup metadata")
+		harvesterv1.BackupTargetConditionAvailable.False(btCpy)
+		harvesterv1.BackupTargetConditionAvailable.Message(btCpy, err.Error())
+	} else {
+		harvesterv1.BackupTargetConditionAvailable.True(btCpy)
+		harvesterv1.BackupTargetConditionAvailable.Message(btCpy, "")
+	}
+
+	if reflect.DeepEqual(bt, btCpy) {
+		return bt, nil
+	}
+	return h.backupTargets.Update(btCpy)
+}
+
+func (h *MetadataHandler) syncVMBackupInBackupTarget(target *settings.BackupTarget) error {
+	if err := h.syncVMBackup(target); err != nil {
+		return err
+	}
+	return h.checkExistingVMBackup(target)
+}
+
+// shouldRefreshBackupTarget returns true if the spec changed since the last
+// sync, or the refresh interval passed. An unavailable target is retried
+// regardless of its refresh interval.
+func (h *MetadataHandler) shouldRefreshBackupTarget(bt *harvesterv1.BackupTarget, specHash string) bool {
+	if bt.Annotations[util.AnnotationHash] != specHash || bt.Status.LastSyncedTime == nil {
+		return true
+	}
+
+	interval := time.Duration(bt.Spec.RefreshIntervalInSeconds) * time.Second
+	if !harvesterv1.BackupTargetConditionAvailable.IsTrue(bt) {
+		interval = backupTargetRetryInterval
+	}
+	if interval == 0 {
+		return false
+	}
+
+	if elapsed := time.Since(bt.Status.LastSyncedTime.Time); elapsed < interval {
+		h.backupTargets.EnqueueAfter(bt.Name, interval-elapsed)
+		return false
+	}
+	return true
+}
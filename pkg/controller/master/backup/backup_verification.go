@@ -0,0 +1,511 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/config"
+	ctlharvcorev1 "github.com/harvester/harvester/pkg/generated/controllers/core/v1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/ref"
+	restorecommon "github.com/harvester/harvester/pkg/restore/common"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	backupVerificationControllerName = "harvester-backup-verification-controller"
+
+	backupVerificationSandboxPrefix = "bv"
+
+	// a running verification is checked every backupVerificationPollInterval
+	backupVerificationPollInterval   = 10 * time.Second
+	defaultBackupVerificationTimeout = 30 * time.Minute
+)
+
+// RegisterBackupVerification registers the controller restoring VM backups in
+// sandbox namespaces to check they boot
+func RegisterBackupVerification(ctx context.Context, management *config.Management, _ config.Options) error {
+	verifications := management.HarvesterFactory.Harvesterhci().V1beta1().BackupVerification()
+	vmBackups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup()
+	vmRestores := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineRestore()
+	namespaces := management.CoreFactory.Core().V1().Namespace()
+	rqs := management.HarvesterCoreFactory.Core().V1().ResourceQuota()
+	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	vmis := management.VirtFactory.Kubevirt().V1().VirtualMachineInstance()
+
+	handler := &backupVerificationHandler{
+		verifications:  verifications,
+		vmBackups:      vmBackups,
+		vmBackupCache:  vmBackups.Cache(),
+		vmRestores:     vmRestores,
+		vmRestoreCache: vmRestores.Cache(),
+		namespaces:     namespaces,
+		namespaceCache: namespaces.Cache(),
+		rqs:            rqs,
+		rqCache:        rqs.Cache(),
+		vms:            vms,
+		vmCache:        vms.Cache(),
+		vmiCache:       vmis.Cache(),
+		vmrr:           restorecommon.NewVMRestoreReader(),
+	}
+
+	verifications.OnChange(ctx, backupVerificationControllerName, handler.OnBackupVerificationChange)
+	verifications.OnRemove(ctx, backupVerificationControllerName, handler.OnBackupVerificationRemove)
+	return nil
+}
+
+type backupVerificationHandler struct {
+	verifications  ctlharvesterv1.BackupVerificationController
+	vmBackups      ctlharvesterv1.VirtualMachineBackupClient
+	vmBackupCache  ctlharvesterv1.VirtualMachineBackupCache
+	vmRestores     ctlharvesterv1.VirtualMachineRestoreClient
+	vmRestoreCache ctlharvesterv1.VirtualMachineRestoreCache
+	namespaces     ctlcorev1.NamespaceClient
+	namespaceCache ctlcorev1.NamespaceCache
+	rqs            ctlharvcorev1.ResourceQuotaClient
+	rqCache        ctlharvcorev1.ResourceQuotaCache
+	vms            ctlkubevirtv1.VirtualMachineClient
+	vmCache        ctlkubevirtv1.VirtualMachineCache
+	vmiCache       ctlkubevirtv1.VirtualMachineInstanceCache
+	vmrr           restorecommon.VMRestoreReader
+}
+
+// OnBackupVerificationChange restores the VM backup in the sandbox namespace,
+// boots the VM without NICs, waits for it to be ready and removes the sandbox
+// once the result is recorded.
+func (h *backupVerificationHandler) OnBackupVerificationChange(_ string, verification *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	if verification == nil || verification.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	phase := verification.Status.Phase
+	if phase == harvesterv1.BackupVerificationPhasePassed || phase == harvesterv1.BackupVerificationPhaseFailed {
+		if err := h.recordResult(verification); err != nil {
+			return nil, err
+		}
+		return nil, h.deleteSandbox(verification)
+	}
+
+	timeout := defaultBackupVerificationTimeout
+	if verification.Spec.Timeout != nil {
+		timeout = verification.Spec.Timeout.Duration
+	}
+	if time.Since(verification.CreationTimestamp.Time) > timeout {
+		return h.complete(verification, false, fmt.Sprintf("verification timed out after %s", timeout))
+	}
+
+	switch phase {
+	case "":
+		return h.startVerification(verification)
+	case harvesterv1.BackupVerificationPhaseRestoring:
+		return h.checkRestore(verification)
+	case harvesterv1.BackupVerificationPhaseBooting:
+		return h.checkBoot(verification)
+	}
+	return nil, nil
+}
+
+// startVerification waits for the VM backup to be ready before the sandbox
+// namespace is picked.
+func (h *backupVerificationHandler) startVerification(verification *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	vmBackup, err := h.vmBackupCache.Get(verification.Namespace, verification.Spec.VMBackupName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return h.complete(verification, false, fmt.Sprintf("vm backup %s/%s not found", verification.Namespace, verification.Spec.VMBackupName))
+		}
+		return nil, err
+	}
+	if !vmBackup.Spec.Type.UsesRemoteBackupTarget() {
+		return h.complete(verification, false, fmt.Sprintf("vm backup of type %s can't be restored in another namespace", vmBackup.Spec.Type))
+	}
+	if vmBackup.Status.Error != nil {
+		return h.complete(verification, false, fmt.Sprintf("vm backup %s/%s failed", vmBackup.Namespace, vmBackup.Name))
+	}
+	if !isSandboxProbe(verification.Spec.ReadinessProbe) {
+		return h.complete(verification, false, "the sandbox vm has no network, only exec and guest agent ping readiness probes are supported")
+	}
+	if vmBackup.Status.ReadyToUse == nil || !*vmBackup.Status.ReadyToUse {
+		h.verifications.EnqueueAfter(verification.Namespace, verification.Name, backupVerificationPollInterval)
+		return nil, nil
+	}
+
+	logrus.WithFields(getBackupVerificationLogFields(verification)).Info("starting backup verification")
+	verificationCpy := verification.DeepCopy()
+	verificationCpy.Status.Phase = harvesterv1.BackupVerificationPhaseRestoring
+	verificationCpy.Status.SandboxNamespace = sandboxNamespaceName(verification)
+	verificationCpy.Status.StartTime = ptr.To(metav1.Now())
+	harvesterv1.BackupVerificationConditionCompleted.False(verificationCpy)
+	return h.updateStatus(verification, verificationCpy)
+}
+
+// checkRestore restores the VM backup as a halted new VM in the sandbox, and
+// isolates the VM before it's started.
+func (h *backupVerificationHandler) checkRestore(verification *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	vmRestore, failure, err := h.ensureRestore(verification)
+	if err != nil {
+		return nil, err
+	}
+	if failure != "" {
+		return h.complete(verification, false, failure)
+	}
+	if h.vmrr.IsFailed(vmRestore) {
+		return h.complete(verification, false, fmt.Sprintf("restore failed: %s", harvesterv1.RestoreConditionReady.GetMessage(vmRestore)))
+	}
+	if !h.vmrr.IsComplete(vmRestore) {
+		h.verifications.EnqueueAfter(verification.Namespace, verification.Name, backupVerificationPollInterval)
+		return nil, nil
+	}
+
+	vm, err := h.vmCache.Get(vmRestore.Namespace, vmRestore.Spec.Target.Name)
+	if err != nil {
+		return nil, err
+	}
+	vmCpy := vm.DeepCopy()
+	isolateVM(vmCpy, verification.Spec.ReadinessProbe)
+	if !reflect.DeepEqual(vm.Spec, vmCpy.Spec) {
+		if _, err := h.vms.Update(vmCpy); err != nil {
+			return nil, err
+		}
+	}
+
+	h.verifications.EnqueueAfter(verification.Namespace, verification.Name, backupVerificationPollInterval)
+	verificationCpy := verification.DeepCopy()
+	verificationCpy.Status.Phase = harvesterv1.BackupVerificationPhaseBooting
+	return h.updateStatus(verification, verificationCpy)
+}
+
+// ensureRestore creates the sandbox namespace and the VirtualMachineRestore
+// in it. The restore is named after the verification. It returns why the
+// verification fails when the VM doesn't fit in the quota of its namespace.
+func (h *backupVerificationHandler) ensureRestore(verification *harvesterv1.BackupVerification) (*harvesterv1.VirtualMachineRestore, string, error) {
+	sandbox := verification.Status.SandboxNamespace
+	vmRestore, err := h.vmRestoreCache.Get(sandbox, verification.Name)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return vmRestore, "", err
+	}
+
+	vmBackup, err := h.vmBackupCache.Get(verification.Namespace, verification.Spec.VMBackupName)
+	if err != nil {
+		return nil, "", err
+	}
+	if failure, err := h.ensureSandbox(verification, vmBackup); failure != "" || err != nil {
+		return nil, failure, err
+	}
+
+	vmRestore = &harvesterv1.VirtualMachineRestore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      verification.Name,
+			Namespace: sandbox,
+		},
+		Spec: harvesterv1.VirtualMachineRestoreSpec{
+			Target: corev1.TypedLocalObjectReference{
+				APIGroup: ptr.To(kubevirtv1.SchemeGroupVersion.Group),
+				Kind:     kubevirtv1.VirtualMachineGroupVersionKind.Kind,
+				Name:     vmBackup.Spec.Source.Name,
+			},
+			VirtualMachineBackupName:      vmBackup.Name,
+			VirtualMachineBackupNamespace: vmBackup.Namespace,
+			NewVM:                         true,
+			HaltAfterRestore:              true,
+		},
+	}
+	logrus.WithFields(getBackupVerificationLogFields(verification)).WithField("sandbox", sandbox).Info("restoring vm backup in the sandbox")
+	vmRestore, err = h.vmRestores.Create(vmRestore)
+	return vmRestore, "", err
+}
+
+// ensureSandbox creates the sandbox namespace in the Rancher project of the
+// namespace of the verification, so the project quota applies to it, and
+// limits the sandbox to what's left of the ResourceQuotas of that namespace.
+// The VM must fit in what's left, otherwise the reason is returned.
+func (h *backupVerificationHandler) ensureSandbox(verification *harvesterv1.BackupVerification, vmBackup *harvesterv1.VirtualMachineBackup) (string, error) {
+	quotas, err := h.rqCache.List(verification.Namespace, labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	requirements := getSandboxRequirements(vmBackup)
+	for _, quota := range quotas {
+		if failure := checkSandboxQuota(quota, requirements); failure != "" {
+			return failure, nil
+		}
+	}
+
+	sandbox := verification.Status.SandboxNamespace
+	if _, err := h.namespaceCache.Get(sandbox); apierrors.IsNotFound(err) {
+		source, err := h.namespaceCache.Get(verification.Namespace)
+		if err != nil {
+			return "", err
+		}
+		namespace := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: sandbox,
+				Annotations: map[string]string{
+					util.AnnotationBackupVerificationID: ref.Construct(verification.Namespace, verification.Name),
+				},
+			},
+		}
+		if project, ok := source.Labels[util.CattleProjectID]; ok {
+			namespace.Labels = map[string]string{util.CattleProjectID: project}
+		}
+		if project, ok := source.Annotations[util.CattleProjectID]; ok {
+			namespace.Annotations[util.CattleProjectID] = project
+		}
+		if _, err := h.namespaces.Create(namespace); err != nil && !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create sandbox namespace %s: %w", sandbox, err)
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	for _, quota := range quotas {
+		sandboxQuota := getSandboxQuota(sandbox, quota)
+		if _, err := h.rqs.Create(sandboxQuota); err != nil && !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create resource quota %s/%s: %w", sandbox, sandboxQuota.Name, err)
+		}
+	}
+	return "", nil
+}
+
+// getSandboxRequirements returns what the restored VM counts against a
+// ResourceQuota. Requests default to limits like they do for the launcher pod.
+func getSandboxRequirements(vmBackup *harvesterv1.VirtualMachineBackup) corev1.ResourceList {
+	requirements := corev1.ResourceList{}
+	if source := vmBackup.Status.SourceSpec; source != nil && source.Spec.Template != nil {
+		resources := source.Spec.Template.Spec.Domain.Resources
+		for name, limitName := range map[corev1.ResourceName]corev1.ResourceName{
+			corev1.ResourceCPU:    corev1.ResourceLimitsCPU,
+			corev1.ResourceMemory: corev1.ResourceLimitsMemory,
+		} {
+			request, ok := resources.Requests[name]
+			if !ok {
+				request = resources.Limits[name]
+			}
+			requirements[name] = request
+			requirements[corev1.ResourceName("requests."+name)] = request
+			requirements[limitName] = resources.Limits[name]
+		}
+	}
+
+	storage := resource.Quantity{}
+	for _, volumeBackup := range vmBackup.Status.VolumeBackups {
+		storage.Add(volumeBackup.PersistentVolumeClaim.Spec.Resources.Requests[corev1.ResourceStorage])
+	}
+	requirements[corev1.ResourceRequestsStorage] = storage
+	requirements[corev1.ResourcePersistentVolumeClaims] = *resource.NewQuantity(int64(len(vmBackup.Status.VolumeBackups)), resource.DecimalSI)
+	return requirements
+}
+
+// checkSandboxQuota returns why the requirements don't fit in what's left of
+// the quota.
+func checkSandboxQuota(quota *corev1.ResourceQuota, requirements corev1.ResourceList) string {
+	for name, required := range requirements {
+		left, ok := getQuotaLeft(quota, name)
+		if ok && required.Cmp(left) > 0 {
+			return fmt.Sprintf("the vm needs %s %s, only %s is left in resource quota %s/%s", required.String(), name, left.String(), quota.Namespace, quota.Name)
+		}
+	}
+	return ""
+}
+
+// getSandboxQuota copies the quota to the sandbox, limited to what's left of it.
+// The labels and annotations of the quota are left out, they belong to the
+// controllers managing the quota of the namespace.
+func getSandboxQuota(sandbox string, quota *corev1.ResourceQuota) *corev1.ResourceQuota {
+	sandboxQuota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name.SafeConcatName(backupVerificationSandboxPrefix, quota.Name),
+			Namespace: sandbox,
+		},
+		Spec: *quota.Spec.DeepCopy(),
+	}
+	for resourceName := range quota.Spec.Hard {
+		left, _ := getQuotaLeft(quota, resourceName)
+		sandboxQuota.Spec.Hard[resourceName] = left
+	}
+	return sandboxQuota
+}
+
+func getQuotaLeft(quota *corev1.ResourceQuota, resourceName corev1.ResourceName) (resource.Quantity, bool) {
+	hard, ok := quota.Spec.Hard[resourceName]
+	if !ok {
+		return resource.Quantity{}, false
+	}
+	left := hard.DeepCopy()
+	left.Sub(quota.Status.Used[resourceName])
+	if left.Sign() < 0 {
+		return resource.Quantity{}, true
+	}
+	return left, true
+}
+
+// isolateVM removes all the NICs of the restored VM, so it can't reach the
+// networks of the source VM, and starts it.
+func isolateVM(vm *kubevirtv1.VirtualMachine, readinessProbe *kubevirtv1.Probe) {
+	spec := &vm.Spec.Template.Spec
+	spec.Networks = nil
+	spec.Domain.Devices.Interfaces = nil
+	spec.Domain.Devices.AutoattachPodInterface = ptr.To(false)
+	if readinessProbe != nil {
+		spec.ReadinessProbe = readinessProbe.DeepCopy()
+	}
+	vm.Spec.Running = nil
+	vm.Spec.RunStrategy = ptr.To(kubevirtv1.RunStrategyAlways)
+}
+
+// isSandboxProbe reports whether the readiness probe can succeed in the
+// sandbox. Exec and guest agent ping probes go through the guest agent, HTTP
+// and TCP probes need a NIC the isolated VM doesn't have.
+func isSandboxProbe(probe *kubevirtv1.Probe) bool {
+	return probe == nil || (probe.HTTPGet == nil && probe.TCPSocket == nil)
+}
+
+// checkBoot waits for the guest agent to connect, or for the readiness probe
+// to succeed if there is one.
+func (h *backupVerificationHandler) checkBoot(verification *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	vmRestore, err := h.vmRestoreCache.Get(verification.Status.SandboxNamespace, verification.Name)
+	if err != nil {
+		return nil, err
+	}
+	vmi, err := h.vmiCache.Get(vmRestore.Namespace, vmRestore.Spec.Target.Name)
+	if apierrors.IsNotFound(err) {
+		h.verifications.EnqueueAfter(verification.Namespace, verification.Name, backupVerificationPollInterval)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if vmi.Status.Phase == kubevirtv1.Failed {
+		return h.complete(verification, false, "vm failed to boot")
+	}
+
+	conditionType := kubevirtv1.VirtualMachineInstanceAgentConnected
+	if verification.Spec.ReadinessProbe != nil {
+		conditionType = kubevirtv1.VirtualMachineInstanceReady
+	}
+	for _, cond := range vmi.Status.Conditions {
+		if cond.Type == conditionType && cond.Status == corev1.ConditionTrue {
+			return h.complete(verification, true, "")
+		}
+	}
+
+	h.verifications.EnqueueAfter(verification.Namespace, verification.Name, backupVerificationPollInterval)
+	return nil, nil
+}
+
+// complete records the result in the verification first, the VM backup status
+// and the removal of the sandbox follow from the completed phase and are retried
+// until they succeed.
+func (h *backupVerificationHandler) complete(verification *harvesterv1.BackupVerification, passed bool, message string) (*harvesterv1.BackupVerification, error) {
+	logFields := getBackupVerificationLogFields(verification)
+	verificationCpy := verification.DeepCopy()
+	if passed {
+		logrus.WithFields(logFields).Info("backup verification passed")
+		verificationCpy.Status.Phase = harvesterv1.BackupVerificationPhasePassed
+	} else {
+		logrus.WithFields(logFields).WithField("reason", message).Info("backup verification failed")
+		verificationCpy.Status.Phase = harvesterv1.BackupVerificationPhaseFailed
+	}
+	verificationCpy.Status.Message = message
+	verificationCpy.Status.CompletionTime = ptr.To(metav1.Now())
+	harvesterv1.BackupVerificationConditionCompleted.True(verificationCpy)
+	harvesterv1.BackupVerificationConditionCompleted.Message(verificationCpy, message)
+	return h.updateStatus(verification, verificationCpy)
+}
+
+// recordResult copies the result of a completed verification to the VM backup
+// status, unless the VM backup already has the result of a later verification.
+func (h *backupVerificationHandler) recordResult(verification *harvesterv1.BackupVerification) error {
+	vmBackup, err := h.vmBackupCache.Get(verification.Namespace, verification.Spec.VMBackupName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	result := &harvesterv1.BackupVerificationResult{
+		Name:    verification.Name,
+		Passed:  verification.Status.Phase == harvesterv1.BackupVerificationPhasePassed,
+		Time:    verification.Status.CompletionTime,
+		Message: verification.Status.Message,
+	}
+	current := vmBackup.Status.Verification
+	if reflect.DeepEqual(current, result) {
+		return nil
+	}
+	if current != nil && current.Name != result.Name && current.Time != nil && result.Time != nil && result.Time.Before(current.Time) {
+		return nil
+	}
+
+	vmBackupCpy := vmBackup.DeepCopy()
+	vmBackupCpy.Status.Verification = result
+	_, err = h.vmBackups.Update(vmBackupCpy)
+	return err
+}
+
+// OnBackupVerificationRemove removes the sandbox of a verification in progress.
+func (h *backupVerificationHandler) OnBackupVerificationRemove(_ string, verification *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	if verification == nil {
+		return nil, nil
+	}
+	return nil, h.deleteSandbox(verification)
+}
+
+// deleteSandbox deletes the sandbox namespace with the restored VM and volumes.
+func (h *backupVerificationHandler) deleteSandbox(verification *harvesterv1.BackupVerification) error {
+	sandbox := verification.Status.SandboxNamespace
+	if sandbox == "" {
+		return nil
+	}
+	namespace, err := h.namespaceCache.Get(sandbox)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// never remove a namespace the verification didn't create
+	if namespace.Annotations[util.AnnotationBackupVerificationID] != ref.Construct(verification.Namespace, verification.Name) {
+		return nil
+	}
+	if namespace.DeletionTimestamp != nil {
+		return nil
+	}
+
+	logrus.WithFields(getBackupVerificationLogFields(verification)).WithField("sandbox", sandbox).Info("removing the sandbox")
+	if err := h.namespaces.Delete(sandbox, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete sandbox namespace %s: %w", sandbox, err)
+	}
+	return nil
+}
+
+func (h *backupVerificationHandler) updateStatus(verification, verificationCpy *harvesterv1.BackupVerification) (*harvesterv1.BackupVerification, error) {
+	if reflect.DeepEqual(verification.Status, verificationCpy.Status) {
+		return verification, nil
+	}
+	return h.verifications.Update(verificationCpy)
+}
+
+func sandboxNamespaceName(verification *harvesterv1.BackupVerification) string {
+	return fmt.Sprintf("%s-%s", backupVerificationSandboxPrefix, verification.UID)
+}
+
+func getBackupVerificationLogFields(verification *harvesterv1.BackupVerification) logrus.Fields {
+	return logrus.Fields{
+		"namespace":    verification.Namespace,
+		"name":         verification.Name,
+		"vmBackupName": verification.Spec.VMBackupName,
+	}
+}
@@ -0,0 +1,303 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	corefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newTestVerificationHandler(objects []runtime.Object, namespaces ...runtime.Object) (*backupVerificationHandler, *fake.Clientset, *corefake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	k8sclientset := corefake.NewClientset(namespaces...)
+	return &backupVerificationHandler{
+		verifications:  fakeclients.BackupVerificationClient(clientset.HarvesterhciV1beta1().BackupVerifications),
+		vmBackups:      fakeclients.VMBackupClient(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		vmBackupCache:  fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		namespaces:     fakeclients.NamespaceClient(k8sclientset.CoreV1().Namespaces),
+		namespaceCache: fakeclients.NamespaceCache(k8sclientset.CoreV1().Namespaces),
+		rqs:            fakeclients.ResourceQuotaClient(k8sclientset.CoreV1().ResourceQuotas),
+		rqCache:        fakeclients.ResourceQuotaCache(k8sclientset.CoreV1().ResourceQuotas),
+	}, clientset, k8sclientset
+}
+
+func newTestVMBackup(ready bool) *harvesterv1.VirtualMachineBackup {
+	return &harvesterv1.VirtualMachineBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+		Spec: harvesterv1.VirtualMachineBackupSpec{
+			Type:   harvesterv1.Backup,
+			Source: corev1.TypedLocalObjectReference{Name: "vm"},
+		},
+		Status: harvesterv1.VirtualMachineBackupStatus{
+			ReadyToUse: ptr.To(ready),
+		},
+	}
+}
+
+func newTestVerification(phase harvesterv1.BackupVerificationPhase) *harvesterv1.BackupVerification {
+	return &harvesterv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "verification",
+			Namespace:         "default",
+			UID:               "verification-uid",
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: harvesterv1.BackupVerificationSpec{
+			VMBackupName: "backup",
+		},
+		Status: harvesterv1.BackupVerificationStatus{
+			Phase:            phase,
+			SandboxNamespace: "bv-verification-uid",
+		},
+	}
+}
+
+func newTestSandbox(owner string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bv-verification-uid",
+			Annotations: map[string]string{util.AnnotationBackupVerificationID: owner},
+		},
+	}
+}
+
+func TestStartVerification(t *testing.T) {
+	t.Run("backup not ready", func(t *testing.T) {
+		verification := newTestVerification("")
+		h, _, _ := newTestVerificationHandler([]runtime.Object{verification, newTestVMBackup(false)})
+
+		updated, err := h.OnBackupVerificationChange("", verification)
+		require.NoError(t, err)
+		assert.Nil(t, updated)
+	})
+
+	t.Run("backup ready", func(t *testing.T) {
+		verification := newTestVerification("")
+		verification.Status.SandboxNamespace = ""
+		h, _, _ := newTestVerificationHandler([]runtime.Object{verification, newTestVMBackup(true)})
+
+		updated, err := h.OnBackupVerificationChange("", verification)
+		require.NoError(t, err)
+		assert.Equal(t, harvesterv1.BackupVerificationPhaseRestoring, updated.Status.Phase)
+		assert.Equal(t, "bv-verification-uid", updated.Status.SandboxNamespace)
+		assert.NotNil(t, updated.Status.StartTime)
+	})
+
+	t.Run("network readiness probe", func(t *testing.T) {
+		verification := newTestVerification("")
+		verification.Spec.ReadinessProbe = &kubevirtv1.Probe{
+			Handler: kubevirtv1.Handler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(22)}},
+		}
+		h, _, _ := newTestVerificationHandler([]runtime.Object{verification, newTestVMBackup(true)})
+
+		updated, err := h.OnBackupVerificationChange("", verification)
+		require.NoError(t, err)
+		assert.Equal(t, harvesterv1.BackupVerificationPhaseFailed, updated.Status.Phase)
+		assert.Contains(t, updated.Status.Message, "no network")
+	})
+
+	t.Run("backup not found", func(t *testing.T) {
+		verification := newTestVerification("")
+		h, _, _ := newTestVerificationHandler([]runtime.Object{verification})
+
+		updated, err := h.OnBackupVerificationChange("", verification)
+		require.NoError(t, err)
+		assert.Equal(t, harvesterv1.BackupVerificationPhaseFailed, updated.Status.Phase)
+		assert.Contains(t, updated.Status.Message, "not found")
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		verification := newTestVerification(harvesterv1.BackupVerificationPhaseBooting)
+		verification.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+		verification.Spec.Timeout = &metav1.Duration{Duration: time.Minute}
+		h, _, _ := newTestVerificationHandler([]runtime.Object{verification, newTestVMBackup(true)})
+
+		updated, err := h.OnBackupVerificationChange("", verification)
+		require.NoError(t, err)
+		assert.Equal(t, harvesterv1.BackupVerificationPhaseFailed, updated.Status.Phase)
+		assert.Contains(t, updated.Status.Message, "timed out")
+	})
+}
+
+func TestCompleteRecordsVerificationBeforeBackup(t *testing.T) {
+	verification := newTestVerification(harvesterv1.BackupVerificationPhaseBooting)
+	h, clientset, k8sclientset := newTestVerificationHandler([]runtime.Object{verification, newTestVMBackup(true)}, newTestSandbox("default/verification"))
+
+	// the result is written to the verification first, the sandbox and the VM backup are untouched
+	updated, err := h.complete(verification, true, "")
+	require.NoError(t, err)
+	assert.Equal(t, harvesterv1.BackupVerificationPhasePassed, updated.Status.Phase)
+	assert.NotNil(t, updated.Status.CompletionTime)
+	assert.True(t, harvesterv1.BackupVerificationConditionCompleted.IsTrue(updated))
+
+	vmBackup, err := clientset.HarvesterhciV1beta1().VirtualMachineBackups("default").Get(context.TODO(), "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, vmBackup.Status.Verification)
+
+	// the completed verification records the result and removes the sandbox
+	_, err = h.OnBackupVerificationChange("", updated)
+	require.NoError(t, err)
+	vmBackup, err = clientset.HarvesterhciV1beta1().VirtualMachineBackups("default").Get(context.TODO(), "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, vmBackup.Status.Verification)
+	assert.Equal(t, "verification", vmBackup.Status.Verification.Name)
+	assert.True(t, vmBackup.Status.Verification.Passed)
+	assert.Equal(t, updated.Status.CompletionTime, vmBackup.Status.Verification.Time)
+
+	_, err = k8sclientset.CoreV1().Namespaces().Get(context.TODO(), "bv-verification-uid", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestRecordResultKeepsLaterVerification(t *testing.T) {
+	verification := newTestVerification(harvesterv1.BackupVerificationPhaseFailed)
+	verification.Status.CompletionTime = ptr.To(metav1.NewTime(time.Now().Add(-time.Hour)))
+	vmBackup := newTestVMBackup(true)
+	later := &harvesterv1.BackupVerificationResult{
+		Name:   "later",
+		Passed: true,
+		Time:   ptr.To(metav1.Now()),
+	}
+	vmBackup.Status.Verification = later
+	h, clientset, _ := newTestVerificationHandler([]runtime.Object{verification, vmBackup})
+
+	require.NoError(t, h.recordResult(verification))
+	vmBackup, err := clientset.HarvesterhciV1beta1().VirtualMachineBackups("default").Get(context.TODO(), "backup", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, later, vmBackup.Status.Verification)
+}
+
+func TestRecordResultWithoutBackup(t *testing.T) {
+	verification := newTestVerification(harvesterv1.BackupVerificationPhasePassed)
+	h, _, _ := newTestVerificationHandler([]runtime.Object{verification})
+
+	assert.NoError(t, h.recordResult(verification))
+}
+
+func TestDeleteSandboxKeepsForeignNamespace(t *testing.T) {
+	verification := newTestVerification(harvesterv1.BackupVerificationPhasePassed)
+	h, _, k8sclientset := newTestVerificationHandler([]runtime.Object{verification}, newTestSandbox("default/other"))
+
+	require.NoError(t, h.deleteSandbox(verification))
+	_, err := k8sclientset.CoreV1().Namespaces().Get(context.TODO(), "bv-verification-uid", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestEnsureSandbox(t *testing.T) {
+	newTestQuota := func() *corev1.ResourceQuota {
+		return &corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "quota",
+				Namespace: "default",
+				Labels:    map[string]string{util.LabelManagementDefaultResourceQuota: "true"},
+			},
+			Spec: corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{
+					corev1.ResourceLimitsCPU:       resource.MustParse("4"),
+					corev1.ResourceRequestsStorage: resource.MustParse("20Gi"),
+				},
+			},
+			Status: corev1.ResourceQuotaStatus{
+				Used: corev1.ResourceList{
+					corev1.ResourceLimitsCPU:       resource.MustParse("2"),
+					corev1.ResourceRequestsStorage: resource.MustParse("5Gi"),
+				},
+			},
+		}
+	}
+	newTestSourceNamespace := func() *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "default",
+				Labels:      map[string]string{util.CattleProjectID: "p-abcde"},
+				Annotations: map[string]string{util.CattleProjectID: "local:p-abcde"},
+			},
+		}
+	}
+	newTestSourceVMBackup := func(cpu, storage string) *harvesterv1.VirtualMachineBackup {
+		vmBackup := newTestVMBackup(true)
+		vmBackup.Status.SourceSpec = &harvesterv1.VirtualMachineSourceSpec{
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Domain: kubevirtv1.DomainSpec{
+							Resources: kubevirtv1.ResourceRequirements{
+								Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+							},
+						},
+					},
+				},
+			},
+		}
+		vmBackup.Status.VolumeBackups = []harvesterv1.VolumeBackup{{
+			PersistentVolumeClaim: harvesterv1.PersistentVolumeClaimSourceSpec{
+				Spec: corev1.PersistentVolumeClaimSpec{
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(storage)},
+					},
+				},
+			},
+		}}
+		return vmBackup
+	}
+
+	t.Run("vm fits", func(t *testing.T) {
+		verification := newTestVerification(harvesterv1.BackupVerificationPhaseRestoring)
+		vmBackup := newTestSourceVMBackup("2", "10Gi")
+		h, _, k8sclientset := newTestVerificationHandler([]runtime.Object{verification, vmBackup}, newTestSourceNamespace(), newTestQuota())
+
+		failure, err := h.ensureSandbox(verification, vmBackup)
+		require.NoError(t, err)
+		assert.Empty(t, failure)
+
+		sandbox, err := k8sclientset.CoreV1().Namespaces().Get(context.TODO(), "bv-verification-uid", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "p-abcde", sandbox.Labels[util.CattleProjectID])
+		assert.Equal(t, "local:p-abcde", sandbox.Annotations[util.CattleProjectID])
+		assert.Equal(t, "default/verification", sandbox.Annotations[util.AnnotationBackupVerificationID])
+
+		quota, err := k8sclientset.CoreV1().ResourceQuotas("bv-verification-uid").Get(context.TODO(), "bv-quota", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, quota.Labels)
+		assert.True(t, resource.MustParse("2").Equal(quota.Spec.Hard[corev1.ResourceLimitsCPU]))
+		assert.True(t, resource.MustParse("15Gi").Equal(quota.Spec.Hard[corev1.ResourceRequestsStorage]))
+	})
+
+	for _, tc := range []struct {
+		name     string
+		cpu      string
+		storage  string
+		resource corev1.ResourceName
+	}{
+		{name: "cpu exceeds quota", cpu: "3", storage: "10Gi", resource: corev1.ResourceLimitsCPU},
+		{name: "storage exceeds quota", cpu: "1", storage: "20Gi", resource: corev1.ResourceRequestsStorage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verification := newTestVerification(harvesterv1.BackupVerificationPhaseRestoring)
+			vmBackup := newTestSourceVMBackup(tc.cpu, tc.storage)
+			h, _, k8sclientset := newTestVerificationHandler([]runtime.Object{verification, vmBackup}, newTestSourceNamespace(), newTestQuota())
+
+			failure, err := h.ensureSandbox(verification, vmBackup)
+			require.NoError(t, err)
+			assert.Contains(t, failure, string(tc.resource))
+
+			_, err = k8sclientset.CoreV1().Namespaces().Get(context.TODO(), "bv-verification-uid", metav1.GetOptions{})
+			assert.True(t, apierrors.IsNotFound(err))
+		})
+	}
+}
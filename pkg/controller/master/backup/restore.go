@@ -5,6 +5,7 @@ package backup
 // Currently, the following features are supported:
 // 1. support VM live & offline backup to the supported backupTarget(i.e, nfs_v4 or s3 storage server).
 // 2. restore a backup to a new VM or replacing it with the existing VM is supported.
+// 3. restore selected volumes of a backup into an existing VM, replacing some of its disks or hotplugging new ones.
 import (
 	"context"
 	"fmt"
@@ -22,6 +23,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
@@ -34,7 +36,7 @@ import (
 	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
 	restorecommon "github.com/harvester/harvester/pkg/restore/common"
 	"github.com/harvester/harvester/pkg/restore/engine"
-
+	restoreexport "github.com/harvester/harvester/pkg/restore/engine/export"
 	"github.com/harvester/harvester/pkg/restore/engine/longhorn"
 
 	restoresnapshot "github.com/harvester/harvester/pkg/restore/engine/snapshot"
@@ -91,7 +93,7 @@ func RegisterRestore(ctx context.Context, management *config.Management, _ confi
 	vmbo, vmro := newRestoreOperators(controllers, restClient)
 
 	// Initialize restore engines
-	engines := newRestoreEngines(controllers, vmbo, vmro)
+	engines := newRestoreEngines(controllers, vmbo, vmro, management.ClientSet)
 
 	// Let each engine wire up its own informer event handlers (e.g. Job
 	// watchers) so engine-owned resource changes feed back into VMRestore
@@ -119,6 +121,7 @@ type restoreControllerSet struct {
 	pvs             ctlcorev1.PersistentVolumeController
 	scs             ctlstoragev1.StorageClassController
 	secrets         ctlcorev1.SecretController
+	backupTargets   ctlharvesterv1.BackupTargetController
 	vss             ctlsnapshotv1.VolumeSnapshotController
 	vscs            ctlsnapshotv1.VolumeSnapshotContentController
 	lhbackups       ctllhv1.BackupController
@@ -140,6 +143,7 @@ func getRestoreControllers(management *config.Management) *restoreControllerSet
 		pvs:             management.CoreFactory.Core().V1().PersistentVolume(),
 		scs:             management.StorageFactory.Storage().V1().StorageClass(),
 		secrets:         management.CoreFactory.Core().V1().Secret(),
+		backupTargets:   management.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget(),
 		vss:             management.SnapshotFactory.Snapshot().V1().VolumeSnapshot(),
 		vscs:            management.SnapshotFactory.Snapshot().V1().VolumeSnapshotContent(),
 		lhbackups:       management.LonghornFactory.Longhorn().V1beta2().Backup(),
@@ -165,6 +169,7 @@ func newRestoreOperators(
 		WithPVCCache(controllers.pvcs.Cache()).
 		WithPVCache(controllers.pvs.Cache()).
 		WithSecretCache(controllers.secrets.Cache()).
+		WithBackupTargetCache(controllers.backupTargets.Cache()).
 		Build()
 
 	vmro := restorecommon.NewVMRestoreOperatorBuilder().
@@ -189,6 +194,7 @@ func newRestoreEngines(
 	controllers *restoreControllerSet,
 	vmbo backupcommon.VMBackupOperator,
 	vmro restorecommon.VMRestoreOperator,
+	clientset kubernetes.Interface,
 ) map[harvesterv1.BackupType]engine.RestoreEngine {
 	return map[harvesterv1.BackupType]engine.RestoreEngine{
 		harvesterv1.Backup: longhorn.GetRestoreEngine(
@@ -217,6 +223,18 @@ func newRestoreEngines(
 			controllers.pvcs,
 			controllers.vss.Cache(),
 		),
+		harvesterv1.SnapshotExport: restoreexport.GetRestoreEngine(
+			vmbo,
+			vmro,
+			controllers.pvcs.Cache(),
+			controllers.pvcs,
+			controllers.pvs.Cache(),
+			controllers.pvs,
+			controllers.secrets.Cache(),
+			controllers.secrets,
+			controllers.jobs,
+			clientset,
+		),
 	}
 }
 
@@ -471,6 +489,16 @@ func (h *RestoreHandler) reconcileResources(
 		return nil, false, err
 	}
 
+	// a partial restore only attaches the restored volumes to the VM, once
+	// they are populated, and leaves the rest of the VM and its secrets alone
+	if h.vmro.IsPartialRestore(vmr) {
+		vm, err := h.reconcilePartialVM(vmr, isVolumesReady)
+		if err != nil {
+			return nil, false, err
+		}
+		return vm, isVolumesReady, nil
+	}
+
 	// reconcile VM
 	vm, err := h.reconcileVM(vmr, vmb)
 	if err != nil {
@@ -566,6 +594,142 @@ func (h *RestoreHandler) updateExistingVM(
 	return h.vmClient.Update(vmCpy)
 }
 
+func (h *RestoreHandler) reconcilePartialVM(
+	vmr *harvesterv1.VirtualMachineRestore,
+	isVolumesReady bool,
+) (*kubevirtv1.VirtualMachine, error) {
+	vm, err := h.vmro.ResolveTargetVM(vmr)
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("target VM %s/%s of the partial restore doesn't exist", h.vmro.GetNamespace(vmr), h.vmro.GetTargetName(vmr))
+	}
+
+	if !isVolumesReady || h.isVMAlreadyRestored(vm, vmr) {
+		return vm, nil
+	}
+
+	return h.updateExistingVMVolumes(vm, vmr)
+}
+
+// updateExistingVMVolumes attaches the restored PVCs of a partial restore to
+// the VM. A replaced disk keeps its definition and only gets the restored PVC,
+// a hotplugged one is added on the SCSI bus like the addVolume action does.
+func (h *RestoreHandler) updateExistingVMVolumes(
+	vm *kubevirtv1.VirtualMachine,
+	vmr *harvesterv1.VirtualMachineRestore,
+) (*kubevirtv1.VirtualMachine, error) {
+	vmCpy := vm.DeepCopy()
+	entries, err := util.UnmarshalVolumeClaimTemplates(vmCpy.Annotations[util.AnnotationVolumeClaimTemplates])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal volumeClaimTemplates annotation: %w", err)
+	}
+
+	namespace := h.vmro.GetNamespace(vmr)
+	replaced := false
+	vrs := h.vmro.GetVolRestores(vmr)
+	for i := range vrs {
+		vr := &vrs[i]
+		sel := h.vmro.GetVolumeSelection(vmr, h.vmro.GetVolRestoreVolumeName(vr))
+		if sel == nil {
+			continue
+		}
+		targetVolumeName := h.vmro.GetSelectionTargetVolumeName(sel)
+		pvcName := h.vmro.GetVolRestorePVCName(vr)
+		entry, err := h.buildVolumeClaimTemplateEntry(namespace, pvcName)
+		if err != nil {
+			return nil, err
+		}
+
+		switch sel.Mode {
+		case harvesterv1.VolumeRestoreModeReplace:
+			oldPVCName, err := replaceVolumeClaim(vmCpy, targetVolumeName, pvcName)
+			if err != nil {
+				return nil, err
+			}
+			entries = removeVolumeClaimTemplateEntry(entries, oldPVCName)
+			replaced = true
+		case harvesterv1.VolumeRestoreModeHotplug:
+			hotplugVolumeClaim(vmCpy, targetVolumeName, pvcName)
+		default:
+			return nil, fmt.Errorf("unsupported volume restore mode %q", sel.Mode)
+		}
+		entries = append(entries, entry)
+	}
+
+	// like a full restore, the VM is started again by ensureVMStartedAndReady
+	if replaced {
+		haltedRunStrategy := kubevirtv1.RunStrategyHalted
+		vmCpy.Spec.RunStrategy = &haltedRunStrategy
+	}
+
+	volumeClaimTemplatesStr, err := util.MarshalVolumeClaimTemplates(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal volumeClaimTemplates: %w", err)
+	}
+	if vmCpy.Annotations == nil {
+		vmCpy.Annotations = make(map[string]string)
+	}
+	vmCpy.Annotations[lastRestoreAnnotation] = h.vmro.GetRestoreID(vmr)
+	vmCpy.Annotations[restorecommon.RestoreNameAnnotation] = h.vmro.GetName(vmr)
+	vmCpy.Annotations[util.AnnotationVolumeClaimTemplates] = volumeClaimTemplatesStr
+
+	return h.vmClient.Update(vmCpy)
+}
+
+// replaceVolumeClaim points the PVC volume of the VM to the claim and returns
+// the claim it used before.
+func replaceVolumeClaim(vm *kubevirtv1.VirtualMachine, volumeName, claimName string) (string, error) {
+	volumes := vm.Spec.Template.Spec.Volumes
+	for i := range volumes {
+		if volumes[i].Name != volumeName {
+			continue
+		}
+		if volumes[i].PersistentVolumeClaim == nil {
+			return "", fmt.Errorf("volume %s of VM %s/%s isn't a PVC volume", volumeName, vm.Namespace, vm.Name)
+		}
+		oldClaimName := volumes[i].PersistentVolumeClaim.ClaimName
+		volumes[i].PersistentVolumeClaim.ClaimName = claimName
+		return oldClaimName, nil
+	}
+	return "", fmt.Errorf("volume %s doesn't exist in VM %s/%s", volumeName, vm.Namespace, vm.Name)
+}
+
+// hotplugVolumeClaim adds the claim to the VM as a new hotpluggable disk.
+func hotplugVolumeClaim(vm *kubevirtv1.VirtualMachine, volumeName, claimName string) {
+	vm.Spec.Template.Spec.Domain.Devices.Disks = append(vm.Spec.Template.Spec.Domain.Devices.Disks, kubevirtv1.Disk{
+		Name: volumeName,
+		DiskDevice: kubevirtv1.DiskDevice{
+			Disk: &kubevirtv1.DiskTarget{
+				// https://kubevirt.io/user-guide/storage/hotplug_volumes/#supported-disk-busses
+				Bus: "scsi",
+			},
+		},
+	})
+	vm.Spec.Template.Spec.Volumes = append(vm.Spec.Template.Spec.Volumes, kubevirtv1.Volume{
+		Name: volumeName,
+		VolumeSource: kubevirtv1.VolumeSource{
+			PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+				PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claimName,
+				},
+				Hotpluggable: true,
+			},
+		},
+	})
+}
+
+func removeVolumeClaimTemplateEntry(entries []util.VolumeClaimTemplateEntry, pvcName string) []util.VolumeClaimTemplateEntry {
+	result := entries[:0]
+	for _, entry := range entries {
+		if entry.Name != pvcName {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
 // setRestoreAnnotations sets the required restore annotations on the VM. The
 // volumeClaimTemplates annotation is rebuilt from the actual restored PVCs so
 // downstream features (storage migration, PVC management UI) see the current
@@ -593,23 +757,11 @@ func (h *RestoreHandler) buildVolumeClaimTemplates(vmr *harvesterv1.VirtualMachi
 	entries := make([]util.VolumeClaimTemplateEntry, 0, len(vrs))
 	namespace := h.vmro.GetNamespace(vmr)
 	for i := range vrs {
-		vr := &vrs[i]
-		pvcName := h.vmro.GetVolRestorePVCName(vr)
-		// Re-fetch the PVC so we pick up the latest annotations (e.g. imageID)
-		// rather than the snapshot in vmr.Status.
-		pvc, err := h.pvcCache.Get(namespace, pvcName)
+		entry, err := h.buildVolumeClaimTemplateEntry(namespace, h.vmro.GetVolRestorePVCName(&vrs[i]))
 		if err != nil {
-			return "", fmt.Errorf("failed to get restored PVC %s/%s: %w", namespace, pvcName, err)
+			return "", err
 		}
-		entries = append(entries, util.VolumeClaimTemplateEntry{
-			PersistentVolumeClaim: corev1.PersistentVolumeClaim{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:        pvc.Name,
-					Annotations: buildVolumeClaimTemplateAnnotations(pvc),
-				},
-				Spec: sanitizeVolumeClaimTemplateSpec(pvc),
-			},
-		})
+		entries = append(entries, entry)
 	}
 
 	volumeClaimTemplatesStr, err := util.MarshalVolumeClaimTemplates(entries)
@@ -619,6 +771,24 @@ func (h *RestoreHandler) buildVolumeClaimTemplates(vmr *harvesterv1.VirtualMachi
 	return volumeClaimTemplatesStr, nil
 }
 
+func (h *RestoreHandler) buildVolumeClaimTemplateEntry(namespace, pvcName string) (util.VolumeClaimTemplateEntry, error) {
+	// Re-fetch the PVC so we pick up the latest annotations (e.g. imageID)
+	// rather than the snapshot in vmr.Status.
+	pvc, err := h.pvcCache.Get(namespace, pvcName)
+	if err != nil {
+		return util.VolumeClaimTemplateEntry{}, fmt.Errorf("failed to get restored PVC %s/%s: %w", namespace, pvcName, err)
+	}
+	return util.VolumeClaimTemplateEntry{
+		PersistentVolumeClaim: corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        pvc.Name,
+				Annotations: buildVolumeClaimTemplateAnnotations(pvc),
+			},
+			Spec: sanitizeVolumeClaimTemplateSpec(pvc),
+		},
+	}, nil
+}
+
 // sanitizeVolumeClaimTemplateSpec returns the minimal PVC spec we want to
 // persist in the volumeClaimTemplates annotation — enough for downstream
 // consumers to recreate or migrate the volume, without dragging the entire
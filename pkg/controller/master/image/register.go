@@ -14,11 +14,13 @@ import (
 )
 
 const (
-	vmImageControllerName = "vm-image-controller"
+	vmImageControllerName      = "vm-image-controller"
+	vmImageUsageControllerName = "vm-image-usage-controller"
 )
 
 func Register(ctx context.Context, management *config.Management, _ config.Options) error {
 	bi := management.LonghornFactory.Longhorn().V1beta2().BackingImage()
+	bids := management.LonghornFactory.Longhorn().V1beta2().BackingImageDataSource()
 	vmi := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImage()
 	sc := management.StorageFactory.Storage().V1().StorageClass()
 	pvcs := management.CoreFactory.Core().V1().PersistentVolumeClaim()
@@ -26,6 +28,9 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 	ctlcdi := management.CdiFactory.Cdi().V1beta1().DataVolume()
 	settingCache := management.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache()
 	configMaps := management.CoreFactory.Core().V1().ConfigMap()
+	cdiUploads := management.CdiUploadFactory.Upload().V1beta1().UploadTokenRequest()
+	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	templateVersions := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineTemplateVersion()
 
 	vmio, err := common.GetVMIOperator(vmi, vmi.Cache(), sc.Cache(), http.Client{Timeout: 15 * time.Second})
 	if err != nil {
@@ -35,10 +40,10 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 		harvesterv1.VMIBackendBackingImage: backingimage.GetBackend(
 			ctx, sc, sc.Cache(),
 			bi, bi, bi.Cache(),
-			pvcs.Cache(), secrets.Cache(),
-			vmi, vmi.Cache(), vmio,
+			bids, pvcs.Cache(), secrets.Cache(),
+			vmi, vmi.Cache(), vmio, management.ClientSet,
 		),
-		harvesterv1.VMIBackendCDI: cdi.GetBackend(ctx, ctlcdi, sc, pvcs.Cache(), vmio, settingCache, configMaps),
+		harvesterv1.VMIBackendCDI: cdi.GetBackend(ctx, ctlcdi, sc, pvcs.Cache(), vmio, settingCache, configMaps, secrets.Cache(), cdiUploads, management.ClientSet),
 	}
 
 	vmImageHandler := &vmImageHandler{
@@ -51,6 +56,19 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 	vmi.OnChange(ctx, vmImageControllerName, vmImageHandler.OnChanged)
 	vmi.OnRemove(ctx, vmImageControllerName, vmImageHandler.OnRemove)
 
+	vmImageUsageHandler := &vmImageUsageHandler{
+		vmiClient:     vmi,
+		vmiController: vmi,
+		pvcCache:      pvcs.Cache(),
+		vmCache:       vms.Cache(),
+		vmtvCache:     templateVersions.Cache(),
+		settingCache:  settingCache,
+	}
+	vmi.OnChange(ctx, vmImageUsageControllerName, vmImageUsageHandler.OnChanged)
+	pvcs.OnChange(ctx, vmImageUsageControllerName, vmImageUsageHandler.OnPVCChanged)
+	vms.OnChange(ctx, vmImageUsageControllerName, vmImageUsageHandler.OnVMChanged)
+	templateVersions.OnChange(ctx, vmImageUsageControllerName, vmImageUsageHandler.OnTemplateVersionChanged)
+
 	for _, b := range backends {
 		b.AddSidecarHandler()
 	}
@@ -155,6 +155,9 @@ func TestVMImageHandler_OnChanged_UploadImageInitialization(t *testing.T) {
 				vmio,
 				fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings),
 				fakeclients.ConfigmapClient(clientset.CoreV1().ConfigMaps),
+				fakeclients.SecretCache(clientset.CoreV1().Secrets),
+				nil,
+				nil,
 			)
 
 			// Create backends map
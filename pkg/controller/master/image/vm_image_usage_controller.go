@@ -0,0 +1,252 @@
+package image
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/indexeres"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	indexeresutil "github.com/harvester/harvester/pkg/util/indexeres"
+)
+
+const (
+	consumerKindPVC             = "PersistentVolumeClaim"
+	consumerKindVM              = "VirtualMachine"
+	consumerKindTemplateVersion = "VirtualMachineTemplateVersion"
+
+	// unused images are checked against the retention policy once in a while
+	retentionCheckInterval = time.Hour
+)
+
+// vmImageUsageHandler tracks the volumes, VMs and template versions using the images, and deletes
+// the images that have been unused for longer than the image retention policy allows.
+type vmImageUsageHandler struct {
+	vmiClient     ctlharvesterv1.VirtualMachineImageClient
+	vmiController ctlharvesterv1.VirtualMachineImageController
+	pvcCache      ctlcorev1.PersistentVolumeClaimCache
+	vmCache       ctlkubevirtv1.VirtualMachineCache
+	vmtvCache     ctlharvesterv1.VirtualMachineTemplateVersionCache
+	settingCache  ctlharvesterv1.SettingCache
+
+	// imageIDs remembers the images of every consumer, since deleted consumers no longer tell
+	imageIDs sync.Map
+}
+
+func (h *vmImageUsageHandler) OnChanged(_ string, vmi *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	if vmi == nil || vmi.DeletionTimestamp != nil {
+		return vmi, nil
+	}
+
+	consumers, err := h.getConsumers(vmi)
+	if err != nil {
+		return vmi, err
+	}
+	if !sameConsumers(consumers, vmi.Status.Consumers) {
+		toUpdate := vmi.DeepCopy()
+		toUpdate.Status.Consumers = consumers
+		toUpdate.Status.ReferenceCount = len(consumers)
+		now := metav1.Now()
+		toUpdate.Status.LastUsedTime = &now
+		return h.vmiClient.Update(toUpdate)
+	}
+
+	h.applyRetentionPolicy(vmi)
+	h.vmiController.EnqueueAfter(vmi.Namespace, vmi.Name, retentionCheckInterval)
+	return vmi, nil
+}
+
+// getConsumers returns the volumes created from the image, the VMs using these volumes and the
+// template versions referring to the image.
+func (h *vmImageUsageHandler) getConsumers(vmi *harvesterv1.VirtualMachineImage) ([]harvesterv1.VirtualMachineImageConsumer, error) {
+	imageID := ref.Construct(vmi.Namespace, vmi.Name)
+	consumers := []harvesterv1.VirtualMachineImageConsumer{}
+	seen := map[harvesterv1.VirtualMachineImageConsumer]bool{}
+	add := func(kind string, obj metav1.Object) {
+		consumer := harvesterv1.VirtualMachineImageConsumer{Kind: kind, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		if !seen[consumer] {
+			seen[consumer] = true
+			consumers = append(consumers, consumer)
+		}
+	}
+
+	pvcs, err := h.pvcCache.GetByIndex(indexeres.PVCByImageIDIndex, imageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		add(consumerKindPVC, pvc)
+		vms, err := h.vmCache.GetByIndex(indexeresutil.VMByPVCIndex, ref.Construct(pvc.Namespace, pvc.Name))
+		if err != nil {
+			return nil, err
+		}
+		for _, vm := range vms {
+			add(consumerKindVM, vm)
+		}
+	}
+
+	templateVersions, err := h.vmtvCache.GetByIndex(indexeres.VMTemplateVersionByImageIDIndex, imageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tv := range templateVersions {
+		add(consumerKindTemplateVersion, tv)
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		a, b := consumers[i], consumers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return consumers, nil
+}
+
+func sameConsumers(a, b []harvesterv1.VirtualMachineImageConsumer) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func (h *vmImageUsageHandler) applyRetentionPolicy(vmi *harvesterv1.VirtualMachineImage) {
+	setting, err := h.settingCache.Get(settings.ImageRetentionPolicySettingName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logrus.WithError(err).Warn("failed to get the image retention policy")
+		}
+		return
+	}
+	policy, err := settings.GetImageRetentionPolicy(setting)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to parse the image retention policy")
+		return
+	}
+	if !policy.Enabled || !isRetentionExpired(vmi, policy.UnusedDays, time.Now()) {
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespace":  vmi.Namespace,
+		"name":       vmi.Name,
+		"unusedDays": policy.UnusedDays,
+	}).Info("deleting unused vmimage by the image retention policy")
+	if err := h.vmiClient.Delete(vmi.Namespace, vmi.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		// the deletion is retried with the next retention check
+		logrus.WithError(err).Warnf("failed to delete unused vmimage %s/%s", vmi.Namespace, vmi.Name)
+	}
+}
+
+// isRetentionExpired returns true if the image has had no consumers for unusedDays. Images that are
+// not imported, belong to template versions, upgrades or image sync policies are never expired.
+func isRetentionExpired(vmi *harvesterv1.VirtualMachineImage, unusedDays int, now time.Time) bool {
+	if unusedDays < 1 || vmi.Status.ReferenceCount > 0 || len(vmi.Status.Consumers) > 0 {
+		return false
+	}
+	if !harvesterv1.ImageImported.IsTrue(vmi) {
+		return false
+	}
+	for _, owner := range vmi.OwnerReferences {
+		if owner.Kind == consumerKindTemplateVersion {
+			return false
+		}
+	}
+	if vmi.Annotations[util.AnnotationUpgradeImage] == "True" || vmi.Labels[util.LabelImageSyncPolicy] != "" {
+		return false
+	}
+
+	lastUsed := getImportedTime(vmi)
+	if vmi.Status.LastUsedTime != nil && vmi.Status.LastUsedTime.After(lastUsed) {
+		lastUsed = vmi.Status.LastUsedTime.Time
+	}
+	return now.Sub(lastUsed) >= time.Duration(unusedDays)*24*time.Hour
+}
+
+// getImportedTime returns when the image finished importing, so a long import doesn't count as
+// unused time. It falls back to the creation time if the condition has no valid timestamp.
+func getImportedTime(vmi *harvesterv1.VirtualMachineImage) time.Time {
+	imported, err := time.Parse(time.RFC3339, harvesterv1.ImageImported.GetLastUpdated(vmi))
+	if err != nil || imported.Before(vmi.CreationTimestamp.Time) {
+		return vmi.CreationTimestamp.Time
+	}
+	return imported
+}
+
+func (h *vmImageUsageHandler) OnPVCChanged(key string, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	var imageIDs []string
+	if pvc != nil {
+		if imageID := pvc.Annotations[util.AnnotationImageID]; imageID != "" {
+			imageIDs = append(imageIDs, imageID)
+		}
+	}
+	h.enqueueImages(consumerKindPVC+"/"+key, imageIDs)
+	return pvc, nil
+}
+
+func (h *vmImageUsageHandler) OnVMChanged(key string, vm *kubevirtv1.VirtualMachine) (*kubevirtv1.VirtualMachine, error) {
+	var imageIDs []string
+	if vm != nil {
+		pvcKeys, err := indexeresutil.VMByPVC(vm)
+		if err != nil {
+			return vm, err
+		}
+		for _, pvcKey := range pvcKeys {
+			namespace, name := ref.Parse(pvcKey)
+			pvc, err := h.pvcCache.Get(namespace, name)
+			if err != nil {
+				continue
+			}
+			if imageID := pvc.Annotations[util.AnnotationImageID]; imageID != "" {
+				imageIDs = append(imageIDs, imageID)
+			}
+		}
+	}
+	h.enqueueImages(consumerKindVM+"/"+key, imageIDs)
+	return vm, nil
+}
+
+func (h *vmImageUsageHandler) OnTemplateVersionChanged(key string, tv *harvesterv1.VirtualMachineTemplateVersion) (*harvesterv1.VirtualMachineTemplateVersion, error) {
+	var imageIDs []string
+	if tv != nil {
+		imageIDs, _ = indexeres.VMTemplateVersionByImageID(tv)
+	}
+	h.enqueueImages(consumerKindTemplateVersion+"/"+key, imageIDs)
+	return tv, nil
+}
+
+// enqueueImages enqueues the images the consumer uses now and the ones it used before.
+func (h *vmImageUsageHandler) enqueueImages(consumerKey string, imageIDs []string) {
+	previous, _ := h.imageIDs.Load(consumerKey)
+	if len(imageIDs) == 0 {
+		h.imageIDs.Delete(consumerKey)
+	} else {
+		h.imageIDs.Store(consumerKey, imageIDs)
+	}
+
+	all := append([]string{}, imageIDs...)
+	if previous != nil {
+		all = append(all, previous.([]string)...)
+	}
+	for _, imageID := range all {
+		if namespace, name := ref.Parse(imageID); namespace != "" {
+			h.vmiController.Enqueue(namespace, name)
+		}
+	}
+}
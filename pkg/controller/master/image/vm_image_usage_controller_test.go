@@ -0,0 +1,132 @@
+package image
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func TestIsRetentionExpired(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(days int) metav1.Time {
+		return metav1.NewTime(now.Add(-time.Duration(days) * 24 * time.Hour))
+	}
+	newImage := func(created int, lastUsed *int) *harvesterv1.VirtualMachineImage {
+		vmi := &harvesterv1.VirtualMachineImage{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "image",
+				Namespace:         "default",
+				CreationTimestamp: daysAgo(created),
+			},
+		}
+		if lastUsed != nil {
+			t := daysAgo(*lastUsed)
+			vmi.Status.LastUsedTime = &t
+		}
+		harvesterv1.ImageImported.True(vmi)
+		harvesterv1.ImageImported.LastUpdated(vmi, vmi.CreationTimestamp.UTC().Format(time.RFC3339))
+		return vmi
+	}
+	days := func(d int) *int { return &d }
+
+	var tests = []struct {
+		name   string
+		vmi    func() *harvesterv1.VirtualMachineImage
+		expect bool
+	}{
+		{
+			name:   "never used image older than the retention",
+			vmi:    func() *harvesterv1.VirtualMachineImage { return newImage(40, nil) },
+			expect: true,
+		},
+		{
+			name:   "never used image younger than the retention",
+			vmi:    func() *harvesterv1.VirtualMachineImage { return newImage(10, nil) },
+			expect: false,
+		},
+		{
+			name:   "old image released recently",
+			vmi:    func() *harvesterv1.VirtualMachineImage { return newImage(100, days(5)) },
+			expect: false,
+		},
+		{
+			name:   "old image released long ago",
+			vmi:    func() *harvesterv1.VirtualMachineImage { return newImage(100, days(31)) },
+			expect: true,
+		},
+		{
+			name: "old image imported recently",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(40, nil)
+				harvesterv1.ImageImported.LastUpdated(vmi, daysAgo(5).UTC().Format(time.RFC3339))
+				return vmi
+			},
+			expect: false,
+		},
+		{
+			name: "image without a valid import time",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(40, nil)
+				harvesterv1.ImageImported.LastUpdated(vmi, "")
+				return vmi
+			},
+			expect: true,
+		},
+		{
+			name: "image in use",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(100, days(60))
+				vmi.Status.ReferenceCount = 1
+				vmi.Status.Consumers = []harvesterv1.VirtualMachineImageConsumer{{Kind: consumerKindPVC, Namespace: "default", Name: "disk-0"}}
+				return vmi
+			},
+			expect: false,
+		},
+		{
+			name: "image owned by a template version",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(100, nil)
+				vmi.OwnerReferences = []metav1.OwnerReference{{Kind: consumerKindTemplateVersion, Name: "template-v1"}}
+				return vmi
+			},
+			expect: false,
+		},
+		{
+			name: "upgrade image",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(100, nil)
+				vmi.Annotations = map[string]string{util.AnnotationUpgradeImage: "True"}
+				return vmi
+			},
+			expect: false,
+		},
+		{
+			name: "image not imported",
+			vmi: func() *harvesterv1.VirtualMachineImage {
+				vmi := newImage(100, nil)
+				harvesterv1.ImageImported.False(vmi)
+				return vmi
+			},
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, isRetentionExpired(tc.vmi(), 30, now))
+		})
+	}
+}
+
+func TestSameConsumers(t *testing.T) {
+	consumers := []harvesterv1.VirtualMachineImageConsumer{{Kind: consumerKindVM, Namespace: "default", Name: "vm"}}
+
+	assert.True(t, sameConsumers(nil, []harvesterv1.VirtualMachineImageConsumer{}))
+	assert.True(t, sameConsumers(consumers, []harvesterv1.VirtualMachineImageConsumer{{Kind: consumerKindVM, Namespace: "default", Name: "vm"}}))
+	assert.False(t, sameConsumers(consumers, nil))
+}
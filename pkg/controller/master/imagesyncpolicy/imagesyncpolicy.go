@@ -0,0 +1,284 @@
+package imagesyncpolicy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/remote"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	defaultSyncInterval = 10 * time.Minute
+	// replicas being imported are checked more often than the source cluster changes
+	importingSyncInterval = 30 * time.Second
+
+	reasonSyncFailed = "SyncFailed"
+	reasonOutOfSync  = "OutOfSync"
+)
+
+type imageSyncPolicyHandler struct {
+	ctx              context.Context
+	policyController ctlharvesterv1.ImageSyncPolicyController
+	policyClient     ctlharvesterv1.ImageSyncPolicyClient
+	vmiClient        ctlharvesterv1.VirtualMachineImageClient
+	vmiCache         ctlharvesterv1.VirtualMachineImageCache
+	secretCache      ctlcorev1.SecretCache
+	// clientset lists the cluster networks, which the source cluster must not be reached through
+	clientset kubernetes.Interface
+}
+
+// OnChanged compares the selected images of the source cluster with their replicas once the sync
+// interval has passed, and requeues the policy for the next sync.
+func (h *imageSyncPolicyHandler) OnChanged(_ string, policy *harvesterv1.ImageSyncPolicy) (*harvesterv1.ImageSyncPolicy, error) {
+	if policy == nil || policy.DeletionTimestamp != nil {
+		return policy, nil
+	}
+
+	if policy.Status.LastSyncTime != nil && policy.Status.ObservedGeneration == policy.Generation {
+		if wait := time.Until(nextSyncTime(policy)); wait > 0 {
+			h.policyController.EnqueueAfter(policy.Namespace, policy.Name, wait)
+			return policy, nil
+		}
+	}
+
+	toUpdate := policy.DeepCopy()
+	if err := h.sync(toUpdate); err != nil {
+		logrus.WithError(err).Errorf("failed to sync image sync policy %s/%s", policy.Namespace, policy.Name)
+		harvesterv1.ImageSyncPolicyConditionSynced.False(toUpdate)
+		harvesterv1.ImageSyncPolicyConditionSynced.Reason(toUpdate, reasonSyncFailed)
+		harvesterv1.ImageSyncPolicyConditionSynced.Message(toUpdate, err.Error())
+	}
+	now := metav1.Now()
+	toUpdate.Status.LastSyncTime = &now
+	toUpdate.Status.ObservedGeneration = policy.Generation
+
+	updated, err := h.policyClient.UpdateStatus(toUpdate)
+	if err != nil {
+		return policy, err
+	}
+	h.policyController.EnqueueAfter(updated.Namespace, updated.Name, time.Until(nextSyncTime(updated)))
+	return updated, nil
+}
+
+func (h *imageSyncPolicyHandler) sync(policy *harvesterv1.ImageSyncPolicy) error {
+	blocked, err := export.BlockedNetworks(h.ctx, h.clientset)
+	if err != nil {
+		return err
+	}
+	cluster, err := remote.NewCluster(h.secretCache, blocked, policy.Namespace, policy.Spec.KubeconfigSecretName)
+	if err != nil {
+		return err
+	}
+	selector, err := sourceSelector(policy.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	sources, err := cluster.Client.HarvesterhciV1beta1().VirtualMachineImages(policy.Spec.SourceNamespace).List(h.ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the images of the source cluster: %w", err)
+	}
+
+	replicas, err := h.vmiCache.List(policy.Namespace, labels.SelectorFromSet(labels.Set{util.LabelImageSyncPolicy: policy.Name}))
+	if err != nil {
+		return err
+	}
+	replicaByName := make(map[string]*harvesterv1.VirtualMachineImage, len(replicas))
+	for _, replica := range replicas {
+		replicaByName[replica.Name] = replica
+	}
+
+	statuses := make([]harvesterv1.ImageSyncStatus, 0, len(sources.Items))
+	for i := range sources.Items {
+		source := &sources.Items[i]
+		if source.DeletionTimestamp != nil {
+			continue
+		}
+		statuses = append(statuses, h.syncImage(policy, source, replicaByName[source.Name]))
+		delete(replicaByName, source.Name)
+	}
+
+	if policy.Spec.Prune {
+		for _, replica := range replicaByName {
+			if replica.DeletionTimestamp != nil {
+				continue
+			}
+			logrus.Infof("pruning image %s/%s, its source image is gone", replica.Namespace, replica.Name)
+			if err := h.vmiClient.Delete(replica.Namespace, replica.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				logrus.WithError(err).Warnf("failed to prune image %s/%s", replica.Namespace, replica.Name)
+			}
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	policy.Status.Images = statuses
+	policy.Status.ImageCount = len(statuses)
+
+	inSync := 0
+	for _, status := range statuses {
+		if status.State == harvesterv1.ImageSyncStateInSync {
+			inSync++
+		}
+	}
+	if inSync == len(statuses) {
+		harvesterv1.ImageSyncPolicyConditionSynced.True(policy)
+		harvesterv1.ImageSyncPolicyConditionSynced.Reason(policy, "")
+		harvesterv1.ImageSyncPolicyConditionSynced.Message(policy, "")
+	} else {
+		harvesterv1.ImageSyncPolicyConditionSynced.False(policy)
+		harvesterv1.ImageSyncPolicyConditionSynced.Reason(policy, reasonOutOfSync)
+		harvesterv1.ImageSyncPolicyConditionSynced.Message(policy, fmt.Sprintf("%d of %d images are in sync", inSync, len(statuses)))
+	}
+	return nil
+}
+
+// syncImage creates the missing replica of the source image or replaces a stale one.
+func (h *imageSyncPolicyHandler) syncImage(policy *harvesterv1.ImageSyncPolicy, source, replica *harvesterv1.VirtualMachineImage) harvesterv1.ImageSyncStatus {
+	status := harvesterv1.ImageSyncStatus{
+		Name:           source.Name,
+		SourceChecksum: remote.SourceChecksum(source),
+	}
+
+	if replica == nil {
+		if _, err := h.vmiCache.Get(policy.Namespace, source.Name); err == nil {
+			status.State = harvesterv1.ImageSyncStateFailed
+			status.Message = fmt.Sprintf("image %s/%s already exists and is not managed by the policy", policy.Namespace, source.Name)
+			return status
+		}
+		if err := remote.IsImportable(source); err != nil {
+			status.State = harvesterv1.ImageSyncStateFailed
+			if !harvesterv1.ImageImported.IsTrue(source) {
+				// the source image is still being imported
+				status.State = harvesterv1.ImageSyncStateSyncing
+			}
+			status.Message = err.Error()
+			return status
+		}
+		if _, err := h.vmiClient.Create(newReplica(policy, source)); err != nil && !apierrors.IsAlreadyExists(err) {
+			status.State = harvesterv1.ImageSyncStateFailed
+			status.Message = fmt.Sprintf("failed to create the replica: %v", err)
+			return status
+		}
+		status.State = harvesterv1.ImageSyncStateSyncing
+		return status
+	}
+
+	status.Checksum = replica.Status.Checksum
+	var replace bool
+	status.State, status.Message, replace = compareReplica(source, replica)
+	if replace {
+		logrus.Infof("replacing image %s/%s: %s", replica.Namespace, replica.Name, status.Message)
+		if err := h.vmiClient.Delete(replica.Namespace, replica.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			status.Message = fmt.Sprintf("%s, failed to replace the replica: %v", status.Message, err)
+		}
+	}
+	return status
+}
+
+// compareReplica returns the sync state of the replica of the source image, and whether the replica
+// has to be replaced since it is not a copy of the current source image.
+func compareReplica(source, replica *harvesterv1.VirtualMachineImage) (harvesterv1.ImageSyncState, string, bool) {
+	if replica.DeletionTimestamp != nil {
+		return harvesterv1.ImageSyncStateSyncing, "the replica is being replaced", false
+	}
+	if replica.Annotations[util.AnnotationImageSyncSourceUID] != string(source.UID) {
+		return harvesterv1.ImageSyncStateOutOfSync, "the source image was recreated", true
+	}
+
+	sourceChecksum := remote.SourceChecksum(source)
+	if sourceChecksum != "" && replica.Status.Checksum != "" && sourceChecksum != replica.Status.Checksum {
+		return harvesterv1.ImageSyncStateOutOfSync,
+			fmt.Sprintf("the checksum %s of the replica differs from the checksum %s of the source image", replica.Status.Checksum, sourceChecksum), true
+	}
+
+	switch {
+	case harvesterv1.ImageImported.IsTrue(replica):
+		return harvesterv1.ImageSyncStateInSync, "", false
+	case harvesterv1.ImageRetryLimitExceeded.IsTrue(replica):
+		return harvesterv1.ImageSyncStateFailed, harvesterv1.ImageImported.GetMessage(replica), false
+	default:
+		return harvesterv1.ImageSyncStateSyncing, harvesterv1.ImageImported.GetMessage(replica), false
+	}
+}
+
+func newReplica(policy *harvesterv1.ImageSyncPolicy, source *harvesterv1.VirtualMachineImage) *harvesterv1.VirtualMachineImage {
+	backend := policy.Spec.Backend
+	if backend == "" {
+		backend = source.Spec.Backend
+	}
+
+	replica := &harvesterv1.VirtualMachineImage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      source.Name,
+			Namespace: policy.Namespace,
+			Labels: map[string]string{
+				util.LabelImageSyncPolicy: policy.Name,
+			},
+			Annotations: map[string]string{
+				util.AnnotationImageSyncSourceUID: string(source.UID),
+			},
+		},
+		Spec: harvesterv1.VirtualMachineImageSpec{
+			Backend:     backend,
+			DisplayName: source.Spec.DisplayName,
+			Description: source.Spec.Description,
+			SourceType:  harvesterv1.VirtualMachineImageSourceTypeRemote,
+			Remote: &harvesterv1.VirtualMachineImageRemoteSource{
+				KubeconfigSecretName: policy.Spec.KubeconfigSecretName,
+				ImageNamespace:       source.Namespace,
+				ImageName:            source.Name,
+			},
+		},
+	}
+	if checksum := remote.SourceChecksum(source); checksum != "" {
+		replica.Spec.Checksum = "sha512:" + checksum
+	}
+	if backend == harvesterv1.VMIBackendCDI {
+		replica.Spec.TargetStorageClassName = policy.Spec.TargetStorageClassName
+		if replica.Spec.TargetStorageClassName == "" {
+			replica.Spec.TargetStorageClassName = source.Spec.TargetStorageClassName
+		}
+	}
+	return replica
+}
+
+func sourceSelector(selector *metav1.LabelSelector) (labels.Selector, error) {
+	if selector == nil {
+		return labels.Everything(), nil
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// nextSyncTime returns when the policy is synced next, which is sooner while replicas are being imported.
+func nextSyncTime(policy *harvesterv1.ImageSyncPolicy) time.Time {
+	interval := defaultSyncInterval
+	if policy.Spec.Interval != nil && policy.Spec.Interval.Duration > 0 {
+		interval = policy.Spec.Interval.Duration
+	}
+	for _, image := range policy.Status.Images {
+		if image.State == harvesterv1.ImageSyncStateSyncing && importingSyncInterval < interval {
+			interval = importingSyncInterval
+			break
+		}
+	}
+	if policy.Status.LastSyncTime == nil {
+		return time.Now()
+	}
+	return policy.Status.LastSyncTime.Add(interval)
+}
@@ -0,0 +1,135 @@
+package imagesyncpolicy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func newImage(uid, checksum string, imported bool) *harvesterv1.VirtualMachineImage {
+	vmi := &harvesterv1.VirtualMachineImage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "image-a",
+			UID:         "source-uid",
+			Annotations: map[string]string{util.AnnotationImageSyncSourceUID: uid},
+		},
+		Status: harvesterv1.VirtualMachineImageStatus{Checksum: checksum},
+	}
+	if imported {
+		harvesterv1.ImageImported.True(vmi)
+	}
+	return vmi
+}
+
+func TestCompareReplica(t *testing.T) {
+	checksumA := strings.Repeat("a", 128)
+	checksumB := strings.Repeat("b", 128)
+
+	var tests = []struct {
+		name          string
+		source        *harvesterv1.VirtualMachineImage
+		replica       *harvesterv1.VirtualMachineImage
+		expectState   harvesterv1.ImageSyncState
+		expectReplace bool
+	}{
+		{
+			name:        "imported replica is in sync",
+			source:      newImage("", checksumA, true),
+			replica:     newImage("source-uid", checksumA, true),
+			expectState: harvesterv1.ImageSyncStateInSync,
+		},
+		{
+			name:        "unknown source checksum is not compared",
+			source:      newImage("", "", true),
+			replica:     newImage("source-uid", checksumA, true),
+			expectState: harvesterv1.ImageSyncStateInSync,
+		},
+		{
+			name:        "replica being imported is syncing",
+			source:      newImage("", checksumA, true),
+			replica:     newImage("source-uid", "", false),
+			expectState: harvesterv1.ImageSyncStateSyncing,
+		},
+		{
+			name:          "recreated source image is replaced",
+			source:        newImage("", checksumA, true),
+			replica:       newImage("previous-uid", checksumA, true),
+			expectState:   harvesterv1.ImageSyncStateOutOfSync,
+			expectReplace: true,
+		},
+		{
+			name:          "checksum mismatch is replaced",
+			source:        newImage("", checksumA, true),
+			replica:       newImage("source-uid", checksumB, true),
+			expectState:   harvesterv1.ImageSyncStateOutOfSync,
+			expectReplace: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			state, _, replace := compareReplica(tc.source, tc.replica)
+			assert.Equal(t, tc.expectState, state)
+			assert.Equal(t, tc.expectReplace, replace)
+		})
+	}
+}
+
+func TestNextSyncTime(t *testing.T) {
+	lastSync := metav1.NewTime(time.Now().Truncate(time.Second))
+	policy := &harvesterv1.ImageSyncPolicy{
+		Spec: harvesterv1.ImageSyncPolicySpec{
+			Interval: &metav1.Duration{Duration: time.Hour},
+		},
+		Status: harvesterv1.ImageSyncPolicyStatus{
+			LastSyncTime: &lastSync,
+			Images: []harvesterv1.ImageSyncStatus{
+				{Name: "image-a", State: harvesterv1.ImageSyncStateInSync},
+			},
+		},
+	}
+	assert.Equal(t, lastSync.Add(time.Hour), nextSyncTime(policy))
+
+	policy.Status.Images = append(policy.Status.Images, harvesterv1.ImageSyncStatus{Name: "image-b", State: harvesterv1.ImageSyncStateSyncing})
+	assert.Equal(t, lastSync.Add(importingSyncInterval), nextSyncTime(policy))
+
+	policy.Spec.Interval = nil
+	policy.Status.Images = nil
+	assert.Equal(t, lastSync.Add(defaultSyncInterval), nextSyncTime(policy))
+}
+
+func TestNewReplica(t *testing.T) {
+	policy := &harvesterv1.ImageSyncPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "mirror", Namespace: "team-a"},
+		Spec: harvesterv1.ImageSyncPolicySpec{
+			KubeconfigSecretName:   "peer-kubeconfig",
+			SourceNamespace:        "default",
+			Backend:                harvesterv1.VMIBackendCDI,
+			TargetStorageClassName: "local",
+		},
+	}
+	source := newImage("", strings.Repeat("a", 128), true)
+	source.Namespace = "default"
+	source.Spec.DisplayName = "Image A"
+
+	replica := newReplica(policy, source)
+	assert.Equal(t, "image-a", replica.Name)
+	assert.Equal(t, "team-a", replica.Namespace)
+	assert.Equal(t, "mirror", replica.Labels[util.LabelImageSyncPolicy])
+	assert.Equal(t, "source-uid", replica.Annotations[util.AnnotationImageSyncSourceUID])
+	assert.Equal(t, harvesterv1.VirtualMachineImageSourceTypeRemote, replica.Spec.SourceType)
+	assert.Equal(t, &harvesterv1.VirtualMachineImageRemoteSource{
+		KubeconfigSecretName: "peer-kubeconfig",
+		ImageNamespace:       "default",
+		ImageName:            "image-a",
+	}, replica.Spec.Remote)
+	assert.Equal(t, "sha512:"+strings.Repeat("a", 128), replica.Spec.Checksum)
+	assert.Equal(t, "local", replica.Spec.TargetStorageClassName)
+	assert.Equal(t, "Image A", replica.Spec.DisplayName)
+}
@@ -0,0 +1,30 @@
+package imagesyncpolicy
+
+import (
+	"context"
+
+	"github.com/harvester/harvester/pkg/config"
+)
+
+const (
+	imageSyncPolicyControllerName = "image-sync-policy-controller"
+)
+
+func Register(ctx context.Context, management *config.Management, _ config.Options) error {
+	policies := management.HarvesterFactory.Harvesterhci().V1beta1().ImageSyncPolicy()
+	vmis := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImage()
+	secrets := management.CoreFactory.Core().V1().Secret()
+
+	handler := &imageSyncPolicyHandler{
+		ctx:              ctx,
+		policyController: policies,
+		policyClient:     policies,
+		vmiClient:        vmis,
+		vmiCache:         vmis.Cache(),
+		secretCache:      secrets.Cache(),
+		clientset:        management.ClientSet,
+	}
+
+	policies.OnChange(ctx, imageSyncPolicyControllerName, handler.OnChanged)
+	return nil
+}
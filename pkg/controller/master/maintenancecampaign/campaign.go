@@ -0,0 +1,547 @@
+package maintenancecampaign
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlnode "github.com/harvester/harvester/pkg/controller/master/node"
+	"github.com/harvester/harvester/pkg/controller/master/nodedrain"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/drainhelper"
+)
+
+const (
+	pollInterval       = 10 * time.Second
+	defaultHookTimeout = time.Hour
+
+	hookStagePre  = "pre"
+	hookStagePost = "post"
+
+	hookContainerName = "hook"
+	hostRootVolume    = "host-root"
+	hostRootMountPath = "/host"
+)
+
+var campaignKind = harvesterv1.SchemeGroupVersion.WithKind("NodeMaintenanceCampaign")
+
+type campaignHandler struct {
+	campaignController ctlharvesterv1.NodeMaintenanceCampaignController
+	campaignClient     ctlharvesterv1.NodeMaintenanceCampaignClient
+	nodeClient         ctlcorev1.NodeClient
+	nodeCache          ctlcorev1.NodeCache
+	jobClient          ctlbatchv1.JobClient
+	jobCache           ctlbatchv1.JobCache
+	vmClient           ctlkubevirtv1.VirtualMachineClient
+	vmCache            ctlkubevirtv1.VirtualMachineCache
+	vmGroupCache       ctlharvesterv1.VirtualMachineGroupCache
+	drainHelper        *nodedrain.ControllerHandler
+	namespace          string
+}
+
+// OnChanged puts the nodes of the campaign into maintenance mode, at most spec.concurrency nodes at
+// the same time. No further node is started once the maintenance of a node failed.
+func (h *campaignHandler) OnChanged(_ string, campaign *harvesterv1.NodeMaintenanceCampaign) (*harvesterv1.NodeMaintenanceCampaign, error) {
+	if campaign == nil || campaign.DeletionTimestamp != nil {
+		return campaign, nil
+	}
+
+	toUpdate := campaign.DeepCopy()
+	requeue, err := h.reconcile(toUpdate)
+	if err != nil {
+		return campaign, err
+	}
+
+	updated := campaign
+	if !equality.Semantic.DeepEqual(campaign.Status, toUpdate.Status) {
+		if updated, err = h.campaignClient.UpdateStatus(toUpdate); err != nil {
+			return campaign, err
+		}
+	}
+	if requeue {
+		h.campaignController.EnqueueAfter(campaign.Name, pollInterval)
+	}
+	return updated, nil
+}
+
+// OnJobChanged enqueues the campaign of a hook job.
+func (h *campaignHandler) OnJobChanged(_ string, job *batchv1.Job) (*batchv1.Job, error) {
+	if job == nil || job.Namespace != h.namespace {
+		return job, nil
+	}
+	if campaignName := job.Labels[util.LabelNodeMaintenanceCampaign]; campaignName != "" {
+		h.campaignController.Enqueue(campaignName)
+	}
+	return job, nil
+}
+
+// reconcile returns whether the campaign has to be checked again.
+func (h *campaignHandler) reconcile(campaign *harvesterv1.NodeMaintenanceCampaign) (bool, error) {
+	switch campaign.Status.Phase {
+	case harvesterv1.NodeMaintenanceCampaignPhaseSucceeded, harvesterv1.NodeMaintenanceCampaignPhaseFailed:
+		return false, nil
+	case harvesterv1.NodeMaintenanceCampaignPhaseRunning:
+	default:
+		if err := h.initialize(campaign); err != nil {
+			return false, err
+		}
+		return campaign.Status.Phase == harvesterv1.NodeMaintenanceCampaignPhaseRunning, nil
+	}
+
+	for i := range campaign.Status.Nodes {
+		if isInProgress(campaign.Status.Nodes[i].State) {
+			if err := h.advance(campaign, &campaign.Status.Nodes[i]); err != nil {
+				return true, err
+			}
+		}
+	}
+
+	inProgress := 0
+	failedNode := ""
+	for _, status := range campaign.Status.Nodes {
+		switch {
+		case isInProgress(status.State):
+			inProgress++
+		case status.State == harvesterv1.NodeMaintenanceStateFailed && failedNode == "":
+			failedNode = status.Name
+		}
+	}
+
+	if failedNode == "" {
+		for i := range campaign.Status.Nodes {
+			status := &campaign.Status.Nodes[i]
+			if inProgress >= concurrency(campaign) {
+				break
+			}
+			if status.State != harvesterv1.NodeMaintenanceStatePending {
+				continue
+			}
+			now := metav1.Now()
+			status.State = harvesterv1.NodeMaintenanceStatePreHook
+			status.StartTime = &now
+			inProgress++
+		}
+	}
+
+	if inProgress > 0 {
+		return true, nil
+	}
+	if failedNode != "" {
+		complete(campaign, harvesterv1.NodeMaintenanceCampaignPhaseFailed, fmt.Sprintf("the maintenance of node %s failed", failedNode))
+		return false, nil
+	}
+	complete(campaign, harvesterv1.NodeMaintenanceCampaignPhaseSucceeded, "")
+	return false, nil
+}
+
+// initialize resolves the nodes of the campaign, the listed nodes come first in their order and the
+// nodes matching the selector follow in the order of their names.
+func (h *campaignHandler) initialize(campaign *harvesterv1.NodeMaintenanceCampaign) error {
+	nodeNames := make([]string, 0, len(campaign.Spec.Nodes))
+	seen := make(map[string]bool)
+	for _, nodeName := range campaign.Spec.Nodes {
+		if !seen[nodeName] {
+			seen[nodeName] = true
+			nodeNames = append(nodeNames, nodeName)
+		}
+	}
+
+	if campaign.Spec.NodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(campaign.Spec.NodeSelector)
+		if err != nil {
+			return err
+		}
+		nodes, err := h.nodeCache.List(selector)
+		if err != nil {
+			return err
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+		for _, node := range nodes {
+			if !seen[node.Name] {
+				seen[node.Name] = true
+				nodeNames = append(nodeNames, node.Name)
+			}
+		}
+	}
+
+	now := metav1.Now()
+	campaign.Status.StartTime = &now
+	if len(nodeNames) == 0 {
+		complete(campaign, harvesterv1.NodeMaintenanceCampaignPhaseFailed, "no nodes match the campaign")
+		return nil
+	}
+
+	// the campaign would disable the maintenance mode of these nodes when it is done with them
+	var inMaintenance []string
+	for _, nodeName := range nodeNames {
+		node, err := h.nodeCache.Get(nodeName)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if inMaintenanceMode(node) {
+			inMaintenance = append(inMaintenance, nodeName)
+		}
+	}
+	if len(inMaintenance) > 0 {
+		complete(campaign, harvesterv1.NodeMaintenanceCampaignPhaseFailed, fmt.Sprintf("nodes are already in maintenance mode: %s", strings.Join(inMaintenance, ", ")))
+		return nil
+	}
+
+	campaign.Status.Phase = harvesterv1.NodeMaintenanceCampaignPhaseRunning
+	campaign.Status.Nodes = make([]harvesterv1.NodeMaintenanceStatus, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		campaign.Status.Nodes = append(campaign.Status.Nodes, harvesterv1.NodeMaintenanceStatus{
+			Name:  nodeName,
+			State: harvesterv1.NodeMaintenanceStatePending,
+		})
+	}
+	return nil
+}
+
+func (h *campaignHandler) advance(campaign *harvesterv1.NodeMaintenanceCampaign, status *harvesterv1.NodeMaintenanceStatus) error {
+	switch status.State {
+	case harvesterv1.NodeMaintenanceStatePreHook:
+		done, err := h.runHook(campaign, status, campaign.Spec.PreHook, hookStagePre)
+		if err != nil || !done {
+			return err
+		}
+		return h.enterMaintenance(campaign, status)
+	case harvesterv1.NodeMaintenanceStateEntering:
+		return h.waitForMaintenance(status)
+	case harvesterv1.NodeMaintenanceStatePostHook:
+		done, err := h.runHook(campaign, status, campaign.Spec.PostHook, hookStagePost)
+		if err != nil || !done {
+			return err
+		}
+		return h.exitMaintenance(status)
+	case harvesterv1.NodeMaintenanceStateExiting:
+		return h.waitForNode(status)
+	}
+	return nil
+}
+
+// enterMaintenance requests maintenance mode of the node like the enableMaintenanceMode action, the
+// maintenance plan of the node is checked first so a node with blocked VMs fails without being drained.
+func (h *campaignHandler) enterMaintenance(campaign *harvesterv1.NodeMaintenanceCampaign, status *harvesterv1.NodeMaintenanceStatus) error {
+	node, err := h.nodeCache.Get(status.Name)
+	if apierrors.IsNotFound(err) {
+		fail(status, "node not found")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the node may have been put into maintenance mode since the campaign started
+	if inMaintenanceMode(node) {
+		fail(status, "the node is already in maintenance mode")
+		return nil
+	}
+
+	plan, err := h.drainHelper.MaintenancePlan(node, campaign.Spec.Force)
+	if err != nil {
+		return err
+	}
+	if !plan.Possible {
+		// another node of the campaign may still be in maintenance mode, e.g. a control plane node
+		if othersInProgress(campaign, status.Name) {
+			status.Message = plan.Message
+			return nil
+		}
+		fail(status, plan.Message)
+		return nil
+	}
+	if plan.Blocked {
+		var blockedVMs []string
+		for _, vm := range plan.VMs {
+			if vm.Outcome == nodedrain.MaintenancePlanBlocked {
+				blockedVMs = append(blockedVMs, fmt.Sprintf("%s/%s", vm.Namespace, vm.Name))
+			}
+		}
+		fail(status, fmt.Sprintf("%s: %s", plan.Message, strings.Join(blockedVMs, ", ")))
+		return nil
+	}
+
+	toUpdate := node.DeepCopy()
+	drainhelper.RequestMaintenanceMode(toUpdate, campaign.Spec.Force)
+	if _, err := h.nodeClient.Update(toUpdate); err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{
+		"campaign": campaign.Name,
+		"node":     status.Name,
+	}).Info("enabling maintenance mode")
+	status.State = harvesterv1.NodeMaintenanceStateEntering
+	status.Message = ""
+	return nil
+}
+
+// waitForMaintenance waits for the maintain controller to complete the maintenance mode of the node.
+// The node drain controller removes the annotations if the node can't be drained.
+func (h *campaignHandler) waitForMaintenance(status *harvesterv1.NodeMaintenanceStatus) error {
+	node, err := h.nodeCache.Get(status.Name)
+	if err != nil {
+		return err
+	}
+	if node.Annotations[ctlnode.MaintainStatusAnnotationKey] == ctlnode.MaintainStatusComplete {
+		status.State = harvesterv1.NodeMaintenanceStatePostHook
+		return nil
+	}
+	if maintenanceRequested(node) {
+		return nil
+	}
+
+	// the cache may not have seen the request yet
+	node, err = h.nodeClient.Get(status.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !maintenanceRequested(node) {
+		fail(status, "maintenance mode was aborted, check the maintenancePlan action of the node")
+	}
+	return nil
+}
+
+// exitMaintenance disables maintenance mode of the node like the disableMaintenanceMode action.
+func (h *campaignHandler) exitMaintenance(status *harvesterv1.NodeMaintenanceStatus) error {
+	node, err := h.nodeClient.Get(status.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	toUpdate := node.DeepCopy()
+	drainhelper.DisableMaintenanceMode(toUpdate)
+	if !equality.Semantic.DeepEqual(node, toUpdate) {
+		if _, err := h.nodeClient.Update(toUpdate); err != nil {
+			return err
+		}
+	}
+
+	if err := ctlnode.RestartVMs(status.Name, util.MaintainModeStrategyShutdownAndRestartAfterDisable, h.vmClient, h.vmCache, h.vmGroupCache); err != nil {
+		return err
+	}
+	status.State = harvesterv1.NodeMaintenanceStateExiting
+	status.Message = ""
+	return nil
+}
+
+// waitForNode waits for the node to be schedulable and ready again.
+func (h *campaignHandler) waitForNode(status *harvesterv1.NodeMaintenanceStatus) error {
+	node, err := h.nodeCache.Get(status.Name)
+	if err != nil {
+		return err
+	}
+	if node.Spec.Unschedulable || !isNodeReady(node) {
+		status.Message = "waiting for the node to be ready"
+		return nil
+	}
+	now := metav1.Now()
+	status.State = harvesterv1.NodeMaintenanceStateCompleted
+	status.Message = ""
+	status.CompletionTime = &now
+	return nil
+}
+
+// runHook creates the hook job of the stage on the node, it returns whether the job succeeded.
+// The node fails if the job fails.
+func (h *campaignHandler) runHook(campaign *harvesterv1.NodeMaintenanceCampaign, status *harvesterv1.NodeMaintenanceStatus,
+	hook *harvesterv1.NodeMaintenanceHook, stage string) (bool, error) {
+	if hook == nil {
+		return true, nil
+	}
+
+	jobName := name.SafeConcatName(campaign.Name, status.Name, stage)
+	job, err := h.jobCache.Get(h.namespace, jobName)
+	if apierrors.IsNotFound(err) {
+		if _, err := h.jobClient.Create(h.newHookJob(campaign, status.Name, hook, jobName)); err != nil && !apierrors.IsAlreadyExists(err) {
+			return false, err
+		}
+		status.Message = fmt.Sprintf("running the %s hook job %s/%s", stage, h.namespace, jobName)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			status.Message = ""
+			return true, nil
+		case batchv1.JobFailed:
+			fail(status, fmt.Sprintf("the %s hook job %s/%s failed: %s", stage, h.namespace, jobName, condition.Message))
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+func (h *campaignHandler) newHookJob(campaign *harvesterv1.NodeMaintenanceCampaign, nodeName string, hook *harvesterv1.NodeMaintenanceHook, jobName string) *batchv1.Job {
+	timeout := defaultHookTimeout
+	if hook.Timeout != nil {
+		timeout = hook.Timeout.Duration
+	}
+	labels := map[string]string{
+		util.LabelNodeMaintenanceCampaign: campaign.Name,
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            jobName,
+			Namespace:       h.namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(campaign, campaignKind)},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          ptr.To(int32(0)), // do not retry
+			ActiveDeadlineSeconds: ptr.To(int64(timeout.Seconds())),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: ptr.To(false),
+					Containers: []corev1.Container{
+						{
+							Name:    hookContainerName,
+							Image:   hook.Image,
+							Command: hook.Command,
+							Args:    hook.Args,
+							SecurityContext: &corev1.SecurityContext{
+								Privileged:               ptr.To(false),
+								AllowPrivilegeEscalation: ptr.To(false),
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+							},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "NODE_NAME",
+									Value: nodeName,
+								},
+							},
+						},
+					},
+					// the node is cordoned and tainted while it is in maintenance mode
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+					Affinity: &corev1.Affinity{
+						NodeAffinity: &corev1.NodeAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+								NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+									MatchExpressions: []corev1.NodeSelectorRequirement{{
+										Key:      corev1.LabelHostname,
+										Operator: corev1.NodeSelectorOpIn,
+										Values:   []string{nodeName},
+									}},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if hook.HostAccess {
+		addHostAccess(&job.Spec.Template.Spec)
+	}
+	return job
+}
+
+// addHostAccess runs the hook privileged in the PID namespace of the host with the root filesystem of
+// the node mounted.
+func addHostAccess(podSpec *corev1.PodSpec) {
+	hostPathDirectory := corev1.HostPathDirectory
+	podSpec.HostPID = true
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: hostRootVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: "/",
+				Type: &hostPathDirectory,
+			},
+		},
+	})
+	container := &podSpec.Containers[0]
+	container.SecurityContext = &corev1.SecurityContext{
+		Privileged: ptr.To(true),
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: hostRootVolume, MountPath: hostRootMountPath})
+}
+
+func concurrency(campaign *harvesterv1.NodeMaintenanceCampaign) int {
+	if campaign.Spec.Concurrency > 0 {
+		return campaign.Spec.Concurrency
+	}
+	return 1
+}
+
+func isInProgress(state harvesterv1.NodeMaintenanceState) bool {
+	switch state {
+	case harvesterv1.NodeMaintenanceStatePreHook, harvesterv1.NodeMaintenanceStateEntering,
+		harvesterv1.NodeMaintenanceStatePostHook, harvesterv1.NodeMaintenanceStateExiting:
+		return true
+	}
+	return false
+}
+
+func othersInProgress(campaign *harvesterv1.NodeMaintenanceCampaign, nodeName string) bool {
+	for _, status := range campaign.Status.Nodes {
+		if status.Name != nodeName && isInProgress(status.State) {
+			return true
+		}
+	}
+	return false
+}
+
+func maintenanceRequested(node *corev1.Node) bool {
+	_, ok := node.Annotations[drainhelper.DrainAnnotation]
+	return ok || node.Annotations[ctlnode.MaintainStatusAnnotationKey] == ctlnode.MaintainStatusRunning
+}
+
+func inMaintenanceMode(node *corev1.Node) bool {
+	return maintenanceRequested(node) || node.Annotations[ctlnode.MaintainStatusAnnotationKey] == ctlnode.MaintainStatusComplete
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func fail(status *harvesterv1.NodeMaintenanceStatus, message string) {
+	now := metav1.Now()
+	status.State = harvesterv1.NodeMaintenanceStateFailed
+	status.Message = message
+	status.CompletionTime = &now
+}
+
+func complete(campaign *harvesterv1.NodeMaintenanceCampaign, phase harvesterv1.NodeMaintenanceCampaignPhase, message string) {
+	now := metav1.Now()
+	campaign.Status.Phase = phase
+	campaign.Status.Message = message
+	campaign.Status.CompletionTime = &now
+}
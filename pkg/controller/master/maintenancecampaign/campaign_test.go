@@ -0,0 +1,246 @@
+package maintenancecampaign
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlnode "github.com/harvester/harvester/pkg/controller/master/node"
+	"github.com/harvester/harvester/pkg/controller/master/nodedrain"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/drainhelper"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+const testNamespace = "harvester-system"
+
+func newReadyNode(name string, labels map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func newCampaign(spec harvesterv1.NodeMaintenanceCampaignSpec) *harvesterv1.NodeMaintenanceCampaign {
+	return &harvesterv1.NodeMaintenanceCampaign{
+		ObjectMeta: metav1.ObjectMeta{Name: "firmware"},
+		Spec:       spec,
+	}
+}
+
+func newHandler(objects ...runtime.Object) (*campaignHandler, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	nodeCache := fakeclients.NodeCache(clientset.CoreV1().Nodes)
+	vmGroupCache := fakeclients.VirtualMachineGroupCache(clientset.HarvesterhciV1beta1().VirtualMachineGroups)
+	return &campaignHandler{
+		campaignClient: fakeclients.NodeMaintenanceCampaignClient(clientset.HarvesterhciV1beta1().NodeMaintenanceCampaigns),
+		nodeClient:     fakeclients.NodeClient(clientset.CoreV1().Nodes),
+		nodeCache:      nodeCache,
+		jobClient:      fakeclients.JobClient(clientset.BatchV1().Jobs),
+		jobCache:       fakeclients.JobCache(clientset.BatchV1().Jobs),
+		vmClient:       fakeclients.VirtualMachineClient(clientset.KubevirtV1().VirtualMachines),
+		vmCache:        fakeclients.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+		vmGroupCache:   vmGroupCache,
+		drainHelper: nodedrain.ActionHelper(nodeCache,
+			fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+			fakeclients.LonghornVolumeCache(clientset.LonghornV1beta2().Volumes),
+			fakeclients.LonghornReplicaCache(clientset.LonghornV1beta2().Replicas),
+			vmGroupCache,
+			fakeclients.VMPlacementPolicyCache(clientset.HarvesterhciV1beta1().VMPlacementPolicies)),
+		namespace: testNamespace,
+	}, clientset
+}
+
+func getNode(t *testing.T, clientset *fake.Clientset, name string) *corev1.Node {
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return node
+}
+
+func nodeStates(campaign *harvesterv1.NodeMaintenanceCampaign) []harvesterv1.NodeMaintenanceState {
+	states := make([]harvesterv1.NodeMaintenanceState, 0, len(campaign.Status.Nodes))
+	for _, status := range campaign.Status.Nodes {
+		states = append(states, status.State)
+	}
+	return states
+}
+
+func TestInitialize(t *testing.T) {
+	selected := map[string]string{"firmware": "outdated"}
+	handler, _ := newHandler(
+		newReadyNode("node1", selected), newReadyNode("node2", selected),
+		newReadyNode("node3", selected), newReadyNode("node4", nil),
+	)
+
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{
+		Nodes:        []string{"node3", "node4"},
+		NodeSelector: &metav1.LabelSelector{MatchLabels: selected},
+	})
+	requeue, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, harvesterv1.NodeMaintenanceCampaignPhaseRunning, campaign.Status.Phase)
+	// the listed nodes come first, the selected nodes follow in the order of their names
+	var names []string
+	for _, status := range campaign.Status.Nodes {
+		names = append(names, status.Name)
+	}
+	assert.Equal(t, []string{"node3", "node4", "node1", "node2"}, names)
+
+	campaign = newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{
+		NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"firmware": "unknown"}},
+	})
+	requeue, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, harvesterv1.NodeMaintenanceCampaignPhaseFailed, campaign.Status.Phase)
+}
+
+func TestMaintenanceOneNodeAtATime(t *testing.T) {
+	handler, clientset := newHandler(newReadyNode("node1", nil), newReadyNode("node2", nil), newReadyNode("node3", nil))
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1", "node2"}})
+
+	_, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStatePreHook, harvesterv1.NodeMaintenanceStatePending}, nodeStates(campaign))
+
+	// without a pre hook maintenance mode is requested right away
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStateEntering, harvesterv1.NodeMaintenanceStatePending}, nodeStates(campaign))
+	node1 := getNode(t, clientset, "node1")
+	assert.Contains(t, node1.Annotations, drainhelper.DrainAnnotation)
+
+	// the node drain and maintain controllers complete the maintenance mode
+	node1.Spec.Unschedulable = true
+	delete(node1.Annotations, drainhelper.DrainAnnotation)
+	node1.Annotations[ctlnode.MaintainStatusAnnotationKey] = ctlnode.MaintainStatusComplete
+	_, err = clientset.CoreV1().Nodes().Update(context.TODO(), node1, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStatePostHook, harvesterv1.NodeMaintenanceStatePending}, nodeStates(campaign))
+
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStateExiting, harvesterv1.NodeMaintenanceStatePending}, nodeStates(campaign))
+	node1 = getNode(t, clientset, "node1")
+	assert.False(t, node1.Spec.Unschedulable)
+	assert.NotContains(t, node1.Annotations, ctlnode.MaintainStatusAnnotationKey)
+
+	// the next node starts once the node is back
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStateCompleted, harvesterv1.NodeMaintenanceStatePreHook}, nodeStates(campaign))
+	assert.NotNil(t, campaign.Status.Nodes[0].CompletionTime)
+}
+
+func TestMaintenanceAborted(t *testing.T) {
+	handler, _ := newHandler(newReadyNode("node1", nil), newReadyNode("node2", nil))
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}})
+	campaign.Status = harvesterv1.NodeMaintenanceCampaignStatus{
+		Phase: harvesterv1.NodeMaintenanceCampaignPhaseRunning,
+		Nodes: []harvesterv1.NodeMaintenanceStatus{{Name: "node1", State: harvesterv1.NodeMaintenanceStateEntering}},
+	}
+
+	// the node drain controller removed the annotations as the node can't be drained
+	requeue, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, harvesterv1.NodeMaintenanceStateFailed, campaign.Status.Nodes[0].State)
+	assert.Equal(t, harvesterv1.NodeMaintenanceCampaignPhaseFailed, campaign.Status.Phase)
+}
+
+func TestHookFailureStopsCampaign(t *testing.T) {
+	handler, clientset := newHandler(newReadyNode("node1", nil), newReadyNode("node2", nil))
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{
+		Nodes:   []string{"node1", "node2"},
+		PreHook: &harvesterv1.NodeMaintenanceHook{Image: "firmware-updater", Command: []string{"update"}},
+	})
+	campaign.Status = harvesterv1.NodeMaintenanceCampaignStatus{
+		Phase: harvesterv1.NodeMaintenanceCampaignPhaseRunning,
+		Nodes: []harvesterv1.NodeMaintenanceStatus{
+			{Name: "node1", State: harvesterv1.NodeMaintenanceStatePreHook},
+			{Name: "node2", State: harvesterv1.NodeMaintenanceStatePending},
+		},
+	}
+
+	_, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, harvesterv1.NodeMaintenanceStatePreHook, campaign.Status.Nodes[0].State)
+	job, err := clientset.BatchV1().Jobs(testNamespace).Get(context.TODO(), "firmware-node1-pre", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "firmware-updater", job.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, []corev1.EnvVar{{Name: "NODE_NAME", Value: "node1"}}, job.Spec.Template.Spec.Containers[0].Env)
+	assert.Equal(t, int64(defaultHookTimeout.Seconds()), *job.Spec.ActiveDeadlineSeconds)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	_, err = clientset.BatchV1().Jobs(testNamespace).UpdateStatus(context.TODO(), job, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	requeue, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, []harvesterv1.NodeMaintenanceState{harvesterv1.NodeMaintenanceStateFailed, harvesterv1.NodeMaintenanceStatePending}, nodeStates(campaign))
+	assert.Equal(t, harvesterv1.NodeMaintenanceCampaignPhaseFailed, campaign.Status.Phase)
+	// the node isn't put into maintenance mode
+	assert.NotContains(t, getNode(t, clientset, "node1").Annotations, drainhelper.DrainAnnotation)
+}
+
+func TestHookJobHostAccess(t *testing.T) {
+	handler, _ := newHandler()
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}})
+
+	job := handler.newHookJob(campaign, "node1", &harvesterv1.NodeMaintenanceHook{Image: "check"}, "firmware-node1-pre")
+	podSpec := job.Spec.Template.Spec
+	assert.False(t, podSpec.HostPID)
+	assert.Empty(t, podSpec.Volumes)
+	assert.False(t, *podSpec.Containers[0].SecurityContext.Privileged)
+	assert.False(t, *podSpec.Containers[0].SecurityContext.AllowPrivilegeEscalation)
+
+	job = handler.newHookJob(campaign, "node1", &harvesterv1.NodeMaintenanceHook{Image: "firmware-updater", HostAccess: true}, "firmware-node1-pre")
+	podSpec = job.Spec.Template.Spec
+	assert.True(t, podSpec.HostPID)
+	assert.Equal(t, "/", podSpec.Volumes[0].HostPath.Path)
+	assert.True(t, *podSpec.Containers[0].SecurityContext.Privileged)
+	assert.Equal(t, []corev1.VolumeMount{{Name: hostRootVolume, MountPath: hostRootMountPath}}, podSpec.Containers[0].VolumeMounts)
+}
+
+func TestNodeAlreadyInMaintenance(t *testing.T) {
+	inMaintenance := newReadyNode("node2", nil)
+	inMaintenance.Spec.Unschedulable = true
+	inMaintenance.Annotations = map[string]string{ctlnode.MaintainStatusAnnotationKey: ctlnode.MaintainStatusComplete}
+	handler, clientset := newHandler(newReadyNode("node1", nil), inMaintenance)
+
+	// the campaign doesn't start if one of its nodes is in maintenance mode
+	campaign := newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1", "node2"}})
+	requeue, err := handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, harvesterv1.NodeMaintenanceCampaignPhaseFailed, campaign.Status.Phase)
+	assert.Contains(t, campaign.Status.Message, "node2")
+	assert.Empty(t, campaign.Status.Nodes)
+
+	// a node put into maintenance mode after the campaign started fails and stays in maintenance mode
+	campaign = newCampaign(harvesterv1.NodeMaintenanceCampaignSpec{Nodes: []string{"node2"}})
+	campaign.Status = harvesterv1.NodeMaintenanceCampaignStatus{
+		Phase: harvesterv1.NodeMaintenanceCampaignPhaseRunning,
+		Nodes: []harvesterv1.NodeMaintenanceStatus{{Name: "node2", State: harvesterv1.NodeMaintenanceStatePreHook}},
+	}
+	_, err = handler.reconcile(campaign)
+	assert.NoError(t, err)
+	assert.Equal(t, harvesterv1.NodeMaintenanceStateFailed, campaign.Status.Nodes[0].State)
+	node2 := getNode(t, clientset, "node2")
+	assert.True(t, node2.Spec.Unschedulable)
+	assert.Equal(t, ctlnode.MaintainStatusComplete, node2.Annotations[ctlnode.MaintainStatusAnnotationKey])
+}
@@ -0,0 +1,44 @@
+package maintenancecampaign
+
+import (
+	"context"
+
+	"github.com/harvester/harvester/pkg/config"
+	"github.com/harvester/harvester/pkg/controller/master/nodedrain"
+)
+
+const (
+	campaignControllerName    = "node-maintenance-campaign-controller"
+	campaignJobControllerName = "node-maintenance-campaign-job-controller"
+)
+
+func Register(ctx context.Context, management *config.Management, options config.Options) error {
+	campaigns := management.HarvesterFactory.Harvesterhci().V1beta1().NodeMaintenanceCampaign()
+	nodes := management.CoreFactory.Core().V1().Node()
+	jobs := management.BatchFactory.Batch().V1().Job()
+	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	vmis := management.VirtFactory.Kubevirt().V1().VirtualMachineInstance()
+	vmGroups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup()
+	vmPlacementPolicies := management.HarvesterFactory.Harvesterhci().V1beta1().VMPlacementPolicy()
+	volumes := management.LonghornFactory.Longhorn().V1beta2().Volume()
+	replicas := management.LonghornFactory.Longhorn().V1beta2().Replica()
+
+	handler := &campaignHandler{
+		campaignController: campaigns,
+		campaignClient:     campaigns,
+		nodeClient:         nodes,
+		nodeCache:          nodes.Cache(),
+		jobClient:          jobs,
+		jobCache:           jobs.Cache(),
+		vmClient:           vms,
+		vmCache:            vms.Cache(),
+		vmGroupCache:       vmGroups.Cache(),
+		drainHelper: nodedrain.ActionHelper(nodes.Cache(), vmis.Cache(), volumes.Cache(), replicas.Cache(),
+			vmGroups.Cache(), vmPlacementPolicies.Cache()),
+		namespace: options.Namespace,
+	}
+
+	campaigns.OnChange(ctx, campaignControllerName, handler.OnChanged)
+	jobs.OnChange(ctx, campaignJobControllerName, handler.OnJobChanged)
+	return nil
+}
@@ -11,9 +11,11 @@ import (
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	"github.com/harvester/harvester/pkg/config"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	v1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
 	"github.com/harvester/harvester/pkg/util"
 	"github.com/harvester/harvester/pkg/util/virtualmachineinstance"
+	"github.com/harvester/harvester/pkg/util/vmgroup"
 )
 
 const (
@@ -31,6 +33,7 @@ type maintainNodeHandler struct {
 	virtualMachineClient        v1.VirtualMachineClient
 	virtualMachineCache         v1.VirtualMachineCache
 	virtualMachineInstanceCache v1.VirtualMachineInstanceCache
+	vmGroupCache                ctlharvesterv1.VirtualMachineGroupCache
 }
 
 // MaintainRegister registers the node controller
@@ -38,12 +41,14 @@ func MaintainRegister(ctx context.Context, management *config.Management, _ conf
 	nodes := management.CoreFactory.Core().V1().Node()
 	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
 	vmis := management.VirtFactory.Kubevirt().V1().VirtualMachineInstance()
+	vmGroups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup()
 	maintainNodeHandler := &maintainNodeHandler{
 		nodes:                       nodes,
 		nodeCache:                   nodes.Cache(),
 		virtualMachineClient:        vms,
 		virtualMachineCache:         vms.Cache(),
 		virtualMachineInstanceCache: vmis.Cache(),
+		vmGroupCache:                vmGroups.Cache(),
 	}
 
 	nodes.OnChange(ctx, maintainNodeControllerName, maintainNodeHandler.OnNodeChanged)
@@ -73,15 +78,39 @@ func (h *maintainNodeHandler) OnNodeChanged(_ string, node *corev1.Node) (*corev
 	// Restart those VMs that have been labeled to be shut down before
 	// maintenance mode and that should be restarted when the node has
 	// successfully switched into maintenance mode.
-	selector := labels.Set{util.LabelMaintainModeStrategy: util.MaintainModeStrategyShutdownAndRestartAfterEnable}.AsSelector()
-	vmList, err := h.virtualMachineCache.List(node.Namespace, selector)
+	if err := RestartVMs(node.Name, util.MaintainModeStrategyShutdownAndRestartAfterEnable, h.virtualMachineClient, h.virtualMachineCache, h.vmGroupCache); err != nil {
+		return node, err
+	}
+
+	toUpdate := node.DeepCopy()
+	toUpdate.Annotations[MaintainStatusAnnotationKey] = MaintainStatusComplete
+	return h.nodes.Update(toUpdate)
+}
+
+// RestartVMs restarts the VMs labeled with the maintain mode strategy which were shut down for the
+// maintenance mode of the node.
+func RestartVMs(nodeName, strategy string, vmClient v1.VirtualMachineClient, vmCache v1.VirtualMachineCache, vmGroupCache ctlharvesterv1.VirtualMachineGroupCache) error {
+	selector := labels.Set{util.LabelMaintainModeStrategy: strategy}.AsSelector()
+	vmList, err := vmCache.List(corev1.NamespaceAll, selector)
 	if err != nil {
-		return node, fmt.Errorf("failed to list VMs with labels %s: %w", selector.String(), err)
+		return fmt.Errorf("failed to list VMs with labels %s: %w", selector.String(), err)
 	}
 	for _, vm := range vmList {
 		// Make sure that this VM was shut down as part of the maintenance
 		// mode of the given node.
-		if vm.Annotations[util.AnnotationMaintainModeStrategyNodeName] != node.Name {
+		if vm.Annotations[util.AnnotationMaintainModeStrategyNodeName] != nodeName {
+			continue
+		}
+
+		// The members of a VM group are started by the group in the
+		// order of their boot order.
+		memberCopy := vm.DeepCopy()
+		delete(memberCopy.Annotations, util.AnnotationMaintainModeStrategyNodeName)
+		started, err := vmgroup.StartWithGroup(vmGroupCache, vmClient, memberCopy)
+		if err != nil {
+			return err
+		}
+		if started {
 			continue
 		}
 
@@ -103,15 +132,12 @@ func (h *maintainNodeHandler) OnNodeChanged(_ string, node *corev1.Node) (*corev
 		vmCopy := vm.DeepCopy()
 		vmCopy.Spec.RunStrategy = &[]kubevirtv1.VirtualMachineRunStrategy{runStrategy}[0]
 		delete(vmCopy.Annotations, util.AnnotationMaintainModeStrategyNodeName)
-		_, err = h.virtualMachineClient.Update(vmCopy)
+		_, err = vmClient.Update(vmCopy)
 		if err != nil {
-			return node, fmt.Errorf("failed to start VM %s/%s: %w", vm.Namespace, vm.Name, err)
+			return fmt.Errorf("failed to start VM %s/%s: %w", vm.Namespace, vm.Name, err)
 		}
 	}
-
-	toUpdate := node.DeepCopy()
-	toUpdate.Annotations[MaintainStatusAnnotationKey] = MaintainStatusComplete
-	return h.nodes.Update(toUpdate)
+	return nil
 }
 
 // OnNodeRemoved Ensure that all "harvesterhci.io/maintain-mode-strategy-node-name"
@@ -0,0 +1,226 @@
+package nodedrain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/drainhelper"
+	"github.com/harvester/harvester/pkg/util/vmgroup"
+)
+
+type MaintenancePlanOutcome string
+
+const (
+	// MaintenancePlanMigrate means the VM is live migrated to another node by the drain
+	MaintenancePlanMigrate MaintenancePlanOutcome = "Migrate"
+	// MaintenancePlanShutdown means the VM is shut down before the drain
+	MaintenancePlanShutdown MaintenancePlanOutcome = "Shutdown"
+	// MaintenancePlanBlocked means the VM blocks enabling maintenance mode unless it is forced
+	MaintenancePlanBlocked MaintenancePlanOutcome = "Blocked"
+)
+
+// MaintenancePlan is what enabling maintenance mode on a node would do, without doing it
+type MaintenancePlan struct {
+	Node   string `json:"node"`
+	Forced bool   `json:"forced"`
+	// Possible is false if the node can't be drained at all, e.g. it is the last node of the cluster
+	Possible bool `json:"possible"`
+	// Blocked is true if enabling maintenance mode is rejected because of the blocked VMs
+	Blocked bool                    `json:"blocked"`
+	Message string                  `json:"message,omitempty"`
+	VMs     []MaintenancePlanVM     `json:"vms"`
+	Volumes []MaintenancePlanVolume `json:"volumes"`
+}
+
+type MaintenancePlanVM struct {
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Outcome   MaintenancePlanOutcome `json:"outcome"`
+	// Reasons are the conditions which prevent the VM from being migrated
+	Reasons []string `json:"reasons,omitempty"`
+	// TargetNode is the node the VM is likely migrated to
+	TargetNode string `json:"targetNode,omitempty"`
+	// MaintainModeStrategy is the value of the harvesterhci.io/maintain-mode-strategy label of the VM
+	MaintainModeStrategy string `json:"maintainModeStrategy,omitempty"`
+	// StopStage is the stage the VM is shut down in, the members of a VM group are shut down
+	// in the reverse order of their boot order
+	StopStage int `json:"stopStage,omitempty"`
+}
+
+// MaintenancePlanVolume is a Longhorn volume whose last healthy replica is on the node
+type MaintenancePlanVolume struct {
+	Name         string   `json:"name"`
+	PVCNamespace string   `json:"pvcNamespace,omitempty"`
+	PVCName      string   `json:"pvcName,omitempty"`
+	VMs          []string `json:"vms,omitempty"`
+}
+
+// MaintenancePlan returns which VMs would be migrated, shut down or block enabling maintenance mode on
+// the node, following the same decisions as OnNodeChange. The target nodes are an estimate, the node
+// with the fewest VMs out of the nodes the VM can be scheduled on is picked.
+func (ndc *ControllerHandler) MaintenancePlan(node *corev1.Node, forced bool) (*MaintenancePlan, error) {
+	plan := &MaintenancePlan{
+		Node:     node.Name,
+		Forced:   forced,
+		Possible: true,
+		VMs:      []MaintenancePlanVM{},
+		Volumes:  []MaintenancePlanVolume{},
+	}
+	if err := drainhelper.DrainPossible(ndc.nodeCache, node); err != nil {
+		plan.Possible = false
+		plan.Message = err.Error()
+	}
+
+	volumes, err := ndc.listVolumeNames(node)
+	if err != nil {
+		return nil, fmt.Errorf("error in listVolumeNames: %v", err)
+	}
+	for _, volume := range volumes {
+		planVolume := MaintenancePlanVolume{
+			Name:         volume.Name,
+			PVCNamespace: volume.Status.KubernetesStatus.Namespace,
+			PVCName:      volume.Status.KubernetesStatus.PVCName,
+		}
+		for _, workload := range volume.Status.KubernetesStatus.WorkloadsStatus {
+			if workload.WorkloadType == defaultWorkloadType {
+				planVolume.VMs = append(planVolume.VMs, fmt.Sprintf("%s/%s", volume.Status.KubernetesStatus.Namespace, workload.WorkloadName))
+			}
+		}
+		plan.Volumes = append(plan.Volumes, planVolume)
+	}
+
+	nonMigratableVMs, err := ndc.FindNonMigratableVMS(node)
+	if err != nil {
+		return nil, fmt.Errorf("error getting non-migratable VMs: %w", err)
+	}
+	reasons := make(map[string][]string)
+	conditions := make([]string, 0, len(nonMigratableVMs))
+	for condition := range nonMigratableVMs {
+		conditions = append(conditions, condition)
+	}
+	sort.Strings(conditions)
+	for _, condition := range conditions {
+		for _, vm := range nonMigratableVMs[condition] {
+			reasons[vm] = append(reasons[vm], condition)
+		}
+	}
+
+	shutdownVMs := make(map[string][]string)
+	switch {
+	case forced:
+		shutdownVMs = nonMigratableVMs
+	case len(nonMigratableVMs) > 0:
+		plan.Blocked = true
+		plan.Message = "enabling maintenance mode is impossible without force, as non-migratable VMs are found"
+	default:
+		strategyVMIs, err := ndc.listVMILabelMaintainModeStrategy(node)
+		if err != nil {
+			return nil, fmt.Errorf("error in the listing of VMIs that are to be administratively stopped before migration: %w", err)
+		}
+		for _, vmi := range strategyVMIs {
+			shutdownVMs[util.MaintainModeStrategyKey] = append(shutdownVMs[util.MaintainModeStrategyKey], namespacedVMName(vmi))
+		}
+	}
+
+	stopStages := make(map[string]int)
+	if ndc.vmGroupCache != nil {
+		stages, err := vmgroup.StopStages(ndc.vmGroupCache, getUniqueVMSfromConditionMap(shutdownVMs))
+		if err != nil {
+			return nil, err
+		}
+		for i, stage := range stages {
+			for _, vm := range stage {
+				stopStages[vm] = i
+			}
+		}
+	}
+
+	vmis, err := ndc.virtualMachineInstanceCache.List(corev1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing VMI: %v", err)
+	}
+	vmisPerNode := make(map[string]int)
+	var nodeVMIs []*kubevirtv1.VirtualMachineInstance
+	for _, vmi := range vmis {
+		vmisPerNode[vmi.Labels[util.LabelNodeNameKey]]++
+		if vmi.Labels[util.LabelNodeNameKey] == node.Name {
+			nodeVMIs = append(nodeVMIs, vmi)
+		}
+	}
+	sort.Slice(nodeVMIs, func(i, j int) bool {
+		return namespacedVMName(nodeVMIs[i]) < namespacedVMName(nodeVMIs[j])
+	})
+
+	validNodes, err := ndc.listValidNodes(node)
+	if err != nil {
+		return nil, err
+	}
+	allShutdownVMs := getUniqueVMSfromConditionMap(shutdownVMs)
+	planned := make(map[string]bool)
+	for _, vmi := range nodeVMIs {
+		name := namespacedVMName(vmi)
+		planned[name] = true
+		planVM := MaintenancePlanVM{
+			Namespace:            vmi.Namespace,
+			Name:                 vmi.Name,
+			Reasons:              reasons[name],
+			MaintainModeStrategy: vmi.Labels[util.LabelMaintainModeStrategy],
+		}
+
+		switch {
+		case slices.Contains(allShutdownVMs, name):
+			planVM.Outcome = MaintenancePlanShutdown
+			planVM.StopStage = stopStages[name]
+		case len(reasons[name]) > 0:
+			planVM.Outcome = MaintenancePlanBlocked
+		default:
+			planVM.Outcome = MaintenancePlanMigrate
+			nodes, err := ndc.findSchedulableNodes(node, validNodes, vmi)
+			if err != nil {
+				return nil, err
+			}
+			if target := leastLoadedNode(nodes, vmisPerNode); target != "" {
+				planVM.TargetNode = target
+				vmisPerNode[target]++
+			}
+		}
+		plan.VMs = append(plan.VMs, planVM)
+	}
+
+	// the VMs on other nodes whose last healthy replica is on the node
+	for _, name := range getUniqueVMSfromConditionMap(nonMigratableVMs) {
+		if planned[name] {
+			continue
+		}
+		namespace, vmName := splitNamespacedName(name)
+		planVM := MaintenancePlanVM{
+			Namespace: namespace,
+			Name:      vmName,
+			Outcome:   MaintenancePlanBlocked,
+			Reasons:   reasons[name],
+		}
+		if forced {
+			planVM.Outcome = MaintenancePlanShutdown
+			planVM.StopStage = stopStages[name]
+		}
+		plan.VMs = append(plan.VMs, planVM)
+	}
+
+	return plan, nil
+}
+
+func leastLoadedNode(nodes []*corev1.Node, vmisPerNode map[string]int) string {
+	target := ""
+	for _, node := range nodes {
+		if target == "" || vmisPerNode[node.Name] < vmisPerNode[target] {
+			target = node.Name
+		}
+	}
+	return target
+}
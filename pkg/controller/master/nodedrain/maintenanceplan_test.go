@@ -0,0 +1,82 @@
+package nodedrain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newVMIOnNode(name, nodeName string) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{util.LabelNodeNameKey: nodeName},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{NodeName: nodeName},
+	}
+}
+
+func Test_MaintenancePlan(t *testing.T) {
+	migratable := newVMIOnNode("migratable", "node1")
+	shutdown := newVMIOnNode("shutdown", "node1")
+	shutdown.Labels[util.LabelMaintainModeStrategy] = util.MaintainModeStrategyShutdown
+	nonMigratable := newVMIOnNode("non-migratable", "node1")
+	nonMigratable.Status.Conditions = []kubevirtv1.VirtualMachineInstanceCondition{
+		{Type: kubevirtv1.VirtualMachineInstanceIsMigratable, Status: corev1.ConditionFalse, Reason: kubevirtv1.VirtualMachineInstanceReasonDisksNotMigratable},
+	}
+
+	clientset := fake.NewSimpleClientset(
+		newReadyNode("node1"), newReadyNode("node2"), newReadyNode("node3"),
+		migratable, shutdown, newVMIOnNode("other", "node2"),
+	)
+	ndc := &ControllerHandler{
+		nodeCache:                   fakeclients.NodeCache(clientset.CoreV1().Nodes),
+		virtualMachineInstanceCache: fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+		longhornReplicaCache:        fakeclients.LonghornReplicaCache(clientset.LonghornV1beta2().Replicas),
+		longhornVolumeCache:         fakeclients.LonghornVolumeCache(clientset.LonghornV1beta2().Volumes),
+	}
+	node1, err := ndc.nodeCache.Get("node1")
+	assert.NoError(t, err)
+
+	plan, err := ndc.MaintenancePlan(node1, false)
+	assert.NoError(t, err)
+	assert.True(t, plan.Possible)
+	assert.False(t, plan.Blocked)
+	assert.Equal(t, []MaintenancePlanVM{
+		// node3 runs fewer VMs than node2
+		{Namespace: "default", Name: "migratable", Outcome: MaintenancePlanMigrate, TargetNode: "node3"},
+		{Namespace: "default", Name: "shutdown", Outcome: MaintenancePlanShutdown, MaintainModeStrategy: util.MaintainModeStrategyShutdown},
+	}, plan.VMs)
+
+	// a non-migratable VM blocks enabling maintenance mode unless it is forced
+	_, err = clientset.KubevirtV1().VirtualMachineInstances("default").Create(context.TODO(), nonMigratable, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	plan, err = ndc.MaintenancePlan(node1, false)
+	assert.NoError(t, err)
+	assert.True(t, plan.Blocked)
+	assert.Contains(t, plan.VMs, MaintenancePlanVM{
+		Namespace: "default",
+		Name:      "non-migratable",
+		Outcome:   MaintenancePlanBlocked,
+		Reasons:   []string{kubevirtv1.VirtualMachineInstanceReasonDisksNotMigratable},
+	})
+
+	plan, err = ndc.MaintenancePlan(node1, true)
+	assert.NoError(t, err)
+	assert.False(t, plan.Blocked)
+	assert.Contains(t, plan.VMs, MaintenancePlanVM{
+		Namespace: "default",
+		Name:      "non-migratable",
+		Outcome:   MaintenancePlanShutdown,
+		Reasons:   []string{kubevirtv1.VirtualMachineInstanceReasonDisksNotMigratable},
+	})
+}
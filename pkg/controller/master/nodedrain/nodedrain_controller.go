@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
@@ -20,16 +21,20 @@ import (
 
 	"github.com/harvester/harvester/pkg/config"
 	ctlnode "github.com/harvester/harvester/pkg/controller/master/node"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
 	ctllhv1 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
 	"github.com/harvester/harvester/pkg/util"
 	"github.com/harvester/harvester/pkg/util/drainhelper"
 	"github.com/harvester/harvester/pkg/util/virtualmachineinstance"
+	"github.com/harvester/harvester/pkg/util/vmgroup"
 )
 
 const (
 	nodeDrainController = "node-drain-controller"
 	defaultWorkloadType = "VirtualMachineInstance"
+	// how often the VMs of a stage are checked to be stopped before the next stage is stopped
+	stopStagePollInterval = 5 * time.Second
 )
 
 // ControllerHandler to drain nodes.
@@ -38,6 +43,7 @@ const (
 // part of the drain process
 type ControllerHandler struct {
 	nodes                        ctlcorev1.NodeClient
+	nodeController               ctlcorev1.NodeController
 	nodeCache                    ctlcorev1.NodeCache
 	virtualMachineInstanceCache  ctlkubevirtv1.VirtualMachineInstanceCache
 	virtualMachineInstanceClient ctlkubevirtv1.VirtualMachineInstanceClient
@@ -45,6 +51,8 @@ type ControllerHandler struct {
 	virtualMachineCache          ctlkubevirtv1.VirtualMachineCache
 	longhornVolumeCache          ctllhv1.VolumeCache
 	longhornReplicaCache         ctllhv1.ReplicaCache
+	vmGroupCache                 ctlharvesterv1.VirtualMachineGroupCache
+	vmPlacementPolicyCache       ctlharvesterv1.VMPlacementPolicyCache
 	restConfig                   *rest.Config
 	context                      context.Context
 }
@@ -55,8 +63,11 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
 	lhv := management.LonghornFactory.Longhorn().V1beta2().Volume()
 	lhr := management.LonghornFactory.Longhorn().V1beta2().Replica()
+	vmGroups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup()
+	vmPlacementPolicies := management.HarvesterFactory.Harvesterhci().V1beta1().VMPlacementPolicy()
 	ndc := &ControllerHandler{
 		nodes:                        nodes,
+		nodeController:               nodes,
 		nodeCache:                    nodes.Cache(),
 		virtualMachineInstanceCache:  vmis.Cache(),
 		virtualMachineInstanceClient: vmis,
@@ -64,6 +75,8 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 		virtualMachineCache:          vms.Cache(),
 		longhornReplicaCache:         lhr.Cache(),
 		longhornVolumeCache:          lhv.Cache(),
+		vmGroupCache:                 vmGroups.Cache(),
+		vmPlacementPolicyCache:       vmPlacementPolicies.Cache(),
 		restConfig:                   management.RestConfig,
 		context:                      ctx,
 	}
@@ -199,22 +212,22 @@ func (ndc *ControllerHandler) OnNodeChange(_ string, node *corev1.Node) (*corev1
 		shutdownVMs = nonMigratableVMs
 	}
 
-	for _, v := range getUniqueVMSfromConditionMap(shutdownVMs) {
-		// Fetch VMI again in case it has been modified.
-		err := ndc.findAndStopVM(v)
+	// The members of a VM group are stopped in the reverse order of their
+	// boot order, a stage is only stopped once the VMs of the previous
+	// stage are gone.
+	stages, err := vmgroup.StopStages(ndc.vmGroupCache, getUniqueVMSfromConditionMap(shutdownVMs))
+	if err != nil {
+		return node, err
+	}
+	for i, stage := range stages {
+		stopping, err := ndc.stopVMs(node, stage)
 		if err != nil {
-			if apierrors.IsNotFound(err) {
-				continue
-			}
 			return node, err
 		}
-
-		ns, name := splitNamespacedName(v)
-		logrus.WithFields(logrus.Fields{
-			"node_name":           node.Name,
-			"namespace":           ns,
-			"virtualmachine_name": name,
-		}).Info("force stopping VM")
+		if stopping && i < len(stages)-1 {
+			ndc.nodeController.EnqueueAfter(node.Name, stopStagePollInterval)
+			return node, nil
+		}
 	}
 
 	nodeCopy := node.DeepCopy()
@@ -232,6 +245,35 @@ func (ndc *ControllerHandler) OnNodeChange(_ string, node *corev1.Node) (*corev1
 	return ndc.nodes.Update(nodeCopy)
 }
 
+// stopVMs stops the VMs and returns whether any of them is still running.
+func (ndc *ControllerHandler) stopVMs(node *corev1.Node, vms []string) (bool, error) {
+	stopping := false
+	for _, v := range vms {
+		// Fetch VMI again in case it has been modified.
+		err := ndc.findAndStopVM(v)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+
+		ns, name := splitNamespacedName(v)
+		logrus.WithFields(logrus.Fields{
+			"node_name":           node.Name,
+			"namespace":           ns,
+			"virtualmachine_name": name,
+		}).Info("force stopping VM")
+
+		if _, err := ndc.virtualMachineInstanceCache.Get(ns, name); err == nil {
+			stopping = true
+		} else if !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return stopping, nil
+}
+
 // findAndStopVM is a wrapper function to identify the owner VM for a VMI, and patch the run strategy
 func (ndc *ControllerHandler) findAndStopVM(vmiName string) error {
 	ns, name := splitNamespacedName(vmiName)
@@ -398,12 +440,15 @@ func (ndc *ControllerHandler) FindNonMigratableVMS(node *corev1.Node) (map[strin
 }
 
 func ActionHelper(nodeCache ctlcorev1.NodeCache, virtualMachineInstanceCache ctlkubevirtv1.VirtualMachineInstanceCache,
-	longhornVolumeCache ctllhv1.VolumeCache, longhornReplicaCache ctllhv1.ReplicaCache) *ControllerHandler {
+	longhornVolumeCache ctllhv1.VolumeCache, longhornReplicaCache ctllhv1.ReplicaCache,
+	vmGroupCache ctlharvesterv1.VirtualMachineGroupCache, vmPlacementPolicyCache ctlharvesterv1.VMPlacementPolicyCache) *ControllerHandler {
 	return &ControllerHandler{
 		nodeCache:                   nodeCache,
 		virtualMachineInstanceCache: virtualMachineInstanceCache,
 		longhornVolumeCache:         longhornVolumeCache,
 		longhornReplicaCache:        longhornReplicaCache,
+		vmGroupCache:                vmGroupCache,
+		vmPlacementPolicyCache:      vmPlacementPolicyCache,
 	}
 }
 
@@ -436,8 +481,28 @@ func splitNamespacedName(namespacedName string) (string, string) {
 // the function will check additional nodes that
 // * are able to satisfy the NodeSelectors terms specified in RequiredDuringSchedulingIgnoredDuringExecution
 // * and node is ready
+// * without violating the hard placement policy the VMI is a member of
 func (ndc *ControllerHandler) CheckVMISchedulingRequirements(originalNode *corev1.Node, vmiList []*kubevirtv1.VirtualMachineInstance) ([]string, error) {
 	var impactedVMS []string
+	validNodes, err := ndc.listValidNodes(originalNode)
+	if err != nil {
+		return nil, err
+	}
+	for _, vmi := range vmiList {
+		matchingNodes, err := ndc.findSchedulableNodes(originalNode, validNodes, vmi)
+		if err != nil {
+			return nil, err
+		}
+		// no valid node found that could meet the requirements
+		if len(matchingNodes) == 0 {
+			impactedVMS = append(impactedVMS, namespacedVMName(vmi))
+		}
+	}
+	return impactedVMS, nil
+}
+
+// listValidNodes lists the ready nodes other than the original node
+func (ndc *ControllerHandler) listValidNodes(originalNode *corev1.Node) ([]*corev1.Node, error) {
 	nodeList, err := ndc.nodeCache.List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("error listing nodes from nodeCache: %v", err)
@@ -448,31 +513,37 @@ func (ndc *ControllerHandler) CheckVMISchedulingRequirements(originalNode *corev
 			validNodes = append(validNodes, v)
 		}
 	}
-	for _, vmi := range vmiList {
-		var possibleNodes, matchingNodes []*corev1.Node
-		if vmi.Spec.Affinity != nil && vmi.Spec.Affinity.NodeAffinity != nil && vmi.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
-			nodeAffinitySelector, err := nodeaffinity.NewNodeSelector(vmi.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
-			if err != nil {
-				return nil, fmt.Errorf("error generating nodeAffinitySelector from node scheduling requirements: %v", err)
-			}
-			// identify if nodeAffinity can be met by other nodes and node is ready
-			for _, v := range validNodes {
-				if nodeAffinitySelector.Match(v) {
-					possibleNodes = append(possibleNodes, v)
-				}
-			}
-		} else {
-			possibleNodes = validNodes
+	return validNodes, nil
+}
+
+// findSchedulableNodes filters the valid nodes for the nodes the VMI can be migrated to
+func (ndc *ControllerHandler) findSchedulableNodes(originalNode *corev1.Node, validNodes []*corev1.Node, vmi *kubevirtv1.VirtualMachineInstance) ([]*corev1.Node, error) {
+	var possibleNodes []*corev1.Node
+	if vmi.Spec.Affinity != nil && vmi.Spec.Affinity.NodeAffinity != nil && vmi.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		nodeAffinitySelector, err := nodeaffinity.NewNodeSelector(vmi.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+		if err != nil {
+			return nil, fmt.Errorf("error generating nodeAffinitySelector from node scheduling requirements: %v", err)
 		}
-		// for VM's using masquerade network no additional network specific affinity rules are added
-		// as a result this check is skipped
-		matchingNodes = filterNodesForNodeSelector(possibleNodes, vmi)
-		// no valid node found that could meet the requirements
-		if len(matchingNodes) == 0 {
-			impactedVMS = append(impactedVMS, namespacedVMName(vmi))
+		// identify if nodeAffinity can be met by other nodes and node is ready
+		for _, v := range validNodes {
+			if nodeAffinitySelector.Match(v) {
+				possibleNodes = append(possibleNodes, v)
+			}
 		}
+	} else {
+		possibleNodes = validNodes
 	}
-	return impactedVMS, nil
+	// for VM's using masquerade network no additional network specific affinity rules are added
+	// as a result this check is skipped
+	matchingNodes := filterNodesForNodeSelector(possibleNodes, vmi)
+	if len(matchingNodes) == 0 {
+		return nil, nil
+	}
+	matchingNodes, err := ndc.filterNodesForPlacementPolicy(originalNode, vmi, matchingNodes)
+	if err != nil {
+		return nil, fmt.Errorf("error checking placement policy of vmi %s: %w", namespacedVMName(vmi), err)
+	}
+	return matchingNodes, nil
 }
 
 // filterNodesForNodeSelector will filter nodes for vmi node selector requirement match
@@ -0,0 +1,91 @@
+package nodedrain
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/placementpolicy"
+)
+
+// filterNodesForPlacementPolicy filters the nodes for the nodes the VMI can be migrated to without violating
+// the hard placement policy it is a member of. The other members running on the original node are ignored,
+// as they are migrated away as well.
+func (ndc *ControllerHandler) filterNodesForPlacementPolicy(originalNode *corev1.Node, vmi *kubevirtv1.VirtualMachineInstance, nodes []*corev1.Node) ([]*corev1.Node, error) {
+	name := vmi.Labels[util.LabelVMPlacementPolicy]
+	if name == "" || ndc.vmPlacementPolicyCache == nil {
+		return nodes, nil
+	}
+	policy, err := ndc.vmPlacementPolicyCache.Get(vmi.Namespace, name)
+	if apierrors.IsNotFound(err) {
+		return nodes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !placementpolicy.IsHard(policy) {
+		return nodes, nil
+	}
+
+	members, err := ndc.virtualMachineInstanceCache.List(vmi.Namespace, labels.SelectorFromSet(labels.Set{util.LabelVMPlacementPolicy: name}))
+	if err != nil {
+		return nil, err
+	}
+
+	// the number of the other members in each topology domain
+	topologyKey := placementpolicy.TopologyKey(policy)
+	domains := make(map[string]int)
+	for _, member := range members {
+		if member.Name == vmi.Name || member.Status.NodeName == "" || member.Status.NodeName == originalNode.Name {
+			continue
+		}
+		node, err := ndc.nodeCache.Get(member.Status.NodeName)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := node.Labels[topologyKey]; ok {
+			domains[value]++
+		}
+	}
+
+	var result []*corev1.Node
+	switch policy.Spec.Type {
+	case harvesterv1.VMPlacementPolicyAntiAffinity:
+		for _, node := range nodes {
+			if value, ok := node.Labels[topologyKey]; !ok || domains[value] == 0 {
+				result = append(result, node)
+			}
+		}
+	case harvesterv1.VMPlacementPolicyAffinity:
+		// the first member is placed anywhere
+		if len(domains) == 0 {
+			return nodes, nil
+		}
+		for _, node := range nodes {
+			if value, ok := node.Labels[topologyKey]; ok && domains[value] > 0 {
+				result = append(result, node)
+			}
+		}
+	case harvesterv1.VMPlacementPolicySpread:
+		minMembers := -1
+		for _, node := range nodes {
+			if value, ok := node.Labels[topologyKey]; ok && (minMembers < 0 || domains[value] < minMembers) {
+				minMembers = domains[value]
+			}
+		}
+		for _, node := range nodes {
+			if value, ok := node.Labels[topologyKey]; ok && domains[value]+1-minMembers <= int(placementpolicy.MaxSkew(policy)) {
+				result = append(result, node)
+			}
+		}
+	default:
+		return nodes, nil
+	}
+	return result, nil
+}
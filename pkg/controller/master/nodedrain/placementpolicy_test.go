@@ -0,0 +1,92 @@
+package nodedrain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newReadyNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{corev1.LabelHostname: name},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func newMemberVMI(name, nodeName string) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{util.LabelVMPlacementPolicy: "apart"},
+		},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{NodeName: nodeName},
+	}
+}
+
+func Test_CheckVMISchedulingRequirementsPlacementPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		enforcement harvesterv1.VMPlacementPolicyEnforcement
+		objects     []runtime.Object
+		expected    []string
+	}{
+		{
+			name:        "every other node runs a member of a hard anti-affinity policy",
+			enforcement: harvesterv1.VMPlacementPolicyHard,
+			expected:    []string{"default/vm1"},
+		},
+		{
+			name:        "soft policy is ignored",
+			enforcement: harvesterv1.VMPlacementPolicySoft,
+		},
+		{
+			name:        "a node without members is left",
+			enforcement: harvesterv1.VMPlacementPolicyHard,
+			objects:     []runtime.Object{newReadyNode("node4")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			vmi := newMemberVMI("vm1", "node1")
+			objects := append([]runtime.Object{
+				newReadyNode("node1"), newReadyNode("node2"), newReadyNode("node3"),
+				vmi, newMemberVMI("vm2", "node2"), newMemberVMI("vm3", "node3"),
+				&harvesterv1.VMPlacementPolicy{
+					ObjectMeta: metav1.ObjectMeta{Name: "apart", Namespace: "default"},
+					Spec: harvesterv1.VMPlacementPolicySpec{
+						Type:        harvesterv1.VMPlacementPolicyAntiAffinity,
+						Enforcement: tc.enforcement,
+					},
+				},
+			}, tc.objects...)
+			clientset := fake.NewSimpleClientset(objects...)
+			ndc := &ControllerHandler{
+				nodeCache:                   fakeclients.NodeCache(clientset.CoreV1().Nodes),
+				virtualMachineInstanceCache: fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+				vmPlacementPolicyCache:      fakeclients.VMPlacementPolicyCache(clientset.HarvesterhciV1beta1().VMPlacementPolicies),
+			}
+
+			node1, err := ndc.nodeCache.Get("node1")
+			assert.NoError(t, err)
+			impactedVMs, err := ndc.CheckVMISchedulingRequirements(node1, []*kubevirtv1.VirtualMachineInstance{vmi})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, impactedVMs)
+		})
+	}
+}
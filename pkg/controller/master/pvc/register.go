@@ -7,7 +7,8 @@ import (
 )
 
 const (
-	pvcControllerName = "persistentvolumeclaim-controller"
+	pvcControllerName         = "persistentvolumeclaim-controller"
+	volumeRekeyControllerName = "volume-rekey-controller"
 )
 
 func Register(ctx context.Context, management *config.Management, _ config.Options) error {
@@ -19,5 +20,14 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 	}
 
 	ctlpvc.OnRemove(ctx, pvcControllerName, pvcHandler.cleanupDataVolume)
+
+	vm := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	volumeRekeyHandler := &volumeRekeyHandler{
+		pvcClient: ctlpvc,
+		pvcCache:  ctlpvc.Cache(),
+		vmClient:  vm,
+		vmCache:   vm.Cache(),
+	}
+	dataVolume.OnChange(ctx, volumeRekeyControllerName, volumeRekeyHandler.OnDataVolumeChanged)
 	return nil
 }
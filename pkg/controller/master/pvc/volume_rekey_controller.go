@@ -0,0 +1,197 @@
+package pvc
+
+import (
+	"fmt"
+	"slices"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/util"
+	indexeresutil "github.com/harvester/harvester/pkg/util/indexeres"
+)
+
+// volumeRekeyHandler tracks the DataVolumes copying encrypted volumes into volumes encrypted with a
+// new secret, switches the stopped VMs to the new volumes once the data is copied, and deletes the
+// volumes encrypted with the old secret. The VM webhook keeps the VMs stopped meanwhile.
+type volumeRekeyHandler struct {
+	pvcClient ctlcorev1.PersistentVolumeClaimClient
+	pvcCache  ctlcorev1.PersistentVolumeClaimCache
+	vmClient  ctlkubevirtv1.VirtualMachineClient
+	vmCache   ctlkubevirtv1.VirtualMachineCache
+}
+
+func (h *volumeRekeyHandler) OnDataVolumeChanged(_ string, dv *cdiv1.DataVolume) (*cdiv1.DataVolume, error) {
+	if dv == nil || dv.DeletionTimestamp != nil || dv.Annotations[util.AnnotationVolumeRekeySource] == "" {
+		return dv, nil
+	}
+
+	source, err := h.pvcCache.Get(dv.Namespace, dv.Annotations[util.AnnotationVolumeRekeySource])
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return dv, nil
+		}
+		return dv, err
+	}
+	if source.Annotations[util.AnnotationVolumeRekeyStatus] != util.VolumeRekeyInProgress ||
+		source.Annotations[util.AnnotationVolumeRekeyTarget] != dv.Name {
+		return dv, nil
+	}
+
+	switch dv.Status.Phase {
+	case cdiv1.Succeeded:
+		if err := h.switchVMs(source, dv); err != nil {
+			logrus.WithError(err).Errorf("failed to switch VMs from volume %s/%s to rekeyed volume %s", source.Namespace, source.Name, dv.Name)
+			return dv, h.updateSourceStatus(source, util.VolumeRekeyFailed, string(dv.Status.Progress), err.Error())
+		}
+		if err := h.updateTargetKeyVersion(dv); err != nil {
+			return dv, err
+		}
+		// the source keeps its in progress status until it's gone, so a failed deletion is retried
+		return dv, h.deleteSource(source)
+	case cdiv1.Failed:
+		return dv, h.updateSourceStatus(source, util.VolumeRekeyFailed, string(dv.Status.Progress), getDataVolumeMessage(dv))
+	default:
+		return dv, h.updateSourceStatus(source, util.VolumeRekeyInProgress, string(dv.Status.Progress), "")
+	}
+}
+
+// switchVMs replaces the source volume with the rekeyed volume in the VMs using it, which are stopped
+// while the volume is rekeyed.
+func (h *volumeRekeyHandler) switchVMs(source *corev1.PersistentVolumeClaim, dv *cdiv1.DataVolume) error {
+	vms, err := h.vmCache.GetByIndex(indexeresutil.VMByPVCIndex, ref.Construct(source.Namespace, source.Name))
+	if err != nil {
+		return err
+	}
+
+	for _, vm := range vms {
+		if vm.Status.Created {
+			return fmt.Errorf("VM %s/%s was started while the volume was rekeyed", vm.Namespace, vm.Name)
+		}
+		toUpdate, err := switchVolume(vm, source.Name, dv)
+		if err != nil {
+			return err
+		}
+		if _, err := h.vmClient.Update(toUpdate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func switchVolume(vm *kubevirtv1.VirtualMachine, sourceName string, dv *cdiv1.DataVolume) (*kubevirtv1.VirtualMachine, error) {
+	toUpdate := vm.DeepCopy()
+	if toUpdate.Spec.Template != nil {
+		for i, vol := range toUpdate.Spec.Template.Spec.Volumes {
+			switch {
+			case vol.PersistentVolumeClaim != nil && vol.PersistentVolumeClaim.ClaimName == sourceName:
+				toUpdate.Spec.Template.Spec.Volumes[i].PersistentVolumeClaim.ClaimName = dv.Name
+			case vol.DataVolume != nil && vol.DataVolume.Name == sourceName:
+				toUpdate.Spec.Template.Spec.Volumes[i].VolumeSource = kubevirtv1.VolumeSource{
+					PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+						PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: dv.Name,
+						},
+						Hotpluggable: vol.DataVolume.Hotpluggable,
+					},
+				}
+			}
+		}
+	}
+
+	// KubeVirt recreates the DataVolumes of the templates which are missing
+	toUpdate.Spec.DataVolumeTemplates = slices.DeleteFunc(toUpdate.Spec.DataVolumeTemplates, func(template kubevirtv1.DataVolumeTemplateSpec) bool {
+		return template.Name == sourceName
+	})
+
+	// the VM controller recreates the volumes of the claim templates which are missing
+	volumeClaimTemplatesStr := toUpdate.Annotations[util.AnnotationVolumeClaimTemplates]
+	if volumeClaimTemplatesStr == "" {
+		return toUpdate, nil
+	}
+	entries, err := util.UnmarshalVolumeClaimTemplates(volumeClaimTemplatesStr)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if entry.Name != sourceName {
+			continue
+		}
+		entries[i].Name = dv.Name
+		if dv.Spec.Storage != nil && dv.Spec.Storage.StorageClassName != nil {
+			entries[i].Spec.StorageClassName = dv.Spec.Storage.StorageClassName
+		}
+	}
+	data, err := util.MarshalVolumeClaimTemplates(entries)
+	if err != nil {
+		return nil, err
+	}
+	toUpdate.Annotations[util.AnnotationVolumeClaimTemplates] = data
+	return toUpdate, nil
+}
+
+// deleteSource deletes the volume encrypted with the old secret, the PVC controller removes its
+// DataVolume if it has one.
+func (h *volumeRekeyHandler) deleteSource(source *corev1.PersistentVolumeClaim) error {
+	if source.DeletionTimestamp != nil {
+		return nil
+	}
+	logrus.Infof("deleting volume %s/%s after it was rekeyed", source.Namespace, source.Name)
+	err := h.pvcClient.Delete(source.Namespace, source.Name, &metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (h *volumeRekeyHandler) updateTargetKeyVersion(dv *cdiv1.DataVolume) error {
+	target, err := h.pvcCache.Get(dv.Namespace, dv.Name)
+	if err != nil {
+		return err
+	}
+	keyVersion := dv.Annotations[util.AnnotationEncryptionKeyVersion]
+	if target.Annotations[util.AnnotationEncryptionKeyVersion] == keyVersion {
+		return nil
+	}
+	toUpdate := target.DeepCopy()
+	if toUpdate.Annotations == nil {
+		toUpdate.Annotations = map[string]string{}
+	}
+	toUpdate.Annotations[util.AnnotationEncryptionKeyVersion] = keyVersion
+	_, err = h.pvcClient.Update(toUpdate)
+	return err
+}
+
+func (h *volumeRekeyHandler) updateSourceStatus(source *corev1.PersistentVolumeClaim, status, progress, message string) error {
+	if source.Annotations[util.AnnotationVolumeRekeyStatus] == status &&
+		source.Annotations[util.AnnotationVolumeRekeyProgress] == progress &&
+		source.Annotations[util.AnnotationVolumeRekeyMessage] == message {
+		return nil
+	}
+	toUpdate := source.DeepCopy()
+	toUpdate.Annotations[util.AnnotationVolumeRekeyStatus] = status
+	toUpdate.Annotations[util.AnnotationVolumeRekeyProgress] = progress
+	if message == "" {
+		delete(toUpdate.Annotations, util.AnnotationVolumeRekeyMessage)
+	} else {
+		toUpdate.Annotations[util.AnnotationVolumeRekeyMessage] = message
+	}
+	_, err := h.pvcClient.Update(toUpdate)
+	return err
+}
+
+func getDataVolumeMessage(dv *cdiv1.DataVolume) string {
+	for _, condition := range dv.Status.Conditions {
+		if condition.Type == cdiv1.DataVolumeRunning && condition.Message != "" {
+			return condition.Message
+		}
+	}
+	return fmt.Sprintf("DataVolume %s/%s failed", dv.Namespace, dv.Name)
+}
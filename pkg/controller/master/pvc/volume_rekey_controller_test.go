@@ -0,0 +1,160 @@
+package pvc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestSwitchVolume(t *testing.T) {
+	sourceSC := "encrypted-key-1"
+	targetSC := "encrypted-key-2"
+	templates, err := util.MarshalVolumeClaimTemplates([]util.VolumeClaimTemplateEntry{
+		{PersistentVolumeClaim: corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "disk-0"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &sourceSC},
+		}},
+		{PersistentVolumeClaim: corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "disk-1"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: &sourceSC},
+		}},
+	})
+	require.NoError(t, err)
+
+	vm := &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vm",
+			Namespace:   "default",
+			Annotations: map[string]string{util.AnnotationVolumeClaimTemplates: templates},
+		},
+		Spec: kubevirtv1.VirtualMachineSpec{
+			DataVolumeTemplates: []kubevirtv1.DataVolumeTemplateSpec{
+				{ObjectMeta: metav1.ObjectMeta{Name: "disk-0"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "disk-2"}},
+			},
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Volumes: []kubevirtv1.Volume{
+						{Name: "disk-0", VolumeSource: kubevirtv1.VolumeSource{PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+							PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "disk-0"},
+						}}},
+						{Name: "disk-1", VolumeSource: kubevirtv1.VolumeSource{PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+							PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "disk-1"},
+						}}},
+					},
+				},
+			},
+		},
+	}
+	dv := &cdiv1.DataVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "disk-0-rekeyed", Namespace: "default"},
+		Spec: cdiv1.DataVolumeSpec{
+			Storage: &cdiv1.StorageSpec{StorageClassName: &targetSC},
+		},
+	}
+
+	toUpdate, err := switchVolume(vm, "disk-0", dv)
+	require.NoError(t, err)
+	volumes := toUpdate.Spec.Template.Spec.Volumes
+	assert.Equal(t, "disk-0-rekeyed", volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "disk-1", volumes[1].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "disk-0", vm.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName, "the cached VM is not modified")
+	require.Len(t, toUpdate.Spec.DataVolumeTemplates, 1, "the template of the source DataVolume is removed")
+	assert.Equal(t, "disk-2", toUpdate.Spec.DataVolumeTemplates[0].Name)
+
+	entries, err := util.UnmarshalVolumeClaimTemplates(toUpdate.Annotations[util.AnnotationVolumeClaimTemplates])
+	require.NoError(t, err)
+	assert.Equal(t, "disk-0-rekeyed", entries[0].Name)
+	assert.Equal(t, targetSC, *entries[0].Spec.StorageClassName)
+	assert.Equal(t, "disk-1", entries[1].Name)
+	assert.Equal(t, sourceSC, *entries[1].Spec.StorageClassName)
+}
+
+func TestOnDataVolumeChanged(t *testing.T) {
+	newSource := func() *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "disk-0",
+				Namespace: "default",
+				Annotations: map[string]string{
+					util.AnnotationVolumeRekeyStatus: util.VolumeRekeyInProgress,
+					util.AnnotationVolumeRekeyTarget: "disk-0-rekeyed",
+				},
+			},
+		}
+	}
+	newDataVolume := func(phase cdiv1.DataVolumePhase) *cdiv1.DataVolume {
+		return &cdiv1.DataVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "disk-0-rekeyed",
+				Namespace: "default",
+				Annotations: map[string]string{
+					util.AnnotationVolumeRekeySource:    "disk-0",
+					util.AnnotationEncryptionKeyVersion: "2",
+				},
+			},
+			Status: cdiv1.DataVolumeStatus{Phase: phase},
+		}
+	}
+	target := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "disk-0-rekeyed", Namespace: "default"}}
+
+	var tests = []struct {
+		name          string
+		phase         cdiv1.DataVolumePhase
+		expectDeleted bool
+		expectStatus  string
+	}{
+		{
+			name:         "source is kept while the data is copied",
+			phase:        cdiv1.CloneInProgress,
+			expectStatus: util.VolumeRekeyInProgress,
+		},
+		{
+			name:          "source is deleted once the data is copied",
+			phase:         cdiv1.Succeeded,
+			expectDeleted: true,
+		},
+		{
+			name:         "source is kept if the copy failed",
+			phase:        cdiv1.Failed,
+			expectStatus: util.VolumeRekeyFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newSource(), target)
+			h := &volumeRekeyHandler{
+				pvcClient: fakeclients.PersistentVolumeClaimClient(clientset.CoreV1().PersistentVolumeClaims),
+				pvcCache:  fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims),
+				vmClient:  fakeclients.VirtualMachineClient(clientset.KubevirtV1().VirtualMachines),
+				vmCache:   fakeclients.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+			}
+
+			_, err := h.OnDataVolumeChanged("", newDataVolume(tc.phase))
+			require.NoError(t, err)
+
+			source, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), "disk-0", metav1.GetOptions{})
+			if tc.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err))
+				rekeyed, err := clientset.CoreV1().PersistentVolumeClaims("default").Get(context.TODO(), "disk-0-rekeyed", metav1.GetOptions{})
+				require.NoError(t, err)
+				assert.Equal(t, "2", rekeyed.Annotations[util.AnnotationEncryptionKeyVersion])
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectStatus, source.Annotations[util.AnnotationVolumeRekeyStatus])
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package rebalancer
+
+import (
+	"sort"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+// nodeLoad is the allocatable and requested CPU in millicores and memory in bytes of a node.
+type nodeLoad struct {
+	name              string
+	allocatableCPU    int64
+	allocatableMemory int64
+	requestedCPU      int64
+	requestedMemory   int64
+}
+
+// candidate is a VM which can be live migrated to any of its target nodes.
+type candidate struct {
+	vm      string
+	node    string
+	cpu     int64
+	memory  int64
+	targets []string
+}
+
+type weights struct {
+	cpu    int
+	memory int
+}
+
+func percentage(requested, allocatable int64) float64 {
+	if allocatable <= 0 {
+		return 100
+	}
+	return float64(requested) * 100 / float64(allocatable)
+}
+
+func (w weights) score(load *nodeLoad) float64 {
+	total := w.cpu + w.memory
+	if total == 0 {
+		return 0
+	}
+	cpu := percentage(load.requestedCPU, load.allocatableCPU)
+	memory := percentage(load.requestedMemory, load.allocatableMemory)
+	return (float64(w.cpu)*cpu + float64(w.memory)*memory) / float64(total)
+}
+
+// nodeScores returns the scores of the nodes sorted from the most to the least allocated one.
+func nodeScores(loads map[string]*nodeLoad, w weights) []harvesterv1.RebalanceNodeScore {
+	scores := make([]harvesterv1.RebalanceNodeScore, 0, len(loads))
+	for _, load := range loads {
+		scores = append(scores, harvesterv1.RebalanceNodeScore{
+			Node:   load.name,
+			CPU:    int(percentage(load.requestedCPU, load.allocatableCPU) + 0.5),
+			Memory: int(percentage(load.requestedMemory, load.allocatableMemory) + 0.5),
+			Score:  int(w.score(load) + 0.5),
+		})
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+		return scores[i].Node < scores[j].Node
+	})
+	return scores
+}
+
+// spread returns the difference between the scores of the most and the least allocated nodes.
+func spread(loads map[string]*nodeLoad, w weights) float64 {
+	first := true
+	var highest, lowest float64
+	for _, load := range loads {
+		s := w.score(load)
+		if first || s > highest {
+			highest = s
+		}
+		if first || s < lowest {
+			lowest = s
+		}
+		first = false
+	}
+	return highest - lowest
+}
+
+func fits(load *nodeLoad, c *candidate) bool {
+	return load.requestedCPU+c.cpu <= load.allocatableCPU && load.requestedMemory+c.memory <= load.allocatableMemory
+}
+
+func shift(loads map[string]*nodeLoad, c *candidate, from, to string) {
+	loads[from].requestedCPU -= c.cpu
+	loads[from].requestedMemory -= c.memory
+	loads[to].requestedCPU += c.cpu
+	loads[to].requestedMemory += c.memory
+}
+
+// plan greedily moves VMs away from the most allocated node to the target which reduces the
+// spread of the scores the most, until the spread is within the threshold, no move reduces the
+// spread anymore or maxMoves moves are planned. The loads are updated with the planned moves.
+func plan(loads map[string]*nodeLoad, candidates []candidate, w weights, threshold float64, maxMoves int) []harvesterv1.RebalanceMove {
+	var moves []harvesterv1.RebalanceMove
+	moved := make(map[string]bool)
+	// nodes where no VM can be moved to reduce the spread
+	stuck := make(map[string]bool)
+
+	for len(moves) < maxMoves {
+		current := spread(loads, w)
+		if current <= threshold {
+			break
+		}
+
+		source := hottestNode(loads, w, stuck)
+		if source == "" {
+			break
+		}
+
+		var best *candidate
+		var bestTarget string
+		bestSpread := current
+		for i := range candidates {
+			c := &candidates[i]
+			if c.node != source || moved[c.vm] {
+				continue
+			}
+			for _, target := range c.targets {
+				load, ok := loads[target]
+				if !ok || target == c.node || !fits(load, c) {
+					continue
+				}
+				shift(loads, c, c.node, target)
+				s := spread(loads, w)
+				shift(loads, c, target, c.node)
+				if s < bestSpread {
+					best, bestTarget, bestSpread = c, target, s
+				}
+			}
+		}
+
+		if best == nil {
+			stuck[source] = true
+			continue
+		}
+
+		shift(loads, best, best.node, bestTarget)
+		moved[best.vm] = true
+		moves = append(moves, harvesterv1.RebalanceMove{
+			VM:         best.vm,
+			SourceNode: best.node,
+			TargetNode: bestTarget,
+			Phase:      harvesterv1.RebalanceMovePlanned,
+		})
+	}
+	return moves
+}
+
+func hottestNode(loads map[string]*nodeLoad, w weights, excluded map[string]bool) string {
+	var hottest string
+	var highest float64
+	for name, load := range loads {
+		if excluded[name] {
+			continue
+		}
+		s := w.score(load)
+		if hottest == "" || s > highest || (s == highest && name < hottest) {
+			hottest, highest = name, s
+		}
+	}
+	return hottest
+}
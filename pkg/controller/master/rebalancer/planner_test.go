@@ -0,0 +1,159 @@
+package rebalancer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const gi = int64(1 << 30)
+
+func newLoads(requested map[string][2]int64) map[string]*nodeLoad {
+	loads := make(map[string]*nodeLoad, len(requested))
+	for name, r := range requested {
+		loads[name] = &nodeLoad{
+			name:              name,
+			allocatableCPU:    10000,
+			allocatableMemory: 100 * gi,
+			requestedCPU:      r[0],
+			requestedMemory:   r[1],
+		}
+	}
+	return loads
+}
+
+func TestNodeScores(t *testing.T) {
+	loads := newLoads(map[string][2]int64{
+		"node1": {8000, 40 * gi},
+		"node2": {2000, 20 * gi},
+	})
+
+	scores := nodeScores(loads, weights{cpu: 1, memory: 1})
+	assert.Equal(t, []harvesterv1.RebalanceNodeScore{
+		{Node: "node1", CPU: 80, Memory: 40, Score: 60},
+		{Node: "node2", CPU: 20, Memory: 20, Score: 20},
+	}, scores)
+
+	scores = nodeScores(loads, weights{cpu: 0, memory: 1})
+	assert.Equal(t, 40, scores[0].Score)
+}
+
+func TestPlan(t *testing.T) {
+	w := weights{cpu: 1, memory: 1}
+
+	tests := []struct {
+		name       string
+		loads      map[string]*nodeLoad
+		candidates []candidate
+		maxMoves   int
+		expected   []harvesterv1.RebalanceMove
+	}{
+		{
+			name: "balanced nodes",
+			loads: newLoads(map[string][2]int64{
+				"node1": {5000, 50 * gi},
+				"node2": {4000, 40 * gi},
+			}),
+			candidates: []candidate{
+				{vm: "default/vm1", node: "node1", cpu: 1000, memory: 10 * gi, targets: []string{"node2"}},
+			},
+			maxMoves: 10,
+		},
+		{
+			name: "moves to the empty node",
+			loads: newLoads(map[string][2]int64{
+				"node1": {8000, 80 * gi},
+				"node2": {5000, 50 * gi},
+				"node3": {0, 0},
+			}),
+			candidates: []candidate{
+				{vm: "default/vm1", node: "node1", cpu: 2000, memory: 20 * gi, targets: []string{"node2", "node3"}},
+				{vm: "default/vm2", node: "node1", cpu: 2000, memory: 20 * gi, targets: []string{"node2", "node3"}},
+				{vm: "default/vm3", node: "node2", cpu: 2000, memory: 20 * gi, targets: []string{"node1", "node3"}},
+			},
+			maxMoves: 10,
+			expected: []harvesterv1.RebalanceMove{
+				{VM: "default/vm1", SourceNode: "node1", TargetNode: "node3", Phase: harvesterv1.RebalanceMovePlanned},
+				{VM: "default/vm2", SourceNode: "node1", TargetNode: "node3", Phase: harvesterv1.RebalanceMovePlanned},
+			},
+		},
+		{
+			name: "the number of moves is bounded",
+			loads: newLoads(map[string][2]int64{
+				"node1": {8000, 80 * gi},
+				"node2": {0, 0},
+			}),
+			candidates: []candidate{
+				{vm: "default/vm1", node: "node1", cpu: 2000, memory: 20 * gi, targets: []string{"node2"}},
+				{vm: "default/vm2", node: "node1", cpu: 2000, memory: 20 * gi, targets: []string{"node2"}},
+			},
+			maxMoves: 1,
+			expected: []harvesterv1.RebalanceMove{
+				{VM: "default/vm1", SourceNode: "node1", TargetNode: "node2", Phase: harvesterv1.RebalanceMovePlanned},
+			},
+		},
+		{
+			name: "only migratable targets are used",
+			loads: newLoads(map[string][2]int64{
+				"node1": {8000, 80 * gi},
+				"node2": {7000, 70 * gi},
+				"node3": {0, 0},
+			}),
+			candidates: []candidate{
+				{vm: "default/vm1", node: "node1", cpu: 2000, memory: 20 * gi, targets: []string{"node2"}},
+			},
+			maxMoves: 10,
+		},
+		{
+			name: "the target must have capacity",
+			loads: newLoads(map[string][2]int64{
+				"node1": {10000, 100 * gi},
+				"node2": {5000, 50 * gi},
+			}),
+			candidates: []candidate{
+				{vm: "default/vm1", node: "node1", cpu: 6000, memory: 60 * gi, targets: []string{"node2"}},
+			},
+			maxMoves: 10,
+		},
+	}
+
+	for _, tc := range tests {
+		moves := plan(tc.loads, tc.candidates, w, 20, tc.maxMoves)
+		assert.Equal(t, tc.expected, moves, tc.name)
+	}
+}
+
+func TestPodRequests(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+					},
+				},
+				{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("100m"),
+						},
+					},
+				},
+			},
+			Overhead: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		},
+	}
+
+	cpu, memory := podRequests(pod)
+	assert.Equal(t, int64(600), cpu)
+	assert.Equal(t, int64(1280<<20), memory)
+}
@@ -0,0 +1,522 @@
+package rebalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/slice"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	nodecontroller "github.com/harvester/harvester/pkg/controller/master/node"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/migration"
+	"github.com/harvester/harvester/pkg/util/virtualmachineinstance"
+)
+
+const (
+	defaultRunInterval = 5 * time.Minute
+	// running migrations are checked more often than the nodes are scored
+	migratingRunInterval = 30 * time.Second
+
+	// the number of moves planned in a run
+	maxPlannedMoves = 10
+	// the number of finished moves kept in the status
+	maxExecutedMoves = 20
+
+	reasonRunFailed   = "RunFailed"
+	reasonPaused      = "Paused"
+	reasonUpgrading   = "Upgrading"
+	reasonMaintenance = "Maintenance"
+	reasonDuplicated  = "Duplicated"
+	reasonUnbalanced  = "Unbalanced"
+)
+
+type rebalancePolicyHandler struct {
+	ctx              context.Context
+	namespace        string
+	policyController ctlharvesterv1.RebalancePolicyController
+	policyClient     ctlharvesterv1.RebalancePolicyClient
+	policyCache      ctlharvesterv1.RebalancePolicyCache
+	nodeCache        ctlcorev1.NodeCache
+	podCache         ctlcorev1.PodCache
+	upgradeCache     ctlharvesterv1.UpgradeCache
+	vmCache          ctlkubevirtv1.VirtualMachineCache
+	vmiCache         ctlkubevirtv1.VirtualMachineInstanceCache
+	vmimClient       ctlkubevirtv1.VirtualMachineInstanceMigrationClient
+	vmimCache        ctlkubevirtv1.VirtualMachineInstanceMigrationCache
+	restClient       rest.Interface
+}
+
+// OnChanged tracks the migrations of the policy, starts the planned ones and plans new ones once
+// the previous moves are finished, and requeues the policy for the next run.
+func (h *rebalancePolicyHandler) OnChanged(_ string, policy *harvesterv1.RebalancePolicy) (*harvesterv1.RebalancePolicy, error) {
+	if policy == nil || policy.DeletionTimestamp != nil {
+		return policy, nil
+	}
+
+	if policy.Status.LastRunTime != nil && policy.Status.ObservedGeneration == policy.Generation {
+		if wait := time.Until(nextRunTime(policy)); wait > 0 {
+			h.policyController.EnqueueAfter(policy.Name, wait)
+			return policy, nil
+		}
+	}
+
+	toUpdate := policy.DeepCopy()
+	if err := h.run(toUpdate); err != nil {
+		logrus.WithError(err).Errorf("failed to run rebalance policy %s", policy.Name)
+		harvesterv1.RebalancePolicyConditionBalanced.False(toUpdate)
+		harvesterv1.RebalancePolicyConditionBalanced.Reason(toUpdate, reasonRunFailed)
+		harvesterv1.RebalancePolicyConditionBalanced.Message(toUpdate, err.Error())
+	}
+	now := metav1.Now()
+	toUpdate.Status.LastRunTime = &now
+	toUpdate.Status.ObservedGeneration = policy.Generation
+
+	updated, err := h.policyClient.UpdateStatus(toUpdate)
+	if err != nil {
+		return policy, err
+	}
+	h.policyController.EnqueueAfter(updated.Name, time.Until(nextRunTime(updated)))
+	return updated, nil
+}
+
+func (h *rebalancePolicyHandler) run(policy *harvesterv1.RebalancePolicy) error {
+	h.trackMoves(policy)
+
+	nodes, err := h.listNodes(policy.Spec.NodeSelector)
+	if err != nil {
+		return err
+	}
+	loads, err := h.nodeLoads(nodes)
+	if err != nil {
+		return err
+	}
+	w := weights{cpu: policy.Spec.CPUWeight, memory: policy.Spec.MemoryWeight}
+	policy.Status.Nodes = nodeScores(loads, w)
+
+	reason, message, err := h.blocked(policy)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		// the moves which haven't started are planned again once the policy is unblocked
+		policy.Status.PlannedMoves = filterMoves(policy.Status.PlannedMoves, harvesterv1.RebalanceMoveMigrating)
+		harvesterv1.RebalancePolicyConditionBalanced.False(policy)
+		harvesterv1.RebalancePolicyConditionBalanced.Reason(policy, reason)
+		harvesterv1.RebalancePolicyConditionBalanced.Message(policy, message)
+		return nil
+	}
+
+	threshold := float64(policy.Spec.Threshold)
+	if len(policy.Status.PlannedMoves) == 0 && spread(loads, w) > threshold {
+		candidates, err := h.candidates(loads, w, threshold)
+		if err != nil {
+			return err
+		}
+		policy.Status.PlannedMoves = plan(loads, candidates, w, threshold, maxPlannedMoves)
+	}
+
+	h.startMoves(policy)
+
+	current := spread(loads, w)
+	switch {
+	case len(policy.Status.PlannedMoves) == 0 && current <= threshold:
+		harvesterv1.RebalancePolicyConditionBalanced.True(policy)
+		harvesterv1.RebalancePolicyConditionBalanced.Reason(policy, "")
+		harvesterv1.RebalancePolicyConditionBalanced.Message(policy, "")
+	case len(policy.Status.PlannedMoves) == 0:
+		harvesterv1.RebalancePolicyConditionBalanced.False(policy)
+		harvesterv1.RebalancePolicyConditionBalanced.Reason(policy, reasonUnbalanced)
+		harvesterv1.RebalancePolicyConditionBalanced.Message(policy,
+			fmt.Sprintf("the spread of the node scores is %d, no migration reduces it below the threshold %d", int(current+0.5), policy.Spec.Threshold))
+	default:
+		harvesterv1.RebalancePolicyConditionBalanced.False(policy)
+		harvesterv1.RebalancePolicyConditionBalanced.Reason(policy, reasonUnbalanced)
+		harvesterv1.RebalancePolicyConditionBalanced.Message(policy,
+			fmt.Sprintf("%d migrations are planned to balance the nodes", len(policy.Status.PlannedMoves)))
+	}
+	return nil
+}
+
+// blocked returns the reason why no migrations are started, which is the case while the policy is
+// paused, another policy is older, an upgrade is in progress or a node is entering maintenance mode.
+func (h *rebalancePolicyHandler) blocked(policy *harvesterv1.RebalancePolicy) (string, string, error) {
+	if policy.Spec.Paused {
+		return reasonPaused, "the policy is paused", nil
+	}
+
+	// the webhook allows a single policy, only the oldest one migrates if several were created at once
+	policies, err := h.policyCache.List(labels.Everything())
+	if err != nil {
+		return "", "", err
+	}
+	for _, other := range policies {
+		if isOlderPolicy(other, policy) {
+			return reasonDuplicated, fmt.Sprintf("rebalance policy %s already balances the nodes", other.Name), nil
+		}
+	}
+
+	upgrades, err := h.upgradeCache.List(util.HarvesterSystemNamespaceName, labels.NewSelector())
+	if err != nil {
+		return "", "", err
+	}
+	for _, upgrade := range upgrades {
+		if util.IsUpgradeInProgress(upgrade) {
+			return reasonUpgrading, fmt.Sprintf("upgrade %s is in progress", upgrade.Name), nil
+		}
+	}
+
+	nodes, err := h.nodeCache.List(labels.Everything())
+	if err != nil {
+		return "", "", err
+	}
+	for _, node := range nodes {
+		if node.Annotations[nodecontroller.MaintainStatusAnnotationKey] == nodecontroller.MaintainStatusRunning {
+			return reasonMaintenance, fmt.Sprintf("node %s is entering maintenance mode", node.Name), nil
+		}
+	}
+	return "", "", nil
+}
+
+// listNodes returns the selected nodes which are ready and not drained, witness nodes don't run VMs.
+func (h *rebalancePolicyHandler) listNodes(nodeSelector *metav1.LabelSelector) ([]*corev1.Node, error) {
+	selector := labels.Everything()
+	if nodeSelector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(nodeSelector); err != nil {
+			return nil, err
+		}
+	}
+	nodes, err := h.nodeCache.List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*corev1.Node, 0, len(nodes))
+	for _, node := range util.ExcludeWitnessNodes(nodes) {
+		if isNodeReady(node) && !migration.IsNodeDrained(node) {
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+// nodeLoads sums the requests of the pods of the nodes, the requests of the VM pods are already
+// reduced by the overcommit setting.
+func (h *rebalancePolicyHandler) nodeLoads(nodes []*corev1.Node) (map[string]*nodeLoad, error) {
+	loads := make(map[string]*nodeLoad, len(nodes))
+	for _, node := range nodes {
+		loads[node.Name] = &nodeLoad{
+			name:              node.Name,
+			allocatableCPU:    node.Status.Allocatable.Cpu().MilliValue(),
+			allocatableMemory: node.Status.Allocatable.Memory().Value(),
+		}
+	}
+
+	pods, err := h.podCache.List(corev1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		load, ok := loads[pod.Spec.NodeName]
+		if !ok || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		cpu, memory := podRequests(pod)
+		load.requestedCPU += cpu
+		load.requestedMemory += memory
+	}
+	return loads, nil
+}
+
+// candidates returns the VMs which can be live migrated away from the nodes whose score is above
+// the threshold from the least allocated node, to the nodes they are migratable to.
+func (h *rebalancePolicyHandler) candidates(loads map[string]*nodeLoad, w weights, threshold float64) ([]candidate, error) {
+	lowest := -1.0
+	for _, load := range loads {
+		if s := w.score(load); lowest < 0 || s < lowest {
+			lowest = s
+		}
+	}
+
+	vmis, err := h.vmiCache.List(corev1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []candidate
+	for _, vmi := range vmis {
+		load, ok := loads[vmi.Status.NodeName]
+		if !ok || w.score(load)-lowest <= threshold {
+			continue
+		}
+		if err := h.validateVMI(vmi); err != nil {
+			continue
+		}
+
+		pod, err := migration.GetVMIPod(vmi, h.podCache)
+		if err != nil {
+			logrus.WithError(err).Debugf("skip rebalancing vmi %s/%s", vmi.Namespace, vmi.Name)
+			continue
+		}
+		targets, err := migration.FindMigratableNodes(vmi, h.podCache, h.nodeCache)
+		if err != nil {
+			logrus.WithError(err).Debugf("skip rebalancing vmi %s/%s", vmi.Namespace, vmi.Name)
+			continue
+		}
+		sort.Strings(targets)
+
+		cpu, memory := podRequests(pod)
+		candidates = append(candidates, candidate{
+			vm:      vmi.Namespace + "/" + vmi.Name,
+			node:    vmi.Status.NodeName,
+			cpu:     cpu,
+			memory:  memory,
+			targets: targets,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].vm < candidates[j].vm
+	})
+	return candidates, nil
+}
+
+// validateVMI checks the VMI is running, ready, not migrating and live migratable, and the VM isn't
+// excluded from rebalancing.
+func (h *rebalancePolicyHandler) validateVMI(vmi *kubevirtv1.VirtualMachineInstance) error {
+	if vmi.DeletionTimestamp != nil || !vmi.IsRunning() {
+		return errors.New("the VM is not in running state")
+	}
+	if !isVMIReady(vmi) {
+		return errors.New("the VM is not in ready status")
+	}
+	if vmi.Annotations[util.AnnotationMigrationState] != "" {
+		return errors.New("the VM is migrating")
+	}
+	if err := virtualmachineinstance.ValidateVMMigratable(vmi); err != nil {
+		return err
+	}
+
+	vm, err := h.vmCache.Get(vmi.Namespace, vmi.Name)
+	if err != nil {
+		return err
+	}
+	if vm.Annotations[util.AnnotationRebalanceExclude] == "true" {
+		return errors.New("the VM is excluded from rebalancing")
+	}
+	return nil
+}
+
+// trackMoves updates the phase of the running migrations and moves the finished ones to the
+// executed moves.
+func (h *rebalancePolicyHandler) trackMoves(policy *harvesterv1.RebalancePolicy) {
+	planned := make([]harvesterv1.RebalanceMove, 0, len(policy.Status.PlannedMoves))
+	for _, move := range policy.Status.PlannedMoves {
+		if move.Phase == harvesterv1.RebalanceMoveMigrating {
+			h.trackMove(&move)
+		}
+		if move.Phase == harvesterv1.RebalanceMoveSucceeded || move.Phase == harvesterv1.RebalanceMoveFailed {
+			addExecutedMove(policy, move)
+			continue
+		}
+		planned = append(planned, move)
+	}
+	policy.Status.PlannedMoves = planned
+}
+
+func (h *rebalancePolicyHandler) trackMove(move *harvesterv1.RebalanceMove) {
+	namespace, _ := splitVM(move.VM)
+	vmim, err := h.vmimCache.Get(namespace, move.Migration)
+	if apierrors.IsNotFound(err) {
+		setMovePhase(move, harvesterv1.RebalanceMoveFailed, "the migration is gone")
+		return
+	} else if err != nil {
+		logrus.WithError(err).Warnf("failed to get migration %s/%s", namespace, move.Migration)
+		return
+	}
+
+	switch vmim.Status.Phase {
+	case kubevirtv1.MigrationSucceeded:
+		setMovePhase(move, harvesterv1.RebalanceMoveSucceeded, "")
+	case kubevirtv1.MigrationFailed:
+		setMovePhase(move, harvesterv1.RebalanceMoveFailed, "the migration failed")
+	}
+}
+
+// startMoves starts the planned moves until the maximum number of concurrent migrations is reached.
+func (h *rebalancePolicyHandler) startMoves(policy *harvesterv1.RebalancePolicy) {
+	running := 0
+	for _, move := range policy.Status.PlannedMoves {
+		if move.Phase == harvesterv1.RebalanceMoveMigrating {
+			running++
+		}
+	}
+
+	planned := make([]harvesterv1.RebalanceMove, 0, len(policy.Status.PlannedMoves))
+	for _, move := range policy.Status.PlannedMoves {
+		if move.Phase == harvesterv1.RebalanceMovePlanned && running < policy.Spec.MaxConcurrentMigrations {
+			if err := h.startMove(policy, &move); err != nil {
+				setMovePhase(&move, harvesterv1.RebalanceMoveFailed, err.Error())
+				addExecutedMove(policy, move)
+				continue
+			}
+			running++
+		}
+		planned = append(planned, move)
+	}
+	policy.Status.PlannedMoves = planned
+}
+
+// startMove checks the VM is still migratable to the target node, like a migration started from the
+// API, and creates the migration.
+func (h *rebalancePolicyHandler) startMove(policy *harvesterv1.RebalancePolicy, move *harvesterv1.RebalanceMove) error {
+	namespace, name := splitVM(move.VM)
+	vmi, err := h.vmiCache.Get(namespace, name)
+	if err != nil {
+		return err
+	}
+	if vmi.Status.NodeName != move.SourceNode {
+		return fmt.Errorf("the VM is not running on the node %s anymore", move.SourceNode)
+	}
+	if err := h.validateVMI(vmi); err != nil {
+		return err
+	}
+	targets, err := migration.FindMigratableNodes(vmi, h.podCache, h.nodeCache)
+	if err != nil {
+		return err
+	}
+	if !slice.ContainsString(targets, move.TargetNode) {
+		return fmt.Errorf("the node %s is non-migratable", move.TargetNode)
+	}
+
+	toUpdateVmi := vmi.DeepCopy()
+	if toUpdateVmi.Annotations == nil {
+		toUpdateVmi.Annotations = make(map[string]string)
+	}
+	toUpdateVmi.Annotations[util.AnnotationMigrationTarget] = move.TargetNode
+	if err := util.VirtClientUpdateVmi(h.ctx, h.restClient, h.namespace, namespace, name, toUpdateVmi); err != nil {
+		return fmt.Errorf("failed to set the migration target: %w", err)
+	}
+
+	vmim, err := h.vmimClient.Create(&kubevirtv1.VirtualMachineInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: name + "-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				util.LabelRebalancePolicy: policy.Name,
+			},
+		},
+		Spec: kubevirtv1.VirtualMachineInstanceMigrationSpec{
+			VMIName: name,
+			AddedNodeSelector: map[string]string{
+				corev1.LabelHostname: move.TargetNode,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the migration: %w", err)
+	}
+
+	logrus.Infof("rebalance policy %s migrates vm %s from %s to %s", policy.Name, move.VM, move.SourceNode, move.TargetNode)
+	move.Migration = vmim.Name
+	setMovePhase(move, harvesterv1.RebalanceMoveMigrating, "")
+	return nil
+}
+
+func addExecutedMove(policy *harvesterv1.RebalancePolicy, move harvesterv1.RebalanceMove) {
+	policy.Status.ExecutedMoves = append(policy.Status.ExecutedMoves, move)
+	if n := len(policy.Status.ExecutedMoves); n > maxExecutedMoves {
+		policy.Status.ExecutedMoves = policy.Status.ExecutedMoves[n-maxExecutedMoves:]
+	}
+}
+
+func setMovePhase(move *harvesterv1.RebalanceMove, phase harvesterv1.RebalanceMovePhase, message string) {
+	now := metav1.Now()
+	move.Phase = phase
+	move.Message = message
+	move.Time = &now
+}
+
+func filterMoves(moves []harvesterv1.RebalanceMove, phase harvesterv1.RebalanceMovePhase) []harvesterv1.RebalanceMove {
+	var result []harvesterv1.RebalanceMove
+	for _, move := range moves {
+		if move.Phase == phase {
+			result = append(result, move)
+		}
+	}
+	return result
+}
+
+func isOlderPolicy(policy, than *harvesterv1.RebalancePolicy) bool {
+	if policy.Name == than.Name {
+		return false
+	}
+	if !policy.CreationTimestamp.Equal(&than.CreationTimestamp) {
+		return policy.CreationTimestamp.Before(&than.CreationTimestamp)
+	}
+	return policy.Name < than.Name
+}
+
+func splitVM(vm string) (string, string) {
+	namespace, name, _ := strings.Cut(vm, "/")
+	return namespace, name
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isVMIReady(vmi *kubevirtv1.VirtualMachineInstance) bool {
+	for _, cond := range vmi.Status.Conditions {
+		if cond.Type == kubevirtv1.VirtualMachineInstanceReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// podRequests returns the requested CPU in millicores and memory in bytes of the pod.
+func podRequests(pod *corev1.Pod) (int64, int64) {
+	var cpu, memory int64
+	for _, container := range pod.Spec.Containers {
+		cpu += container.Resources.Requests.Cpu().MilliValue()
+		memory += container.Resources.Requests.Memory().Value()
+	}
+	cpu += pod.Spec.Overhead.Cpu().MilliValue()
+	memory += pod.Spec.Overhead.Memory().Value()
+	return cpu, memory
+}
+
+// nextRunTime returns when the policy runs next, which is sooner while moves are planned.
+func nextRunTime(policy *harvesterv1.RebalancePolicy) time.Time {
+	interval := defaultRunInterval
+	if policy.Spec.Interval != nil && policy.Spec.Interval.Duration > 0 {
+		interval = policy.Spec.Interval.Duration
+	}
+	if len(policy.Status.PlannedMoves) > 0 && migratingRunInterval < interval {
+		interval = migratingRunInterval
+	}
+	if policy.Status.LastRunTime == nil {
+		return time.Now()
+	}
+	return policy.Status.LastRunTime.Add(interval)
+}
@@ -0,0 +1,175 @@
+package rebalancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	nodecontroller "github.com/harvester/harvester/pkg/controller/master/node"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newTestHandler(objects ...runtime.Object) *rebalancePolicyHandler {
+	clientset := fake.NewSimpleClientset(objects...)
+	return &rebalancePolicyHandler{
+		policyCache:  fakeclients.RebalancePolicyCache(clientset.HarvesterhciV1beta1().RebalancePolicies),
+		nodeCache:    fakeclients.NodeCache(clientset.CoreV1().Nodes),
+		podCache:     fakeclients.PodCache(clientset.CoreV1().Pods),
+		upgradeCache: fakeclients.UpgradeCache(clientset.HarvesterhciV1beta1().Upgrades),
+		vmCache:      fakeclients.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+		vmiCache:     fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+		vmimCache:    fakeclients.VirtualMachineInstanceMigrationCache(clientset.KubevirtV1().VirtualMachineInstanceMigrations),
+	}
+}
+
+func newPolicy(name string, created time.Time) *harvesterv1.RebalancePolicy {
+	return &harvesterv1.RebalancePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		Spec: harvesterv1.RebalancePolicySpec{
+			CPUWeight:               1,
+			MemoryWeight:            1,
+			Threshold:               20,
+			MaxConcurrentMigrations: 1,
+		},
+		Status: harvesterv1.RebalancePolicyStatus{
+			PlannedMoves: []harvesterv1.RebalanceMove{
+				{
+					VM:         "default/vm1",
+					SourceNode: "node1",
+					TargetNode: "node2",
+					Migration:  "vm1-abcde",
+					Phase:      harvesterv1.RebalanceMoveMigrating,
+				},
+				{
+					VM:         "default/vm2",
+					SourceNode: "node1",
+					TargetNode: "node2",
+					Phase:      harvesterv1.RebalanceMovePlanned,
+				},
+			},
+		},
+	}
+}
+
+func newReadyNode(name string, annotations map[string]string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func newRunningMigration() *kubevirtv1.VirtualMachineInstanceMigration {
+	return &kubevirtv1.VirtualMachineInstanceMigration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vm1-abcde", Namespace: "default"},
+		Status:     kubevirtv1.VirtualMachineInstanceMigrationStatus{Phase: kubevirtv1.MigrationRunning},
+	}
+}
+
+func TestRunBlocked(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		paused         bool
+		objects        []runtime.Object
+		expectedReason string
+	}{
+		{
+			name:           "paused policy",
+			paused:         true,
+			objects:        []runtime.Object{newReadyNode("node1", nil), newReadyNode("node2", nil)},
+			expectedReason: reasonPaused,
+		},
+		{
+			name: "upgrade in progress",
+			objects: []runtime.Object{
+				newReadyNode("node1", nil),
+				newReadyNode("node2", nil),
+				&harvesterv1.Upgrade{ObjectMeta: metav1.ObjectMeta{Name: "upgrade", Namespace: util.HarvesterSystemNamespaceName}},
+			},
+			expectedReason: reasonUpgrading,
+		},
+		{
+			name: "node entering maintenance mode",
+			objects: []runtime.Object{
+				newReadyNode("node1", map[string]string{nodecontroller.MaintainStatusAnnotationKey: nodecontroller.MaintainStatusRunning}),
+				newReadyNode("node2", nil),
+			},
+			expectedReason: reasonMaintenance,
+		},
+		{
+			name: "older policy",
+			objects: []runtime.Object{
+				newReadyNode("node1", nil),
+				newReadyNode("node2", nil),
+				newPolicy("older", created.Add(-time.Hour)),
+			},
+			expectedReason: reasonDuplicated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := newPolicy("policy", created)
+			policy.Spec.Paused = tc.paused
+			h := newTestHandler(append(tc.objects, policy, newRunningMigration())...)
+
+			require.NoError(t, h.run(policy))
+			assert.True(t, harvesterv1.RebalancePolicyConditionBalanced.IsFalse(policy))
+			assert.Equal(t, tc.expectedReason, harvesterv1.RebalancePolicyConditionBalanced.GetReason(policy))
+			// the running migration is tracked, the planned move is dropped until the policy is unblocked
+			require.Len(t, policy.Status.PlannedMoves, 1)
+			assert.Equal(t, "default/vm1", policy.Status.PlannedMoves[0].VM)
+			assert.Equal(t, harvesterv1.RebalanceMoveMigrating, policy.Status.PlannedMoves[0].Phase)
+			assert.Empty(t, policy.Status.ExecutedMoves)
+			assert.NotEmpty(t, policy.Status.Nodes)
+		})
+	}
+}
+
+func TestRunBlockedTracksFinishedMigration(t *testing.T) {
+	policy := newPolicy("policy", time.Now())
+	policy.Spec.Paused = true
+	migration := newRunningMigration()
+	migration.Status.Phase = kubevirtv1.MigrationSucceeded
+	h := newTestHandler(policy, migration)
+
+	require.NoError(t, h.run(policy))
+	assert.Empty(t, policy.Status.PlannedMoves)
+	require.Len(t, policy.Status.ExecutedMoves, 1)
+	assert.Equal(t, harvesterv1.RebalanceMoveSucceeded, policy.Status.ExecutedMoves[0].Phase)
+}
+
+func TestRunNewerPolicyDoesNotBlockOlder(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := newPolicy("policy", created)
+	policy.Status.PlannedMoves = nil
+	h := newTestHandler(policy, newPolicy("newer", created.Add(time.Hour)), newReadyNode("node1", nil), newReadyNode("node2", nil))
+
+	require.NoError(t, h.run(policy))
+	assert.True(t, harvesterv1.RebalancePolicyConditionBalanced.IsTrue(policy))
+}
+
+func TestIsOlderPolicy(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := newPolicy("a", created)
+	b := newPolicy("b", created)
+	c := newPolicy("c", created.Add(-time.Minute))
+
+	assert.True(t, isOlderPolicy(a, b))
+	assert.False(t, isOlderPolicy(b, a))
+	assert.True(t, isOlderPolicy(c, a))
+	assert.False(t, isOlderPolicy(a, c))
+	assert.False(t, isOlderPolicy(a, a))
+}
@@ -0,0 +1,47 @@
+package rebalancer
+
+import (
+	"context"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/harvester/harvester/pkg/config"
+	virtv1 "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/kubevirt.io/v1"
+)
+
+const (
+	rebalancePolicyControllerName = "rebalance-policy-controller"
+)
+
+func Register(ctx context.Context, management *config.Management, options config.Options) error {
+	virtv1Client, err := virtv1.NewForConfig(rest.CopyConfig(management.RestConfig))
+	if err != nil {
+		return err
+	}
+	policies := management.HarvesterFactory.Harvesterhci().V1beta1().RebalancePolicy()
+	nodes := management.CoreFactory.Core().V1().Node()
+	pods := management.CoreFactory.Core().V1().Pod()
+	upgrades := management.HarvesterFactory.Harvesterhci().V1beta1().Upgrade()
+	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	vmis := management.VirtFactory.Kubevirt().V1().VirtualMachineInstance()
+	vmims := management.VirtFactory.Kubevirt().V1().VirtualMachineInstanceMigration()
+
+	handler := &rebalancePolicyHandler{
+		ctx:              ctx,
+		namespace:        options.Namespace,
+		policyController: policies,
+		policyClient:     policies,
+		policyCache:      policies.Cache(),
+		nodeCache:        nodes.Cache(),
+		podCache:         pods.Cache(),
+		upgradeCache:     upgrades.Cache(),
+		vmCache:          vms.Cache(),
+		vmiCache:         vmis.Cache(),
+		vmimClient:       vmims,
+		vmimCache:        vmims.Cache(),
+		restClient:       virtv1Client.RESTClient(),
+	}
+
+	policies.OnChange(ctx, rebalancePolicyControllerName, handler.OnChanged)
+	return nil
+}
@@ -182,6 +182,10 @@ func gcVMBackups(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) e
 		return nil
 	}
 
+	if svmbackup.Spec.RetentionPolicy != nil {
+		return gcVMBackupsByRetentionPolicy(h, svmbackup, vmBackups, lastVMBackup)
+	}
+
 	// we clear the failure backups first, and the successful backup from the oldest one
 	// the #target-delete-backups according to `.spec.retain`
 	var errs error
@@ -227,6 +231,10 @@ func convertVMBackupToInfo(vmbr common.VMBackupReader, vmbackup *harvesterv1.Vir
 		vmBackupInfo.Error = status.Error
 	}
 
+	if status.Verification != nil {
+		vmBackupInfo.Verification = status.Verification
+	}
+
 	volBackups := vmbr.GetVolBackups(vmbackup)
 	if len(volBackups) == 0 {
 		return vmBackupInfo
@@ -260,8 +268,19 @@ func reconcileVMBackupList(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleV
 	svmbackupCpy := svmbackup.DeepCopy()
 	svmbackupCpy.Status.VMBackupInfo = make([]harvesterv1.VMBackupInfo, len(vmbackups))
 	svmbackupCpy.Status.Failure = failure
+	tiers := getRetentionTiers(svmbackup.Spec.RetentionPolicy, readyVMBackups(h, vmbackups))
 	for i := 0; i < len(vmbackups); i++ {
 		svmbackupCpy.Status.VMBackupInfo[i] = convertVMBackupToInfo(h.vmbr, vmbackups[i])
+		svmbackupCpy.Status.VMBackupInfo[i].RetentionTiers = tiers[vmbackups[i].Name]
+	}
+
+	vmBackupCopies, err := currentVMBackupCopies(h, svmbackup)
+	if err != nil {
+		return err
+	}
+	svmbackupCpy.Status.VMBackupCopyInfo = nil
+	for _, vmBackupCopy := range vmBackupCopies {
+		svmbackupCpy.Status.VMBackupCopyInfo = append(svmbackupCpy.Status.VMBackupCopyInfo, convertVMBackupCopyToInfo(vmBackupCopy))
 	}
 
 	if reflect.DeepEqual(svmbackup.Status, svmbackupCpy.Status) {
@@ -282,6 +301,16 @@ func updateVMBackups(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBacku
 		errs = multierr.Append(errs, err)
 	}
 
+	err = updateVMBackupCopies(h, svmbackup)
+	if err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	err = verifyLastVMBackup(h, svmbackup)
+	if err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
 	err = reconcileVMBackupList(h, svmbackup)
 	if err != nil {
 		errs = multierr.Append(errs, err)
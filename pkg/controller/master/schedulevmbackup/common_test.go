@@ -1,8 +1,10 @@
 package schedulevmbackup
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	batchv1 "k8s.io/api/batch/v1"
@@ -217,10 +219,11 @@ func Test_ReconcileVMBackupList(t *testing.T) {
 	assert := require.New(t)
 
 	h := &svmbackupHandler{
-		vmBackupCache:   fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
-		svmbackupClient: fakeclients.SVMBackupClient(clientset.HarvesterhciV1beta1().ScheduleVMBackups),
-		svmbackupCache:  fakeclients.SVMBackupCache(clientset.HarvesterhciV1beta1().ScheduleVMBackups),
-		vmbr:            common.NewVMBackupReader(),
+		vmBackupCache:     fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		vmBackupCopyCache: fakeclients.VMBackupCopyCache(clientset.HarvesterhciV1beta1().VirtualMachineBackupCopies),
+		svmbackupClient:   fakeclients.SVMBackupClient(clientset.HarvesterhciV1beta1().ScheduleVMBackups),
+		svmbackupCache:    fakeclients.SVMBackupCache(clientset.HarvesterhciV1beta1().ScheduleVMBackups),
+		vmbr:              common.NewVMBackupReader(),
 	}
 
 	err := clientset.Tracker().Add(vmbackup1)
@@ -293,3 +296,148 @@ func Test_NewVMBackups(t *testing.T) {
 	assert.Nil(err, "new vmbackup should success")
 	assert.Equal(vmbackup3.Spec, getVMbackup.Spec, "new vmbackup should equals vmbackup3")
 }
+
+func Test_UpdateVMBackupCopies(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	assert := require.New(t)
+
+	h := &svmbackupHandler{
+		vmBackupCache:      fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		vmBackupCopyClient: fakeclients.VMBackupCopyClient(clientset.HarvesterhciV1beta1().VirtualMachineBackupCopies),
+		vmBackupCopyCache:  fakeclients.VMBackupCopyCache(clientset.HarvesterhciV1beta1().VirtualMachineBackupCopies),
+		vmbr:               common.NewVMBackupReader(),
+	}
+
+	copySVMBackup := svmbackup.DeepCopy()
+	copySVMBackup.Spec.Copy = &harvesterv1.ScheduleVMBackupCopy{BackupTargetName: "offsite", Retain: 1}
+
+	err := clientset.Tracker().Add(vmbackup1)
+	assert.Nil(err, "vmbackup1 should add into fake controller")
+
+	err = updateVMBackupCopies(h, copySVMBackup)
+	assert.Nil(err, "update vmbackup copies should success")
+
+	err = clientset.Tracker().Add(vmbackup2)
+	assert.Nil(err, "vmbackup2 should add into fake controller")
+
+	err = updateVMBackupCopies(h, copySVMBackup)
+	assert.Nil(err, "update vmbackup copies should success")
+
+	// only the copy of the latest vmbackup is retained
+	vmBackupCopies, err := currentVMBackupCopies(h, copySVMBackup)
+	assert.Nil(err, "vmbackup copies should get from fake controller")
+	assert.Len(vmBackupCopies, 1, "expected to find 1 vmbackup copy")
+	assert.Equal(vmbackup2.Name, vmBackupCopies[0].Spec.VMBackupName, "vmbackup copy should copy vmbackup2")
+	assert.Equal("offsite", vmBackupCopies[0].Spec.BackupTargetName, "vmbackup copy should copy to the schedule backup target")
+}
+
+func Test_GetRetentionTiers(t *testing.T) {
+	assert := require.New(t)
+
+	newVMBackup := func(name, created string) *harvesterv1.VirtualMachineBackup {
+		creationTime, err := time.Parse(time.RFC3339, created)
+		assert.Nil(err, "creation time should parse")
+		return &harvesterv1.VirtualMachineBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(creationTime),
+			},
+		}
+	}
+
+	vmbackups := []*harvesterv1.VirtualMachineBackup{
+		newVMBackup("apr-30", "2024-04-30T23:00:00Z"),
+		newVMBackup("may-06", "2024-05-06T12:00:00Z"),
+		newVMBackup("may-07-a", "2024-05-07T10:00:00Z"),
+		newVMBackup("may-07-b", "2024-05-07T11:00:00Z"),
+		newVMBackup("may-07-c", "2024-05-07T11:30:00Z"),
+	}
+	policy := &harvesterv1.ScheduleVMBackupRetentionPolicy{Hourly: 2, Daily: 2, Weekly: 2, Monthly: 3}
+
+	tiers := getRetentionTiers(policy, vmbackups)
+	assert.Equal(map[string][]harvesterv1.RetentionTier{
+		"may-07-c": {harvesterv1.RetentionTierHourly, harvesterv1.RetentionTierDaily, harvesterv1.RetentionTierWeekly, harvesterv1.RetentionTierMonthly},
+		"may-07-a": {harvesterv1.RetentionTierHourly},
+		"may-06":   {harvesterv1.RetentionTierDaily},
+		"apr-30":   {harvesterv1.RetentionTierWeekly, harvesterv1.RetentionTierMonthly},
+	}, tiers, "the latest backup of each period should be kept")
+
+	assert.Empty(getRetentionTiers(nil, vmbackups), "no backup should be kept without policy")
+}
+
+func Test_GCVMBackupsWithRetainAndRetentionPolicy(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	assert := require.New(t)
+
+	h := &svmbackupHandler{
+		vmBackupClient: fakeclients.VMBackupClient(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		vmBackupCache:  fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		vmbr:           common.NewVMBackupReader(),
+	}
+
+	newVMBackup := func(day int, ready bool) *harvesterv1.VirtualMachineBackup {
+		vmbackup := vmbackup1.DeepCopy()
+		vmbackup.Name = fmt.Sprintf("daily-%d", day)
+		vmbackup.Labels[util.LabelSVMBackupTimestamp] = fmt.Sprintf("202407%02d.0000", day)
+		vmbackup.CreationTimestamp = metav1.NewTime(time.Date(2024, time.July, day, 0, 0, 0, 0, time.UTC))
+		if !ready {
+			vmbackup.Status.ReadyToUse = ptr.To(false)
+			vmbackup.Status.Error = &harvesterv1.Error{Message: ptr.To("failed")}
+		}
+		return vmbackup
+	}
+	for day := 10; day <= 15; day++ {
+		err := clientset.Tracker().Add(newVMBackup(day, day != 13))
+		assert.Nil(err, "vmbackup should add into fake controller")
+	}
+
+	// the policy keeps more backups than `.spec.retain`
+	policySVMBackup := svmbackup.DeepCopy()
+	policySVMBackup.Spec.RetentionPolicy = &harvesterv1.ScheduleVMBackupRetentionPolicy{Daily: 4}
+	err := gcVMBackups(h, policySVMBackup)
+	assert.Nil(err, "gc vmbackups should success")
+
+	vmbackups, err := clientset.HarvesterhciV1beta1().VirtualMachineBackups(svmbackup.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(err, "vmbackups should list from fake controller")
+	var names []string
+	for _, vmbackup := range vmbackups.Items {
+		names = append(names, vmbackup.Name)
+	}
+	assert.ElementsMatch([]string{"daily-11", "daily-12", "daily-14", "daily-15"}, names,
+		"only the retention policy should decide which backups are kept")
+}
+
+func Test_VerifyLastVMBackup(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	assert := require.New(t)
+
+	h := &svmbackupHandler{
+		vmBackupCache:      fakeclients.VMBackupCache(clientset.HarvesterhciV1beta1().VirtualMachineBackups),
+		verificationClient: fakeclients.BackupVerificationClient(clientset.HarvesterhciV1beta1().BackupVerifications),
+		verificationCache:  fakeclients.BackupVerificationCache(clientset.HarvesterhciV1beta1().BackupVerifications),
+		vmbr:               common.NewVMBackupReader(),
+	}
+
+	verifySVMBackup := svmbackup.DeepCopy()
+	verifySVMBackup.Spec.Verification = &harvesterv1.BackupVerificationTemplate{
+		Timeout: &metav1.Duration{Duration: time.Hour},
+	}
+
+	err := clientset.Tracker().Add(vmbackup1)
+	assert.Nil(err, "vmbackup1 should add into fake controller")
+	err = clientset.Tracker().Add(vmbackup2)
+	assert.Nil(err, "vmbackup2 should add into fake controller")
+
+	err = verifyLastVMBackup(h, verifySVMBackup)
+	assert.Nil(err, "verify last vmbackup should success")
+	err = verifyLastVMBackup(h, verifySVMBackup)
+	assert.Nil(err, "verify last vmbackup again should success")
+
+	// only the latest vmbackup is verified, once
+	verifications, err := clientset.HarvesterhciV1beta1().BackupVerifications(svmbackup.Namespace).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(err, "verifications should list from fake controller")
+	assert.Len(verifications.Items, 1, "expected to find 1 verification")
+	assert.Equal(vmbackup2.Name, verifications.Items[0].Spec.VMBackupName, "verification should verify vmbackup2")
+	assert.Equal(verifySVMBackup.Spec.Verification.Timeout, verifications.Items[0].Spec.Timeout, "verification should use the schedule template")
+	assert.Equal(vmbackup2.Name, verifications.Items[0].OwnerReferences[0].Name, "verification should be owned by vmbackup2")
+}
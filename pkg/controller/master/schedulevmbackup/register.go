@@ -20,6 +20,7 @@ const (
 	cronJobControllerName          = "cron-job-controller"
 	vmBackupControllerName         = "vm-backup-controller"
 	longhornBackupControllerName   = "longhorn-backup-controller"
+	vmBackupCopyControllerName     = "vm-backup-copy-controller"
 
 	vmBackupKindName = "VirtualMachineBackup"
 )
@@ -35,6 +36,10 @@ type svmbackupHandler struct {
 	vmBackupController   ctlharvesterv1.VirtualMachineBackupController
 	vmBackupClient       ctlharvesterv1.VirtualMachineBackupClient
 	vmBackupCache        ctlharvesterv1.VirtualMachineBackupCache
+	vmBackupCopyClient   ctlharvesterv1.VirtualMachineBackupCopyClient
+	vmBackupCopyCache    ctlharvesterv1.VirtualMachineBackupCopyCache
+	verificationClient   ctlharvesterv1.BackupVerificationClient
+	verificationCache    ctlharvesterv1.BackupVerificationCache
 	snapshotCache        ctlsnapshotv1.VolumeSnapshotCache
 	lhsnapshotClient     ctllonghornv2.SnapshotClient
 	lhsnapshotCache      ctllonghornv2.SnapshotCache
@@ -52,6 +57,8 @@ func Register(ctx context.Context, management *config.Management, options config
 	svmbackups := management.HarvesterFactory.Harvesterhci().V1beta1().ScheduleVMBackup()
 	cronJobs := management.HarvesterBatchFactory.Batch().V1().CronJob()
 	vmBackups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup()
+	vmBackupCopies := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackupCopy()
+	verifications := management.HarvesterFactory.Harvesterhci().V1beta1().BackupVerification()
 	snapshots := management.SnapshotFactory.Snapshot().V1().VolumeSnapshot()
 	lhsnapshots := management.LonghornFactory.Longhorn().V1beta2().Snapshot()
 	settings := management.HarvesterFactory.Harvesterhci().V1beta1().Setting()
@@ -68,6 +75,10 @@ func Register(ctx context.Context, management *config.Management, options config
 		vmBackupController:   vmBackups,
 		vmBackupClient:       vmBackups,
 		vmBackupCache:        vmBackups.Cache(),
+		vmBackupCopyClient:   vmBackupCopies,
+		vmBackupCopyCache:    vmBackupCopies.Cache(),
+		verificationClient:   verifications,
+		verificationCache:    verifications.Cache(),
 		snapshotCache:        snapshots.Cache(),
 		lhsnapshotClient:     lhsnapshots,
 		lhsnapshotCache:      lhsnapshots.Cache(),
@@ -87,5 +98,6 @@ func Register(ctx context.Context, management *config.Management, options config
 	vmBackups.OnChange(ctx, vmBackupControllerName, svmbackupHandler.OnVMBackupChange)
 	vmBackups.OnRemove(ctx, vmBackupControllerName, svmbackupHandler.OnVMBackupRemove)
 	lhbackups.OnChange(ctx, longhornBackupControllerName, svmbackupHandler.OnLHBackupChanged)
+	vmBackupCopies.OnChange(ctx, vmBackupCopyControllerName, svmbackupHandler.OnVMBackupCopyChange)
 	return nil
 }
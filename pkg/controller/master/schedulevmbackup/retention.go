@@ -0,0 +1,110 @@
+package schedulevmbackup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/multierr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+type retentionTier struct {
+	tier  harvesterv1.RetentionTier
+	count func(policy *harvesterv1.ScheduleVMBackupRetentionPolicy) int
+	// period returns the key of the period the time belongs to
+	period func(t time.Time) string
+}
+
+var retentionTiers = []retentionTier{
+	{
+		tier:   harvesterv1.RetentionTierHourly,
+		count:  func(p *harvesterv1.ScheduleVMBackupRetentionPolicy) int { return p.Hourly },
+		period: func(t time.Time) string { return t.Format("2006010215") },
+	},
+	{
+		tier:   harvesterv1.RetentionTierDaily,
+		count:  func(p *harvesterv1.ScheduleVMBackupRetentionPolicy) int { return p.Daily },
+		period: func(t time.Time) string { return t.Format("20060102") },
+	},
+	{
+		tier:  harvesterv1.RetentionTierWeekly,
+		count: func(p *harvesterv1.ScheduleVMBackupRetentionPolicy) int { return p.Weekly },
+		period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		},
+	},
+	{
+		tier:   harvesterv1.RetentionTierMonthly,
+		count:  func(p *harvesterv1.ScheduleVMBackupRetentionPolicy) int { return p.Monthly },
+		period: func(t time.Time) string { return t.Format("200601") },
+	},
+}
+
+// getRetentionTiers returns the tiers each backup is kept for, by name. The
+// latest backup of each of the last N periods having backups is kept, the
+// periods are computed from the creation time in UTC. Backups not in the
+// result aren't kept by any tier.
+func getRetentionTiers(policy *harvesterv1.ScheduleVMBackupRetentionPolicy, vmbackups []*harvesterv1.VirtualMachineBackup) map[string][]harvesterv1.RetentionTier {
+	result := map[string][]harvesterv1.RetentionTier{}
+	if policy == nil {
+		return result
+	}
+
+	sorted := make([]*harvesterv1.VirtualMachineBackup, len(vmbackups))
+	copy(sorted, vmbackups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Before(&sorted[i].CreationTimestamp)
+	})
+
+	for _, rt := range retentionTiers {
+		count := rt.count(policy)
+		lastPeriod := ""
+		for _, vmbackup := range sorted {
+			if count <= 0 {
+				break
+			}
+			period := rt.period(vmbackup.CreationTimestamp.UTC())
+			if period == lastPeriod {
+				continue
+			}
+			lastPeriod = period
+			count--
+			result[vmbackup.Name] = append(result[vmbackup.Name], rt.tier)
+		}
+	}
+	return result
+}
+
+// readyVMBackups filters the backups the retention policy applies to.
+func readyVMBackups(h *svmbackupHandler, vmbackups []*harvesterv1.VirtualMachineBackup) []*harvesterv1.VirtualMachineBackup {
+	ready := make([]*harvesterv1.VirtualMachineBackup, 0, len(vmbackups))
+	for _, vmbackup := range vmbackups {
+		if h.vmbr.IsReady(vmbackup) {
+			ready = append(ready, vmbackup)
+		}
+	}
+	return ready
+}
+
+// gcVMBackupsByRetentionPolicy clears the backups not kept by any tier of
+// `.spec.retentionPolicy`, including the failed ones. The latest backup is
+// always kept.
+func gcVMBackupsByRetentionPolicy(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup,
+	vmbackups []*harvesterv1.VirtualMachineBackup, lastVMBackup *harvesterv1.VirtualMachineBackup) error {
+	tiers := getRetentionTiers(svmbackup.Spec.RetentionPolicy, readyVMBackups(h, vmbackups))
+
+	var errs error
+	for _, vmbackup := range vmbackups {
+		if vmbackup.Name == lastVMBackup.Name || len(tiers[vmbackup.Name]) > 0 || vmbackup.DeletionTimestamp != nil {
+			continue
+		}
+		if err := cleanseVMBackup(h, vmbackup); err != nil && !apierrors.IsNotFound(err) {
+			errs = multierr.Append(errs, fmt.Errorf("svmbackup %s clear VMBackup %s failed %w", svmbackup.Name, vmbackup.Name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,61 @@
+package schedulevmbackup
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// createVerification verifies a VM backup of the schedule. The verification
+// has the name of the VM backup and is removed with it.
+func createVerification(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup, vmbackup *harvesterv1.VirtualMachineBackup) (*harvesterv1.BackupVerification, error) {
+	verification := &harvesterv1.BackupVerification{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vmbackup.Name,
+			Namespace: vmbackup.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationSVMBackupID: vmbackup.Annotations[util.AnnotationSVMBackupID],
+			},
+			Labels: map[string]string{
+				util.LabelSVMBackupUID:       string(svmbackup.UID),
+				util.LabelSVMBackupTimestamp: vmbackup.Labels[util.LabelSVMBackupTimestamp],
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(vmbackup, vmBackupKind)},
+		},
+		Spec: harvesterv1.BackupVerificationSpec{
+			VMBackupName:               vmbackup.Name,
+			BackupVerificationTemplate: *svmbackup.Spec.Verification.DeepCopy(),
+		},
+	}
+
+	return h.verificationClient.Create(verification)
+}
+
+// verifyLastVMBackup verifies the latest VM backup once it's ready. Like
+// copyLastVMBackup, the older ones aren't verified when the verification is
+// enabled later.
+func verifyLastVMBackup(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) error {
+	if svmbackup.Spec.Verification == nil {
+		return nil
+	}
+
+	_, _, lastVMBackup, _, err := currentVMBackups(h, svmbackup)
+	if err != nil {
+		return err
+	}
+
+	if lastVMBackup == nil || !h.vmbr.IsReady(lastVMBackup) || lastVMBackup.Status.Verification != nil {
+		return nil
+	}
+
+	if _, err := h.verificationCache.Get(lastVMBackup.Namespace, lastVMBackup.Name); err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := createVerification(h, svmbackup, lastVMBackup); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
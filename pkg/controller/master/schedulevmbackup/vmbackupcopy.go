@@ -0,0 +1,160 @@
+package schedulevmbackup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/multierr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func (h *svmbackupHandler) OnVMBackupCopyChange(_ string, vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	if vmBackupCopy == nil {
+		return nil, nil
+	}
+
+	svmbackup := util.ResolveSVMBackupRef(h.svmbackupCache, vmBackupCopy)
+	if svmbackup == nil {
+		return nil, nil
+	}
+
+	h.svmbackupController.Enqueue(svmbackup.Namespace, svmbackup.Name)
+	return nil, nil
+}
+
+// currentVMBackupCopies returns the copies made by the schedule, the oldest first.
+func currentVMBackupCopies(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) ([]*harvesterv1.VirtualMachineBackupCopy, error) {
+	sets := labels.Set{
+		util.LabelSVMBackupUID: string(svmbackup.UID),
+	}
+	vmBackupCopies, err := h.vmBackupCopyCache.List(svmbackup.Namespace, sets.AsSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(vmBackupCopies, func(i, j int) bool {
+		time1, _ := time.Parse(timeFormat, vmBackupCopies[i].Labels[util.LabelSVMBackupTimestamp])
+		time2, _ := time.Parse(timeFormat, vmBackupCopies[j].Labels[util.LabelSVMBackupTimestamp])
+		return time1.Before(time2)
+	})
+	return vmBackupCopies, nil
+}
+
+// createVMBackupCopy copies a VM backup of the schedule, the copy has the
+// name of the VM backup.
+func createVMBackupCopy(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup, vmbackup *harvesterv1.VirtualMachineBackup) (*harvesterv1.VirtualMachineBackupCopy, error) {
+	vmBackupCopy := &harvesterv1.VirtualMachineBackupCopy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vmbackup.Name,
+			Namespace: vmbackup.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationSVMBackupID: vmbackup.Annotations[util.AnnotationSVMBackupID],
+			},
+			Labels: map[string]string{
+				util.LabelSVMBackupUID:       string(svmbackup.UID),
+				util.LabelSVMBackupTimestamp: vmbackup.Labels[util.LabelSVMBackupTimestamp],
+			},
+		},
+		Spec: harvesterv1.VirtualMachineBackupCopySpec{
+			VMBackupName:     vmbackup.Name,
+			BackupTargetName: svmbackup.Spec.Copy.BackupTargetName,
+		},
+	}
+
+	return h.vmBackupCopyClient.Create(vmBackupCopy)
+}
+
+// copyLastVMBackup copies the latest VM backup once it's ready. Only the
+// latest one is copied, so the copies already removed by gcVMBackupCopies
+// aren't made again while the schedule keeps their VM backups.
+func copyLastVMBackup(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) error {
+	_, _, lastVMBackup, _, err := currentVMBackups(h, svmbackup)
+	if err != nil {
+		return err
+	}
+
+	if lastVMBackup == nil || !h.vmbr.IsReady(lastVMBackup) {
+		return nil
+	}
+
+	if _, err := h.vmBackupCopyCache.Get(lastVMBackup.Namespace, lastVMBackup.Name); err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := createVMBackupCopy(h, svmbackup, lastVMBackup); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// gcVMBackupCopies keeps the `.spec.copy.retain` latest copies, the failed
+// copies are removed first.
+func gcVMBackupCopies(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) error {
+	vmBackupCopies, err := currentVMBackupCopies(h, svmbackup)
+	if err != nil {
+		return err
+	}
+
+	left := len(vmBackupCopies) - svmbackup.Spec.Copy.Retain
+	if left <= 0 {
+		return nil
+	}
+
+	failed := make([]*harvesterv1.VirtualMachineBackupCopy, 0, len(vmBackupCopies))
+	others := make([]*harvesterv1.VirtualMachineBackupCopy, 0, len(vmBackupCopies))
+	for _, vmBackupCopy := range vmBackupCopies {
+		if vmBackupCopy.Status.Error != nil {
+			failed = append(failed, vmBackupCopy)
+		} else {
+			others = append(others, vmBackupCopy)
+		}
+	}
+
+	var errs error
+	for _, vmBackupCopy := range append(failed, others...) {
+		if left <= 0 {
+			break
+		}
+		left--
+		if vmBackupCopy.DeletionTimestamp != nil {
+			continue
+		}
+		if err := h.vmBackupCopyClient.Delete(vmBackupCopy.Namespace, vmBackupCopy.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			errs = multierr.Append(errs, fmt.Errorf("svmbackup %s clear VMBackupCopy %s failed %w", svmbackup.Name, vmBackupCopy.Name, err))
+		}
+	}
+	return errs
+}
+
+func updateVMBackupCopies(h *svmbackupHandler, svmbackup *harvesterv1.ScheduleVMBackup) error {
+	if svmbackup.Spec.Copy == nil {
+		return nil
+	}
+
+	var errs error
+	if err := copyLastVMBackup(h, svmbackup); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	if err := gcVMBackupCopies(h, svmbackup); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	return errs
+}
+
+// Record the VM backup copies status in `.status.vmbackupCopyInfo`
+func convertVMBackupCopyToInfo(vmBackupCopy *harvesterv1.VirtualMachineBackupCopy) harvesterv1.VMBackupCopyInfo {
+	return harvesterv1.VMBackupCopyInfo{
+		Name:         vmBackupCopy.Name,
+		VMBackupName: vmBackupCopy.Spec.VMBackupName,
+		ReadyToUse:   vmBackupCopy.Status.ReadyToUse,
+		Error:        vmBackupCopy.Status.Error,
+	}
+}
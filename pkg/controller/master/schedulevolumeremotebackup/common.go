@@ -0,0 +1,212 @@
+package schedulevolumeremotebackup
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.uber.org/multierr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+)
+
+const (
+	timeFormat = "20060102.1504"
+
+	reachMaxFailure  = "Reach Max Failure"
+	proactiveSuspend = "Proactive Schedule Suspend"
+
+	svrbackupPrefix = "svrb"
+
+	cronJobNamespace    = "harvester-system"
+	cronJobBackoffLimit = 3
+	cronJobCmd          = "sleep"
+	cronJobArg          = "10"
+)
+
+func vrbName(svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, timestamp string) string {
+	return fmt.Sprintf("%s-%s-%s", svrbackupPrefix, svrbackup.UID, timestamp)
+}
+
+// sortVolumeRemoteBackups sorts the backups of a schedule from the oldest to the latest.
+func sortVolumeRemoteBackups(vrbs []*harvesterv1.VolumeRemoteBackup) {
+	sort.SliceStable(vrbs, func(i, j int) bool {
+		time1, _ := time.Parse(timeFormat, vrbs[i].Labels[util.LabelSVRBackupTimestamp])
+		time2, _ := time.Parse(timeFormat, vrbs[j].Labels[util.LabelSVRBackupTimestamp])
+		return time1.Before(time2)
+	})
+}
+
+func currentVolumeRemoteBackups(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) ([]*harvesterv1.VolumeRemoteBackup, error) {
+	sets := labels.Set{
+		util.LabelSVRBackupUID: string(svrbackup.UID),
+	}
+	vrbs, err := h.vrbCache.List(svrbackup.Namespace, sets.AsSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	sortVolumeRemoteBackups(vrbs)
+	return vrbs, nil
+}
+
+// countFailure returns the number of the failed backups since the last successful one.
+func countFailure(bo common.BackupOperator, vrbs []*harvesterv1.VolumeRemoteBackup) int {
+	failure := 0
+	for _, vrb := range vrbs {
+		if bo.GetSuccess(vrb) {
+			failure = 0
+			continue
+		}
+		if bo.GetError(vrb) != "" {
+			failure++
+		}
+	}
+	return failure
+}
+
+// isInProgress checks if the backup neither succeeded nor failed yet.
+func isInProgress(bo common.BackupOperator, vrb *harvesterv1.VolumeRemoteBackup) bool {
+	return !bo.GetSuccess(vrb) && bo.GetError(vrb) == ""
+}
+
+// backupsToClear returns the backups to delete to keep `retain` of them, the failed backups are
+// cleared first and then the successful ones from the oldest. The latest backup is always kept.
+func backupsToClear(bo common.BackupOperator, vrbs []*harvesterv1.VolumeRemoteBackup, retain int) []*harvesterv1.VolumeRemoteBackup {
+	toClear := []*harvesterv1.VolumeRemoteBackup{}
+	left := len(vrbs) - retain
+	if left <= 0 {
+		return toClear
+	}
+
+	cleared := map[string]bool{}
+	for _, failed := range []bool{true, false} {
+		for _, vrb := range vrbs[:len(vrbs)-1] {
+			if left <= 0 {
+				return toClear
+			}
+			if cleared[vrb.Name] || (bo.GetError(vrb) != "") != failed {
+				continue
+			}
+			cleared[vrb.Name] = true
+			toClear = append(toClear, vrb)
+			left--
+		}
+	}
+	return toClear
+}
+
+func deleteVolumeRemoteBackup(h *svrbackupHandler, vrb *harvesterv1.VolumeRemoteBackup) error {
+	propagation := metav1.DeletePropagationForeground
+	return h.vrbClient.Delete(vrb.Namespace, vrb.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+func gcVolumeRemoteBackups(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, vrbs []*harvesterv1.VolumeRemoteBackup) error {
+	if len(vrbs) == 0 {
+		return nil
+	}
+
+	// only clear the backups once the latest one succeeded, the failed ones are kept to count the
+	// failures until then
+	if !h.bo.GetSuccess(vrbs[len(vrbs)-1]) {
+		return nil
+	}
+
+	var errs error
+	for _, vrb := range backupsToClear(h.bo, vrbs, svrbackup.Spec.Retain) {
+		if vrb.DeletionTimestamp != nil {
+			continue
+		}
+		if err := deleteVolumeRemoteBackup(h, vrb); err != nil && !apierrors.IsNotFound(err) {
+			errs = multierr.Append(errs, fmt.Errorf("svrbackup %s clear VolumeRemoteBackup %s failed %w", svrbackup.Name, vrb.Name, err))
+		}
+	}
+	return errs
+}
+
+// Record the backups state in `.status.volumeRemoteBackupInfo`
+func reconcileVolumeRemoteBackupList(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, vrbs []*harvesterv1.VolumeRemoteBackup) error {
+	svrbackupCpy := svrbackup.DeepCopy()
+	svrbackupCpy.Status.Failure = countFailure(h.bo, vrbs)
+	svrbackupCpy.Status.VolumeRemoteBackupInfo = make([]harvesterv1.VolumeRemoteBackupInfo, len(vrbs))
+	for i, vrb := range vrbs {
+		svrbackupCpy.Status.VolumeRemoteBackupInfo[i] = harvesterv1.VolumeRemoteBackupInfo{
+			Name:    vrb.Name,
+			Success: h.bo.GetSuccess(vrb),
+			Error:   h.bo.GetError(vrb),
+		}
+	}
+
+	if reflect.DeepEqual(svrbackup.Status, svrbackupCpy.Status) {
+		return nil
+	}
+
+	_, err := h.svrbackupClient.Update(svrbackupCpy)
+	return err
+}
+
+func updateVolumeRemoteBackups(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) error {
+	vrbs, err := currentVolumeRemoteBackups(h, svrbackup)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	if err := gcVolumeRemoteBackups(h, svrbackup, vrbs); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	if err := reconcileVolumeRemoteBackupList(h, svrbackup, vrbs); err != nil {
+		errs = multierr.Append(errs, err)
+	}
+
+	return errs
+}
+
+func createVolumeRemoteBackup(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, timestamp string) (*harvesterv1.VolumeRemoteBackup, error) {
+	vrb := &harvesterv1.VolumeRemoteBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vrbName(svrbackup, timestamp),
+			Namespace: svrbackup.Namespace,
+			Annotations: map[string]string{
+				util.AnnotationSVRBackupID: ref.Construct(svrbackup.Namespace, svrbackup.Name),
+			},
+			Labels: map[string]string{
+				util.LabelSVRBackupUID:       string(svrbackup.UID),
+				util.LabelSVRBackupTimestamp: timestamp,
+			},
+		},
+		Spec: svrbackup.Spec.VolumeRemoteBackupSpec,
+	}
+
+	return h.vrbClient.Create(vrb)
+}
+
+func newVolumeRemoteBackup(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, timestamp string) (*harvesterv1.VolumeRemoteBackup, error) {
+	vrbs, err := currentVolumeRemoteBackups(h, svrbackup)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vrbs) == 0 {
+		return createVolumeRemoteBackup(h, svrbackup, timestamp)
+	}
+
+	if failure := countFailure(h.bo, vrbs); failure >= svrbackup.Spec.MaxFailure {
+		msg := fmt.Sprintf("failure backups %v reach max tolerance %v", failure, svrbackup.Spec.MaxFailure)
+		return nil, updateSuspendState(h, svrbackup, true, reachMaxFailure, msg)
+	}
+
+	if lastVRB := vrbs[len(vrbs)-1]; isInProgress(h.bo, lastVRB) {
+		return nil, fmt.Errorf("latest volume remote backup %v/%v in progress", lastVRB.Namespace, lastVRB.Name)
+	}
+
+	return createVolumeRemoteBackup(h, svrbackup, timestamp)
+}
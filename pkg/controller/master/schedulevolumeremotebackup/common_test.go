@@ -0,0 +1,135 @@
+package schedulevolumeremotebackup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+)
+
+const (
+	stateSuccess    = "success"
+	stateFailed     = "failed"
+	stateInProgress = "inProgress"
+)
+
+func newTestVRB(name, timestamp, state string) *harvesterv1.VolumeRemoteBackup {
+	vrb := &harvesterv1.VolumeRemoteBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				util.LabelSVRBackupTimestamp: timestamp,
+			},
+		},
+	}
+	switch state {
+	case stateSuccess:
+		vrb.Status.Success = true
+	case stateFailed:
+		vrb.Status.Conditions = []harvesterv1.Condition{{
+			Type:    common.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  common.ReasonError,
+			Message: "snapshot failed",
+		}}
+	case stateInProgress:
+		vrb.Status.Conditions = []harvesterv1.Condition{{
+			Type:    common.ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Message: "Operation in progress",
+		}}
+	}
+	return vrb
+}
+
+func names(vrbs []*harvesterv1.VolumeRemoteBackup) []string {
+	result := []string{}
+	for _, vrb := range vrbs {
+		result = append(result, vrb.Name)
+	}
+	return result
+}
+
+func TestCountFailure(t *testing.T) {
+	bo := common.NewBackupOperator(nil, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		states   []string
+		expected int
+	}{
+		{
+			name:     "no backups",
+			expected: 0,
+		},
+		{
+			name:     "failures after the last success",
+			states:   []string{stateFailed, stateSuccess, stateFailed, stateInProgress, stateFailed},
+			expected: 2,
+		},
+		{
+			name:     "success resets the failures",
+			states:   []string{stateFailed, stateFailed, stateSuccess},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		vrbs := []*harvesterv1.VolumeRemoteBackup{}
+		for i, state := range tc.states {
+			vrbs = append(vrbs, newTestVRB(string(rune('a'+i)), "", state))
+		}
+		assert.Equal(t, tc.expected, countFailure(bo, vrbs), tc.name)
+	}
+}
+
+func TestBackupsToClear(t *testing.T) {
+	bo := common.NewBackupOperator(nil, nil, nil, nil)
+
+	vrbs := []*harvesterv1.VolumeRemoteBackup{
+		newTestVRB("d", "20260104.0000", stateSuccess),
+		newTestVRB("b", "20260102.0000", stateFailed),
+		newTestVRB("a", "20260101.0000", stateSuccess),
+		newTestVRB("c", "20260103.0000", stateSuccess),
+		newTestVRB("e", "20260105.0000", stateSuccess),
+	}
+	sortVolumeRemoteBackups(vrbs)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names(vrbs))
+
+	tests := []struct {
+		name     string
+		retain   int
+		expected []string
+	}{
+		{
+			name:     "nothing to clear",
+			retain:   5,
+			expected: []string{},
+		},
+		{
+			name:     "failed backups are cleared first",
+			retain:   4,
+			expected: []string{"b"},
+		},
+		{
+			name:     "successful backups are cleared from the oldest",
+			retain:   2,
+			expected: []string{"b", "a", "c"},
+		},
+		{
+			name:     "the latest backup is kept",
+			retain:   0,
+			expected: []string{"b", "a", "c", "d"},
+		},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, names(backupsToClear(bo, vrbs, tc.retain)), tc.name)
+	}
+}
@@ -0,0 +1,43 @@
+package schedulevolumeremotebackup
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func (h *svrbackupHandler) OnCronjobChanged(_ string, cronJob *batchv1.CronJob) (*batchv1.CronJob, error) {
+	if cronJob == nil || cronJob.DeletionTimestamp != nil || cronJob.Status.LastScheduleTime == nil {
+		return cronJob, nil
+	}
+
+	svrbackup := util.ResolveSVRBackupRef(h.svrbackupCache, cronJob)
+	if svrbackup == nil {
+		return nil, nil
+	}
+
+	// the last schedule time is out-of-date if the schedule was suspended and resumed,
+	// wait for the next schedule then
+	if time.Since(cronJob.Status.LastScheduleTime.Time) > time.Minute {
+		return nil, nil
+	}
+
+	timestamp := cronJob.Status.LastScheduleTime.Format(timeFormat)
+	_, err := h.vrbCache.Get(svrbackup.Namespace, vrbName(svrbackup, timestamp))
+	if err == nil {
+		return cronJob, nil
+	}
+
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if _, err := newVolumeRemoteBackup(h, svrbackup, timestamp); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,59 @@
+package schedulevolumeremotebackup
+
+import (
+	"context"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvester/harvester/pkg/config"
+	ctlharvbatchv1 "github.com/harvester/harvester/pkg/generated/controllers/batch/v1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+)
+
+const (
+	scheduleVolumeRemoteBackupControllerName = "schedule-volume-remote-backup-controller"
+	svrbackupCronJobControllerName           = "schedule-volume-remote-backup-cron-job-controller"
+	svrbackupVRBControllerName               = "schedule-volume-remote-backup-vrb-controller"
+)
+
+type svrbackupHandler struct {
+	svrbackupController ctlharvesterv1.ScheduleVolumeRemoteBackupController
+	svrbackupClient     ctlharvesterv1.ScheduleVolumeRemoteBackupClient
+	svrbackupCache      ctlharvesterv1.ScheduleVolumeRemoteBackupCache
+	cronJobsClient      ctlharvbatchv1.CronJobClient
+	cronJobCache        ctlharvbatchv1.CronJobCache
+	vrbClient           ctlharvesterv1.VolumeRemoteBackupClient
+	vrbCache            ctlharvesterv1.VolumeRemoteBackupCache
+	bo                  common.BackupOperator
+	namespace           string
+	clientset           kubernetes.Interface
+}
+
+func Register(ctx context.Context, management *config.Management, options config.Options) error {
+	svrbackups := management.HarvesterFactory.Harvesterhci().V1beta1().ScheduleVolumeRemoteBackup()
+	cronJobs := management.HarvesterBatchFactory.Batch().V1().CronJob()
+	vrbs := management.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteBackup()
+	pvcs := management.CoreFactory.Core().V1().PersistentVolumeClaim()
+	scs := management.StorageFactory.Storage().V1().StorageClass()
+	settings := management.HarvesterFactory.Harvesterhci().V1beta1().Setting()
+
+	handler := &svrbackupHandler{
+		svrbackupController: svrbackups,
+		svrbackupClient:     svrbackups,
+		svrbackupCache:      svrbackups.Cache(),
+		cronJobsClient:      cronJobs,
+		cronJobCache:        cronJobs.Cache(),
+		vrbClient:           vrbs,
+		vrbCache:            vrbs.Cache(),
+		bo:                  common.NewBackupOperator(vrbs, pvcs.Cache(), scs.Cache(), settings.Cache()),
+		namespace:           options.Namespace,
+		clientset:           management.ClientSet,
+	}
+
+	svrbackups.OnChange(ctx, scheduleVolumeRemoteBackupControllerName, handler.OnChanged)
+	svrbackups.OnRemove(ctx, scheduleVolumeRemoteBackupControllerName, handler.OnRemove)
+	cronJobs.OnChange(ctx, svrbackupCronJobControllerName, handler.OnCronjobChanged)
+	vrbs.OnChange(ctx, svrbackupVRBControllerName, handler.OnVolumeRemoteBackupChange)
+	return nil
+}
@@ -0,0 +1,233 @@
+package schedulevolumeremotebackup
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/util"
+	utilHelm "github.com/harvester/harvester/pkg/util/helm"
+)
+
+const (
+	releaseAppHarvesterName = "harvester"
+)
+
+func cronJobName(svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) string {
+	return fmt.Sprintf("%s-%s", svrbackupPrefix, svrbackup.UID)
+}
+
+func getCronJob(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) (*batchv1.CronJob, error) {
+	return h.cronJobCache.Get(cronJobNamespace, cronJobName(svrbackup))
+}
+
+func deleteCronJob(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) error {
+	cronJob, err := getCronJob(h, svrbackup)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationForeground
+	return h.cronJobsClient.Delete(cronJob.Namespace, cronJob.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+// The cronjob doesn't do anything in its own job, it's utilized to trigger OnCronjobChanged()
+// which creates the VolumeRemoteBackup of the schedule.
+func createCronJob(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) (*batchv1.CronJob, error) {
+	backoffLimit := int32(cronJobBackoffLimit)
+	jobImage, err := utilHelm.FetchImageFromHelmValues(h.clientset, h.namespace,
+		releaseAppHarvesterName, []string{"generalJob", "image"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get harvester image (%s): %v", jobImage.ImageName(), err)
+	}
+
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJobName(svrbackup),
+			Namespace: cronJobNamespace,
+			Annotations: map[string]string{
+				util.AnnotationSVRBackupID: ref.Construct(svrbackup.Namespace, svrbackup.Name),
+			},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          svrbackup.Spec.Cron,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: cronJobName(svrbackup),
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{
+									Name:            cronJobName(svrbackup),
+									Image:           jobImage.ImageName(),
+									Command:         []string{cronJobCmd},
+									Args:            []string{cronJobArg},
+									ImagePullPolicy: corev1.PullIfNotPresent,
+								},
+							},
+							RestartPolicy: corev1.RestartPolicyNever,
+						},
+					},
+				},
+			},
+		},
+	}
+	return h.cronJobsClient.Create(cronJob)
+}
+
+func updateCronExpression(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) error {
+	cronJob, err := getCronJob(h, svrbackup)
+	if err != nil {
+		return err
+	}
+
+	if cronJob.Spec.Schedule == svrbackup.Spec.Cron {
+		return nil
+	}
+
+	cronJobCpy := cronJob.DeepCopy()
+	cronJobCpy.Spec.Schedule = svrbackup.Spec.Cron
+	_, err = h.cronJobsClient.Update(cronJobCpy)
+	return err
+}
+
+func updateSuspendState(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup, suspend bool, reason, msg string) error {
+	cronJob, err := getCronJob(h, svrbackup)
+	if err != nil {
+		return err
+	}
+
+	cronJobCpy := cronJob.DeepCopy()
+	cronJobCpy.Spec.Suspend = &suspend
+	if !reflect.DeepEqual(cronJob, cronJobCpy) {
+		if _, err := h.cronJobsClient.Update(cronJobCpy); err != nil {
+			return err
+		}
+	}
+
+	svrbackupCpy := svrbackup.DeepCopy()
+	svrbackupCpy.Spec.Suspend = suspend
+	svrbackupCpy.Status.Suspended = suspend
+	if suspend {
+		harvesterv1.BackupSuspend.True(svrbackupCpy)
+	} else {
+		harvesterv1.BackupSuspend.False(svrbackupCpy)
+	}
+	harvesterv1.BackupSuspend.Reason(svrbackupCpy, reason)
+	harvesterv1.BackupSuspend.Message(svrbackupCpy, msg)
+
+	if reflect.DeepEqual(svrbackup, svrbackupCpy) {
+		return nil
+	}
+
+	_, err = h.svrbackupClient.Update(svrbackupCpy)
+	return err
+}
+
+func handleResume(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) error {
+	vrbs, err := currentVolumeRemoteBackups(h, svrbackup)
+	if err != nil {
+		return err
+	}
+
+	if countFailure(h.bo, vrbs) < svrbackup.Spec.MaxFailure {
+		return updateSuspendState(h, svrbackup, false, "", "")
+	}
+
+	// remove the failed backups to resume the schedule
+	for _, vrb := range vrbs {
+		if h.bo.GetError(vrb) == "" {
+			continue
+		}
+		if err := deleteVolumeRemoteBackup(h, vrb); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("svrbackup %s/%s retry handle resume", svrbackup.Namespace, svrbackup.Name)
+}
+
+func updateResumeOrSuspend(h *svrbackupHandler, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) error {
+	if svrbackup.Spec.Suspend == svrbackup.Status.Suspended {
+		return nil
+	}
+
+	if svrbackup.Spec.Suspend {
+		return updateSuspendState(h, svrbackup, true, proactiveSuspend, proactiveSuspend)
+	}
+
+	return handleResume(h, svrbackup)
+}
+
+func (h *svrbackupHandler) OnChanged(_ string, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) (*harvesterv1.ScheduleVolumeRemoteBackup, error) {
+	if svrbackup == nil || svrbackup.DeletionTimestamp != nil {
+		return svrbackup, nil
+	}
+
+	defer func() {
+		if err := updateVolumeRemoteBackups(h, svrbackup); err != nil {
+			logrus.Infof("OnChanged svrbackup %v/%v update volume remote backups err %v", svrbackup.Namespace, svrbackup.Name, err)
+		}
+	}()
+
+	_, err := getCronJob(h, svrbackup)
+	if errors.IsNotFound(err) {
+		if _, err := createCronJob(h, svrbackup); err != nil {
+			return nil, err
+		}
+
+		h.svrbackupController.Enqueue(svrbackup.Namespace, svrbackup.Name)
+		return svrbackup, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateResumeOrSuspend(h, svrbackup); err != nil {
+		return nil, err
+	}
+
+	if err := updateCronExpression(h, svrbackup); err != nil {
+		return nil, err
+	}
+
+	return svrbackup, nil
+}
+
+func (h *svrbackupHandler) OnRemove(_ string, svrbackup *harvesterv1.ScheduleVolumeRemoteBackup) (*harvesterv1.ScheduleVolumeRemoteBackup, error) {
+	if svrbackup == nil {
+		return nil, nil
+	}
+
+	return svrbackup, deleteCronJob(h, svrbackup)
+}
+
+func (h *svrbackupHandler) OnVolumeRemoteBackupChange(_ string, vrb *harvesterv1.VolumeRemoteBackup) (*harvesterv1.VolumeRemoteBackup, error) {
+	if vrb == nil || vrb.DeletionTimestamp != nil {
+		return vrb, nil
+	}
+
+	svrbackup := util.ResolveSVRBackupRef(h.svrbackupCache, vrb)
+	if svrbackup == nil {
+		return vrb, nil
+	}
+
+	h.svrbackupController.Enqueue(svrbackup.Namespace, svrbackup.Name)
+	return vrb, nil
+}
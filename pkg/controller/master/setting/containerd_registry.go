@@ -25,7 +25,7 @@ import (
 
 const (
 	containerdRegistrySecretsNamespace = util.FleetLocalNamespaceName
-	containerdRegistrySecretDataHost   = "host"
+	containerdRegistrySecretDataHost   = util.ContainerdRegistryConfigSecretDataHost
 )
 
 func (h *Handler) syncContainerdRegistry(setting *harvesterv1.Setting) error {
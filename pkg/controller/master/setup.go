@@ -10,17 +10,21 @@ import (
 	"github.com/harvester/harvester/pkg/controller/master/addon"
 	"github.com/harvester/harvester/pkg/controller/master/backup"
 	"github.com/harvester/harvester/pkg/controller/master/image"
+	"github.com/harvester/harvester/pkg/controller/master/imagesyncpolicy"
 	"github.com/harvester/harvester/pkg/controller/master/keypair"
 	"github.com/harvester/harvester/pkg/controller/master/kubevirt"
 	"github.com/harvester/harvester/pkg/controller/master/machine"
+	"github.com/harvester/harvester/pkg/controller/master/maintenancecampaign"
 	"github.com/harvester/harvester/pkg/controller/master/mcmsettings"
 	"github.com/harvester/harvester/pkg/controller/master/migration"
 	"github.com/harvester/harvester/pkg/controller/master/node"
 	"github.com/harvester/harvester/pkg/controller/master/nodedrain"
 	"github.com/harvester/harvester/pkg/controller/master/pvc"
 	"github.com/harvester/harvester/pkg/controller/master/rancher"
+	"github.com/harvester/harvester/pkg/controller/master/rebalancer"
 	"github.com/harvester/harvester/pkg/controller/master/resourcequota"
 	"github.com/harvester/harvester/pkg/controller/master/schedulevmbackup"
+	"github.com/harvester/harvester/pkg/controller/master/schedulevolumeremotebackup"
 	"github.com/harvester/harvester/pkg/controller/master/setting"
 	"github.com/harvester/harvester/pkg/controller/master/storageclass"
 	"github.com/harvester/harvester/pkg/controller/master/storagenetwork"
@@ -29,6 +33,7 @@ import (
 	"github.com/harvester/harvester/pkg/controller/master/upgrade"
 	"github.com/harvester/harvester/pkg/controller/master/upgradelog"
 	"github.com/harvester/harvester/pkg/controller/master/virtualmachine"
+	"github.com/harvester/harvester/pkg/controller/master/vmgroup"
 	"github.com/harvester/harvester/pkg/controller/master/vmimagedownloader"
 	pvcbackup "github.com/harvester/harvester/pkg/controller/master/volumeremotebackup"
 )
@@ -38,14 +43,20 @@ type registerFunc func(context.Context, *config.Management, config.Options) erro
 var registerFuncs = []registerFunc{
 	addon.Register,
 	backup.RegisterBackup,
+	backup.RegisterBackupCopy,
+	backup.RegisterBackupVerification,
+	backup.RegisterBackupBrowse,
 	backup.RegisterBackupBackingImage,
 	backup.RegisterBackupMetadata,
 	backup.RegisterBackupTarget,
 	backup.RegisterRestore,
 	image.Register,
+	imagesyncpolicy.Register,
+	rebalancer.Register,
 	keypair.Register,
 	kubevirt.Register,
 	machine.ControlPlaneRegister,
+	maintenancecampaign.Register,
 	mcmsettings.Register,
 	migration.Register,
 	node.CPUManagerRegister,
@@ -60,6 +71,7 @@ var registerFuncs = []registerFunc{
 	rancher.Register,
 	resourcequota.Register,
 	schedulevmbackup.Register,
+	schedulevolumeremotebackup.Register,
 	setting.Register,
 	storageclass.Register,
 	storagenetwork.Register,
@@ -68,6 +80,7 @@ var registerFuncs = []registerFunc{
 	upgrade.Register,
 	upgradelog.Register,
 	virtualmachine.Register,
+	vmgroup.Register,
 	vmimagedownloader.Register,
 	node.ConditionAnnotationRegister,
 	pvcbackup.Register,
@@ -0,0 +1,33 @@
+package vmgroup
+
+import (
+	"context"
+
+	"github.com/harvester/harvester/pkg/config"
+	vmgrouputil "github.com/harvester/harvester/pkg/util/vmgroup"
+)
+
+const (
+	vmGroupControllerName   = "vm-group-controller"
+	vmGroupVMControllerName = "vm-group-vm-controller"
+)
+
+func Register(ctx context.Context, management *config.Management, _ config.Options) error {
+	groups := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup()
+	vms := management.VirtFactory.Kubevirt().V1().VirtualMachine()
+	vmis := management.VirtFactory.Kubevirt().V1().VirtualMachineInstance()
+
+	handler := &vmGroupHandler{
+		groupController: groups,
+		groupClient:     groups,
+		groupCache:      groups.Cache(),
+		vmClient:        vms,
+		vmCache:         vms.Cache(),
+		vmiCache:        vmis.Cache(),
+		prober:          vmgrouputil.NewLauncherPortProber(management.ClientSet, management.RestConfig),
+	}
+
+	groups.OnChange(ctx, vmGroupControllerName, handler.OnChanged)
+	vms.OnChange(ctx, vmGroupVMControllerName, handler.OnVMChanged)
+	return nil
+}
@@ -0,0 +1,344 @@
+package vmgroup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+	vmgrouputil "github.com/harvester/harvester/pkg/util/vmgroup"
+)
+
+const (
+	defaultTimeout = 10 * time.Minute
+	pollInterval   = 5 * time.Second
+)
+
+type vmGroupHandler struct {
+	groupController ctlharvesterv1.VirtualMachineGroupController
+	groupClient     ctlharvesterv1.VirtualMachineGroupClient
+	groupCache      ctlharvesterv1.VirtualMachineGroupCache
+	vmClient        ctlkubevirtv1.VirtualMachineClient
+	vmCache         ctlkubevirtv1.VirtualMachineCache
+	vmiCache        ctlkubevirtv1.VirtualMachineInstanceCache
+	prober          vmgrouputil.PortProber
+}
+
+// OnChanged runs the requested action of the group stage by stage, and starts the members which were
+// stopped for maintenance mode in the order of their boot order.
+func (h *vmGroupHandler) OnChanged(_ string, group *harvesterv1.VirtualMachineGroup) (*harvesterv1.VirtualMachineGroup, error) {
+	if group == nil || group.DeletionTimestamp != nil {
+		return group, nil
+	}
+
+	toUpdate := group.DeepCopy()
+	requeue, err := h.reconcile(toUpdate)
+	if err != nil {
+		return group, err
+	}
+
+	updated := group
+	if !equality.Semantic.DeepEqual(group.Status, toUpdate.Status) {
+		if updated, err = h.groupClient.UpdateStatus(toUpdate); err != nil {
+			return group, err
+		}
+	}
+	if requeue {
+		h.groupController.EnqueueAfter(group.Namespace, group.Name, pollInterval)
+	}
+	return updated, nil
+}
+
+// OnVMChanged enqueues the group of a VM which is waiting to be started.
+func (h *vmGroupHandler) OnVMChanged(_ string, vm *kubevirtv1.VirtualMachine) (*kubevirtv1.VirtualMachine, error) {
+	if vm == nil || vm.DeletionTimestamp != nil || vm.Annotations[util.AnnotationVMGroupPendingStart] != "true" {
+		return vm, nil
+	}
+	group, _, err := vmgrouputil.FindMember(h.groupCache, vm.Namespace, vm.Name)
+	if err != nil || group == nil {
+		return vm, err
+	}
+	h.groupController.Enqueue(group.Namespace, group.Name)
+	return vm, nil
+}
+
+// reconcile returns whether the group has to be checked again.
+func (h *vmGroupHandler) reconcile(group *harvesterv1.VirtualMachineGroup) (bool, error) {
+	switch group.Status.Phase {
+	case harvesterv1.VirtualMachineGroupPhaseStopping:
+		done, err := h.stop(group)
+		if err != nil || !done {
+			return true, err
+		}
+		if group.Status.Action == harvesterv1.VirtualMachineGroupActionRestart {
+			group.Status.Phase = harvesterv1.VirtualMachineGroupPhaseStarting
+			return true, nil
+		}
+		complete(group, harvesterv1.VirtualMachineGroupPhaseSucceeded, "")
+		return false, nil
+	case harvesterv1.VirtualMachineGroupPhaseStarting:
+		done, err := h.start(group, false)
+		if err != nil || !done {
+			return true, err
+		}
+		complete(group, harvesterv1.VirtualMachineGroupPhaseSucceeded, "")
+		return false, nil
+	default:
+		pending, err := h.hasPendingMembers(group)
+		if err != nil || !pending {
+			return false, err
+		}
+		done, err := h.start(group, true)
+		return !done, err
+	}
+}
+
+// stop stops the members in the reverse order of their boot order, it returns whether all members
+// are stopped.
+func (h *vmGroupHandler) stop(group *harvesterv1.VirtualMachineGroup) (bool, error) {
+	orders := vmgrouputil.BootOrders(group)
+	if timedOut(group, len(orders)) {
+		complete(group, harvesterv1.VirtualMachineGroupPhaseFailed, "timed out waiting for the members to stop")
+		return false, nil
+	}
+
+	statuses := make(map[string]harvesterv1.VirtualMachineGroupMemberStatus, len(group.Spec.Members))
+	stopping := false
+	for i := len(orders) - 1; i >= 0; i-- {
+		for _, member := range group.Spec.Members {
+			if member.BootOrder != orders[i] {
+				continue
+			}
+			if stopping {
+				statuses[member.Name] = memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberWaiting, "")
+				continue
+			}
+
+			status, err := h.stopMember(group.Namespace, member.Name)
+			if err != nil {
+				return false, err
+			}
+			statuses[member.Name] = status
+		}
+		for _, status := range statuses {
+			if status.State == harvesterv1.VirtualMachineGroupMemberStopping {
+				stopping = true
+			}
+		}
+	}
+	setMemberStatuses(group, statuses)
+	return !stopping, nil
+}
+
+func (h *vmGroupHandler) stopMember(namespace, name string) (harvesterv1.VirtualMachineGroupMemberStatus, error) {
+	vm, err := h.vmCache.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return memberStatus(name, harvesterv1.VirtualMachineGroupMemberStopped, "the VM is not found"), nil
+	} else if err != nil {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, err
+	}
+
+	runStrategy, err := vm.RunStrategy()
+	if err != nil {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, err
+	}
+	if runStrategy != kubevirtv1.RunStrategyHalted || vm.Annotations[util.AnnotationVMGroupPendingStart] != "" {
+		vmCopy := vm.DeepCopy()
+		vmCopy.Spec.Running = nil
+		vmCopy.Spec.RunStrategy = &[]kubevirtv1.VirtualMachineRunStrategy{kubevirtv1.RunStrategyHalted}[0]
+		delete(vmCopy.Annotations, util.AnnotationVMGroupPendingStart)
+		if _, err := h.vmClient.Update(vmCopy); err != nil {
+			return harvesterv1.VirtualMachineGroupMemberStatus{}, fmt.Errorf("failed to stop VM %s/%s: %w", namespace, name, err)
+		}
+		logrus.WithFields(logrus.Fields{
+			"namespace":           namespace,
+			"virtualmachine_name": name,
+		}).Info("stopping the VM of a group")
+	}
+
+	if _, err := h.vmiCache.Get(namespace, name); err == nil {
+		return memberStatus(name, harvesterv1.VirtualMachineGroupMemberStopping, ""), nil
+	} else if !apierrors.IsNotFound(err) {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, err
+	}
+	return memberStatus(name, harvesterv1.VirtualMachineGroupMemberStopped, ""), nil
+}
+
+// start starts the members in the order of their boot order, a member is started once the members
+// with a lower boot order are ready and its start delay has passed. If pendingOnly is set, only the
+// members waiting to be started after maintenance mode are started and the stopped members don't
+// block the members with a higher boot order. It returns whether all started members are ready.
+func (h *vmGroupHandler) start(group *harvesterv1.VirtualMachineGroup, pendingOnly bool) (bool, error) {
+	orders := vmgrouputil.BootOrders(group)
+	if !pendingOnly && timedOut(group, len(orders)) {
+		complete(group, harvesterv1.VirtualMachineGroupPhaseFailed, "timed out waiting for the members to become ready")
+		return false, nil
+	}
+
+	statuses := make(map[string]harvesterv1.VirtualMachineGroupMemberStatus, len(group.Spec.Members))
+	// when the members with a lower boot order became ready
+	var readySince time.Time
+	blocked := false
+	for _, order := range orders {
+		stageReady := true
+		stageReadySince := readySince
+		for i := range group.Spec.Members {
+			member := &group.Spec.Members[i]
+			if member.BootOrder != order {
+				continue
+			}
+
+			status, since, err := h.startMember(group.Namespace, member, pendingOnly, blocked, readySince)
+			if err != nil {
+				return false, err
+			}
+			statuses[member.Name] = status
+			switch {
+			case status.State == harvesterv1.VirtualMachineGroupMemberReady:
+				if since.After(stageReadySince) {
+					stageReadySince = since
+				}
+			case status.State != harvesterv1.VirtualMachineGroupMemberStopped:
+				stageReady = false
+			}
+		}
+		if !stageReady {
+			blocked = true
+		}
+		readySince = stageReadySince
+	}
+	setMemberStatuses(group, statuses)
+	return !blocked, nil
+}
+
+// startMember returns the status of the member and when it became ready.
+func (h *vmGroupHandler) startMember(namespace string, member *harvesterv1.VirtualMachineGroupMember, pendingOnly, blocked bool, readySince time.Time) (harvesterv1.VirtualMachineGroupMemberStatus, time.Time, error) {
+	vm, err := h.vmCache.Get(namespace, member.Name)
+	if apierrors.IsNotFound(err) {
+		return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberWaiting, "the VM is not found"), time.Time{}, nil
+	} else if err != nil {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, time.Time{}, err
+	}
+
+	runStrategy, err := vm.RunStrategy()
+	if err != nil {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, time.Time{}, err
+	}
+	pending := vm.Annotations[util.AnnotationVMGroupPendingStart] == "true"
+	halted := runStrategy == kubevirtv1.RunStrategyHalted
+	if pendingOnly && halted && !pending {
+		return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberStopped, ""), time.Time{}, nil
+	}
+
+	if halted || pending {
+		if blocked {
+			return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberWaiting, "waiting for the members with a lower boot order"), time.Time{}, nil
+		}
+		if member.StartDelay != nil {
+			if wait := time.Until(readySince.Add(member.StartDelay.Duration)); wait > 0 {
+				return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberWaiting,
+					fmt.Sprintf("starting in %s", wait.Round(time.Second))), time.Time{}, nil
+			}
+		}
+		if err := h.startVM(vm, halted); err != nil {
+			return harvesterv1.VirtualMachineGroupMemberStatus{}, time.Time{}, err
+		}
+		return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberStarting, ""), time.Time{}, nil
+	}
+
+	vmi, err := h.vmiCache.Get(namespace, member.Name)
+	if apierrors.IsNotFound(err) {
+		return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberStarting, "the VM is not running"), time.Time{}, nil
+	} else if err != nil {
+		return harvesterv1.VirtualMachineGroupMemberStatus{}, time.Time{}, err
+	}
+	if ready, message := vmgrouputil.IsMemberReady(vmi, member, h.prober); !ready {
+		return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberStarting, message), time.Time{}, nil
+	}
+	return memberStatus(member.Name, harvesterv1.VirtualMachineGroupMemberReady, ""), vmgrouputil.ReadySince(vmi), nil
+}
+
+// startVM restores the run strategy the VM had before it was stopped, like a VM which was stopped
+// for maintenance mode.
+func (h *vmGroupHandler) startVM(vm *kubevirtv1.VirtualMachine, halted bool) error {
+	vmCopy := vm.DeepCopy()
+	delete(vmCopy.Annotations, util.AnnotationVMGroupPendingStart)
+	if halted {
+		runStrategy := kubevirtv1.VirtualMachineRunStrategy(vm.Annotations[util.AnnotationRunStrategy])
+		if runStrategy == "" || runStrategy == kubevirtv1.RunStrategyHalted {
+			runStrategy = kubevirtv1.RunStrategyRerunOnFailure
+		}
+		vmCopy.Spec.Running = nil
+		vmCopy.Spec.RunStrategy = &runStrategy
+		logrus.WithFields(logrus.Fields{
+			"namespace":           vm.Namespace,
+			"virtualmachine_name": vm.Name,
+		}).Info("starting the VM of a group")
+	}
+	if _, err := h.vmClient.Update(vmCopy); err != nil {
+		return fmt.Errorf("failed to start VM %s/%s: %w", vm.Namespace, vm.Name, err)
+	}
+	return nil
+}
+
+func (h *vmGroupHandler) hasPendingMembers(group *harvesterv1.VirtualMachineGroup) (bool, error) {
+	for _, member := range group.Spec.Members {
+		vm, err := h.vmCache.Get(group.Namespace, member.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if vm.Annotations[util.AnnotationVMGroupPendingStart] == "true" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// timedOut checks whether the action takes longer than the timeout for each boot order.
+func timedOut(group *harvesterv1.VirtualMachineGroup, stages int) bool {
+	if group.Status.StartTime == nil {
+		return false
+	}
+	timeout := defaultTimeout
+	if group.Spec.Timeout != nil && group.Spec.Timeout.Duration > 0 {
+		timeout = group.Spec.Timeout.Duration
+	}
+	for _, member := range group.Spec.Members {
+		if member.StartDelay != nil {
+			timeout += member.StartDelay.Duration
+		}
+	}
+	return time.Since(group.Status.StartTime.Time) > timeout*time.Duration(stages)
+}
+
+func complete(group *harvesterv1.VirtualMachineGroup, phase harvesterv1.VirtualMachineGroupPhase, message string) {
+	now := metav1.Now()
+	group.Status.Phase = phase
+	group.Status.Message = message
+	group.Status.CompletionTime = &now
+}
+
+func memberStatus(name string, state harvesterv1.VirtualMachineGroupMemberState, message string) harvesterv1.VirtualMachineGroupMemberStatus {
+	return harvesterv1.VirtualMachineGroupMemberStatus{Name: name, State: state, Message: message}
+}
+
+// setMemberStatuses sets the member statuses in the order of the members of the spec.
+func setMemberStatuses(group *harvesterv1.VirtualMachineGroup, statuses map[string]harvesterv1.VirtualMachineGroupMemberStatus) {
+	members := make([]harvesterv1.VirtualMachineGroupMemberStatus, 0, len(group.Spec.Members))
+	for _, member := range group.Spec.Members {
+		if status, ok := statuses[member.Name]; ok {
+			members = append(members, status)
+		}
+	}
+	group.Status.Members = members
+}
@@ -0,0 +1,175 @@
+package vmgroup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+const testNamespace = "default"
+
+func newVM(name string, runStrategy kubevirtv1.VirtualMachineRunStrategy) *kubevirtv1.VirtualMachine {
+	return &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   testNamespace,
+			Annotations: map[string]string{util.AnnotationRunStrategy: string(kubevirtv1.RunStrategyAlways)},
+		},
+		Spec: kubevirtv1.VirtualMachineSpec{RunStrategy: &runStrategy},
+	}
+}
+
+func newReadyVMI(name string) *kubevirtv1.VirtualMachineInstance {
+	return &kubevirtv1.VirtualMachineInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: testNamespace},
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase: kubevirtv1.Running,
+			Conditions: []kubevirtv1.VirtualMachineInstanceCondition{
+				{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()},
+			},
+		},
+	}
+}
+
+func newGroup(phase harvesterv1.VirtualMachineGroupPhase) *harvesterv1.VirtualMachineGroup {
+	now := metav1.Now()
+	return &harvesterv1.VirtualMachineGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: testNamespace},
+		Spec: harvesterv1.VirtualMachineGroupSpec{
+			Members: []harvesterv1.VirtualMachineGroupMember{
+				{Name: "app", BootOrder: 1},
+				{Name: "db", BootOrder: 0},
+			},
+		},
+		Status: harvesterv1.VirtualMachineGroupStatus{Phase: phase, StartTime: &now},
+	}
+}
+
+func newHandler(objects ...runtime.Object) (*vmGroupHandler, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	return &vmGroupHandler{
+		groupClient: fakeclients.VirtualMachineGroupClient(clientset.HarvesterhciV1beta1().VirtualMachineGroups),
+		groupCache:  fakeclients.VirtualMachineGroupCache(clientset.HarvesterhciV1beta1().VirtualMachineGroups),
+		vmClient:    fakeclients.VirtualMachineClient(clientset.KubevirtV1().VirtualMachines),
+		vmCache:     fakeclients.VirtualMachineCache(clientset.KubevirtV1().VirtualMachines),
+		vmiCache:    fakeclients.VirtualMachineInstanceCache(clientset.KubevirtV1().VirtualMachineInstances),
+	}, clientset
+}
+
+func getRunStrategy(t *testing.T, clientset *fake.Clientset, name string) kubevirtv1.VirtualMachineRunStrategy {
+	vm, err := clientset.KubevirtV1().VirtualMachines(testNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return *vm.Spec.RunStrategy
+}
+
+func memberState(group *harvesterv1.VirtualMachineGroup, name string) harvesterv1.VirtualMachineGroupMemberState {
+	for _, member := range group.Status.Members {
+		if member.Name == name {
+			return member.State
+		}
+	}
+	return ""
+}
+
+func TestStartInBootOrder(t *testing.T) {
+	group := newGroup(harvesterv1.VirtualMachineGroupPhaseStarting)
+	h, clientset := newHandler(newVM("app", kubevirtv1.RunStrategyHalted), newVM("db", kubevirtv1.RunStrategyHalted))
+
+	requeue, err := h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, kubevirtv1.RunStrategyAlways, getRunStrategy(t, clientset, "db"))
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, getRunStrategy(t, clientset, "app"))
+	assert.Equal(t, harvesterv1.VirtualMachineGroupMemberStarting, memberState(group, "db"))
+	assert.Equal(t, harvesterv1.VirtualMachineGroupMemberWaiting, memberState(group, "app"))
+
+	_, err = clientset.KubevirtV1().VirtualMachineInstances(testNamespace).Create(context.TODO(), newReadyVMI("db"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	requeue, err = h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, kubevirtv1.RunStrategyAlways, getRunStrategy(t, clientset, "app"))
+	assert.Equal(t, harvesterv1.VirtualMachineGroupMemberReady, memberState(group, "db"))
+
+	_, err = clientset.KubevirtV1().VirtualMachineInstances(testNamespace).Create(context.TODO(), newReadyVMI("app"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	requeue, err = h.reconcile(group)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+	assert.Equal(t, harvesterv1.VirtualMachineGroupPhaseSucceeded, group.Status.Phase)
+	assert.NotNil(t, group.Status.CompletionTime)
+}
+
+func TestStartDelay(t *testing.T) {
+	group := newGroup(harvesterv1.VirtualMachineGroupPhaseStarting)
+	group.Spec.Members[0].StartDelay = &metav1.Duration{Duration: time.Hour}
+	h, clientset := newHandler(newVM("app", kubevirtv1.RunStrategyHalted), newVM("db", kubevirtv1.RunStrategyAlways), newReadyVMI("db"))
+
+	requeue, err := h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, getRunStrategy(t, clientset, "app"))
+	assert.Equal(t, harvesterv1.VirtualMachineGroupMemberWaiting, memberState(group, "app"))
+}
+
+func TestStopInReverseBootOrder(t *testing.T) {
+	group := newGroup(harvesterv1.VirtualMachineGroupPhaseStopping)
+	group.Status.Action = harvesterv1.VirtualMachineGroupActionRestart
+	h, clientset := newHandler(
+		newVM("app", kubevirtv1.RunStrategyAlways), newVM("db", kubevirtv1.RunStrategyAlways),
+		newReadyVMI("app"), newReadyVMI("db"),
+	)
+
+	requeue, err := h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, getRunStrategy(t, clientset, "app"))
+	assert.Equal(t, kubevirtv1.RunStrategyAlways, getRunStrategy(t, clientset, "db"))
+
+	assert.NoError(t, clientset.KubevirtV1().VirtualMachineInstances(testNamespace).Delete(context.TODO(), "app", metav1.DeleteOptions{}))
+	_, err = h.reconcile(group)
+	assert.NoError(t, err)
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, getRunStrategy(t, clientset, "db"))
+	assert.Equal(t, harvesterv1.VirtualMachineGroupPhaseStopping, group.Status.Phase)
+
+	// the restart starts the members once all of them are stopped
+	assert.NoError(t, clientset.KubevirtV1().VirtualMachineInstances(testNamespace).Delete(context.TODO(), "db", metav1.DeleteOptions{}))
+	requeue, err = h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, harvesterv1.VirtualMachineGroupPhaseStarting, group.Status.Phase)
+}
+
+func TestStartPendingMembers(t *testing.T) {
+	group := newGroup("")
+	app := newVM("app", kubevirtv1.RunStrategyHalted)
+	app.Annotations[util.AnnotationVMGroupPendingStart] = "true"
+	// the stopped db isn't waiting to be started, so it doesn't block the app
+	h, clientset := newHandler(app, newVM("db", kubevirtv1.RunStrategyHalted))
+
+	requeue, err := h.reconcile(group)
+	assert.NoError(t, err)
+	assert.True(t, requeue)
+	assert.Equal(t, kubevirtv1.RunStrategyAlways, getRunStrategy(t, clientset, "app"))
+	assert.Equal(t, kubevirtv1.RunStrategyHalted, getRunStrategy(t, clientset, "db"))
+
+	vm, err := clientset.KubevirtV1().VirtualMachines(testNamespace).Get(context.TODO(), "app", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotContains(t, vm.Annotations, util.AnnotationVMGroupPendingStart)
+
+	// nothing is done once no member is waiting to be started
+	requeue, err = h.reconcile(group)
+	assert.NoError(t, err)
+	assert.False(t, requeue)
+}
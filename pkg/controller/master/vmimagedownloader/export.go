@@ -0,0 +1,271 @@
+package vmimagedownloader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	exportPollInterval = 10 * time.Second
+	sourcePVCSuffix    = "-source"
+	exportJobSuffix    = "export"
+	// labelImageExport points the export Job and its pods back to the downloader.
+	labelImageExport = "harvesterhci.io/vmimage-downloader-export"
+)
+
+func getCompressType(downloader *harvesterv1.VirtualMachineImageDownloader) string {
+	if downloader.Spec.CompressType == "" {
+		return harvesterv1.ImageDownloaderCompressTypeQCOW2
+	}
+	return downloader.Spec.CompressType
+}
+
+func isExportFinished(downloader *harvesterv1.VirtualMachineImageDownloader) bool {
+	if downloader.Spec.Export == nil || downloader.Status.Export == nil {
+		return false
+	}
+	return downloader.Status.Export.Phase == harvesterv1.ImageExportPhaseCompleted ||
+		downloader.Status.Export.Phase == harvesterv1.ImageExportPhaseFailed
+}
+
+// getSourcePVC returns the volume the downloader reads the image from. The cdi backend keeps the image
+// in a PVC named after the image, the backing image backend needs a volume created from the backing image.
+func (h *vmImageDownloaderHandler) getSourcePVC(vmImage *harvesterv1.VirtualMachineImage, downloader *harvesterv1.VirtualMachineImageDownloader) (*corev1.PersistentVolumeClaim, error) {
+	if util.GetVMIBackend(vmImage) == harvesterv1.VMIBackendCDI {
+		pvc, err := h.pvcCache.Get(downloader.Namespace, vmImage.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pvc %s: %v", vmImage.Name, err)
+		}
+		return pvc, nil
+	}
+
+	pvcName := downloader.Name + sourcePVCSuffix
+	pvc, err := h.pvcCache.Get(downloader.Namespace, pvcName)
+	if err == nil {
+		return pvc, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get pvc %s: %v", pvcName, err)
+	}
+
+	if !harvesterv1.ImageImported.IsTrue(vmImage) {
+		return nil, fmt.Errorf("vm image %s/%s is not imported yet", vmImage.Namespace, vmImage.Name)
+	}
+	if util.IsImageEncrypted(vmImage) {
+		return nil, fmt.Errorf("vm image %s/%s is encrypted, which can not be downloaded", vmImage.Namespace, vmImage.Name)
+	}
+
+	size := vmImage.Status.VirtualSize
+	if size == 0 {
+		size = vmImage.Status.Size
+	}
+	volumeMode := corev1.PersistentVolumeBlock
+	pvc = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pvcName,
+			Namespace: downloader.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         harvesterv1.SchemeGroupVersion.String(),
+					Kind:               kindVirtualMachineImageDownloader,
+					Name:               downloader.Name,
+					UID:                downloader.UID,
+					BlockOwnerDeletion: &boolTrue,
+				},
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &vmImage.Status.StorageClassName,
+			VolumeMode:       &volumeMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: *resource.NewQuantity(size, resource.BinarySI),
+				},
+			},
+		},
+	}
+	return h.pvcClient.Create(pvc)
+}
+
+// syncExport pushes the image to the export target with a Job, so the transfer neither runs
+// with the privileges of the controller nor from its network, and reports the result of the Job.
+func (h *vmImageDownloaderHandler) syncExport(downloader *harvesterv1.VirtualMachineImageDownloader, vmImage *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImageDownloader, error) {
+	jobName := getExportJobName(downloader)
+	job, err := h.jobCache.Get(downloader.Namespace, jobName)
+	if apierrors.IsNotFound(err) {
+		return h.startExport(downloader, vmImage, jobName)
+	} else if err != nil {
+		return downloader, err
+	}
+
+	finished, failure := datamover.IsJobFinished(job)
+	if !finished {
+		h.vmImageDownloaderController.EnqueueAfter(downloader.Namespace, downloader.Name, exportPollInterval)
+		return downloader, nil
+	}
+
+	downloaderCpy := downloader.DeepCopy()
+	if failure != "" {
+		logrus.Errorf("failed to export vm image downloader %s/%s: %s", downloader.Namespace, downloader.Name, failure)
+		setExportFailed(downloaderCpy, errors.New(failure))
+		return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
+	}
+	result, err := h.getExportResult(job)
+	if err != nil {
+		setExportFailed(downloaderCpy, err)
+		return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
+	}
+	logrus.Infof("Exported vm image downloader %s/%s", downloader.Namespace, downloader.Name)
+	setExportCompleted(downloaderCpy, result)
+	return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
+}
+
+// startExport creates the network policy confining the export Job and the Job itself.
+func (h *vmImageDownloaderHandler) startExport(downloader *harvesterv1.VirtualMachineImageDownloader, vmImage *harvesterv1.VirtualMachineImage, jobName string) (*harvesterv1.VirtualMachineImageDownloader, error) {
+	uploader, err := h.newExportUploader(downloader, vmImage)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to export vm image downloader %s/%s", downloader.Namespace, downloader.Name)
+		downloaderCpy := downloader.DeepCopy()
+		setExportFailed(downloaderCpy, err)
+		return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
+	}
+
+	blockedCIDRs, err := export.ClusterCIDRs(h.ctx, h.clientSet)
+	if err != nil {
+		return downloader, err
+	}
+	image, err := datamover.GetImage(h.clientSet)
+	if err != nil {
+		return downloader, fmt.Errorf("failed to get data mover image: %w", err)
+	}
+
+	ownerRefs := []metav1.OwnerReference{*metav1.NewControllerRef(downloader, harvesterv1.SchemeGroupVersion.WithKind(kindVirtualMachineImageDownloader))}
+	labels := map[string]string{labelImageExport: downloader.Name}
+	sourceLabels := map[string]string{"app": getDeploymentName(downloader)}
+	policy := export.BuildNetworkPolicy(jobName, downloader.Namespace, ownerRefs, labels, sourceLabels, blockedCIDRs)
+	if _, err := h.clientSet.NetworkingV1().NetworkPolicies(policy.Namespace).Create(h.ctx, policy, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return downloader, fmt.Errorf("failed to create network policy %s/%s: %w", policy.Namespace, policy.Name, err)
+	}
+
+	job, err := export.BuildJob(export.JobOptions{
+		Name:            jobName,
+		Namespace:       downloader.Namespace,
+		Labels:          labels,
+		OwnerReferences: ownerRefs,
+		Image:           image,
+		Command:         datamover.BinaryName,
+		Target:          downloader.Spec.Export,
+		SourceURL:       downloader.Status.DownloadURL,
+		DefaultKey:      getExportDefaultKey(downloader, vmImage),
+		BlockedCIDRs:    blockedCIDRs,
+	})
+	if err != nil {
+		return downloader, err
+	}
+	logrus.Infof("Exporting vm image downloader %s/%s to %s", downloader.Namespace, downloader.Name, uploader.URL())
+	if _, err := h.jobs.Create(job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return downloader, fmt.Errorf("failed to create export job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+
+	downloaderCpy := downloader.DeepCopy()
+	downloaderCpy.Status.Export = &harvesterv1.VirtualMachineImageExportStatus{
+		Phase: harvesterv1.ImageExportPhaseInProgress,
+		URL:   uploader.URL(),
+	}
+	downloaderCpy.Status.Conditions = updateConds(downloaderCpy.Status.Conditions, harvesterv1.VirtualMachineImageDownloaderCondition{
+		Type:               harvesterv1.DownloaderCondsExported,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "VM Image Exporting",
+		Message:            fmt.Sprintf("Uploading the image to %s", uploader.URL()),
+	})
+	return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
+}
+
+// newExportUploader validates the export target and returns its uploader, the controller only uses it for the target URL.
+func (h *vmImageDownloaderHandler) newExportUploader(downloader *harvesterv1.VirtualMachineImageDownloader, vmImage *harvesterv1.VirtualMachineImage) (export.Uploader, error) {
+	target := downloader.Spec.Export
+	var data map[string][]byte
+	if target.SecretName != "" {
+		secret, err := h.secretCache.Get(downloader.Namespace, target.SecretName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get export secret %s: %v", target.SecretName, err)
+		}
+		data = secret.Data
+	}
+	return export.NewUploader(target, data, getExportDefaultKey(downloader, vmImage), export.NewHTTPClient(export.DefaultBlockedNetworks()))
+}
+
+// getExportResult returns the result the succeeded pod of the Job reported.
+func (h *vmImageDownloaderHandler) getExportResult(job *batchv1.Job) (*export.Result, error) {
+	pods, err := h.podCache.List(job.Namespace, labels.SelectorFromSet(labels.Set{batchv1.JobNameLabel: job.Name}))
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded {
+			return export.ParseResult(pod)
+		}
+	}
+	return nil, fmt.Errorf("export job %s/%s has no succeeded pod", job.Namespace, job.Name)
+}
+
+func getExportJobName(downloader *harvesterv1.VirtualMachineImageDownloader) string {
+	return name.SafeConcatName(downloader.Name, exportJobSuffix)
+}
+
+func getExportDefaultKey(downloader *harvesterv1.VirtualMachineImageDownloader, vmImage *harvesterv1.VirtualMachineImage) string {
+	return fmt.Sprintf("%s/%s.%s", vmImage.Namespace, vmImage.Name, getCompressType(downloader))
+}
+
+func setExportCompleted(downloader *harvesterv1.VirtualMachineImageDownloader, result *export.Result) {
+	if downloader.Status.Export == nil {
+		downloader.Status.Export = &harvesterv1.VirtualMachineImageExportStatus{}
+	}
+	now := metav1.Now()
+	downloader.Status.Export.Phase = harvesterv1.ImageExportPhaseCompleted
+	downloader.Status.Export.Progress = 100
+	downloader.Status.Export.UploadedBytes = result.Size
+	downloader.Status.Export.TotalBytes = result.Size
+	downloader.Status.Export.Checksum = result.Checksum
+	downloader.Status.Export.Message = ""
+	downloader.Status.Export.CompletionTime = &now
+	downloader.Status.Conditions = updateConds(downloader.Status.Conditions, harvesterv1.VirtualMachineImageDownloaderCondition{
+		Type:               harvesterv1.DownloaderCondsExported,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: now,
+		Reason:             "VM Image Exported",
+		Message:            fmt.Sprintf("The image is uploaded to %s", downloader.Status.Export.URL),
+	})
+}
+
+func setExportFailed(downloader *harvesterv1.VirtualMachineImageDownloader, err error) {
+	if downloader.Status.Export == nil {
+		downloader.Status.Export = &harvesterv1.VirtualMachineImageExportStatus{}
+	}
+	downloader.Status.Export.Phase = harvesterv1.ImageExportPhaseFailed
+	downloader.Status.Export.Message = err.Error()
+	downloader.Status.Conditions = updateConds(downloader.Status.Conditions, harvesterv1.VirtualMachineImageDownloaderCondition{
+		Type:               harvesterv1.DownloaderCondsExported,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "VM Image Export Failed",
+		Message:            err.Error(),
+	})
+}
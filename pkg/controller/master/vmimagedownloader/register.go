@@ -18,18 +18,27 @@ func Register(ctx context.Context, management *config.Management, _ config.Optio
 	vmImageDownloader := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImageDownloader()
 	vmImage := management.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImage()
 	deployment := management.AppsFactory.Apps().V1().Deployment()
-	pvcCache := management.CoreFactory.Core().V1().PersistentVolumeClaim().Cache()
+	pvcs := management.CoreFactory.Core().V1().PersistentVolumeClaim()
+	secretCache := management.CoreFactory.Core().V1().Secret().Cache()
 	scCache := management.StorageFactory.Storage().V1().StorageClass().Cache()
+	jobs := management.BatchFactory.Batch().V1().Job()
+	podCache := management.CoreFactory.Core().V1().Pod().Cache()
 	clientSet := management.ClientSet
 
 	storageProfileHandler := &vmImageDownloaderHandler{
+		ctx:                         ctx,
 		clientSet:                   clientSet,
 		vmImageClient:               vmImage,
-		pvcCache:                    pvcCache,
+		pvcClient:                   pvcs,
+		pvcCache:                    pvcs.Cache(),
+		secretCache:                 secretCache,
 		scCache:                     scCache,
 		deploymentClient:            deployment,
 		vmImageDownloaders:          vmImageDownloader,
 		vmImageDownloaderController: vmImageDownloader,
+		jobs:                        jobs,
+		jobCache:                    jobs.Cache(),
+		podCache:                    podCache,
 	}
 
 	vmImageDownloader.OnChange(ctx, vmImageDownloaderControllerName, storageProfileHandler.OnChanged)
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	ctlappsv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/apps/v1"
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 	"github.com/rancher/wrangler/v3/pkg/relatedresource"
@@ -34,17 +35,23 @@ var (
 
 // storageProfileHandler dynamically manages storage profiles
 type vmImageDownloaderHandler struct {
+	ctx                         context.Context
 	clientSet                   kubernetes.Interface
+	pvcClient                   ctlcorev1.PersistentVolumeClaimClient
 	pvcCache                    ctlcorev1.PersistentVolumeClaimCache
+	secretCache                 ctlcorev1.SecretCache
 	scCache                     ctlstoragev1.StorageClassCache
 	vmImageClient               v1beta1.VirtualMachineImageClient
 	deploymentClient            ctlappsv1.DeploymentClient
 	vmImageDownloaders          v1beta1.VirtualMachineImageDownloaderClient
 	vmImageDownloaderController v1beta1.VirtualMachineImageDownloaderController
+	jobs                        ctlbatchv1.JobClient
+	jobCache                    ctlbatchv1.JobCache
+	podCache                    ctlcorev1.PodCache
 }
 
 func (h *vmImageDownloaderHandler) OnChanged(_ string, downloader *harvesterv1.VirtualMachineImageDownloader) (*harvesterv1.VirtualMachineImageDownloader, error) {
-	if downloader == nil || downloader.DeletionTimestamp != nil {
+	if downloader == nil || downloader.DeletionTimestamp != nil || isExportFinished(downloader) {
 		return downloader, nil
 	}
 
@@ -59,7 +66,7 @@ func (h *vmImageDownloaderHandler) OnChanged(_ string, downloader *harvesterv1.V
 		return downloader, err
 	}
 
-	deploymentName := fmt.Sprintf("%s-downloader", downloader.Name)
+	deploymentName := getDeploymentName(downloader)
 	// get or create deployment
 	deployment, err := h.getOrCreateDownloaderDeployment(deploymentName, vmImage, downloader)
 	if err != nil {
@@ -115,7 +122,7 @@ func (h *vmImageDownloaderHandler) OnChanged(_ string, downloader *harvesterv1.V
 	if _, err := h.clientSet.CoreV1().Services(downloader.Namespace).Create(context.TODO(), serviceTemplate, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
 		return downloader, fmt.Errorf("failed to create the download server service with VM Image(%s): %v", downloader.Name, err)
 	}
-	downloadURL := fmt.Sprintf("http://%s.%s/images/%s.%s", deploymentName, downloader.Namespace, vmImage.Name, downloader.Spec.CompressType)
+	downloadURL := fmt.Sprintf("http://%s.%s/images/%s.%s", deploymentName, downloader.Namespace, vmImage.Name, getCompressType(downloader))
 	downloaderCpy := downloader.DeepCopy()
 	conds := harvesterv1.VirtualMachineImageDownloaderCondition{
 		Type:               harvesterv1.DownloaderCondsReady,
@@ -131,6 +138,10 @@ func (h *vmImageDownloaderHandler) OnChanged(_ string, downloader *harvesterv1.V
 	if !reflect.DeepEqual(downloader, downloaderCpy) {
 		return h.vmImageDownloaders.UpdateStatus(downloaderCpy)
 	}
+
+	if downloader.Spec.Export != nil {
+		return h.syncExport(downloader, vmImage)
+	}
 	return downloader, nil
 }
 
@@ -139,7 +150,7 @@ func (h *vmImageDownloaderHandler) OnRemoved(_ string, downloader *harvesterv1.V
 		return nil, nil
 	}
 
-	deploymentName := fmt.Sprintf("%s-downloader", downloader.Name)
+	deploymentName := getDeploymentName(downloader)
 	if err := h.deploymentClient.Delete(downloader.Namespace, deploymentName, &metav1.DeleteOptions{}); err != nil {
 		if apierrors.IsNotFound(err) {
 			logrus.Infof("The corresponding deployment %s not found, skip this", deploymentName)
@@ -158,6 +169,10 @@ func (h *vmImageDownloaderHandler) OnRemoved(_ string, downloader *harvesterv1.V
 	return downloader, nil
 }
 
+func getDeploymentName(downloader *harvesterv1.VirtualMachineImageDownloader) string {
+	return fmt.Sprintf("%s-downloader", downloader.Name)
+}
+
 func (h *vmImageDownloaderHandler) getOrCreateDownloaderDeployment(deploymentName string, vmImage *harvesterv1.VirtualMachineImage, downloader *harvesterv1.VirtualMachineImageDownloader) (*appsv1.Deployment, error) {
 	deployment, err := h.deploymentClient.Get(downloader.Namespace, deploymentName, metav1.GetOptions{})
 	if err != nil {
@@ -171,16 +186,16 @@ func (h *vmImageDownloaderHandler) getOrCreateDownloaderDeployment(deploymentNam
 }
 
 func (h *vmImageDownloaderHandler) createDownloaderDeployment(deploymentName string, vmImage *harvesterv1.VirtualMachineImage, downloader *harvesterv1.VirtualMachineImageDownloader) (*appsv1.Deployment, error) {
-	pvc, err := h.pvcCache.Get(downloader.Namespace, vmImage.Name)
+	pvc, err := h.getSourcePVC(vmImage, downloader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pvc %s: %v", vmImage.Name, err)
+		return nil, err
 	}
 	if pvc.Spec.VolumeMode == nil {
-		return nil, fmt.Errorf("failed to get volume mode of pvc %s", vmImage.Name)
+		return nil, fmt.Errorf("failed to get volume mode of pvc %s", pvc.Name)
 	}
 	volMode := pvc.Spec.VolumeMode
 	clusterRepoImageStr := h.getClusterRepoImage()
-	initContainer := h.genInitContainer(volMode, vmImage.Name)
+	initContainer := h.genInitContainer(volMode, vmImage.Name, getCompressType(downloader))
 	affinity := h.getAffinity(pvc)
 
 	replicaNum := int32(1)
@@ -218,7 +233,7 @@ func (h *vmImageDownloaderHandler) createDownloaderDeployment(deploymentName str
 							Name: "image-vol",
 							VolumeSource: corev1.VolumeSource{
 								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-									ClaimName: vmImage.Name,
+									ClaimName: pvc.Name,
 								},
 							},
 						},
@@ -361,7 +376,7 @@ func (h *vmImageDownloaderHandler) ReconcileDeploymentOwners(_ string, _ string,
 	return nil, nil
 }
 
-func (h *vmImageDownloaderHandler) genInitContainer(mode *corev1.PersistentVolumeMode, vmImageName string) corev1.Container {
+func (h *vmImageDownloaderHandler) genInitContainer(mode *corev1.PersistentVolumeMode, vmImageName, compressType string) corev1.Container {
 	virtImageStr := h.getVirtHandlerImage()
 	initContainer := corev1.Container{
 		Name:            "image-coverter",
@@ -394,7 +409,14 @@ func (h *vmImageDownloaderHandler) genInitContainer(mode *corev1.PersistentVolum
 			MountPath: targetImgVolPath,
 		})
 	}
-	convertCmd := fmt.Sprintf("qemu-img convert -t none -T none -W -m 8 -f raw %s -O qcow2 -c -S 4K /image-dir/%s.qcow2", convertSrcPath, vmImageName)
+	imageFile := fmt.Sprintf("/image-dir/%s.qcow2", vmImageName)
+	convertCmd := fmt.Sprintf("qemu-img convert -t none -T none -W -m 8 -f raw %s -O qcow2 -c -S 4K %s", convertSrcPath, imageFile)
+	if compressType == harvesterv1.ImageDownloaderCompressTypeRaw {
+		imageFile = fmt.Sprintf("/image-dir/%s.raw", vmImageName)
+		convertCmd = fmt.Sprintf("qemu-img convert -t none -T none -W -m 8 -f raw %s -O raw -S 4K %s", convertSrcPath, imageFile)
+	}
+	// publish the checksum next to the image file, peer clusters importing the image verify it
+	convertCmd = fmt.Sprintf("%s && sha512sum %s | cut -d ' ' -f 1 > %s.sha512", convertCmd, imageFile, imageFile)
 
 	initContainer.Args = []string{convertCmd}
 	return initContainer
@@ -412,6 +434,10 @@ func updateConds(curConds []harvesterv1.VirtualMachineImageDownloaderCondition,
 	}
 
 	if found {
+		// keep the transition time if nothing changed, so that reconciling does not update the status again
+		if curConds[pod].Status == c.Status && curConds[pod].Reason == c.Reason && curConds[pod].Message == c.Message {
+			c.LastTransitionTime = curConds[pod].LastTransitionTime
+		}
 		curConds[pod] = c
 	} else {
 		curConds = append(curConds, c)
@@ -12,6 +12,7 @@ import (
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
 	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/csi"
 	"github.com/harvester/harvester/pkg/volumeremotebackup/driver"
 	"github.com/harvester/harvester/pkg/volumeremotebackup/longhorn"
 )
@@ -239,6 +240,15 @@ func registerBackupHandlers(
 			pvcs.Cache(),
 			scs.Cache(),
 		),
+		harvesterv1.VolumeRemoteBackupCSI: csi.GetCSIBackupOperation(
+			bo,
+			vss.Cache(),
+			vss,
+			vsClasses.Cache(),
+			vscs.Cache(),
+			pvcs.Cache(),
+			scs.Cache(),
+		),
 	}
 
 	remotebackupHandler := &remoteBackupHandler{
@@ -285,6 +295,19 @@ func registerRestoreHandlers(
 			pvcs,
 			scs.Cache(),
 		),
+		harvesterv1.VolumeRemoteRestoreCSI: csi.GetCSIRestoreOperation(
+			ro,
+			bo,
+			vrbs.Cache(),
+			vss.Cache(),
+			vss,
+			vsClasses.Cache(),
+			vscs.Cache(),
+			vscs,
+			pvcs.Cache(),
+			pvcs,
+			scs.Cache(),
+		),
 	}
 
 	remoteRestoreHandler := &remoteRestoreHandler{
@@ -27,6 +27,9 @@ func createCRDs(ctx context.Context, restConfig *rest.Config) error {
 	return factory.
 		BatchCreateCRDsIfNotExisted(
 			crd.NonNamespacedFromGV(harvesterv1.SchemeGroupVersion, "Setting", harvesterv1.Setting{}),
+			crd.NonNamespacedFromGV(harvesterv1.SchemeGroupVersion, "BackupTarget", harvesterv1.BackupTarget{}),
+			crd.NonNamespacedFromGV(harvesterv1.SchemeGroupVersion, "RebalancePolicy", harvesterv1.RebalancePolicy{}).WithStatus(),
+			crd.NonNamespacedFromGV(harvesterv1.SchemeGroupVersion, "NodeMaintenanceCampaign", harvesterv1.NodeMaintenanceCampaign{}).WithStatus(),
 			crd.NonNamespacedFromGV(rancherv3.SchemeGroupVersion, "APIService", rancherv3.APIService{}),
 			crd.NonNamespacedFromGV(rancherv3.SchemeGroupVersion, "Setting", rancherv3.Setting{}),
 			crd.NonNamespacedFromGV(rancherv3.SchemeGroupVersion, "User", rancherv3.User{}),
@@ -47,6 +50,12 @@ func createCRDs(ctx context.Context, restConfig *rest.Config) error {
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineTemplate", harvesterv1.VirtualMachineTemplate{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineTemplateVersion", harvesterv1.VirtualMachineTemplateVersion{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineBackup", harvesterv1.VirtualMachineBackup{}),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineBackupCopy", harvesterv1.VirtualMachineBackupCopy{}),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "BackupVerification", harvesterv1.BackupVerification{}),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "BackupBrowseSession", harvesterv1.BackupBrowseSession{}),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "ImageSyncPolicy", harvesterv1.ImageSyncPolicy{}).WithStatus(),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineGroup", harvesterv1.VirtualMachineGroup{}).WithStatus(),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "VMPlacementPolicy", harvesterv1.VMPlacementPolicy{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VirtualMachineRestore", harvesterv1.VirtualMachineRestore{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "Preference", harvesterv1.Preference{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "SupportBundle", harvesterv1.SupportBundle{}),
@@ -54,6 +63,7 @@ func createCRDs(ctx context.Context, restConfig *rest.Config) error {
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "ScheduleVMBackup", harvesterv1.ScheduleVMBackup{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VolumeRemoteBackup", harvesterv1.VolumeRemoteBackup{}),
 			crd.FromGV(harvesterv1.SchemeGroupVersion, "VolumeRemoteRestore", harvesterv1.VolumeRemoteRestore{}),
+			crd.FromGV(harvesterv1.SchemeGroupVersion, "ScheduleVolumeRemoteBackup", harvesterv1.ScheduleVolumeRemoteBackup{}),
 			// The BackingImage struct is not compatible with wrangler schemas generation, pass nil as the workaround.
 			// The expected CRD will be applied by Longhorn chart.
 			crd.FromGV(lhv1beta2.SchemeGroupVersion, "BackingImage", nil),
@@ -32,14 +32,38 @@ func (c *FakeHarvesterhciV1beta1) Addons(namespace string) v1beta1.AddonInterfac
 	return newFakeAddons(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) BackupBrowseSessions(namespace string) v1beta1.BackupBrowseSessionInterface {
+	return newFakeBackupBrowseSessions(c, namespace)
+}
+
+func (c *FakeHarvesterhciV1beta1) BackupTargets() v1beta1.BackupTargetInterface {
+	return newFakeBackupTargets(c)
+}
+
+func (c *FakeHarvesterhciV1beta1) BackupVerifications(namespace string) v1beta1.BackupVerificationInterface {
+	return newFakeBackupVerifications(c, namespace)
+}
+
+func (c *FakeHarvesterhciV1beta1) ImageSyncPolicies(namespace string) v1beta1.ImageSyncPolicyInterface {
+	return newFakeImageSyncPolicies(c, namespace)
+}
+
 func (c *FakeHarvesterhciV1beta1) KeyPairs(namespace string) v1beta1.KeyPairInterface {
 	return newFakeKeyPairs(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) NodeMaintenanceCampaigns() v1beta1.NodeMaintenanceCampaignInterface {
+	return newFakeNodeMaintenanceCampaigns(c)
+}
+
 func (c *FakeHarvesterhciV1beta1) Preferences(namespace string) v1beta1.PreferenceInterface {
 	return newFakePreferences(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) RebalancePolicies() v1beta1.RebalancePolicyInterface {
+	return newFakeRebalancePolicies(c)
+}
+
 func (c *FakeHarvesterhciV1beta1) ResourceQuotas(namespace string) v1beta1.ResourceQuotaInterface {
 	return newFakeResourceQuotas(c, namespace)
 }
@@ -48,6 +72,10 @@ func (c *FakeHarvesterhciV1beta1) ScheduleVMBackups(namespace string) v1beta1.Sc
 	return newFakeScheduleVMBackups(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) ScheduleVolumeRemoteBackups(namespace string) v1beta1.ScheduleVolumeRemoteBackupInterface {
+	return newFakeScheduleVolumeRemoteBackups(c, namespace)
+}
+
 func (c *FakeHarvesterhciV1beta1) Settings() v1beta1.SettingInterface {
 	return newFakeSettings(c)
 }
@@ -64,6 +92,10 @@ func (c *FakeHarvesterhciV1beta1) UpgradeLogs(namespace string) v1beta1.UpgradeL
 	return newFakeUpgradeLogs(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) VMPlacementPolicies(namespace string) v1beta1.VMPlacementPolicyInterface {
+	return newFakeVMPlacementPolicies(c, namespace)
+}
+
 func (c *FakeHarvesterhciV1beta1) Versions(namespace string) v1beta1.VersionInterface {
 	return newFakeVersions(c, namespace)
 }
@@ -72,6 +104,14 @@ func (c *FakeHarvesterhciV1beta1) VirtualMachineBackups(namespace string) v1beta
 	return newFakeVirtualMachineBackups(c, namespace)
 }
 
+func (c *FakeHarvesterhciV1beta1) VirtualMachineBackupCopies(namespace string) v1beta1.VirtualMachineBackupCopyInterface {
+	return newFakeVirtualMachineBackupCopies(c, namespace)
+}
+
+func (c *FakeHarvesterhciV1beta1) VirtualMachineGroups(namespace string) v1beta1.VirtualMachineGroupInterface {
+	return newFakeVirtualMachineGroups(c, namespace)
+}
+
 func (c *FakeHarvesterhciV1beta1) VirtualMachineImages(namespace string) v1beta1.VirtualMachineImageInterface {
 	return newFakeVirtualMachineImages(c, namespace)
 }
@@ -20,14 +20,28 @@ package v1beta1
 
 type AddonExpansion interface{}
 
+type BackupBrowseSessionExpansion interface{}
+
+type BackupTargetExpansion interface{}
+
+type BackupVerificationExpansion interface{}
+
+type ImageSyncPolicyExpansion interface{}
+
 type KeyPairExpansion interface{}
 
+type NodeMaintenanceCampaignExpansion interface{}
+
 type PreferenceExpansion interface{}
 
+type RebalancePolicyExpansion interface{}
+
 type ResourceQuotaExpansion interface{}
 
 type ScheduleVMBackupExpansion interface{}
 
+type ScheduleVolumeRemoteBackupExpansion interface{}
+
 type SettingExpansion interface{}
 
 type SupportBundleExpansion interface{}
@@ -36,10 +50,16 @@ type UpgradeExpansion interface{}
 
 type UpgradeLogExpansion interface{}
 
+type VMPlacementPolicyExpansion interface{}
+
 type VersionExpansion interface{}
 
 type VirtualMachineBackupExpansion interface{}
 
+type VirtualMachineBackupCopyExpansion interface{}
+
+type VirtualMachineGroupExpansion interface{}
+
 type VirtualMachineImageExpansion interface{}
 
 type VirtualMachineImageDownloaderExpansion interface{}
@@ -29,16 +29,26 @@ import (
 type HarvesterhciV1beta1Interface interface {
 	RESTClient() rest.Interface
 	AddonsGetter
+	BackupBrowseSessionsGetter
+	BackupTargetsGetter
+	BackupVerificationsGetter
+	ImageSyncPoliciesGetter
 	KeyPairsGetter
+	NodeMaintenanceCampaignsGetter
 	PreferencesGetter
+	RebalancePoliciesGetter
 	ResourceQuotasGetter
 	ScheduleVMBackupsGetter
+	ScheduleVolumeRemoteBackupsGetter
 	SettingsGetter
 	SupportBundlesGetter
 	UpgradesGetter
 	UpgradeLogsGetter
+	VMPlacementPoliciesGetter
 	VersionsGetter
 	VirtualMachineBackupsGetter
+	VirtualMachineBackupCopiesGetter
+	VirtualMachineGroupsGetter
 	VirtualMachineImagesGetter
 	VirtualMachineImageDownloadersGetter
 	VirtualMachineRestoresGetter
@@ -57,14 +67,38 @@ func (c *HarvesterhciV1beta1Client) Addons(namespace string) AddonInterface {
 	return newAddons(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) BackupBrowseSessions(namespace string) BackupBrowseSessionInterface {
+	return newBackupBrowseSessions(c, namespace)
+}
+
+func (c *HarvesterhciV1beta1Client) BackupTargets() BackupTargetInterface {
+	return newBackupTargets(c)
+}
+
+func (c *HarvesterhciV1beta1Client) BackupVerifications(namespace string) BackupVerificationInterface {
+	return newBackupVerifications(c, namespace)
+}
+
+func (c *HarvesterhciV1beta1Client) ImageSyncPolicies(namespace string) ImageSyncPolicyInterface {
+	return newImageSyncPolicies(c, namespace)
+}
+
 func (c *HarvesterhciV1beta1Client) KeyPairs(namespace string) KeyPairInterface {
 	return newKeyPairs(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) NodeMaintenanceCampaigns() NodeMaintenanceCampaignInterface {
+	return newNodeMaintenanceCampaigns(c)
+}
+
 func (c *HarvesterhciV1beta1Client) Preferences(namespace string) PreferenceInterface {
 	return newPreferences(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) RebalancePolicies() RebalancePolicyInterface {
+	return newRebalancePolicies(c)
+}
+
 func (c *HarvesterhciV1beta1Client) ResourceQuotas(namespace string) ResourceQuotaInterface {
 	return newResourceQuotas(c, namespace)
 }
@@ -73,6 +107,10 @@ func (c *HarvesterhciV1beta1Client) ScheduleVMBackups(namespace string) Schedule
 	return newScheduleVMBackups(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) ScheduleVolumeRemoteBackups(namespace string) ScheduleVolumeRemoteBackupInterface {
+	return newScheduleVolumeRemoteBackups(c, namespace)
+}
+
 func (c *HarvesterhciV1beta1Client) Settings() SettingInterface {
 	return newSettings(c)
 }
@@ -89,6 +127,10 @@ func (c *HarvesterhciV1beta1Client) UpgradeLogs(namespace string) UpgradeLogInte
 	return newUpgradeLogs(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) VMPlacementPolicies(namespace string) VMPlacementPolicyInterface {
+	return newVMPlacementPolicies(c, namespace)
+}
+
 func (c *HarvesterhciV1beta1Client) Versions(namespace string) VersionInterface {
 	return newVersions(c, namespace)
 }
@@ -97,6 +139,14 @@ func (c *HarvesterhciV1beta1Client) VirtualMachineBackups(namespace string) Virt
 	return newVirtualMachineBackups(c, namespace)
 }
 
+func (c *HarvesterhciV1beta1Client) VirtualMachineBackupCopies(namespace string) VirtualMachineBackupCopyInterface {
+	return newVirtualMachineBackupCopies(c, namespace)
+}
+
+func (c *HarvesterhciV1beta1Client) VirtualMachineGroups(namespace string) VirtualMachineGroupInterface {
+	return newVirtualMachineGroups(c, namespace)
+}
+
 func (c *HarvesterhciV1beta1Client) VirtualMachineImages(namespace string) VirtualMachineImageInterface {
 	return newVirtualMachineImages(c, namespace)
 }
@@ -32,16 +32,26 @@ func init() {
 
 type Interface interface {
 	Addon() AddonController
+	BackupBrowseSession() BackupBrowseSessionController
+	BackupTarget() BackupTargetController
+	BackupVerification() BackupVerificationController
+	ImageSyncPolicy() ImageSyncPolicyController
 	KeyPair() KeyPairController
+	NodeMaintenanceCampaign() NodeMaintenanceCampaignController
 	Preference() PreferenceController
+	RebalancePolicy() RebalancePolicyController
 	ResourceQuota() ResourceQuotaController
 	ScheduleVMBackup() ScheduleVMBackupController
+	ScheduleVolumeRemoteBackup() ScheduleVolumeRemoteBackupController
 	Setting() SettingController
 	SupportBundle() SupportBundleController
 	Upgrade() UpgradeController
 	UpgradeLog() UpgradeLogController
+	VMPlacementPolicy() VMPlacementPolicyController
 	Version() VersionController
 	VirtualMachineBackup() VirtualMachineBackupController
+	VirtualMachineBackupCopy() VirtualMachineBackupCopyController
+	VirtualMachineGroup() VirtualMachineGroupController
 	VirtualMachineImage() VirtualMachineImageController
 	VirtualMachineImageDownloader() VirtualMachineImageDownloaderController
 	VirtualMachineRestore() VirtualMachineRestoreController
@@ -65,14 +75,38 @@ func (v *version) Addon() AddonController {
 	return generic.NewController[*v1beta1.Addon, *v1beta1.AddonList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "Addon"}, "addons", true, v.controllerFactory)
 }
 
+func (v *version) BackupBrowseSession() BackupBrowseSessionController {
+	return generic.NewController[*v1beta1.BackupBrowseSession, *v1beta1.BackupBrowseSessionList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "BackupBrowseSession"}, "backupbrowsesessions", true, v.controllerFactory)
+}
+
+func (v *version) BackupTarget() BackupTargetController {
+	return generic.NewNonNamespacedController[*v1beta1.BackupTarget, *v1beta1.BackupTargetList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "BackupTarget"}, "backuptargets", v.controllerFactory)
+}
+
+func (v *version) BackupVerification() BackupVerificationController {
+	return generic.NewController[*v1beta1.BackupVerification, *v1beta1.BackupVerificationList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "BackupVerification"}, "backupverifications", true, v.controllerFactory)
+}
+
+func (v *version) ImageSyncPolicy() ImageSyncPolicyController {
+	return generic.NewController[*v1beta1.ImageSyncPolicy, *v1beta1.ImageSyncPolicyList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "ImageSyncPolicy"}, "imagesyncpolicies", true, v.controllerFactory)
+}
+
 func (v *version) KeyPair() KeyPairController {
 	return generic.NewController[*v1beta1.KeyPair, *v1beta1.KeyPairList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "KeyPair"}, "keypairs", true, v.controllerFactory)
 }
 
+func (v *version) NodeMaintenanceCampaign() NodeMaintenanceCampaignController {
+	return generic.NewNonNamespacedController[*v1beta1.NodeMaintenanceCampaign, *v1beta1.NodeMaintenanceCampaignList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "NodeMaintenanceCampaign"}, "nodemaintenancecampaigns", v.controllerFactory)
+}
+
 func (v *version) Preference() PreferenceController {
 	return generic.NewController[*v1beta1.Preference, *v1beta1.PreferenceList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "Preference"}, "preferences", true, v.controllerFactory)
 }
 
+func (v *version) RebalancePolicy() RebalancePolicyController {
+	return generic.NewNonNamespacedController[*v1beta1.RebalancePolicy, *v1beta1.RebalancePolicyList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "RebalancePolicy"}, "rebalancepolicies", v.controllerFactory)
+}
+
 func (v *version) ResourceQuota() ResourceQuotaController {
 	return generic.NewController[*v1beta1.ResourceQuota, *v1beta1.ResourceQuotaList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "ResourceQuota"}, "resourcequotas", true, v.controllerFactory)
 }
@@ -81,6 +115,10 @@ func (v *version) ScheduleVMBackup() ScheduleVMBackupController {
 	return generic.NewController[*v1beta1.ScheduleVMBackup, *v1beta1.ScheduleVMBackupList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "ScheduleVMBackup"}, "schedulevmbackups", true, v.controllerFactory)
 }
 
+func (v *version) ScheduleVolumeRemoteBackup() ScheduleVolumeRemoteBackupController {
+	return generic.NewController[*v1beta1.ScheduleVolumeRemoteBackup, *v1beta1.ScheduleVolumeRemoteBackupList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "ScheduleVolumeRemoteBackup"}, "schedulevolumeremotebackups", true, v.controllerFactory)
+}
+
 func (v *version) Setting() SettingController {
 	return generic.NewNonNamespacedController[*v1beta1.Setting, *v1beta1.SettingList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "Setting"}, "settings", v.controllerFactory)
 }
@@ -97,6 +135,10 @@ func (v *version) UpgradeLog() UpgradeLogController {
 	return generic.NewController[*v1beta1.UpgradeLog, *v1beta1.UpgradeLogList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "UpgradeLog"}, "upgradelogs", true, v.controllerFactory)
 }
 
+func (v *version) VMPlacementPolicy() VMPlacementPolicyController {
+	return generic.NewController[*v1beta1.VMPlacementPolicy, *v1beta1.VMPlacementPolicyList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "VMPlacementPolicy"}, "vmplacementpolicies", true, v.controllerFactory)
+}
+
 func (v *version) Version() VersionController {
 	return generic.NewController[*v1beta1.Version, *v1beta1.VersionList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "Version"}, "versions", true, v.controllerFactory)
 }
@@ -105,6 +147,14 @@ func (v *version) VirtualMachineBackup() VirtualMachineBackupController {
 	return generic.NewController[*v1beta1.VirtualMachineBackup, *v1beta1.VirtualMachineBackupList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "VirtualMachineBackup"}, "virtualmachinebackups", true, v.controllerFactory)
 }
 
+func (v *version) VirtualMachineBackupCopy() VirtualMachineBackupCopyController {
+	return generic.NewController[*v1beta1.VirtualMachineBackupCopy, *v1beta1.VirtualMachineBackupCopyList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "VirtualMachineBackupCopy"}, "virtualmachinebackupcopies", true, v.controllerFactory)
+}
+
+func (v *version) VirtualMachineGroup() VirtualMachineGroupController {
+	return generic.NewController[*v1beta1.VirtualMachineGroup, *v1beta1.VirtualMachineGroupList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "VirtualMachineGroup"}, "virtualmachinegroups", true, v.controllerFactory)
+}
+
 func (v *version) VirtualMachineImage() VirtualMachineImageController {
 	return generic.NewController[*v1beta1.VirtualMachineImage, *v1beta1.VirtualMachineImageList](schema.GroupVersionKind{Group: "harvesterhci.io", Version: "v1beta1", Kind: "VirtualMachineImage"}, "virtualmachineimages", true, v.controllerFactory)
 }
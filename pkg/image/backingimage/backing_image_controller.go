@@ -5,9 +5,11 @@ import (
 
 	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlharvesterv1beta1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctllhv1 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
 	"github.com/harvester/harvester/pkg/image/common"
 	"github.com/harvester/harvester/pkg/ref"
 	"github.com/harvester/harvester/pkg/settings"
@@ -16,6 +18,7 @@ import (
 
 // backingImageHandler syncs upload progress from backing image to vm image status
 type backingImageHandler struct {
+	biClient ctllhv1.BackingImageClient
 	vmiCache ctlharvesterv1beta1.VirtualMachineImageCache
 	vmio     common.VMIOperator
 }
@@ -30,7 +33,7 @@ func (h *backingImageHandler) updateBackupTarget(vmi *harvesterv1.VirtualMachine
 		return nil
 	}
 
-	_, err = h.vmio.UpdateBackupTarget(vmi, &harvesterv1.BackupTarget{
+	_, err = h.vmio.UpdateBackupTarget(vmi, &harvesterv1.BackupTargetLocation{
 		Endpoint:     target.Endpoint,
 		BucketName:   target.BucketName,
 		BucketRegion: target.BucketRegion,
@@ -53,6 +56,13 @@ func (h *backingImageHandler) OnChanged(_ string, bi *lhv1beta2.BackingImage) (*
 		return nil, err
 	}
 
+	// the intermediate backing image of a rekeyed image is not needed once the image is encrypted with the new secret
+	if h.vmio.IsRekeyOperation(vmi) && isBackingImageReady(bi) {
+		if err := h.biClient.Delete(util.LonghornSystemNamespaceName, rekeyBackingImageName(vmi), &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
 	if h.vmio.IsRetryLimitExceeded(vmi) {
 		return bi, nil
 	}
@@ -77,20 +87,32 @@ func (h *backingImageHandler) OnChanged(_ string, bi *lhv1beta2.BackingImage) (*
 
 	err = nil
 	for _, status := range bi.Status.DiskFileStatusMap {
+		progress := status.Progress
+		switch {
+		case h.vmio.IsRekeyOperation(vmi):
+			progress = rekeyProgress(true, progress)
+		case isConvertedDownload(vmi):
+			// the download reports its own progress until the upload starts
+			if status.Progress == 0 && status.State != lhv1beta2.BackingImageStateReady && status.State != lhv1beta2.BackingImageStateFailed {
+				continue
+			}
+			progress = convertedDownloadProgress(true, progress)
+		}
+
 		if status.State == lhv1beta2.BackingImageStateFailed {
-			_, err = h.vmio.FailImported(vmi, fmt.Errorf("%s", status.Message), status.Progress)
+			_, err = h.vmio.FailImported(vmi, fmt.Errorf("%s", status.Message), progress)
 			continue
 		}
 
 		if status.State == lhv1beta2.BackingImageStateReady {
 			biSize := bi.Status.Size
 			biVsize := bi.Status.VirtualSize
-			_, err = h.vmio.Imported(vmi, status.Message, status.Progress, biSize, biVsize)
+			_, err = h.vmio.Imported(vmi, status.Message, progress, biSize, biVsize)
 			continue
 		}
 
-		if status.Progress != vmi.Status.Progress {
-			_, err = h.vmio.Importing(vmi, status.Message, status.Progress)
+		if progress != vmi.Status.Progress {
+			_, err = h.vmio.Importing(vmi, status.Message, progress)
 		}
 	}
 
@@ -5,6 +5,7 @@ import (
 	errs "errors"
 	"fmt"
 	"strconv"
+	"sync"
 
 	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
 	lhmanager "github.com/longhorn/longhorn-manager/manager"
@@ -16,6 +17,7 @@ import (
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/ptr"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
@@ -23,6 +25,8 @@ import (
 	ctllhv1 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
 	"github.com/harvester/harvester/pkg/image/backend"
 	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/image/convert"
+	"github.com/harvester/harvester/pkg/image/verify"
 	"github.com/harvester/harvester/pkg/ref"
 	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
@@ -45,18 +49,24 @@ type Backend struct {
 	biController ctllhv1.BackingImageController
 	biClient     ctllhv1.BackingImageClient
 	biCache      ctllhv1.BackingImageCache
+	bidsClient   ctllhv1.BackingImageDataSourceClient
 	pvcCache     ctlcorev1.PersistentVolumeClaimCache
 	secretCache  ctlcorev1.SecretCache
 	vmiClient    ctlharvesterv1.VirtualMachineImageClient
 	vmiCache     ctlharvesterv1.VirtualMachineImageCache
 	vmio         common.VMIOperator
+	// clientset lists the cluster networks, which downloads and peer clusters must not reach
+	clientset kubernetes.Interface
+
+	// streamImports tracks the images being streamed into backing images, keyed by VM image UID
+	streamImports sync.Map
 }
 
 func GetBackend(ctx context.Context, scClient ctlstoragev1.StorageClassClient, scCache ctlstoragev1.StorageClassCache,
 	biController ctllhv1.BackingImageController, biClient ctllhv1.BackingImageClient, biCache ctllhv1.BackingImageCache,
-	pvcCache ctlcorev1.PersistentVolumeClaimCache, secretCache ctlcorev1.SecretCache,
+	bidsClient ctllhv1.BackingImageDataSourceClient, pvcCache ctlcorev1.PersistentVolumeClaimCache, secretCache ctlcorev1.SecretCache,
 	vmiClient ctlharvesterv1.VirtualMachineImageClient, vmiCache ctlharvesterv1.VirtualMachineImageCache,
-	vmio common.VMIOperator) backend.Backend {
+	vmio common.VMIOperator, clientset kubernetes.Interface) backend.Backend {
 	return &Backend{
 		ctx:          ctx,
 		scClient:     scClient,
@@ -64,11 +74,13 @@ func GetBackend(ctx context.Context, scClient ctlstoragev1.StorageClassClient, s
 		biController: biController,
 		biClient:     biClient,
 		biCache:      biCache,
+		bidsClient:   bidsClient,
 		pvcCache:     pvcCache,
 		secretCache:  secretCache,
 		vmiClient:    vmiClient,
 		vmiCache:     vmiCache,
 		vmio:         vmio,
+		clientset:    clientset,
 	}
 }
 
@@ -78,6 +90,10 @@ func (bib *Backend) deleteBackingImage(vmi *harvesterv1.VirtualMachineImage) err
 		return err
 	}
 
+	if err := bib.deleteRekeyBackingImage(vmi); err != nil {
+		return err
+	}
+
 	propagation := metav1.DeletePropagationForeground
 	return bib.biClient.Delete(util.LonghornSystemNamespaceName, biName, &metav1.DeleteOptions{PropagationPolicy: &propagation})
 }
@@ -102,38 +118,18 @@ func (bib *Backend) createBackingImage(vmi *harvesterv1.VirtualMachineImage) err
 		return fmt.Errorf("backing image %s is being deleted", cachedBI.Name)
 	}
 
-	biName, err := util.GetBackingImageName(bib.biCache, vmi)
+	bi, err := bib.newBackingImage(vmi)
 	if err != nil {
 		return err
 	}
 
 	vmio := bib.vmio
-	// use target storage class's numberOfReplicas as minNumberOfCopies for image HA
-	numOfCopiesStr := vmio.GetSCParameters(vmi)[longhorntypes.OptionNumberOfReplicas]
-	numOfCopies, err := strconv.Atoi(numOfCopiesStr)
-	if err != nil {
-		return err
-	}
-
-	bi := &lhv1beta2.BackingImage{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      biName,
-			Namespace: util.LonghornSystemNamespaceName,
-			Annotations: map[string]string{
-				util.AnnotationImageID: ref.Construct(vmio.GetNamespace(vmi), vmio.GetName(vmi)),
-			},
-		},
-		Spec: lhv1beta2.BackingImageSpec{
-			SourceType:        lhv1beta2.BackingImageDataSourceType(vmio.GetSourceType(vmi)),
-			SourceParameters:  map[string]string{},
-			Checksum:          vmio.GetChecksum(vmi),
-			MinNumberOfCopies: numOfCopies,
-		},
-	}
-
 	switch vmio.GetSourceType(vmi) {
 	case harvesterv1.VirtualMachineImageSourceTypeDownload:
-		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeDownloadParameterURL] = vmio.GetURL(vmi)
+		// a converted image is uploaded by Harvester
+		if !isStreamed(vmi) {
+			bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeDownloadParameterURL] = vmio.GetURL(vmi)
+		}
 	case harvesterv1.VirtualMachineImageSourceTypeExportVolume:
 		pvc, err := bib.pvcCache.Get(vmio.GetPVCNamespace(vmi), vmio.GetPVCName(vmi))
 		if err != nil {
@@ -145,8 +141,6 @@ func (bib *Backend) createBackingImage(vmi *harvesterv1.VirtualMachineImage) err
 	case harvesterv1.VirtualMachineImageSourceTypeRestore:
 		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeRestoreParameterBackupURL] = vmio.GetURL(vmi)
 	case harvesterv1.VirtualMachineImageSourceTypeClone:
-		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterEncryption] = vmio.GetSecurityCryptoOption(vmi)
-
 		sourceImage, err := bib.vmiClient.Get(vmio.GetSecuritySrcImgNamespace(vmi), vmio.GetSecuritySrcImgName(vmi), metav1.GetOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to get source vmimage %s/%s, error: %s", vmio.GetSecuritySrcImgNamespace(vmi), vmio.GetSecuritySrcImgName(vmi), err.Error())
@@ -157,23 +151,87 @@ func (bib *Backend) createBackingImage(vmi *harvesterv1.VirtualMachineImage) err
 			return fmt.Errorf("failed to get source backing image name for vmimage %s/%s, error: %s", sourceImage.Namespace, sourceImage.Name, err.Error())
 		}
 
-		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterBackingImage] = sourceBiName
-
-		targetImage := vmi
-
-		if vmio.IsDecryptOperation(vmi) {
+		switch {
+		case vmio.IsRekeyOperation(vmi):
+			// Longhorn either decrypts or encrypts a cloned backing image, so the source image is decrypted
+			// into an intermediate backing image first, which is encrypted with the new secret by Check.
+			bi.Name = rekeyBackingImageName(vmi)
+			delete(bi.Annotations, util.AnnotationImageID)
+			setCloneParameters(bi, harvesterv1.VirtualMachineImageCryptoOperationTypeDecrypt, sourceBiName, vmio.GetSCParameters(sourceImage))
+		case vmio.IsDecryptOperation(vmi):
 			// if try to decrypt image, we should get the storage class of source virtual machine image.
-			targetImage = sourceImage
+			setCloneParameters(bi, harvesterv1.VirtualMachineImageCryptoOperationTypeDecrypt, sourceBiName, vmio.GetSCParameters(sourceImage))
+		default:
+			setCloneParameters(bi, harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt, sourceBiName, vmio.GetSCParameters(vmi))
 		}
-
-		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterSecret] = vmio.GetSCParameters(targetImage)[util.CSINodePublishSecretNameKey]
-		bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterSecretNamespace] = vmio.GetSCParameters(targetImage)[util.CSINodePublishSecretNamespaceKey]
 	}
 
 	_, err = bib.biClient.Create(bi)
 	return err
 }
 
+func (bib *Backend) newBackingImage(vmi *harvesterv1.VirtualMachineImage) (*lhv1beta2.BackingImage, error) {
+	biName, err := util.GetBackingImageName(bib.biCache, vmi)
+	if err != nil {
+		return nil, err
+	}
+
+	vmio := bib.vmio
+	// use target storage class's numberOfReplicas as minNumberOfCopies for image HA
+	numOfCopiesStr := vmio.GetSCParameters(vmi)[longhorntypes.OptionNumberOfReplicas]
+	numOfCopies, err := strconv.Atoi(numOfCopiesStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lhv1beta2.BackingImage{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      biName,
+			Namespace: util.LonghornSystemNamespaceName,
+			Annotations: map[string]string{
+				util.AnnotationImageID: ref.Construct(vmio.GetNamespace(vmi), vmio.GetName(vmi)),
+			},
+		},
+		Spec: lhv1beta2.BackingImageSpec{
+			SourceType:        getBackingImageDataSourceType(vmi),
+			SourceParameters:  map[string]string{},
+			Checksum:          getBackingImageChecksum(vmi),
+			MinNumberOfCopies: numOfCopies,
+		},
+	}, nil
+}
+
+// setCloneParameters clones the source backing image, and decrypts or encrypts it with the secret
+// of the storage class parameters.
+func setCloneParameters(bi *lhv1beta2.BackingImage, op harvesterv1.VirtualMachineImageCryptoOperationType, sourceBiName string, scParams map[string]string) {
+	bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterEncryption] = string(op)
+	bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterBackingImage] = sourceBiName
+	bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterSecret] = scParams[util.CSINodePublishSecretNameKey]
+	bi.Spec.SourceParameters[lhv1beta2.DataSourceTypeCloneParameterSecretNamespace] = scParams[util.CSINodePublishSecretNamespaceKey]
+}
+
+// getBackingImageDataSourceType maps the image source type to the Longhorn data source type.
+// Images streamed by Harvester are imported through an upload data source.
+func getBackingImageDataSourceType(vmi *harvesterv1.VirtualMachineImage) lhv1beta2.BackingImageDataSourceType {
+	if isStreamed(vmi) {
+		return lhv1beta2.BackingImageDataSourceTypeUpload
+	}
+	return lhv1beta2.BackingImageDataSourceType(vmi.Spec.SourceType)
+}
+
+// getBackingImageChecksum returns the checksum Longhorn verifies the imported file with. The checksum of
+// an image that is converted is the one of the source file, which is verified before the conversion,
+// streamed downloads are verified by Harvester.
+func getBackingImageChecksum(vmi *harvesterv1.VirtualMachineImage) string {
+	if convert.NeedsConversion(vmi) || isStreamed(vmi) {
+		return ""
+	}
+	if _, digest, err := verify.ParseChecksum(vmi.Spec.Checksum); err == nil {
+		return digest
+	}
+	return vmi.Spec.Checksum
+}
+
 func (bib *Backend) createStorageClass(vmi *harvesterv1.VirtualMachineImage) error {
 	storageClassName := bib.vmio.GetStorageClassName(vmi)
 	if cachedSC, _ := bib.scCache.Get(storageClassName); cachedSC != nil && cachedSC.DeletionTimestamp != nil {
@@ -214,6 +272,7 @@ func (bib *Backend) createBackingImageAndStorageClass(vmi *harvesterv1.VirtualMa
 }
 
 func (bib *Backend) Initialize(vmi *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	bib.cancelStreamImport(vmi)
 	if err := bib.deleteBackingImageAndStorageClass(vmi); err != nil {
 		return vmi, err
 	}
@@ -223,14 +282,39 @@ func (bib *Backend) Initialize(vmi *harvesterv1.VirtualMachineImage) (*harvester
 		return checkedImg, err
 	}
 
+	var imp *streamImport
+	switch {
+	case bib.vmio.GetSourceType(checkedImg) == harvesterv1.VirtualMachineImageSourceTypeClone:
+		if checkedImg, err = bib.withEncryptionStatus(checkedImg); err != nil {
+			return checkedImg, err
+		}
+	case bib.vmio.GetSourceType(checkedImg) == harvesterv1.VirtualMachineImageSourceTypeRegistry:
+		if checkedImg, imp, err = bib.resolveRegistryImage(checkedImg); err != nil {
+			return checkedImg, err
+		}
+	case bib.vmio.GetSourceType(checkedImg) == harvesterv1.VirtualMachineImageSourceTypeRemote:
+		imp = bib.remoteImport(checkedImg)
+	case isStreamed(checkedImg):
+		imp = bib.streamedDownload(checkedImg)
+	}
+
 	toUpdate, err := bib.createBackingImageAndStorageClass(checkedImg)
 	if err != nil {
 		return toUpdate, err
 	}
+	if imp != nil {
+		bib.startStreamImport(toUpdate, imp)
+	}
 	return bib.vmio.UpdateVMI(checkedImg, toUpdate)
 }
 
 func (bib *Backend) Check(vmi *harvesterv1.VirtualMachineImage) error {
+	if bib.vmio.IsRekeyOperation(vmi) {
+		if err := bib.checkRekey(vmi); err != nil {
+			return err
+		}
+	}
+
 	bi, err := util.GetBackingImage(bib.biCache, vmi)
 	if errors.IsNotFound(err) {
 		return common.ErrRetryAble
@@ -244,10 +328,14 @@ func (bib *Backend) Check(vmi *harvesterv1.VirtualMachineImage) error {
 		return common.ErrRetryLater
 	}
 
-	for _, status := range bi.Status.DiskFileStatusMap {
-		if status.State == lhv1beta2.BackingImageStateFailed {
-			return common.ErrRetryAble
-		}
+	if isBackingImageFailed(bi) {
+		return common.ErrRetryAble
+	}
+
+	// the image is streamed by this process, start over if the import is gone, e.g. it was
+	// started by the previous leader
+	if isStreamed(vmi) && !isBackingImageReady(bi) && !bib.isStreamImportActive(vmi) {
+		return common.ErrRetryAble
 	}
 
 	storageClassName := bib.vmio.GetStorageClassName(vmi)
@@ -320,6 +408,7 @@ func (bib *Backend) deleteVMImageMetadata(vmi *harvesterv1.VirtualMachineImage)
 }
 
 func (bib *Backend) Delete(vmi *harvesterv1.VirtualMachineImage) error {
+	bib.cancelStreamImport(vmi)
 	if err := bib.deleteBackingImageAndStorageClass(vmi); err != nil {
 		return err
 	}
@@ -329,6 +418,7 @@ func (bib *Backend) Delete(vmi *harvesterv1.VirtualMachineImage) error {
 
 func (bib *Backend) AddSidecarHandler() {
 	backingImageHandler := &backingImageHandler{
+		biClient: bib.biClient,
 		vmiCache: bib.vmiCache,
 		vmio:     bib.vmio,
 	}
@@ -30,7 +30,7 @@ func (bid *Downloader) DoDownload(vmi *harvesterv1.VirtualMachineImage, rw http.
 	if !bid.vmio.IsImported(vmi) {
 		return fmt.Errorf("please wait until the image has been imported")
 	}
-	if bid.vmio.IsEncryptOperation(vmi) {
+	if util.IsImageEncrypted(vmi) {
 		return fmt.Errorf("encrypted image is not supported for download")
 	}
 
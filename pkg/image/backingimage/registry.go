@@ -0,0 +1,55 @@
+package backingimage
+
+import (
+	"context"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/registry"
+)
+
+// resolveRegistryImage resolves the registry reference of the image and returns the import streaming the disk image.
+func (bib *Backend) resolveRegistryImage(vmi *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, *streamImport, error) {
+	ref, err := registry.ParseReference(bib.vmio.GetURL(vmi))
+	if err != nil {
+		return vmi, nil, err
+	}
+
+	config, err := registry.LoadConfig(bib.secretCache)
+	if err != nil {
+		return vmi, nil, err
+	}
+	// a credential secret of the image replaces the credentials of the containerd-registry setting
+	if secretName := vmi.Spec.RegistryCredentialSecretName; secretName != "" {
+		username, password, err := registry.LoadCredentials(bib.secretCache, vmi.Namespace, secretName)
+		if err != nil {
+			return vmi, nil, err
+		}
+		config = config.WithCredentials(username, password)
+	}
+
+	client := registry.NewClient(config)
+	image, err := client.Resolve(bib.ctx, ref)
+	if err != nil {
+		return vmi, nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespace": vmi.Namespace,
+		"name":      vmi.Name,
+		"reference": ref.String(),
+		"digest":    image.Digest,
+	}).Info("resolved registry image")
+
+	updated, err := bib.vmio.UpdateResolvedDigest(vmi, image.Digest)
+	if err != nil {
+		return vmi, nil, err
+	}
+	return updated, &streamImport{
+		open: func(ctx context.Context) (io.ReadCloser, int64, error) {
+			return client.OpenDisk(ctx, image)
+		},
+	}, nil
+}
@@ -0,0 +1,124 @@
+package backingimage
+
+import (
+	"fmt"
+
+	lhdatastore "github.com/longhorn/longhorn-manager/datastore"
+	lhutil "github.com/longhorn/longhorn-manager/util"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// rekeyBackingImageName is the name of the intermediate backing image a rekeyed image is decrypted into.
+func rekeyBackingImageName(vmi *harvesterv1.VirtualMachineImage) string {
+	return lhutil.AutoCorrectName(fmt.Sprintf("vmi-%s-rekey", vmi.UID), lhdatastore.NameMaximumLength)
+}
+
+// rekeyProgress maps the progress of both backing images of a rekeyed image to the image progress,
+// the decryption is the first half of it.
+func rekeyProgress(decrypted bool, progress int) int {
+	if decrypted {
+		return 50 + progress/2
+	}
+	return progress / 2
+}
+
+// checkRekey waits for the source image to be decrypted into the intermediate backing image, and then
+// encrypts it with the new secret into the backing image of the image. The intermediate backing image
+// holds the data in plaintext, so it's deleted as soon as the backing image of the image is ready.
+func (bib *Backend) checkRekey(vmi *harvesterv1.VirtualMachineImage) error {
+	if bi, err := util.GetBackingImage(bib.biCache, vmi); !errors.IsNotFound(err) {
+		// the backing image is checked like the ones of any other image
+		if err == nil && isBackingImageReady(bi) {
+			return bib.deleteRekeyBackingImage(vmi)
+		}
+		return nil
+	}
+
+	decrypted, err := bib.biCache.Get(util.LonghornSystemNamespaceName, rekeyBackingImageName(vmi))
+	if errors.IsNotFound(err) {
+		return common.ErrRetryAble
+	}
+	if err != nil {
+		return errBackingImage
+	}
+	if decrypted.DeletionTimestamp != nil {
+		return common.ErrRetryLater
+	}
+	if isBackingImageFailed(decrypted) {
+		return common.ErrRetryAble
+	}
+
+	if !isBackingImageReady(decrypted) {
+		for _, status := range decrypted.Status.DiskFileStatusMap {
+			if progress := rekeyProgress(false, status.Progress); progress != vmi.Status.Progress {
+				if _, err := bib.vmio.Importing(vmi, "decrypting the source image", progress); err != nil {
+					return err
+				}
+			}
+		}
+		return common.ErrRetryLater
+	}
+
+	bi, err := bib.newBackingImage(vmi)
+	if err != nil {
+		return err
+	}
+	setCloneParameters(bi, harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt, decrypted.Name, bib.vmio.GetSCParameters(vmi))
+	if _, err := bib.biClient.Create(bi); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return common.ErrRetryLater
+}
+
+func (bib *Backend) deleteRekeyBackingImage(vmi *harvesterv1.VirtualMachineImage) error {
+	decrypted, err := bib.biCache.Get(util.LonghornSystemNamespaceName, rekeyBackingImageName(vmi))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if decrypted.DeletionTimestamp != nil {
+		return nil
+	}
+	err = bib.biClient.Delete(decrypted.Namespace, decrypted.Name, &metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// encryptionStatus returns the secret and the key version the image is encrypted with, the key
+// version of a rekeyed image follows the one of its source image.
+func encryptionStatus(vmi, sourceImage *harvesterv1.VirtualMachineImage) *harvesterv1.VirtualMachineImageEncryptionStatus {
+	if !util.IsImageEncrypted(vmi) {
+		return nil
+	}
+
+	keyVersion := 1
+	if vmi.Spec.SecurityParameters.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeRekey {
+		keyVersion = util.GetImageKeyVersion(sourceImage) + 1
+	}
+	return &harvesterv1.VirtualMachineImageEncryptionStatus{
+		SecretName:      vmi.Spec.StorageClassParameters[util.CSINodePublishSecretNameKey],
+		SecretNamespace: vmi.Spec.StorageClassParameters[util.CSINodePublishSecretNamespaceKey],
+		KeyVersion:      keyVersion,
+	}
+}
+
+// withEncryptionStatus records the encryption of a cloned image, which is saved once it is initialized.
+func (bib *Backend) withEncryptionStatus(vmi *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	sourceImage, err := bib.vmiCache.Get(bib.vmio.GetSecuritySrcImgNamespace(vmi), bib.vmio.GetSecuritySrcImgName(vmi))
+	if err != nil {
+		return vmi, fmt.Errorf("failed to get source vmimage %s/%s, error: %w", bib.vmio.GetSecuritySrcImgNamespace(vmi), bib.vmio.GetSecuritySrcImgName(vmi), err)
+	}
+
+	toUpdate := vmi.DeepCopy()
+	toUpdate.Status.Encryption = encryptionStatus(vmi, sourceImage)
+	return toUpdate, nil
+}
@@ -0,0 +1,133 @@
+package backingimage
+
+import (
+	"context"
+	"testing"
+
+	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestEncryptionStatus(t *testing.T) {
+	newImage := func(op harvesterv1.VirtualMachineImageCryptoOperationType, secret string) *harvesterv1.VirtualMachineImage {
+		return &harvesterv1.VirtualMachineImage{
+			Spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeClone,
+				SecurityParameters: &harvesterv1.VirtualMachineImageSecurityParameters{
+					CryptoOperation: op,
+				},
+				StorageClassParameters: map[string]string{
+					util.CSINodePublishSecretNameKey:      secret,
+					util.CSINodePublishSecretNamespaceKey: "default",
+				},
+			},
+		}
+	}
+	plain := &harvesterv1.VirtualMachineImage{}
+	encrypted := newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt, "key-1")
+	rekeyed := newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeRekey, "key-2")
+	rekeyed.Status.Encryption = &harvesterv1.VirtualMachineImageEncryptionStatus{SecretName: "key-2", SecretNamespace: "default", KeyVersion: 2}
+
+	var tests = []struct {
+		name        string
+		vmi         *harvesterv1.VirtualMachineImage
+		sourceImage *harvesterv1.VirtualMachineImage
+		expect      *harvesterv1.VirtualMachineImageEncryptionStatus
+	}{
+		{
+			name:        "encrypted image is on the first key version",
+			vmi:         newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt, "key-1"),
+			sourceImage: plain,
+			expect:      &harvesterv1.VirtualMachineImageEncryptionStatus{SecretName: "key-1", SecretNamespace: "default", KeyVersion: 1},
+		},
+		{
+			name:        "decrypted image is not encrypted",
+			vmi:         newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeDecrypt, "key-1"),
+			sourceImage: encrypted,
+		},
+		{
+			name:        "rekey of an image encrypted before key versions were tracked",
+			vmi:         newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeRekey, "key-2"),
+			sourceImage: encrypted,
+			expect:      &harvesterv1.VirtualMachineImageEncryptionStatus{SecretName: "key-2", SecretNamespace: "default", KeyVersion: 2},
+		},
+		{
+			name:        "rekey of a rekeyed image",
+			vmi:         newImage(harvesterv1.VirtualMachineImageCryptoOperationTypeRekey, "key-3"),
+			sourceImage: rekeyed,
+			expect:      &harvesterv1.VirtualMachineImageEncryptionStatus{SecretName: "key-3", SecretNamespace: "default", KeyVersion: 3},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, encryptionStatus(tc.vmi, tc.sourceImage))
+		})
+	}
+}
+
+func TestRekeyProgress(t *testing.T) {
+	assert.Equal(t, 0, rekeyProgress(false, 0))
+	assert.Equal(t, 25, rekeyProgress(false, 50))
+	assert.Equal(t, 50, rekeyProgress(true, 0))
+	assert.Equal(t, 100, rekeyProgress(true, 100))
+}
+
+func TestCheckRekeyDeletesDecryptedBackingImage(t *testing.T) {
+	vmi := &harvesterv1.VirtualMachineImage{
+		ObjectMeta: metav1.ObjectMeta{Name: "image", Namespace: "default", UID: "uid"},
+	}
+	newBackingImage := func(name string, state lhv1beta2.BackingImageState) *lhv1beta2.BackingImage {
+		return &lhv1beta2.BackingImage{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: util.LonghornSystemNamespaceName},
+			Status: lhv1beta2.BackingImageStatus{
+				DiskFileStatusMap: map[string]*lhv1beta2.BackingImageDiskFileStatus{
+					"disk-1": {State: state},
+				},
+			},
+		}
+	}
+	biName, err := util.GetBackingImageName(fakeclients.BackingImageCache(fake.NewSimpleClientset().LonghornV1beta2().BackingImages), vmi)
+	require.NoError(t, err)
+
+	var tests = []struct {
+		name          string
+		state         lhv1beta2.BackingImageState
+		expectDeleted bool
+	}{
+		{
+			name:  "decrypted backing image is kept while the image is encrypted",
+			state: lhv1beta2.BackingImageStateInProgress,
+		},
+		{
+			name:          "decrypted backing image is deleted once the image is ready",
+			state:         lhv1beta2.BackingImageStateReady,
+			expectDeleted: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				newBackingImage(biName, tc.state),
+				newBackingImage(rekeyBackingImageName(vmi), lhv1beta2.BackingImageStateReady),
+			)
+			bib := &Backend{
+				biClient: fakeclients.BackingImageClient(clientset.LonghornV1beta2().BackingImages),
+				biCache:  fakeclients.BackingImageCache(clientset.LonghornV1beta2().BackingImages),
+			}
+
+			require.NoError(t, bib.checkRekey(vmi))
+			_, err := clientset.LonghornV1beta2().BackingImages(util.LonghornSystemNamespaceName).Get(context.TODO(), rekeyBackingImageName(vmi), metav1.GetOptions{})
+			assert.Equal(t, tc.expectDeleted, apierrors.IsNotFound(err))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package backingimage
+
+import (
+	"context"
+	"io"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/remote"
+)
+
+// remoteImport streams the image of a peer cluster and records the checksum of the imported file.
+func (bib *Backend) remoteImport(vmi *harvesterv1.VirtualMachineImage) *streamImport {
+	var stream *remote.Stream
+	return &streamImport{
+		open: func(ctx context.Context) (io.ReadCloser, int64, error) {
+			blocked, err := export.BlockedNetworks(ctx, bib.clientset)
+			if err != nil {
+				return nil, 0, err
+			}
+			if stream, err = remote.Open(ctx, bib.secretCache, blocked, vmi); err != nil {
+				return nil, 0, err
+			}
+			return stream, stream.Size, nil
+		},
+		finish: func() error {
+			return bib.vmio.UpdateChecksum(vmi, stream.Checksum())
+		},
+	}
+}
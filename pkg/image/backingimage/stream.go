@@ -0,0 +1,293 @@
+package backingimage
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/sirupsen/logrus"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/convert"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/verify"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// downloadProgressInterval throttles the progress updates of a converted download.
+const downloadProgressInterval = 5 * time.Second
+
+// streamImport is an image Harvester streams into the upload data source of a backing image,
+// because Longhorn can not fetch it by itself. Imports only live in this process, an image
+// whose import is not tracked is initialized again, e.g. the new leader restarts the imports
+// of the previous one.
+type streamImport struct {
+	// open returns the image file and its size
+	open func(ctx context.Context) (io.ReadCloser, int64, error)
+	// finish is called once the image file has been uploaded, if set
+	finish func() error
+	cancel context.CancelFunc
+	failed atomic.Bool
+}
+
+// isStreamed returns true if the image is streamed by Harvester instead of being imported by Longhorn.
+func isStreamed(vmi *harvesterv1.VirtualMachineImage) bool {
+	return vmi.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeRegistry ||
+		vmi.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeRemote ||
+		(vmi.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeDownload && (convert.NeedsConversion(vmi) || !verifiedByLonghorn(vmi)))
+}
+
+// verifiedByLonghorn returns true if Longhorn is able to verify the image file by itself, which only
+// knows about SHA-512 checksums.
+func verifiedByLonghorn(vmi *harvesterv1.VirtualMachineImage) bool {
+	if vmi.Spec.Signature != nil {
+		return false
+	}
+	if vmi.Spec.Checksum == "" {
+		return true
+	}
+	algorithm, _, err := verify.ParseChecksum(vmi.Spec.Checksum)
+	return err == nil && algorithm == crypto.SHA512
+}
+
+// isConvertedDownload returns true if the downloaded image file is converted before it's uploaded into
+// the backing image, so the download isn't part of the upload progress reported by Longhorn.
+func isConvertedDownload(vmi *harvesterv1.VirtualMachineImage) bool {
+	return vmi.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeDownload && convert.NeedsConversion(vmi)
+}
+
+// convertedDownloadProgress maps the progress of a converted download to the image progress, the
+// download and the conversion are the first half of it and the upload into the backing image the second.
+func convertedDownloadProgress(uploading bool, progress int) int {
+	if uploading {
+		return 50 + progress/2
+	}
+	return progress / 2
+}
+
+// progressReader calls report with the percentage of the file read so far, at most once per interval.
+type progressReader struct {
+	io.Reader
+	size     int64
+	read     int64
+	interval time.Duration
+	last     time.Time
+	report   func(percent int)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	if now := time.Now(); now.Sub(r.last) >= r.interval {
+		r.last = now
+		r.report(int(min(r.read*100/r.size, 100)))
+	}
+	return n, err
+}
+
+// reportDownloadProgress records the progress of a converted download in the image status.
+func (bib *Backend) reportDownloadProgress(vmi *harvesterv1.VirtualMachineImage, percent int) {
+	current, err := bib.vmiCache.Get(vmi.Namespace, vmi.Name)
+	if err != nil || current.UID != vmi.UID || bib.vmio.IsImported(current) {
+		return
+	}
+	progress := convertedDownloadProgress(false, percent)
+	if progress == current.Status.Progress {
+		return
+	}
+	if _, err := bib.vmio.Importing(current, "Downloading the image", progress); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"namespace": vmi.Namespace,
+			"name":      vmi.Name,
+		}).Warn("failed to update the download progress")
+	}
+}
+
+// streamedDownload downloads the image file, verifies it and converts it if needed.
+func (bib *Backend) streamedDownload(vmi *harvesterv1.VirtualMachineImage) *streamImport {
+	return &streamImport{
+		open: func(ctx context.Context) (io.ReadCloser, int64, error) {
+			verifiers, err := verify.ImageVerifiers(ctx, bib.secretCache, vmi)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			blocked, err := export.BlockedNetworks(ctx, bib.clientset)
+			if err != nil {
+				return nil, 0, err
+			}
+			body, size, err := convert.Download(ctx, blocked, bib.vmio.GetURL(vmi))
+			if err != nil {
+				return nil, 0, err
+			}
+			file := verify.NewReader(body, verifiers...)
+
+			if !convert.NeedsConversion(vmi) {
+				if size < 0 {
+					body.Close()
+					return nil, 0, fmt.Errorf("the size of %s is unknown", bib.vmio.GetURL(vmi))
+				}
+				return struct {
+					io.Reader
+					io.Closer
+				}{file, body}, size, nil
+			}
+
+			defer body.Close()
+			if size > 0 {
+				file = &progressReader{
+					Reader:   file,
+					size:     size,
+					interval: downloadProgressInterval,
+					report:   func(percent int) { bib.reportDownloadProgress(vmi, percent) },
+				}
+			}
+			img, err := convert.Run(ctx, bib.vmio, vmi, file)
+			if err != nil {
+				return nil, 0, err
+			}
+			return img, img.Size, nil
+		},
+	}
+}
+
+func (bib *Backend) startStreamImport(vmi *harvesterv1.VirtualMachineImage, imp *streamImport) {
+	ctx, cancel := context.WithCancel(bib.ctx)
+	imp.cancel = cancel
+	bib.cancelStreamImport(vmi)
+	bib.streamImports.Store(vmi.UID, imp)
+
+	go func() {
+		defer cancel()
+		err := bib.runStreamImport(ctx, vmi, imp)
+		if err == nil || ctx.Err() != nil {
+			// a cancelled import has been superseded by a new one or the image is gone
+			return
+		}
+
+		imp.failed.Store(true)
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"namespace": vmi.Namespace,
+			"name":      vmi.Name,
+		}).Error("failed to stream image into backing image")
+
+		// the backing image handler reports the failure once Longhorn marks the backing image failed
+		if bi, biErr := util.GetBackingImage(bib.biCache, vmi); biErr == nil && isBackingImageFailed(bi) {
+			return
+		}
+		current, getErr := bib.vmiCache.Get(vmi.Namespace, vmi.Name)
+		if getErr != nil || current.UID != vmi.UID {
+			return
+		}
+		if _, updateErr := bib.vmio.FailImported(current, err, current.Status.Progress); updateErr != nil {
+			logrus.WithError(updateErr).Errorf("failed to update vmimage %s/%s", vmi.Namespace, vmi.Name)
+		}
+	}()
+}
+
+func (bib *Backend) cancelStreamImport(vmi *harvesterv1.VirtualMachineImage) {
+	if previous, ok := bib.streamImports.LoadAndDelete(vmi.UID); ok {
+		previous.(*streamImport).cancel()
+	}
+}
+
+// isStreamImportActive returns false if no import of the image is running in this process or it has failed.
+func (bib *Backend) isStreamImportActive(vmi *harvesterv1.VirtualMachineImage) bool {
+	imp, ok := bib.streamImports.Load(vmi.UID)
+	return ok && !imp.(*streamImport).failed.Load()
+}
+
+func (bib *Backend) runStreamImport(ctx context.Context, vmi *harvesterv1.VirtualMachineImage, imp *streamImport) error {
+	dsName, err := util.GetBackingImageDataSourceName(bib.biCache, vmi)
+	if err != nil {
+		return fmt.Errorf("failed to get backing image name for VMImage %s/%s, error: %w", vmi.Namespace, vmi.Name, err)
+	}
+
+	if err := waitForBackingImageDataSourceReady(bib.bidsClient, dsName); err != nil {
+		return err
+	}
+
+	file, size, err := imp.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := uploadToDataSource(ctx, http.DefaultClient, dsName, file, size); err != nil {
+		return err
+	}
+	if imp.finish != nil {
+		return imp.finish()
+	}
+	return nil
+}
+
+// uploadToDataSource streams the image file into the upload data source the same way a browser upload does.
+func uploadToDataSource(ctx context.Context, httpClient *http.Client, dsName string, file io.Reader, size int64) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("chunk", "blob")
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	uploadURL := fmt.Sprintf("%s/backingimages/%s", util.LonghornDefaultManagerURL, dsName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("failed to create the upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.URL.RawQuery = url.Values{
+		"action": []string{"upload"},
+		"size":   []string{strconv.FormatInt(size, 10)},
+	}.Encode()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		body.Close()
+		return fmt.Errorf("failed to send the upload request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("upload failed: %s", string(respBody))
+	}
+	return nil
+}
+
+func isBackingImageFailed(bi *lhv1beta2.BackingImage) bool {
+	for _, status := range bi.Status.DiskFileStatusMap {
+		if status.State == lhv1beta2.BackingImageStateFailed {
+			return true
+		}
+	}
+	return false
+}
+
+func isBackingImageReady(bi *lhv1beta2.BackingImage) bool {
+	for _, status := range bi.Status.DiskFileStatusMap {
+		if status.State == lhv1beta2.BackingImageStateReady {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package backingimage
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	fakegenerated "github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestDownloadVerification(t *testing.T) {
+	sha512Digest := strings.Repeat("ab", 64)
+
+	var tests = []struct {
+		name           string
+		spec           harvesterv1.VirtualMachineImageSpec
+		expectStreamed bool
+		expectChecksum string
+	}{
+		{
+			name: "unverified download is imported by longhorn",
+			spec: harvesterv1.VirtualMachineImageSpec{SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload},
+		},
+		{
+			name: "sha512 checksum is verified by longhorn",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Checksum:   "sha512:" + strings.ToUpper(sha512Digest),
+			},
+			expectChecksum: sha512Digest,
+		},
+		{
+			name: "sha256 checksum is verified by harvester",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Checksum:   strings.Repeat("ab", 32),
+			},
+			expectStreamed: true,
+		},
+		{
+			name: "signature is verified by harvester",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Checksum:   sha512Digest,
+				Signature: &harvesterv1.VirtualMachineImageSignature{
+					Type:                harvesterv1.VirtualMachineImageSignatureTypeGPG,
+					URL:                 "https://example.com/image.qcow2.sig",
+					PublicKeySecretName: "image-signing-key",
+				},
+			},
+			expectStreamed: true,
+		},
+		{
+			name: "remote image is streamed by harvester",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRemote,
+				Checksum:   sha512Digest,
+			},
+			expectStreamed: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			vmi := &harvesterv1.VirtualMachineImage{Spec: tc.spec}
+			assert.Equal(t, tc.expectStreamed, isStreamed(vmi))
+			assert.Equal(t, tc.expectChecksum, getBackingImageChecksum(vmi))
+		})
+	}
+}
+
+func TestConvertedDownloadProgress(t *testing.T) {
+	assert.Equal(t, 0, convertedDownloadProgress(false, 0))
+	assert.Equal(t, 40, convertedDownloadProgress(false, 80))
+	assert.Equal(t, 50, convertedDownloadProgress(true, 0))
+	assert.Equal(t, 100, convertedDownloadProgress(true, 100))
+
+	var reported []int
+	r := &progressReader{
+		Reader: strings.NewReader(strings.Repeat("x", 10)),
+		size:   10,
+		report: func(percent int) { reported = append(reported, percent) },
+	}
+	buf := make([]byte, 4)
+	for {
+		if _, err := r.Read(buf); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, []int{40, 80, 100, 100}, reported)
+}
+
+func TestCheckRestartsUntrackedStreamImport(t *testing.T) {
+	vmi := &harvesterv1.VirtualMachineImage{
+		ObjectMeta: metav1.ObjectMeta{Name: testImageName, Namespace: testNamespace, UID: "uid"},
+		Spec: harvesterv1.VirtualMachineImageSpec{
+			SourceType:   harvesterv1.VirtualMachineImageSourceTypeDownload,
+			Backend:      harvesterv1.VMIBackendBackingImage,
+			SourceFormat: harvesterv1.VirtualMachineImageFormatQCOW2,
+		},
+	}
+	clientset := fakegenerated.NewSimpleClientset(vmi)
+	vmio, err := common.GetVMIOperator(
+		fakeclients.VirtualMachineImageClient(clientset.HarvesterhciV1beta1().VirtualMachineImages),
+		fakeclients.VirtualMachineImageCache(clientset.HarvesterhciV1beta1().VirtualMachineImages),
+		fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses),
+		http.Client{},
+	)
+	require.NoError(t, err)
+	biName, err := util.GetBackingImageName(fakeclients.BackingImageCache(clientset.LonghornV1beta2().BackingImages), vmi)
+	require.NoError(t, err)
+	require.NoError(t, clientset.Tracker().Add(&lhv1beta2.BackingImage{
+		ObjectMeta: metav1.ObjectMeta{Name: biName, Namespace: util.LonghornSystemNamespaceName},
+		Status: lhv1beta2.BackingImageStatus{
+			DiskFileStatusMap: map[string]*lhv1beta2.BackingImageDiskFileStatus{
+				"disk-1": {State: lhv1beta2.BackingImageStateInProgress},
+			},
+		},
+	}))
+	require.NoError(t, clientset.Tracker().Add(&storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: vmio.GetStorageClassName(vmi)},
+	}))
+	bib := &Backend{
+		biCache: fakeclients.BackingImageCache(clientset.LonghornV1beta2().BackingImages),
+		scCache: fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses),
+		vmio:    vmio,
+	}
+
+	// e.g. the import was started by the previous leader
+	assert.True(t, common.IsRetryAble(bib.Check(vmi)), "an untracked import should be started over")
+
+	bib.streamImports.Store(vmi.UID, &streamImport{})
+	assert.NoError(t, bib.Check(vmi), "a running import should be waited for")
+}
@@ -17,6 +17,8 @@ import (
 	ctllhv1 "github.com/harvester/harvester/pkg/generated/controllers/longhorn.io/v1beta2"
 	"github.com/harvester/harvester/pkg/image/backend"
 	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/image/convert"
+	"github.com/harvester/harvester/pkg/image/verify"
 	"github.com/harvester/harvester/pkg/util"
 )
 
@@ -39,10 +41,10 @@ func GetUploader(biCache ctllhv1.BackingImageCache,
 	}
 }
 
-func (biu *Uploader) waitForBackingImageDataSourceReady(name string) error {
+func waitForBackingImageDataSourceReady(bidsClient ctllhv1.BackingImageDataSourceClient, name string) error {
 	retry := 30
 	for i := 0; i < retry; i++ {
-		ds, err := biu.bidsClient.Get(util.LonghornSystemNamespaceName, name, metav1.GetOptions{})
+		ds, err := bidsClient.Get(util.LonghornSystemNamespaceName, name, metav1.GetOptions{})
 		if err != nil && !apierrors.IsNotFound(err) {
 			return fmt.Errorf("failed waiting for backing image data source to be ready: %w", err)
 		}
@@ -75,7 +77,13 @@ func (biu *Uploader) DoUpload(vmi *harvesterv1.VirtualMachineImage, req *http.Re
 		return fmt.Errorf("failed to get backing image name for VMImage %s/%s, error: %w", vmi.Namespace, vmi.Name, err)
 	}
 
-	if err := biu.waitForBackingImageDataSourceReady(dsName); err != nil {
+	if err := waitForBackingImageDataSourceReady(biu.bidsClient, dsName); err != nil {
+		return err
+	}
+
+	if convert.NeedsConversion(vmi) {
+		// err will be recorded in image condition in the defer function
+		err = biu.uploadConverted(vmi, req, dsName)
 		return err
 	}
 
@@ -111,3 +119,25 @@ func (biu *Uploader) DoUpload(vmi *harvesterv1.VirtualMachineImage, req *http.Re
 
 	return nil
 }
+
+// uploadConverted converts the uploaded image file before it is streamed into the data source.
+func (biu *Uploader) uploadConverted(vmi *harvesterv1.VirtualMachineImage, req *http.Request, dsName string) error {
+	file, err := convert.UploadedFile(req)
+	if err != nil {
+		return fmt.Errorf("failed to read the uploaded image: %w", err)
+	}
+
+	// uploaded images are not signed, only their checksum is verified
+	file, err = verify.NewImageReader(req.Context(), nil, vmi, file)
+	if err != nil {
+		return err
+	}
+
+	img, err := convert.Run(req.Context(), biu.vmio, vmi, file)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	return uploadToDataSource(req.Context(), &biu.httpClient, dsName, img, img.Size)
+}
@@ -28,6 +28,22 @@ func (biv *Validator) Create(request *types.Request, vmi *harvesterv1.VirtualMac
 		return err
 	}
 
+	if err := biv.vmiv.CheckFormat(vmi); err != nil {
+		return err
+	}
+
+	if err := biv.vmiv.CheckVerification(vmi); err != nil {
+		return err
+	}
+
+	if err := biv.vmiv.CheckRemote(vmi); err != nil {
+		return err
+	}
+
+	if err := biv.vmiv.CheckRegistryCredential(request, vmi); err != nil {
+		return err
+	}
+
 	if err := biv.vmiv.CheckSecurityParameters(vmi); err != nil {
 		return err
 	}
@@ -62,6 +78,14 @@ func (biv *Validator) Update(oldVMI, newVMI *harvesterv1.VirtualMachineImage) er
 		return err
 	}
 
+	if err := biv.vmiv.FormatConsistency(oldVMI, newVMI); err != nil {
+		return err
+	}
+
+	if err := biv.vmiv.RemoteConsistency(oldVMI, newVMI); err != nil {
+		return err
+	}
+
 	if err := biv.vmiv.SecurityParameterConsistency(oldVMI, newVMI); err != nil {
 		return err
 	}
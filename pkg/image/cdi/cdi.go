@@ -3,8 +3,10 @@ package cdi
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
@@ -12,11 +14,13 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlcdiv1 "github.com/harvester/harvester/pkg/generated/controllers/cdi.kubevirt.io/v1beta1"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlcdiuploadv1 "github.com/harvester/harvester/pkg/generated/controllers/upload.cdi.kubevirt.io/v1beta1"
 	"github.com/harvester/harvester/pkg/image/backend"
 	"github.com/harvester/harvester/pkg/image/common"
 	"github.com/harvester/harvester/pkg/settings"
@@ -31,9 +35,16 @@ type Backend struct {
 	vmio             common.VMIOperator
 	settingCache     ctlharvesterv1.SettingCache
 	configMaps       ctlcorev1.ConfigMapClient
+	secretCache      ctlcorev1.SecretCache
+	uploader         *Uploader
+	// clientset lists the cluster networks, which downloads and peer clusters must not reach
+	clientset kubernetes.Interface
+
+	// streams tracks the downloaded images being streamed into upload DataVolumes, keyed by VM image UID
+	streams sync.Map
 }
 
-func GetBackend(ctx context.Context, dataVolumeClient ctlcdiv1.DataVolumeClient, scClient ctlstoragev1.StorageClassClient, pvcCache ctlcorev1.PersistentVolumeClaimCache, vmio common.VMIOperator, settingCache ctlharvesterv1.SettingCache, configMaps ctlcorev1.ConfigMapClient) backend.Backend {
+func GetBackend(ctx context.Context, dataVolumeClient ctlcdiv1.DataVolumeClient, scClient ctlstoragev1.StorageClassClient, pvcCache ctlcorev1.PersistentVolumeClaimCache, vmio common.VMIOperator, settingCache ctlharvesterv1.SettingCache, configMaps ctlcorev1.ConfigMapClient, secretCache ctlcorev1.SecretCache, cdiUploadClient ctlcdiuploadv1.UploadTokenRequestClient, clientset kubernetes.Interface) backend.Backend {
 	return &Backend{
 		ctx:              ctx,
 		dataVolumeClient: dataVolumeClient,
@@ -42,6 +53,9 @@ func GetBackend(ctx context.Context, dataVolumeClient ctlcdiv1.DataVolumeClient,
 		vmio:             vmio,
 		settingCache:     settingCache,
 		configMaps:       configMaps,
+		secretCache:      secretCache,
+		clientset:        clientset,
+		uploader:         GetUploader(dataVolumeClient, scClient, cdiUploadClient, http.Client{}, vmio).(*Uploader),
 	}
 }
 
@@ -57,12 +71,19 @@ func (b *Backend) Initialize(vmImg *harvesterv1.VirtualMachineImage) (*harvester
 
 	switch b.vmio.GetSourceType(vmImg) {
 	case harvesterv1.VirtualMachineImageSourceTypeDownload:
+		if isStreamed(vmImg) {
+			return b.initializeStreamedDownload(vmImg)
+		}
 		return b.initializeDownload(vmImg)
 	case harvesterv1.VirtualMachineImageSourceTypeUpload:
 		// do nothing when vmimage source is upload, dataVolume will be created by upload handler
 		return vmImg, nil
 	case harvesterv1.VirtualMachineImageSourceTypeExportVolume:
 		return b.initializeExportFromVolume(vmImg)
+	case harvesterv1.VirtualMachineImageSourceTypeRegistry:
+		return b.initializeRegistry(vmImg)
+	case harvesterv1.VirtualMachineImageSourceTypeRemote:
+		return b.initializeRemote(vmImg)
 	default:
 		return vmImg, fmt.Errorf("unsupported source type: %s", vmImg.Spec.SourceType)
 	}
@@ -73,12 +94,18 @@ func (b *Backend) Check(vmImg *harvesterv1.VirtualMachineImage) error {
 	targetDVName := b.vmio.GetName(vmImg)
 	targetDV, err := b.dataVolumeClient.Get(targetDVNs, targetDVName, metav1.GetOptions{})
 	if err != nil {
+		// the DataVolume is created once the image is downloaded, verified and converted
+		if apierrors.IsNotFound(err) && b.isStreamActive(vmImg) {
+			return common.ErrRetryLater
+		}
 		return b.handleDataVolumeError(err, targetDVNs, targetDVName)
 	}
 
 	// upload source type will update the progress on the upload handler
 	if vmImg.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeDownload ||
-		vmImg.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeExportVolume {
+		vmImg.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeExportVolume ||
+		vmImg.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeRegistry ||
+		vmImg.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeRemote {
 		progress := string(targetDV.Status.Progress)
 		if progress != "N/A" && progress != "" {
 			// progress format looks like "88.82%", we just need the integer part
@@ -121,6 +148,7 @@ func (b *Backend) UpdateVirtualSize(vmi *harvesterv1.VirtualMachineImage) (*harv
 }
 
 func (b *Backend) Delete(vmImg *harvesterv1.VirtualMachineImage) error {
+	b.cancelStream(vmImg)
 	targetDVNs := b.vmio.GetNamespace(vmImg)
 	targetDVName := b.vmio.GetName(vmImg)
 	_, err := b.dataVolumeClient.Get(targetDVNs, targetDVName, metav1.GetOptions{})
@@ -187,51 +215,13 @@ func (b *Backend) initializeDownload(vmImg *harvesterv1.VirtualMachineImage) (*h
 		return vmImg, fmt.Errorf("failed to read or create the additional CA ConfigMap: %w", err)
 	}
 
-	dvName := b.vmio.GetName(vmImg)
-	dvNamespace := b.vmio.GetNamespace(vmImg)
-
-	targetSC, err := b.scClient.Get(vmImg.Spec.TargetStorageClassName, metav1.GetOptions{})
-	if err != nil {
-		return vmImg, fmt.Errorf("failed to get StorageClass %s: %v", vmImg.Spec.TargetStorageClassName, err)
-	}
-
 	// generate DV source with certConfigMap
 	dvSource, err := generateDVSource(vmImg, b.vmio.GetSourceType(vmImg), certConfigMapName)
 	if err != nil {
 		return vmImg, fmt.Errorf("failed to generate DV source: %v", err)
 	}
 
-	// generate DV target storage
-	dvTargetStorage, err := generateDVTargetStorage(vmImg)
-	if err != nil {
-		return vmImg, fmt.Errorf("failed to generate DV target storage: %v", err)
-	}
-	var boolTrue = true
-	dataVolumeTemplate := &cdiv1.DataVolume{
-		ObjectMeta: metav1.ObjectMeta{
-			Annotations: GenerateDVAnnotations(targetSC),
-			Name:        dvName,
-			Namespace:   dvNamespace,
-			OwnerReferences: []metav1.OwnerReference{
-				{
-					APIVersion:         common.HarvesterAPIV1Beta1,
-					Kind:               common.VMImageKind,
-					Name:               b.vmio.GetName(vmImg),
-					UID:                b.vmio.GetUID(vmImg),
-					BlockOwnerDeletion: &boolTrue,
-				},
-			},
-		},
-		Spec: cdiv1.DataVolumeSpec{
-			Source:  dvSource,
-			Storage: dvTargetStorage,
-		},
-	}
-	if _, err := b.dataVolumeClient.Create(dataVolumeTemplate); err != nil {
-		return vmImg, fmt.Errorf("failed to create DataVolume %s/%s: %v", dvNamespace, dvName, err)
-	}
-	logrus.Infof("DataVolume %s/%s created", dvNamespace, dvName)
-	return vmImg, nil
+	return vmImg, b.createDataVolume(vmImg, dvSource)
 }
 
 // ensureAdditionalCAConfigMap ensures that an additional CA ConfigMap exists
@@ -297,24 +287,29 @@ func (b *Backend) initializeExportFromVolume(vmImg *harvesterv1.VirtualMachineIm
 		vmImg = updatedVMImg
 	}
 
+	// generate DV source
+	dvSource, err := generateDVSource(vmImg, b.vmio.GetSourceType(vmImg), "")
+	if err != nil {
+		return vmImg, fmt.Errorf("failed to generate DV source: %v", err)
+	}
+
+	return vmImg, b.createDataVolume(vmImg, dvSource)
+}
+
+// createDataVolume creates the DataVolume importing the image from the given source.
+func (b *Backend) createDataVolume(vmImg *harvesterv1.VirtualMachineImage, dvSource *cdiv1.DataVolumeSource) error {
 	dvName := b.vmio.GetName(vmImg)
 	dvNamespace := b.vmio.GetNamespace(vmImg)
 
 	targetSC, err := b.scClient.Get(vmImg.Spec.TargetStorageClassName, metav1.GetOptions{})
 	if err != nil {
-		return vmImg, fmt.Errorf("failed to get StorageClass %s: %v", vmImg.Spec.TargetStorageClassName, err)
-	}
-
-	// generate DV source
-	dvSource, err := generateDVSource(vmImg, b.vmio.GetSourceType(vmImg), "")
-	if err != nil {
-		return vmImg, fmt.Errorf("failed to generate DV source: %v", err)
+		return fmt.Errorf("failed to get StorageClass %s: %v", vmImg.Spec.TargetStorageClassName, err)
 	}
 
 	// generate DV target storage
 	dvTargetStorage, err := generateDVTargetStorage(vmImg)
 	if err != nil {
-		return vmImg, fmt.Errorf("failed to generate DV target storage: %v", err)
+		return fmt.Errorf("failed to generate DV target storage: %v", err)
 	}
 	var boolTrue = true
 	dataVolumeTemplate := &cdiv1.DataVolume{
@@ -338,8 +333,8 @@ func (b *Backend) initializeExportFromVolume(vmImg *harvesterv1.VirtualMachineIm
 		},
 	}
 	if _, err := b.dataVolumeClient.Create(dataVolumeTemplate); err != nil {
-		return vmImg, fmt.Errorf("failed to create DataVolume %s/%s: %v", dvNamespace, dvName, err)
+		return fmt.Errorf("failed to create DataVolume %s/%s: %v", dvNamespace, dvName, err)
 	}
 	logrus.Infof("DataVolume %s/%s created", dvNamespace, dvName)
-	return vmImg, nil
+	return nil
 }
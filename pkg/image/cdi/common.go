@@ -192,12 +192,17 @@ func fetchImageVirtualSize(url string) (int64, error) {
 		return 0, fmt.Errorf("content length is less than 128 bytes")
 	}
 
+	return parseQcow2VirtualSize(rawContent), nil
+}
+
+// parseQcow2VirtualSize returns the virtual size from a qcow2 header, or 0 if the header is not qcow2.
+func parseQcow2VirtualSize(rawContent []byte) int64 {
 	// REF: https://gitlab.com/qemu-project/qemu/-/blob/master/docs/interop/qcow2.txt
 	// 0-3 bytes are the magic number, should be "QFI\xfb" for qcow format
 	magicNumber := rawContent[0:4]
 	if string(magicNumber) != "QFI\xfb" {
 		logrus.Infof("Magic number is not correct: %v, this image is not qcow format", magicNumber)
-		return 0, nil
+		return 0
 	}
 
 	// 24-31 bytes are the virtual size
@@ -205,5 +210,5 @@ func fetchImageVirtualSize(url string) (int64, error) {
 	virtualSize := binary.BigEndian.Uint64(virtualSizeRaw)
 
 	// ensure the virtual size is not too large, skip gosec G115
-	return int64(virtualSize), nil //nolint:gosec
+	return int64(virtualSize) //nolint:gosec
 }
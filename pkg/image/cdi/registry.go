@@ -0,0 +1,116 @@
+package cdi
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	cdiv1 "kubevirt.io/containerized-data-importer-api/pkg/apis/core/v1beta1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/registry"
+)
+
+const (
+	qcow2HeaderSize = 32
+)
+
+func (b *Backend) initializeRegistry(vmImg *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	ref, err := registry.ParseReference(b.vmio.GetURL(vmImg))
+	if err != nil {
+		return vmImg, err
+	}
+
+	config, err := registry.LoadConfig(b.secretCache)
+	if err != nil {
+		return vmImg, err
+	}
+	// A credential secret of the image replaces the credentials of the containerd-registry setting.
+	// The importer runs in the image namespace, the credentials of the setting aren't copied there,
+	// the image is pulled by the container runtime of the node instead, which is configured with them.
+	pullMethod := cdiv1.RegistryPullPod
+	secretName := vmImg.Spec.RegistryCredentialSecretName
+	if secretName != "" {
+		username, password, err := registry.LoadCredentials(b.secretCache, b.vmio.GetNamespace(vmImg), secretName)
+		if err != nil {
+			return vmImg, err
+		}
+		config = config.WithCredentials(username, password)
+	} else if hasCredentials, err := config.HasCredentials(ref.Registry); err != nil {
+		return vmImg, err
+	} else if hasCredentials {
+		pullMethod = cdiv1.RegistryPullNode
+	}
+
+	client := registry.NewClient(config)
+	img, err := client.Resolve(b.ctx, ref)
+	if err != nil {
+		return vmImg, err
+	}
+	// the CDI importer extracts the disk image from a container disk only
+	if !img.IsContainerDisk() {
+		return vmImg, fmt.Errorf("image %s is not a container disk, which is required by the cdi backend", ref)
+	}
+
+	if vmImg.Status.Size == 0 && vmImg.Status.VirtualSize == 0 {
+		size, virtualSize, err := b.inspectRegistryDisk(client, img)
+		if err != nil {
+			return vmImg, fmt.Errorf("failed to fetch image size: %v", err)
+		}
+		logrus.Infof("Update VM Image size (%v) and virtual size (%v) before we create the DataVolume", size, virtualSize)
+		updatedVMImg, err := b.vmio.UpdateVirtualSizeAndSize(vmImg, virtualSize, size)
+		if err != nil {
+			return vmImg, fmt.Errorf("failed to update VM Image size and virtual size: %v", err)
+		}
+		vmImg = updatedVMImg
+	}
+
+	if vmImg, err = b.vmio.UpdateResolvedDigest(vmImg, img.Digest); err != nil {
+		return vmImg, fmt.Errorf("failed to update VM Image resolved digest: %v", err)
+	}
+
+	certConfigMapName, err := b.ensureAdditionalCAConfigMap(vmImg.Namespace)
+	if err != nil {
+		return vmImg, fmt.Errorf("failed to read or create the additional CA ConfigMap: %w", err)
+	}
+
+	dvSource := &cdiv1.DataVolumeSource{
+		Registry: generateDVSourceRegistry(img.PullURL(), secretName, certConfigMapName, pullMethod),
+	}
+	return vmImg, b.createDataVolume(vmImg, dvSource)
+}
+
+// inspectRegistryDisk reads the beginning of the disk image to find out its size and virtual size.
+func (b *Backend) inspectRegistryDisk(client *registry.Client, img *registry.Image) (int64, int64, error) {
+	disk, size, err := client.OpenDisk(b.ctx, img)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer disk.Close()
+
+	header := make([]byte, qcow2HeaderSize)
+	if _, err := io.ReadFull(disk, header); err != nil {
+		return 0, 0, fmt.Errorf("failed to read disk header: %w", err)
+	}
+
+	virtualSize := parseQcow2VirtualSize(header)
+	// means the image is not qcow format
+	if virtualSize == 0 {
+		virtualSize = size
+	}
+	return size, virtualSize, nil
+}
+
+func generateDVSourceRegistry(url, secretName, certConfigMapName string, pullMethod cdiv1.RegistryPullMethod) *cdiv1.DataVolumeSourceRegistry {
+	dvSourceRegistry := &cdiv1.DataVolumeSourceRegistry{
+		URL:        &url,
+		PullMethod: &pullMethod,
+	}
+	if secretName != "" {
+		dvSourceRegistry.SecretRef = &secretName
+	}
+	if certConfigMapName != "" {
+		dvSourceRegistry.CertConfigMap = &certConfigMapName
+	}
+	return dvSourceRegistry
+}
@@ -0,0 +1,139 @@
+package cdi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/convert"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/remote"
+	"github.com/harvester/harvester/pkg/image/verify"
+)
+
+// isStreamed returns true if the downloaded image is streamed by Harvester into an upload DataVolume,
+// since the CDI importer can neither convert nor verify the image by itself.
+func isStreamed(vmImg *harvesterv1.VirtualMachineImage) bool {
+	return convert.NeedsConversion(vmImg) || verify.Required(vmImg)
+}
+
+// initializeRemote streams the image of a peer cluster into an upload DataVolume.
+func (b *Backend) initializeRemote(vmImg *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	return b.initializeStream(vmImg, b.streamRemote)
+}
+
+// initializeStreamedDownload downloads, verifies and converts the image in this process and uploads
+// the result to an upload DataVolume.
+func (b *Backend) initializeStreamedDownload(vmImg *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
+	return b.initializeStream(vmImg, b.streamDownload)
+}
+
+// initializeStream runs stream in the background unless the image is already being streamed.
+func (b *Backend) initializeStream(vmImg *harvesterv1.VirtualMachineImage, stream func(context.Context, *harvesterv1.VirtualMachineImage) error) (*harvesterv1.VirtualMachineImage, error) {
+	if b.isStreamActive(vmImg) {
+		return vmImg, nil
+	}
+
+	ctx, cancel := context.WithCancel(b.ctx)
+	b.streams.Store(vmImg.UID, cancel)
+
+	go func() {
+		defer b.cancelStream(vmImg)
+		err := stream(ctx, vmImg)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+
+		logger := logrus.WithFields(logrus.Fields{
+			"namespace": vmImg.Namespace,
+			"name":      vmImg.Name,
+		})
+		logger.WithError(err).Error("failed to import the streamed image")
+		// start over from the download when the image is retried
+		if deleteErr := b.Delete(vmImg); deleteErr != nil {
+			logger.WithError(deleteErr).Warn("failed to delete the DataVolume of the streamed image")
+		}
+		current, getErr := b.vmio.GetVMImageObj(vmImg.Namespace, vmImg.Name)
+		if getErr != nil || current.UID != vmImg.UID {
+			return
+		}
+		if _, updateErr := b.vmio.FailImported(current, err, current.Status.Progress); updateErr != nil {
+			logger.WithError(updateErr).Error("failed to update vmimage")
+		}
+	}()
+	return vmImg, nil
+}
+
+func (b *Backend) streamDownload(ctx context.Context, vmImg *harvesterv1.VirtualMachineImage) error {
+	verifiers, err := verify.ImageVerifiers(ctx, b.secretCache, vmImg)
+	if err != nil {
+		return err
+	}
+
+	blocked, err := export.BlockedNetworks(ctx, b.clientset)
+	if err != nil {
+		return err
+	}
+	body, size, err := convert.Download(ctx, blocked, b.vmio.GetURL(vmImg))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	file := verify.NewReader(body, verifiers...)
+
+	var req *http.Request
+	if convert.NeedsConversion(vmImg) {
+		img, err := convert.Run(ctx, b.vmio, vmImg, file)
+		if err != nil {
+			return err
+		}
+		defer img.Close()
+
+		if req, err = img.UploadRequest(ctx); err != nil {
+			return err
+		}
+	} else {
+		if size < 0 {
+			return fmt.Errorf("the size of %s is unknown", b.vmio.GetURL(vmImg))
+		}
+		if req, err = convert.UploadRequest(ctx, file, size); err != nil {
+			return err
+		}
+	}
+	return b.uploader.doUpload(vmImg, req)
+}
+
+func (b *Backend) streamRemote(ctx context.Context, vmImg *harvesterv1.VirtualMachineImage) error {
+	blocked, err := export.BlockedNetworks(ctx, b.clientset)
+	if err != nil {
+		return err
+	}
+	stream, err := remote.Open(ctx, b.secretCache, blocked, vmImg)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	req, err := convert.UploadRequest(ctx, stream, stream.Size)
+	if err != nil {
+		return err
+	}
+	if err := b.uploader.doUpload(vmImg, req); err != nil {
+		return err
+	}
+	return b.vmio.UpdateChecksum(vmImg, stream.Checksum())
+}
+
+func (b *Backend) isStreamActive(vmImg *harvesterv1.VirtualMachineImage) bool {
+	_, ok := b.streams.Load(vmImg.UID)
+	return ok
+}
+
+func (b *Backend) cancelStream(vmImg *harvesterv1.VirtualMachineImage) {
+	if cancel, ok := b.streams.LoadAndDelete(vmImg.UID); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
@@ -29,6 +29,8 @@ import (
 	ctlcdiuploadv1 "github.com/harvester/harvester/pkg/generated/controllers/upload.cdi.kubevirt.io/v1beta1"
 	"github.com/harvester/harvester/pkg/image/backend"
 	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/image/convert"
+	"github.com/harvester/harvester/pkg/image/verify"
 )
 
 const (
@@ -65,6 +67,40 @@ func GetUploader(dataVolumeClient ctlcdiv1.DataVolumeClient,
 }
 
 func (cu *Uploader) DoUpload(vmImg *harvesterv1.VirtualMachineImage, req *http.Request) error {
+	if !convert.NeedsConversion(vmImg) {
+		return cu.doUpload(vmImg, req)
+	}
+
+	img, err := cu.convertUpload(vmImg, req)
+	if err != nil {
+		if updateErr := cu.vmio.FailUpload(vmImg, err.Error()); updateErr != nil {
+			logrus.Error(updateErr)
+		}
+		return err
+	}
+	defer img.Close()
+
+	convertedReq, err := img.UploadRequest(req.Context())
+	if err != nil {
+		return fmt.Errorf("failed to create the upload request of the converted image: %w", err)
+	}
+	return cu.doUpload(vmImg, convertedReq)
+}
+
+func (cu *Uploader) convertUpload(vmImg *harvesterv1.VirtualMachineImage, req *http.Request) (*convert.Image, error) {
+	file, err := convert.UploadedFile(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the uploaded image: %w", err)
+	}
+	// uploaded images are not signed, only their checksum is verified
+	if file, err = verify.NewImageReader(req.Context(), nil, vmImg, file); err != nil {
+		return nil, err
+	}
+	return convert.Run(req.Context(), cu.vmio, vmImg, file)
+}
+
+// doUpload creates the upload DataVolume and streams the image file of req into it.
+func (cu *Uploader) doUpload(vmImg *harvesterv1.VirtualMachineImage, req *http.Request) error {
 	var err, uploadErr error
 	defer func() {
 		if err != nil {
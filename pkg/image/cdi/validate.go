@@ -28,6 +28,22 @@ func (cv *Validator) Create(req *types.Request, vmImg *harvesterv1.VirtualMachin
 		return err
 	}
 
+	if err := cv.vmiv.CheckFormat(vmImg); err != nil {
+		return err
+	}
+
+	if err := cv.vmiv.CheckVerification(vmImg); err != nil {
+		return err
+	}
+
+	if err := cv.vmiv.CheckRemote(vmImg); err != nil {
+		return err
+	}
+
+	if err := cv.vmiv.CheckRegistryCredential(req, vmImg); err != nil {
+		return err
+	}
+
 	if err := cv.vmiv.CheckPVCInUse(vmImg); err != nil {
 		return err
 	}
@@ -57,6 +73,14 @@ func (cv *Validator) Update(oldVMImg, newVMImg *harvesterv1.VirtualMachineImage)
 		return err
 	}
 
+	if err := cv.vmiv.FormatConsistency(oldVMImg, newVMImg); err != nil {
+		return err
+	}
+
+	if err := cv.vmiv.RemoteConsistency(oldVMImg, newVMImg); err != nil {
+		return err
+	}
+
 	if err := cv.vmiv.CheckUpdateDisplayName(oldVMImg, newVMImg); err != nil {
 		return err
 	}
@@ -10,7 +10,9 @@ import (
 	"github.com/rancher/norman/condition"
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 
@@ -22,6 +24,10 @@ import (
 
 const (
 	uploadFailReason = "UploadFailed"
+
+	convertingReason     = "Converting"
+	convertedReason      = "Converted"
+	convertFailureReason = "ConversionFailed"
 )
 
 var (
@@ -65,7 +71,7 @@ type VMIOperator interface {
 	GetSecurityCryptoOption(vmi *harvesterv1.VirtualMachineImage) string
 	GetSecuritySrcImgNamespace(vmi *harvesterv1.VirtualMachineImage) string
 	GetSecuritySrcImgName(vmi *harvesterv1.VirtualMachineImage) string
-	GetBackupTarget(vmi *harvesterv1.VirtualMachineImage) *harvesterv1.BackupTarget
+	GetBackupTarget(vmi *harvesterv1.VirtualMachineImage) *harvesterv1.BackupTargetLocation
 	GetDisplayName(vmi *harvesterv1.VirtualMachineImage) string
 	GetStorageClassName(vmi *harvesterv1.VirtualMachineImage) string
 
@@ -73,6 +79,7 @@ type VMIOperator interface {
 	IsImported(vmi *harvesterv1.VirtualMachineImage) bool
 	IsDecryptOperation(vmi *harvesterv1.VirtualMachineImage) bool
 	IsEncryptOperation(vmi *harvesterv1.VirtualMachineImage) bool
+	IsRekeyOperation(vmi *harvesterv1.VirtualMachineImage) bool
 	IsRetryLimitExceeded(vmi *harvesterv1.VirtualMachineImage) bool
 
 	CheckURLAndUpdate(old *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error)
@@ -80,10 +87,16 @@ type VMIOperator interface {
 	UpdateSize(old *harvesterv1.VirtualMachineImage, size int64) (*harvesterv1.VirtualMachineImage, error)
 	UpdateVirtualSizeAndSize(old *harvesterv1.VirtualMachineImage, virtualSize, size int64) (*harvesterv1.VirtualMachineImage, error)
 	UpdateLastFailedTime(old *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error)
-	UpdateBackupTarget(old *harvesterv1.VirtualMachineImage, bt *harvesterv1.BackupTarget) (*harvesterv1.VirtualMachineImage, error)
+	UpdateBackupTarget(old *harvesterv1.VirtualMachineImage, bt *harvesterv1.BackupTargetLocation) (*harvesterv1.VirtualMachineImage, error)
+	UpdateResolvedDigest(old *harvesterv1.VirtualMachineImage, digest string) (*harvesterv1.VirtualMachineImage, error)
+	UpdateChecksum(old *harvesterv1.VirtualMachineImage, checksum string) error
 
 	FailUpload(old *harvesterv1.VirtualMachineImage, msg string) error
 
+	Converting(old *harvesterv1.VirtualMachineImage) error
+	Converted(old *harvesterv1.VirtualMachineImage, msg string) error
+	FailConverted(old *harvesterv1.VirtualMachineImage, err error) error
+
 	FailInitial(old *harvesterv1.VirtualMachineImage, err error) (*harvesterv1.VirtualMachineImage, error)
 	FailImported(old *harvesterv1.VirtualMachineImage, err error, progress int) (*harvesterv1.VirtualMachineImage, error)
 	Initialized(old *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error)
@@ -204,7 +217,7 @@ func (vmio *vmiOperator) GetStorageClassName(vmi *harvesterv1.VirtualMachineImag
 	return scName
 }
 
-func (vmio *vmiOperator) GetBackupTarget(vmi *harvesterv1.VirtualMachineImage) *harvesterv1.BackupTarget {
+func (vmio *vmiOperator) GetBackupTarget(vmi *harvesterv1.VirtualMachineImage) *harvesterv1.BackupTargetLocation {
 	return vmi.Status.BackupTarget
 }
 
@@ -232,6 +245,14 @@ func (vmio *vmiOperator) IsEncryptOperation(vmi *harvesterv1.VirtualMachineImage
 	return vmi.Spec.SecurityParameters.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt
 }
 
+func (vmio *vmiOperator) IsRekeyOperation(vmi *harvesterv1.VirtualMachineImage) bool {
+	if vmi.Spec.SecurityParameters == nil {
+		return false
+	}
+
+	return vmi.Spec.SecurityParameters.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeRekey
+}
+
 func (vmio *vmiOperator) CheckURLAndUpdate(old *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImage, error) {
 	if old.Spec.SourceType != harvesterv1.VirtualMachineImageSourceTypeDownload {
 		return old, nil
@@ -282,12 +303,36 @@ func (vmio *vmiOperator) UpdateLastFailedTime(old *harvesterv1.VirtualMachineIma
 	return vmio.UpdateVMI(old, newVMI)
 }
 
-func (vmio *vmiOperator) UpdateBackupTarget(old *harvesterv1.VirtualMachineImage, bt *harvesterv1.BackupTarget) (*harvesterv1.VirtualMachineImage, error) {
+func (vmio *vmiOperator) UpdateBackupTarget(old *harvesterv1.VirtualMachineImage, bt *harvesterv1.BackupTargetLocation) (*harvesterv1.VirtualMachineImage, error) {
 	newVMI := old.DeepCopy()
 	newVMI.Status.BackupTarget = bt
 	return vmio.UpdateVMI(old, newVMI)
 }
 
+func (vmio *vmiOperator) UpdateResolvedDigest(old *harvesterv1.VirtualMachineImage, digest string) (*harvesterv1.VirtualMachineImage, error) {
+	newVMI := old.DeepCopy()
+	newVMI.Status.AppliedURL = newVMI.Spec.URL
+	newVMI.Status.ResolvedDigest = digest
+	return vmio.UpdateVMI(old, newVMI)
+}
+
+// UpdateChecksum retries on conflicts, the image is streamed alongside the image controller.
+func (vmio *vmiOperator) UpdateChecksum(old *harvesterv1.VirtualMachineImage, checksum string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := vmio.client.Get(old.Namespace, old.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.UID != old.UID || current.DeletionTimestamp != nil || current.Status.Checksum == checksum {
+			return nil
+		}
+		newVMI := current.DeepCopy()
+		newVMI.Status.Checksum = checksum
+		_, err = vmio.UpdateVMI(current, newVMI)
+		return err
+	})
+}
+
 func (vmio *vmiOperator) FailUpload(old *harvesterv1.VirtualMachineImage, msg string) error {
 	retry := 3
 	for i := 0; i < retry; i++ {
@@ -311,6 +356,38 @@ func (vmio *vmiOperator) FailUpload(old *harvesterv1.VirtualMachineImage, msg st
 	return errors.New("failed to update image uploaded condition, max retries exceeded")
 }
 
+func (vmio *vmiOperator) Converting(old *harvesterv1.VirtualMachineImage) error {
+	return vmio.updateConvertedCondition(old, harvesterv1.ImageConverted.Unknown, convertingReason, "")
+}
+
+func (vmio *vmiOperator) Converted(old *harvesterv1.VirtualMachineImage, msg string) error {
+	return vmio.updateConvertedCondition(old, harvesterv1.ImageConverted.True, convertedReason, msg)
+}
+
+func (vmio *vmiOperator) FailConverted(old *harvesterv1.VirtualMachineImage, err error) error {
+	return vmio.updateConvertedCondition(old, harvesterv1.ImageConverted.False, convertFailureReason, err.Error())
+}
+
+// updateConvertedCondition retries on conflicts, the conversion runs alongside the image controller.
+func (vmio *vmiOperator) updateConvertedCondition(old *harvesterv1.VirtualMachineImage, setStatus func(interface{}), reason, msg string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := vmio.client.Get(old.Namespace, old.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if current.UID != old.UID || current.DeletionTimestamp != nil {
+			return nil
+		}
+		newVMI := current.DeepCopy()
+		setStatus(newVMI)
+		harvesterv1.ImageConverted.Reason(newVMI, reason)
+		harvesterv1.ImageConverted.Message(newVMI, msg)
+		harvesterv1.ImageConverted.LastUpdated(newVMI, time.Now().Format(time.RFC3339))
+		_, err = vmio.UpdateVMI(current, newVMI)
+		return err
+	})
+}
+
 func (vmio *vmiOperator) failStateTransit(old *harvesterv1.VirtualMachineImage, cond condition.Cond, msg string, progress int) (*harvesterv1.VirtualMachineImage, error) {
 	newVMI := old.DeepCopy()
 	newVMI.Status.Failed++
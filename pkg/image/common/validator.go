@@ -18,6 +18,9 @@ import (
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/registry"
+	"github.com/harvester/harvester/pkg/image/verify"
+	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
 	werror "github.com/harvester/harvester/pkg/webhook/error"
 	"github.com/harvester/harvester/pkg/webhook/indexeres"
@@ -25,7 +28,8 @@ import (
 )
 
 const (
-	fieldDisplayName = "spec.displayName"
+	fieldDisplayName                  = "spec.displayName"
+	fieldRegistryCredentialSecretName = "spec.registryCredentialSecretName"
 )
 
 type VMIValidator interface {
@@ -35,6 +39,10 @@ type VMIValidator interface {
 	CheckUpdateDisplayName(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
 	CheckURL(vmi *v1beta1.VirtualMachineImage) error
 	CheckSecurityParameters(vmi *v1beta1.VirtualMachineImage) error
+	CheckFormat(vmi *v1beta1.VirtualMachineImage) error
+	CheckVerification(vmi *v1beta1.VirtualMachineImage) error
+	CheckRemote(vmi *v1beta1.VirtualMachineImage) error
+	CheckRegistryCredential(request *types.Request, vmi *v1beta1.VirtualMachineImage) error
 	CheckImagePVC(request *types.Request, vmi *v1beta1.VirtualMachineImage) error
 	CheckPVCInUse(vmi *v1beta1.VirtualMachineImage) error
 
@@ -46,6 +54,8 @@ type VMIValidator interface {
 	PVCConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
 	URLConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
 	SecurityParameterConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
+	FormatConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
+	RemoteConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error
 
 	VMTemplateVersionOccupation(vmi *v1beta1.VirtualMachineImage) error
 	PVCOccupation(vmi *v1beta1.VirtualMachineImage) error
@@ -60,6 +70,7 @@ type vmiValidator struct {
 	pvcCache               ctlcorev1.PersistentVolumeClaimCache
 	vmTemplateVersionCache ctlharvesterv1.VirtualMachineTemplateVersionCache
 	vmBackupCache          ctlharvesterv1.VirtualMachineBackupCache
+	settingCache           ctlharvesterv1.SettingCache
 }
 
 func GetVMIValidator(vmiCache ctlharvesterv1.VirtualMachineImageCache,
@@ -68,7 +79,8 @@ func GetVMIValidator(vmiCache ctlharvesterv1.VirtualMachineImageCache,
 	podCache ctlcorev1.PodCache,
 	pvcCache ctlcorev1.PersistentVolumeClaimCache,
 	vmTemplateVersionCache ctlharvesterv1.VirtualMachineTemplateVersionCache,
-	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache) VMIValidator {
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache,
+	settingCache ctlharvesterv1.SettingCache) VMIValidator {
 	vmiv := &vmiValidator{
 		vmiCache:               vmiCache,
 		scCache:                scCache,
@@ -77,6 +89,7 @@ func GetVMIValidator(vmiCache ctlharvesterv1.VirtualMachineImageCache,
 		pvcCache:               pvcCache,
 		vmTemplateVersionCache: vmTemplateVersionCache,
 		vmBackupCache:          vmBackupCache,
+		settingCache:           settingCache,
 	}
 	vmiv.podCache.AddIndexer(util.IndexPodByPVC, util.IndexPodByPVCFunc)
 	return vmiv
@@ -133,6 +146,16 @@ func (v *vmiValidator) CheckURL(vmi *v1beta1.VirtualMachineImage) error {
 		shouldHaveURL = true
 	}
 
+	if vmi.Spec.SourceType == v1beta1.VirtualMachineImageSourceTypeRegistry {
+		if vmi.Spec.URL == "" {
+			return werror.NewInvalidError("url is required", "spec.url")
+		}
+		if _, err := registry.ParseReference(vmi.Spec.URL); err != nil {
+			return werror.NewInvalidError(fmt.Sprintf("url is not a valid image reference: %s", err.Error()), "spec.url")
+		}
+		return nil
+	}
+
 	if shouldHaveURL {
 		if vmi.Spec.URL == "" {
 			return werror.NewInvalidError("url is required", "spec.url")
@@ -182,17 +205,18 @@ func (v *vmiValidator) CheckSecurityParameters(vmi *v1beta1.VirtualMachineImage)
 		return werror.NewInternalError(fmt.Sprintf("failed to get source image %s/%s: %v", sp.SourceImageNamespace, sp.SourceImageName, err))
 	}
 
-	sourceSp := sourceImage.Spec.SecurityParameters
-	if (sourceSp != nil && sourceSp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeEncrypt) &&
-		sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeEncrypt {
+	if util.IsImageEncrypted(sourceImage) && sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeEncrypt {
 		return werror.NewInvalidError(fmt.Sprintf("can not re-encrypt, source image %s/%s (%s) is already encrypted", sourceImage.Namespace, sourceImage.Name, sourceImage.Spec.DisplayName), "")
 	}
 
-	if (sourceSp == nil || sourceSp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeDecrypt) &&
-		sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeDecrypt {
+	if !util.IsImageEncrypted(sourceImage) && sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeDecrypt {
 		return werror.NewInvalidError(fmt.Sprintf("can not re-decrypt, source image %s/%s (%s) is not encrypted", sourceImage.Namespace, sourceImage.Name, sourceImage.Spec.DisplayName), "")
 	}
 
+	if !util.IsImageEncrypted(sourceImage) && sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeRekey {
+		return werror.NewInvalidError(fmt.Sprintf("can not rekey, source image %s/%s (%s) is not encrypted", sourceImage.Namespace, sourceImage.Name, sourceImage.Spec.DisplayName), "")
+	}
+
 	if !v1beta1.ImageImported.IsTrue(sourceImage) {
 		return werror.NewInvalidError(fmt.Sprintf("source image %s/%s (%s) is not ready", sourceImage.Namespace, sourceImage.Name, sourceImage.Spec.DisplayName), "")
 	}
@@ -222,9 +246,126 @@ func (v *vmiValidator) CheckSecurityParameters(vmi *v1beta1.VirtualMachineImage)
 		return werror.NewInvalidError(fmt.Sprintf("storage class %s is not for encryption or decryption", scName), fmt.Sprintf("spec.parameters[%s] must be true", util.LonghornOptionEncrypted))
 	}
 
+	// A rekey is pointless with the secret the source image is already encrypted with.
+	if sp.CryptoOperation == v1beta1.VirtualMachineImageCryptoOperationTypeRekey && sameEncryptionSecret(sc.Parameters, sourceImage.Spec.StorageClassParameters) {
+		return werror.NewInvalidError(fmt.Sprintf("storage class %s uses the secret source image %s/%s (%s) is encrypted with", scName, sourceImage.Namespace, sourceImage.Name, sourceImage.Spec.DisplayName),
+			fmt.Sprintf("metadata.annotations[%s]", util.AnnotationStorageClassName))
+	}
+
+	return nil
+}
+
+func sameEncryptionSecret(a, b map[string]string) bool {
+	return a[util.CSINodePublishSecretNameKey] == b[util.CSINodePublishSecretNameKey] &&
+		a[util.CSINodePublishSecretNamespaceKey] == b[util.CSINodePublishSecretNamespaceKey]
+}
+
+// CheckFormat checks the image conversion options, only downloaded and uploaded image files are converted.
+func (v *vmiValidator) CheckFormat(vmi *v1beta1.VirtualMachineImage) error {
+	if vmi.Spec.SourceFormat == "" {
+		if vmi.Spec.TargetFormat != "" {
+			return werror.NewInvalidError("targetFormat requires sourceFormat", "spec.targetFormat")
+		}
+		return nil
+	}
+
+	if vmi.Spec.SourceType != v1beta1.VirtualMachineImageSourceTypeDownload && vmi.Spec.SourceType != v1beta1.VirtualMachineImageSourceTypeUpload {
+		return werror.NewInvalidError(fmt.Sprintf(`sourceFormat is not supported when image source type is "%s"`, vmi.Spec.SourceType), "spec.sourceFormat")
+	}
+
+	if vmi.Spec.Backend == v1beta1.VMIBackendCDI && vmi.Spec.TargetFormat == v1beta1.VirtualMachineImageFormatQCOW2 {
+		return werror.NewInvalidError("the cdi backend stores images as raw volumes, targetFormat must be raw", "spec.targetFormat")
+	}
+	return nil
+}
+
+// CheckVerification checks the checksum and the signature of the image, downloaded images must have
+// one of them if the image verification policy requires it.
+func (v *vmiValidator) CheckVerification(vmi *v1beta1.VirtualMachineImage) error {
+	isDownload := vmi.Spec.SourceType == v1beta1.VirtualMachineImageSourceTypeDownload
+
+	if sig := vmi.Spec.Signature; sig != nil {
+		if !isDownload {
+			return werror.NewInvalidError(fmt.Sprintf(`signature is not supported when image source type is "%s"`, vmi.Spec.SourceType), "spec.signature")
+		}
+		switch sig.Type {
+		case v1beta1.VirtualMachineImageSignatureTypeCosign, v1beta1.VirtualMachineImageSignatureTypeGPG:
+		default:
+			return werror.NewInvalidError(fmt.Sprintf("unsupported signature type %q", sig.Type), "spec.signature.type")
+		}
+		if u, err := url.Parse(sig.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return werror.NewInvalidError("signature url must be a http or https url", "spec.signature.url")
+		}
+		if sig.PublicKeySecretName == "" {
+			return werror.NewInvalidError("publicKeySecretName is required", "spec.signature.publicKeySecretName")
+		}
+	}
+
+	if !isDownload {
+		return nil
+	}
+
+	if vmi.Spec.Checksum != "" {
+		if _, _, err := verify.ParseChecksum(vmi.Spec.Checksum); err != nil {
+			return werror.NewInvalidError(err.Error(), "spec.checksum")
+		}
+		return nil
+	}
+	if vmi.Spec.Signature != nil {
+		return nil
+	}
+
+	policy, err := v.getImageVerificationPolicy()
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	if policy.Required {
+		return werror.NewInvalidError(fmt.Sprintf("a checksum or a signature is required for downloaded images by the %s setting", settings.ImageVerificationPolicySettingName), "spec.checksum")
+	}
 	return nil
 }
 
+// CheckRemote checks the peer cluster image of a remote image, whose checksum is the one of its raw disk.
+func (v *vmiValidator) CheckRemote(vmi *v1beta1.VirtualMachineImage) error {
+	if vmi.Spec.SourceType != v1beta1.VirtualMachineImageSourceTypeRemote {
+		if vmi.Spec.Remote != nil {
+			return werror.NewInvalidError(fmt.Sprintf(`remote is not supported when image source type is "%s"`, vmi.Spec.SourceType), "spec.remote")
+		}
+		return nil
+	}
+
+	remote := vmi.Spec.Remote
+	if remote == nil {
+		return werror.NewInvalidError("remote is required", "spec.remote")
+	}
+	if remote.KubeconfigSecretName == "" {
+		return werror.NewInvalidError("kubeconfigSecretName is required", "spec.remote.kubeconfigSecretName")
+	}
+	if remote.ImageNamespace == "" {
+		return werror.NewInvalidError("imageNamespace is required", "spec.remote.imageNamespace")
+	}
+	if remote.ImageName == "" {
+		return werror.NewInvalidError("imageName is required", "spec.remote.imageName")
+	}
+	if vmi.Spec.Checksum != "" {
+		if _, _, err := verify.ParseChecksum(vmi.Spec.Checksum); err != nil {
+			return werror.NewInvalidError(err.Error(), "spec.checksum")
+		}
+	}
+	return nil
+}
+
+func (v *vmiValidator) getImageVerificationPolicy() (*settings.ImageVerificationPolicyConfig, error) {
+	setting, err := v.settingCache.Get(settings.ImageVerificationPolicySettingName)
+	if apierrors.IsNotFound(err) {
+		return settings.DecodeConfig[settings.ImageVerificationPolicyConfig](settings.ImageVerificationPolicy.GetDefault())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get setting %s: %w", settings.ImageVerificationPolicySettingName, err)
+	}
+	return settings.GetImageVerificationPolicy(setting)
+}
+
 // CheckPVCInUse checks if the PVC is in use by any pods, this is only used to CDI backend
 func (v *vmiValidator) CheckPVCInUse(vmi *v1beta1.VirtualMachineImage) error {
 	if vmi.Spec.Backend != v1beta1.VMIBackendCDI {
@@ -285,6 +426,40 @@ func (v *vmiValidator) CheckImagePVC(request *types.Request, vmi *v1beta1.Virtua
 	return nil
 }
 
+// CheckRegistryCredential checks the user can read the registry credential secret the image is
+// pulled with, as the credentials could be sent to any registry.
+func (v *vmiValidator) CheckRegistryCredential(request *types.Request, vmi *v1beta1.VirtualMachineImage) error {
+	secretName := vmi.Spec.RegistryCredentialSecretName
+	if secretName == "" {
+		return nil
+	}
+	if vmi.Spec.SourceType != v1beta1.VirtualMachineImageSourceTypeRegistry {
+		return werror.NewInvalidError(fmt.Sprintf(`registryCredentialSecretName should be empty when image source type is "%s"`, vmi.Spec.SourceType), fieldRegistryCredentialSecretName)
+	}
+
+	ssar, err := v.ssar.Create(request.Context, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: vmi.Namespace,
+				Verb:      "get",
+				Group:     "",
+				Version:   "*",
+				Resource:  "secrets",
+				Name:      secretName,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		message := fmt.Sprintf("failed to check user permission, error: %s", err.Error())
+		return werror.NewInvalidError(message, "")
+	}
+	if !ssar.Status.Allowed || ssar.Status.Denied {
+		message := fmt.Sprintf("user has no permission to get the secret %s/%s", vmi.Namespace, secretName)
+		return werror.NewInvalidError(message, fieldRegistryCredentialSecretName)
+	}
+	return nil
+}
+
 func (v *vmiValidator) IsExportVolume(vmi *v1beta1.VirtualMachineImage) bool {
 	return vmi.Spec.SourceType == v1beta1.VirtualMachineImageSourceTypeExportVolume
 }
@@ -319,6 +494,9 @@ func (v *vmiValidator) URLConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImag
 	if oldVMI.Spec.URL != newVMI.Spec.URL {
 		return werror.NewInvalidError("url cannot be modified", "spec.url")
 	}
+	if oldVMI.Spec.RegistryCredentialSecretName != newVMI.Spec.RegistryCredentialSecretName {
+		return werror.NewInvalidError("registryCredentialSecretName cannot be modified", fieldRegistryCredentialSecretName)
+	}
 	return nil
 }
 
@@ -329,6 +507,23 @@ func (v *vmiValidator) SecurityParameterConsistency(oldVMI, newVMI *v1beta1.Virt
 	return nil
 }
 
+func (v *vmiValidator) FormatConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error {
+	if oldVMI.Spec.SourceFormat != newVMI.Spec.SourceFormat {
+		return werror.NewInvalidError("sourceFormat cannot be modified", "spec.sourceFormat")
+	}
+	if oldVMI.Spec.TargetFormat != newVMI.Spec.TargetFormat {
+		return werror.NewInvalidError("targetFormat cannot be modified", "spec.targetFormat")
+	}
+	return nil
+}
+
+func (v *vmiValidator) RemoteConsistency(oldVMI, newVMI *v1beta1.VirtualMachineImage) error {
+	if !reflect.DeepEqual(oldVMI.Spec.Remote, newVMI.Spec.Remote) {
+		return werror.NewInvalidError("remote cannot be modified", "spec.remote")
+	}
+	return nil
+}
+
 func (v *vmiValidator) VMTemplateVersionOccupation(vmi *v1beta1.VirtualMachineImage) error {
 	for _, ownerRef := range vmi.GetOwnerReferences() {
 		if ownerRef.Kind == "VirtualMachineTemplateVersion" {
@@ -1,16 +1,25 @@
 package common
 
 import (
+	"context"
 	"strings"
 	"testing"
 
+	"github.com/rancher/wrangler/v3/pkg/webhook"
 	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
 	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/webhook/types"
 )
 
 func TestCheckDisplayName(t *testing.T) {
@@ -160,3 +169,433 @@ func TestCheckUpdateDisplayName(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckFormat(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spec        harvesterv1.VirtualMachineImageSpec
+		expectErr   bool
+		errContains string
+	}{
+		{
+			name: "accepts image without conversion",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+			},
+		},
+		{
+			name: "accepts downloaded vmdk image",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				Backend:      harvesterv1.VMIBackendBackingImage,
+				SourceType:   harvesterv1.VirtualMachineImageSourceTypeDownload,
+				SourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+				TargetFormat: harvesterv1.VirtualMachineImageFormatQCOW2,
+			},
+		},
+		{
+			name: "accepts uploaded vhdx image",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				Backend:      harvesterv1.VMIBackendCDI,
+				SourceType:   harvesterv1.VirtualMachineImageSourceTypeUpload,
+				SourceFormat: harvesterv1.VirtualMachineImageFormatVHDX,
+			},
+		},
+		{
+			name: "rejects targetFormat without sourceFormat",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType:   harvesterv1.VirtualMachineImageSourceTypeDownload,
+				TargetFormat: harvesterv1.VirtualMachineImageFormatRaw,
+			},
+			expectErr:   true,
+			errContains: "targetFormat requires sourceFormat",
+		},
+		{
+			name: "rejects conversion of exported volume",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType:   harvesterv1.VirtualMachineImageSourceTypeExportVolume,
+				SourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+			},
+			expectErr:   true,
+			errContains: "sourceFormat is not supported",
+		},
+		{
+			name: "rejects qcow2 target of cdi backend",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				Backend:      harvesterv1.VMIBackendCDI,
+				SourceType:   harvesterv1.VirtualMachineImageSourceTypeUpload,
+				SourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+				TargetFormat: harvesterv1.VirtualMachineImageFormatQCOW2,
+			},
+			expectErr:   true,
+			errContains: "targetFormat must be raw",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := &vmiValidator{}
+			err := validator.CheckFormat(&harvesterv1.VirtualMachineImage{Spec: tc.spec})
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckVerification(t *testing.T) {
+	sha256Checksum := "sha256:" + strings.Repeat("a", 64)
+	signature := &harvesterv1.VirtualMachineImageSignature{
+		Type:                harvesterv1.VirtualMachineImageSignatureTypeCosign,
+		URL:                 "https://example.com/image.qcow2.sig",
+		PublicKeySecretName: "image-signing-key",
+	}
+
+	testCases := []struct {
+		name        string
+		policy      string
+		spec        harvesterv1.VirtualMachineImageSpec
+		expectErr   bool
+		errContains string
+	}{
+		{
+			name:   "accepts unverified download without policy",
+			policy: `{"required":false}`,
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+			},
+		},
+		{
+			name:   "rejects unverified download when required",
+			policy: `{"required":true}`,
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+			},
+			expectErr:   true,
+			errContains: "a checksum or a signature is required",
+		},
+		{
+			name:   "accepts download with checksum when required",
+			policy: `{"required":true}`,
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Checksum:   sha256Checksum,
+			},
+		},
+		{
+			name:   "accepts download with signature when required",
+			policy: `{"required":true}`,
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Signature:  signature,
+			},
+		},
+		{
+			name:   "accepts unverified upload when required",
+			policy: `{"required":true}`,
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeUpload,
+			},
+		},
+		{
+			name: "rejects invalid checksum",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Checksum:   "md5:0123",
+			},
+			expectErr:   true,
+			errContains: "unsupported checksum algorithm",
+		},
+		{
+			name: "rejects signature of uploaded image",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeUpload,
+				Signature:  signature,
+			},
+			expectErr:   true,
+			errContains: "signature is not supported",
+		},
+		{
+			name: "rejects signature without public key",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Signature: &harvesterv1.VirtualMachineImageSignature{
+					Type: harvesterv1.VirtualMachineImageSignatureTypeGPG,
+					URL:  "https://example.com/image.qcow2.asc",
+				},
+			},
+			expectErr:   true,
+			errContains: "publicKeySecretName is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSet := fake.NewSimpleClientset()
+			if tc.policy != "" {
+				err := clientSet.Tracker().Add(&harvesterv1.Setting{
+					ObjectMeta: metav1.ObjectMeta{Name: settings.ImageVerificationPolicySettingName},
+					Value:      tc.policy,
+				})
+				assert.NoError(t, err)
+			}
+
+			validator := &vmiValidator{
+				settingCache: fakeclients.HarvesterSettingCache(clientSet.HarvesterhciV1beta1().Settings),
+			}
+			err := validator.CheckVerification(&harvesterv1.VirtualMachineImage{Spec: tc.spec})
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckRemote(t *testing.T) {
+	remote := &harvesterv1.VirtualMachineImageRemoteSource{
+		KubeconfigSecretName: "peer-kubeconfig",
+		ImageNamespace:       "default",
+		ImageName:            "image-a",
+	}
+
+	testCases := []struct {
+		name        string
+		spec        harvesterv1.VirtualMachineImageSpec
+		expectErr   bool
+		errContains string
+	}{
+		{
+			name: "accepts remote image",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRemote,
+				Remote:     remote,
+				Checksum:   "sha512:" + strings.Repeat("a", 128),
+			},
+		},
+		{
+			name: "rejects remote image without remote source",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRemote,
+			},
+			expectErr:   true,
+			errContains: "remote is required",
+		},
+		{
+			name: "rejects remote source without image name",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRemote,
+				Remote: &harvesterv1.VirtualMachineImageRemoteSource{
+					KubeconfigSecretName: "peer-kubeconfig",
+					ImageNamespace:       "default",
+				},
+			},
+			expectErr:   true,
+			errContains: "imageName is required",
+		},
+		{
+			name: "rejects invalid checksum",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRemote,
+				Remote:     remote,
+				Checksum:   "md5:0123",
+			},
+			expectErr:   true,
+			errContains: "unsupported checksum algorithm",
+		},
+		{
+			name: "rejects remote source of other source types",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeDownload,
+				Remote:     remote,
+			},
+			expectErr:   true,
+			errContains: "remote is not supported",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := &vmiValidator{}
+			err := validator.CheckRemote(&harvesterv1.VirtualMachineImage{Spec: tc.spec})
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckSecurityParametersRekey(t *testing.T) {
+	secretParams := func(name string) map[string]string {
+		return map[string]string{
+			util.LonghornOptionEncrypted:          "true",
+			util.CSINodePublishSecretNameKey:      name,
+			util.CSINodePublishSecretNamespaceKey: "default",
+		}
+	}
+	newSourceImage := func(name string, op harvesterv1.VirtualMachineImageCryptoOperationType) *harvesterv1.VirtualMachineImage {
+		vmi := &harvesterv1.VirtualMachineImage{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: harvesterv1.VirtualMachineImageSpec{
+				DisplayName:            name,
+				SourceType:             harvesterv1.VirtualMachineImageSourceTypeDownload,
+				StorageClassParameters: secretParams("key-1"),
+			},
+		}
+		if op != "" {
+			vmi.Spec.SourceType = harvesterv1.VirtualMachineImageSourceTypeClone
+			vmi.Spec.SecurityParameters = &harvesterv1.VirtualMachineImageSecurityParameters{
+				CryptoOperation:      op,
+				SourceImageName:      "plain",
+				SourceImageNamespace: "default",
+			}
+		}
+		harvesterv1.ImageImported.True(vmi)
+		return vmi
+	}
+
+	testCases := []struct {
+		name        string
+		sourceImage string
+		scName      string
+		expectErr   bool
+		errContains string
+	}{
+		{
+			name:        "accepts rekey of an encrypted image with a new secret",
+			sourceImage: "encrypted",
+			scName:      "encrypted-key-2",
+		},
+		{
+			name:        "accepts rekey of a rekeyed image",
+			sourceImage: "rekeyed",
+			scName:      "encrypted-key-2",
+		},
+		{
+			name:        "rejects rekey of an image that is not encrypted",
+			sourceImage: "plain",
+			scName:      "encrypted-key-2",
+			expectErr:   true,
+			errContains: "is not encrypted",
+		},
+		{
+			name:        "rejects rekey with the current secret",
+			sourceImage: "encrypted",
+			scName:      "encrypted-key-1",
+			expectErr:   true,
+			errContains: "uses the secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientSet := fake.NewSimpleClientset(
+				newSourceImage("plain", ""),
+				newSourceImage("encrypted", harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt),
+				newSourceImage("rekeyed", harvesterv1.VirtualMachineImageCryptoOperationTypeRekey),
+				&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "encrypted-key-1"}, Parameters: secretParams("key-1")},
+				&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "encrypted-key-2"}, Parameters: secretParams("key-2")},
+			)
+			validator := &vmiValidator{
+				vmiCache: fakeclients.VirtualMachineImageCache(clientSet.HarvesterhciV1beta1().VirtualMachineImages),
+				scCache:  fakeclients.StorageClassCache(clientSet.StorageV1().StorageClasses),
+			}
+			vmi := &harvesterv1.VirtualMachineImage{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "rekey",
+					Namespace:   "default",
+					Annotations: map[string]string{util.AnnotationStorageClassName: tc.scName},
+				},
+				Spec: harvesterv1.VirtualMachineImageSpec{
+					SourceType: harvesterv1.VirtualMachineImageSourceTypeClone,
+					SecurityParameters: &harvesterv1.VirtualMachineImageSecurityParameters{
+						CryptoOperation:      harvesterv1.VirtualMachineImageCryptoOperationTypeRekey,
+						SourceImageName:      tc.sourceImage,
+						SourceImageNamespace: "default",
+					},
+				},
+			}
+			err := validator.CheckSecurityParameters(vmi)
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckRegistryCredential(t *testing.T) {
+	testCases := []struct {
+		name        string
+		spec        harvesterv1.VirtualMachineImageSpec
+		allowed     bool
+		expectErr   bool
+		errContains string
+	}{
+		{
+			name: "accepts registry image without credential secret",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType: harvesterv1.VirtualMachineImageSourceTypeRegistry,
+			},
+		},
+		{
+			name: "accepts credential secret the user can read",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType:                   harvesterv1.VirtualMachineImageSourceTypeRegistry,
+				RegistryCredentialSecretName: "registry-credential",
+			},
+			allowed: true,
+		},
+		{
+			name: "rejects credential secret the user cannot read",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType:                   harvesterv1.VirtualMachineImageSourceTypeRegistry,
+				RegistryCredentialSecretName: "registry-credential",
+			},
+			expectErr:   true,
+			errContains: "user has no permission to get the secret default/registry-credential",
+		},
+		{
+			name: "rejects credential secret of other source types",
+			spec: harvesterv1.VirtualMachineImageSpec{
+				SourceType:                   harvesterv1.VirtualMachineImageSourceTypeDownload,
+				RegistryCredentialSecretName: "registry-credential",
+			},
+			allowed:     true,
+			expectErr:   true,
+			errContains: "registryCredentialSecretName should be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := k8sfake.NewSimpleClientset()
+			clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				ssar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				ssar.Status.Allowed = tc.allowed
+				return true, ssar, nil
+			})
+			validator := &vmiValidator{ssar: clientset.AuthorizationV1().SelfSubjectAccessReviews()}
+			vmi := &harvesterv1.VirtualMachineImage{
+				ObjectMeta: metav1.ObjectMeta{Name: "image", Namespace: "default"},
+				Spec:       tc.spec,
+			}
+			err := validator.CheckRegistryCredential(&types.Request{Request: &webhook.Request{Context: context.TODO()}}, vmi)
+			if tc.expectErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/common"
+	"github.com/harvester/harvester/pkg/image/export"
+)
+
+const (
+	sourceFileName = "source"
+	targetFileName = "target"
+)
+
+var (
+	// qemuImg is the qemu-img binary, replaced in tests
+	qemuImg = "qemu-img"
+	// Dir is where images are staged while they are converted, the system temporary directory if empty
+	Dir = ""
+	// MaxSourceSize is the size of the largest image file which is staged, unlimited if 0
+	MaxSourceSize int64
+	// slots admits one conversion at a time, each one may fill the volume with the image file and the
+	// converted image. A conversion holds its slot until the converted image is closed.
+	slots = make(chan struct{}, 1)
+)
+
+// Configure stages the images in dir, which is a volume of sizeLimit. The volume holds the image file
+// and the converted image of one conversion at a time, so image files larger than half of the size
+// limit are rejected.
+func Configure(dir, sizeLimit string) error {
+	Dir = dir
+	MaxSourceSize = 0
+	if sizeLimit == "" {
+		return nil
+	}
+	limit, err := resource.ParseQuantity(sizeLimit)
+	if err != nil {
+		return fmt.Errorf("invalid image conversion size limit %s: %w", sizeLimit, err)
+	}
+	MaxSourceSize = limit.Value() / 2
+	return nil
+}
+
+// NeedsConversion returns true if the image file has to be converted before it is imported.
+func NeedsConversion(vmi *harvesterv1.VirtualMachineImage) bool {
+	return vmi.Spec.SourceFormat != "" && vmi.Spec.SourceFormat != TargetFormat(vmi)
+}
+
+// TargetFormat returns the format the image is converted to.
+func TargetFormat(vmi *harvesterv1.VirtualMachineImage) harvesterv1.VirtualMachineImageFormat {
+	if vmi.Spec.TargetFormat == "" {
+		return harvesterv1.VirtualMachineImageFormatRaw
+	}
+	return vmi.Spec.TargetFormat
+}
+
+// Image is a converted image file staged on the local disk, Close removes it and lets the next
+// conversion start.
+type Image struct {
+	*os.File
+	dir         string
+	Format      harvesterv1.VirtualMachineImageFormat
+	Size        int64
+	VirtualSize int64
+	release     sync.Once
+}
+
+func (img *Image) Close() error {
+	img.File.Close()
+	err := os.RemoveAll(img.dir)
+	img.release.Do(releaseSlot)
+	return err
+}
+
+// acquireSlot waits until no other conversion stages an image, or ctx is done.
+func acquireSlot(ctx context.Context) error {
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseSlot() {
+	<-slots
+}
+
+// UploadRequest returns a request carrying the image the same way a client uploads an image file.
+func (img *Image) UploadRequest(ctx context.Context) (*http.Request, error) {
+	if _, err := img.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return UploadRequest(ctx, img.File, img.Size)
+}
+
+// UploadRequest returns a request carrying the image file read from r the same way a client uploads an image file.
+func UploadRequest(ctx context.Context, r io.Reader, size int64) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", io.NopCloser(r))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.URL.RawQuery = url.Values{"size": []string{strconv.FormatInt(size, 10)}}.Encode()
+	return req, nil
+}
+
+type imageInfo struct {
+	Format      string `json:"format"`
+	VirtualSize int64  `json:"virtual-size"`
+}
+
+// Convert stages the image read from r, validates that it is a sourceFormat image and converts it to
+// targetFormat. The image file is verified by the reader, a read error fails the conversion.
+// Conversions run one at a time, Convert waits until the image of the previous one is closed.
+func Convert(ctx context.Context, r io.Reader, sourceFormat, targetFormat harvesterv1.VirtualMachineImageFormat) (*Image, error) {
+	if err := acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(Dir, "image-convert-")
+	if err != nil {
+		releaseSlot()
+		return nil, fmt.Errorf("failed to create the conversion directory: %w", err)
+	}
+	img, err := convert(ctx, dir, r, sourceFormat, targetFormat)
+	if err != nil {
+		os.RemoveAll(dir)
+		releaseSlot()
+		return nil, err
+	}
+	return img, nil
+}
+
+func convert(ctx context.Context, dir string, r io.Reader, sourceFormat, targetFormat harvesterv1.VirtualMachineImageFormat) (*Image, error) {
+	sourcePath := filepath.Join(dir, sourceFileName)
+	targetPath := filepath.Join(dir, targetFileName)
+
+	if err := stage(sourcePath, r); err != nil {
+		return nil, err
+	}
+
+	source, err := inspect(ctx, sourcePath, sourceFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := runQemuImg(ctx, "convert", "-f", string(sourceFormat), "-O", string(targetFormat), sourcePath, targetPath); err != nil {
+		return nil, fmt.Errorf("failed to convert the image from %s to %s: %w", sourceFormat, targetFormat, err)
+	}
+	// the source is not needed anymore, free the space before the image is uploaded
+	if err := os.Remove(sourcePath); err != nil {
+		logrus.WithError(err).Warnf("failed to remove %s", sourcePath)
+	}
+
+	target, err := inspect(ctx, targetPath, targetFormat)
+	if err != nil {
+		return nil, err
+	}
+	if target.VirtualSize != source.VirtualSize {
+		return nil, fmt.Errorf("the converted image has virtual size %d, expected %d", target.VirtualSize, source.VirtualSize)
+	}
+	if targetFormat == harvesterv1.VirtualMachineImageFormatQCOW2 {
+		if _, err := runQemuImg(ctx, "check", "-f", string(targetFormat), targetPath); err != nil {
+			return nil, fmt.Errorf("the converted image is corrupted: %w", err)
+		}
+	}
+
+	f, err := os.Open(targetPath)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Image{
+		File:        f,
+		dir:         dir,
+		Format:      targetFormat,
+		Size:        stat.Size(),
+		VirtualSize: target.VirtualSize,
+	}, nil
+}
+
+// stage writes r to path, at most MaxSourceSize bytes.
+func stage(path string, r io.Reader) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if MaxSourceSize > 0 {
+		r = io.LimitReader(r, MaxSourceSize+1)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return fmt.Errorf("failed to receive the image: %w", err)
+	}
+	if MaxSourceSize > 0 && n > MaxSourceSize {
+		return fmt.Errorf("the image file is larger than %d bytes, the limit of images which are converted", MaxSourceSize)
+	}
+	return f.Sync()
+}
+
+// inspect validates that the file at path is an image of the given format and returns its information.
+func inspect(ctx context.Context, path string, format harvesterv1.VirtualMachineImageFormat) (*imageInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err == nil {
+		var detected harvesterv1.VirtualMachineImageFormat
+		if detected, err = detectFileFormat(f, stat.Size()); err == nil && detected != format {
+			err = fmt.Errorf("the image is not a %s image, it looks like a %s image", format, detected)
+		}
+	}
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := runQemuImg(ctx, "info", "--output=json", "-f", string(format), path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s image: %w", format, err)
+	}
+	info := &imageInfo{}
+	if err := json.Unmarshal(out, info); err != nil {
+		return nil, fmt.Errorf("failed to parse the image information: %w", err)
+	}
+	if info.VirtualSize <= 0 {
+		return nil, fmt.Errorf("invalid %s image: virtual size is %d", format, info.VirtualSize)
+	}
+	return info, nil
+}
+
+func runQemuImg(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, qemuImg, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Run converts the image read from r as the spec of vmi asks for and records the outcome in the
+// Converted condition of vmi. The caller verifies the image file with the reader it passes.
+func Run(ctx context.Context, vmio common.VMIOperator, vmi *harvesterv1.VirtualMachineImage, r io.Reader) (*Image, error) {
+	logger := logrus.WithFields(logrus.Fields{
+		"namespace": vmi.Namespace,
+		"name":      vmi.Name,
+	})
+	if err := vmio.Converting(vmi); err != nil {
+		logger.WithError(err).Warn("failed to update the converted condition")
+	}
+
+	img, err := Convert(ctx, r, vmi.Spec.SourceFormat, TargetFormat(vmi))
+	if err != nil {
+		if ctx.Err() == nil {
+			if updateErr := vmio.FailConverted(vmi, err); updateErr != nil {
+				logger.WithError(updateErr).Warn("failed to update the converted condition")
+			}
+		}
+		return nil, err
+	}
+
+	logger.Infof("converted image from %s to %s, virtual size %d", vmi.Spec.SourceFormat, img.Format, img.VirtualSize)
+	if err := vmio.Converted(vmi, fmt.Sprintf("Converted from %s to %s", vmi.Spec.SourceFormat, img.Format)); err != nil {
+		logger.WithError(err).Warn("failed to update the converted condition")
+	}
+	return img, nil
+}
+
+// Download opens the image file at url and returns its size, which is -1 if the server does not report it.
+// The url, every redirect and every address dialed for them are checked against the blocked networks,
+// so an image URL can't reach into the cluster.
+func Download(ctx context.Context, blocked []*net.IPNet, url string) (io.ReadCloser, int64, error) {
+	if err := export.CheckURL(ctx, url, blocked); err != nil {
+		return nil, 0, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := export.NewHTTPClient(blocked).Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// UploadedFile returns the image file of an upload request, which is either the body or the file of a multipart form.
+func UploadedFile(req *http.Request) (io.Reader, error) {
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		return req.Body, nil
+	}
+
+	form, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := form.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("no file found in the upload form")
+			}
+			return nil, err
+		}
+		if part.FileName() != "" {
+			return part, nil
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/verify"
+)
+
+// fakeQemuImg reports FAKE_VIRTUAL_SIZE as the virtual size of any image, and converts an image by
+// dropping its 4 bytes magic number. FAKE_FAIL makes the given subcommand fail.
+const fakeQemuImg = `#!/bin/sh
+if [ "$1" = "$FAKE_FAIL" ]; then
+	echo "$1 failed" >&2
+	exit 1
+fi
+case "$1" in
+info)
+	echo "{\"format\": \"$4\", \"virtual-size\": $FAKE_VIRTUAL_SIZE}"
+	;;
+convert)
+	tail -c +5 "$6" > "$7"
+	;;
+esac
+`
+
+func setupFakeQemuImg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qemu-img")
+	require.NoError(t, os.WriteFile(path, []byte(fakeQemuImg), 0755))
+
+	previousQemuImg, previousDir := qemuImg, Dir
+	qemuImg, Dir = path, t.TempDir()
+	t.Cleanup(func() { qemuImg, Dir = previousQemuImg, previousDir })
+	t.Setenv("FAKE_VIRTUAL_SIZE", "1048576")
+}
+
+func TestDetectFormat(t *testing.T) {
+	fixedVHDFooter := append([]byte("conectix"), make([]byte, 504)...)
+
+	var tests = []struct {
+		name   string
+		header []byte
+		footer []byte
+		expect harvesterv1.VirtualMachineImageFormat
+	}{
+		{name: "qcow2", header: []byte("QFI\xfb\x00\x00\x00\x03"), expect: harvesterv1.VirtualMachineImageFormatQCOW2},
+		{name: "sparse vmdk", header: []byte("KDMV\x01\x00\x00\x00"), expect: harvesterv1.VirtualMachineImageFormatVMDK},
+		{name: "vmdk descriptor", header: []byte("# Disk DescriptorFile\nversion=1"), expect: harvesterv1.VirtualMachineImageFormatVMDK},
+		{name: "vhdx", header: []byte("vhdxfile"), expect: harvesterv1.VirtualMachineImageFormatVHDX},
+		{name: "dynamic vhd", header: []byte("conectix"), expect: harvesterv1.VirtualMachineImageFormatVPC},
+		{name: "fixed vhd", header: make([]byte, 512), footer: fixedVHDFooter, expect: harvesterv1.VirtualMachineImageFormatVPC},
+		{name: "raw", header: make([]byte, 512), footer: make([]byte, 512), expect: harvesterv1.VirtualMachineImageFormatRaw},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, DetectFormat(tc.header, tc.footer))
+		})
+	}
+}
+
+func TestNeedsConversion(t *testing.T) {
+	vmi := &harvesterv1.VirtualMachineImage{}
+	assert.False(t, NeedsConversion(vmi))
+
+	vmi.Spec.SourceFormat = harvesterv1.VirtualMachineImageFormatRaw
+	assert.False(t, NeedsConversion(vmi))
+
+	vmi.Spec.SourceFormat = harvesterv1.VirtualMachineImageFormatVMDK
+	assert.True(t, NeedsConversion(vmi))
+	assert.Equal(t, harvesterv1.VirtualMachineImageFormatRaw, TargetFormat(vmi))
+
+	vmi.Spec.SourceFormat = harvesterv1.VirtualMachineImageFormatQCOW2
+	vmi.Spec.TargetFormat = harvesterv1.VirtualMachineImageFormatQCOW2
+	assert.False(t, NeedsConversion(vmi))
+}
+
+func TestConvert(t *testing.T) {
+	setupFakeQemuImg(t)
+
+	payload := bytes.Repeat([]byte{0xab}, 4096)
+	source := append([]byte("KDMV"), payload...)
+	sum := sha512.Sum512(source)
+
+	checksum, err := verify.NewChecksumVerifier(hex.EncodeToString(sum[:]))
+	require.NoError(t, err)
+
+	img, err := Convert(context.Background(), verify.NewReader(bytes.NewReader(source), checksum), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	require.NoError(t, err)
+	assert.Equal(t, harvesterv1.VirtualMachineImageFormatRaw, img.Format)
+	assert.Equal(t, int64(len(payload)), img.Size)
+	assert.Equal(t, int64(1048576), img.VirtualSize)
+
+	req, err := img.UploadRequest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "4096", req.URL.Query().Get("size"))
+	converted, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, payload, converted)
+
+	require.NoError(t, img.Close())
+	entries, err := os.ReadDir(Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the staged files should be removed")
+}
+
+func TestConvertInvalidImage(t *testing.T) {
+	setupFakeQemuImg(t)
+	source := append([]byte("KDMV"), make([]byte, 1024)...)
+
+	var tests = []struct {
+		name         string
+		sourceFormat harvesterv1.VirtualMachineImageFormat
+		checksum     string
+		fail         string
+		errContains  string
+	}{
+		{
+			name:         "checksum mismatch",
+			sourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+			checksum:     "sha256:" + strings.Repeat("0", 64),
+			errContains:  "sha256 checksum mismatch",
+		},
+		{
+			name:         "wrong source format",
+			sourceFormat: harvesterv1.VirtualMachineImageFormatVHDX,
+			errContains:  "not a vhdx image, it looks like a vmdk image",
+		},
+		{
+			name:         "corrupted source",
+			sourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+			fail:         "info",
+			errContains:  "invalid vmdk image: info failed",
+		},
+		{
+			name:         "conversion failure",
+			sourceFormat: harvesterv1.VirtualMachineImageFormatVMDK,
+			fail:         "convert",
+			errContains:  "failed to convert the image from vmdk to raw: convert failed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("FAKE_FAIL", tc.fail)
+			var r io.Reader = bytes.NewReader(source)
+			if tc.checksum != "" {
+				checksum, err := verify.NewChecksumVerifier(tc.checksum)
+				require.NoError(t, err)
+				r = verify.NewReader(r, checksum)
+			}
+			_, err := Convert(context.Background(), r, tc.sourceFormat, harvesterv1.VirtualMachineImageFormatRaw)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errContains)
+
+			entries, err := os.ReadDir(Dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "the staged files should be removed")
+		})
+	}
+}
+
+func TestConvertSizeLimit(t *testing.T) {
+	setupFakeQemuImg(t)
+	dir := Dir
+	t.Cleanup(func() { MaxSourceSize = 0 })
+
+	require.NoError(t, Configure(dir, "2Ki"))
+	assert.Equal(t, dir, Dir)
+	assert.Equal(t, int64(1024), MaxSourceSize)
+
+	_, err := Convert(context.Background(), bytes.NewReader(append([]byte("KDMV"), make([]byte, 1024)...)), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	assert.ErrorContains(t, err, "the image file is larger than 1024 bytes")
+	entries, err := os.ReadDir(Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the staged files should be removed")
+
+	img, err := Convert(context.Background(), bytes.NewReader(append([]byte("KDMV"), make([]byte, 1020)...)), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	require.NoError(t, err)
+	require.NoError(t, img.Close())
+
+	assert.Error(t, Configure(dir, "a lot"))
+}
+
+func TestUploadedFile(t *testing.T) {
+	body := "--boundary\r\nContent-Disposition: form-data; name=\"chunk\"; filename=\"disk.vmdk\"\r\n\r\nKDMV-data\r\n--boundary--\r\n"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+
+	file, err := UploadedFile(req)
+	require.NoError(t, err)
+	data, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "KDMV-data", string(data))
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("KDMV-data"))
+	file, err = UploadedFile(req)
+	require.NoError(t, err)
+	data, err = io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, "KDMV-data", string(data))
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/redirect":
+			http.Redirect(w, req, "/image", http.StatusFound)
+			return
+		case "/redirect-loop":
+			http.Redirect(w, req, "/redirect-loop", http.StatusFound)
+			return
+		case "/redirect-blocked":
+			http.Redirect(w, req, "http://10.52.0.10/image", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("image"))
+	}))
+	defer server.Close()
+
+	body, size, err := Download(context.Background(), nil, server.URL+"/image")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+	assert.Equal(t, int64(5), size)
+
+	// the test server listens on the loopback network
+	_, _, err = Download(context.Background(), export.DefaultBlockedNetworks(), server.URL+"/image")
+	assert.ErrorContains(t, err, "blocked network")
+
+	body, _, err = Download(context.Background(), nil, server.URL+"/redirect")
+	require.NoError(t, err)
+	data, err = io.ReadAll(body)
+	body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(data))
+
+	_, _, err = Download(context.Background(), nil, server.URL+"/redirect-loop")
+	assert.ErrorContains(t, err, "stopped after 10 redirects")
+
+	// the first hop is allowed, the redirect points into the pod network
+	podNetwork, err := export.ParseCIDRs([]string{"10.52.0.0/16"})
+	require.NoError(t, err)
+	_, _, err = Download(context.Background(), podNetwork, server.URL+"/redirect-blocked")
+	assert.ErrorContains(t, err, "redirect to http://10.52.0.10/image")
+	assert.ErrorContains(t, err, "blocked network")
+
+	_, _, err = Download(context.Background(), nil, "file:///etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestConvertOneAtATime(t *testing.T) {
+	setupFakeQemuImg(t)
+	source := append([]byte("KDMV"), bytes.Repeat([]byte{0xab}, 4096)...)
+
+	img, err := Convert(context.Background(), bytes.NewReader(source), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	require.NoError(t, err)
+
+	// the second conversion waits for the staged image of the first one
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = Convert(ctx, bytes.NewReader(source), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, img.Close())
+	require.NoError(t, img.Close())
+	img, err = Convert(context.Background(), bytes.NewReader(source), harvesterv1.VirtualMachineImageFormatVMDK, harvesterv1.VirtualMachineImageFormatRaw)
+	require.NoError(t, err)
+	require.NoError(t, img.Close())
+}
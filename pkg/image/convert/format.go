@@ -0,0 +1,56 @@
+package convert
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const headerSize = 512
+
+var (
+	qcow2Magic = []byte("QFI\xfb")
+	// KDMV is the magic of hosted sparse extents, COWD the one of the older ESX sparse extents
+	vmdkSparseMagic     = []byte("KDMV")
+	vmdkCOWDMagic       = []byte("COWD")
+	vmdkDescriptorMagic = []byte("# Disk DescriptorFile")
+	vhdxMagic           = []byte("vhdxfile")
+	// dynamic VHDs start with a copy of the footer, fixed VHDs only have the footer at the end
+	vpcMagic = []byte("conectix")
+)
+
+// DetectFormat returns the format of an image by its first and last 512 bytes, anything unknown is raw.
+func DetectFormat(header, footer []byte) harvesterv1.VirtualMachineImageFormat {
+	switch {
+	case bytes.HasPrefix(header, qcow2Magic):
+		return harvesterv1.VirtualMachineImageFormatQCOW2
+	case bytes.HasPrefix(header, vmdkSparseMagic), bytes.HasPrefix(header, vmdkCOWDMagic), bytes.HasPrefix(header, vmdkDescriptorMagic):
+		return harvesterv1.VirtualMachineImageFormatVMDK
+	case bytes.HasPrefix(header, vhdxMagic):
+		return harvesterv1.VirtualMachineImageFormatVHDX
+	case bytes.HasPrefix(header, vpcMagic), bytes.HasPrefix(footer, vpcMagic):
+		return harvesterv1.VirtualMachineImageFormatVPC
+	default:
+		return harvesterv1.VirtualMachineImageFormatRaw
+	}
+}
+
+func detectFileFormat(f *os.File, size int64) (harvesterv1.VirtualMachineImageFormat, error) {
+	header := make([]byte, headerSize)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	var footer []byte
+	if size >= headerSize {
+		footer = make([]byte, headerSize)
+		if _, err := f.ReadAt(footer, size-headerSize); err != nil && err != io.EOF {
+			return "", err
+		}
+	}
+	return DetectFormat(header, footer), nil
+}
@@ -0,0 +1,71 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	// SecretTokenKey is the secret key holding the bearer token of an http export target.
+	SecretTokenKey = "token"
+)
+
+// Uploader pushes an image stream to an export target.
+type Uploader interface {
+	// Upload reads r to the end and stores it on the target. size is -1 if unknown.
+	Upload(ctx context.Context, r io.Reader, size int64) error
+	// URL returns where the image is stored on the target.
+	URL() string
+}
+
+// NewUploader returns the uploader of the target pushing through client. data holds the
+// credentials of the target and may be nil.
+func NewUploader(target *harvesterv1.VirtualMachineImageExportTarget, data map[string][]byte, defaultKey string, client *http.Client) (Uploader, error) {
+	switch target.Type {
+	case harvesterv1.ImageExportTargetTypeHTTP:
+		return newHTTPUploader(target, data, client)
+	case harvesterv1.ImageExportTargetTypeS3:
+		return newS3Uploader(target, data, defaultKey, client)
+	default:
+		return nil, fmt.Errorf("unsupported export target type %q", target.Type)
+	}
+}
+
+// ProgressReader counts and checksums the bytes read through it.
+type ProgressReader struct {
+	reader io.Reader
+	hash   hash.Hash
+	read   atomic.Int64
+}
+
+func NewProgressReader(r io.Reader) *ProgressReader {
+	return &ProgressReader{
+		reader: r,
+		hash:   sha256.New(),
+	}
+}
+
+func (p *ProgressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.hash.Write(b[:n])
+	p.read.Add(int64(n))
+	return n, err
+}
+
+// BytesRead may be called while the reader is being read.
+func (p *ProgressReader) BytesRead() int64 {
+	return p.read.Load()
+}
+
+// Checksum returns the sha256 checksum of the bytes read so far, it must not be called while the reader is being read.
+func (p *ProgressReader) Checksum() string {
+	return hex.EncodeToString(p.hash.Sum(nil))
+}
@@ -0,0 +1,205 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func TestProgressReader(t *testing.T) {
+	data := []byte("virtual machine image")
+	reader := NewProgressReader(bytes.NewReader(data))
+
+	read, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+	assert.Equal(t, int64(len(data)), reader.BytesRead())
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), reader.Checksum())
+}
+
+func TestHTTPUploader(t *testing.T) {
+	data := []byte("virtual machine image")
+
+	var tests = []struct {
+		name       string
+		secret     map[string][]byte
+		status     int
+		expectAuth string
+		expectErr  bool
+	}{
+		{
+			name:   "anonymous",
+			status: http.StatusCreated,
+		},
+		{
+			name: "basic auth",
+			secret: map[string][]byte{
+				corev1.BasicAuthUsernameKey: []byte("user"),
+				corev1.BasicAuthPasswordKey: []byte("pass"),
+			},
+			status:     http.StatusOK,
+			expectAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name: "bearer token",
+			secret: map[string][]byte{
+				SecretTokenKey: []byte("secret-token"),
+			},
+			status:     http.StatusNoContent,
+			expectAuth: "Bearer secret-token",
+		},
+		{
+			name:      "rejected",
+			status:    http.StatusForbidden,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body []byte
+			var auth string
+			var contentLength int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPut, r.Method)
+				auth = r.Header.Get("Authorization")
+				contentLength = r.ContentLength
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			target := &harvesterv1.VirtualMachineImageExportTarget{
+				Type: harvesterv1.ImageExportTargetTypeHTTP,
+				URL:  server.URL + "/images/image.qcow2",
+			}
+			uploader, err := NewUploader(target, tc.secret, "", NewHTTPClient(nil))
+			require.NoError(t, err)
+			assert.Equal(t, target.URL, uploader.URL())
+
+			err = uploader.Upload(context.Background(), bytes.NewReader(data), int64(len(data)))
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, data, body)
+			assert.Equal(t, int64(len(data)), contentLength)
+			assert.Equal(t, tc.expectAuth, auth)
+		})
+	}
+}
+
+func TestNewUploaderInvalidTarget(t *testing.T) {
+	_, err := NewUploader(&harvesterv1.VirtualMachineImageExportTarget{
+		Type: harvesterv1.ImageExportTargetTypeHTTP,
+		URL:  "ftp://example.com/image.qcow2",
+	}, nil, "", NewHTTPClient(nil))
+	assert.Error(t, err)
+
+	_, err = NewUploader(&harvesterv1.VirtualMachineImageExportTarget{
+		Type: harvesterv1.ImageExportTargetTypeS3,
+	}, nil, "", NewHTTPClient(nil))
+	assert.Error(t, err)
+}
+
+// fakeS3 implements just enough of the multipart upload API for the uploader.
+type fakeS3 struct {
+	mu      sync.Mutex
+	parts   map[int][]byte
+	objects map[string][]byte
+	aborted bool
+	failPut bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.parts = map[int][]byte{}
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		if f.failPut {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		partNumber, _ := strconv.Atoi(query.Get("partNumber"))
+		f.parts[partNumber], _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, partNumber))
+	case r.Method == http.MethodPost && query.Get("uploadId") == "upload-1":
+		numbers := make([]int, 0, len(f.parts))
+		for n := range f.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var object []byte
+		for _, n := range numbers {
+			object = append(object, f.parts[n]...)
+		}
+		f.objects[r.URL.Path] = object
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "upload-1":
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestS3Uploader(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	secret := map[string][]byte{
+		util.AWSAccessKey: []byte("access"),
+		util.AWSSecretKey: []byte("secret"),
+	}
+	target := &harvesterv1.VirtualMachineImageExportTarget{
+		Type:   harvesterv1.ImageExportTargetTypeS3,
+		URL:    server.URL,
+		Bucket: "images",
+	}
+
+	uploader, err := NewUploader(target, secret, "default/image.qcow2", NewHTTPClient(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "s3://images/default/image.qcow2", uploader.URL())
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	require.NoError(t, uploader.Upload(context.Background(), bytes.NewReader(data), int64(len(data))))
+	assert.Equal(t, data, fake.objects["/images/default/image.qcow2"])
+
+	// an explicit key wins over the default one
+	target.Key = "/exports/image.raw"
+	uploader, err = NewUploader(target, secret, "default/image.qcow2", NewHTTPClient(nil))
+	require.NoError(t, err)
+	require.NoError(t, uploader.Upload(context.Background(), bytes.NewReader(nil), 0))
+	object, ok := fake.objects["/images/exports/image.raw"]
+	assert.True(t, ok)
+	assert.Empty(t, object)
+
+	fake.failPut = true
+	assert.Error(t, uploader.Upload(context.Background(), bytes.NewReader(data), int64(len(data))))
+	assert.True(t, fake.aborted)
+}
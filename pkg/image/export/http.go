@@ -0,0 +1,74 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const maxErrorBodySize = 4096
+
+type httpUploader struct {
+	url        string
+	username   string
+	password   string
+	token      string
+	httpClient *http.Client
+}
+
+func newHTTPUploader(target *harvesterv1.VirtualMachineImageExportTarget, data map[string][]byte, client *http.Client) (*httpUploader, error) {
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("export url must be http or https")
+	}
+
+	return &httpUploader{
+		url:        target.URL,
+		username:   string(data[corev1.BasicAuthUsernameKey]),
+		password:   string(data[corev1.BasicAuthPasswordKey]),
+		token:      string(data[SecretTokenKey]),
+		httpClient: client,
+	}, nil
+}
+
+func (u *httpUploader) URL() string {
+	return u.url
+}
+
+func (u *httpUploader) Upload(ctx context.Context, r io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.url, r)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	switch {
+	case u.token != "":
+		req.Header.Set("Authorization", "Bearer "+u.token)
+	case u.username != "" || u.password != "":
+		req.SetBasicAuth(u.username, u.password)
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("upload to %s failed with status %s: %s", u.url, resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	// CommandImageExport is the data mover command pushing an image to an export target.
+	CommandImageExport = "image-export"
+	// EnvExportTarget carries the JSON encoded export target of the Job.
+	EnvExportTarget = "EXPORT_TARGET"
+	// SecretMountPath is where the Job mounts the secret of the export target.
+	SecretMountPath = "/etc/export-secret"
+
+	progressInterval = 30 * time.Second
+	containerName    = "image-export"
+	secretVolumeName = "export-secret"
+	jobBackoffLimit  = 0
+)
+
+// JobOptions describes the Job pushing the image served by the downloader to the export target.
+type JobOptions struct {
+	Name            string
+	Namespace       string
+	Labels          map[string]string
+	OwnerReferences []metav1.OwnerReference
+	Image           settings.Image
+	// Command is the data mover binary, which runs CommandImageExport.
+	Command    string
+	Target     *harvesterv1.VirtualMachineImageExportTarget
+	SourceURL  string
+	DefaultKey string
+	// BlockedCIDRs are refused by the Job on top of DefaultBlockedNetworks.
+	BlockedCIDRs []string
+}
+
+// Result is what the Job reports in its termination message.
+type Result struct {
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+}
+
+// BuildJob builds the Job running the export. It gets no service account token
+// and no capabilities, the secret of the target is the only credential it sees.
+func BuildJob(opts JobOptions) (*batchv1.Job, error) {
+	targetJSON, err := json.Marshal(opts.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		CommandImageExport,
+		"--source-url", opts.SourceURL,
+		"--default-key", opts.DefaultKey,
+	}
+	for _, cidr := range opts.BlockedCIDRs {
+		args = append(args, "--blocked-cidr", cidr)
+	}
+
+	container := corev1.Container{
+		Name:            containerName,
+		Image:           opts.Image.ImageName(),
+		ImagePullPolicy: opts.Image.GetImagePullPolicy(),
+		Command:         []string{opts.Command},
+		Args:            args,
+		Env: []corev1.EnvVar{{
+			Name:  EnvExportTarget,
+			Value: string(targetJSON),
+		}},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		SecurityContext: &corev1.SecurityContext{
+			Privileged:               ptr.To(false),
+			AllowPrivilegeEscalation: ptr.To(false),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+		},
+	}
+	var volumes []corev1.Volume
+	if opts.Target.SecretName != "" {
+		container.VolumeMounts = []corev1.VolumeMount{{
+			Name:      secretVolumeName,
+			MountPath: SecretMountPath,
+			ReadOnly:  true,
+		}}
+		volumes = []corev1.Volume{{
+			Name: secretVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: opts.Target.SecretName},
+			},
+		}}
+	}
+
+	labels := map[string]string{
+		util.LabelGeneratedBy: util.ValueGeneratedByHarvester,
+	}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            opts.Name,
+			Namespace:       opts.Namespace,
+			Labels:          labels,
+			OwnerReferences: opts.OwnerReferences,
+		},
+		Spec: batchv1.JobSpec{
+			// a failed upload is reported, the user recreates the downloader to retry
+			BackoffLimit: ptr.To(int32(jobBackoffLimit)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: ptr.To(false),
+					Containers:                   []corev1.Container{container},
+					Volumes:                      volumes,
+				},
+			},
+		},
+	}, nil
+}
+
+// BuildNetworkPolicy builds the egress policy of the Job pods selected by podLabels. They
+// may reach the downloader pods selected by sourceLabels, the cluster DNS and any address
+// outside of the blocked CIDRs.
+func BuildNetworkPolicy(
+	name, namespace string,
+	ownerReferences []metav1.OwnerReference,
+	podLabels, sourceLabels map[string]string,
+	blockedCIDRs []string,
+) *networkingv1.NetworkPolicy {
+	ipv4 := &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}
+	ipv6 := &networkingv1.IPBlock{CIDR: "::/0"}
+	for _, cidr := range append(append([]string{}, defaultBlockedCIDRs...), blockedCIDRs...) {
+		ip, _, err := net.ParseCIDR(cidr)
+		switch {
+		case err != nil:
+			continue
+		case ip.To4() != nil:
+			// 0.0.0.0/0 itself can't be an exception of 0.0.0.0/0
+			if cidr != ipv4.CIDR {
+				ipv4.Except = append(ipv4.Except, cidr)
+			}
+		default:
+			if cidr != ipv6.CIDR {
+				ipv6.Except = append(ipv6.Except, cidr)
+			}
+		}
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			OwnerReferences: ownerReferences,
+			Labels: map[string]string{
+				util.LabelGeneratedBy: util.ValueGeneratedByHarvester,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: podLabels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Egress: []networkingv1.NetworkPolicyEgressRule{
+				{
+					To: []networkingv1.NetworkPolicyPeer{{
+						PodSelector: &metav1.LabelSelector{MatchLabels: sourceLabels},
+					}},
+				},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{
+						NamespaceSelector: &metav1.LabelSelector{},
+						PodSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"k8s-app": "kube-dns"},
+						},
+					}},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(53))},
+						{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(53))},
+					},
+				},
+				{
+					To: []networkingv1.NetworkPolicyPeer{{IPBlock: ipv4}, {IPBlock: ipv6}},
+				},
+			},
+		},
+	}
+}
+
+// ParseResult returns the result the Job reported in the termination message of its pod.
+func ParseResult(pod *corev1.Pod) (*Result, error) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != containerName || status.State.Terminated == nil {
+			continue
+		}
+		result := &Result{}
+		if err := json.Unmarshal([]byte(status.State.Terminated.Message), result); err != nil {
+			return nil, fmt.Errorf("invalid export result of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("pod %s/%s has no export result", pod.Namespace, pod.Name)
+}
+
+// ReadSecretDir reads the secret mounted at dir, it returns nil if nothing is mounted.
+func ReadSecretDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for _, entry := range entries {
+		// the kubelet keeps the versioned data in hidden directories
+		if entry.IsDir() || entry.Name()[0] == '.' {
+			continue
+		}
+		value, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = value
+	}
+	return data, nil
+}
+
+// Run streams the image served at sourceURL to the uploader.
+func Run(ctx context.Context, sourceClient *http.Client, sourceURL string, uploader Uploader) (*Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sourceClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image from %s: %w", sourceURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download image from %s: %s", sourceURL, resp.Status)
+	}
+
+	total := resp.ContentLength
+	reader := NewProgressReader(resp.Body)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				logrus.Infof("Uploaded %d of %d bytes to %s", reader.BytesRead(), total, uploader.URL())
+			}
+		}
+	}()
+
+	if err := uploader.Upload(ctx, reader, total); err != nil {
+		return nil, err
+	}
+	if total > 0 && reader.BytesRead() != total {
+		return nil, fmt.Errorf("image is truncated, read %d of %d bytes", reader.BytesRead(), total)
+	}
+	return &Result{Checksum: reader.Checksum(), Size: reader.BytesRead()}, nil
+}
+
+// NewSourceClient returns the client downloading the image from the downloader service
+// inside the cluster, which must not hang forever either.
+func NewSourceClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       idleConnTimeout,
+		},
+	}
+}
+
+// WriteResult writes the result to the termination message of the Job pod.
+func WriteResult(w io.Writer, result *Result) error {
+	return json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func TestBuildJob(t *testing.T) {
+	target := &harvesterv1.VirtualMachineImageExportTarget{
+		Type:       harvesterv1.ImageExportTargetTypeS3,
+		Bucket:     "images",
+		SecretName: "s3-credentials",
+	}
+	job, err := BuildJob(JobOptions{
+		Name:         "downloader-export",
+		Namespace:    "default",
+		Labels:       map[string]string{"export": "downloader"},
+		Command:      "harvester-datamover",
+		Target:       target,
+		SourceURL:    "http://downloader-downloader.default/images/image.qcow2",
+		DefaultKey:   "default/image.qcow2",
+		BlockedCIDRs: []string{"10.52.0.0/16"},
+	})
+	require.NoError(t, err)
+
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, "downloader", job.Spec.Template.Labels["export"])
+	assert.False(t, *podSpec.AutomountServiceAccountToken)
+	require.Len(t, podSpec.Containers, 1)
+	container := podSpec.Containers[0]
+	assert.Equal(t, []string{"harvester-datamover"}, container.Command)
+	assert.Equal(t, []string{
+		CommandImageExport,
+		"--source-url", "http://downloader-downloader.default/images/image.qcow2",
+		"--default-key", "default/image.qcow2",
+		"--blocked-cidr", "10.52.0.0/16",
+	}, container.Args)
+	assert.False(t, *container.SecurityContext.Privileged)
+	assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation)
+	assert.Equal(t, []corev1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+	assert.JSONEq(t, `{"type":"s3","bucket":"images","secretName":"s3-credentials"}`, container.Env[0].Value)
+	require.Len(t, podSpec.Volumes, 1)
+	assert.Equal(t, "s3-credentials", podSpec.Volumes[0].Secret.SecretName)
+	assert.Equal(t, SecretMountPath, container.VolumeMounts[0].MountPath)
+}
+
+func TestBuildNetworkPolicy(t *testing.T) {
+	policy := BuildNetworkPolicy("downloader-export", "default", nil,
+		map[string]string{"export": "downloader"},
+		map[string]string{"app": "downloader-downloader"},
+		[]string{"10.52.0.0/16", "fd00:52::/64"})
+
+	require.Len(t, policy.Spec.Egress, 3)
+	assert.Equal(t, "downloader-downloader", policy.Spec.Egress[0].To[0].PodSelector.MatchLabels["app"])
+	ipBlocks := policy.Spec.Egress[2].To
+	require.Len(t, ipBlocks, 2)
+	assert.Equal(t, "0.0.0.0/0", ipBlocks[0].IPBlock.CIDR)
+	assert.Contains(t, ipBlocks[0].IPBlock.Except, "10.52.0.0/16")
+	assert.Contains(t, ipBlocks[0].IPBlock.Except, "169.254.0.0/16")
+	assert.NotContains(t, ipBlocks[0].IPBlock.Except, "fd00:52::/64")
+	assert.Equal(t, "::/0", ipBlocks[1].IPBlock.CIDR)
+	assert.Contains(t, ipBlocks[1].IPBlock.Except, "fd00:52::/64")
+}
+
+func TestParseResult(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "downloader-export-abcde", Namespace: "default"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: containerName,
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{Message: `{"checksum":"abc","size":42}` + "\n"},
+				},
+			}},
+		},
+	}
+	result, err := ParseResult(pod)
+	require.NoError(t, err)
+	assert.Equal(t, &Result{Checksum: "abc", Size: 42}, result)
+
+	pod.Status.ContainerStatuses[0].State.Terminated = nil
+	_, err = ParseResult(pod)
+	assert.Error(t, err)
+}
+
+func TestReadSecretDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, SecretTokenKey), []byte("secret-token"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0700))
+
+	data, err := ReadSecretDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{SecretTokenKey: []byte("secret-token")}, data)
+
+	data, err = ReadSecretDir(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func TestRun(t *testing.T) {
+	image := []byte("virtual machine image")
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(image)
+	}))
+	defer source.Close()
+
+	var uploaded []byte
+	target := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		uploaded, _ = io.ReadAll(r.Body)
+	}))
+	defer target.Close()
+
+	uploader, err := NewUploader(&harvesterv1.VirtualMachineImageExportTarget{
+		Type: harvesterv1.ImageExportTargetTypeHTTP,
+		URL:  target.URL + "/image.qcow2",
+	}, nil, "", NewHTTPClient(nil))
+	require.NoError(t, err)
+
+	result, err := Run(context.Background(), NewSourceClient(), source.URL, uploader)
+	require.NoError(t, err)
+	sum := sha256.Sum256(image)
+	assert.Equal(t, &Result{Checksum: hex.EncodeToString(sum[:]), Size: int64(len(image))}, result)
+	assert.Equal(t, image, uploaded)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteResult(&buf, result))
+	assert.JSONEq(t, `{"checksum":"`+result.Checksum+`","size":21}`, buf.String())
+}
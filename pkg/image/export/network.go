@@ -0,0 +1,215 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	dialTimeout           = 30 * time.Second
+	tlsHandshakeTimeout   = 30 * time.Second
+	responseHeaderTimeout = 5 * time.Minute
+	idleConnTimeout       = 90 * time.Second
+	maxRedirects          = 10
+)
+
+// defaultBlockedCIDRs are never a valid export target, whatever the cluster networks are.
+var defaultBlockedCIDRs = []string{
+	"0.0.0.0/8",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"224.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// DefaultBlockedNetworks returns the loopback, link-local, unspecified and multicast networks.
+func DefaultBlockedNetworks() []*net.IPNet {
+	networks, _ := ParseCIDRs(defaultBlockedCIDRs)
+	return networks
+}
+
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ClusterCIDRs returns the pod CIDRs of the nodes and the service CIDRs of the cluster,
+// an export must not reach the workloads and services inside the cluster.
+func ClusterCIDRs(ctx context.Context, clientset kubernetes.Interface) ([]string, error) {
+	var cidrs []string
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		cidrs = append(cidrs, node.Spec.PodCIDRs...)
+		if len(node.Spec.PodCIDRs) == 0 && node.Spec.PodCIDR != "" {
+			cidrs = append(cidrs, node.Spec.PodCIDR)
+		}
+	}
+
+	serviceCIDRs, err := clientset.NetworkingV1().ServiceCIDRs().List(ctx, metav1.ListOptions{})
+	// the ServiceCIDR API is only served since Kubernetes 1.33
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to list service cidrs: %w", err)
+	}
+	if err == nil {
+		for _, serviceCIDR := range serviceCIDRs.Items {
+			cidrs = append(cidrs, serviceCIDR.Spec.CIDRs...)
+		}
+	}
+	return cidrs, nil
+}
+
+// BlockedNetworks returns the default blocked networks and the cluster CIDRs.
+func BlockedNetworks(ctx context.Context, clientset kubernetes.Interface) ([]*net.IPNet, error) {
+	cidrs, err := ClusterCIDRs(ctx, clientset)
+	if err != nil {
+		return nil, err
+	}
+	clusterNetworks, err := ParseCIDRs(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	return append(DefaultBlockedNetworks(), clusterNetworks...), nil
+}
+
+// CheckIP returns an error if ip is in one of the blocked networks.
+func CheckIP(ip net.IP, blocked []*net.IPNet) error {
+	for _, network := range blocked {
+		if network.Contains(ip) {
+			return fmt.Errorf("address %s is in the blocked network %s", ip, network)
+		}
+	}
+	return nil
+}
+
+// CheckURL returns an error if rawURL is not an http or https URL, or its host
+// resolves to an address in one of the blocked networks.
+func CheckURL(ctx context.Context, rawURL string, blocked []*net.IPNet) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url must be http or https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("url must have a host")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not carry credentials, use the secret instead")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return CheckIP(ip, blocked)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if err := CheckIP(addr.IP, blocked); err != nil {
+			return fmt.Errorf("host %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// NewDialer returns a dialer refusing to connect to the blocked networks. The check runs
+// on the resolved address of every connection, so a host resolving to a blocked address
+// after it was validated is refused as well.
+func NewDialer(blocked []*net.IPNet) *net.Dialer {
+	return &net.Dialer{
+		Timeout: dialTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return fmt.Errorf("invalid address %s", address)
+			}
+			return CheckIP(ip, blocked)
+		},
+	}
+}
+
+// NewHTTPClient returns a client refusing to connect to the blocked networks, see NewDialer.
+// Requests go through the proxy of the environment, which follows the http-proxy setting;
+// the proxy is trusted, the hosts it's asked for were checked by CheckURL. Redirects are
+// followed up to maxRedirects times, and every one of them is checked by CheckURL too.
+// There's no overall timeout, an image upload can take hours.
+func NewHTTPClient(blocked []*net.IPNet) *http.Client {
+	dialer := NewDialer(blocked)
+	proxyDialer := &net.Dialer{Timeout: dialTimeout}
+	proxies := proxyAddresses()
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if proxies[address] {
+					return proxyDialer.DialContext(ctx, network, address)
+				}
+				return dialer.DialContext(ctx, network, address)
+			},
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   tlsHandshakeTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       idleConnTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if err := CheckURL(req.Context(), req.URL.String(), blocked); err != nil {
+				return fmt.Errorf("redirect to %s: %w", req.URL.Redacted(), err)
+			}
+			return nil
+		},
+	}
+}
+
+// proxyAddresses returns the host:port of the proxies of the environment, which are
+// dialed without checking the blocked networks, e.g. a proxy in the cluster network.
+func proxyAddresses() map[string]bool {
+	config := httpproxy.FromEnvironment()
+	addresses := map[string]bool{}
+	for _, proxy := range []string{config.HTTPProxy, config.HTTPSProxy} {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		addresses[net.JoinHostPort(u.Hostname(), port)] = true
+	}
+	return addresses
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/sirupsen/logrus"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	// s3PartSize is the size of a multipart upload part, S3 requires at least 5MiB
+	// for all parts but the last and allows at most 10000 parts.
+	s3PartSize = 64 << 20
+	// s3MaxParts is the maximum number of parts of a multipart upload.
+	s3MaxParts = 10000
+)
+
+type s3Uploader struct {
+	client *s3.S3
+	bucket string
+	key    string
+	url    string
+}
+
+func newS3Uploader(target *harvesterv1.VirtualMachineImageExportTarget, data map[string][]byte, defaultKey string, client *http.Client) (*s3Uploader, error) {
+	if target.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required for the s3 export target")
+	}
+
+	config := aws.NewConfig().WithRegion(target.Region).WithHTTPClient(client)
+	if config.Region == nil || *config.Region == "" {
+		config = config.WithRegion("us-east-1")
+	}
+	if target.URL != "" {
+		if _, err := url.Parse(target.URL); err != nil {
+			return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+		}
+		config = config.WithEndpoint(target.URL).WithS3ForcePathStyle(true)
+	}
+	if accessKey := string(data[util.AWSAccessKey]); accessKey != "" {
+		config = config.WithCredentials(credentials.NewStaticCredentials(accessKey, string(data[util.AWSSecretKey]), ""))
+	}
+
+	options := session.Options{Config: *config}
+	if cert := data[util.AWSCERT]; len(cert) > 0 {
+		options.CustomCABundle = bytes.NewReader(cert)
+	}
+	sess, err := session.NewSessionWithOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 session: %w", err)
+	}
+
+	key := strings.TrimPrefix(target.Key, "/")
+	if key == "" {
+		key = defaultKey
+	}
+	return &s3Uploader{
+		client: s3.New(sess),
+		bucket: target.Bucket,
+		key:    key,
+		url:    fmt.Sprintf("s3://%s/%s", target.Bucket, key),
+	}, nil
+}
+
+func (u *s3Uploader) URL() string {
+	return u.url
+}
+
+// Upload streams r with a multipart upload, so the image size does not need to be known up front.
+func (u *s3Uploader) Upload(ctx context.Context, r io.Reader, size int64) error {
+	partSize := int64(s3PartSize)
+	if size > partSize*s3MaxParts {
+		partSize = size/s3MaxParts + 1
+	}
+
+	created, err := u.client.CreateMultipartUploadWithContext(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(u.bucket),
+		Key:         aws.String(u.key),
+		ContentType: aws.String("application/octet-stream"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create multipart upload to %s: %w", u.url, err)
+	}
+
+	if err := u.uploadParts(ctx, r, created.UploadId, partSize); err != nil {
+		// abort with a fresh context, ctx may be the reason of the failure
+		if _, abortErr := u.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(u.bucket),
+			Key:      aws.String(u.key),
+			UploadId: created.UploadId,
+		}); abortErr != nil {
+			logrus.WithError(abortErr).Warnf("failed to abort multipart upload to %s", u.url)
+		}
+		return err
+	}
+	return nil
+}
+
+func (u *s3Uploader) uploadParts(ctx context.Context, r io.Reader, uploadID *string, partSize int64) error {
+	var completed []*s3.CompletedPart
+	buf := make([]byte, partSize)
+	for partNumber := int64(1); ; partNumber++ {
+		n, readErr := io.ReadFull(r, buf)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return readErr
+		}
+		// an empty image still needs one part to complete the upload
+		if n == 0 && len(completed) > 0 {
+			break
+		}
+
+		part, err := u.client.UploadPartWithContext(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(u.bucket),
+			Key:        aws.String(u.key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int64(partNumber),
+			Body:       bytes.NewReader(buf[:n]),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d to %s: %w", partNumber, u.url, err)
+		}
+		completed = append(completed, &s3.CompletedPart{ETag: part.ETag, PartNumber: aws.Int64(partNumber)})
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	_, err := u.client.CompleteMultipartUploadWithContext(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload to %s: %w", u.url, err)
+	}
+	return nil
+}
@@ -0,0 +1,553 @@
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// diskDirectory is where container disks keep the disk image inside their filesystem layer.
+	diskDirectory = "disk/"
+
+	maxManifestSize = 4 << 20
+	tokenClientID   = "harvester"
+)
+
+var manifestAccept = strings.Join([]string{
+	MediaTypeOCIIndex,
+	MediaTypeOCIManifest,
+	MediaTypeDockerManifestList,
+	MediaTypeDockerManifest,
+}, ", ")
+
+type platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+}
+
+// Image is an image reference resolved against one of the registry endpoints.
+type Image struct {
+	Reference *Reference
+	// Digest is the manifest digest the reference resolved to.
+	Digest string
+	// Repository is the repository on the endpoint, after mirror rewrites are applied.
+	Repository string
+
+	endpoint   endpoint
+	httpClient *http.Client
+	layers     []descriptor
+}
+
+// Host returns the host of the endpoint the image was resolved from.
+func (i *Image) Host() string {
+	return i.endpoint.base.Host
+}
+
+// IsContainerDisk returns true if the disk image is stored in a filesystem layer rather than as an artifact layer.
+func (i *Image) IsContainerDisk() bool {
+	for _, layer := range i.layers {
+		if !isTarLayer(layer.MediaType) {
+			return false
+		}
+	}
+	return true
+}
+
+// PullURL returns a docker:// URL pinning the resolved manifest on the endpoint it was resolved from.
+func (i *Image) PullURL() string {
+	return TransportPrefix + i.endpoint.base.Host + "/" + i.Repository + "@" + i.Digest
+}
+
+// Client pulls disk images stored in OCI registries, either as KubeVirt container disks
+// (a disk image under /disk in a filesystem layer) or as OCI artifacts with the disk image as a layer.
+type Client struct {
+	config *Config
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+func NewClient(config *Config) *Client {
+	return &Client{
+		config: config,
+		tokens: map[string]string{},
+	}
+}
+
+// Credentials returns the username and password configured for the endpoint the image was resolved from.
+func (c *Client) Credentials(img *Image) (string, string, error) {
+	username, password, _, err := c.config.credentials(&img.endpoint)
+	return username, password, err
+}
+
+// Resolve resolves the reference to a manifest digest, trying the configured mirrors before the registry itself.
+func (c *Client) Resolve(ctx context.Context, ref *Reference) (*Image, error) {
+	endpoints, err := c.config.endpoints(ref.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, e := range endpoints {
+		img, err := c.resolve(ctx, ref, e)
+		if err == nil {
+			return img, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.base.Host, err))
+	}
+	return nil, fmt.Errorf("failed to resolve image %s: %w", ref, errors.Join(errs...))
+}
+
+func (c *Client) resolve(ctx context.Context, ref *Reference, e endpoint) (*Image, error) {
+	repository, err := e.repository(ref.Repository)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := c.config.httpClient(&e)
+	if err != nil {
+		return nil, err
+	}
+	img := &Image{
+		Reference:  ref,
+		Repository: repository,
+		endpoint:   e,
+		httpClient: httpClient,
+	}
+
+	m, digest, err := c.fetchManifest(ctx, img, ref.Identifier())
+	if err != nil {
+		return nil, err
+	}
+	img.Digest = digest
+
+	if len(m.Manifests) > 0 {
+		desc, err := selectPlatform(m.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		if m, _, err = c.fetchManifest(ctx, img, desc.Digest); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.Layers) == 0 {
+		return nil, fmt.Errorf("manifest %s has no layers", digest)
+	}
+	img.layers = m.Layers
+	return img, nil
+}
+
+func (c *Client) fetchManifest(ctx context.Context, img *Image, identifier string) (*manifest, string, error) {
+	resp, err := c.get(ctx, img, "manifests/"+identifier, manifestAccept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxManifestSize {
+		return nil, "", fmt.Errorf("manifest %s exceeds %d bytes", identifier, maxManifestSize)
+	}
+
+	algorithm := "sha256"
+	if digestRegexp.MatchString(identifier) {
+		algorithm, _, _ = strings.Cut(identifier, ":")
+	}
+	h, err := newHash(algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+	h.Write(body)
+	digest := algorithm + ":" + hex.EncodeToString(h.Sum(nil))
+	if digestRegexp.MatchString(identifier) && digest != identifier {
+		return nil, "", fmt.Errorf("manifest digest mismatch: expected %s, got %s", identifier, digest)
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, "", fmt.Errorf("failed to parse manifest %s: %w", identifier, err)
+	}
+	return m, digest, nil
+}
+
+func selectPlatform(manifests []descriptor) (*descriptor, error) {
+	for i := range manifests {
+		p := manifests[i].Platform
+		if p != nil && p.OS == "linux" && p.Architecture == runtime.GOARCH {
+			return &manifests[i], nil
+		}
+	}
+	if len(manifests) == 1 && manifests[0].Platform == nil {
+		return &manifests[0], nil
+	}
+	return nil, fmt.Errorf("no manifest found for platform linux/%s", runtime.GOARCH)
+}
+
+// OpenDisk opens the disk image carried by the image and returns it along with its size.
+// The layer digest is verified once the returned reader reaches EOF.
+func (c *Client) OpenDisk(ctx context.Context, img *Image) (io.ReadCloser, int64, error) {
+	var artifact *descriptor
+	for i := range img.layers {
+		layer := &img.layers[i]
+		if isTarLayer(layer.MediaType) {
+			continue
+		}
+		if artifact == nil || layer.Size > artifact.Size {
+			artifact = layer
+		}
+	}
+	if artifact != nil {
+		blob, err := c.openBlob(ctx, img, artifact)
+		if err != nil {
+			return nil, 0, err
+		}
+		return blob, artifact.Size, nil
+	}
+
+	for i := len(img.layers) - 1; i >= 0; i-- {
+		disk, size, err := c.openContainerDisk(ctx, img, &img.layers[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		if disk != nil {
+			return disk, size, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("no disk image found under /%s in image %s", diskDirectory, img.Reference)
+}
+
+func isTarLayer(mediaType string) bool {
+	return strings.Contains(mediaType, ".tar")
+}
+
+// openContainerDisk returns a nil reader when the layer does not contain a disk image.
+func (c *Client) openContainerDisk(ctx context.Context, img *Image, layer *descriptor) (io.ReadCloser, int64, error) {
+	if strings.Contains(layer.MediaType, "zstd") {
+		return nil, 0, fmt.Errorf("unsupported layer media type %s", layer.MediaType)
+	}
+
+	blob, err := c.openBlob(ctx, img, layer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	disk := &diskReader{blob: blob}
+	var layerReader io.Reader = blob
+	if strings.Contains(layer.MediaType, "gzip") {
+		gz, err := gzip.NewReader(blob)
+		if err != nil {
+			blob.Close()
+			return nil, 0, fmt.Errorf("failed to decompress layer %s: %w", layer.Digest, err)
+		}
+		disk.gz = gz
+		layerReader = gz
+	}
+
+	tr := tar.NewReader(layerReader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			disk.Close()
+			return nil, 0, nil
+		}
+		if err != nil {
+			disk.Close()
+			return nil, 0, fmt.Errorf("failed to read layer %s: %w", layer.Digest, err)
+		}
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if header.Typeflag == tar.TypeReg && strings.HasPrefix(name, diskDirectory) {
+			disk.Reader = tr
+			return disk, header.Size, nil
+		}
+	}
+}
+
+func (c *Client) openBlob(ctx context.Context, img *Image, desc *descriptor) (*verifyingReader, error) {
+	algorithm, _, _ := strings.Cut(desc.Digest, ":")
+	h, err := newHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.get(ctx, img, "blobs/"+desc.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{
+		body:   resp.Body,
+		hash:   h,
+		digest: desc.Digest,
+		size:   desc.Size,
+	}, nil
+}
+
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+}
+
+// get requests a repository resource, authenticating and retrying once when the registry asks for it.
+func (c *Client) get(ctx context.Context, img *Image, resource, accept string) (*http.Response, error) {
+	resourceURL := img.endpoint.base.JoinPath(img.Repository, resource).String()
+	tokenKey := img.endpoint.base.String() + "/" + img.Repository
+
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		c.mu.Lock()
+		authorization := c.tokens[tokenKey]
+		c.mu.Unlock()
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return img.httpClient.Do(req)
+	}
+
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		authorization, err := c.authorize(ctx, img, challenge)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.tokens[tokenKey] = authorization
+		c.mu.Unlock()
+
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s for %s", resp.Status, resource)
+	}
+	return resp, nil
+}
+
+// authorize answers a WWW-Authenticate challenge and returns the Authorization header value to use.
+func (c *Client) authorize(ctx context.Context, img *Image, challenge string) (string, error) {
+	username, password, identityToken, err := c.config.credentials(&img.endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" && password == "" {
+			return "", fmt.Errorf("registry %s requires credentials", img.Host())
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, img, params, username, password, identityToken)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported authentication challenge %q from registry %s", challenge, img.Host())
+	}
+}
+
+func (c *Client) fetchToken(ctx context.Context, img *Image, params map[string]string, username, password, identityToken string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry %s sent a bearer challenge without realm", img.Host())
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + img.Repository + ":pull"
+	}
+
+	var req *http.Request
+	var err error
+	if identityToken != "" {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", identityToken)
+		form.Set("service", params["service"])
+		form.Set("scope", scope)
+		form.Set("client_id", tokenClientID)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, realm, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		tokenURL, err := url.Parse(realm)
+		if err != nil {
+			return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+		}
+		query := tokenURL.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		query.Set("scope", scope)
+		tokenURL.RawQuery = query.Encode()
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil); err != nil {
+			return "", err
+		}
+		if username != "" || password != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	resp, err := img.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get token from %s: unexpected status %s", realm, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse token from %s: %w", realm, err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned from %s", realm)
+}
+
+// parseChallenge parses a WWW-Authenticate header such as
+// Bearer realm="https://auth.example.com/token",service="registry",scope="repository:foo:pull".
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimLeft(rest, ", ") {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+			continue
+		}
+		value, rest, _ = strings.Cut(value, ",")
+		params[key] = strings.TrimSpace(value)
+	}
+	return scheme, params
+}
+
+// verifyingReader checks the size and digest of a blob once it is read to the end.
+type verifyingReader struct {
+	body   io.ReadCloser
+	hash   hash.Hash
+	digest string
+	size   int64
+	read   int64
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.body.Read(p)
+	v.hash.Write(p[:n])
+	v.read += int64(n)
+	if err == io.EOF {
+		algorithm, _, _ := strings.Cut(v.digest, ":")
+		if actual := algorithm + ":" + hex.EncodeToString(v.hash.Sum(nil)); actual != v.digest {
+			return n, fmt.Errorf("blob digest mismatch: expected %s, got %s", v.digest, actual)
+		}
+		if v.read != v.size {
+			return n, fmt.Errorf("blob size mismatch: expected %d, got %d", v.size, v.read)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.body.Close()
+}
+
+// diskReader reads a disk image out of a container disk layer. Once the disk image is read,
+// the rest of the layer is drained so that the layer digest can be verified.
+type diskReader struct {
+	io.Reader
+	blob *verifyingReader
+	gz   *gzip.Reader
+}
+
+func (d *diskReader) Read(p []byte) (int, error) {
+	n, err := d.Reader.Read(p)
+	if err == io.EOF {
+		if _, drainErr := io.Copy(io.Discard, d.blob); drainErr != nil {
+			return n, drainErr
+		}
+	}
+	return n, err
+}
+
+func (d *diskReader) Close() error {
+	if d.gz != nil {
+		d.gz.Close()
+	}
+	return d.blob.Close()
+}
@@ -0,0 +1,331 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/harvester/harvester/pkg/containerd"
+)
+
+const (
+	testUsername = "user"
+	testPassword = "pass"
+	testToken    = "token"
+)
+
+type fakeRegistry struct {
+	// objects maps a path below /v2/ to its content type and body.
+	objects   map[string]fakeObject
+	basicAuth bool
+}
+
+type fakeObject struct {
+	mediaType string
+	body      []byte
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (f *fakeRegistry) addManifest(repository, tag string, m interface{}) string {
+	body, _ := json.Marshal(m)
+	digest := digestOf(body)
+	mediaType := MediaTypeOCIManifest
+	if mm, ok := m.(*manifest); ok && mm.MediaType != "" {
+		mediaType = mm.MediaType
+	}
+	f.objects[repository+"/manifests/"+digest] = fakeObject{mediaType, body}
+	if tag != "" {
+		f.objects[repository+"/manifests/"+tag] = fakeObject{mediaType, body}
+	}
+	return digest
+}
+
+func (f *fakeRegistry) addBlob(repository string, body []byte) descriptor {
+	digest := digestOf(body)
+	f.objects[repository+"/blobs/"+digest] = fakeObject{"application/octet-stream", body}
+	return descriptor{Digest: digest, Size: int64(len(body))}
+}
+
+func (f *fakeRegistry) handler(serverURL func() string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != testUsername || password != testPassword {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"` + testToken + `"}`))
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+		if f.basicAuth {
+			if username, password, ok := r.BasicAuth(); !ok || username != testUsername || password != testPassword {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else if authorization != "Bearer "+testToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:images/disk:pull"`, serverURL()))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		object, ok := f.objects[strings.TrimPrefix(r.URL.Path, "/v2/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.mediaType)
+		_, _ = w.Write(object.body)
+	})
+}
+
+func newFakeRegistry(t *testing.T, f *fakeRegistry) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(f.handler(func() string { return server.URL }))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(server *httptest.Server, rewrites map[string]string) *Client {
+	host := strings.TrimPrefix(server.URL, "http://")
+	return NewClient(&Config{
+		Mirrors: map[string]containerd.Mirror{
+			"registry.example.com": {Endpoints: []string{server.URL}, Rewrites: rewrites},
+		},
+		Configs: map[string]containerd.RegistryConfig{
+			host: {Auth: &containerd.AuthConfig{Username: testUsername, Password: testPassword}},
+		},
+	})
+}
+
+func containerDiskLayer(t *testing.T, disk []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "disk/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "disk/disk.img", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(disk))}))
+	_, err := tw.Write(disk)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func Test_ParseReference(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected *Reference
+		errorStr string
+	}{
+		{
+			name:     "docker hub short name",
+			input:    "ubuntu",
+			expected: &Reference{Registry: "docker.io", Repository: "library/ubuntu", Tag: "latest"},
+		},
+		{
+			name:     "registry with port and tag",
+			input:    "docker://registry.example.com:5000/images/ubuntu:22.04",
+			expected: &Reference{Registry: "registry.example.com:5000", Repository: "images/ubuntu", Tag: "22.04"},
+		},
+		{
+			name:  "digest",
+			input: "quay.io/containerdisks/fedora@sha256:" + strings.Repeat("a", 64),
+			expected: &Reference{
+				Registry:   "quay.io",
+				Repository: "containerdisks/fedora",
+				Digest:     "sha256:" + strings.Repeat("a", 64),
+			},
+		},
+		{
+			name:     "invalid digest",
+			input:    "quay.io/containerdisks/fedora@sha256:abc",
+			errorStr: "invalid digest",
+		},
+		{
+			name:     "invalid repository",
+			input:    "registry.example.com/Images/Ubuntu:22.04",
+			errorStr: "invalid repository",
+		},
+		{
+			name:     "empty",
+			input:    "docker://",
+			errorStr: "image reference is empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ref, err := ParseReference(tc.input)
+			if tc.errorStr != "" {
+				assert.ErrorContains(t, err, tc.errorStr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func Test_parseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry",scope="repository:foo:pull,push"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry",
+		"scope":   "repository:foo:pull,push",
+	}, params)
+}
+
+func Test_ContainerDisk(t *testing.T) {
+	disk := []byte("QFI\xfbcontainer disk content")
+	f := &fakeRegistry{objects: map[string]fakeObject{}}
+	layer := f.addBlob("images/disk", containerDiskLayer(t, disk))
+	layer.MediaType = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+	digest := f.addManifest("images/disk", "v1", &manifest{MediaType: MediaTypeDockerManifest, Layers: []descriptor{layer}})
+	server := newFakeRegistry(t, f)
+
+	// the mirror keeps the images under a different repository
+	client := newTestClient(server, map[string]string{"^mirrored/(.*)": "images/$1"})
+	ref, err := ParseReference("registry.example.com/mirrored/disk:v1")
+	require.NoError(t, err)
+
+	img, err := client.Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, img.Digest)
+	assert.Equal(t, "images/disk", img.Repository)
+	assert.Equal(t, "docker://"+strings.TrimPrefix(server.URL, "http://")+"/images/disk@"+digest, img.PullURL())
+
+	reader, size, err := client.OpenDisk(context.Background(), img)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(disk)), size)
+	assert.Equal(t, disk, content)
+}
+
+func Test_ArtifactFromIndex(t *testing.T) {
+	disk := []byte("raw disk artifact")
+	f := &fakeRegistry{objects: map[string]fakeObject{}, basicAuth: true}
+	layer := f.addBlob("images/disk", disk)
+	layer.MediaType = "application/vnd.example.disk.qcow2"
+	manifestDigest := f.addManifest("images/disk", "", &manifest{MediaType: MediaTypeOCIManifest, Layers: []descriptor{layer}})
+	indexDigest := f.addManifest("images/disk", "", &manifest{
+		MediaType: MediaTypeOCIIndex,
+		Manifests: []descriptor{
+			{MediaType: MediaTypeOCIManifest, Digest: digestOf([]byte("other")), Platform: &platform{OS: "linux", Architecture: "other"}},
+			{MediaType: MediaTypeOCIManifest, Digest: manifestDigest, Platform: &platform{OS: "linux", Architecture: runtime.GOARCH}},
+		},
+	})
+	server := newFakeRegistry(t, f)
+
+	client := newTestClient(server, nil)
+	ref, err := ParseReference("registry.example.com/images/disk@" + indexDigest)
+	require.NoError(t, err)
+
+	img, err := client.Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, indexDigest, img.Digest)
+
+	reader, size, err := client.OpenDisk(context.Background(), img)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(disk)), size)
+	assert.Equal(t, disk, content)
+}
+
+func Test_BlobDigestMismatch(t *testing.T) {
+	f := &fakeRegistry{objects: map[string]fakeObject{}}
+	layer := f.addBlob("images/disk", []byte("expected content"))
+	layer.MediaType = "application/vnd.example.disk.raw"
+	f.objects["images/disk/blobs/"+layer.Digest] = fakeObject{"application/octet-stream", []byte("tampered content")}
+	f.addManifest("images/disk", "v1", &manifest{MediaType: MediaTypeOCIManifest, Layers: []descriptor{layer}})
+	server := newFakeRegistry(t, f)
+
+	client := newTestClient(server, nil)
+	ref, err := ParseReference("registry.example.com/images/disk:v1")
+	require.NoError(t, err)
+
+	img, err := client.Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	reader, _, err := client.OpenDisk(context.Background(), img)
+	require.NoError(t, err)
+	defer reader.Close()
+	_, err = io.ReadAll(reader)
+	assert.ErrorContains(t, err, "blob digest mismatch")
+}
+
+func Test_ResolveMissingTag(t *testing.T) {
+	f := &fakeRegistry{objects: map[string]fakeObject{}}
+	server := newFakeRegistry(t, f)
+
+	client := newTestClient(server, nil)
+	ref, err := ParseReference("registry.example.com/images/disk:missing")
+	require.NoError(t, err)
+
+	_, err = client.Resolve(context.Background(), ref)
+	assert.ErrorContains(t, err, "404")
+}
+
+func Test_WithCredentials(t *testing.T) {
+	f := &fakeRegistry{objects: map[string]fakeObject{}, basicAuth: true}
+	layer := f.addBlob("images/disk", []byte("raw disk artifact"))
+	layer.MediaType = "application/vnd.example.disk.raw"
+	digest := f.addManifest("images/disk", "v1", &manifest{MediaType: MediaTypeOCIManifest, Layers: []descriptor{layer}})
+	server := newFakeRegistry(t, f)
+	ref, err := ParseReference("registry.example.com/images/disk:v1")
+	require.NoError(t, err)
+
+	config := newTestClient(server, nil).config
+
+	// the image credentials replace the ones of the containerd-registry setting
+	_, err = NewClient(config.WithCredentials("other", "wrong")).Resolve(context.Background(), ref)
+	assert.ErrorContains(t, err, "401")
+
+	// empty image credentials access the registry anonymously
+	_, err = NewClient(config.WithCredentials("", "")).Resolve(context.Background(), ref)
+	assert.ErrorContains(t, err, "requires credentials")
+
+	// without image credentials the ones of the containerd-registry setting are used
+	img, err := NewClient(config).Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, img.Digest)
+
+	img, err = NewClient((&Config{Mirrors: config.Mirrors}).WithCredentials(testUsername, testPassword)).Resolve(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, digest, img.Digest)
+
+	// the config is copied
+	assert.NotNil(t, config.Configs[strings.TrimPrefix(server.URL, "http://")].Auth)
+	assert.False(t, config.overrideAuth)
+
+	hasCredentials, err := config.HasCredentials(ref.Registry)
+	require.NoError(t, err)
+	assert.True(t, hasCredentials)
+	hasCredentials, err = (&Config{Mirrors: config.Mirrors}).HasCredentials(ref.Registry)
+	require.NoError(t, err)
+	assert.False(t, hasCredentials)
+}
@@ -0,0 +1,279 @@
+package registry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	cdicommon "kubevirt.io/containerized-data-importer/pkg/common"
+
+	"github.com/harvester/harvester/pkg/containerd"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	mirrorWildcard = "*"
+	apiVersionPath = "/v2"
+)
+
+// Config holds the mirrors, TLS options and credentials used to pull images from registries.
+type Config struct {
+	Mirrors map[string]containerd.Mirror
+	Configs map[string]containerd.RegistryConfig
+	// AdditionalCA is a PEM bundle trusted in addition to the system roots.
+	AdditionalCA string
+
+	// auth replaces the credentials of Configs for every registry if overrideAuth is set
+	auth         *containerd.AuthConfig
+	overrideAuth bool
+}
+
+// LoadConfig builds a Config from the containerd-registry setting. The setting controller strips
+// credentials from the setting value once they are synced, so the credentials are read back from
+// the per-registry secrets it maintains.
+func LoadConfig(secretCache ctlcorev1.SecretCache) (*Config, error) {
+	registry := &containerd.Registry{}
+	if value := settings.ContainerdRegistry.Get(); value != "" {
+		if err := json.Unmarshal([]byte(value), registry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s setting: %w", settings.ContainerdRegistrySettingName, err)
+		}
+	}
+
+	config := &Config{
+		Mirrors:      registry.Mirrors,
+		Configs:      map[string]containerd.RegistryConfig{},
+		AdditionalCA: settings.AdditionalCA.Get(),
+	}
+	for host, registryConfig := range registry.Configs {
+		config.Configs[host] = registryConfig
+	}
+
+	secrets, err := secretCache.List(util.FleetLocalNamespaceName, labels.SelectorFromSet(map[string]string{
+		util.LabelSetting: settings.ContainerdRegistrySettingName,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		host := string(secret.Data[util.ContainerdRegistryConfigSecretDataHost])
+		if host == "" {
+			continue
+		}
+		registryConfig := config.Configs[host]
+		if registryConfig.Auth != nil {
+			continue
+		}
+		registryConfig.Auth = &containerd.AuthConfig{
+			Username:      string(secret.Data[rkev1.UsernameAuthConfigSecretKey]),
+			Password:      string(secret.Data[rkev1.PasswordAuthConfigSecretKey]),
+			Auth:          string(secret.Data[rkev1.AuthAuthConfigSecretKey]),
+			IdentityToken: string(secret.Data[rkev1.IdentityTokenAuthConfigSecretKey]),
+		}
+		config.Configs[host] = registryConfig
+	}
+	return config, nil
+}
+
+// WithCredentials returns a copy of the config pulling from every registry with the credentials
+// instead of the ones of the containerd-registry setting. Empty credentials pull anonymously. It's
+// used for the credential secret of an image, without one the credentials of the setting are used.
+func (c *Config) WithCredentials(username, password string) *Config {
+	config := *c
+	config.overrideAuth = true
+	config.auth = nil
+	if username != "" || password != "" {
+		config.auth = &containerd.AuthConfig{Username: username, Password: password}
+	}
+	return &config
+}
+
+// LoadCredentials reads the username and password of the registry credential secret of an image.
+func LoadCredentials(secretCache ctlcorev1.SecretCache, namespace, name string) (string, string, error) {
+	secret, err := secretCache.Get(namespace, name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get registry credential secret %s/%s: %w", namespace, name, err)
+	}
+	return string(secret.Data[cdicommon.KeyAccess]), string(secret.Data[cdicommon.KeySecret]), nil
+}
+
+// endpoint is a registry API root an image can be pulled from.
+type endpoint struct {
+	// base is the API root, e.g. https://mirror.example.com/v2
+	base     *url.URL
+	rewrites map[string]string
+	// configKeys are the Configs keys consulted for TLS options and credentials, in order.
+	configKeys []string
+}
+
+// endpoints returns the mirrors configured for the registry followed by the registry itself,
+// in the order the container runtime tries them.
+func (c *Config) endpoints(registry string) ([]endpoint, error) {
+	var endpoints []endpoint
+
+	mirror, ok := c.Mirrors[registry]
+	if !ok {
+		mirror, ok = c.Mirrors[mirrorWildcard]
+	}
+	if ok {
+		for _, raw := range mirror.Endpoints {
+			base, err := parseEndpoint(raw)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, endpoint{
+				base:       base,
+				rewrites:   mirror.Rewrites,
+				configKeys: []string{base.Host},
+			})
+		}
+	}
+
+	defaultEndpoint := "https://" + registry
+	if registry == dockerHubRegistry {
+		defaultEndpoint = dockerHubEndpoint
+	}
+	base, err := parseEndpoint(defaultEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range endpoints {
+		if e.base.String() == base.String() {
+			return endpoints, nil
+		}
+	}
+	return append(endpoints, endpoint{
+		base:       base,
+		configKeys: []string{base.Host, registry},
+	}), nil
+}
+
+func parseEndpoint(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry endpoint %q: %w", raw, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid registry endpoint %q: host is empty", raw)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = apiVersionPath
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
+
+// repository applies the first matching mirror rewrite rule to the repository.
+func (e *endpoint) repository(repository string) (string, error) {
+	patterns := make([]string, 0, len(e.rewrites))
+	for pattern := range e.rewrites {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid rewrite rule %q: %w", pattern, err)
+		}
+		if re.MatchString(repository) {
+			return re.ReplaceAllString(repository, e.rewrites[pattern]), nil
+		}
+	}
+	return repository, nil
+}
+
+func (c *Config) registryConfig(e *endpoint) containerd.RegistryConfig {
+	var result containerd.RegistryConfig
+	for _, key := range e.configKeys {
+		registryConfig, ok := c.Configs[key]
+		if !ok {
+			continue
+		}
+		if result.Auth == nil {
+			result.Auth = registryConfig.Auth
+		}
+		if result.TLS == nil {
+			result.TLS = registryConfig.TLS
+		}
+	}
+	if c.overrideAuth {
+		result.Auth = c.auth
+	}
+	return result
+}
+
+// HasCredentials reports whether the containerd-registry setting configures credentials for the
+// registry or one of its mirrors.
+func (c *Config) HasCredentials(registry string) (bool, error) {
+	endpoints, err := c.endpoints(registry)
+	if err != nil {
+		return false, err
+	}
+	for i := range endpoints {
+		username, password, identityToken, err := c.credentials(&endpoints[i])
+		if err != nil {
+			return false, err
+		}
+		if username != "" || password != "" || identityToken != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// credentials returns the username and password or identity token configured for the endpoint.
+func (c *Config) credentials(e *endpoint) (username, password, identityToken string, err error) {
+	auth := c.registryConfig(e).Auth
+	if auth == nil {
+		return "", "", "", nil
+	}
+	if auth.Username != "" || auth.Password != "" || auth.Auth == "" {
+		return auth.Username, auth.Password, auth.IdentityToken, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to decode registry auth: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", "", fmt.Errorf("registry auth is not in the form of username:password")
+	}
+	return username, password, auth.IdentityToken, nil
+}
+
+func (c *Config) httpClient(e *endpoint) (*http.Client, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if c.AdditionalCA != "" && !rootCAs.AppendCertsFromPEM([]byte(c.AdditionalCA)) {
+		return nil, fmt.Errorf("failed to parse %s setting", settings.AdditionalCASettingName)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    rootCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	if registryTLS := c.registryConfig(e).TLS; registryTLS != nil {
+		tlsConfig.InsecureSkipVerify = registryTLS.InsecureSkipVerify //nolint:gosec
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
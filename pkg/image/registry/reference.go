@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHubRegistry  = "docker.io"
+	dockerHubEndpoint  = "https://registry-1.docker.io"
+	dockerHubNamespace = "library"
+	defaultTag         = "latest"
+
+	// TransportPrefix is the optional scheme accepted in front of a registry image reference.
+	TransportPrefix = "docker://"
+)
+
+var (
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|[-]+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^(?:sha256:[a-f0-9]{64}|sha512:[a-f0-9]{128})$`)
+)
+
+// Reference is a parsed container image reference, e.g. registry.example.com/images/ubuntu:22.04
+// or registry.example.com/images/ubuntu@sha256:<hex>.
+type Reference struct {
+	// Registry is the registry host, optionally with a port. Docker Hub references are normalized to docker.io.
+	Registry string
+	// Repository is the repository path inside the registry.
+	Repository string
+	// Tag is empty when the reference is pinned by digest only.
+	Tag string
+	// Digest is empty when the reference is a tag.
+	Digest string
+}
+
+// ParseReference parses an image reference the same way the container runtime does,
+// so a reference that can be pulled as a container disk can be used as an image source.
+func ParseReference(s string) (*Reference, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(s), TransportPrefix)
+	if raw == "" {
+		return nil, fmt.Errorf("image reference is empty")
+	}
+
+	ref := &Reference{}
+	name := raw
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !digestRegexp.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("invalid digest %q in image reference %q", ref.Digest, s)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i+1:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return nil, fmt.Errorf("invalid tag %q in image reference %q", ref.Tag, s)
+		}
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 && isRegistryHost(name[:i]) {
+		ref.Registry = name[:i]
+		ref.Repository = name[i+1:]
+	} else {
+		ref.Registry = dockerHubRegistry
+		ref.Repository = name
+	}
+
+	if ref.Registry == "index.docker.io" || ref.Registry == "registry-1.docker.io" {
+		ref.Registry = dockerHubRegistry
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = dockerHubNamespace + "/" + ref.Repository
+	}
+
+	if !repositoryRegexp.MatchString(ref.Repository) {
+		return nil, fmt.Errorf("invalid repository %q in image reference %q", ref.Repository, s)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+func isRegistryHost(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == "localhost"
+}
+
+// Identifier returns the digest when the reference is pinned, the tag otherwise.
+func (r *Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// Name returns the fully qualified repository name without tag or digest.
+func (r *Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+func (r *Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
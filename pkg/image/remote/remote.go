@@ -0,0 +1,356 @@
+package remote
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/image/verify"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	// KubeconfigSecretKey is the key of the kubeconfig in the secret of a peer cluster
+	KubeconfigSecretKey = "kubeconfig"
+	// ChecksumSuffix is appended to the download URL of an image downloader to get the SHA-512 checksum of the image file
+	ChecksumSuffix = ".sha512"
+
+	// LabelRemoteImportUID is put on the downloaders created on the peer cluster, it is the UID of the importing image
+	LabelRemoteImportUID = "harvesterhci.io/remote-import-uid"
+
+	downloaderPrefix       = "remote-import-"
+	downloaderPollInterval = 5 * time.Second
+	// the downloader converts the whole image before it serves it
+	downloaderReadyTimeout = 2 * time.Hour
+	maxChecksumSize        = 1024
+)
+
+// Cluster is a peer Harvester cluster reached through a kubeconfig.
+type Cluster struct {
+	config     *rest.Config
+	httpClient *http.Client
+	Client     versioned.Interface
+}
+
+// NewCluster returns the peer cluster of the kubeconfig in the secret. The kubeconfig comes from the
+// owner of the secret, so it may only carry inline credentials, and the peer cluster is never reached
+// through an address in the blocked networks.
+func NewCluster(secretCache ctlcorev1.SecretCache, blocked []*net.IPNet, namespace, secretName string) (*Cluster, error) {
+	secret, err := secretCache.Get(namespace, secretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kubeconfig secret %s/%s: %w", namespace, secretName, err)
+	}
+	kubeconfig := secret.Data[KubeconfigSecretKey]
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("the kubeconfig secret %s/%s has no %s key", namespace, secretName, KubeconfigSecretKey)
+	}
+
+	if err := ValidateKubeconfig(kubeconfig); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %s/%s: %w", namespace, secretName, err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the kubeconfig in secret %s/%s: %w", namespace, secretName, err)
+	}
+	config.Dial = export.NewDialer(blocked).DialContext
+	client, err := versioned.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Cluster{
+		config:     config,
+		httpClient: httpClient,
+		Client:     client,
+	}, nil
+}
+
+// ValidateKubeconfig returns an error unless the kubeconfig only carries inline credentials. Exec
+// plugins and auth providers would run commands in Harvester, and file paths would read its files,
+// such as the token of its service account, which is then sent to the server of the kubeconfig.
+func ValidateKubeconfig(kubeconfig []byte) error {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to parse the kubeconfig: %w", err)
+	}
+	for name, authInfo := range config.AuthInfos {
+		if authInfo.Exec != nil {
+			return fmt.Errorf("user %s uses an exec plugin, only inline credentials are supported", name)
+		}
+		if authInfo.AuthProvider != nil {
+			return fmt.Errorf("user %s uses an auth provider, only inline credentials are supported", name)
+		}
+		if authInfo.TokenFile != "" || authInfo.ClientCertificate != "" || authInfo.ClientKey != "" {
+			return fmt.Errorf("user %s refers files, only inline token and client certificate data are supported", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("cluster %s refers a certificate authority file, only inline certificate authority data is supported", name)
+		}
+	}
+	return nil
+}
+
+// SourceChecksum returns the SHA-512 checksum of the raw disk of an image if it is known, which is only
+// the case for images that were themselves streamed from a peer cluster.
+func SourceChecksum(vmi *harvesterv1.VirtualMachineImage) string {
+	return vmi.Status.Checksum
+}
+
+// IsImportable returns an error if the image of the peer cluster can not be imported.
+func IsImportable(vmi *harvesterv1.VirtualMachineImage) error {
+	if !harvesterv1.ImageImported.IsTrue(vmi) {
+		return fmt.Errorf("image %s/%s is not imported yet", vmi.Namespace, vmi.Name)
+	}
+	if util.IsImageEncrypted(vmi) {
+		return fmt.Errorf("image %s/%s is encrypted", vmi.Namespace, vmi.Name)
+	}
+	return nil
+}
+
+// Stream is the raw disk of an image read from the downloader of a peer cluster. It is verified
+// against the checksum published by the downloader, and removes the downloader once it is closed.
+type Stream struct {
+	io.Reader
+	Size int64
+
+	body    io.ReadCloser
+	hash    hash.Hash
+	cleanup func()
+}
+
+// Checksum returns the SHA-512 checksum of the image file, once it has been read completely.
+func (s *Stream) Checksum() string {
+	return hex.EncodeToString(s.hash.Sum(nil))
+}
+
+func (s *Stream) Close() error {
+	err := s.body.Close()
+	s.cleanup()
+	return err
+}
+
+// Open starts an image downloader for the image of the peer cluster the remote image refers to,
+// and returns the stream of the raw disk it serves.
+func Open(ctx context.Context, secretCache ctlcorev1.SecretCache, blocked []*net.IPNet, vmi *harvesterv1.VirtualMachineImage) (*Stream, error) {
+	source := vmi.Spec.Remote
+	if source == nil {
+		return nil, fmt.Errorf("image %s/%s has no remote source", vmi.Namespace, vmi.Name)
+	}
+	cluster, err := NewCluster(secretCache, blocked, vmi.Namespace, source.KubeconfigSecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceImage, err := cluster.Client.HarvesterhciV1beta1().VirtualMachineImages(source.ImageNamespace).Get(ctx, source.ImageName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the source image %s/%s: %w", source.ImageNamespace, source.ImageName, err)
+	}
+	if err := IsImportable(sourceImage); err != nil {
+		return nil, err
+	}
+
+	downloader, err := cluster.startDownloader(ctx, sourceImage, vmi)
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		// the import context is gone once the image is deleted
+		deleteCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		err := cluster.Client.HarvesterhciV1beta1().VirtualMachineImageDownloaders(downloader.Namespace).Delete(deleteCtx, downloader.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			logrus.WithError(err).Warnf("failed to delete the image downloader %s/%s of the peer cluster", downloader.Namespace, downloader.Name)
+		}
+	}
+
+	stream, err := cluster.openDownload(ctx, downloader, sourceImage, vmi.Spec.Checksum)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	stream.cleanup = cleanup
+	return stream, nil
+}
+
+func (c *Cluster) startDownloader(ctx context.Context, sourceImage, vmi *harvesterv1.VirtualMachineImage) (*harvesterv1.VirtualMachineImageDownloader, error) {
+	downloaders := c.Client.HarvesterhciV1beta1().VirtualMachineImageDownloaders(sourceImage.Namespace)
+	name := downloaderPrefix + string(vmi.UID)[:8]
+	_, err := downloaders.Create(ctx, &harvesterv1.VirtualMachineImageDownloader{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: sourceImage.Namespace,
+			Labels: map[string]string{
+				LabelRemoteImportUID: string(vmi.UID),
+			},
+		},
+		Spec: harvesterv1.VirtualMachineImageDownloaderSpec{
+			ImageName:    sourceImage.Name,
+			CompressType: harvesterv1.ImageDownloaderCompressTypeRaw,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create the image downloader of the peer cluster: %w", err)
+	}
+
+	var downloader *harvesterv1.VirtualMachineImageDownloader
+	err = wait.PollUntilContextTimeout(ctx, downloaderPollInterval, downloaderReadyTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := downloaders.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		downloader = current
+		return current.Status.Status == harvesterv1.ImageDownloaderStatusReady && current.Status.DownloadURL != "", nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for the image downloader %s/%s of the peer cluster: %w", sourceImage.Namespace, name, err)
+	}
+	return downloader, nil
+}
+
+// openDownload returns the stream of the image served by the downloader, which is verified against the
+// checksum published by the downloader and the expected checksum if any.
+func (c *Cluster) openDownload(ctx context.Context, downloader *harvesterv1.VirtualMachineImageDownloader, sourceImage *harvesterv1.VirtualMachineImage, expected string) (*Stream, error) {
+	downloadURL, err := ProxyURL(c.config.Host, downloader.Status.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := c.getChecksum(ctx, downloadURL+ChecksumSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	var checksums []string
+	if checksum != "" {
+		checksums = append(checksums, "sha512:"+checksum)
+	} else {
+		logrus.Warnf("the image downloader %s/%s of the peer cluster publishes no checksum", downloader.Namespace, downloader.Name)
+	}
+	if expected != "" {
+		checksums = append(checksums, expected)
+	}
+	verifiers := make([]verify.Verifier, 0, len(checksums))
+	for _, checksum := range checksums {
+		verifier, err := verify.NewChecksumVerifier(checksum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image checksum: %w", err)
+		}
+		verifiers = append(verifiers, verifier)
+	}
+
+	resp, err := c.get(ctx, downloadURL)
+	if err != nil {
+		return nil, err
+	}
+	size := resp.ContentLength
+	if size < 0 {
+		// the raw disk is as large as the virtual size
+		size = sourceImage.Status.VirtualSize
+	}
+	if size <= 0 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("the size of image %s/%s is unknown", sourceImage.Namespace, sourceImage.Name)
+	}
+
+	stream := &Stream{
+		Size: size,
+		body: resp.Body,
+		hash: sha512.New(),
+	}
+	r := io.TeeReader(resp.Body, stream.hash)
+	if len(verifiers) > 0 {
+		r = verify.NewReader(r, verifiers...)
+	}
+	stream.Reader = r
+	return stream, nil
+}
+
+// getChecksum returns the checksum published by the downloader, which is empty for downloaders of older releases.
+func (c *Cluster) getChecksum(ctx context.Context, checksumURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the image checksum: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get the image checksum: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to get the image checksum: %w", err)
+	}
+	// the checksum file is the output of sha512sum
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+func (c *Cluster) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the image from the peer cluster: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download the image from the peer cluster: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// ProxyURL returns the URL of the in-cluster download URL of an image downloader through the service
+// proxy of the API server at host, since the downloader service is not reachable from outside of its cluster.
+func ProxyURL(host, downloadURL string) (string, error) {
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL %q: %w", downloadURL, err)
+	}
+	// the downloader service is addressed as <service>.<namespace>[.svc.<cluster domain>]
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) < 2 || labels[0] == "" || labels[1] == "" {
+		return "", fmt.Errorf("download URL %q does not address a service", downloadURL)
+	}
+	service, namespace := labels[0], labels[1]
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s:%s/proxy%s",
+		strings.TrimSuffix(host, "/"), namespace, u.Scheme, service, port, u.EscapedPath()), nil
+}
@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/image/export"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestProxyURL(t *testing.T) {
+	var tests = []struct {
+		name        string
+		host        string
+		downloadURL string
+		expectURL   string
+		expectErr   bool
+	}{
+		{
+			name:        "default port",
+			host:        "https://peer.example.com:6443",
+			downloadURL: "http://image-a-downloader.default/images/image-a.raw",
+			expectURL:   "https://peer.example.com:6443/api/v1/namespaces/default/services/http:image-a-downloader:80/proxy/images/image-a.raw",
+		},
+		{
+			name:        "explicit port and rancher proxy path",
+			host:        "https://rancher.example.com/k8s/clusters/c-m-abc/",
+			downloadURL: "http://image-a-downloader.team-a.svc.cluster.local:8080/images/image-a.raw.sha512",
+			expectURL:   "https://rancher.example.com/k8s/clusters/c-m-abc/api/v1/namespaces/team-a/services/http:image-a-downloader:8080/proxy/images/image-a.raw.sha512",
+		},
+		{
+			name:        "not a service",
+			host:        "https://peer.example.com:6443",
+			downloadURL: "http://localhost/images/image-a.raw",
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ProxyURL(tc.host, tc.downloadURL)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectURL, u)
+		})
+	}
+}
+
+func TestValidateKubeconfig(t *testing.T) {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: source
+  cluster:
+    server: https://source.example.com:6443
+%s
+contexts:
+- name: source
+  context:
+    cluster: source
+    user: source
+current-context: source
+users:
+- name: source
+  user:
+%s
+`
+	var tests = []struct {
+		name        string
+		cluster     string
+		user        string
+		errContains string
+	}{
+		{
+			name: "inline token",
+			user: "    token: token",
+		},
+		{
+			name:    "inline certificates",
+			cluster: "    certificate-authority-data: Y2E=",
+			user:    "    client-certificate-data: Y2VydA==\n    client-key-data: a2V5",
+		},
+		{
+			name:        "exec plugin",
+			user:        "    exec:\n      apiVersion: client.authentication.k8s.io/v1\n      command: sh",
+			errContains: "exec plugin",
+		},
+		{
+			name:        "auth provider",
+			user:        "    auth-provider:\n      name: oidc",
+			errContains: "auth provider",
+		},
+		{
+			name:        "token file",
+			user:        "    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token",
+			errContains: "refers files",
+		},
+		{
+			name:        "client certificate file",
+			user:        "    client-certificate: /etc/cert\n    client-key: /etc/key",
+			errContains: "refers files",
+		},
+		{
+			name:        "certificate authority file",
+			cluster:     "    certificate-authority: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt",
+			user:        "    token: token",
+			errContains: "certificate authority file",
+		},
+	}
+
+	for _, tc := range tests {
+		err := ValidateKubeconfig([]byte(fmt.Sprintf(kubeconfig, tc.cluster, tc.user)))
+		if tc.errContains == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.errContains, tc.name)
+		}
+	}
+}
+
+func TestNewClusterBlockedNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"apiVersion":"harvesterhci.io/v1beta1","kind":"VirtualMachineImageList","items":[]}`))
+	}))
+	defer server.Close()
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: source
+  cluster:
+    server: %s
+contexts:
+- name: source
+  context:
+    cluster: source
+    user: source
+current-context: source
+users:
+- name: source
+  user:
+    token: token
+`, server.URL)
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"},
+		Data:       map[string][]byte{KubeconfigSecretKey: []byte(kubeconfig)},
+	})
+	secretCache := fakeclients.SecretCache(clientset.CoreV1().Secrets)
+
+	cluster, err := NewCluster(secretCache, nil, "default", "source")
+	require.NoError(t, err)
+	_, err = cluster.Client.HarvesterhciV1beta1().VirtualMachineImages("default").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	// the test server listens on the loopback network
+	cluster, err = NewCluster(secretCache, export.DefaultBlockedNetworks(), "default", "source")
+	require.NoError(t, err)
+	_, err = cluster.Client.HarvesterhciV1beta1().VirtualMachineImages("default").List(context.Background(), metav1.ListOptions{})
+	assert.ErrorContains(t, err, "blocked network")
+}
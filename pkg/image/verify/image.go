@@ -0,0 +1,109 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+const (
+	// PublicKeySecretKey is the key of the public key in the secret named by the image signature
+	PublicKeySecretKey = "publicKey"
+
+	// signatures are tiny, anything larger is not a signature
+	maxSignatureSize = 64 * 1024
+)
+
+// Required returns true if the downloaded image file has to be verified before it is imported.
+func Required(vmi *harvesterv1.VirtualMachineImage) bool {
+	return vmi.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeDownload &&
+		(vmi.Spec.Checksum != "" || vmi.Spec.Signature != nil)
+}
+
+// ImageVerifiers returns the verifiers of the checksum and the signature of the image. The public key
+// of the signature is read from secretCache, which is only used when the image is signed.
+func ImageVerifiers(ctx context.Context, secretCache ctlcorev1.SecretCache, vmi *harvesterv1.VirtualMachineImage) ([]Verifier, error) {
+	var verifiers []Verifier
+	if vmi.Spec.Checksum != "" {
+		v, err := NewChecksumVerifier(vmi.Spec.Checksum)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, v)
+	}
+
+	if sig := vmi.Spec.Signature; sig != nil {
+		v, err := signatureVerifier(ctx, secretCache, vmi.Namespace, sig)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, v)
+	}
+	return verifiers, nil
+}
+
+// NewImageReader returns a reader verifying the image file read from r against the checksum and the signature of the image.
+func NewImageReader(ctx context.Context, secretCache ctlcorev1.SecretCache, vmi *harvesterv1.VirtualMachineImage, r io.Reader) (io.Reader, error) {
+	verifiers, err := ImageVerifiers(ctx, secretCache, vmi)
+	if err != nil {
+		return nil, err
+	}
+	if len(verifiers) == 0 {
+		return r, nil
+	}
+	return NewReader(r, verifiers...), nil
+}
+
+func signatureVerifier(ctx context.Context, secretCache ctlcorev1.SecretCache, namespace string, sig *harvesterv1.VirtualMachineImageSignature) (Verifier, error) {
+	secret, err := secretCache.Get(namespace, sig.PublicKeySecretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the public key secret %s/%s: %w", namespace, sig.PublicKeySecretName, err)
+	}
+	publicKey := secret.Data[PublicKeySecretKey]
+	if len(publicKey) == 0 {
+		return nil, fmt.Errorf("the public key secret %s/%s has no %s key", namespace, sig.PublicKeySecretName, PublicKeySecretKey)
+	}
+
+	signature, err := downloadSignature(ctx, sig.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sig.Type {
+	case harvesterv1.VirtualMachineImageSignatureTypeCosign:
+		return NewCosignVerifier(publicKey, signature)
+	case harvesterv1.VirtualMachineImageSignatureTypeGPG:
+		return NewGPGVerifier(publicKey, signature)
+	default:
+		return nil, fmt.Errorf("unsupported signature type %q", sig.Type)
+	}
+}
+
+func downloadSignature(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the signature %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the signature %s: %s", url, resp.Status)
+	}
+
+	signature, err := io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the signature %s: %w", url, err)
+	}
+	if len(signature) > maxSignatureSize {
+		return nil, fmt.Errorf("the signature %s is larger than %d bytes", url, maxSignatureSize)
+	}
+	return signature, nil
+}
@@ -0,0 +1,165 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+var errSignatureMismatch = errors.New("signature verification failed, the image file was not signed by the trusted key")
+
+type cosignVerifier struct {
+	hash.Hash
+	publicKey crypto.PublicKey
+	signature []byte
+}
+
+// NewCosignVerifier returns a verifier for a signature made by cosign sign-blob, which is the base64
+// encoded signature of the SHA-256 digest of the image file. The public key is a PEM encoded ECDSA
+// or RSA key, ed25519 keys sign the whole file instead of its digest and are not supported.
+func NewCosignVerifier(publicKey, signature []byte) (Verifier, error) {
+	block, _ := pem.Decode(publicKey)
+	if block == nil {
+		return nil, fmt.Errorf("the cosign public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the cosign public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+	case ed25519.PublicKey:
+		return nil, fmt.Errorf("ed25519 cosign keys are not supported")
+	default:
+		return nil, fmt.Errorf("unsupported cosign public key type %T", key)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return nil, fmt.Errorf("the cosign signature is not base64 encoded: %w", err)
+	}
+	return &cosignVerifier{
+		Hash:      crypto.SHA256.New(),
+		publicKey: key,
+		signature: decoded,
+	}, nil
+}
+
+func (v *cosignVerifier) Verify() error {
+	digest := v.Sum(nil)
+	switch key := v.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, v.signature) {
+			return errSignatureMismatch
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, v.signature); err != nil {
+			return errSignatureMismatch
+		}
+	}
+	return nil
+}
+
+type gpgVerifier struct {
+	io.Writer
+	signature *packet.Signature
+	keys      []openpgp.Key
+	// every key verifies the signature with its own hash, verifying consumes the hash
+	hashes []hash.Hash
+}
+
+// NewGPGVerifier returns a verifier for a binary or armored detached signature made by gpg --detach-sign,
+// the public key is a binary or armored key ring holding the signing key.
+func NewGPGVerifier(publicKey, signature []byte) (Verifier, error) {
+	keyring, err := readGPGKeyRing(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the gpg public key: %w", err)
+	}
+	sig, err := readGPGSignature(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the gpg signature: %w", err)
+	}
+	if sig.SigType != packet.SigTypeBinary {
+		return nil, fmt.Errorf("unsupported gpg signature type %d, the image file must be signed as binary", sig.SigType)
+	}
+	if sig.IssuerKeyId == nil {
+		return nil, fmt.Errorf("the gpg signature does not name its issuer")
+	}
+	if !sig.Hash.Available() {
+		return nil, fmt.Errorf("unsupported gpg signature hash %d", sig.Hash)
+	}
+
+	keys := keyring.KeysByIdUsage(*sig.IssuerKeyId, packet.KeyFlagSign)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("the gpg signature was made by key %X, which is not in the public key", *sig.IssuerKeyId)
+	}
+
+	v := &gpgVerifier{signature: sig, keys: keys}
+	writers := make([]io.Writer, 0, len(keys))
+	for range keys {
+		// v6 signatures are salted, the hash must be prepared by the signature
+		h, err := sig.PrepareVerify()
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare the gpg signature hash: %w", err)
+		}
+		v.hashes = append(v.hashes, h)
+		writers = append(writers, h)
+	}
+	v.Writer = io.MultiWriter(writers...)
+	return v, nil
+}
+
+func (v *gpgVerifier) Verify() error {
+	for i, key := range v.keys {
+		if err := key.PublicKey.VerifySignature(v.hashes[i], v.signature); err == nil {
+			return nil
+		}
+	}
+	return errSignatureMismatch
+}
+
+func readGPGKeyRing(data []byte) (openpgp.EntityList, error) {
+	if isArmored(data) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+func readGPGSignature(data []byte) (*packet.Signature, error) {
+	var r io.Reader = bytes.NewReader(data)
+	if isArmored(data) {
+		block, err := armor.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		r = block.Body
+	}
+
+	p, err := packet.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, fmt.Errorf("not a signature packet")
+	}
+	return sig, nil
+}
+
+func isArmored(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP"))
+}
@@ -0,0 +1,175 @@
+package verify
+
+import (
+	"crypto"
+	// register the hash functions checksums and signatures are made with
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Verifier is written the image file and verifies it once the whole file has been written.
+type Verifier interface {
+	io.Writer
+	Verify() error
+}
+
+var checksumAlgorithms = map[string]crypto.Hash{
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+// ParseChecksum returns the hash function and the hex digest of a checksum, which is either a bare
+// SHA-256 or SHA-512 digest told apart by its length, or a digest prefixed with sha256: or sha512:.
+func ParseChecksum(checksum string) (crypto.Hash, string, error) {
+	digest := strings.ToLower(strings.TrimSpace(checksum))
+
+	var algorithm crypto.Hash
+	if name, value, found := strings.Cut(digest, ":"); found {
+		var ok bool
+		if algorithm, ok = checksumAlgorithms[name]; !ok {
+			return 0, "", fmt.Errorf("unsupported checksum algorithm %q, supported algorithms are sha256 and sha512", name)
+		}
+		digest = value
+	} else {
+		switch len(digest) {
+		case hex.EncodedLen(crypto.SHA256.Size()):
+			algorithm = crypto.SHA256
+		case hex.EncodedLen(crypto.SHA512.Size()):
+			algorithm = crypto.SHA512
+		default:
+			return 0, "", fmt.Errorf("checksum %q is neither a sha256 nor a sha512 digest", checksum)
+		}
+	}
+
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != hex.EncodedLen(algorithm.Size()) {
+		return 0, "", fmt.Errorf("checksum %q is not a valid %s digest", checksum, checksumName(algorithm))
+	}
+	return algorithm, digest, nil
+}
+
+func checksumName(algorithm crypto.Hash) string {
+	for name, h := range checksumAlgorithms {
+		if h == algorithm {
+			return name
+		}
+	}
+	return algorithm.String()
+}
+
+type checksumVerifier struct {
+	hash.Hash
+	algorithm crypto.Hash
+	expected  string
+}
+
+// NewChecksumVerifier returns a verifier comparing the digest of the image file with checksum.
+func NewChecksumVerifier(checksum string) (Verifier, error) {
+	algorithm, digest, err := ParseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &checksumVerifier{
+		Hash:      algorithm.New(),
+		algorithm: algorithm,
+		expected:  digest,
+	}, nil
+}
+
+func (v *checksumVerifier) Verify() error {
+	if actual := hex.EncodeToString(v.Sum(nil)); actual != v.expected {
+		return fmt.Errorf("%s checksum mismatch, expected %s but got %s", checksumName(v.algorithm), v.expected, actual)
+	}
+	return nil
+}
+
+// reader holds back the last byte of the image file until all verifiers pass, so whoever reads
+// the file never receives a complete image that failed the verification.
+type reader struct {
+	r         io.Reader
+	w         io.Writer
+	verifiers []Verifier
+	held      []byte
+	verified  bool
+	err       error
+}
+
+// NewReader returns a reader passing the image file read from r to the verifiers, which fails
+// instead of returning the last byte of the file if any of them rejects it.
+func NewReader(r io.Reader, verifiers ...Verifier) io.Reader {
+	writers := make([]io.Writer, 0, len(verifiers))
+	for _, v := range verifiers {
+		writers = append(writers, v)
+	}
+	return &reader{
+		r:         r,
+		w:         io.MultiWriter(writers...),
+		verifiers: verifiers,
+		held:      make([]byte, 0, 1),
+	}
+}
+
+func (vr *reader) Read(p []byte) (int, error) {
+	if vr.err != nil {
+		return 0, vr.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if vr.verified {
+		n := copy(p, vr.held)
+		vr.held = vr.held[n:]
+		vr.err = io.EOF
+		if n > 0 {
+			return n, nil
+		}
+		return 0, io.EOF
+	}
+
+	n, err := vr.r.Read(p)
+	if n > 0 {
+		if _, werr := vr.w.Write(p[:n]); werr != nil {
+			vr.err = werr
+			return 0, werr
+		}
+		last := p[n-1]
+		if len(vr.held) > 0 {
+			copy(p[1:n], p[:n-1])
+			p[0] = vr.held[0]
+		} else {
+			n--
+		}
+		vr.held = append(vr.held[:0], last)
+	}
+
+	switch {
+	case err == io.EOF:
+		if verr := vr.verify(); verr != nil {
+			vr.err = verr
+			return n, verr
+		}
+		vr.verified = true
+		if n == 0 {
+			return vr.Read(p)
+		}
+		return n, nil
+	case err != nil:
+		vr.err = err
+		return n, err
+	default:
+		return n, nil
+	}
+}
+
+func (vr *reader) verify() error {
+	for _, v := range vr.verifiers {
+		if err := v.Verify(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package verify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var image = bytes.Repeat([]byte("virtual machine image "), 1024)
+
+func TestParseChecksum(t *testing.T) {
+	sha256Sum := sha256.Sum256(image)
+	sha512Sum := sha512.Sum512(image)
+	sha256Hex := hex.EncodeToString(sha256Sum[:])
+	sha512Hex := hex.EncodeToString(sha512Sum[:])
+
+	var tests = []struct {
+		name            string
+		checksum        string
+		expectAlgorithm crypto.Hash
+		expectDigest    string
+		expectErr       bool
+	}{
+		{name: "bare sha256", checksum: sha256Hex, expectAlgorithm: crypto.SHA256, expectDigest: sha256Hex},
+		{name: "bare sha512", checksum: strings.ToUpper(sha512Hex), expectAlgorithm: crypto.SHA512, expectDigest: sha512Hex},
+		{name: "prefixed sha256", checksum: "sha256:" + sha256Hex, expectAlgorithm: crypto.SHA256, expectDigest: sha256Hex},
+		{name: "prefixed sha512", checksum: "SHA512:" + sha512Hex, expectAlgorithm: crypto.SHA512, expectDigest: sha512Hex},
+		{name: "unknown algorithm", checksum: "md5:0123", expectErr: true},
+		{name: "wrong length", checksum: "sha512:" + sha256Hex, expectErr: true},
+		{name: "not hex", checksum: strings.Repeat("z", 64), expectErr: true},
+		{name: "unknown length", checksum: "0123", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			algorithm, digest, err := ParseChecksum(tc.checksum)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectAlgorithm, algorithm)
+			assert.Equal(t, tc.expectDigest, digest)
+		})
+	}
+}
+
+// smallReads returns at most 7 bytes per read to exercise the byte held back by the reader.
+type smallReads struct {
+	r io.Reader
+}
+
+func (s smallReads) Read(p []byte) (int, error) {
+	if len(p) > 7 {
+		p = p[:7]
+	}
+	return s.r.Read(p)
+}
+
+func TestReader(t *testing.T) {
+	sum := sha256.Sum256(image)
+	valid, err := NewChecksumVerifier("sha256:" + hex.EncodeToString(sum[:]))
+	require.NoError(t, err)
+
+	read, err := io.ReadAll(NewReader(smallReads{bytes.NewReader(image)}, valid))
+	require.NoError(t, err)
+	assert.Equal(t, image, read)
+
+	invalid, err := NewChecksumVerifier(strings.Repeat("0", 64))
+	require.NoError(t, err)
+	read, err = io.ReadAll(NewReader(smallReads{bytes.NewReader(image)}, invalid))
+	assert.ErrorContains(t, err, "sha256 checksum mismatch")
+	assert.Equal(t, image[:len(image)-1], read, "the last byte should be held back")
+
+	read, err = io.ReadAll(NewReader(bytes.NewReader(nil)))
+	require.NoError(t, err)
+	assert.Empty(t, read)
+}
+
+func verifyImage(t *testing.T, v Verifier, data []byte) error {
+	_, err := io.Copy(v, bytes.NewReader(data))
+	require.NoError(t, err)
+	return v.Verify()
+}
+
+func TestCosignVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	digest := sha256.Sum256(image)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	signature := []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+
+	v, err := NewCosignVerifier(publicKey, signature)
+	require.NoError(t, err)
+	assert.NoError(t, verifyImage(t, v, image))
+
+	v, err = NewCosignVerifier(publicKey, signature)
+	require.NoError(t, err)
+	assert.ErrorIs(t, verifyImage(t, v, append([]byte("tampered"), image...)), errSignatureMismatch)
+
+	_, err = NewCosignVerifier([]byte("not a key"), signature)
+	assert.Error(t, err)
+}
+
+func TestGPGVerifier(t *testing.T) {
+	entity, err := openpgp.NewEntity("image signer", "", "signer@example.com", nil)
+	require.NoError(t, err)
+
+	var publicKey bytes.Buffer
+	w, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	var binarySignature, armoredSignature bytes.Buffer
+	require.NoError(t, openpgp.DetachSign(&binarySignature, entity, bytes.NewReader(image), nil))
+	require.NoError(t, openpgp.ArmoredDetachSign(&armoredSignature, entity, bytes.NewReader(image), nil))
+
+	for _, signature := range [][]byte{binarySignature.Bytes(), armoredSignature.Bytes()} {
+		v, err := NewGPGVerifier(publicKey.Bytes(), signature)
+		require.NoError(t, err)
+		assert.NoError(t, verifyImage(t, v, image))
+
+		v, err = NewGPGVerifier(publicKey.Bytes(), signature)
+		require.NoError(t, err)
+		assert.ErrorIs(t, verifyImage(t, v, image[1:]), errSignatureMismatch)
+	}
+
+	other, err := openpgp.NewEntity("someone else", "", "other@example.com", nil)
+	require.NoError(t, err)
+	var otherKey bytes.Buffer
+	require.NoError(t, other.Serialize(&otherKey))
+	_, err = NewGPGVerifier(otherKey.Bytes(), binarySignature.Bytes())
+	assert.ErrorContains(t, err, "which is not in the public key")
+}
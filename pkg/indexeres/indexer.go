@@ -18,6 +18,7 @@ import (
 
 const (
 	PVCByDataSourceVolumeSnapshotIndex = "harvesterhci.io/pvc-by-data-source-volume-snapshot"
+	PVCByImageIDIndex                  = "harvesterhci.io/pvc-by-image-id"
 	PodByNodeNameIndex                 = "harvesterhci.io/pod-by-nodename"
 	PodByPVCIndex                      = "harvesterhci.io/pod-by-pvc"
 	VolumeByNodeIndex                  = "harvesterhci.io/volume-by-node"
@@ -33,6 +34,7 @@ func Setup(ctx context.Context, _ *server.Server, _ *server.Controllers, _ confi
 
 	pvcInformer := management.CoreFactory.Core().V1().PersistentVolumeClaim().Cache()
 	pvcInformer.AddIndexer(PVCByDataSourceVolumeSnapshotIndex, pvcByDataSourceVolumeSnapshot)
+	pvcInformer.AddIndexer(PVCByImageIDIndex, PVCByImageID)
 
 	podInformer := management.CoreFactory.Core().V1().Pod().Cache()
 	podInformer.AddIndexer(PodByNodeNameIndex, PodByNodeName)
@@ -93,6 +95,13 @@ func VMBackupBySourceVMName(obj *harvesterv1.VirtualMachineBackup) ([]string, er
 	return []string{obj.Spec.Source.Name}, nil
 }
 
+func PVCByImageID(obj *corev1.PersistentVolumeClaim) ([]string, error) {
+	if imageID := obj.Annotations[util.AnnotationImageID]; imageID != "" {
+		return []string{imageID}, nil
+	}
+	return []string{}, nil
+}
+
 func VMTemplateVersionByImageID(obj *harvesterv1.VirtualMachineTemplateVersion) ([]string, error) {
 	volumeClaimTemplateStr, ok := obj.Spec.VM.ObjectMeta.Annotations[util.AnnotationVolumeClaimTemplates]
 	if !ok || volumeClaimTemplateStr == "" {
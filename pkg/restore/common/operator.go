@@ -145,6 +145,7 @@ type VMRestoreReader interface {
 	IsMissingVolumes(vmr *harvesterv1.VirtualMachineRestore) bool
 	IsDeleting(vmr *harvesterv1.VirtualMachineRestore) bool
 	IsNewVMOrHasRetainPolicy(vmr *harvesterv1.VirtualMachineRestore) bool
+	IsPartialRestore(vmr *harvesterv1.VirtualMachineRestore) bool
 	HasStatus(vmr *harvesterv1.VirtualMachineRestore) bool
 	HasOwnerReference(vmr *harvesterv1.VirtualMachineRestore) bool
 
@@ -173,6 +174,10 @@ type VMRestoreReader interface {
 	GetTargetUID(vmr *harvesterv1.VirtualMachineRestore) *types.UID
 	GetRestoreTime(vmr *harvesterv1.VirtualMachineRestore) *metav1.Time
 	GetProgress(vmr *harvesterv1.VirtualMachineRestore) int
+
+	// Partial restore accessors
+	GetVolumeSelection(vmr *harvesterv1.VirtualMachineRestore, volumeName string) *harvesterv1.VolumeRestoreSelection
+	GetSelectionTargetVolumeName(sel *harvesterv1.VolumeRestoreSelection) string
 }
 
 type vmrestoreReader struct{}
@@ -225,7 +230,13 @@ func (c *vmrestoreReader) IsKeepHaltedAfterRestore(vmr *harvesterv1.VirtualMachi
 	return vmr.Spec.HaltAfterRestore
 }
 
+// IsMissingVolumes reports whether InitVolumesStatus still has to run. A
+// partial restore may replace no volume at all, so its deleted volumes are
+// initialized along with the volume restores.
 func (c *vmrestoreReader) IsMissingVolumes(vmr *harvesterv1.VirtualMachineRestore) bool {
+	if c.IsPartialRestore(vmr) {
+		return len(vmr.Status.VolumeRestores) == 0
+	}
 	return len(vmr.Status.VolumeRestores) == 0 ||
 		(!isNewVMOrHasRetainPolicy(vmr) && len(vmr.Status.DeletedVolumes) == 0)
 }
@@ -238,6 +249,12 @@ func (c *vmrestoreReader) IsNewVMOrHasRetainPolicy(vmr *harvesterv1.VirtualMachi
 	return isNewVMOrHasRetainPolicy(vmr)
 }
 
+// IsPartialRestore reports whether only the selected volumes are restored into
+// the existing VM.
+func (c *vmrestoreReader) IsPartialRestore(vmr *harvesterv1.VirtualMachineRestore) bool {
+	return len(vmr.Spec.Volumes) > 0
+}
+
 func (c *vmrestoreReader) HasStatus(vmr *harvesterv1.VirtualMachineRestore) bool {
 	return vmr.Status.Complete != nil
 }
@@ -358,6 +375,29 @@ func (a *vmrestoreReader) GetProgress(vmr *harvesterv1.VirtualMachineRestore) in
 	return vmr.Status.Progress
 }
 
+// GetVolumeSelection returns the selection of the backup volume, or nil if
+// the volume isn't part of the partial restore.
+func (a *vmrestoreReader) GetVolumeSelection(vmr *harvesterv1.VirtualMachineRestore, volumeName string) *harvesterv1.VolumeRestoreSelection {
+	for i := range vmr.Spec.Volumes {
+		if vmr.Spec.Volumes[i].VolumeName == volumeName {
+			return &vmr.Spec.Volumes[i]
+		}
+	}
+	return nil
+}
+
+// GetSelectionTargetVolumeName returns the volume of the VM the selected
+// volume is restored as, defaulted by the mode.
+func (a *vmrestoreReader) GetSelectionTargetVolumeName(sel *harvesterv1.VolumeRestoreSelection) string {
+	if sel.TargetVolumeName != "" {
+		return sel.TargetVolumeName
+	}
+	if sel.Mode == harvesterv1.VolumeRestoreModeHotplug {
+		return sel.VolumeName + "-restored"
+	}
+	return sel.VolumeName
+}
+
 type vmrestoreOperator struct {
 	// Embedded so existing callers (which depend on the full VMRestoreOperator
 	// interface) get the Is*/Has*/Get* methods for free — no behaviour change.
@@ -804,6 +844,33 @@ func (vmro *vmrestoreOperator) getDeletedVolumes(vmb *harvesterv1.VirtualMachine
 	return deletedVolumes, nil
 }
 
+// getReplacedVolumes returns the PVCs of the target VM disks that a partial
+// restore replaces. Hotplugged volumes don't replace anything.
+func (vmro *vmrestoreOperator) getReplacedVolumes(vmr *harvesterv1.VirtualMachineRestore) ([]string, error) {
+	vm, err := vmro.ResolveTargetVM(vmr)
+	if err != nil {
+		return nil, err
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("target VM %s/%s of the partial restore doesn't exist", vmr.Namespace, vmr.Spec.Target.Name)
+	}
+
+	var replacedVolumes []string
+	for i := range vmr.Spec.Volumes {
+		sel := &vmr.Spec.Volumes[i]
+		if sel.Mode != harvesterv1.VolumeRestoreModeReplace {
+			continue
+		}
+		targetVolumeName := vmro.GetSelectionTargetVolumeName(sel)
+		for _, volume := range vm.Spec.Template.Spec.Volumes {
+			if volume.Name == targetVolumeName && volume.PersistentVolumeClaim != nil {
+				replacedVolumes = append(replacedVolumes, volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+	return replacedVolumes, nil
+}
+
 // getRestorePVCName generates a PVC name for restore.
 // Format: restore-<backupName>-<vmrestoreUID>-<diskName>
 // This matches the historical Harvester convention: the VMRestore UID
@@ -856,6 +923,11 @@ func (vmro *vmrestoreOperator) buildVolumeRestoresFromBackup(
 	vrs := make([]harvesterv1.VolumeRestore, 0, len(vmb.Status.VolumeBackups))
 
 	for _, vb := range vmb.Status.VolumeBackups {
+		// A partial restore leaves the volumes it doesn't select alone
+		if vmro.IsPartialRestore(vmr) && vmro.GetVolumeSelection(vmr, vb.VolumeName) == nil {
+			continue
+		}
+
 		// Check if we already have a VolumeRestore for this volume
 		if existingVR := vmro.findExistingVolumeRestore(vmr, vb.VolumeName); existingVR != nil {
 			vrs = append(vrs, *existingVR)
@@ -890,7 +962,15 @@ func (vmro *vmrestoreOperator) InitVolumesStatus(old *harvesterv1.VirtualMachine
 	}
 
 	deletedVolumes := vmro.GetDeletedVolumes(vmrCpy)
-	if !isNewVMOrHasRetainPolicy(vmrCpy) && len(deletedVolumes) == 0 {
+	if vmro.IsPartialRestore(vmrCpy) {
+		if !isNewVMOrHasRetainPolicy(vmrCpy) {
+			replacedVolumes, err := vmro.getReplacedVolumes(vmrCpy)
+			if err != nil {
+				return err
+			}
+			vmrCpy.Status.DeletedVolumes = replacedVolumes
+		}
+	} else if !isNewVMOrHasRetainPolicy(vmrCpy) && len(deletedVolumes) == 0 {
 		deletedVolumes, err := vmro.getDeletedVolumes(vmb)
 		if err != nil {
 			return err
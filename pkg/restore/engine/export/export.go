@@ -0,0 +1,429 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/longhorn/backupstore"
+	ctlbatchv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/batch/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	restorecommon "github.com/harvester/harvester/pkg/restore/common"
+	"github.com/harvester/harvester/pkg/restore/engine"
+	"github.com/harvester/harvester/pkg/restore/pvchelper"
+	"github.com/harvester/harvester/pkg/settings"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+)
+
+const (
+	// restoredAnnotation is set on the staging PVC once the data mover
+	// filled it, so the Job can be removed without losing that information.
+	restoredAnnotation = "harvesterhci.io/datamover-restored"
+
+	restoreProgressComplete = 100
+	restoreJobWatcherName   = "snapshot-export-restore-job-watcher"
+	restoreSuffix           = "restore"
+)
+
+// ExportRestoreEngine implements RestoreEngine for snapshot-export backups.
+// It runs a data mover Job that downloads the exported volume from the
+// backup target into a staging PVC in datamover.Namespace, so the backup
+// target credentials stay there, and then hands the PV of the staging PVC
+// over to the restored PVC.
+type ExportRestoreEngine struct {
+	vmbo          common.VMBackupOperator
+	vmro          restorecommon.VMRestoreOperator
+	pvcCache      ctlcorev1.PersistentVolumeClaimCache
+	pvcClient     ctlcorev1.PersistentVolumeClaimClient
+	pvCache       ctlcorev1.PersistentVolumeCache
+	pvClient      ctlcorev1.PersistentVolumeClient
+	secretCache   ctlcorev1.SecretCache
+	secretClient  ctlcorev1.SecretClient
+	jobCache      ctlbatchv1.JobCache
+	jobController ctlbatchv1.JobController
+	clientset     kubernetes.Interface
+}
+
+func GetRestoreEngine(
+	vmbo common.VMBackupOperator,
+	vmro restorecommon.VMRestoreOperator,
+	pvcCache ctlcorev1.PersistentVolumeClaimCache,
+	pvcClient ctlcorev1.PersistentVolumeClaimClient,
+	pvCache ctlcorev1.PersistentVolumeCache,
+	pvClient ctlcorev1.PersistentVolumeClient,
+	secretCache ctlcorev1.SecretCache,
+	secretClient ctlcorev1.SecretClient,
+	jobController ctlbatchv1.JobController,
+	clientset kubernetes.Interface,
+) engine.RestoreEngine {
+	return &ExportRestoreEngine{
+		vmbo:          vmbo,
+		vmro:          vmro,
+		pvcCache:      pvcCache,
+		pvcClient:     pvcClient,
+		pvCache:       pvCache,
+		pvClient:      pvClient,
+		secretCache:   secretCache,
+		secretClient:  secretClient,
+		jobCache:      jobController.Cache(),
+		jobController: jobController,
+		clientset:     clientset,
+	}
+}
+
+// stagingName names the staging PVC and the data mover Job in
+// datamover.Namespace restoring a PVC.
+func stagingName(namespace, pvcName string) string {
+	return datamover.ResourceName(namespace, pvcName, restoreSuffix)
+}
+
+func (ere *ExportRestoreEngine) Reconcile(
+	vmr *harvesterv1.VirtualMachineRestore,
+	vmb *harvesterv1.VirtualMachineBackup,
+	volIndex int,
+) error {
+	vr := ere.vmro.GetVolRestore(vmr, volIndex)
+	if vr == nil {
+		return fmt.Errorf("volume restore at index %d not found", volIndex)
+	}
+
+	vb := ere.vmbo.GetVolBackup(vmb, volIndex)
+	if vb == nil {
+		return fmt.Errorf("volume backup at index %d not found", volIndex)
+	}
+
+	pvcName := ere.vmro.GetVolRestorePVCName(vr)
+	namespace := ere.vmro.GetNamespace(vmr)
+
+	// The restored PVC only exists once the staging PVC is filled.
+	pvc, err := ere.pvcCache.Get(namespace, pvcName)
+	if err == nil {
+		return ere.completeHandOver(vr, pvc)
+	}
+	if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get PVC %s/%s: %w", namespace, pvcName, err)
+	}
+
+	staging, err := ere.pvcCache.Get(datamover.Namespace, stagingName(namespace, pvcName))
+	if apierrors.IsNotFound(err) {
+		if err := ere.createStagingPVC(vmr, vr, vb); err != nil {
+			return err
+		}
+		return engine.ErrRetryLater
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get staging PVC: %w", err)
+	}
+
+	if staging.Annotations[restoredAnnotation] == strconv.FormatBool(true) {
+		return ere.handOver(vmr, vr, vb, staging)
+	}
+
+	job, err := ere.jobCache.Get(datamover.Namespace, staging.Name)
+	if apierrors.IsNotFound(err) {
+		if err := ere.createRestoreJob(vmr, vmb, vb, staging, volIndex); err != nil {
+			return err
+		}
+		return engine.ErrRetryLater
+	}
+	if err != nil {
+		return err
+	}
+
+	return ere.reconcileJob(vmr, vmb, vr, vb, staging, job, volIndex)
+}
+
+// stagingLabels point the resources in datamover.Namespace back to the
+// VMRestore, owner references can't cross namespaces.
+func (ere *ExportRestoreEngine) stagingLabels(vmr *harvesterv1.VirtualMachineRestore) map[string]string {
+	return map[string]string{
+		datamover.LabelVMRestore: ere.vmro.GetName(vmr),
+		datamover.LabelNamespace: ere.vmro.GetNamespace(vmr),
+	}
+}
+
+func (ere *ExportRestoreEngine) createStagingPVC(
+	vmr *harvesterv1.VirtualMachineRestore,
+	vr *harvesterv1.VolumeRestore,
+	vb *harvesterv1.VolumeBackup,
+) error {
+	pvc := pvchelper.BuildEmptyPVC(datamover.Namespace, stagingName(ere.vmro.GetNamespace(vmr), ere.vmro.GetVolRestorePVCName(vr)),
+		ere.stagingLabels(vmr), nil, ere.vmbo.GetVolBackupPVCSpec(vb))
+	if _, err := ere.pvcClient.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create staging PVC %s/%s: %w", pvc.Namespace, pvc.Name, err)
+	}
+	return nil
+}
+
+// handOver retains the PV of the filled staging PVC and creates the restored
+// PVC on it. completeHandOver binds them once the staging PVC is gone.
+func (ere *ExportRestoreEngine) handOver(
+	vmr *harvesterv1.VirtualMachineRestore,
+	vr *harvesterv1.VolumeRestore,
+	vb *harvesterv1.VolumeBackup,
+	staging *corev1.PersistentVolumeClaim,
+) error {
+	if staging.Spec.VolumeName == "" {
+		return fmt.Errorf("staging PVC %s/%s is not bound", staging.Namespace, staging.Name)
+	}
+	pv, err := ere.pvCache.Get(staging.Spec.VolumeName)
+	if err != nil {
+		return fmt.Errorf("failed to get PV %s: %w", staging.Spec.VolumeName, err)
+	}
+	if err := datamover.RetainPV(ere.pvClient, pv); err != nil {
+		return err
+	}
+
+	annotations := pvchelper.BuildRestoreAnnotations(
+		ere.vmbo.GetVolBackupPVCAnnotations(vb), ere.vmro.GetName(vmr), restorecommon.RestoreNameAnnotation)
+	annotations[restoredAnnotation] = strconv.FormatBool(true)
+	// Strip CDI ownership markers so CDI doesn't latch onto the restored PVC.
+	labels := pvchelper.BuildRestoreLabels(ere.vmbo.GetVolBackupPVCLabels(vb))
+
+	pvc := pvchelper.BuildEmptyPVC(ere.vmro.GetNamespace(vmr), ere.vmro.GetVolRestorePVCName(vr),
+		labels, annotations, ere.vmbo.GetVolBackupPVCSpec(vb))
+	pvc.Spec.VolumeName = pv.Name
+	if _, err := ere.pvcClient.Create(pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return engine.ErrRetryLater
+}
+
+// completeHandOver removes the staging PVC and binds its PV to the restored
+// PVC, then puts back the reclaim policy of the PV.
+func (ere *ExportRestoreEngine) completeHandOver(vr *harvesterv1.VolumeRestore, pvc *corev1.PersistentVolumeClaim) error {
+	if pvc.Annotations[restoredAnnotation] != strconv.FormatBool(true) || pvc.Spec.VolumeName == "" {
+		return fmt.Errorf("PVC %s/%s already exists and is not restored from the backup", pvc.Namespace, pvc.Name)
+	}
+
+	pv, err := ere.pvCache.Get(pvc.Spec.VolumeName)
+	if err != nil {
+		return fmt.Errorf("failed to get PV %s: %w", pvc.Spec.VolumeName, err)
+	}
+
+	if pvc.Status.Phase != corev1.ClaimBound {
+		stagingName := stagingName(pvc.Namespace, pvc.Name)
+		if err := ere.pvcClient.Delete(datamover.Namespace, stagingName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete staging PVC %s/%s: %w", datamover.Namespace, stagingName, err)
+		}
+		if _, err := ere.pvcCache.Get(datamover.Namespace, stagingName); err == nil {
+			return engine.ErrRetryLater
+		} else if !apierrors.IsNotFound(err) {
+			return err
+		}
+		if err := datamover.ClaimPV(ere.pvClient, pv, pvc); err != nil {
+			return err
+		}
+		return pvchelper.CheckPVCStatus(pvc)
+	}
+
+	if err := datamover.RestoreReclaimPolicy(ere.pvClient, pv); err != nil {
+		return err
+	}
+	return ere.vmro.SetVolRestoreProgress(vr, restoreProgressComplete)
+}
+
+// getBackupTarget returns the current backup target, and fails if the VMBackup
+// wasn't exported to it.
+func (ere *ExportRestoreEngine) getBackupTarget(vmb *harvesterv1.VirtualMachineBackup) (*settings.BackupTarget, error) {
+	target, err := ere.vmbo.GetCurrentBackupTarget(vmb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup target: %w", err)
+	}
+	if target.IsDefaultBackupTarget() {
+		return nil, errors.New("backup target is not set")
+	}
+	if !ere.vmbo.IsTargetConsistent(vmb, target) {
+		return nil, fmt.Errorf("VMBackup %s/%s is not in the current backup target",
+			ere.vmbo.GetNamespace(vmb), ere.vmbo.GetName(vmb))
+	}
+	return target, nil
+}
+
+func (ere *ExportRestoreEngine) exportPath(vmb *harvesterv1.VirtualMachineBackup, vb *harvesterv1.VolumeBackup) (string, error) {
+	vbName := ere.vmbo.GetVolBackupName(vb)
+	if vbName == nil {
+		return "", fmt.Errorf("%w in volume backup metadata", common.ErrVolumeBackupNameNil)
+	}
+	return backuputil.GetVolumeExportPath(ere.vmbo.GetNamespace(vmb), ere.vmbo.GetName(vmb), *vbName), nil
+}
+
+func (ere *ExportRestoreEngine) progressPath(vmr *harvesterv1.VirtualMachineRestore, exportPath string, volIndex int) string {
+	return datamover.GetRestoreProgressPath(exportPath, fmt.Sprintf("%s-%d", ere.vmro.GetRestoreID(vmr), volIndex))
+}
+
+func (ere *ExportRestoreEngine) createRestoreJob(
+	vmr *harvesterv1.VirtualMachineRestore,
+	vmb *harvesterv1.VirtualMachineBackup,
+	vb *harvesterv1.VolumeBackup,
+	staging *corev1.PersistentVolumeClaim,
+	volIndex int,
+) error {
+	target, err := ere.getBackupTarget(vmb)
+	if err != nil {
+		return err
+	}
+
+	exportPath, err := ere.exportPath(vmb, vb)
+	if err != nil {
+		return err
+	}
+
+	secretName, err := datamover.EnsureCredentialSecret(ere.secretCache, ere.secretClient, target)
+	if err != nil {
+		return err
+	}
+
+	image, err := datamover.GetImage(ere.clientset)
+	if err != nil {
+		return fmt.Errorf("failed to get data mover image: %w", err)
+	}
+
+	job, err := datamover.BuildJob(datamover.JobOptions{
+		Name:                 staging.Name,
+		Labels:               ere.stagingLabels(vmr),
+		Image:                image,
+		Target:               target,
+		CredentialSecretName: secretName,
+		PVCName:              staging.Name,
+		VolumeMode:           staging.Spec.VolumeMode,
+		Command:              datamover.CommandDownload,
+		ExportPath:           exportPath,
+		ProgressPath:         ere.progressPath(vmr, exportPath, volIndex),
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"namespace":  ere.vmro.GetNamespace(vmr),
+		"vmRestore":  ere.vmro.GetName(vmr),
+		"pvc":        staging.Name,
+		"exportPath": exportPath,
+	}).Info("creating data mover job to restore volume export")
+	if _, err := ere.jobController.Create(job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create data mover job %s/%s: %w", job.Namespace, job.Name, err)
+	}
+	return nil
+}
+
+func (ere *ExportRestoreEngine) reconcileJob(
+	vmr *harvesterv1.VirtualMachineRestore,
+	vmb *harvesterv1.VirtualMachineBackup,
+	vr *harvesterv1.VolumeRestore,
+	vb *harvesterv1.VolumeBackup,
+	staging *corev1.PersistentVolumeClaim,
+	job *batchv1.Job,
+	volIndex int,
+) error {
+	finished, failure := datamover.IsJobFinished(job)
+	if failure != "" {
+		return errors.New(failure)
+	}
+
+	bsDriver, err := ere.getBackupStoreDriver(vmb)
+	if err != nil {
+		return err
+	}
+	exportPath, err := ere.exportPath(vmb, vb)
+	if err != nil {
+		return err
+	}
+	progressPath := ere.progressPath(vmr, exportPath, volIndex)
+
+	if !finished {
+		progress, err := datamover.LoadProgress(bsDriver, progressPath)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to load data mover progress of PVC %s/%s", staging.Namespace, staging.Name)
+		} else if err := ere.vmro.SetVolRestoreProgress(vr, int(progress.Percentage())); err != nil {
+			return err
+		}
+		return engine.ErrRetryLater
+	}
+
+	stagingCpy := staging.DeepCopy()
+	if stagingCpy.Annotations == nil {
+		stagingCpy.Annotations = map[string]string{}
+	}
+	stagingCpy.Annotations[restoredAnnotation] = strconv.FormatBool(true)
+	if _, err := ere.pvcClient.Update(stagingCpy); err != nil {
+		return err
+	}
+
+	if err := ere.deleteJob(job.Namespace, job.Name); err != nil {
+		return err
+	}
+	if err := bsDriver.Remove(progressPath); err != nil {
+		logrus.WithError(err).Warnf("failed to remove data mover progress %s", progressPath)
+	}
+
+	return ere.handOver(vmr, vr, vb, stagingCpy)
+}
+
+func (ere *ExportRestoreEngine) getBackupStoreDriver(vmb *harvesterv1.VirtualMachineBackup) (backupstore.BackupStoreDriver, error) {
+	target, err := ere.getBackupTarget(vmb)
+	if err != nil {
+		return nil, err
+	}
+	return backuputil.GetBackupStoreDriver(ere.secretCache, target)
+}
+
+func (ere *ExportRestoreEngine) deleteJob(namespace, name string) error {
+	err := ere.jobController.Delete(namespace, name, &metav1.DeleteOptions{
+		PropagationPolicy: ptr.To(metav1.DeletePropagationBackground),
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete data mover job %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+func (ere *ExportRestoreEngine) UpdateProgress(vr *harvesterv1.VolumeRestore) (int64, error) {
+	// Reconcile refreshes the progress from the data mover while the Job runs.
+	return int64(ere.vmro.GetVolRestoreProgress(vr)), nil
+}
+
+// Delete removes the data mover Job and the staging PVC of the volume, they
+// aren't garbage collected with the VMRestore in another namespace. A PV
+// already handed over stays with the restored PVC.
+func (ere *ExportRestoreEngine) Delete(vmr *harvesterv1.VirtualMachineRestore, volIndex int) error {
+	vr := ere.vmro.GetVolRestore(vmr, volIndex)
+	if vr == nil {
+		return nil
+	}
+
+	name := stagingName(ere.vmro.GetNamespace(vmr), ere.vmro.GetVolRestorePVCName(vr))
+	if err := ere.deleteJob(datamover.Namespace, name); err != nil {
+		return err
+	}
+	if err := ere.pvcClient.Delete(datamover.Namespace, name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete staging PVC %s/%s: %w", datamover.Namespace, name, err)
+	}
+	return nil
+}
+
+// RegisterWatchers maps data mover Job changes back to the VMRestore through
+// the labels set on the Job.
+func (ere *ExportRestoreEngine) RegisterWatchers(ctx context.Context, enqueueVMRestore func(namespace, name string)) {
+	ere.jobController.OnChange(ctx, restoreJobWatcherName, func(_ string, job *batchv1.Job) (*batchv1.Job, error) {
+		if job == nil || job.DeletionTimestamp != nil {
+			return nil, nil
+		}
+		if vmrName, ok := job.Labels[datamover.LabelVMRestore]; ok && job.Namespace == datamover.Namespace {
+			enqueueVMRestore(job.Labels[datamover.LabelNamespace], vmrName)
+		}
+		return nil, nil
+	})
+}
@@ -232,29 +232,29 @@ func (lre *LonghornRestoreEngine) validateVolumeSnapshotContent(vb *harvesterv1.
 	_, vol, backup := decodeSnapshotID(*vsc.Spec.Source.SnapshotHandle)
 
 	// Check if the Longhorn backup exists
-	if err := lre.validateLonghornBackup(backup); err != nil {
+	lhBackup, err := lre.lhBackupCache.Get(util.LonghornSystemNamespaceName, backup)
+	if err != nil {
 		return err
 	}
 
 	// Check if the backup volume exists
-	if err := lre.validateBackupVolume(vol, vsc); err != nil {
+	if err := lre.validateBackupVolume(vol, lhBackup.Status.BackupTargetName, vsc); err != nil {
 		return fmt.Errorf("volume backup %s needs to update VolumeSnapshot", *volBackupName)
 	}
 
 	return nil
 }
 
-// validateLonghornBackup checks if the Longhorn backup exists
-func (lre *LonghornRestoreEngine) validateLonghornBackup(backupName string) error {
-	_, err := lre.lhBackupCache.Get(util.LonghornSystemNamespaceName, backupName)
-	return err
-}
-
-// validateBackupVolume checks if the backup volume exists and is unique
-func (lre *LonghornRestoreEngine) validateBackupVolume(volumeName string, vsc *snapshotv1.VolumeSnapshotContent) error {
+// validateBackupVolume checks if the backup volume exists and is unique in
+// the backup target of the backup. A volume has a backup volume in every
+// backup target it's backed up to.
+func (lre *LonghornRestoreEngine) validateBackupVolume(volumeName, backupTargetName string, vsc *snapshotv1.VolumeSnapshotContent) error {
 	sets := labels.Set{
 		types.LonghornLabelBackupVolume: volumeName,
 	}
+	if backupTargetName != "" {
+		sets[types.LonghornLabelBackupTarget] = backupTargetName
+	}
 
 	bvs, err := lre.lhBackupVolumeCache.List(util.LonghornSystemNamespaceName, sets.AsSelector())
 	if err != nil {
@@ -24,6 +24,24 @@ func BuildPVCFromSnapshot(
 	labels map[string]string,
 	annotations map[string]string,
 	pvcSpec corev1.PersistentVolumeClaimSpec,
+) *corev1.PersistentVolumeClaim {
+	pvc := BuildEmptyPVC(namespace, pvcName, labels, annotations, pvcSpec)
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: ptr.To(snapshotv1.SchemeGroupVersion.Group),
+		Kind:     volumeSnapshotKind,
+		Name:     vsName,
+	}
+	return pvc
+}
+
+// BuildEmptyPVC creates a PVC spec without data source, for engines that
+// populate the PVC content by themselves
+func BuildEmptyPVC(
+	namespace string,
+	pvcName string,
+	labels map[string]string,
+	annotations map[string]string,
+	pvcSpec corev1.PersistentVolumeClaimSpec,
 ) *corev1.PersistentVolumeClaim {
 	return &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -33,12 +51,7 @@ func BuildPVCFromSnapshot(
 			Annotations: annotations,
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes: pvcSpec.AccessModes,
-			DataSource: &corev1.TypedLocalObjectReference{
-				APIGroup: ptr.To(snapshotv1.SchemeGroupVersion.Group),
-				Kind:     volumeSnapshotKind,
-				Name:     vsName,
-			},
+			AccessModes:      pvcSpec.AccessModes,
 			Resources:        pvcSpec.Resources,
 			StorageClassName: pvcSpec.StorageClassName,
 			VolumeMode:       pvcSpec.VolumeMode,
@@ -69,6 +69,8 @@ var (
 	VMMigrationNetwork                = NewSetting(VMMigrationNetworkSettingName, "")
 	KubeVirtMigration                 = NewSetting(KubeVirtMigrationSettingName, `{"parallelOutboundMigrationsPerNode":2,"parallelMigrationsPerCluster":5,"allowAutoConverge":false,"bandwidthPerMigration":0,"completionTimeoutPerGiB":150,"progressTimeout":150,"unsafeMigrationOverride":false,"allowPostCopy":false,"allowWorkloadDisruption":false,"disableTLS":false,"matchSELinuxLevelOnMigration":false}`)
 	ClusterPodSecurityStandardSetting = NewSetting(ClusterPodSecurityStandardSettingName, `{"enabled":false,"whitelistedNamespacesList":"", "privilegedNamespacesList":"", "restrictedNamespacesList":""}`)
+	ImageVerificationPolicy           = NewSetting(ImageVerificationPolicySettingName, `{"required":false}`)
+	ImageRetentionPolicy              = NewSetting(ImageRetentionPolicySettingName, `{"enabled":false,"unusedDays":30}`)
 )
 
 const (
@@ -125,6 +127,8 @@ const (
 	RancherClusterSettingName                         = "rancher-cluster"
 	KubeVirtMigrationSettingName                      = "kubevirt-migration"
 	ClusterPodSecurityStandardSettingName             = "cluster-pod-security-standard"
+	ImageVerificationPolicySettingName                = "image-verification-policy"
+	ImageRetentionPolicySettingName                   = "image-retention-policy"
 
 	// settings have `default` and `value` string used in many places, replace them with const
 	KeywordDefault = "default"
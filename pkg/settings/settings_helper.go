@@ -30,6 +30,14 @@ type BackupTarget struct {
 	Cert                     string     `json:"cert"`
 	VirtualHostedStyle       bool       `json:"virtualHostedStyle"`
 	RefreshIntervalInSeconds int64      `json:"refreshIntervalInSeconds"`
+	// EncryptionKeySecret is the name of a secret in the harvester-system namespace.
+	// When set, the metadata files and the volume exports in the backup target are encrypted with its key.
+	EncryptionKeySecret string `json:"encryptionKeySecret,omitempty"`
+
+	// Name is the name of the BackupTarget resource, it's empty for the backup-target setting.
+	Name string `json:"-"`
+	// CredentialSecret is the secret in the harvester-system namespace with the credentials of a BackupTarget resource.
+	CredentialSecret string `json:"-"`
 }
 
 type VMForceResetPolicy struct {
@@ -415,3 +423,48 @@ func GetRWXNetworkConfig(setting *harvesterv1.Setting) (*RWXNetworkConfig, error
 	}
 	return rwxConfig, nil
 }
+
+// ImageVerificationPolicyConfig is the policy downloaded VM images are verified with.
+type ImageVerificationPolicyConfig struct {
+	// Required rejects downloaded images that have neither a checksum nor a signature
+	Required bool `json:"required"`
+}
+
+func GetImageVerificationPolicy(setting *harvesterv1.Setting) (*ImageVerificationPolicyConfig, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("the setting is empty, can't get the setting")
+	}
+
+	policy := &ImageVerificationPolicyConfig{}
+	value := setting.EffectiveValue()
+	if value == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("invalid JSON `%s`: %s", value, err.Error())
+	}
+	return policy, nil
+}
+
+// ImageRetentionPolicyConfig is the policy unused VM images are deleted with.
+type ImageRetentionPolicyConfig struct {
+	// Enabled deletes images that have been unused for UnusedDays
+	Enabled    bool `json:"enabled"`
+	UnusedDays int  `json:"unusedDays"`
+}
+
+func GetImageRetentionPolicy(setting *harvesterv1.Setting) (*ImageRetentionPolicyConfig, error) {
+	if setting == nil {
+		return nil, fmt.Errorf("the setting is empty, can't get the setting")
+	}
+
+	policy := &ImageRetentionPolicyConfig{}
+	value := setting.EffectiveValue()
+	if value == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("invalid JSON `%s`: %s", value, err.Error())
+	}
+	return policy, nil
+}
@@ -1,9 +1,7 @@
 package backup
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -13,11 +11,16 @@ import (
 
 	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/settings"
-	"github.com/harvester/harvester/pkg/util"
 )
 
 const (
 	VMImageMetadataFolderPath = "harvester/vmimages/"
+	// VolumeExportFolderPath holds the volume data written by the snapshot-export data mover.
+	VolumeExportFolderPath = "harvester/volumeexports/"
+	// VolumeChunkFolderPath holds the content-addressed blocks shared by all volume exports.
+	VolumeChunkFolderPath = "harvester/volumechunks/"
+	// VMBackupCopyFolderPath holds the progress of the data mover copying VM backups into the backup target.
+	VMBackupCopyFolderPath = "harvester/vmbackupcopies/"
 	// The webhook timeout is 10 seconds, so we can't set too long timeout here.
 	ConnectBackupStoreTimeout = 8 * time.Second
 )
@@ -35,42 +38,35 @@ func ConstructEndpoint(target *settings.BackupTarget) string {
 }
 
 func GetBackupStoreDriver(secretCache ctlcorev1.SecretCache, target *settings.BackupTarget) (backupstore.BackupStoreDriver, error) {
-	if target.Type == settings.S3BackupType {
-		secret, err := secretCache.Get(util.LonghornSystemNamespaceName, util.BackupTargetSecretName)
-		if err != nil {
-			return nil, err
-		}
-		os.Setenv(util.AWSAccessKey, string(secret.Data[util.AWSAccessKey]))
-		os.Setenv(util.AWSSecretKey, string(secret.Data[util.AWSSecretKey]))
-		os.Setenv(util.AWSEndpoints, string(secret.Data[util.AWSEndpoints]))
-		os.Setenv(util.AWSCERT, string(secret.Data[util.AWSCERT]))
-	}
-
-	endpoint := ConstructEndpoint(target)
-
-	// There might be a goroutine leak if the driver doesn't end properly,
-	// Although we can pass ctx, but the underlying driver implementation doesn't support it.
-	// So we should be careful when using GetBackupStoreDriver function.
-	bsDriver, err := util.RunWithTimeoutAndResult(ConnectBackupStoreTimeout, func(_ context.Context) (backupstore.BackupStoreDriver, error) {
-		return backupstore.GetBackupStoreDriver(endpoint)
-	})
+	credentials, err := GetBackupTargetCredentials(secretCache, target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to backup target, reason: %w", err)
+		return nil, err
 	}
-	return bsDriver, nil
+
+	return GetBackupStoreDriverWithCredentials(target, credentials)
 }
 
-func IsBackupTargetSame(statusBackupTarget *harvesterv1.BackupTarget, target *settings.BackupTarget) bool {
+func IsBackupTargetSame(statusBackupTarget *harvesterv1.BackupTargetLocation, target *settings.BackupTarget) bool {
 	if (statusBackupTarget == nil && target != nil) || (statusBackupTarget != nil && target == nil) {
 		return false
 	}
-	return statusBackupTarget.Endpoint == target.Endpoint && statusBackupTarget.BucketName == target.BucketName && statusBackupTarget.BucketRegion == target.BucketRegion
+	return statusBackupTarget.Name == target.Name && statusBackupTarget.Endpoint == target.Endpoint && statusBackupTarget.BucketName == target.BucketName && statusBackupTarget.BucketRegion == target.BucketRegion
 }
 
 func GetVMImageMetadataFilePath(vmImageNamespace, vmImageName string) string {
 	return filepath.Join(VMImageMetadataFolderPath, vmImageNamespace, fmt.Sprintf("%s.cfg", vmImageName))
 }
 
+// GetVolumeExportPath returns the folder holding the exported data of a single volume backup.
+func GetVolumeExportPath(vmBackupNamespace, vmBackupName, volumeBackupName string) string {
+	return filepath.Join(VolumeExportFolderPath, vmBackupNamespace, vmBackupName, volumeBackupName)
+}
+
+// GetVMBackupCopyProgressPath returns the progress file of a VirtualMachineBackupCopy in its destination backup target.
+func GetVMBackupCopyProgressPath(namespace, name string) string {
+	return filepath.Join(VMBackupCopyFolderPath, namespace, fmt.Sprintf("%s.json", name))
+}
+
 func LHSnapToVSCName(lhSnapshotName string) string {
 	return strings.Replace(lhSnapshotName, "snapshot", "snapcontent", 1)
 }
@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/longhorn/backupstore"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	// EncryptionKeySecretKey is the key of the secret data which holds the
+	// backup encryption key, 32 random bytes encoded in base64.
+	EncryptionKeySecretKey = "encryptionKey"
+
+	metadataEncryptionAlgorithm = "aes-256-gcm"
+	encryptionKeySize           = 32
+
+	// The keys are derived from the encryption key for each use with HKDF.
+	metadataKeyInfo = "harvester backup metadata"
+	keyIDInfo       = "harvester backup key id"
+)
+
+// ErrMetadataDecryption is returned when a metadata file can't be decrypted
+// with the current key, or isn't encrypted while the backup target has a key.
+var ErrMetadataDecryption = errors.New("failed to decrypt metadata")
+
+// encryptedMetadata is the content of an encrypted metadata file.
+// Plain metadata files are JSON objects without the encryption field.
+type encryptedMetadata struct {
+	Encryption string `json:"encryption"`
+	KeyID      string `json:"keyID"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// GetEncryptionKey returns the key of the secret referenced by the backup target,
+// or nil if the backup target doesn't enable encryption.
+func GetEncryptionKey(secretCache ctlcorev1.SecretCache, target *settings.BackupTarget) ([]byte, error) {
+	if target == nil || target.EncryptionKeySecret == "" {
+		return nil, nil
+	}
+
+	secret, err := secretCache.Get(util.HarvesterSystemNamespaceName, target.EncryptionKeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encryption key secret %s/%s: %w", util.HarvesterSystemNamespaceName, target.EncryptionKeySecret, err)
+	}
+	return ParseEncryptionKey(secret.Data[EncryptionKeySecretKey])
+}
+
+// ParseEncryptionKey decodes the secret value, which must be a random key of
+// 32 bytes encoded in base64, e.g. generated by "openssl rand -base64 32".
+// Passphrases are rejected, the key is used as is and isn't stretched.
+func ParseEncryptionKey(secretValue []byte) ([]byte, error) {
+	if len(secretValue) == 0 {
+		return nil, fmt.Errorf("encryption key secret has no %s", EncryptionKeySecretKey)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(secretValue)))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("%s of the encryption key secret must be %d random bytes encoded in base64", EncryptionKeySecretKey, encryptionKeySize)
+	}
+	return key, nil
+}
+
+// DeriveKey derives the key for a single use, named by info, from the encryption key.
+func DeriveKey(key []byte, info string) ([]byte, error) {
+	return hkdf.Key(sha256.New, key, nil, info, encryptionKeySize)
+}
+
+// EncryptMetadata encrypts the metadata of the file at filePath with the key.
+// The path is authenticated along with the data, so a metadata file can't be
+// swapped for another one. The metadata is returned as is if the key is nil.
+func EncryptMetadata(key []byte, filePath string, data []byte) ([]byte, error) {
+	if key == nil {
+		return data, nil
+	}
+
+	gcm, err := newMetadataGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := getKeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&encryptedMetadata{
+		Encryption: metadataEncryptionAlgorithm,
+		KeyID:      keyID,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, data, getAdditionalData(filePath)),
+	})
+}
+
+// DecryptMetadata returns the plain metadata of the file at filePath. Once the
+// backup target has a key, plain metadata is rejected like metadata encrypted
+// with another key, the owner of the backup rewrites it encrypted.
+func DecryptMetadata(key []byte, filePath string, data []byte) ([]byte, error) {
+	envelope := &encryptedMetadata{}
+	if err := json.Unmarshal(data, envelope); err != nil || envelope.Encryption == "" {
+		if key != nil {
+			return nil, fmt.Errorf("%w: metadata is not encrypted, but the backup target has an encryption key", ErrMetadataDecryption)
+		}
+		return data, nil
+	}
+
+	if envelope.Encryption != metadataEncryptionAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported encryption %s", ErrMetadataDecryption, envelope.Encryption)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: metadata is encrypted, but the backup target has no encryption key", ErrMetadataDecryption)
+	}
+	keyID, err := getKeyID(key)
+	if err != nil {
+		return nil, err
+	}
+	if envelope.KeyID != keyID {
+		return nil, fmt.Errorf("%w: metadata is encrypted with another key %s", ErrMetadataDecryption, envelope.KeyID)
+	}
+
+	gcm, err := newMetadataGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, envelope.Nonce, envelope.Data, getAdditionalData(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMetadataDecryption, err)
+	}
+	return plain, nil
+}
+
+// ReadMetadata reads a metadata file from the backup target and decrypts it.
+func ReadMetadata(bsDriver backupstore.BackupStoreDriver, filePath string, key []byte) ([]byte, error) {
+	if !bsDriver.FileExists(filePath) {
+		return nil, fmt.Errorf("cannot find %v in backupstore", filePath)
+	}
+
+	rc, err := bsDriver.Read(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptMetadata(key, filePath, data)
+}
+
+// WriteMetadata encrypts the metadata with the key, if any, and writes it to the backup target.
+func WriteMetadata(bsDriver backupstore.BackupStoreDriver, filePath string, key, data []byte) error {
+	data, err := EncryptMetadata(key, filePath, data)
+	if err != nil {
+		return err
+	}
+	return bsDriver.Write(filePath, bytes.NewReader(data))
+}
+
+func newMetadataGCM(key []byte) (cipher.AEAD, error) {
+	metadataKey, err := DeriveKey(key, metadataKeyInfo)
+	if err != nil {
+		return nil, err
+	}
+	return NewGCM(metadataKey)
+}
+
+// NewGCM returns the AES-256-GCM cipher of a derived key.
+func NewGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// getKeyID identifies the key without revealing it, so that a wrong key is reported clearly.
+func getKeyID(key []byte) (string, error) {
+	id, err := hkdf.Key(sha256.New, key, nil, keyIDInfo, 8)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// getAdditionalData binds the ciphertext to the cleaned path of its file.
+func getAdditionalData(filePath string) []byte {
+	return []byte(path.Clean(filePath))
+}
@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMetadataPath = "harvester/vmbackups/default/vmb.cfg"
+
+func newTestEncryptionKey(t *testing.T) ([]byte, []byte) {
+	secretValue := make([]byte, encryptionKeySize)
+	_, err := rand.Read(secretValue)
+	require.NoError(t, err)
+	encoded := []byte(base64.StdEncoding.EncodeToString(secretValue))
+	key, err := ParseEncryptionKey(encoded)
+	require.NoError(t, err)
+	return encoded, key
+}
+
+func TestMetadataEncryption(t *testing.T) {
+	metadata := []byte(`{"name":"vmb","namespace":"default","secretBackups":[{"name":"s","data":{"password":"cGFzcw=="}}]}`)
+
+	secretValue, key := newTestEncryptionKey(t)
+	_, otherKey := newTestEncryptionKey(t)
+
+	encrypted, err := EncryptMetadata(key, testMetadataPath, metadata)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "secretBackups")
+	assert.NotContains(t, string(encrypted), "cGFzcw==")
+
+	// a cluster sharing the key can read it
+	sharedKey, err := ParseEncryptionKey(append(secretValue, '\n'))
+	require.NoError(t, err)
+	plain, err := DecryptMetadata(sharedKey, testMetadataPath, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, plain)
+
+	_, err = DecryptMetadata(otherKey, testMetadataPath, encrypted)
+	assert.ErrorIs(t, err, ErrMetadataDecryption)
+
+	_, err = DecryptMetadata(nil, testMetadataPath, encrypted)
+	assert.ErrorIs(t, err, ErrMetadataDecryption)
+
+	// the metadata of another file can't be swapped in
+	_, err = DecryptMetadata(key, "harvester/vmbackups/default/other.cfg", encrypted)
+	assert.ErrorIs(t, err, ErrMetadataDecryption)
+}
+
+func TestMetadataWithoutEncryption(t *testing.T) {
+	metadata := []byte(`{"name":"vmb","namespace":"default"}`)
+
+	data, err := EncryptMetadata(nil, testMetadataPath, metadata)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, data)
+
+	plain, err := DecryptMetadata(nil, testMetadataPath, metadata)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, plain)
+
+	// plain metadata is rejected once the backup target has a key
+	_, key := newTestEncryptionKey(t)
+	_, err = DecryptMetadata(key, testMetadataPath, metadata)
+	assert.ErrorIs(t, err, ErrMetadataDecryption)
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	for name, secretValue := range map[string][]byte{
+		"empty":      nil,
+		"passphrase": []byte("passphrase"),
+		"short key":  []byte(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseEncryptionKey(secretValue)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/longhorn/backupstore"
+	longhorntypes "github.com/longhorn/longhorn-manager/types"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	wranglername "github.com/rancher/wrangler/v3/pkg/name"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// longhornBackupTargetPrefix keeps the Longhorn BackupTargets of BackupTarget
+// resources apart from the default one and the ones created in Longhorn.
+const longhornBackupTargetPrefix = "harvester"
+
+// GetBackupTarget returns the BackupTarget with the name, or the backup-target
+// setting if the name is empty.
+//
+// The setting isn't migrated into a BackupTarget resource: it's the source of
+// the default BackupTarget of Longhorn, which the existing Longhorn volumes
+// and their backups refer to, and the UI and the upgrade paths still manage
+// the backup target through it. BackupTarget resources are synced to Longhorn
+// BackupTargets of their own, see GetLonghornBackupTargetName.
+func GetBackupTarget(btCache ctlharvesterv1.BackupTargetCache, name string) (*settings.BackupTarget, error) {
+	if name == "" {
+		return settings.DecodeBackupTarget(settings.BackupTargetSet.Get())
+	}
+
+	bt, err := btCache.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup target %s: %w", name, err)
+	}
+	return ToSettingBackupTarget(bt), nil
+}
+
+// GetLonghornBackupTargetName returns the name of the Longhorn BackupTarget
+// Longhorn backups stored in the BackupTarget with the name are written to.
+// The backup-target setting is synced to the default one.
+func GetLonghornBackupTargetName(name string) string {
+	if name == "" {
+		return longhorntypes.DefaultBackupTargetName
+	}
+	return wranglername.SafeConcatName(longhornBackupTargetPrefix, name)
+}
+
+// ToSettingBackupTarget converts a BackupTarget resource to the struct of the
+// backup-target setting, which is what the backup store helpers work with.
+func ToSettingBackupTarget(bt *harvesterv1.BackupTarget) *settings.BackupTarget {
+	return &settings.BackupTarget{
+		Name:                     bt.Name,
+		Type:                     settings.TargetType(bt.Spec.Type),
+		Endpoint:                 bt.Spec.Endpoint,
+		BucketName:               bt.Spec.BucketName,
+		BucketRegion:             bt.Spec.BucketRegion,
+		VirtualHostedStyle:       bt.Spec.VirtualHostedStyle,
+		RefreshIntervalInSeconds: bt.Spec.RefreshIntervalInSeconds,
+		EncryptionKeySecret:      bt.Spec.EncryptionKeySecret,
+		CredentialSecret:         bt.Spec.CredentialSecret,
+	}
+}
+
+// GetBackupTargetLocation returns what a VMBackup records about the backup target it's stored in.
+func GetBackupTargetLocation(target *settings.BackupTarget) *harvesterv1.BackupTargetLocation {
+	return &harvesterv1.BackupTargetLocation{
+		Name:         target.Name,
+		Endpoint:     target.Endpoint,
+		BucketName:   target.BucketName,
+		BucketRegion: target.BucketRegion,
+	}
+}
+
+// GetBackupTargetCredentials returns the environment variables the S3 driver
+// needs to connect to the backup target. The backup-target setting keeps them
+// in the secret synced to Longhorn, a BackupTarget resource in its own secret.
+func GetBackupTargetCredentials(secretCache ctlcorev1.SecretCache, target *settings.BackupTarget) (map[string][]byte, error) {
+	if target.Type != settings.S3BackupType {
+		return nil, nil
+	}
+
+	if target.Name == "" {
+		secret, err := secretCache.Get(util.LonghornSystemNamespaceName, util.BackupTargetSecretName)
+		if err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	}
+
+	if target.CredentialSecret == "" {
+		return nil, fmt.Errorf("backup target %s has no credential secret", target.Name)
+	}
+	secret, err := secretCache.Get(util.HarvesterSystemNamespaceName, target.CredentialSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential secret of backup target %s: %w", target.Name, err)
+	}
+	credentials := map[string][]byte{
+		util.AWSEndpoints:       []byte(target.Endpoint),
+		util.VirtualHostedStyle: []byte(strconv.FormatBool(target.VirtualHostedStyle)),
+	}
+	for k, v := range secret.Data {
+		credentials[k] = v
+	}
+	return credentials, nil
+}
+
+// envLock serializes the S3 drivers. The S3 driver reads its credentials
+// from the process environment on every request, so two targets can't use
+// it at the same time.
+var envLock sync.Mutex
+
+var driverEnvKeys = []string{util.AWSAccessKey, util.AWSSecretKey, util.AWSEndpoints, util.AWSCERT, util.VirtualHostedStyle}
+
+// withEnv runs f with the environment set to env. The previous values are
+// restored afterwards, e.g. the credentials a data mover Pod started with.
+func withEnv(env map[string][]byte, f func()) {
+	if env == nil {
+		f()
+		return
+	}
+
+	envLock.Lock()
+	defer envLock.Unlock()
+
+	previous := map[string]*string{}
+	for _, key := range driverEnvKeys {
+		if v, ok := os.LookupEnv(key); ok {
+			previous[key] = &v
+		} else {
+			previous[key] = nil
+		}
+		os.Setenv(key, string(env[key]))
+	}
+	defer func() {
+		for key, v := range previous {
+			if v == nil {
+				os.Unsetenv(key)
+			} else {
+				os.Setenv(key, *v)
+			}
+		}
+	}()
+	f()
+}
+
+// GetCredentialsFromEnv returns the credentials of a backup target passed to
+// a Pod in environment variables with the prefix, or nil if there are none.
+func GetCredentialsFromEnv(prefix string) map[string][]byte {
+	var credentials map[string][]byte
+	for _, key := range driverEnvKeys {
+		if v, ok := os.LookupEnv(prefix + key); ok {
+			if credentials == nil {
+				credentials = map[string][]byte{}
+			}
+			credentials[key] = []byte(v)
+		}
+	}
+	return credentials
+}
+
+// envDriver sets the environment of its backup target around every call.
+type envDriver struct {
+	driver backupstore.BackupStoreDriver
+	env    map[string][]byte
+}
+
+// GetBackupStoreDriverWithCredentials connects to the backup target with the
+// credentials of GetBackupTargetCredentials, so that drivers of several S3
+// targets can be used at the same time.
+func GetBackupStoreDriverWithCredentials(target *settings.BackupTarget, credentials map[string][]byte) (backupstore.BackupStoreDriver, error) {
+	endpoint := ConstructEndpoint(target)
+
+	// There might be a goroutine leak if the driver doesn't end properly,
+	// Although we can pass ctx, but the underlying driver implementation doesn't support it.
+	// So we should be careful when using this function.
+	driver, err := util.RunWithTimeoutAndResult(ConnectBackupStoreTimeout, func(_ context.Context) (backupstore.BackupStoreDriver, error) {
+		var driver backupstore.BackupStoreDriver
+		var err error
+		withEnv(credentials, func() {
+			driver, err = backupstore.GetBackupStoreDriver(endpoint)
+		})
+		return driver, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to backup target, reason: %w", err)
+	}
+	if credentials == nil {
+		return driver, nil
+	}
+	return &envDriver{driver: driver, env: credentials}, nil
+}
+
+func (d *envDriver) Kind() string   { return d.driver.Kind() }
+func (d *envDriver) GetURL() string { return d.driver.GetURL() }
+
+func (d *envDriver) FileExists(filePath string) (exists bool) {
+	withEnv(d.env, func() { exists = d.driver.FileExists(filePath) })
+	return
+}
+
+func (d *envDriver) FileSize(filePath string) (size int64) {
+	withEnv(d.env, func() { size = d.driver.FileSize(filePath) })
+	return
+}
+
+func (d *envDriver) FileTime(filePath string) (t time.Time) {
+	withEnv(d.env, func() { t = d.driver.FileTime(filePath) })
+	return
+}
+
+func (d *envDriver) Remove(path string) (err error) {
+	withEnv(d.env, func() { err = d.driver.Remove(path) })
+	return
+}
+
+func (d *envDriver) Read(src string) (rc io.ReadCloser, err error) {
+	withEnv(d.env, func() { rc, err = d.driver.Read(src) })
+	return
+}
+
+func (d *envDriver) Write(dst string, rs io.ReadSeeker) (err error) {
+	withEnv(d.env, func() { err = d.driver.Write(dst, rs) })
+	return
+}
+
+func (d *envDriver) List(path string) (names []string, err error) {
+	withEnv(d.env, func() { names, err = d.driver.List(path) })
+	return
+}
+
+func (d *envDriver) Upload(src, dst string) (err error) {
+	withEnv(d.env, func() { err = d.driver.Upload(src, dst) })
+	return
+}
+
+func (d *envDriver) Download(src, dst string) (err error) {
+	withEnv(d.env, func() { err = d.driver.Download(src, dst) })
+	return
+}
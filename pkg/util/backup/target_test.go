@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// fakeSecretCache can't be the one of fakeclients, which imports this package.
+type fakeSecretCache []*corev1.Secret
+
+func (c fakeSecretCache) Get(namespace, name string) (*corev1.Secret, error) {
+	for _, secret := range c {
+		if secret.Namespace == namespace && secret.Name == name {
+			return secret, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+}
+
+func (c fakeSecretCache) List(_ string, _ labels.Selector) ([]*corev1.Secret, error) {
+	return c, nil
+}
+
+func (c fakeSecretCache) AddIndexer(_ string, _ generic.Indexer[*corev1.Secret]) {}
+
+func (c fakeSecretCache) GetByIndex(_, _ string) ([]*corev1.Secret, error) {
+	return nil, nil
+}
+
+func TestGetBackupTargetCredentials(t *testing.T) {
+	secretCache := fakeSecretCache{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: util.LonghornSystemNamespaceName, Name: util.BackupTargetSecretName},
+			Data:       map[string][]byte{util.AWSAccessKey: []byte("default")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: util.HarvesterSystemNamespaceName, Name: "offsite"},
+			Data:       map[string][]byte{util.AWSAccessKey: []byte("offsite")},
+		},
+	}
+
+	credentials, err := GetBackupTargetCredentials(secretCache, &settings.BackupTarget{Type: settings.S3BackupType})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("default"), credentials[util.AWSAccessKey])
+
+	target := ToSettingBackupTarget(&harvesterv1.BackupTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "offsite"},
+		Spec: harvesterv1.BackupTargetSpec{
+			Type:               harvesterv1.BackupTargetTypeS3,
+			Endpoint:           "https://s3.example.com",
+			BucketName:         "bucket",
+			BucketRegion:       "us-east-1",
+			VirtualHostedStyle: true,
+			CredentialSecret:   "offsite",
+		},
+	})
+	credentials, err = GetBackupTargetCredentials(secretCache, target)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("offsite"), credentials[util.AWSAccessKey])
+	assert.Equal(t, []byte("https://s3.example.com"), credentials[util.AWSEndpoints])
+	assert.Equal(t, []byte("true"), credentials[util.VirtualHostedStyle])
+
+	target.CredentialSecret = ""
+	_, err = GetBackupTargetCredentials(secretCache, target)
+	assert.Error(t, err)
+
+	credentials, err = GetBackupTargetCredentials(secretCache, &settings.BackupTarget{Name: "local", Type: settings.NFSBackupType})
+	require.NoError(t, err)
+	assert.Nil(t, credentials)
+}
+
+func TestWithEnvRestoresEnvironment(t *testing.T) {
+	t.Setenv(util.AWSAccessKey, "default")
+	require.NoError(t, os.Unsetenv(util.AWSEndpoints))
+
+	withEnv(map[string][]byte{util.AWSAccessKey: []byte("offsite"), util.AWSEndpoints: []byte("https://s3.example.com")}, func() {
+		assert.Equal(t, "offsite", os.Getenv(util.AWSAccessKey))
+		assert.Equal(t, "https://s3.example.com", os.Getenv(util.AWSEndpoints))
+	})
+
+	assert.Equal(t, "default", os.Getenv(util.AWSAccessKey))
+	_, ok := os.LookupEnv(util.AWSEndpoints)
+	assert.False(t, ok)
+}
+
+func TestGetLonghornBackupTargetName(t *testing.T) {
+	assert.Equal(t, "default", GetLonghornBackupTargetName(""))
+	assert.Equal(t, "harvester-default", GetLonghornBackupTargetName("default"))
+	assert.Equal(t, "harvester-offsite", GetLonghornBackupTargetName("offsite"))
+}
@@ -22,12 +22,29 @@ const (
 	AnnotationSnapshotRevise            = prefix + "/snapRevise"
 	AnnotationSVMBackupID               = prefix + "/svmbackupId"
 	AnnotationSVMBackupSkipCronCheck    = prefix + "/svmbackupSkipCronCheck"
+	AnnotationSVRBackupID               = prefix + "/svrbackupId"
+	AnnotationBackupVerificationID      = prefix + "/backupVerificationId"
 	AnnotationGoldenImage               = prefix + "/goldenImage"
 	LabelImageDisplayName               = prefix + "/imageDisplayName"
+	LabelImageSyncPolicy                = prefix + "/imageSyncPolicy"
+	AnnotationImageSyncSourceUID        = prefix + "/imageSyncSourceUID"
+	LabelRebalancePolicy                = prefix + "/rebalancePolicy"
+	AnnotationRebalanceExclude          = prefix + "/rebalanceExclude"
+	AnnotationVMGroupPendingStart       = prefix + "/vmGroupPendingStart"
+	LabelVMPlacementPolicy              = prefix + "/placementPolicy"
+	LabelNodeMaintenanceCampaign        = prefix + "/nodeMaintenanceCampaign"
+	AnnotationEncryptionKeyVersion      = prefix + "/encryptionKeyVersion"
+	AnnotationVolumeRekeySource         = prefix + "/volumeRekeySource"
+	AnnotationVolumeRekeyTarget         = prefix + "/volumeRekeyTarget"
+	AnnotationVolumeRekeyStatus         = prefix + "/volumeRekeyStatus"
+	AnnotationVolumeRekeyProgress       = prefix + "/volumeRekeyProgress"
+	AnnotationVolumeRekeyMessage        = prefix + "/volumeRekeyMessage"
 	LabelSetting                        = prefix + "/setting"
 	LabelVMName                         = prefix + "/vmName"
 	LabelSVMBackupUID                   = prefix + "/svmbackupUID"
 	LabelSVMBackupTimestamp             = prefix + "/svmbackupTimestamp"
+	LabelSVRBackupUID                   = prefix + "/svrbackupUID"
+	LabelSVRBackupTimestamp             = prefix + "/svrbackupTimestamp"
 	LabelVMCreator                      = prefix + "/creator"
 	LabelVMimported                     = "migration.harvesterhci.io/imported"
 	LabelNodeNameKey                    = "kubevirt.io/nodeName"
@@ -112,6 +129,10 @@ const (
 	CloneActionDeleteEFI          = "delete-efi"
 	CloneActionDeleteTPMRenameEFI = "delete-tpm-and-rename-efi"
 
+	// Values of AnnotationVolumeRekeyStatus, a rekeyed volume is deleted.
+	VolumeRekeyInProgress = "rekeying"
+	VolumeRekeyFailed     = "failed"
+
 	HarvesterManagedNodeLabelKey = prefix + "/managed"
 
 	HarvesterPromoteNodeLabelKey        = prefix + "/promote-node"
@@ -119,6 +140,9 @@ const (
 
 	ContainerdRegistrySecretName = "harvester-containerd-registry"
 	ContainerdRegistryFileName   = "registries.yaml"
+	// ContainerdRegistryConfigSecretDataHost is the data key holding the registry host
+	// in the per-registry credential secrets synced from the containerd-registry setting.
+	ContainerdRegistryConfigSecretDataHost = "host"
 
 	BackupTargetSecretName              = "harvester-backup-target-secret"
 	InternalTLSSecretName               = "tls-rancher-internal"
@@ -208,6 +232,7 @@ const (
 
 	FieldCattlePrefix             = "field.cattle.io"
 	CattleAnnotationResourceQuota = FieldCattlePrefix + "/resourceQuota"
+	CattleProjectID               = FieldCattlePrefix + "/projectId"
 
 	ManagementCattlePrefix                   = "management.cattle.io"
 	LabelManagementDefaultResourceQuota      = "resourcequota." + ManagementCattlePrefix + "/default-resource-quota"
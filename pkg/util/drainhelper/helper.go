@@ -32,6 +32,7 @@ const (
 	defaultGracePeriodSeconds = 180
 	defaultTimeOut            = 240 * time.Second
 	DrainAnnotation           = "harvesterhci.io/drain-requested"
+	drainTaintKey             = "kubevirt.io/drain"
 	ForcedDrain               = "harvesterhci.io/drain-forced"
 	defaultSingleCPCount      = 1
 	defaultHACPCount          = 3
@@ -151,6 +152,31 @@ func DrainPossible(nodeCache ctlcorev1.NodeCache, node *corev1.Node) error {
 	return nil
 }
 
+// RequestMaintenanceMode annotates the node so the node drain controller puts it into maintenance mode.
+func RequestMaintenanceMode(node *corev1.Node, forced bool) {
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[DrainAnnotation] = "true"
+	if forced {
+		node.Annotations[ForcedDrain] = "true"
+	}
+}
+
+// DisableMaintenanceMode uncordons the node and removes the annotations of the maintenance mode.
+func DisableMaintenanceMode(node *corev1.Node) {
+	node.Spec.Unschedulable = false
+	for i, taint := range node.Spec.Taints {
+		if taint.Key == drainTaintKey {
+			node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
+			break
+		}
+	}
+	delete(node.Annotations, DrainAnnotation)
+	delete(node.Annotations, ForcedDrain)
+	delete(node.Annotations, ctlnode.MaintainStatusAnnotationKey)
+}
+
 func maintainModeStrategyFilter(pod corev1.Pod) drain.PodDeleteStatus {
 	// If this label is set, the Pod belongs to a VM. Otherwise we don't need to
 	// skip the Pod.
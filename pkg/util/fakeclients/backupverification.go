@@ -0,0 +1,122 @@
+package fakeclients
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type BackupVerificationClient func(string) harvestertype.BackupVerificationInterface
+
+func (c BackupVerificationClient) Create(verification *harvesterv1beta1.BackupVerification) (*harvesterv1beta1.BackupVerification, error) {
+	return c(verification.Namespace).Create(context.TODO(), verification, metav1.CreateOptions{})
+}
+
+func (c BackupVerificationClient) Update(verification *harvesterv1beta1.BackupVerification) (*harvesterv1beta1.BackupVerification, error) {
+	return c(verification.Namespace).Update(context.TODO(), verification, metav1.UpdateOptions{})
+}
+
+func (c BackupVerificationClient) UpdateStatus(_ *harvesterv1beta1.BackupVerification) (*harvesterv1beta1.BackupVerification, error) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	return c(namespace).Delete(context.TODO(), name, *options)
+}
+
+func (c BackupVerificationClient) Get(namespace, name string, options metav1.GetOptions) (*harvesterv1beta1.BackupVerification, error) {
+	return c(namespace).Get(context.TODO(), name, options)
+}
+
+func (c BackupVerificationClient) List(namespace string, opts metav1.ListOptions) (*harvesterv1beta1.BackupVerificationList, error) {
+	return c(namespace).List(context.TODO(), opts)
+}
+
+func (c BackupVerificationClient) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c(namespace).Watch(context.TODO(), opts)
+}
+
+func (c BackupVerificationClient) Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (result *harvesterv1beta1.BackupVerification, err error) {
+	return c(namespace).Patch(context.TODO(), name, pt, data, metav1.PatchOptions{}, subresources...)
+}
+
+func (c BackupVerificationClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.ClientInterface[*harvesterv1beta1.BackupVerification, *harvesterv1beta1.BackupVerificationList], error) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) Informer() cache.SharedIndexInformer {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) GroupVersionKind() schema.GroupVersionKind {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) AddGenericHandler(_ context.Context, _ string, _ generic.Handler) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) AddGenericRemoveHandler(_ context.Context, _ string, _ generic.Handler) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) Updater() generic.Updater {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) OnChange(_ context.Context, _ string, _ generic.ObjectHandler[*harvesterv1beta1.BackupVerification]) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) OnRemove(_ context.Context, _ string, _ generic.ObjectHandler[*harvesterv1beta1.BackupVerification]) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) Cache() generic.CacheInterface[*harvesterv1beta1.BackupVerification] {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) Enqueue(_, _ string) {
+	panic("implement me")
+}
+
+func (c BackupVerificationClient) EnqueueAfter(_, _ string, _ time.Duration) {
+	// do nothing
+}
+
+type BackupVerificationCache func(string) harvestertype.BackupVerificationInterface
+
+func (c BackupVerificationCache) Get(namespace, name string) (*harvesterv1beta1.BackupVerification, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c BackupVerificationCache) List(namespace string, selector labels.Selector) ([]*harvesterv1beta1.BackupVerification, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.BackupVerification, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c BackupVerificationCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.BackupVerification]) {
+	panic("implement me")
+}
+
+func (c BackupVerificationCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.BackupVerification, error) {
+	panic("implement me")
+}
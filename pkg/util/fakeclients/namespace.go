@@ -56,8 +56,8 @@ func (c NamespaceClient) Create(namespace *v1.Namespace) (*v1.Namespace, error)
 	return c().Create(context.TODO(), namespace, metav1.CreateOptions{})
 }
 
-func (c NamespaceClient) Delete(_ string, _ *metav1.DeleteOptions) error {
-	panic("implement me")
+func (c NamespaceClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return c().Delete(context.TODO(), name, *options)
 }
 
 func (c NamespaceClient) List(metav1.ListOptions) (*v1.NamespaceList, error) {
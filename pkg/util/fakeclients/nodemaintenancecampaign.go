@@ -0,0 +1,79 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type NodeMaintenanceCampaignClient func() harvestertype.NodeMaintenanceCampaignInterface
+
+func (c NodeMaintenanceCampaignClient) Create(campaign *harvesterv1beta1.NodeMaintenanceCampaign) (*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	return c().Create(context.TODO(), campaign, metav1.CreateOptions{})
+}
+
+func (c NodeMaintenanceCampaignClient) Update(campaign *harvesterv1beta1.NodeMaintenanceCampaign) (*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	return c().Update(context.TODO(), campaign, metav1.UpdateOptions{})
+}
+
+func (c NodeMaintenanceCampaignClient) UpdateStatus(campaign *harvesterv1beta1.NodeMaintenanceCampaign) (*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	return c().UpdateStatus(context.TODO(), campaign, metav1.UpdateOptions{})
+}
+
+func (c NodeMaintenanceCampaignClient) Delete(name string, options *metav1.DeleteOptions) error {
+	return c().Delete(context.TODO(), name, *options)
+}
+
+func (c NodeMaintenanceCampaignClient) Get(name string, options metav1.GetOptions) (*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	return c().Get(context.TODO(), name, options)
+}
+
+func (c NodeMaintenanceCampaignClient) List(opts metav1.ListOptions) (*harvesterv1beta1.NodeMaintenanceCampaignList, error) {
+	return c().List(context.TODO(), opts)
+}
+
+func (c NodeMaintenanceCampaignClient) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	return c().Watch(context.TODO(), opts)
+}
+
+func (c NodeMaintenanceCampaignClient) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *harvesterv1beta1.NodeMaintenanceCampaign, err error) {
+	return c().Patch(context.TODO(), name, pt, data, metav1.PatchOptions{}, subresources...)
+}
+
+func (c NodeMaintenanceCampaignClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.NonNamespacedClientInterface[*harvesterv1beta1.NodeMaintenanceCampaign, *harvesterv1beta1.NodeMaintenanceCampaignList], error) {
+	panic("implement me")
+}
+
+type NodeMaintenanceCampaignCache func() harvestertype.NodeMaintenanceCampaignInterface
+
+func (c NodeMaintenanceCampaignCache) Get(name string) (*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	return c().Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c NodeMaintenanceCampaignCache) List(selector labels.Selector) ([]*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	list, err := c().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.NodeMaintenanceCampaign, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c NodeMaintenanceCampaignCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.NodeMaintenanceCampaign]) {
+	panic("implement me")
+}
+
+func (c NodeMaintenanceCampaignCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.NodeMaintenanceCampaign, error) {
+	panic("implement me")
+}
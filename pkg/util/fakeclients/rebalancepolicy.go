@@ -0,0 +1,38 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harv1type "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type RebalancePolicyCache func() harv1type.RebalancePolicyInterface
+
+func (c RebalancePolicyCache) Get(name string) (*harvesterv1.RebalancePolicy, error) {
+	return c().Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c RebalancePolicyCache) List(selector labels.Selector) ([]*harvesterv1.RebalancePolicy, error) {
+	list, err := c().List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1.RebalancePolicy, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c RebalancePolicyCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1.RebalancePolicy]) {
+	panic("implement me")
+}
+
+func (c RebalancePolicyCache) GetByIndex(_, _ string) ([]*harvesterv1.RebalancePolicy, error) {
+	panic("implement me")
+}
@@ -0,0 +1,79 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type VirtualMachineGroupClient func(string) harvestertype.VirtualMachineGroupInterface
+
+func (c VirtualMachineGroupClient) Create(group *harvesterv1beta1.VirtualMachineGroup) (*harvesterv1beta1.VirtualMachineGroup, error) {
+	return c(group.Namespace).Create(context.TODO(), group, metav1.CreateOptions{})
+}
+
+func (c VirtualMachineGroupClient) Update(group *harvesterv1beta1.VirtualMachineGroup) (*harvesterv1beta1.VirtualMachineGroup, error) {
+	return c(group.Namespace).Update(context.TODO(), group, metav1.UpdateOptions{})
+}
+
+func (c VirtualMachineGroupClient) UpdateStatus(group *harvesterv1beta1.VirtualMachineGroup) (*harvesterv1beta1.VirtualMachineGroup, error) {
+	return c(group.Namespace).UpdateStatus(context.TODO(), group, metav1.UpdateOptions{})
+}
+
+func (c VirtualMachineGroupClient) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	return c(namespace).Delete(context.TODO(), name, *options)
+}
+
+func (c VirtualMachineGroupClient) Get(namespace, name string, options metav1.GetOptions) (*harvesterv1beta1.VirtualMachineGroup, error) {
+	return c(namespace).Get(context.TODO(), name, options)
+}
+
+func (c VirtualMachineGroupClient) List(namespace string, opts metav1.ListOptions) (*harvesterv1beta1.VirtualMachineGroupList, error) {
+	return c(namespace).List(context.TODO(), opts)
+}
+
+func (c VirtualMachineGroupClient) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c(namespace).Watch(context.TODO(), opts)
+}
+
+func (c VirtualMachineGroupClient) Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (result *harvesterv1beta1.VirtualMachineGroup, err error) {
+	return c(namespace).Patch(context.TODO(), name, pt, data, metav1.PatchOptions{}, subresources...)
+}
+
+func (c VirtualMachineGroupClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.ClientInterface[*harvesterv1beta1.VirtualMachineGroup, *harvesterv1beta1.VirtualMachineGroupList], error) {
+	panic("implement me")
+}
+
+type VirtualMachineGroupCache func(string) harvestertype.VirtualMachineGroupInterface
+
+func (c VirtualMachineGroupCache) Get(namespace, name string) (*harvesterv1beta1.VirtualMachineGroup, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c VirtualMachineGroupCache) List(namespace string, selector labels.Selector) ([]*harvesterv1beta1.VirtualMachineGroup, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.VirtualMachineGroup, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c VirtualMachineGroupCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.VirtualMachineGroup]) {
+	panic("implement me")
+}
+
+func (c VirtualMachineGroupCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.VirtualMachineGroup, error) {
+	panic("implement me")
+}
@@ -0,0 +1,79 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type VMBackupCopyClient func(string) harvestertype.VirtualMachineBackupCopyInterface
+
+func (c VMBackupCopyClient) Create(vmBackupCopy *harvesterv1beta1.VirtualMachineBackupCopy) (*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	return c(vmBackupCopy.Namespace).Create(context.TODO(), vmBackupCopy, metav1.CreateOptions{})
+}
+
+func (c VMBackupCopyClient) Update(vmBackupCopy *harvesterv1beta1.VirtualMachineBackupCopy) (*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	return c(vmBackupCopy.Namespace).Update(context.TODO(), vmBackupCopy, metav1.UpdateOptions{})
+}
+
+func (c VMBackupCopyClient) UpdateStatus(_ *harvesterv1beta1.VirtualMachineBackupCopy) (*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	panic("implement me")
+}
+
+func (c VMBackupCopyClient) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	return c(namespace).Delete(context.TODO(), name, *options)
+}
+
+func (c VMBackupCopyClient) Get(namespace, name string, options metav1.GetOptions) (*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	return c(namespace).Get(context.TODO(), name, options)
+}
+
+func (c VMBackupCopyClient) List(namespace string, opts metav1.ListOptions) (*harvesterv1beta1.VirtualMachineBackupCopyList, error) {
+	return c(namespace).List(context.TODO(), opts)
+}
+
+func (c VMBackupCopyClient) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c(namespace).Watch(context.TODO(), opts)
+}
+
+func (c VMBackupCopyClient) Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (result *harvesterv1beta1.VirtualMachineBackupCopy, err error) {
+	return c(namespace).Patch(context.TODO(), name, pt, data, metav1.PatchOptions{}, subresources...)
+}
+
+func (c VMBackupCopyClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.ClientInterface[*harvesterv1beta1.VirtualMachineBackupCopy, *harvesterv1beta1.VirtualMachineBackupCopyList], error) {
+	panic("implement me")
+}
+
+type VMBackupCopyCache func(string) harvestertype.VirtualMachineBackupCopyInterface
+
+func (c VMBackupCopyCache) Get(namespace, name string) (*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c VMBackupCopyCache) List(namespace string, selector labels.Selector) ([]*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.VirtualMachineBackupCopy, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c VMBackupCopyCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.VirtualMachineBackupCopy]) {
+	panic("implement me")
+}
+
+func (c VMBackupCopyCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.VirtualMachineBackupCopy, error) {
+	panic("implement me")
+}
@@ -0,0 +1,38 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type VMPlacementPolicyCache func(string) harvestertype.VMPlacementPolicyInterface
+
+func (c VMPlacementPolicyCache) Get(namespace, name string) (*harvesterv1beta1.VMPlacementPolicy, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c VMPlacementPolicyCache) List(namespace string, selector labels.Selector) ([]*harvesterv1beta1.VMPlacementPolicy, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.VMPlacementPolicy, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c VMPlacementPolicyCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.VMPlacementPolicy]) {
+	panic("implement me")
+}
+
+func (c VMPlacementPolicyCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.VMPlacementPolicy, error) {
+	panic("implement me")
+}
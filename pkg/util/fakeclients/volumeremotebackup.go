@@ -0,0 +1,79 @@
+package fakeclients
+
+import (
+	"context"
+
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	harvesterv1beta1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	harvestertype "github.com/harvester/harvester/pkg/generated/clientset/versioned/typed/harvesterhci.io/v1beta1"
+)
+
+type VolumeRemoteBackupClient func(string) harvestertype.VolumeRemoteBackupInterface
+
+func (c VolumeRemoteBackupClient) Create(obj *harvesterv1beta1.VolumeRemoteBackup) (*harvesterv1beta1.VolumeRemoteBackup, error) {
+	return c(obj.Namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+}
+
+func (c VolumeRemoteBackupClient) Update(obj *harvesterv1beta1.VolumeRemoteBackup) (*harvesterv1beta1.VolumeRemoteBackup, error) {
+	return c(obj.Namespace).Update(context.TODO(), obj, metav1.UpdateOptions{})
+}
+
+func (c VolumeRemoteBackupClient) UpdateStatus(obj *harvesterv1beta1.VolumeRemoteBackup) (*harvesterv1beta1.VolumeRemoteBackup, error) {
+	return c(obj.Namespace).UpdateStatus(context.TODO(), obj, metav1.UpdateOptions{})
+}
+
+func (c VolumeRemoteBackupClient) Delete(namespace, name string, options *metav1.DeleteOptions) error {
+	return c(namespace).Delete(context.TODO(), name, *options)
+}
+
+func (c VolumeRemoteBackupClient) Get(namespace, name string, options metav1.GetOptions) (*harvesterv1beta1.VolumeRemoteBackup, error) {
+	return c(namespace).Get(context.TODO(), name, options)
+}
+
+func (c VolumeRemoteBackupClient) List(namespace string, opts metav1.ListOptions) (*harvesterv1beta1.VolumeRemoteBackupList, error) {
+	return c(namespace).List(context.TODO(), opts)
+}
+
+func (c VolumeRemoteBackupClient) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c(namespace).Watch(context.TODO(), opts)
+}
+
+func (c VolumeRemoteBackupClient) Patch(namespace, name string, pt types.PatchType, data []byte, subresources ...string) (result *harvesterv1beta1.VolumeRemoteBackup, err error) {
+	return c(namespace).Patch(context.TODO(), name, pt, data, metav1.PatchOptions{}, subresources...)
+}
+
+func (c VolumeRemoteBackupClient) WithImpersonation(_ rest.ImpersonationConfig) (generic.ClientInterface[*harvesterv1beta1.VolumeRemoteBackup, *harvesterv1beta1.VolumeRemoteBackupList], error) {
+	panic("implement me")
+}
+
+type VolumeRemoteBackupCache func(string) harvestertype.VolumeRemoteBackupInterface
+
+func (c VolumeRemoteBackupCache) Get(namespace, name string) (*harvesterv1beta1.VolumeRemoteBackup, error) {
+	return c(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c VolumeRemoteBackupCache) List(namespace string, selector labels.Selector) ([]*harvesterv1beta1.VolumeRemoteBackup, error) {
+	list, err := c(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*harvesterv1beta1.VolumeRemoteBackup, 0, len(list.Items))
+	for i := range list.Items {
+		result = append(result, &list.Items[i])
+	}
+	return result, err
+}
+
+func (c VolumeRemoteBackupCache) AddIndexer(_ string, _ generic.Indexer[*harvesterv1beta1.VolumeRemoteBackup]) {
+	panic("implement me")
+}
+
+func (c VolumeRemoteBackupCache) GetByIndex(_, _ string) ([]*harvesterv1beta1.VolumeRemoteBackup, error) {
+	panic("implement me")
+}
@@ -82,7 +82,7 @@ func GetImageStorageClassParameters(backingImageCache ctllhv1.BackingImageCache,
 		LonghornOptionBackingImageName: biName,
 	}
 
-	if image.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeClone && image.Spec.SecurityParameters.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt {
+	if image.Spec.SourceType == harvesterv1.VirtualMachineImageSourceTypeClone && IsImageEncrypted(image) {
 		params[LonghornOptionBackingImageDataSourceName] = string(lhv1beta2.BackingImageDataSourceTypeClone)
 	}
 
@@ -92,6 +92,28 @@ func GetImageStorageClassParameters(backingImageCache ctllhv1.BackingImageCache,
 	return params, nil
 }
 
+// IsImageEncrypted returns true if the image is encrypted by an encrypt or a rekey operation.
+func IsImageEncrypted(image *harvesterv1.VirtualMachineImage) bool {
+	sp := image.Spec.SecurityParameters
+	if sp == nil || image.Spec.SourceType != harvesterv1.VirtualMachineImageSourceTypeClone {
+		return false
+	}
+	return sp.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeEncrypt ||
+		sp.CryptoOperation == harvesterv1.VirtualMachineImageCryptoOperationTypeRekey
+}
+
+// GetImageKeyVersion returns the key version of an encrypted image, images encrypted before the key
+// version was tracked are on the first version.
+func GetImageKeyVersion(image *harvesterv1.VirtualMachineImage) int {
+	if !IsImageEncrypted(image) {
+		return 0
+	}
+	if image.Status.Encryption != nil && image.Status.Encryption.KeyVersion > 0 {
+		return image.Status.Encryption.KeyVersion
+	}
+	return 1
+}
+
 func GetImageDefaultStorageClassParameters() map[string]string {
 	return map[string]string{
 		longhorntypes.OptionNumberOfReplicas:    "3",
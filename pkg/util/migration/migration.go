@@ -0,0 +1,181 @@
+package migration
+
+import (
+	"fmt"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	nodecontroller "github.com/harvester/harvester/pkg/controller/master/node"
+	"github.com/harvester/harvester/pkg/util/drainhelper"
+)
+
+// FindMigratableNodes returns the nodes the VMI can be live migrated to, which match the node
+// selector and the required node affinity of the VMI pod and aren't drained.
+func FindMigratableNodes(vmi *kubevirtv1.VirtualMachineInstance, podCache ctlcorev1.PodCache, nodeCache ctlcorev1.NodeCache) ([]string, error) {
+	nodeFilter, err := getNodeSelectorRequirementFromVMI(vmi, podCache)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := nodeCache.List(nodeFilter)
+	if err != nil || len(nodes) == 0 {
+		return nil, err
+	}
+
+	// ignore the node where the VM is running
+	migratableNodes := make([]string, 0, len(nodes)-1)
+	for _, node := range nodes {
+		if vmi.Status.NodeName == node.Name {
+			continue
+		}
+
+		if IsNodeDrained(node) {
+			continue
+		}
+
+		migratableNodes = append(migratableNodes, node.Name)
+	}
+	return migratableNodes, nil
+}
+
+// IsNodeDrained checks if the node is in maintenance mode, being drained or unschedulable.
+func IsNodeDrained(node *corev1.Node) bool {
+	if _, ok := node.Annotations[nodecontroller.MaintainStatusAnnotationKey]; ok {
+		return ok
+	}
+	if _, ok := node.Annotations[drainhelper.DrainAnnotation]; ok {
+		return ok
+	}
+	if node.Spec.Unschedulable {
+		return true
+	}
+	if node.Spec.Taints != nil {
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == corev1.TaintNodeUnreachable || taint.Key == corev1.TaintNodeUnschedulable {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func getNodeSelectorRequirementFromVMI(vmi *kubevirtv1.VirtualMachineInstance, podCache ctlcorev1.PodCache) (labels.Selector, error) {
+	if vmi == nil {
+		return labels.Everything(), nil
+	}
+
+	vmiPod, err := GetVMIPod(vmi, podCache)
+	if err != nil {
+		return labels.Nothing(), err
+	}
+
+	nodeFilter := labels.NewSelector()
+
+	for key, value := range vmiPod.Spec.NodeSelector {
+		if key == corev1.LabelHostname {
+			continue
+		}
+		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create requirement for %s=%s: %w", key, value, err)
+		}
+		nodeFilter = nodeFilter.Add(*requirement)
+	}
+
+	if isRequiredAffinityFilterPresent(vmiPod) {
+		required := vmiPod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		var err error
+		nodeFilter, err = addNodeAffinityFilters(nodeFilter, required.NodeSelectorTerms)
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return nodeFilter, nil
+}
+
+func isRequiredAffinityFilterPresent(pod *corev1.Pod) bool {
+	return pod.Spec.Affinity != nil &&
+		pod.Spec.Affinity.NodeAffinity != nil &&
+		pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil
+}
+
+func addNodeAffinityFilters(nodeFilter labels.Selector, terms []corev1.NodeSelectorTerm) (labels.Selector, error) {
+	for _, term := range terms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == corev1.LabelHostname {
+				continue
+			}
+
+			requirement, err := convertNodeSelectorRequirementToSelector(expr)
+			if err != nil {
+				return nil, err
+			}
+			if requirement != nil {
+				nodeFilter = nodeFilter.Add(*requirement)
+			}
+		}
+	}
+	return nodeFilter, nil
+}
+
+func convertNodeSelectorRequirementToSelector(req corev1.NodeSelectorRequirement) (*labels.Requirement, error) {
+	var op selection.Operator
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn:
+		op = selection.In
+	case corev1.NodeSelectorOpNotIn:
+		op = selection.NotIn
+	case corev1.NodeSelectorOpExists:
+		op = selection.Exists
+	case corev1.NodeSelectorOpDoesNotExist:
+		op = selection.DoesNotExist
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		logrus.Debugf("Skipping unsupported node selector operator %s for key %s", req.Operator, req.Key)
+		return nil, nil
+	default:
+		logrus.Warnf("Unknown node selector operator %s for key %s", req.Operator, req.Key)
+		return nil, nil
+	}
+
+	requirement, err := labels.NewRequirement(req.Key, op, req.Values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create requirement for %s %v %v: %w", req.Key, op, req.Values, err)
+	}
+	return requirement, nil
+}
+
+// GetVMIPod returns the only active virt-launcher pod of the VMI.
+func GetVMIPod(vmi *kubevirtv1.VirtualMachineInstance, podCache ctlcorev1.PodCache) (*corev1.Pod, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		kubevirtv1.CreatedByLabel: string(vmi.UID),
+	})
+
+	vmiPods, err := podCache.List(vmi.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods for VMI %s/%s: %w", vmi.Namespace, vmi.Name, err)
+	}
+
+	var activePods []*corev1.Pod
+	for _, pod := range vmiPods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			activePods = append(activePods, pod)
+		}
+	}
+
+	if len(activePods) == 0 {
+		return nil, fmt.Errorf("there are no active pods for VMI: %s/%s, migration target cannot be picked unless only 1 pod is active", vmi.Namespace, vmi.Name)
+	}
+	if len(activePods) > 1 {
+		return nil, fmt.Errorf("there are multiple active pods for VMI: %s/%s, migration target can be picked only when at most 1 pod is active", vmi.Namespace, vmi.Name)
+	}
+
+	return activePods[0], nil
+}
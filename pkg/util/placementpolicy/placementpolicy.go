@@ -0,0 +1,148 @@
+package placementpolicy
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const (
+	defaultWeight  = 100
+	defaultMaxSkew = 1
+)
+
+// TopologyKey returns the node label the policy applies to.
+func TopologyKey(policy *harvesterv1.VMPlacementPolicy) string {
+	if policy.Spec.TopologyKey != "" {
+		return policy.Spec.TopologyKey
+	}
+	if policy.Spec.Type == harvesterv1.VMPlacementPolicySpread {
+		return corev1.LabelTopologyZone
+	}
+	return corev1.LabelHostname
+}
+
+// IsHard returns true if a member isn't scheduled when the policy can't be met.
+func IsHard(policy *harvesterv1.VMPlacementPolicy) bool {
+	return policy.Spec.Enforcement == harvesterv1.VMPlacementPolicyHard
+}
+
+// MaxSkew returns the maximum difference of the number of members between the topology domains.
+func MaxSkew(policy *harvesterv1.VMPlacementPolicy) int32 {
+	if policy.Spec.MaxSkew > 0 {
+		return policy.Spec.MaxSkew
+	}
+	return defaultMaxSkew
+}
+
+// ApplyToAffinity removes the pod affinity terms of a former policy from the affinity and adds the
+// terms of the policy, the policy may be nil if the VM isn't a member of any policy.
+func ApplyToAffinity(affinity *corev1.Affinity, policy *harvesterv1.VMPlacementPolicy) {
+	if affinity.PodAffinity == nil {
+		affinity.PodAffinity = &corev1.PodAffinity{}
+	}
+	if affinity.PodAntiAffinity == nil {
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+
+	podAffinity, podAntiAffinity := affinity.PodAffinity, affinity.PodAntiAffinity
+	podAffinity.RequiredDuringSchedulingIgnoredDuringExecution = removeTerms(podAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	podAffinity.PreferredDuringSchedulingIgnoredDuringExecution = removeWeightedTerms(podAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+	podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = removeTerms(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = removeWeightedTerms(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+
+	if policy != nil && policy.Spec.Type != harvesterv1.VMPlacementPolicySpread {
+		term := corev1.PodAffinityTerm{
+			LabelSelector: labelSelector(policy),
+			TopologyKey:   TopologyKey(policy),
+		}
+		weight := policy.Spec.Weight
+		if weight == 0 {
+			weight = defaultWeight
+		}
+		weightedTerm := corev1.WeightedPodAffinityTerm{Weight: weight, PodAffinityTerm: term}
+
+		switch {
+		case policy.Spec.Type == harvesterv1.VMPlacementPolicyAffinity && IsHard(policy):
+			podAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(podAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+		case policy.Spec.Type == harvesterv1.VMPlacementPolicyAffinity:
+			podAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(podAffinity.PreferredDuringSchedulingIgnoredDuringExecution, weightedTerm)
+		case IsHard(policy):
+			podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, term)
+		default:
+			podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, weightedTerm)
+		}
+	}
+
+	// drop the empty rules, so the affinity stays the same for the VMs which were never a member of a policy
+	if len(podAffinity.RequiredDuringSchedulingIgnoredDuringExecution) == 0 && len(podAffinity.PreferredDuringSchedulingIgnoredDuringExecution) == 0 {
+		affinity.PodAffinity = nil
+	}
+	if len(podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution) == 0 && len(podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) == 0 {
+		affinity.PodAntiAffinity = nil
+	}
+}
+
+// ApplyToTopologySpreadConstraints removes the constraint of a former policy and adds the
+// constraint of a Spread policy, the policy may be nil if the VM isn't a member of any policy.
+func ApplyToTopologySpreadConstraints(constraints []corev1.TopologySpreadConstraint, policy *harvesterv1.VMPlacementPolicy) []corev1.TopologySpreadConstraint {
+	var result []corev1.TopologySpreadConstraint
+	for _, constraint := range constraints {
+		if !isPolicySelector(constraint.LabelSelector) {
+			result = append(result, constraint)
+		}
+	}
+
+	if policy == nil || policy.Spec.Type != harvesterv1.VMPlacementPolicySpread {
+		return result
+	}
+
+	whenUnsatisfiable := corev1.ScheduleAnyway
+	if IsHard(policy) {
+		whenUnsatisfiable = corev1.DoNotSchedule
+	}
+	return append(result, corev1.TopologySpreadConstraint{
+		MaxSkew:           MaxSkew(policy),
+		TopologyKey:       TopologyKey(policy),
+		WhenUnsatisfiable: whenUnsatisfiable,
+		LabelSelector:     labelSelector(policy),
+	})
+}
+
+func labelSelector(policy *harvesterv1.VMPlacementPolicy) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{util.LabelVMPlacementPolicy: policy.Name},
+	}
+}
+
+// isPolicySelector returns true if the selector selects the members of a policy, these are the
+// terms and constraints added by the VM mutator.
+func isPolicySelector(selector *metav1.LabelSelector) bool {
+	if selector == nil {
+		return false
+	}
+	_, ok := selector.MatchLabels[util.LabelVMPlacementPolicy]
+	return ok
+}
+
+func removeTerms(terms []corev1.PodAffinityTerm) []corev1.PodAffinityTerm {
+	var result []corev1.PodAffinityTerm
+	for _, term := range terms {
+		if !isPolicySelector(term.LabelSelector) {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+func removeWeightedTerms(terms []corev1.WeightedPodAffinityTerm) []corev1.WeightedPodAffinityTerm {
+	var result []corev1.WeightedPodAffinityTerm
+	for _, term := range terms {
+		if !isPolicySelector(term.PodAffinityTerm.LabelSelector) {
+			result = append(result, term)
+		}
+	}
+	return result
+}
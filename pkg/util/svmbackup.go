@@ -28,7 +28,12 @@ func ResolveSVMBackupRef(svmbackupCache ctlharvesterv1.ScheduleVMBackupCache, ob
 }
 
 func GetCronGranularity(svmbackup *harvesterv1.ScheduleVMBackup) (time.Duration, error) {
-	schedule, err := cron.ParseStandard(svmbackup.Spec.Cron)
+	return GetCronExpressionGranularity(svmbackup.Spec.Cron)
+}
+
+// GetCronExpressionGranularity returns the interval between the next two runs of a cron expression.
+func GetCronExpressionGranularity(expression string) (time.Duration, error) {
+	schedule, err := cron.ParseStandard(expression)
 	if err != nil {
 		return time.Duration(math.MinInt64), err
 	}
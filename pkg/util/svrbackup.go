@@ -0,0 +1,24 @@
+package util
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/ref"
+)
+
+func ResolveSVRBackupRef(svrbackupCache ctlharvesterv1.ScheduleVolumeRemoteBackupCache, obj metav1.Object) *harvesterv1.ScheduleVolumeRemoteBackup {
+	var annotations = obj.GetAnnotations()
+	if annotations == nil || annotations[AnnotationSVRBackupID] == "" {
+		return nil
+	}
+
+	namespace, name := ref.Parse(annotations[AnnotationSVRBackupID])
+	svrbackup, err := svrbackupCache.Get(namespace, name)
+	if err != nil {
+		return nil
+	}
+
+	return svrbackup
+}
@@ -0,0 +1,134 @@
+package vmgroup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	computeContainerName = "compute"
+	defaultVMNetworkCIDR = "10.0.2.0/24"
+	tcpProbeTimeout      = 3 * time.Second
+
+	// tcpProbeScript connects to the port $1 of the address $0 with the
+	// /dev/tcp redirection of bash, the compute container has no nc.
+	tcpProbeScript = `exec timeout "$2" bash -c 'exec 3<>"/dev/tcp/$0/$1"' "$0" "$1"`
+)
+
+// PortProber checks that a TCP port of the guest of a VMI accepts connections.
+type PortProber interface {
+	ProbeTCPPort(vmi *kubevirtv1.VirtualMachineInstance, port int32) error
+}
+
+// LauncherPortProber connects to the guest from the network namespace of the
+// compute container of the virt-launcher pod. The guest can't be reached from
+// the Harvester pods, a masquerade pod network is only routed inside the pod.
+type LauncherPortProber struct {
+	clientset  kubernetes.Interface
+	restConfig *rest.Config
+}
+
+func NewLauncherPortProber(clientset kubernetes.Interface, restConfig *rest.Config) *LauncherPortProber {
+	return &LauncherPortProber{
+		clientset:  clientset,
+		restConfig: restConfig,
+	}
+}
+
+func (p *LauncherPortProber) ProbeTCPPort(vmi *kubevirtv1.VirtualMachineInstance, port int32) error {
+	ip, err := GuestPodNetworkIP(vmi)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*tcpProbeTimeout)
+	defer cancel()
+
+	pod, err := p.getLauncherPod(ctx, vmi)
+	if err != nil {
+		return err
+	}
+
+	req := p.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: computeContainerName,
+			Command: []string{"sh", "-c", tcpProbeScript, ip.String(), strconv.Itoa(int(port)),
+				strconv.Itoa(int(tcpProbeTimeout.Seconds()))},
+			Stdout: true,
+			Stderr: true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(p.restConfig, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}); err != nil {
+		return fmt.Errorf("port %d is not open: %w: %s", port, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (p *LauncherPortProber) getLauncherPod(ctx context.Context, vmi *kubevirtv1.VirtualMachineInstance) (*corev1.Pod, error) {
+	pods, err := p.clientset.CoreV1().Pods(vmi.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{kubevirtv1.CreatedByLabel: string(vmi.UID)}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pods for VMI %s/%s: %w", vmi.Namespace, vmi.Name, err)
+	}
+	for i := range pods.Items {
+		if pods.Items[i].Status.Phase == corev1.PodRunning {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("there is no running pod for VMI %s/%s", vmi.Namespace, vmi.Name)
+}
+
+// GuestPodNetworkIP returns the address of the guest on the masquerade pod
+// network of the VMI. KubeVirt gives the guest the second address of the VM
+// network, the first one is the gateway in the virt-launcher pod.
+func GuestPodNetworkIP(vmi *kubevirtv1.VirtualMachineInstance) (netip.Addr, error) {
+	for _, network := range vmi.Spec.Networks {
+		if network.Pod == nil {
+			continue
+		}
+		for _, iface := range vmi.Spec.Domain.Devices.Interfaces {
+			if iface.Name != network.Name || iface.Masquerade == nil {
+				continue
+			}
+			cidr := network.Pod.VMNetworkCIDR
+			if cidr == "" {
+				cidr = defaultVMNetworkCIDR
+			}
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return netip.Addr{}, fmt.Errorf("invalid VM network CIDR %s: %w", cidr, err)
+			}
+			return prefix.Masked().Addr().Next().Next(), nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("the VM has no masquerade pod network")
+}
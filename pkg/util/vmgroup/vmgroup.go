@@ -0,0 +1,147 @@
+package vmgroup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// FindMember returns the group of the namespace the VM is a member of, a VM is a member of one group at most.
+func FindMember(groupCache ctlharvesterv1.VirtualMachineGroupCache, namespace, name string) (*harvesterv1.VirtualMachineGroup, *harvesterv1.VirtualMachineGroupMember, error) {
+	groups, err := groupCache.List(namespace, labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, group := range groups {
+		for i := range group.Spec.Members {
+			if group.Spec.Members[i].Name == name {
+				return group, &group.Spec.Members[i], nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// StartWithGroup leaves the start of the VM to its group if it is a member of one, so the members
+// are started in the order of their boot order. The VM is updated with the pending start annotation
+// in that case, it returns false if the VM isn't a member of a group.
+func StartWithGroup(groupCache ctlharvesterv1.VirtualMachineGroupCache, vmClient ctlkubevirtv1.VirtualMachineClient, vm *kubevirtv1.VirtualMachine) (bool, error) {
+	group, _, err := FindMember(groupCache, vm.Namespace, vm.Name)
+	if err != nil || group == nil {
+		return false, err
+	}
+
+	vmCopy := vm.DeepCopy()
+	if vmCopy.Annotations == nil {
+		vmCopy.Annotations = make(map[string]string)
+	}
+	vmCopy.Annotations[util.AnnotationVMGroupPendingStart] = "true"
+	if _, err := vmClient.Update(vmCopy); err != nil {
+		return false, fmt.Errorf("failed to start VM %s/%s with group %s: %w", vm.Namespace, vm.Name, group.Name, err)
+	}
+	return true, nil
+}
+
+// BootOrders returns the distinct boot orders of the members in ascending order.
+func BootOrders(group *harvesterv1.VirtualMachineGroup) []int {
+	seen := make(map[int]bool, len(group.Spec.Members))
+	orders := make([]int, 0, len(group.Spec.Members))
+	for _, member := range group.Spec.Members {
+		if !seen[member.BootOrder] {
+			seen[member.BootOrder] = true
+			orders = append(orders, member.BootOrder)
+		}
+	}
+	sort.Ints(orders)
+	return orders
+}
+
+// StopStages splits the VMs in the form of <namespace>/<name> into the stages they are stopped in.
+// The members of a group are stopped in the reverse order of their boot order, the VMs which aren't
+// members of a group and the members with the highest boot order of each group are stopped first.
+func StopStages(groupCache ctlharvesterv1.VirtualMachineGroupCache, vms []string) ([][]string, error) {
+	var stages [][]string
+	for _, vm := range vms {
+		namespace, name, _ := strings.Cut(vm, "/")
+		group, member, err := FindMember(groupCache, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+
+		stage := 0
+		if group != nil {
+			orders := BootOrders(group)
+			stage = len(orders) - 1 - sort.SearchInts(orders, member.BootOrder)
+		}
+		for len(stages) <= stage {
+			stages = append(stages, nil)
+		}
+		stages[stage] = append(stages[stage], vm)
+	}
+
+	// skip the stages without VMs
+	result := make([][]string, 0, len(stages))
+	for _, stage := range stages {
+		if len(stage) > 0 {
+			result = append(result, stage)
+		}
+	}
+	return result, nil
+}
+
+// IsMemberReady checks the VMI is ready and the readiness gates of the member pass, it returns why
+// the member isn't ready otherwise. The TCPPort gates are checked with the prober.
+func IsMemberReady(vmi *kubevirtv1.VirtualMachineInstance, member *harvesterv1.VirtualMachineGroupMember, prober PortProber) (bool, string) {
+	if vmi == nil || vmi.DeletionTimestamp != nil || !vmi.IsRunning() {
+		return false, "the VM is not running"
+	}
+	if !hasCondition(vmi, kubevirtv1.VirtualMachineInstanceReady) {
+		return false, "the VM is not ready"
+	}
+
+	for _, gate := range member.ReadinessGates {
+		switch gate.Type {
+		case harvesterv1.VirtualMachineGroupReadinessGuestAgent:
+			if !hasCondition(vmi, kubevirtv1.VirtualMachineInstanceAgentConnected) {
+				return false, "the guest agent is not connected"
+			}
+		case harvesterv1.VirtualMachineGroupReadinessTCPPort:
+			if err := prober.ProbeTCPPort(vmi, gate.Port); err != nil {
+				return false, err.Error()
+			}
+		default:
+			// the webhook rejects unknown gates, a gate which can't be checked never passes
+			return false, fmt.Sprintf("the readiness gate %s is not supported", gate.Type)
+		}
+	}
+	return true, ""
+}
+
+// ReadySince returns when the VMI became ready.
+func ReadySince(vmi *kubevirtv1.VirtualMachineInstance) time.Time {
+	for _, cond := range vmi.Status.Conditions {
+		if cond.Type == kubevirtv1.VirtualMachineInstanceReady {
+			return cond.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
+
+func hasCondition(vmi *kubevirtv1.VirtualMachineInstance, conditionType kubevirtv1.VirtualMachineInstanceConditionType) bool {
+	for _, cond := range vmi.Status.Conditions {
+		if cond.Type == conditionType && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package vmgroup
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestStopStages(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&harvesterv1.VirtualMachineGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "default"},
+		Spec: harvesterv1.VirtualMachineGroupSpec{
+			Members: []harvesterv1.VirtualMachineGroupMember{
+				{Name: "db", BootOrder: 0},
+				{Name: "cache", BootOrder: 0},
+				{Name: "app", BootOrder: 5},
+				{Name: "web", BootOrder: 10},
+			},
+		},
+	})
+	groupCache := fakeclients.VirtualMachineGroupCache(clientset.HarvesterhciV1beta1().VirtualMachineGroups)
+
+	stages, err := StopStages(groupCache, []string{"default/db", "default/other", "default/app", "default/cache", "other/db"})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"default/other", "other/db"},
+		{"default/app"},
+		{"default/db", "default/cache"},
+	}, stages)
+
+	stages, err = StopStages(groupCache, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, stages)
+}
+
+func TestStartWithGroup(t *testing.T) {
+	member := &kubevirtv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	other := &kubevirtv1.VirtualMachine{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+	clientset := fake.NewSimpleClientset(member, other, &harvesterv1.VirtualMachineGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack", Namespace: "default"},
+		Spec: harvesterv1.VirtualMachineGroupSpec{
+			Members: []harvesterv1.VirtualMachineGroupMember{{Name: "db"}},
+		},
+	})
+	groupCache := fakeclients.VirtualMachineGroupCache(clientset.HarvesterhciV1beta1().VirtualMachineGroups)
+	vmClient := fakeclients.VirtualMachineClient(clientset.KubevirtV1().VirtualMachines)
+
+	started, err := StartWithGroup(groupCache, vmClient, member)
+	assert.NoError(t, err)
+	assert.True(t, started)
+	vm, err := vmClient.Get("default", "db", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "true", vm.Annotations[util.AnnotationVMGroupPendingStart])
+
+	started, err = StartWithGroup(groupCache, vmClient, other)
+	assert.NoError(t, err)
+	assert.False(t, started)
+	vm, err = vmClient.Get("default", "other", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, vm.Annotations)
+}
+
+type fakePortProber map[int32]bool
+
+func (p fakePortProber) ProbeTCPPort(_ *kubevirtv1.VirtualMachineInstance, port int32) error {
+	if !p[port] {
+		return fmt.Errorf("port %d is not open", port)
+	}
+	return nil
+}
+
+func TestIsMemberReady(t *testing.T) {
+	vmi := &kubevirtv1.VirtualMachineInstance{
+		Status: kubevirtv1.VirtualMachineInstanceStatus{
+			Phase: kubevirtv1.Running,
+			Conditions: []kubevirtv1.VirtualMachineInstanceCondition{
+				{Type: kubevirtv1.VirtualMachineInstanceReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		gates  []harvesterv1.VirtualMachineGroupReadinessGate
+		expect bool
+	}{
+		{
+			name:   "no readiness gates",
+			expect: true,
+		},
+		{
+			name:  "guest agent is not connected",
+			gates: []harvesterv1.VirtualMachineGroupReadinessGate{{Type: harvesterv1.VirtualMachineGroupReadinessGuestAgent}},
+		},
+		{
+			name:   "open port",
+			gates:  []harvesterv1.VirtualMachineGroupReadinessGate{{Type: harvesterv1.VirtualMachineGroupReadinessTCPPort, Port: 80}},
+			expect: true,
+		},
+		{
+			name:  "closed port",
+			gates: []harvesterv1.VirtualMachineGroupReadinessGate{{Type: harvesterv1.VirtualMachineGroupReadinessTCPPort, Port: 443}},
+		},
+		{
+			name:  "unknown readiness gate",
+			gates: []harvesterv1.VirtualMachineGroupReadinessGate{{Type: "TCP"}},
+		},
+	}
+
+	prober := fakePortProber{80: true}
+	for _, tc := range tests {
+		ready, _ := IsMemberReady(vmi, &harvesterv1.VirtualMachineGroupMember{ReadinessGates: tc.gates}, prober)
+		assert.Equal(t, tc.expect, ready, tc.name)
+	}
+
+	ready, _ := IsMemberReady(nil, &harvesterv1.VirtualMachineGroupMember{}, prober)
+	assert.False(t, ready)
+}
+
+func TestGuestPodNetworkIP(t *testing.T) {
+	newVMI := func(cidr string, binding kubevirtv1.InterfaceBindingMethod) *kubevirtv1.VirtualMachineInstance {
+		vmi := &kubevirtv1.VirtualMachineInstance{}
+		vmi.Spec.Networks = []kubevirtv1.Network{{
+			Name:          "default",
+			NetworkSource: kubevirtv1.NetworkSource{Pod: &kubevirtv1.PodNetwork{VMNetworkCIDR: cidr}},
+		}}
+		vmi.Spec.Domain.Devices.Interfaces = []kubevirtv1.Interface{{Name: "default", InterfaceBindingMethod: binding}}
+		return vmi
+	}
+	masquerade := kubevirtv1.InterfaceBindingMethod{Masquerade: &kubevirtv1.InterfaceMasquerade{}}
+
+	ip, err := GuestPodNetworkIP(newVMI("", masquerade))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.2.2", ip.String())
+
+	ip, err = GuestPodNetworkIP(newVMI("10.11.12.0/24", masquerade))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.11.12.2", ip.String())
+
+	_, err = GuestPodNetworkIP(newVMI("", kubevirtv1.InterfaceBindingMethod{Bridge: &kubevirtv1.InterfaceBridge{}}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package vmtemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+// Change is a field that differs between two template versions, From is empty when the field is
+// added and To is empty when the field is removed.
+type Change struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// DiffVersions returns the changes of the spec of a template version against another version.
+func DiffVersions(from, to *harvesterv1.VirtualMachineTemplateVersion) ([]Change, error) {
+	fromSpec, err := diffableSpec(from)
+	if err != nil {
+		return nil, err
+	}
+	toSpec, err := diffableSpec(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	diff("spec", fromSpec, toSpec, &changes)
+	return changes, nil
+}
+
+// diffableSpec converts the spec to a generic object, the volume claim templates annotation is
+// decoded so the changes of the disks are reported by field rather than as a whole JSON string.
+func diffableSpec(tv *harvesterv1.VirtualMachineTemplateVersion) (map[string]interface{}, error) {
+	spec := tv.Spec.DeepCopy()
+	spec.TemplateID = ""
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert template version %s/%s: %w", tv.Namespace, tv.Name, err)
+	}
+
+	if data := spec.VM.ObjectMeta.Annotations[util.AnnotationVolumeClaimTemplates]; data != "" {
+		var volumeClaimTemplates []interface{}
+		if err := json.Unmarshal([]byte(data), &volumeClaimTemplates); err != nil {
+			return nil, fmt.Errorf("failed to decode volume claim templates of template version %s/%s: %w", tv.Namespace, tv.Name, err)
+		}
+		if err := unstructured.SetNestedField(obj, volumeClaimTemplates, "vm", "metadata", "annotations", util.AnnotationVolumeClaimTemplates); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func diff(path string, from, to interface{}, changes *[]Change) {
+	switch fromValue := from.(type) {
+	case map[string]interface{}:
+		if toValue, ok := to.(map[string]interface{}); ok {
+			diffMap(path, fromValue, toValue, changes)
+			return
+		}
+	case []interface{}:
+		if toValue, ok := to.([]interface{}); ok {
+			diffSlice(path, fromValue, toValue, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, Change{Path: path, From: from, To: to})
+	}
+}
+
+func diffMap(path string, from, to map[string]interface{}, changes *[]Change) {
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		diff(fmt.Sprintf("%s.%s", path, key), from[key], to[key], changes)
+	}
+}
+
+func diffSlice(path string, from, to []interface{}, changes *[]Change) {
+	for i := 0; i < len(from) || i < len(to); i++ {
+		var fromItem, toItem interface{}
+		if i < len(from) {
+			fromItem = from[i]
+		}
+		if i < len(to) {
+			toItem = to[i]
+		}
+		diff(fmt.Sprintf("%s[%d]", path, i), fromItem, toItem, changes)
+	}
+}
@@ -0,0 +1,48 @@
+package vmtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+func TestDiffVersions(t *testing.T) {
+	vm := newTestVM()
+	from := &harvesterv1.VirtualMachineTemplateVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "template-a", Namespace: "default"},
+		Spec: harvesterv1.VirtualMachineTemplateVersionSpec{
+			TemplateID:  "default/template",
+			Description: "first",
+			VM: harvesterv1.VirtualMachineSourceSpec{
+				ObjectMeta: vm.ObjectMeta,
+				Spec:       vm.Spec,
+			},
+		},
+	}
+
+	changes, err := DiffVersions(from, from.DeepCopy())
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	to := from.DeepCopy()
+	to.Name = "template-b"
+	to.Spec.Description = "second"
+	to.Spec.VM.Spec.Template.Spec.Domain.CPU.Cores = 4
+	to.Spec.VM.ObjectMeta.Annotations[util.AnnotationVolumeClaimTemplates] = `[{"metadata":{"name":"tv-disk-0"},"spec":{"resources":{"requests":{"storage":"20Gi"}}}}]`
+	to.Spec.Parameters = []harvesterv1.VirtualMachineTemplateParameter{
+		{Name: "cpu", Type: harvesterv1.TemplateParameterCPU},
+	}
+
+	changes, err = DiffVersions(from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "spec.description", From: "first", To: "second"},
+		{Path: "spec.parameters", To: []interface{}{map[string]interface{}{"name": "cpu", "type": "cpu"}}},
+		{Path: "spec.vm.metadata.annotations.harvesterhci.io/volumeClaimTemplates[0].spec.resources.requests.storage", From: "10Gi", To: "20Gi"},
+		{Path: "spec.vm.spec.template.spec.domain.cpu.cores", From: uint64(2), To: uint64(4)},
+	}, changes)
+}
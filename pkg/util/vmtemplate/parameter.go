@@ -0,0 +1,371 @@
+package vmtemplate
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+var parameterReference = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// ValidateParameters checks the parameters declared by a template version are well-formed and
+// their targets exist in the VM of the version.
+func ValidateParameters(spec *harvesterv1.VirtualMachineTemplateVersionSpec) error {
+	names := map[string]bool{}
+	for _, p := range spec.Parameters {
+		if p.Name == "" {
+			return fmt.Errorf("parameter name is required")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("parameter %s is declared more than once", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := validateBounds(p); err != nil {
+			return err
+		}
+		for _, value := range p.Enum {
+			if err := checkValue(p, value); err != nil {
+				return fmt.Errorf("invalid enum value of parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Default != "" {
+			if err := checkValue(p, p.Default); err != nil {
+				return fmt.Errorf("invalid default value of parameter %s: %w", p.Name, err)
+			}
+		}
+		if err := validateTarget(spec.VM.Spec.Template, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBounds(p harvesterv1.VirtualMachineTemplateParameter) error {
+	if p.Min == "" && p.Max == "" {
+		return nil
+	}
+
+	switch p.Type {
+	case harvesterv1.TemplateParameterCPU:
+		minimum, maximum := 0, 0
+		var err error
+		if p.Min != "" {
+			if minimum, err = strconv.Atoi(p.Min); err != nil {
+				return fmt.Errorf("invalid min of parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Max != "" {
+			if maximum, err = strconv.Atoi(p.Max); err != nil {
+				return fmt.Errorf("invalid max of parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Min != "" && p.Max != "" && minimum > maximum {
+			return fmt.Errorf("min of parameter %s is greater than max", p.Name)
+		}
+	case harvesterv1.TemplateParameterMemory, harvesterv1.TemplateParameterDiskSize:
+		var minimum, maximum resource.Quantity
+		var err error
+		if p.Min != "" {
+			if minimum, err = resource.ParseQuantity(p.Min); err != nil {
+				return fmt.Errorf("invalid min of parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Max != "" {
+			if maximum, err = resource.ParseQuantity(p.Max); err != nil {
+				return fmt.Errorf("invalid max of parameter %s: %w", p.Name, err)
+			}
+		}
+		if p.Min != "" && p.Max != "" && minimum.Cmp(maximum) > 0 {
+			return fmt.Errorf("min of parameter %s is greater than max", p.Name)
+		}
+	default:
+		return fmt.Errorf("min and max are not supported by %s parameter %s", p.Type, p.Name)
+	}
+	return nil
+}
+
+func validateTarget(template *kubevirtv1.VirtualMachineInstanceTemplateSpec, p harvesterv1.VirtualMachineTemplateParameter) error {
+	switch p.Type {
+	case harvesterv1.TemplateParameterDiskSize, harvesterv1.TemplateParameterNetwork:
+		if p.Target == "" {
+			return fmt.Errorf("target of %s parameter %s is required", p.Type, p.Name)
+		}
+	case harvesterv1.TemplateParameterCPU, harvesterv1.TemplateParameterMemory:
+	default:
+		return nil
+	}
+
+	if template == nil {
+		return fmt.Errorf("parameter %s requires the VM template", p.Name)
+	}
+
+	switch p.Type {
+	case harvesterv1.TemplateParameterDiskSize:
+		for _, volume := range template.Spec.Volumes {
+			if volume.Name == p.Target && volume.PersistentVolumeClaim != nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("target %s of parameter %s isn't a PVC volume of the VM", p.Target, p.Name)
+	case harvesterv1.TemplateParameterNetwork:
+		for _, network := range template.Spec.Networks {
+			if network.Name == p.Target && network.Multus != nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("target %s of parameter %s isn't a multus network of the VM", p.Target, p.Name)
+	}
+	return nil
+}
+
+// checkValue checks a value of the parameter satisfies its type and constraints.
+func checkValue(p harvesterv1.VirtualMachineTemplateParameter, value string) error {
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, value) {
+		return fmt.Errorf("value %s isn't one of %v", value, p.Enum)
+	}
+
+	switch p.Type {
+	case harvesterv1.TemplateParameterCPU:
+		cpu, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || cpu == 0 {
+			return fmt.Errorf("value %s isn't a positive 32-bit integer", value)
+		}
+		if p.Min != "" {
+			if minimum, err := strconv.ParseUint(p.Min, 10, 32); err == nil && cpu < minimum {
+				return fmt.Errorf("value %s is less than %s", value, p.Min)
+			}
+		}
+		if p.Max != "" {
+			if maximum, err := strconv.ParseUint(p.Max, 10, 32); err == nil && cpu > maximum {
+				return fmt.Errorf("value %s is greater than %s", value, p.Max)
+			}
+		}
+	case harvesterv1.TemplateParameterMemory, harvesterv1.TemplateParameterDiskSize:
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil || quantity.Sign() <= 0 {
+			return fmt.Errorf("value %s isn't a positive quantity", value)
+		}
+		if p.Min != "" {
+			if minimum, err := resource.ParseQuantity(p.Min); err == nil && quantity.Cmp(minimum) < 0 {
+				return fmt.Errorf("value %s is less than %s", value, p.Min)
+			}
+		}
+		if p.Max != "" {
+			if maximum, err := resource.ParseQuantity(p.Max); err == nil && quantity.Cmp(maximum) > 0 {
+				return fmt.Errorf("value %s is greater than %s", value, p.Max)
+			}
+		}
+	case harvesterv1.TemplateParameterNetwork:
+		if namespace, name := ref.Parse(value); namespace == "" || name == "" {
+			return fmt.Errorf("value %s isn't a network in the form of <namespace>/<name>", value)
+		}
+	case harvesterv1.TemplateParameterCloudInit:
+		// the value is substituted as is, a line break would let it inject cloud-init directives
+		if strings.ContainsFunc(value, unicode.IsControl) {
+			return fmt.Errorf("value of a cloudInit parameter must be a single line without control characters")
+		}
+	default:
+		return fmt.Errorf("unknown parameter type %s", p.Type)
+	}
+	return nil
+}
+
+// ResolveParameters merges the input values with the defaults of the parameters and checks them
+// against the constraints. Optional parameters without a value are left out of the result.
+func ResolveParameters(params []harvesterv1.VirtualMachineTemplateParameter, input map[string]string) (map[string]string, error) {
+	declared := map[string]bool{}
+	for _, p := range params {
+		declared[p.Name] = true
+	}
+	for name := range input {
+		if !declared[name] {
+			return nil, fmt.Errorf("parameter %s isn't declared by the template version", name)
+		}
+	}
+
+	values := map[string]string{}
+	for _, p := range params {
+		value, ok := input[p.Name]
+		if !ok || value == "" {
+			value = p.Default
+		}
+		if value == "" {
+			if p.Required {
+				return nil, fmt.Errorf("parameter %s is required", p.Name)
+			}
+			continue
+		}
+		if err := checkValue(p, value); err != nil {
+			return nil, fmt.Errorf("invalid parameter %s: %w", p.Name, err)
+		}
+		values[p.Name] = value
+	}
+	return values, nil
+}
+
+// ApplyParameters applies the resolved values of the cpu, memory, diskSize and network parameters
+// to the VM. The cloudInit parameters are applied with RenderCloudInit.
+func ApplyParameters(vm *kubevirtv1.VirtualMachine, params []harvesterv1.VirtualMachineTemplateParameter, values map[string]string) error {
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok || p.Type == harvesterv1.TemplateParameterCloudInit {
+			continue
+		}
+		if vm.Spec.Template == nil {
+			return fmt.Errorf("parameter %s requires the VM template", p.Name)
+		}
+
+		var err error
+		switch p.Type {
+		case harvesterv1.TemplateParameterCPU:
+			err = applyCPU(&vm.Spec.Template.Spec, value)
+		case harvesterv1.TemplateParameterMemory:
+			err = applyMemory(&vm.Spec.Template.Spec, value)
+		case harvesterv1.TemplateParameterDiskSize:
+			err = applyDiskSize(vm, p.Target, value)
+		case harvesterv1.TemplateParameterNetwork:
+			err = applyNetwork(&vm.Spec.Template.Spec, p.Target, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply parameter %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func applyCPU(spec *kubevirtv1.VirtualMachineInstanceSpec, value string) error {
+	cpu, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return err
+	}
+	if cpu < 1 {
+		return fmt.Errorf("cpu must be at least 1")
+	}
+
+	if spec.Domain.CPU == nil {
+		spec.Domain.CPU = &kubevirtv1.CPU{}
+	}
+	// sockets are the unit of the CPU hotplug, otherwise the VM gets the cores of one socket
+	if spec.Domain.CPU.MaxSockets > 0 {
+		// #nosec G115
+		spec.Domain.CPU.Sockets = uint32(cpu)
+		spec.Domain.CPU.Cores = 1
+	} else {
+		spec.Domain.CPU.Sockets = 1
+		// #nosec G115
+		spec.Domain.CPU.Cores = uint32(cpu)
+	}
+	spec.Domain.CPU.Threads = 1
+
+	if spec.Domain.Resources.Limits == nil {
+		spec.Domain.Resources.Limits = corev1.ResourceList{}
+	}
+	spec.Domain.Resources.Limits[corev1.ResourceCPU] = *resource.NewQuantity(int64(cpu), resource.DecimalSI) // #nosec G115
+	return nil
+}
+
+func applyMemory(spec *kubevirtv1.VirtualMachineInstanceSpec, value string) error {
+	memory, err := resource.ParseQuantity(value)
+	if err != nil {
+		return err
+	}
+
+	if spec.Domain.Resources.Limits == nil {
+		spec.Domain.Resources.Limits = corev1.ResourceList{}
+	}
+	spec.Domain.Resources.Limits[corev1.ResourceMemory] = memory
+	// the requests and the guest memory are derived from the limits by the VM mutator
+	if spec.Domain.Memory != nil && spec.Domain.Memory.Guest != nil {
+		spec.Domain.Memory.Guest = &memory
+	}
+	return nil
+}
+
+func applyDiskSize(vm *kubevirtv1.VirtualMachine, target, value string) error {
+	size, err := resource.ParseQuantity(value)
+	if err != nil {
+		return err
+	}
+
+	claimName := ""
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		if volume.Name == target && volume.PersistentVolumeClaim != nil {
+			claimName = volume.PersistentVolumeClaim.ClaimName
+		}
+	}
+	if claimName == "" {
+		return fmt.Errorf("volume %s isn't a PVC volume of the VM", target)
+	}
+
+	entries, err := util.UnmarshalVolumeClaimTemplates(vm.Annotations[util.AnnotationVolumeClaimTemplates])
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range entries {
+		if entries[i].Name != claimName {
+			continue
+		}
+		if entries[i].Spec.Resources.Requests == nil {
+			entries[i].Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		entries[i].Spec.Resources.Requests[corev1.ResourceStorage] = size
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("volume claim template %s of volume %s isn't found", claimName, target)
+	}
+
+	data, err := util.MarshalVolumeClaimTemplates(entries)
+	if err != nil {
+		return err
+	}
+	vm.Annotations[util.AnnotationVolumeClaimTemplates] = data
+	return nil
+}
+
+func applyNetwork(spec *kubevirtv1.VirtualMachineInstanceSpec, target, value string) error {
+	for i, network := range spec.Networks {
+		if network.Name == target && network.Multus != nil {
+			spec.Networks[i].Multus.NetworkName = value
+			return nil
+		}
+	}
+	return fmt.Errorf("network %s isn't a multus network of the VM", target)
+}
+
+// RenderCloudInit replaces the `${name}` references of the cloudInit parameters in the cloud-init
+// data. The references of the parameters without a value are replaced with an empty string and the
+// other references, e.g. shell variables of the scripts, are kept. The values are expected to be
+// checked by ResolveParameters, which rejects multi-line values.
+func RenderCloudInit(data string, params []harvesterv1.VirtualMachineTemplateParameter, values map[string]string) string {
+	cloudInit := map[string]bool{}
+	for _, p := range params {
+		if p.Type == harvesterv1.TemplateParameterCloudInit {
+			cloudInit[p.Name] = true
+		}
+	}
+	if len(cloudInit) == 0 {
+		return data
+	}
+
+	return parameterReference.ReplaceAllStringFunc(data, func(reference string) string {
+		name := parameterReference.FindStringSubmatch(reference)[1]
+		if !cloudInit[name] {
+			return reference
+		}
+		return values[name]
+	})
+}
@@ -0,0 +1,232 @@
+package vmtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+)
+
+const testVolumeClaimTemplates = `[{"metadata":{"name":"tv-disk-0"},"spec":{"resources":{"requests":{"storage":"10Gi"}}}}]`
+
+func newTestVM() *kubevirtv1.VirtualMachine {
+	return &kubevirtv1.VirtualMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				util.AnnotationVolumeClaimTemplates: testVolumeClaimTemplates,
+			},
+		},
+		Spec: kubevirtv1.VirtualMachineSpec{
+			Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+				Spec: kubevirtv1.VirtualMachineInstanceSpec{
+					Domain: kubevirtv1.DomainSpec{
+						CPU: &kubevirtv1.CPU{Cores: 2, Sockets: 1, Threads: 1},
+						Resources: kubevirtv1.ResourceRequirements{
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("2"),
+								corev1.ResourceMemory: resource.MustParse("4Gi"),
+							},
+						},
+					},
+					Networks: []kubevirtv1.Network{
+						{
+							Name: "nic-1",
+							NetworkSource: kubevirtv1.NetworkSource{
+								Multus: &kubevirtv1.MultusNetwork{NetworkName: "default/vlan1"},
+							},
+						},
+					},
+					Volumes: []kubevirtv1.Volume{
+						{
+							Name: "disk-0",
+							VolumeSource: kubevirtv1.VolumeSource{
+								PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+									PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "tv-disk-0"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	vm := newTestVM()
+	newSpec := func(params ...harvesterv1.VirtualMachineTemplateParameter) *harvesterv1.VirtualMachineTemplateVersionSpec {
+		return &harvesterv1.VirtualMachineTemplateVersionSpec{
+			VM:         harvesterv1.VirtualMachineSourceSpec{Spec: vm.Spec},
+			Parameters: params,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		spec      *harvesterv1.VirtualMachineTemplateVersionSpec
+		expectErr bool
+	}{
+		{
+			name: "valid parameters",
+			spec: newSpec(
+				harvesterv1.VirtualMachineTemplateParameter{Name: "cpu", Type: harvesterv1.TemplateParameterCPU, Default: "2", Min: "1", Max: "8"},
+				harvesterv1.VirtualMachineTemplateParameter{Name: "memory", Type: harvesterv1.TemplateParameterMemory, Enum: []string{"2Gi", "4Gi"}},
+				harvesterv1.VirtualMachineTemplateParameter{Name: "disk", Type: harvesterv1.TemplateParameterDiskSize, Target: "disk-0", Min: "10Gi"},
+				harvesterv1.VirtualMachineTemplateParameter{Name: "net", Type: harvesterv1.TemplateParameterNetwork, Target: "nic-1"},
+				harvesterv1.VirtualMachineTemplateParameter{Name: "password", Type: harvesterv1.TemplateParameterCloudInit, Required: true},
+			),
+		},
+		{
+			name: "duplicated names",
+			spec: newSpec(
+				harvesterv1.VirtualMachineTemplateParameter{Name: "cpu", Type: harvesterv1.TemplateParameterCPU},
+				harvesterv1.VirtualMachineTemplateParameter{Name: "cpu", Type: harvesterv1.TemplateParameterCPU},
+			),
+			expectErr: true,
+		},
+		{
+			name:      "min greater than max",
+			spec:      newSpec(harvesterv1.VirtualMachineTemplateParameter{Name: "memory", Type: harvesterv1.TemplateParameterMemory, Min: "8Gi", Max: "4Gi"}),
+			expectErr: true,
+		},
+		{
+			name:      "default out of bounds",
+			spec:      newSpec(harvesterv1.VirtualMachineTemplateParameter{Name: "cpu", Type: harvesterv1.TemplateParameterCPU, Default: "16", Max: "8"}),
+			expectErr: true,
+		},
+		{
+			name:      "bounds of a cloudInit parameter",
+			spec:      newSpec(harvesterv1.VirtualMachineTemplateParameter{Name: "user", Type: harvesterv1.TemplateParameterCloudInit, Min: "1"}),
+			expectErr: true,
+		},
+		{
+			name:      "unknown disk",
+			spec:      newSpec(harvesterv1.VirtualMachineTemplateParameter{Name: "disk", Type: harvesterv1.TemplateParameterDiskSize, Target: "disk-1"}),
+			expectErr: true,
+		},
+		{
+			name:      "network without target",
+			spec:      newSpec(harvesterv1.VirtualMachineTemplateParameter{Name: "net", Type: harvesterv1.TemplateParameterNetwork}),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		err := ValidateParameters(tc.spec)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
+
+func TestResolveParameters(t *testing.T) {
+	params := []harvesterv1.VirtualMachineTemplateParameter{
+		{Name: "cpu", Type: harvesterv1.TemplateParameterCPU, Default: "2", Max: "8"},
+		{Name: "password", Type: harvesterv1.TemplateParameterCloudInit, Required: true},
+		{Name: "user", Type: harvesterv1.TemplateParameterCloudInit},
+	}
+
+	tests := []struct {
+		name      string
+		input     map[string]string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{
+			name:     "defaults are applied",
+			input:    map[string]string{"password": "secret"},
+			expected: map[string]string{"cpu": "2", "password": "secret"},
+		},
+		{
+			name:     "input overrides the default",
+			input:    map[string]string{"cpu": "4", "password": "secret", "user": "admin"},
+			expected: map[string]string{"cpu": "4", "password": "secret", "user": "admin"},
+		},
+		{
+			name:      "required parameter is missing",
+			input:     map[string]string{"cpu": "4"},
+			expectErr: true,
+		},
+		{
+			name:      "value out of bounds",
+			input:     map[string]string{"cpu": "16", "password": "secret"},
+			expectErr: true,
+		},
+		{
+			name:      "undeclared parameter",
+			input:     map[string]string{"password": "secret", "disk": "10Gi"},
+			expectErr: true,
+		},
+		{
+			name:      "cpu overflows 32 bits",
+			input:     map[string]string{"cpu": "4294967297", "password": "secret"},
+			expectErr: true,
+		},
+		{
+			name:      "multi-line cloudInit value",
+			input:     map[string]string{"password": "secret\nruncmd:\n  - reboot"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		values, err := ResolveParameters(params, tc.input)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+			continue
+		}
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.expected, values, tc.name)
+	}
+}
+
+func TestApplyParameters(t *testing.T) {
+	vm := newTestVM()
+	params := []harvesterv1.VirtualMachineTemplateParameter{
+		{Name: "cpu", Type: harvesterv1.TemplateParameterCPU},
+		{Name: "memory", Type: harvesterv1.TemplateParameterMemory},
+		{Name: "disk", Type: harvesterv1.TemplateParameterDiskSize, Target: "disk-0"},
+		{Name: "net", Type: harvesterv1.TemplateParameterNetwork, Target: "nic-1"},
+		{Name: "unset", Type: harvesterv1.TemplateParameterCPU},
+	}
+	values := map[string]string{
+		"cpu":    "4",
+		"memory": "8Gi",
+		"disk":   "40Gi",
+		"net":    "tenant/vlan100",
+	}
+
+	assert.NoError(t, ApplyParameters(vm, params, values))
+
+	domain := vm.Spec.Template.Spec.Domain
+	assert.Equal(t, uint32(4), domain.CPU.Cores)
+	assert.Equal(t, uint32(1), domain.CPU.Sockets)
+	assert.Equal(t, "4", domain.Resources.Limits.Cpu().String())
+	assert.Equal(t, resource.MustParse("8Gi"), domain.Resources.Limits[corev1.ResourceMemory])
+	assert.Equal(t, "tenant/vlan100", vm.Spec.Template.Spec.Networks[0].Multus.NetworkName)
+
+	entries, err := util.UnmarshalVolumeClaimTemplates(vm.Annotations[util.AnnotationVolumeClaimTemplates])
+	assert.NoError(t, err)
+	assert.Equal(t, resource.MustParse("40Gi"), entries[0].Spec.Resources.Requests[corev1.ResourceStorage])
+}
+
+func TestRenderCloudInit(t *testing.T) {
+	params := []harvesterv1.VirtualMachineTemplateParameter{
+		{Name: "password", Type: harvesterv1.TemplateParameterCloudInit},
+		{Name: "user", Type: harvesterv1.TemplateParameterCloudInit},
+		{Name: "cpu", Type: harvesterv1.TemplateParameterCPU},
+	}
+	values := map[string]string{"password": "secret", "cpu": "4"}
+
+	data := "password: ${password}\nuser: ${user}\ncpu: ${cpu}\nruncmd:\n  - echo ${HOME}\n"
+	expected := "password: secret\nuser: \ncpu: ${cpu}\nruncmd:\n  - echo ${HOME}\n"
+	assert.Equal(t, expected, RenderCloudInit(data, params, values))
+}
@@ -22,6 +22,9 @@ import (
 
 const (
 	ConditionReady condition.Cond = "Ready"
+
+	// ReasonError is the reason of the Ready condition when the operation failed
+	ReasonError = "Error"
 )
 
 var (
@@ -92,6 +95,7 @@ type BackupOperator interface {
 	GetSnapshotClassInfo(vrb *harvesterv1.VolumeRemoteBackup) (*settings.CSIDriverInfo, error)
 	GetCSIProvider(vrb *harvesterv1.VolumeRemoteBackup) string
 	GetSourceSpec(vrb *harvesterv1.VolumeRemoteBackup) corev1.PersistentVolumeClaimSpec
+	GetError(vrb *harvesterv1.VolumeRemoteBackup) string
 
 	// Update
 	Update(oldVrb, newVrb *harvesterv1.VolumeRemoteBackup) (*harvesterv1.VolumeRemoteBackup, error)
@@ -217,6 +221,20 @@ func (bo *backupOperator) GetSourceSpec(vrb *harvesterv1.VolumeRemoteBackup) cor
 	return vrb.Status.SourceSpec
 }
 
+// GetError returns the message of the error the backup failed with, it's empty if the backup
+// succeeded or is in progress.
+func (bo *backupOperator) GetError(vrb *harvesterv1.VolumeRemoteBackup) string {
+	if vrb.Status.Success {
+		return ""
+	}
+	for _, c := range vrb.Status.Conditions {
+		if c.Type == ConditionReady && c.Status == corev1.ConditionFalse && c.Reason == ReasonError {
+			return c.Message
+		}
+	}
+	return ""
+}
+
 func (bo *backupOperator) SetHandle(vrb *harvesterv1.VolumeRemoteBackup, handle string) (*harvesterv1.VolumeRemoteBackup, error) {
 	return bo.apply(vrb, bo.withHandle(handle))
 }
@@ -252,7 +270,7 @@ func (bo *backupOperator) SetProcessing(vrb *harvesterv1.VolumeRemoteBackup) (*h
 }
 
 func (bo *backupOperator) SetError(vrb *harvesterv1.VolumeRemoteBackup, err error) (*harvesterv1.VolumeRemoteBackup, error) {
-	return bo.setCondition(vrb, newReadyCondition(corev1.ConditionFalse, "", err.Error()))
+	return bo.setCondition(vrb, newReadyCondition(corev1.ConditionFalse, ReasonError, err.Error()))
 }
 
 func (bo *backupOperator) setCondition(vrb *harvesterv1.VolumeRemoteBackup, c harvesterv1.Condition) (*harvesterv1.VolumeRemoteBackup, error) {
@@ -317,6 +335,10 @@ type RestoreOperator interface {
 	GetUID(vrr *harvesterv1.VolumeRemoteRestore) types.UID
 	GetType(vrr *harvesterv1.VolumeRemoteRestore) harvesterv1.VolumeRemoteRestoreType
 	GetFrom(vrr *harvesterv1.VolumeRemoteRestore) string
+	GetTargetNamespace(vrr *harvesterv1.VolumeRemoteRestore) string
+	GetTargetPVCName(vrr *harvesterv1.VolumeRemoteRestore) string
+	IsCrossNamespace(vrr *harvesterv1.VolumeRemoteRestore) bool
+	GetTargetPVCSpec(vrr *harvesterv1.VolumeRemoteRestore, vrb *harvesterv1.VolumeRemoteBackup) corev1.PersistentVolumeClaimSpec
 	GetSuccess(vrr *harvesterv1.VolumeRemoteRestore) bool
 	GetSnapshotClassInfo(vrr *harvesterv1.VolumeRemoteRestore) (*settings.CSIDriverInfo, error)
 
@@ -400,8 +422,54 @@ func (ro *restoreOperator) GetFrom(vrr *harvesterv1.VolumeRemoteRestore) string
 	return vrr.Spec.From
 }
 
+func (ro *restoreOperator) GetTargetNamespace(vrr *harvesterv1.VolumeRemoteRestore) string {
+	if vrr.Spec.TargetNamespace != "" {
+		return vrr.Spec.TargetNamespace
+	}
+	return vrr.Namespace
+}
+
+func (ro *restoreOperator) GetTargetPVCName(vrr *harvesterv1.VolumeRemoteRestore) string {
+	if vrr.Spec.TargetPVCName != "" {
+		return vrr.Spec.TargetPVCName
+	}
+	return vrr.Name
+}
+
+// IsCrossNamespace checks if the PVC is restored into another namespace than the one of the restore.
+func (ro *restoreOperator) IsCrossNamespace(vrr *harvesterv1.VolumeRemoteRestore) bool {
+	return ro.GetTargetNamespace(vrr) != vrr.Namespace
+}
+
+// GetTargetPVCSpec returns the spec of the restored PVC, the recorded spec of the source PVC with
+// the storage class and size of the restore.
+func (ro *restoreOperator) GetTargetPVCSpec(vrr *harvesterv1.VolumeRemoteRestore, vrb *harvesterv1.VolumeRemoteBackup) corev1.PersistentVolumeClaimSpec {
+	sourceSpec := ro.bo.GetSourceSpec(vrb)
+	spec := corev1.PersistentVolumeClaimSpec{
+		AccessModes:      sourceSpec.AccessModes,
+		Resources:        *sourceSpec.Resources.DeepCopy(),
+		StorageClassName: sourceSpec.StorageClassName,
+		VolumeMode:       sourceSpec.VolumeMode,
+	}
+
+	if vrr.Spec.StorageClassName != "" {
+		scName := vrr.Spec.StorageClassName
+		spec.StorageClassName = &scName
+	}
+	if vrr.Spec.Size != nil {
+		if spec.Resources.Requests == nil {
+			spec.Resources.Requests = corev1.ResourceList{}
+		}
+		spec.Resources.Requests[corev1.ResourceStorage] = *vrr.Spec.Size
+	}
+	return spec
+}
+
 func (ro *restoreOperator) GetSnapshotClassInfo(vrr *harvesterv1.VolumeRemoteRestore) (*settings.CSIDriverInfo, error) {
 	vrbNamespace, vrbName := ref.Parse(ro.GetFrom(vrr))
+	if vrbNamespace == "" {
+		vrbNamespace = ro.GetNamespace(vrr)
+	}
 	vrb, err := ro.vrbCache.Get(vrbNamespace, vrbName)
 	if err != nil {
 		return nil, err
@@ -437,7 +505,7 @@ func (ro *restoreOperator) SetProcessing(vrr *harvesterv1.VolumeRemoteRestore) (
 }
 
 func (ro *restoreOperator) SetError(vrr *harvesterv1.VolumeRemoteRestore, err error) (*harvesterv1.VolumeRemoteRestore, error) {
-	return ro.setCondition(vrr, newReadyCondition(corev1.ConditionFalse, "", err.Error()))
+	return ro.setCondition(vrr, newReadyCondition(corev1.ConditionFalse, ReasonError, err.Error()))
 }
 
 func (ro *restoreOperator) setCondition(vrr *harvesterv1.VolumeRemoteRestore, c harvesterv1.Condition) (*harvesterv1.VolumeRemoteRestore, error) {
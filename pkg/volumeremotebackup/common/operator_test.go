@@ -0,0 +1,62 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+)
+
+func TestRestoreTarget(t *testing.T) {
+	ro := NewRestoreOperator(nil, nil, nil, nil, nil, NewBackupOperator(nil, nil, nil, nil))
+	vrb := &harvesterv1.VolumeRemoteBackup{
+		Status: harvesterv1.VolumeRemoteBackupStatus{
+			SourceSpec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				StorageClassName: ptr.To("harvester-longhorn"),
+				VolumeMode:       ptr.To(corev1.PersistentVolumeBlock),
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("10Gi"),
+					},
+				},
+				VolumeName: "pvc-source",
+			},
+		},
+	}
+
+	vrr := &harvesterv1.VolumeRemoteRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+	}
+	assert.Equal(t, "default", ro.GetTargetNamespace(vrr))
+	assert.Equal(t, "restore", ro.GetTargetPVCName(vrr))
+	assert.False(t, ro.IsCrossNamespace(vrr))
+
+	spec := ro.GetTargetPVCSpec(vrr, vrb)
+	assert.Equal(t, "harvester-longhorn", *spec.StorageClassName)
+	assert.Equal(t, resource.MustParse("10Gi"), spec.Resources.Requests[corev1.ResourceStorage])
+	assert.Empty(t, spec.VolumeName)
+
+	size := resource.MustParse("20Gi")
+	vrr.Spec = harvesterv1.VolumeRemoteRestoreSpec{
+		TargetNamespace:  "tenant",
+		TargetPVCName:    "data",
+		StorageClassName: "fast",
+		Size:             &size,
+	}
+	assert.Equal(t, "tenant", ro.GetTargetNamespace(vrr))
+	assert.Equal(t, "data", ro.GetTargetPVCName(vrr))
+	assert.True(t, ro.IsCrossNamespace(vrr))
+
+	spec = ro.GetTargetPVCSpec(vrr, vrb)
+	assert.Equal(t, "fast", *spec.StorageClassName)
+	assert.Equal(t, size, spec.Resources.Requests[corev1.ResourceStorage])
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}, spec.AccessModes)
+	// the recorded spec of the backup isn't changed
+	assert.Equal(t, resource.MustParse("10Gi"), vrb.Status.SourceSpec.Resources.Requests[corev1.ResourceStorage])
+}
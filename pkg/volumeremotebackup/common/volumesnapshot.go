@@ -1,4 +1,4 @@
-package longhorn
+package common
 
 import (
 	"fmt"
@@ -6,8 +6,8 @@ import (
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 )
 
-// checkVolumeSnapshotError returns an error when a VolumeSnapshot reports one.
-func checkVolumeSnapshotError(vs *snapshotv1.VolumeSnapshot) error {
+// CheckVolumeSnapshotError returns an error when a VolumeSnapshot reports one.
+func CheckVolumeSnapshotError(vs *snapshotv1.VolumeSnapshot) error {
 	if vs.Status == nil || vs.Status.Error == nil {
 		return nil
 	}
@@ -0,0 +1,199 @@
+package csi
+
+import (
+	"fmt"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/driver"
+)
+
+// CSIBackupOperation backs up a volume of any CSI driver with a VolumeSnapshot of the
+// `backupVolumeSnapshotClassName` configured for the driver in the csi-driver-config setting.
+// Where the snapshot is kept is up to the driver and its VolumeSnapshotClass.
+type CSIBackupOperation struct {
+	bo           common.BackupOperator
+	vsCache      ctlsnapshotv1.VolumeSnapshotCache
+	vsClient     ctlsnapshotv1.VolumeSnapshotClient
+	vsClassCache ctlsnapshotv1.VolumeSnapshotClassCache
+	vscCache     ctlsnapshotv1.VolumeSnapshotContentCache
+	pvcCache     ctlcorev1.PersistentVolumeClaimCache
+	scCache      ctlstoragev1.StorageClassCache
+}
+
+func GetCSIBackupOperation(
+	bo common.BackupOperator,
+	vsCache ctlsnapshotv1.VolumeSnapshotCache,
+	vsClient ctlsnapshotv1.VolumeSnapshotClient,
+	vsClassCache ctlsnapshotv1.VolumeSnapshotClassCache,
+	vscCache ctlsnapshotv1.VolumeSnapshotContentCache,
+	pvcCache ctlcorev1.PersistentVolumeClaimCache,
+	scCache ctlstoragev1.StorageClassCache,
+) driver.BackupOperation {
+	return &CSIBackupOperation{
+		bo:           bo,
+		vsCache:      vsCache,
+		vsClient:     vsClient,
+		vsClassCache: vsClassCache,
+		vscCache:     vscCache,
+		pvcCache:     pvcCache,
+		scCache:      scCache,
+	}
+}
+
+// GetBackupVolumeSnapshotClass returns the VolumeSnapshotClass the source PVC of the
+// `VolumeRemoteBackup` is backed up with.
+func GetBackupVolumeSnapshotClass(
+	bo common.BackupOperator,
+	vsClassCache ctlsnapshotv1.VolumeSnapshotClassCache,
+	vrb *harvesterv1.VolumeRemoteBackup,
+) (*snapshotv1.VolumeSnapshotClass, error) {
+	vsClassInfo, err := bo.GetSnapshotClassInfo(vrb)
+	if err != nil {
+		return nil, err
+	}
+
+	if vsClassInfo.BackupVolumeSnapshotClassName == "" {
+		return nil, fmt.Errorf("backupVolumeSnapshotClassName isn't configured for the CSI driver of PVC %s/%s",
+			bo.GetNamespace(vrb), bo.GetSource(vrb))
+	}
+
+	return vsClassCache.Get(vsClassInfo.BackupVolumeSnapshotClassName)
+}
+
+// BuildOwnerReference creates an owner reference to a `VolumeRemoteBackup` CR, the VolumeSnapshot
+// is garbage collected with it. The backup is kept or deleted by the deletion policy of the
+// VolumeSnapshotClass.
+func (cbo *CSIBackupOperation) BuildOwnerReference(vrb *harvesterv1.VolumeRemoteBackup) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: harvesterv1.SchemeGroupVersion.String(),
+		Kind:       cbo.bo.GetKind(vrb),
+		Name:       cbo.bo.GetName(vrb),
+		UID:        cbo.bo.GetUID(vrb),
+		Controller: ptr.To(true),
+	}
+}
+
+func (cbo *CSIBackupOperation) createVolumeSnapshot(vrb *harvesterv1.VolumeRemoteBackup, vsClass *snapshotv1.VolumeSnapshotClass) error {
+	pvc, err := cbo.pvcCache.Get(cbo.bo.GetNamespace(vrb), cbo.bo.GetSource(vrb))
+	if err != nil {
+		return err
+	}
+
+	provisioner := util.GetProvisionedPVCProvisioner(pvc, cbo.scCache)
+	if vsClass.Driver != provisioner {
+		return fmt.Errorf("VolumeSnapshotClass %s of driver %s can't back up PVC %s/%s provisioned by %s",
+			vsClass.Name, vsClass.Driver, pvc.Namespace, pvc.Name, provisioner)
+	}
+
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cbo.bo.GetName(vrb),
+			Namespace:       cbo.bo.GetNamespace(vrb),
+			OwnerReferences: []metav1.OwnerReference{cbo.BuildOwnerReference(vrb)},
+			Annotations: map[string]string{
+				util.AnnotationStorageProvisioner: provisioner,
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: ptr.To(pvc.Name),
+			},
+			VolumeSnapshotClassName: ptr.To(vsClass.Name),
+		},
+	}
+	if scName := ptr.Deref(pvc.Spec.StorageClassName, ""); scName != "" {
+		vs.Annotations[util.AnnotationStorageClassName] = scName
+	}
+
+	_, err = cbo.vsClient.Create(vs)
+	return err
+}
+
+// setHandle records the snapshot handle of the bound VolumeSnapshotContent, the restore
+// pre-provisions a VolumeSnapshotContent with it.
+func (cbo *CSIBackupOperation) setHandle(vrb *harvesterv1.VolumeRemoteBackup, vs *snapshotv1.VolumeSnapshot) (bool, error) {
+	if cbo.bo.GetHandle(vrb) != "" {
+		return true, nil
+	}
+
+	if vs.Status == nil || vs.Status.BoundVolumeSnapshotContentName == nil {
+		return false, nil
+	}
+
+	vsc, err := cbo.vscCache.Get(*vs.Status.BoundVolumeSnapshotContentName)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if vsc.Status == nil || ptr.Deref(vsc.Status.SnapshotHandle, "") == "" {
+		return false, nil
+	}
+
+	if _, err := cbo.bo.SetHandle(vrb, *vsc.Status.SnapshotHandle); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (cbo *CSIBackupOperation) Create(vrb *harvesterv1.VolumeRemoteBackup) error {
+	if vrb == nil {
+		return fmt.Errorf("RemoteBackup cannot be nil")
+	}
+
+	_, err := cbo.vsCache.Get(cbo.bo.GetNamespace(vrb), cbo.bo.GetName(vrb))
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	vsClass, err := GetBackupVolumeSnapshotClass(cbo.bo, cbo.vsClassCache, vrb)
+	if err != nil {
+		return err
+	}
+	return cbo.createVolumeSnapshot(vrb, vsClass)
+}
+
+func (cbo *CSIBackupOperation) Readiness(vrb *harvesterv1.VolumeRemoteBackup) (bool, error) {
+	if vrb == nil {
+		return false, fmt.Errorf("RemoteBackup cannot be nil")
+	}
+
+	vs, err := cbo.vsCache.Get(cbo.bo.GetNamespace(vrb), cbo.bo.GetName(vrb))
+	if err != nil {
+		return false, err
+	}
+
+	if vs.DeletionTimestamp != nil {
+		return false, common.ErrRetryLater
+	}
+
+	if err := common.CheckVolumeSnapshotError(vs); err != nil {
+		return false, err
+	}
+
+	if !util.IsVolumeSnapshotReady(vs) {
+		return false, nil
+	}
+
+	return cbo.setHandle(vrb, vs)
+}
+
+func (cbo *CSIBackupOperation) Delete(_ *harvesterv1.VolumeRemoteBackup) error {
+	// owner references will handle garbage collection
+	return nil
+}
@@ -0,0 +1,182 @@
+package csi
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/settings"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+)
+
+const (
+	testNamespace   = "default"
+	testDriver      = "rbd.csi.ceph.com"
+	testBackupClass = "rbd-backup"
+	testHandle      = "0001-0009-rook-ceph-snap"
+)
+
+func newCSIDriverConfig() *harvesterv1.Setting {
+	return &harvesterv1.Setting{
+		ObjectMeta: metav1.ObjectMeta{Name: settings.CSIDriverConfigSettingName},
+		Default:    `{"rbd.csi.ceph.com":{"volumeSnapshotClassName":"rbd-snapshot","backupVolumeSnapshotClassName":"rbd-backup"}}`,
+	}
+}
+
+func newSourcePVC() *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{util.AnnStorageProvisioner: testDriver},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: ptr.To("rbd"),
+		},
+	}
+}
+
+func newBackupClass(driver string) *snapshotv1.VolumeSnapshotClass {
+	return &snapshotv1.VolumeSnapshotClass{
+		ObjectMeta:     metav1.ObjectMeta{Name: testBackupClass},
+		Driver:         driver,
+		DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain,
+	}
+}
+
+func newCSIBackup() *harvesterv1.VolumeRemoteBackup {
+	return &harvesterv1.VolumeRemoteBackup{
+		TypeMeta:   metav1.TypeMeta{Kind: "VolumeRemoteBackup"},
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: testNamespace, UID: "backup-uid"},
+		Spec: harvesterv1.VolumeRemoteBackupSpec{
+			Type:   harvesterv1.VolumeRemoteBackupCSI,
+			Source: "data",
+		},
+	}
+}
+
+func newBackupOperation(objects ...runtime.Object) (*CSIBackupOperation, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	pvcCache := fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims)
+	scCache := fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses)
+	bo := common.NewBackupOperator(
+		fakeclients.VolumeRemoteBackupClient(clientset.HarvesterhciV1beta1().VolumeRemoteBackups),
+		pvcCache,
+		scCache,
+		fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings),
+	)
+	op := GetCSIBackupOperation(
+		bo,
+		fakeclients.VolumeSnapshotCache(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClient(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClassCache(clientset.SnapshotV1().VolumeSnapshotClasses),
+		fakeclients.VolumeSnapshotContentCache(clientset.SnapshotV1().VolumeSnapshotContents),
+		pvcCache,
+		scCache,
+	)
+	return op.(*CSIBackupOperation), clientset
+}
+
+func TestCSIBackupOperationCreate(t *testing.T) {
+	vrb := newCSIBackup()
+	op, clientset := newBackupOperation(newCSIDriverConfig(), newSourcePVC(), newBackupClass(testDriver), vrb)
+
+	require.NoError(t, op.Create(vrb))
+	vs, err := clientset.SnapshotV1().VolumeSnapshots(testNamespace).Get(context.TODO(), vrb.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "data", *vs.Spec.Source.PersistentVolumeClaimName)
+	assert.Equal(t, testBackupClass, *vs.Spec.VolumeSnapshotClassName)
+	assert.Equal(t, testDriver, vs.Annotations[util.AnnotationStorageProvisioner])
+	assert.Equal(t, "rbd", vs.Annotations[util.AnnotationStorageClassName])
+	require.Len(t, vs.OwnerReferences, 1)
+	assert.Equal(t, vrb.UID, vs.OwnerReferences[0].UID)
+
+	// the VolumeSnapshot already exists
+	assert.NoError(t, op.Create(vrb))
+}
+
+func TestCSIBackupOperationCreateRejectsOtherDriver(t *testing.T) {
+	vrb := newCSIBackup()
+	op, _ := newBackupOperation(newCSIDriverConfig(), newSourcePVC(), newBackupClass("nfs.csi.k8s.io"), vrb)
+
+	assert.ErrorContains(t, op.Create(vrb), "can't back up PVC default/data")
+}
+
+func TestCSIBackupOperationCreateWithoutBackupClass(t *testing.T) {
+	vrb := newCSIBackup()
+	config := newCSIDriverConfig()
+	config.Default = `{"rbd.csi.ceph.com":{"volumeSnapshotClassName":"rbd-snapshot"}}`
+	op, _ := newBackupOperation(config, newSourcePVC(), vrb)
+
+	assert.ErrorContains(t, op.Create(vrb), "backupVolumeSnapshotClassName isn't configured")
+}
+
+func TestCSIBackupOperationReadiness(t *testing.T) {
+	vrb := newCSIBackup()
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: vrb.Name, Namespace: testNamespace},
+		Status: &snapshotv1.VolumeSnapshotStatus{
+			ReadyToUse: ptr.To(false),
+		},
+	}
+	vsc := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: "snapcontent-backup"},
+	}
+
+	op, clientset := newBackupOperation(vrb)
+	_, err := op.Readiness(vrb)
+	assert.True(t, apierrors.IsNotFound(err))
+
+	op, clientset = newBackupOperation(vrb, vs, vsc)
+	ready, err := op.Readiness(vrb)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	// ready, but the content has no handle yet
+	vs.Status = &snapshotv1.VolumeSnapshotStatus{
+		ReadyToUse:                     ptr.To(true),
+		BoundVolumeSnapshotContentName: ptr.To(vsc.Name),
+	}
+	_, err = clientset.SnapshotV1().VolumeSnapshots(testNamespace).Update(context.TODO(), vs, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	ready, err = op.Readiness(vrb)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	vsc.Status = &snapshotv1.VolumeSnapshotContentStatus{SnapshotHandle: ptr.To(testHandle)}
+	_, err = clientset.SnapshotV1().VolumeSnapshotContents().Update(context.TODO(), vsc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	ready, err = op.Readiness(vrb)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	vrb, err = clientset.HarvesterhciV1beta1().VolumeRemoteBackups(testNamespace).Get(context.TODO(), vrb.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, testHandle, vrb.Status.Handle)
+}
+
+func TestCSIBackupOperationReadinessError(t *testing.T) {
+	vrb := newCSIBackup()
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: vrb.Name, Namespace: testNamespace},
+		Status: &snapshotv1.VolumeSnapshotStatus{
+			Error: &snapshotv1.VolumeSnapshotError{Message: ptr.To("snapshot failed")},
+		},
+	}
+	op, _ := newBackupOperation(vrb, vs)
+
+	_, err := op.Readiness(vrb)
+	assert.ErrorContains(t, err, "snapshot failed")
+}
@@ -0,0 +1,304 @@
+package csi
+
+import (
+	"fmt"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
+	"github.com/harvester/harvester/pkg/ref"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/driver"
+)
+
+// CSIRestoreOperation restores a `VolumeRemoteBackup` taken by CSIBackupOperation. It pre-provisions
+// a VolumeSnapshotContent with the snapshot handle of the backup and creates the PVC from a
+// VolumeSnapshot bound to it. The VolumeSnapshotContent retains the snapshot, so deleting the
+// restore never deletes the backup.
+type CSIRestoreOperation struct {
+	ro           common.RestoreOperator
+	bo           common.BackupOperator
+	vrbCache     ctlharvesterv1.VolumeRemoteBackupCache
+	vsCache      ctlsnapshotv1.VolumeSnapshotCache
+	vsClient     ctlsnapshotv1.VolumeSnapshotClient
+	vsClassCache ctlsnapshotv1.VolumeSnapshotClassCache
+	vscCache     ctlsnapshotv1.VolumeSnapshotContentCache
+	vscClient    ctlsnapshotv1.VolumeSnapshotContentClient
+	pvcCache     ctlcorev1.PersistentVolumeClaimCache
+	pvcClient    ctlcorev1.PersistentVolumeClaimClient
+	scCache      ctlstoragev1.StorageClassCache
+}
+
+func GetCSIRestoreOperation(
+	ro common.RestoreOperator,
+	bo common.BackupOperator,
+	vrbCache ctlharvesterv1.VolumeRemoteBackupCache,
+	vsCache ctlsnapshotv1.VolumeSnapshotCache,
+	vsClient ctlsnapshotv1.VolumeSnapshotClient,
+	vsClassCache ctlsnapshotv1.VolumeSnapshotClassCache,
+	vscCache ctlsnapshotv1.VolumeSnapshotContentCache,
+	vscClient ctlsnapshotv1.VolumeSnapshotContentClient,
+	pvcCache ctlcorev1.PersistentVolumeClaimCache,
+	pvcClient ctlcorev1.PersistentVolumeClaimClient,
+	scCache ctlstoragev1.StorageClassCache,
+) driver.RestoreOperation {
+	return &CSIRestoreOperation{
+		ro:           ro,
+		bo:           bo,
+		vrbCache:     vrbCache,
+		vsCache:      vsCache,
+		vsClient:     vsClient,
+		vsClassCache: vsClassCache,
+		vscCache:     vscCache,
+		vscClient:    vscClient,
+		pvcCache:     pvcCache,
+		pvcClient:    pvcClient,
+		scCache:      scCache,
+	}
+}
+
+// BuildOwnerReference creates an owner reference to a `VolumeRemoteRestore` CR, the restored PVC
+// is garbage collected with it unless it's restored into another namespace.
+func (cro *CSIRestoreOperation) BuildOwnerReference(vrr *harvesterv1.VolumeRemoteRestore) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: harvesterv1.SchemeGroupVersion.String(),
+		Kind:       cro.ro.GetKind(vrr),
+		Name:       cro.ro.GetName(vrr),
+		UID:        cro.ro.GetUID(vrr),
+		Controller: ptr.To(true),
+	}
+}
+
+func (cro *CSIRestoreOperation) restoreRef(vrr *harvesterv1.VolumeRemoteRestore) string {
+	return fmt.Sprintf("%s/%s", cro.ro.GetNamespace(vrr), vrr.Name)
+}
+
+func (cro *CSIRestoreOperation) getSourceRemoteBackup(vrr *harvesterv1.VolumeRemoteRestore) (*harvesterv1.VolumeRemoteBackup, error) {
+	namespace, name := ref.Parse(cro.ro.GetFrom(vrr))
+	if namespace == "" {
+		namespace = cro.ro.GetNamespace(vrr)
+	}
+
+	vrb, err := cro.vrbCache.Get(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if cro.bo.GetType(vrb) != harvesterv1.VolumeRemoteBackupCSI {
+		return nil, fmt.Errorf("source RemoteBackup %s/%s isn't a %s backup", namespace, name, harvesterv1.VolumeRemoteBackupCSI)
+	}
+	if cro.bo.GetHandle(vrb) == "" {
+		return nil, fmt.Errorf("source RemoteBackup %s/%s has no handle", namespace, name)
+	}
+	return vrb, nil
+}
+
+func (cro *CSIRestoreOperation) Create(vrr *harvesterv1.VolumeRemoteRestore) error {
+	if vrr == nil {
+		return fmt.Errorf("RemoteRestore cannot be nil")
+	}
+
+	vrb, err := cro.getSourceRemoteBackup(vrr)
+	if err != nil {
+		return err
+	}
+
+	vsClassInfo, err := cro.ro.GetSnapshotClassInfo(vrr)
+	if err != nil {
+		return err
+	}
+	if vsClassInfo.BackupVolumeSnapshotClassName == "" {
+		return fmt.Errorf("backupVolumeSnapshotClassName isn't configured for the CSI driver %s", cro.bo.GetCSIProvider(vrb))
+	}
+	vsClass, err := cro.vsClassCache.Get(vsClassInfo.BackupVolumeSnapshotClassName)
+	if err != nil {
+		return err
+	}
+
+	vsc, err := cro.vscCache.Get(cro.ro.GetName(vrr))
+	if apierrors.IsNotFound(err) {
+		vsc, err = cro.createVSC(vrr, vrb, vsClass)
+	}
+	if err != nil {
+		return err
+	}
+
+	vs, err := cro.vsCache.Get(cro.ro.GetTargetNamespace(vrr), cro.ro.GetName(vrr))
+	if apierrors.IsNotFound(err) {
+		vs, err = cro.createVolumeSnapshot(vrr, vsc, vsClass)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = cro.pvcCache.Get(cro.ro.GetTargetNamespace(vrr), cro.ro.GetTargetPVCName(vrr))
+	if apierrors.IsNotFound(err) {
+		_, err = cro.createPVCFromSnapshot(vrr, vrb, vs)
+	}
+	return err
+}
+
+// createVSC pre-provisions the VolumeSnapshotContent of the backup handle. It retains the
+// snapshot, the backup owns it.
+func (cro *CSIRestoreOperation) createVSC(
+	vrr *harvesterv1.VolumeRemoteRestore,
+	vrb *harvesterv1.VolumeRemoteBackup,
+	vsClass *snapshotv1.VolumeSnapshotClass,
+) (*snapshotv1.VolumeSnapshotContent, error) {
+	vsc := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cro.ro.GetName(vrr),
+			Annotations: map[string]string{
+				driver.AnnotationPVCRestoreRef: cro.restoreRef(vrr),
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotContentSpec{
+			DeletionPolicy: snapshotv1.VolumeSnapshotContentRetain,
+			Driver:         vsClass.Driver,
+			Source: snapshotv1.VolumeSnapshotContentSource{
+				SnapshotHandle: ptr.To(cro.bo.GetHandle(vrb)),
+			},
+			VolumeSnapshotRef: corev1.ObjectReference{
+				Name:      cro.ro.GetName(vrr),
+				Namespace: cro.ro.GetTargetNamespace(vrr),
+			},
+			VolumeSnapshotClassName: ptr.To(vsClass.Name),
+		},
+	}
+	return cro.vscClient.Create(vsc)
+}
+
+func (cro *CSIRestoreOperation) createVolumeSnapshot(
+	vrr *harvesterv1.VolumeRemoteRestore,
+	vsc *snapshotv1.VolumeSnapshotContent,
+	vsClass *snapshotv1.VolumeSnapshotClass,
+) (*snapshotv1.VolumeSnapshot, error) {
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cro.ro.GetName(vrr),
+			Namespace: cro.ro.GetTargetNamespace(vrr),
+			Annotations: map[string]string{
+				driver.AnnotationPVCRestoreRef: cro.restoreRef(vrr),
+			},
+		},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source: snapshotv1.VolumeSnapshotSource{
+				VolumeSnapshotContentName: ptr.To(vsc.Name),
+			},
+			VolumeSnapshotClassName: ptr.To(vsClass.Name),
+		},
+	}
+	return cro.vsClient.Create(vs)
+}
+
+func (cro *CSIRestoreOperation) createPVCFromSnapshot(
+	vrr *harvesterv1.VolumeRemoteRestore,
+	vrb *harvesterv1.VolumeRemoteBackup,
+	vs *snapshotv1.VolumeSnapshot,
+) (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cro.ro.GetTargetPVCName(vrr),
+			Namespace:   cro.ro.GetTargetNamespace(vrr),
+			Annotations: map[string]string{},
+		},
+		Spec: cro.ro.GetTargetPVCSpec(vrr, vrb),
+	}
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: ptr.To(snapshotv1.GroupName),
+		Kind:     "VolumeSnapshot",
+		Name:     vs.Name,
+	}
+
+	// owner references can't cross namespaces, the restore is resolved by the annotation instead
+	if cro.ro.IsCrossNamespace(vrr) {
+		pvc.Annotations[driver.AnnotationPVCRestoreRef] = cro.restoreRef(vrr)
+	} else {
+		pvc.OwnerReferences = []metav1.OwnerReference{cro.BuildOwnerReference(vrr)}
+	}
+	return cro.pvcClient.Create(pvc)
+}
+
+func (cro *CSIRestoreOperation) Readiness(vrr *harvesterv1.VolumeRemoteRestore) (bool, error) {
+	if vrr == nil {
+		return false, fmt.Errorf("RemoteRestore cannot be nil")
+	}
+
+	vs, err := cro.vsCache.Get(cro.ro.GetTargetNamespace(vrr), cro.ro.GetName(vrr))
+	if err != nil {
+		return false, err
+	}
+	if vs.DeletionTimestamp != nil {
+		return false, common.ErrRetryLater
+	}
+	if err := common.CheckVolumeSnapshotError(vs); err != nil {
+		return false, err
+	}
+	if !util.IsVolumeSnapshotReady(vs) {
+		return false, nil
+	}
+
+	pvc, err := cro.pvcCache.Get(cro.ro.GetTargetNamespace(vrr), cro.ro.GetTargetPVCName(vrr))
+	if err != nil {
+		return false, err
+	}
+	if pvc.DeletionTimestamp != nil {
+		return false, common.ErrRetryLater
+	}
+	if pvc.Spec.StorageClassName != nil {
+		sc, err := cro.scCache.Get(*pvc.Spec.StorageClassName)
+		if err != nil {
+			return false, err
+		}
+		// the PVC isn't bound before its first consumer
+		if sc.VolumeBindingMode != nil && *sc.VolumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer {
+			return true, nil
+		}
+	}
+	return pvc.Status.Phase == corev1.ClaimBound, nil
+}
+
+// Delete removes the VolumeSnapshot and the pre-provisioned VolumeSnapshotContent, the
+// snapshot itself is retained for the backup.
+func (cro *CSIRestoreOperation) Delete(vrr *harvesterv1.VolumeRemoteRestore) error {
+	// the content stays bound until its VolumeSnapshot is gone
+	_, err := cro.vsCache.Get(cro.ro.GetTargetNamespace(vrr), cro.ro.GetName(vrr))
+	if err == nil {
+		err = cro.vsClient.Delete(cro.ro.GetTargetNamespace(vrr), cro.ro.GetName(vrr), &metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return common.ErrRetryLater
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	vsc, err := cro.vscCache.Get(cro.ro.GetName(vrr))
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// only delete the content this restore pre-provisioned
+	if vsc.Annotations[driver.AnnotationPVCRestoreRef] != cro.restoreRef(vrr) {
+		return nil
+	}
+	if vsc.Spec.DeletionPolicy != snapshotv1.VolumeSnapshotContentRetain {
+		return fmt.Errorf("VolumeSnapshotContent %s doesn't retain its snapshot, refusing to delete it", vsc.Name)
+	}
+	if err := cro.vscClient.Delete(vsc.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
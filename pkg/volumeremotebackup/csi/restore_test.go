@@ -0,0 +1,179 @@
+package csi
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/driver"
+)
+
+func newCompletedCSIBackup() *harvesterv1.VolumeRemoteBackup {
+	vrb := newCSIBackup()
+	vrb.Status = harvesterv1.VolumeRemoteBackupStatus{
+		Success:     true,
+		Handle:      testHandle,
+		CSIProvider: testDriver,
+		SourceSpec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: ptr.To("rbd"),
+		},
+	}
+	return vrb
+}
+
+func newCSIRestore() *harvesterv1.VolumeRemoteRestore {
+	return &harvesterv1.VolumeRemoteRestore{
+		TypeMeta:   metav1.TypeMeta{Kind: "VolumeRemoteRestore"},
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: testNamespace, UID: "restore-uid"},
+		Spec: harvesterv1.VolumeRemoteRestoreSpec{
+			Type: harvesterv1.VolumeRemoteRestoreCSI,
+			From: "backup",
+		},
+	}
+}
+
+func newRestoreOperation(objects ...runtime.Object) (*CSIRestoreOperation, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	pvcCache := fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims)
+	scCache := fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses)
+	settingCache := fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings)
+	vrbCache := fakeclients.VolumeRemoteBackupCache(clientset.HarvesterhciV1beta1().VolumeRemoteBackups)
+	bo := common.NewBackupOperator(
+		fakeclients.VolumeRemoteBackupClient(clientset.HarvesterhciV1beta1().VolumeRemoteBackups),
+		pvcCache,
+		scCache,
+		settingCache,
+	)
+	ro := common.NewRestoreOperator(nil, pvcCache, scCache, settingCache, vrbCache, bo)
+	op := GetCSIRestoreOperation(
+		ro,
+		bo,
+		vrbCache,
+		fakeclients.VolumeSnapshotCache(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClient(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClassCache(clientset.SnapshotV1().VolumeSnapshotClasses),
+		fakeclients.VolumeSnapshotContentCache(clientset.SnapshotV1().VolumeSnapshotContents),
+		fakeclients.VolumeSnapshotContentClient(clientset.SnapshotV1().VolumeSnapshotContents),
+		pvcCache,
+		fakeclients.PersistentVolumeClaimClient(clientset.CoreV1().PersistentVolumeClaims),
+		scCache,
+	)
+	return op.(*CSIRestoreOperation), clientset
+}
+
+func TestCSIRestoreOperationCreate(t *testing.T) {
+	vrr := newCSIRestore()
+	op, clientset := newRestoreOperation(newCSIDriverConfig(), newBackupClass(testDriver), newCompletedCSIBackup())
+
+	_, err := op.Readiness(vrr)
+	require.True(t, apierrors.IsNotFound(err))
+	require.NoError(t, op.Create(vrr))
+
+	vsc, err := clientset.SnapshotV1().VolumeSnapshotContents().Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, snapshotv1.VolumeSnapshotContentRetain, vsc.Spec.DeletionPolicy)
+	assert.Equal(t, testDriver, vsc.Spec.Driver)
+	assert.Equal(t, testHandle, *vsc.Spec.Source.SnapshotHandle)
+	assert.Equal(t, corev1.ObjectReference{Name: vrr.Name, Namespace: testNamespace}, vsc.Spec.VolumeSnapshotRef)
+
+	vs, err := clientset.SnapshotV1().VolumeSnapshots(testNamespace).Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, vsc.Name, *vs.Spec.Source.VolumeSnapshotContentName)
+	assert.Equal(t, testBackupClass, *vs.Spec.VolumeSnapshotClassName)
+
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims(testNamespace).Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, vs.Name, pvc.Spec.DataSource.Name)
+	assert.Equal(t, "rbd", *pvc.Spec.StorageClassName)
+	require.Len(t, pvc.OwnerReferences, 1)
+	assert.Equal(t, vrr.UID, pvc.OwnerReferences[0].UID)
+
+	// the restore resources already exist
+	assert.NoError(t, op.Create(vrr))
+}
+
+func TestCSIRestoreOperationCreateCrossNamespace(t *testing.T) {
+	vrr := newCSIRestore()
+	vrr.Spec.TargetNamespace = "restored"
+	op, clientset := newRestoreOperation(newCSIDriverConfig(), newBackupClass(testDriver), newCompletedCSIBackup())
+
+	require.NoError(t, op.Create(vrr))
+	pvc, err := clientset.CoreV1().PersistentVolumeClaims("restored").Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, pvc.OwnerReferences)
+	assert.Equal(t, "default/restore", pvc.Annotations[driver.AnnotationPVCRestoreRef])
+}
+
+func TestCSIRestoreOperationCreateRejectsLonghornBackup(t *testing.T) {
+	vrb := newCompletedCSIBackup()
+	vrb.Spec.Type = harvesterv1.VolumeRemoteBackupLH
+	op, _ := newRestoreOperation(newCSIDriverConfig(), newBackupClass(testDriver), vrb)
+
+	assert.ErrorContains(t, op.Create(newCSIRestore()), "isn't a csi backup")
+}
+
+func TestCSIRestoreOperationReadiness(t *testing.T) {
+	vrr := newCSIRestore()
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: vrr.Name, Namespace: testNamespace},
+		Status:     &snapshotv1.VolumeSnapshotStatus{ReadyToUse: ptr.To(true)},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: vrr.Name, Namespace: testNamespace},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	}
+	op, clientset := newRestoreOperation(vs, pvc)
+
+	ready, err := op.Readiness(vrr)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	pvc.Status.Phase = corev1.ClaimBound
+	_, err = clientset.CoreV1().PersistentVolumeClaims(testNamespace).UpdateStatus(context.TODO(), pvc, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	ready, err = op.Readiness(vrr)
+	require.NoError(t, err)
+	assert.True(t, ready)
+}
+
+func TestCSIRestoreOperationDelete(t *testing.T) {
+	vrr := newCSIRestore()
+	op, clientset := newRestoreOperation(newCSIDriverConfig(), newBackupClass(testDriver), newCompletedCSIBackup())
+	require.NoError(t, op.Create(vrr))
+
+	// the VolumeSnapshot is deleted first
+	assert.True(t, common.IsRetryLater(op.Delete(vrr)))
+	_, err := clientset.SnapshotV1().VolumeSnapshots(testNamespace).Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	require.NoError(t, op.Delete(vrr))
+	_, err = clientset.SnapshotV1().VolumeSnapshotContents().Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.NoError(t, op.Delete(vrr))
+}
+
+func TestCSIRestoreOperationDeleteKeepsForeignContent(t *testing.T) {
+	vrr := newCSIRestore()
+	vsc := &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{Name: vrr.Name},
+		Spec:       snapshotv1.VolumeSnapshotContentSpec{DeletionPolicy: snapshotv1.VolumeSnapshotContentDelete},
+	}
+	op, clientset := newRestoreOperation(vsc)
+
+	require.NoError(t, op.Delete(vrr))
+	_, err := clientset.SnapshotV1().VolumeSnapshotContents().Get(context.TODO(), vrr.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
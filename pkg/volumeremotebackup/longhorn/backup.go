@@ -175,7 +175,7 @@ func (lbo *LHBackupOperation) readiness(vrb *harvesterv1.VolumeRemoteBackup) (bo
 	}
 
 	// Check if VolumeSnapshot is in error state
-	if err := checkVolumeSnapshotError(vs); err != nil {
+	if err := common.CheckVolumeSnapshotError(vs); err != nil {
 		return false, err
 	}
 
@@ -1,6 +1,7 @@
 package longhorn
 
 import (
+	"errors"
 	"fmt"
 
 	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
@@ -155,7 +156,7 @@ func (lbr *LHRestoreOperation) buildRestoreSteps(
 					return lbr.createVSC(vrr, vrb, vsClass)
 				},
 				func() (*snapshotv1.VolumeSnapshotContent, error) {
-					return lbr.vscCache.Get(lbr.ro.GetName(vrr))
+					return lbr.getVSCForRestore(vrr)
 				},
 			)
 			return err
@@ -171,7 +172,7 @@ func (lbr *LHRestoreOperation) buildRestoreSteps(
 					return lbr.createVolumeSnapshot(vrr, vsc, vsClass)
 				},
 				func() (*snapshotv1.VolumeSnapshot, error) {
-					return lbr.vsCache.Get(lbr.ro.GetNamespace(vrr), lbr.ro.GetName(vrr))
+					return lbr.getVolumeSnapshotForPVCRestore(vrr)
 				},
 			)
 			return err
@@ -186,7 +187,7 @@ func (lbr *LHRestoreOperation) buildRestoreSteps(
 					return lbr.createPVCFromSnapshot(vrr, vrb, vs)
 				},
 				func() (*corev1.PersistentVolumeClaim, error) {
-					return lbr.pvcCache.Get(lbr.ro.GetNamespace(vrr), lbr.ro.GetName(vrr))
+					return lbr.pvcCache.Get(lbr.ro.GetTargetNamespace(vrr), lbr.ro.GetTargetPVCName(vrr))
 				},
 			)
 			return err
@@ -243,14 +244,14 @@ func (lbr *LHRestoreOperation) createVSC(
 ) (*snapshotv1.VolumeSnapshotContent, error) {
 	vscName := lbr.ro.GetName(vrr)
 	vsName := lbr.ro.GetName(vrr)
-	vsNamespace := lbr.ro.GetNamespace(vrr)
+	vsNamespace := lbr.ro.GetTargetNamespace(vrr)
 	handle := lbr.bo.GetHandle(vrb)
 
 	vsc := &snapshotv1.VolumeSnapshotContent{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: vscName,
 			Annotations: map[string]string{
-				driver.AnnotationPVCRestoreRef: fmt.Sprintf("%s/%s", vsNamespace, vrr.Name),
+				driver.AnnotationPVCRestoreRef: lbr.restoreRef(vrr),
 			},
 		},
 		Spec: snapshotv1.VolumeSnapshotContentSpec{
@@ -277,7 +278,7 @@ func (lbr *LHRestoreOperation) createVolumeSnapshot(
 	vsClass *snapshotv1.VolumeSnapshotClass,
 ) (*snapshotv1.VolumeSnapshot, error) {
 	vsName := lbr.ro.GetName(vrr)
-	vsNamespace := lbr.ro.GetNamespace(vrr)
+	vsNamespace := lbr.ro.GetTargetNamespace(vrr)
 	vscName := vsc.Name
 
 	vs := &snapshotv1.VolumeSnapshot{
@@ -285,7 +286,7 @@ func (lbr *LHRestoreOperation) createVolumeSnapshot(
 			Name:      vsName,
 			Namespace: vsNamespace,
 			Annotations: map[string]string{
-				driver.AnnotationPVCRestoreRef: fmt.Sprintf("%s/%s", vsNamespace, vrr.Name),
+				driver.AnnotationPVCRestoreRef: lbr.restoreRef(vrr),
 			},
 		},
 		Spec: snapshotv1.VolumeSnapshotSpec{
@@ -305,49 +306,83 @@ func (lbr *LHRestoreOperation) createPVCFromSnapshot(
 	vrb *harvesterv1.VolumeRemoteBackup,
 	vs *snapshotv1.VolumeSnapshot,
 ) (*corev1.PersistentVolumeClaim, error) {
-	pvcName := lbr.ro.GetName(vrr)
-	pvcNamespace := lbr.ro.GetNamespace(vrr)
-	ownerRef := lbr.BuildOwnerReference(vrr)
-
-	// Get source spec from pb operator
-	sourceSpec := lbr.bo.GetSourceSpec(vrb)
-
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            pvcName,
-			Namespace:       pvcNamespace,
-			Annotations:     map[string]string{},
-			OwnerReferences: []metav1.OwnerReference{ownerRef},
-		},
-		Spec: corev1.PersistentVolumeClaimSpec{
-			AccessModes:      sourceSpec.AccessModes,
-			Resources:        sourceSpec.Resources,
-			StorageClassName: sourceSpec.StorageClassName,
-			VolumeMode:       sourceSpec.VolumeMode,
-			DataSource: &corev1.TypedLocalObjectReference{
-				APIGroup: ptr.To(snapshotv1.GroupName),
-				Kind:     "VolumeSnapshot",
-				Name:     vs.Name,
-			},
+			Name:        lbr.ro.GetTargetPVCName(vrr),
+			Namespace:   lbr.ro.GetTargetNamespace(vrr),
+			Annotations: map[string]string{},
 		},
+		Spec: lbr.ro.GetTargetPVCSpec(vrr, vrb),
+	}
+	pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: ptr.To(snapshotv1.GroupName),
+		Kind:     "VolumeSnapshot",
+		Name:     vs.Name,
+	}
+
+	// owner references can't cross namespaces, the restore is resolved by the annotation instead
+	if lbr.ro.IsCrossNamespace(vrr) {
+		pvc.Annotations[driver.AnnotationPVCRestoreRef] = lbr.restoreRef(vrr)
+	} else {
+		pvc.OwnerReferences = []metav1.OwnerReference{lbr.BuildOwnerReference(vrr)}
 	}
 
 	return lbr.pvcClient.Create(pvc)
 }
 
+// restoreRef is the value of the annotation tying the restored resources to the PVCRestore.
+func (lbr *LHRestoreOperation) restoreRef(vrr *harvesterv1.VolumeRemoteRestore) string {
+	return fmt.Sprintf("%s/%s", lbr.ro.GetNamespace(vrr), vrr.Name)
+}
+
 // getVSC retrieves the VolumeSnapshotContent associated with a PVCRestore.
+// A VolumeSnapshotContent created by anything else only collides with its name.
 func (lbr *LHRestoreOperation) getVSCForRestore(vrr *harvesterv1.VolumeRemoteRestore) (*snapshotv1.VolumeSnapshotContent, error) {
-	return lbr.vscCache.Get(lbr.ro.GetName(vrr))
+	vsc, err := lbr.vscCache.Get(lbr.ro.GetName(vrr))
+	if err != nil {
+		return nil, err
+	}
+	if vsc.Annotations[driver.AnnotationPVCRestoreRef] != lbr.restoreRef(vrr) {
+		return nil, newNameCollisionError("VolumeSnapshotContent", vsc.Name, lbr.restoreRef(vrr))
+	}
+	return vsc, nil
 }
 
 // getVolumeSnapshotForPVCRestore retrieves the VolumeSnapshot associated with a PVCRestore.
+// A VolumeSnapshot created by anything else only collides with its name.
 func (lbr *LHRestoreOperation) getVolumeSnapshotForPVCRestore(vrr *harvesterv1.VolumeRemoteRestore) (*snapshotv1.VolumeSnapshot, error) {
-	return lbr.vsCache.Get(lbr.ro.GetNamespace(vrr), lbr.ro.GetName(vrr))
+	vs, err := lbr.vsCache.Get(lbr.ro.GetTargetNamespace(vrr), lbr.ro.GetName(vrr))
+	if err != nil {
+		return nil, err
+	}
+	if vs.Annotations[driver.AnnotationPVCRestoreRef] != lbr.restoreRef(vrr) {
+		return nil, newNameCollisionError("VolumeSnapshot", fmt.Sprintf("%s/%s", vs.Namespace, vs.Name), lbr.restoreRef(vrr))
+	}
+	return vs, nil
+}
+
+// nameCollisionError is returned when a resource of the restore already exists, but wasn't
+// created by the restore. It must neither be restored from nor deleted.
+type nameCollisionError struct {
+	kind, name, restoreRef string
+}
+
+func newNameCollisionError(kind, name, restoreRef string) error {
+	return &nameCollisionError{kind: kind, name: name, restoreRef: restoreRef}
+}
+
+func (e *nameCollisionError) Error() string {
+	return fmt.Sprintf("%s %s already exists and doesn't belong to RemoteRestore %s", e.kind, e.name, e.restoreRef)
+}
+
+func isNameCollision(err error) bool {
+	var collision *nameCollisionError
+	return errors.As(err, &collision)
 }
 
 // getPVCForPVCRestore retrieves the PVC associated with a PVCRestore.
 func (lbr *LHRestoreOperation) getPVCForPVCRestore(vrr *harvesterv1.VolumeRemoteRestore) (*corev1.PersistentVolumeClaim, error) {
-	return lbr.pvcCache.Get(lbr.ro.GetNamespace(vrr), lbr.ro.GetName(vrr))
+	return lbr.pvcCache.Get(lbr.ro.GetTargetNamespace(vrr), lbr.ro.GetTargetPVCName(vrr))
 }
 
 // isVSCDeleting checks if a VolumeSnapshotContent is being deleted.
@@ -415,7 +450,7 @@ func (lbr *LHRestoreOperation) checkVolumeSnapshotReadiness(vrr *harvesterv1.Vol
 		return nil, common.ErrRetryLater
 	}
 
-	if err := checkVolumeSnapshotError(vs); err != nil {
+	if err := common.CheckVolumeSnapshotError(vs); err != nil {
 		return nil, err
 	}
 
@@ -504,7 +539,7 @@ func (lbr *LHRestoreOperation) Readiness(pr *harvesterv1.VolumeRemoteRestore) (b
 // deleteVolumeSnapshot deletes the VolumeSnapshot associated with a PVCRestore
 func (lbr *LHRestoreOperation) deleteVolumeSnapshot(vrr *harvesterv1.VolumeRemoteRestore) error {
 	vs, err := lbr.getVolumeSnapshotForPVCRestore(vrr)
-	if apierrors.IsNotFound(err) {
+	if apierrors.IsNotFound(err) || isNameCollision(err) {
 		// VolumeSnapshot already deleted or doesn't exist
 		return nil
 	}
@@ -527,7 +562,7 @@ func (lbr *LHRestoreOperation) hasSnapshotHandle(vsc *snapshotv1.VolumeSnapshotC
 func (lbr *LHRestoreOperation) Delete(vrr *harvesterv1.VolumeRemoteRestore) error {
 	// Check if the related VolumeSnapshotContent exists
 	vsc, err := lbr.getVSCForRestore(vrr)
-	if apierrors.IsNotFound(err) {
+	if apierrors.IsNotFound(err) || isNameCollision(err) {
 		// VSC doesn't exist, check and delete VolumeSnapshot
 		return lbr.deleteVolumeSnapshot(vrr)
 	}
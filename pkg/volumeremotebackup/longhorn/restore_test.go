@@ -0,0 +1,110 @@
+package longhorn
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/driver"
+)
+
+func newLHRestore() *harvesterv1.VolumeRemoteRestore {
+	return &harvesterv1.VolumeRemoteRestore{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default", UID: "restore-uid"},
+		Spec: harvesterv1.VolumeRemoteRestoreSpec{
+			Type:            harvesterv1.VolumeRemoteRestoreLH,
+			From:            "backup",
+			TargetNamespace: "restored",
+		},
+	}
+}
+
+func newRestoreOperation(objects ...runtime.Object) (*LHRestoreOperation, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(objects...)
+	pvcCache := fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims)
+	scCache := fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses)
+	settingCache := fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings)
+	vrbCache := fakeclients.VolumeRemoteBackupCache(clientset.HarvesterhciV1beta1().VolumeRemoteBackups)
+	bo := common.NewBackupOperator(
+		fakeclients.VolumeRemoteBackupClient(clientset.HarvesterhciV1beta1().VolumeRemoteBackups),
+		pvcCache,
+		scCache,
+		settingCache,
+	)
+	op := GetLHRestoreOperation(
+		common.NewRestoreOperator(nil, pvcCache, scCache, settingCache, vrbCache, bo),
+		bo,
+		vrbCache,
+		fakeclients.VolumeSnapshotCache(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClient(clientset.SnapshotV1().VolumeSnapshots),
+		fakeclients.VolumeSnapshotClassCache(clientset.SnapshotV1().VolumeSnapshotClasses),
+		fakeclients.VolumeSnapshotContentCache(clientset.SnapshotV1().VolumeSnapshotContents),
+		fakeclients.VolumeSnapshotContentClient(clientset.SnapshotV1().VolumeSnapshotContents),
+		pvcCache,
+		fakeclients.PersistentVolumeClaimClient(clientset.CoreV1().PersistentVolumeClaims),
+		scCache,
+	)
+	return op.(*LHRestoreOperation), clientset
+}
+
+func newForeignVolumeSnapshot() *snapshotv1.VolumeSnapshot {
+	return &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "restored"},
+	}
+}
+
+func newRestoreVSC(restoreRef string) *snapshotv1.VolumeSnapshotContent {
+	return &snapshotv1.VolumeSnapshotContent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "restore",
+			Annotations: map[string]string{driver.AnnotationPVCRestoreRef: restoreRef},
+		},
+	}
+}
+
+func TestLHRestoreOperationCreateRejectsForeignVolumeSnapshot(t *testing.T) {
+	vsc := newRestoreVSC("default/restore")
+	vsc.Status = &snapshotv1.VolumeSnapshotContentStatus{ReadyToUse: ptr.To(true)}
+	op, _ := newRestoreOperation(vsc, newForeignVolumeSnapshot())
+
+	err := op.Create(newLHRestore())
+	assert.ErrorContains(t, err, "VolumeSnapshot restored/restore already exists and doesn't belong to RemoteRestore default/restore")
+}
+
+func TestLHRestoreOperationCreateRejectsForeignContent(t *testing.T) {
+	// a restore with the same name in another namespace pre-provisioned the content
+	op, _ := newRestoreOperation(newRestoreVSC("other/restore"))
+
+	err := op.Create(newLHRestore())
+	assert.ErrorContains(t, err, "VolumeSnapshotContent restore already exists and doesn't belong to RemoteRestore default/restore")
+}
+
+func TestLHRestoreOperationDeleteKeepsForeignResources(t *testing.T) {
+	op, clientset := newRestoreOperation(newRestoreVSC("other/restore"), newForeignVolumeSnapshot())
+
+	require.NoError(t, op.Delete(newLHRestore()))
+	_, err := clientset.SnapshotV1().VolumeSnapshots("restored").Get(context.TODO(), "restore", metav1.GetOptions{})
+	assert.NoError(t, err)
+	_, err = clientset.SnapshotV1().VolumeSnapshotContents().Get(context.TODO(), "restore", metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestLHRestoreOperationDeleteOwnVolumeSnapshot(t *testing.T) {
+	vs := newForeignVolumeSnapshot()
+	vs.Annotations = map[string]string{driver.AnnotationPVCRestoreRef: "default/restore"}
+	op, clientset := newRestoreOperation(vs)
+
+	require.NoError(t, op.Delete(newLHRestore()))
+	_, err := clientset.SnapshotV1().VolumeSnapshots("restored").Get(context.TODO(), "restore", metav1.GetOptions{})
+	assert.Error(t, err)
+}
@@ -81,7 +81,8 @@ func vmBackupBySourceUID(obj *harvesterv1.VirtualMachineBackup) ([]string, error
 }
 
 func vmBackupSnapshotByPVCNamespaceAndName(obj *harvesterv1.VirtualMachineBackup) ([]string, error) {
-	if obj.Spec.Type == harvesterv1.Backup {
+	// Only in-cluster snapshots depend on the source PVCs after the backup completes.
+	if obj.Spec.Type.UsesRemoteBackupTarget() {
 		return []string{}, nil
 	}
 
@@ -0,0 +1,81 @@
+package backupbrowsesession
+
+import (
+	"fmt"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/datamover"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldVMBackupName = "spec.vmBackupName"
+	fieldVolumeName   = "spec.volumeName"
+	fieldTTL          = "spec.ttl"
+)
+
+func NewValidator(vmBackupCache ctlharvesterv1.VirtualMachineBackupCache) types.Validator {
+	return &backupBrowseSessionValidator{
+		vmBackupCache: vmBackupCache,
+	}
+}
+
+type backupBrowseSessionValidator struct {
+	types.DefaultValidator
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache
+}
+
+func (v *backupBrowseSessionValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.BackupBrowseSessionResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.BackupBrowseSession{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+		},
+	}
+}
+
+func (v *backupBrowseSessionValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	session := newObj.(*v1beta1.BackupBrowseSession)
+
+	if session.Spec.VMBackupName == "" {
+		return werror.NewInvalidError("vm backup name is empty", fieldVMBackupName)
+	}
+	vmBackup, err := v.vmBackupCache.Get(session.Namespace, session.Spec.VMBackupName)
+	if err != nil {
+		return werror.NewInvalidError(err.Error(), fieldVMBackupName)
+	}
+	if vmBackup.DeletionTimestamp != nil {
+		return werror.NewInvalidError(fmt.Sprintf("vm backup %s is being deleted", vmBackup.Name), fieldVMBackupName)
+	}
+	if vmBackup.Status.Error != nil {
+		return werror.NewInvalidError(fmt.Sprintf("vm backup %s failed", vmBackup.Name), fieldVMBackupName)
+	}
+
+	found := false
+	for _, vb := range vmBackup.Status.VolumeBackups {
+		if vb.VolumeName == session.Spec.VolumeName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return werror.NewInvalidError(fmt.Sprintf("volume %s not found in vm backup %s", session.Spec.VolumeName, vmBackup.Name), fieldVolumeName)
+	}
+
+	if session.Spec.TTL != nil && session.Spec.TTL.Duration <= 0 {
+		return werror.NewInvalidError("must be positive", fieldTTL)
+	}
+	if session.Spec.TTL != nil && session.Spec.TTL.Duration > datamover.BrowseMaxTTL {
+		return werror.NewInvalidError(fmt.Sprintf("must not exceed %s", datamover.BrowseMaxTTL), fieldTTL)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package backuptarget
+
+import (
+	"fmt"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldEndpoint            = "spec.endpoint"
+	fieldBucketName          = "spec.bucketName"
+	fieldCredentialSecret    = "spec.credentialSecret"
+	fieldEncryptionKeySecret = "spec.encryptionKeySecret"
+)
+
+func NewValidator(
+	secretCache ctlcorev1.SecretCache,
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache,
+	svmBackupCache ctlharvesterv1.ScheduleVMBackupCache,
+	vmBackupCopyCache ctlharvesterv1.VirtualMachineBackupCopyCache,
+) types.Validator {
+	return &backupTargetValidator{
+		secretCache:       secretCache,
+		vmBackupCache:     vmBackupCache,
+		svmBackupCache:    svmBackupCache,
+		vmBackupCopyCache: vmBackupCopyCache,
+	}
+}
+
+type backupTargetValidator struct {
+	types.DefaultValidator
+	secretCache       ctlcorev1.SecretCache
+	vmBackupCache     ctlharvesterv1.VirtualMachineBackupCache
+	svmBackupCache    ctlharvesterv1.ScheduleVMBackupCache
+	vmBackupCopyCache ctlharvesterv1.VirtualMachineBackupCopyCache
+}
+
+func (v *backupTargetValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.BackupTargetResourceName},
+		Scope:      admissionregv1.ClusterScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.BackupTarget{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+			admissionregv1.Delete,
+		},
+	}
+}
+
+func (v *backupTargetValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	return v.validateSpec(newObj.(*v1beta1.BackupTarget))
+}
+
+func (v *backupTargetValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+	oldBT := oldObj.(*v1beta1.BackupTarget)
+	newBT := newObj.(*v1beta1.BackupTarget)
+
+	if !newBT.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// the backups already stored in the target would be lost
+	if oldBT.Spec.Type != newBT.Spec.Type || oldBT.Spec.Endpoint != newBT.Spec.Endpoint || oldBT.Spec.BucketName != newBT.Spec.BucketName {
+		inUse, err := v.isInUse(newBT.Name)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return werror.NewInvalidError("the location of a backup target with VM backups can't be changed", fieldEndpoint)
+		}
+	}
+
+	return v.validateSpec(newBT)
+}
+
+func (v *backupTargetValidator) Delete(_ *types.Request, oldObj runtime.Object) error {
+	bt := oldObj.(*v1beta1.BackupTarget)
+
+	inUse, err := v.isInUse(bt.Name)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return werror.NewBadRequest(fmt.Sprintf("backup target %s is used by VM backups, their copies or schedules", bt.Name))
+	}
+	return nil
+}
+
+func (v *backupTargetValidator) validateSpec(bt *v1beta1.BackupTarget) error {
+	switch bt.Spec.Type {
+	case v1beta1.BackupTargetTypeS3:
+		if bt.Spec.BucketName == "" || bt.Spec.BucketRegion == "" {
+			return werror.NewInvalidError("S3 backup target should have bucket name and region", fieldBucketName)
+		}
+		if bt.Spec.CredentialSecret == "" {
+			return werror.NewInvalidError("S3 backup target should have credential secret", fieldCredentialSecret)
+		}
+		secret, err := v.secretCache.Get(util.HarvesterSystemNamespaceName, bt.Spec.CredentialSecret)
+		if err != nil {
+			return werror.NewInvalidError(err.Error(), fieldCredentialSecret)
+		}
+		if len(secret.Data[util.AWSAccessKey]) == 0 || len(secret.Data[util.AWSSecretKey]) == 0 {
+			return werror.NewInvalidError(fmt.Sprintf("credential secret should have %s and %s", util.AWSAccessKey, util.AWSSecretKey), fieldCredentialSecret)
+		}
+	case v1beta1.BackupTargetTypeNFS:
+		if bt.Spec.Endpoint == "" {
+			return werror.NewInvalidError("NFS backup target should have endpoint", fieldEndpoint)
+		}
+		if bt.Spec.BucketName != "" || bt.Spec.BucketRegion != "" {
+			return werror.NewInvalidError("NFS backup target should not have bucket name or region", fieldBucketName)
+		}
+		if bt.Spec.CredentialSecret != "" {
+			return werror.NewInvalidError("NFS backup target should not have credential secret", fieldCredentialSecret)
+		}
+	default:
+		return werror.NewInvalidError("Invalid backup target type", "spec.type")
+	}
+
+	if _, err := backuputil.GetEncryptionKey(v.secretCache, backuputil.ToSettingBackupTarget(bt)); err != nil {
+		return werror.NewInvalidError(err.Error(), fieldEncryptionKeySecret)
+	}
+	return nil
+}
+
+// isInUse returns true if VM backups or their copies are stored in the backup
+// target, or schedules create them there.
+func (v *backupTargetValidator) isInUse(name string) (bool, error) {
+	vmBackups, err := v.vmBackupCache.List(metav1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return false, werror.NewInternalError(fmt.Sprintf("Can't list VM backups, err: %+v", err.Error()))
+	}
+	for _, vmBackup := range vmBackups {
+		if vmBackup.Spec.BackupTargetName == name {
+			return true, nil
+		}
+	}
+
+	svmBackups, err := v.svmBackupCache.List(metav1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return false, werror.NewInternalError(fmt.Sprintf("Can't list VM backup schedules, err: %+v", err.Error()))
+	}
+	for _, svmBackup := range svmBackups {
+		if svmBackup.Spec.VMBackupSpec.BackupTargetName == name ||
+			(svmBackup.Spec.Copy != nil && svmBackup.Spec.Copy.BackupTargetName == name) {
+			return true, nil
+		}
+	}
+
+	vmBackupCopies, err := v.vmBackupCopyCache.List(metav1.NamespaceAll, labels.Everything())
+	if err != nil {
+		return false, werror.NewInternalError(fmt.Sprintf("Can't list VM backup copies, err: %+v", err.Error()))
+	}
+	for _, vmBackupCopy := range vmBackupCopies {
+		if vmBackupCopy.Spec.BackupTargetName == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
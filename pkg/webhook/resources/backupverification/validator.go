@@ -0,0 +1,73 @@
+package backupverification
+
+import (
+	"fmt"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldVMBackupName = "spec.vmBackupName"
+)
+
+func NewValidator(vmBackupCache ctlharvesterv1.VirtualMachineBackupCache) types.Validator {
+	return &backupVerificationValidator{
+		vmBackupCache: vmBackupCache,
+	}
+}
+
+type backupVerificationValidator struct {
+	types.DefaultValidator
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache
+}
+
+func (v *backupVerificationValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.BackupVerificationResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.BackupVerification{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+		},
+	}
+}
+
+func (v *backupVerificationValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	verification := newObj.(*v1beta1.BackupVerification)
+
+	if verification.Spec.VMBackupName == "" {
+		return werror.NewInvalidError("vm backup name is empty", fieldVMBackupName)
+	}
+	vmBackup, err := v.vmBackupCache.Get(verification.Namespace, verification.Spec.VMBackupName)
+	if err != nil {
+		return werror.NewInvalidError(err.Error(), fieldVMBackupName)
+	}
+	if vmBackup.DeletionTimestamp != nil {
+		return werror.NewInvalidError(fmt.Sprintf("vm backup %s is being deleted", vmBackup.Name), fieldVMBackupName)
+	}
+	if !vmBackup.Spec.Type.UsesRemoteBackupTarget() {
+		return werror.NewInvalidError(fmt.Sprintf("vm backup of type %s can't be restored in a sandbox namespace", vmBackup.Spec.Type), fieldVMBackupName)
+	}
+
+	return ValidateTemplate(&verification.Spec.BackupVerificationTemplate, "spec")
+}
+
+// ValidateTemplate checks how the restored VM is verified, fieldPath is the
+// path of the template in the validated object.
+func ValidateTemplate(template *v1beta1.BackupVerificationTemplate, fieldPath string) error {
+	if template.Timeout != nil && template.Timeout.Duration <= 0 {
+		return werror.NewInvalidError("must be positive", fieldPath+".timeout")
+	}
+	if probe := template.ReadinessProbe; probe != nil && (probe.HTTPGet != nil || probe.TCPSocket != nil) {
+		return werror.NewInvalidError("the restored vm has no network, only exec and guest agent ping probes are supported", fieldPath+".readinessProbe")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package imagesyncpolicy
+
+import (
+	"fmt"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/remote"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldKubeconfigSecretName = "spec.kubeconfigSecretName"
+	fieldSourceNamespace      = "spec.sourceNamespace"
+	fieldSelector             = "spec.selector"
+	fieldInterval             = "spec.interval"
+
+	// every sync lists the images of the source cluster, don't poll it more often
+	minSyncInterval = time.Minute
+)
+
+func NewValidator(secretCache ctlcorev1.SecretCache) types.Validator {
+	return &imageSyncPolicyValidator{
+		secretCache: secretCache,
+	}
+}
+
+type imageSyncPolicyValidator struct {
+	types.DefaultValidator
+	secretCache ctlcorev1.SecretCache
+}
+
+func (v *imageSyncPolicyValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.ImageSyncPolicyResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.ImageSyncPolicy{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+func (v *imageSyncPolicyValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	return v.validate(newObj.(*v1beta1.ImageSyncPolicy))
+}
+
+func (v *imageSyncPolicyValidator) Update(_ *types.Request, _ runtime.Object, newObj runtime.Object) error {
+	policy := newObj.(*v1beta1.ImageSyncPolicy)
+	if policy.DeletionTimestamp != nil {
+		return nil
+	}
+	return v.validate(policy)
+}
+
+func (v *imageSyncPolicyValidator) validate(policy *v1beta1.ImageSyncPolicy) error {
+	if policy.Spec.SourceNamespace == "" {
+		return werror.NewInvalidError("source namespace is required", fieldSourceNamespace)
+	}
+	if policy.Spec.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(policy.Spec.Selector); err != nil {
+			return werror.NewInvalidError(err.Error(), fieldSelector)
+		}
+	}
+	if policy.Spec.Interval != nil && policy.Spec.Interval.Duration < minSyncInterval {
+		return werror.NewInvalidError(fmt.Sprintf("interval must be at least %s", minSyncInterval), fieldInterval)
+	}
+	return v.checkKubeconfigSecret(policy.Namespace, policy.Spec.KubeconfigSecretName)
+}
+
+// checkKubeconfigSecret validates the secret holds a kubeconfig with inline credentials only, the
+// source cluster itself is only reached by the controller.
+func (v *imageSyncPolicyValidator) checkKubeconfigSecret(namespace, name string) error {
+	if name == "" {
+		return werror.NewInvalidError("kubeconfig secret name is required", fieldKubeconfigSecretName)
+	}
+	secret, err := v.secretCache.Get(namespace, name)
+	if err != nil {
+		return werror.NewInvalidError(fmt.Sprintf("failed to get the kubeconfig secret %s/%s: %v", namespace, name, err), fieldKubeconfigSecretName)
+	}
+	kubeconfig := secret.Data[remote.KubeconfigSecretKey]
+	if len(kubeconfig) == 0 {
+		return werror.NewInvalidError(fmt.Sprintf("the kubeconfig secret %s/%s has no %s key", namespace, name, remote.KubeconfigSecretKey), fieldKubeconfigSecretName)
+	}
+	if err := remote.ValidateKubeconfig(kubeconfig); err != nil {
+		return werror.NewInvalidError(fmt.Sprintf("invalid kubeconfig in secret %s/%s: %v", namespace, name, err), fieldKubeconfigSecretName)
+	}
+	if _, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig); err != nil {
+		return werror.NewInvalidError(fmt.Sprintf("failed to parse the kubeconfig in secret %s/%s: %v", namespace, name, err), fieldKubeconfigSecretName)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package imagesyncpolicy
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: source
+  cluster:
+    server: https://source.example.com:6443
+contexts:
+- name: source
+  context:
+    cluster: source
+    user: source
+current-context: source
+users:
+- name: source
+  user:
+    token: token
+`
+
+func newKubeconfigSecret(name string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func newPolicy() *v1beta1.ImageSyncPolicy {
+	return &v1beta1.ImageSyncPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"},
+		Spec: v1beta1.ImageSyncPolicySpec{
+			KubeconfigSecretName: "source",
+			SourceNamespace:      "images",
+		},
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      func() *v1beta1.ImageSyncPolicy
+		errContains string
+	}{
+		{
+			name:   "valid policy",
+			policy: newPolicy,
+		},
+		{
+			name: "valid selector and interval",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"os": "linux"}}
+				policy.Spec.Interval = &metav1.Duration{Duration: time.Hour}
+				return policy
+			},
+		},
+		{
+			name: "missing source namespace",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.SourceNamespace = ""
+				return policy
+			},
+			errContains: "source namespace is required",
+		},
+		{
+			name: "invalid selector",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.Selector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "os", Operator: "Near"}},
+				}
+				return policy
+			},
+			errContains: "not a valid label selector operator",
+		},
+		{
+			name: "interval too short",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.Interval = &metav1.Duration{Duration: 10 * time.Second}
+				return policy
+			},
+			errContains: "interval must be at least 1m0s",
+		},
+		{
+			name: "negative interval",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.Interval = &metav1.Duration{Duration: -time.Hour}
+				return policy
+			},
+			errContains: "interval must be at least 1m0s",
+		},
+		{
+			name: "missing kubeconfig secret name",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.KubeconfigSecretName = ""
+				return policy
+			},
+			errContains: "kubeconfig secret name is required",
+		},
+		{
+			name: "missing kubeconfig secret",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.KubeconfigSecretName = "missing"
+				return policy
+			},
+			errContains: "failed to get the kubeconfig secret default/missing",
+		},
+		{
+			name: "secret without kubeconfig",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.KubeconfigSecretName = "empty"
+				return policy
+			},
+			errContains: "the kubeconfig secret default/empty has no kubeconfig key",
+		},
+		{
+			name: "invalid kubeconfig",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.KubeconfigSecretName = "invalid"
+				return policy
+			},
+			errContains: "invalid kubeconfig in secret default/invalid",
+		},
+		{
+			name: "kubeconfig with exec plugin",
+			policy: func() *v1beta1.ImageSyncPolicy {
+				policy := newPolicy()
+				policy.Spec.KubeconfigSecretName = "exec"
+				return policy
+			},
+			errContains: "user source uses an exec plugin",
+		},
+	}
+
+	clientset := fake.NewSimpleClientset([]runtime.Object{
+		newKubeconfigSecret("source", map[string][]byte{"kubeconfig": []byte(testKubeconfig)}),
+		newKubeconfigSecret("empty", map[string][]byte{"token": []byte("token")}),
+		newKubeconfigSecret("invalid", map[string][]byte{"kubeconfig": []byte("not a kubeconfig")}),
+		newKubeconfigSecret("exec", map[string][]byte{"kubeconfig": []byte(strings.Replace(testKubeconfig, "    token: token", "    exec:\n      command: sh", 1))}),
+	}...)
+	validator := NewValidator(fakeclients.SecretCache(clientset.CoreV1().Secrets))
+	for _, tc := range tests {
+		err := validator.Create(nil, tc.policy())
+		if tc.errContains == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.errContains, tc.name)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newKubeconfigSecret("source", map[string][]byte{"kubeconfig": []byte(testKubeconfig)}))
+	validator := NewValidator(fakeclients.SecretCache(clientset.CoreV1().Secrets))
+
+	oldPolicy := newPolicy()
+	newPolicy := oldPolicy.DeepCopy()
+	newPolicy.Spec.Prune = true
+	assert.NoError(t, validator.Update(nil, oldPolicy, newPolicy))
+
+	newPolicy.Spec.KubeconfigSecretName = "missing"
+	assert.ErrorContains(t, validator.Update(nil, oldPolicy, newPolicy), "failed to get the kubeconfig secret")
+
+	// a policy being deleted is not validated, its secret may be gone already
+	newPolicy.DeletionTimestamp = &metav1.Time{}
+	assert.NoError(t, validator.Update(nil, oldPolicy, newPolicy))
+}
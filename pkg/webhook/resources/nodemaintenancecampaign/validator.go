@@ -0,0 +1,110 @@
+package nodemaintenancecampaign
+
+import (
+	"fmt"
+	"reflect"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldSpec         = "spec"
+	fieldNodes        = "spec.nodes"
+	fieldNodeSelector = "spec.nodeSelector"
+	fieldConcurrency  = "spec.concurrency"
+	fieldPreHook      = "spec.preHook"
+	fieldPostHook     = "spec.postHook"
+)
+
+func NewValidator(campaignCache ctlharvesterv1.NodeMaintenanceCampaignCache) types.Validator {
+	return &nodeMaintenanceCampaignValidator{
+		campaignCache: campaignCache,
+	}
+}
+
+type nodeMaintenanceCampaignValidator struct {
+	types.DefaultValidator
+	campaignCache ctlharvesterv1.NodeMaintenanceCampaignCache
+}
+
+func (v *nodeMaintenanceCampaignValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.NodeMaintenanceCampaignResourceName},
+		Scope:      admissionregv1.ClusterScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.NodeMaintenanceCampaign{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+func (v *nodeMaintenanceCampaignValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	campaign := newObj.(*v1beta1.NodeMaintenanceCampaign)
+
+	if len(campaign.Spec.Nodes) == 0 && campaign.Spec.NodeSelector == nil {
+		return werror.NewInvalidError("either nodes or nodeSelector is required", fieldNodes)
+	}
+	if campaign.Spec.NodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(campaign.Spec.NodeSelector); err != nil {
+			return werror.NewInvalidError(fmt.Sprintf("invalid node selector: %v", err), fieldNodeSelector)
+		}
+	}
+	if campaign.Spec.Concurrency < 1 {
+		return werror.NewInvalidError("concurrency must be at least 1", fieldConcurrency)
+	}
+	if err := validateHook(campaign.Spec.PreHook, fieldPreHook); err != nil {
+		return err
+	}
+	if err := validateHook(campaign.Spec.PostHook, fieldPostHook); err != nil {
+		return err
+	}
+
+	// the campaigns would put their nodes into maintenance mode regardless of each other
+	campaigns, err := v.campaignCache.List(labels.Everything())
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	for _, existing := range campaigns {
+		// a campaign which has not been initialized yet is about to run
+		switch existing.Status.Phase {
+		case v1beta1.NodeMaintenanceCampaignPhaseSucceeded, v1beta1.NodeMaintenanceCampaignPhaseFailed:
+		default:
+			return werror.NewConflict(fmt.Sprintf("node maintenance campaign %s is still running", existing.Name))
+		}
+	}
+	return nil
+}
+
+func (v *nodeMaintenanceCampaignValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+	oldCampaign := oldObj.(*v1beta1.NodeMaintenanceCampaign)
+	newCampaign := newObj.(*v1beta1.NodeMaintenanceCampaign)
+
+	if newCampaign.DeletionTimestamp == nil && !reflect.DeepEqual(oldCampaign.Spec, newCampaign.Spec) {
+		return werror.NewInvalidError("the spec of a node maintenance campaign is immutable, create a new campaign instead", fieldSpec)
+	}
+	return nil
+}
+
+func validateHook(hook *v1beta1.NodeMaintenanceHook, field string) error {
+	if hook == nil {
+		return nil
+	}
+	if hook.Image == "" {
+		return werror.NewInvalidError("image is required", field+".image")
+	}
+	if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+		return werror.NewInvalidError("timeout must be positive", field+".timeout")
+	}
+	return nil
+}
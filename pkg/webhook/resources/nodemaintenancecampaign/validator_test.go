@@ -0,0 +1,80 @@
+package nodemaintenancecampaign
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        v1beta1.NodeMaintenanceCampaignSpec
+		existing    *v1beta1.NodeMaintenanceCampaign
+		errContains string
+	}{
+		{
+			name: "accepts campaign",
+			spec: v1beta1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}, Concurrency: 1},
+			existing: &v1beta1.NodeMaintenanceCampaign{
+				ObjectMeta: metav1.ObjectMeta{Name: "done"},
+				Status:     v1beta1.NodeMaintenanceCampaignStatus{Phase: v1beta1.NodeMaintenanceCampaignPhaseSucceeded},
+			},
+		},
+		{
+			name:        "rejects campaign without nodes",
+			spec:        v1beta1.NodeMaintenanceCampaignSpec{Concurrency: 1},
+			errContains: "either nodes or nodeSelector is required",
+		},
+		{
+			name:        "rejects zero concurrency",
+			spec:        v1beta1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}},
+			errContains: "concurrency must be at least 1",
+		},
+		{
+			name:        "rejects hook without image",
+			spec:        v1beta1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}, Concurrency: 1, PreHook: &v1beta1.NodeMaintenanceHook{}},
+			errContains: "image is required",
+		},
+		{
+			name: "rejects campaign while another one is running",
+			spec: v1beta1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}, Concurrency: 1},
+			existing: &v1beta1.NodeMaintenanceCampaign{
+				ObjectMeta: metav1.ObjectMeta{Name: "running"},
+				Status:     v1beta1.NodeMaintenanceCampaignStatus{Phase: v1beta1.NodeMaintenanceCampaignPhaseRunning},
+			},
+			errContains: "node maintenance campaign running is still running",
+		},
+		{
+			name:        "rejects campaign while another one is not initialized yet",
+			spec:        v1beta1.NodeMaintenanceCampaignSpec{Nodes: []string{"node1"}, Concurrency: 1},
+			existing:    &v1beta1.NodeMaintenanceCampaign{ObjectMeta: metav1.ObjectMeta{Name: "new"}},
+			errContains: "node maintenance campaign new is still running",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			if tc.existing != nil {
+				assert.NoError(t, clientset.Tracker().Add(tc.existing))
+			}
+			validator := NewValidator(fakeclients.NodeMaintenanceCampaignCache(clientset.HarvesterhciV1beta1().NodeMaintenanceCampaigns))
+
+			err := validator.Create(nil, &v1beta1.NodeMaintenanceCampaign{
+				ObjectMeta: metav1.ObjectMeta{Name: "firmware"},
+				Spec:       tc.spec,
+			})
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package rebalancepolicy
+
+import (
+	"fmt"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldNodeSelector = "spec.nodeSelector"
+	fieldWeights      = "spec.cpuWeight"
+	fieldInterval     = "spec.interval"
+)
+
+func NewValidator(policyCache ctlharvesterv1.RebalancePolicyCache) types.Validator {
+	return &rebalancePolicyValidator{
+		policyCache: policyCache,
+	}
+}
+
+type rebalancePolicyValidator struct {
+	types.DefaultValidator
+	policyCache ctlharvesterv1.RebalancePolicyCache
+}
+
+func (v *rebalancePolicyValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.RebalancePolicyResourceName},
+		Scope:      admissionregv1.ClusterScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.RebalancePolicy{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+// Create allows a single policy, the limit of concurrent migrations and the blocked checks are
+// per policy and several policies would migrate the same VMs at once.
+func (v *rebalancePolicyValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	policy := newObj.(*v1beta1.RebalancePolicy)
+	policies, err := v.policyCache.List(labels.Everything())
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	for _, existing := range policies {
+		if existing.Name != policy.Name {
+			return werror.NewInvalidError(fmt.Sprintf("only one rebalance policy is allowed, %s already exists", existing.Name), "metadata.name")
+		}
+	}
+	return validateSpec(&policy.Spec)
+}
+
+func (v *rebalancePolicyValidator) Update(_ *types.Request, _ runtime.Object, newObj runtime.Object) error {
+	return validateSpec(&newObj.(*v1beta1.RebalancePolicy).Spec)
+}
+
+func validateSpec(spec *v1beta1.RebalancePolicySpec) error {
+	if spec.CPUWeight+spec.MemoryWeight <= 0 {
+		return werror.NewInvalidError("either the CPU or the memory weight must be positive", fieldWeights)
+	}
+	if spec.NodeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.NodeSelector); err != nil {
+			return werror.NewInvalidError(err.Error(), fieldNodeSelector)
+		}
+	}
+	if spec.Interval != nil && spec.Interval.Duration <= 0 {
+		return werror.NewInvalidError("must be positive", fieldInterval)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package rebalancepolicy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newPolicy(name string) *v1beta1.RebalancePolicy {
+	return &v1beta1.RebalancePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1beta1.RebalancePolicySpec{
+			CPUWeight:    1,
+			MemoryWeight: 1,
+		},
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    []runtime.Object
+		policy      func() *v1beta1.RebalancePolicy
+		errContains string
+	}{
+		{
+			name:   "first policy",
+			policy: func() *v1beta1.RebalancePolicy { return newPolicy("default") },
+		},
+		{
+			name:        "second policy",
+			existing:    []runtime.Object{newPolicy("default")},
+			policy:      func() *v1beta1.RebalancePolicy { return newPolicy("other") },
+			errContains: "only one rebalance policy is allowed",
+		},
+		{
+			name: "no weight",
+			policy: func() *v1beta1.RebalancePolicy {
+				policy := newPolicy("default")
+				policy.Spec.CPUWeight, policy.Spec.MemoryWeight = 0, 0
+				return policy
+			},
+			errContains: "weight must be positive",
+		},
+		{
+			name: "invalid node selector",
+			policy: func() *v1beta1.RebalancePolicy {
+				policy := newPolicy("default")
+				policy.Spec.NodeSelector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "zone", Operator: "Near"}},
+				}
+				return policy
+			},
+			errContains: "not a valid label selector operator",
+		},
+		{
+			name: "negative interval",
+			policy: func() *v1beta1.RebalancePolicy {
+				policy := newPolicy("default")
+				policy.Spec.Interval = &metav1.Duration{Duration: -time.Minute}
+				return policy
+			},
+			errContains: "must be positive",
+		},
+	}
+
+	for _, tc := range tests {
+		clientset := fake.NewSimpleClientset(tc.existing...)
+		validator := NewValidator(fakeclients.RebalancePolicyCache(clientset.HarvesterhciV1beta1().RebalancePolicies))
+		err := validator.Create(nil, tc.policy())
+		if tc.errContains == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.errContains, tc.name)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newPolicy("default"))
+	validator := NewValidator(fakeclients.RebalancePolicyCache(clientset.HarvesterhciV1beta1().RebalancePolicies))
+
+	oldPolicy := newPolicy("default")
+	newPolicy := oldPolicy.DeepCopy()
+	newPolicy.Spec.Paused = true
+	assert.NoError(t, validator.Update(nil, oldPolicy, newPolicy))
+
+	newPolicy.Spec.CPUWeight, newPolicy.Spec.MemoryWeight = 0, 0
+	assert.ErrorContains(t, validator.Update(nil, oldPolicy, newPolicy), "weight must be positive")
+}
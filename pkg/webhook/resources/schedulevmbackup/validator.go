@@ -9,6 +9,7 @@ import (
 	"github.com/robfig/cron"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
@@ -17,13 +18,18 @@ import (
 	backuputil "github.com/harvester/harvester/pkg/util/backup"
 	werror "github.com/harvester/harvester/pkg/webhook/error"
 	"github.com/harvester/harvester/pkg/webhook/indexeres"
+	"github.com/harvester/harvester/pkg/webhook/resources/backupverification"
 	"github.com/harvester/harvester/pkg/webhook/types"
+	webhookutil "github.com/harvester/harvester/pkg/webhook/util"
 )
 
 const (
+	fieldCopy       = "spec.copy"
 	fieldCron       = "spec.cron"
 	fieldMaxFailure = "spec.maxFailure"
+	fieldRetention  = "spec.retentionPolicy"
 	fieldSuspend    = "spec.suspend"
+	fieldVerify     = "spec.verification"
 	fieldVMBackup   = "spec.vmbackup"
 
 	minCronGranularity = time.Hour
@@ -35,17 +41,23 @@ type scheuldeVMBackupValidator struct {
 	settingCache   ctlharvesterv1.SettingCache
 	secretCache    ctlv1.SecretCache
 	svmbackupCache ctlharvesterv1.ScheduleVMBackupCache
+	btCache        ctlharvesterv1.BackupTargetCache
+	sar            authorizationv1client.SubjectAccessReviewInterface
 }
 
 func NewValidator(
 	settingCache ctlharvesterv1.SettingCache,
 	secretCache ctlv1.SecretCache,
 	svmbackupCache ctlharvesterv1.ScheduleVMBackupCache,
+	btCache ctlharvesterv1.BackupTargetCache,
+	sar authorizationv1client.SubjectAccessReviewInterface,
 ) types.Validator {
 	return &scheuldeVMBackupValidator{
 		settingCache:   settingCache,
 		secretCache:    secretCache,
 		svmbackupCache: svmbackupCache,
+		btCache:        btCache,
+		sar:            sar,
 	}
 }
 
@@ -64,32 +76,125 @@ func (v *scheuldeVMBackupValidator) Resource() types.Resource {
 	}
 }
 
-func (v *scheuldeVMBackupValidator) checkTargetHealth() error {
-	targetSetting, err := v.settingCache.Get(settings.BackupTargetSettingName)
+func (v *scheuldeVMBackupValidator) checkTargetHealth(svmbackup *v1beta1.ScheduleVMBackup) error {
+	if backupTargetName := svmbackup.Spec.VMBackupSpec.BackupTargetName; backupTargetName != "" {
+		backupType := svmbackup.Spec.VMBackupSpec.Type
+		if !backupType.UsesRemoteBackupTarget() {
+			return fmt.Errorf("%s backups can't be stored in backup target %s", backupType, backupTargetName)
+		}
+		target, err := backuputil.GetBackupTarget(v.btCache, backupTargetName)
+		if err != nil {
+			return err
+		}
+		if backupType == v1beta1.Backup && target.EncryptionKeySecret != "" {
+			return fmt.Errorf("backup target %s is encrypted, only %s backups can be stored in it", backupTargetName, v1beta1.SnapshotExport)
+		}
+		_, err = backuputil.GetBackupStoreDriver(v.secretCache, target)
+		return err
+	}
+
+	target, err := v.getBackupTargetSetting()
 	if err != nil {
 		return err
 	}
 
+	if _, err := backuputil.GetBackupStoreDriver(v.secretCache, target); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *scheuldeVMBackupValidator) getBackupTargetSetting() (*settings.BackupTarget, error) {
+	targetSetting, err := v.settingCache.Get(settings.BackupTargetSettingName)
+	if err != nil {
+		return nil, err
+	}
+
 	if targetSetting.Value == "" {
-		return fmt.Errorf("setting %s is empty", settings.BackupTargetSettingName)
+		return nil, fmt.Errorf("setting %s is empty", settings.BackupTargetSettingName)
 	}
 
 	target, err := settings.DecodeBackupTarget(targetSetting.Value)
 	if err != nil {
-		return fmt.Errorf("can't decode setting %s value %s, error: %w", settings.BackupTargetSettingName, targetSetting.Value, err)
+		return nil, fmt.Errorf("can't decode setting %s value %s, error: %w", settings.BackupTargetSettingName, targetSetting.Value, err)
 	}
 
 	if target.IsDefaultBackupTarget() {
-		return fmt.Errorf("setting %s is not set", settings.BackupTargetSettingName)
+		return nil, fmt.Errorf("setting %s is not set", settings.BackupTargetSettingName)
 	}
+	return target, nil
+}
 
-	if _, err := backuputil.GetBackupStoreDriver(v.secretCache, target); err != nil {
-		return err
+// checkCopy validates the backup target the scheduled VM backups are copied to.
+func (v *scheuldeVMBackupValidator) checkCopy(svmbackup *v1beta1.ScheduleVMBackup) error {
+	copySpec := svmbackup.Spec.Copy
+	if copySpec == nil {
+		return nil
+	}
+
+	if svmbackup.Spec.VMBackupSpec.Type == v1beta1.Snapshot {
+		return werror.NewInvalidError("snapshots are not stored in a backup target and can't be copied", fieldCopy)
+	}
+	if copySpec.BackupTargetName == svmbackup.Spec.VMBackupSpec.BackupTargetName {
+		return werror.NewInvalidError("backups can't be copied to the backup target they are stored in", fieldCopy)
 	}
 
+	if copySpec.BackupTargetName != "" {
+		if _, err := v.btCache.Get(copySpec.BackupTargetName); err != nil {
+			return werror.NewInvalidError(err.Error(), fieldCopy)
+		}
+		return nil
+	}
+	if _, err := v.getBackupTargetSetting(); err != nil {
+		return werror.NewInvalidError(err.Error(), fieldCopy)
+	}
 	return nil
 }
 
+// checkMaxFailure keeps the failed backups, which are only cleared after a
+// successful one, fewer than the backups the schedule keeps. The retention
+// policy replaces `.spec.retain` when it's set.
+func checkMaxFailure(svmbackup *v1beta1.ScheduleVMBackup) error {
+	if policy := svmbackup.Spec.RetentionPolicy; policy != nil {
+		if svmbackup.Spec.MaxFailure >= policy.Hourly+policy.Daily+policy.Weekly+policy.Monthly {
+			return werror.NewInvalidError("max failure should be less than the backups kept by the retention policy", fieldMaxFailure)
+		}
+		return nil
+	}
+
+	if svmbackup.Spec.MaxFailure >= svmbackup.Spec.Retain {
+		return werror.NewInvalidError("max failure should be less than retain", fieldMaxFailure)
+	}
+	return nil
+}
+
+func checkRetentionPolicy(svmbackup *v1beta1.ScheduleVMBackup) error {
+	policy := svmbackup.Spec.RetentionPolicy
+	if policy == nil {
+		return nil
+	}
+
+	if policy.Hourly < 0 || policy.Daily < 0 || policy.Weekly < 0 || policy.Monthly < 0 {
+		return werror.NewInvalidError("retention counts can't be negative", fieldRetention)
+	}
+	if policy.Hourly+policy.Daily+policy.Weekly+policy.Monthly == 0 {
+		return werror.NewInvalidError("retention policy should keep at least one tier", fieldRetention)
+	}
+	return nil
+}
+
+func checkVerification(svmbackup *v1beta1.ScheduleVMBackup) error {
+	if svmbackup.Spec.Verification == nil {
+		return nil
+	}
+
+	if svmbackup.Spec.VMBackupSpec.Type == v1beta1.Snapshot {
+		return werror.NewInvalidError("snapshots can't be restored in a sandbox namespace to be verified", fieldVerify)
+	}
+	return backupverification.ValidateTemplate(svmbackup.Spec.Verification, fieldVerify)
+}
+
 func cronGranularityCheck(v *scheuldeVMBackupValidator, svmbackup *v1beta1.ScheduleVMBackup) error {
 	granularity, err := util.GetCronGranularity(svmbackup)
 	if err != nil {
@@ -124,11 +229,11 @@ func cronGranularityCheck(v *scheuldeVMBackupValidator, svmbackup *v1beta1.Sched
 	return nil
 }
 
-func (v *scheuldeVMBackupValidator) Create(_ *types.Request, newObj runtime.Object) error {
+func (v *scheuldeVMBackupValidator) Create(request *types.Request, newObj runtime.Object) error {
 	newSVMBackup := newObj.(*v1beta1.ScheduleVMBackup)
 
-	if newSVMBackup.Spec.MaxFailure >= newSVMBackup.Spec.Retain {
-		return werror.NewInvalidError("max failure should be less than retain", fieldMaxFailure)
+	if err := checkMaxFailure(newSVMBackup); err != nil {
+		return err
 	}
 
 	if _, err := cron.ParseStandard(newSVMBackup.Spec.Cron); err != nil {
@@ -141,23 +246,49 @@ func (v *scheuldeVMBackupValidator) Create(_ *types.Request, newObj runtime.Obje
 		}
 	}
 
+	if err := webhookutil.ValidateBackupHooks(newSVMBackup.Spec.VMBackupSpec.Hooks, fieldVMBackup+".hooks"); err != nil {
+		return err
+	}
+	// the VM backup spec can't be changed, so the hooks are only checked here
+	if webhookutil.HasBackupHooks(newSVMBackup.Spec.VMBackupSpec.Hooks) {
+		if err := webhookutil.CheckBackupHooksPermission(v.sar, request, newSVMBackup.Namespace,
+			newSVMBackup.Spec.VMBackupSpec.Source.Name, fieldVMBackup+".hooks"); err != nil {
+			return err
+		}
+	}
+
+	if err := v.checkCopy(newSVMBackup); err != nil {
+		return err
+	}
+
+	if err := checkRetentionPolicy(newSVMBackup); err != nil {
+		return err
+	}
+
+	if err := checkVerification(newSVMBackup); err != nil {
+		return err
+	}
+
 	srcVM := fmt.Sprintf("%s/%s", newSVMBackup.Namespace, newSVMBackup.Spec.VMBackupSpec.Source.Name)
 	svmbackups, err := v.svmbackupCache.GetByIndex(indexeres.ScheduleVMBackupBySourceVM, srcVM)
 	if err != nil {
 		return err
 	}
 
-	if len(svmbackups) != 0 {
-		//we should only find one existing schedule
-		msg := fmt.Sprintf("VM %s already has %s schedule", srcVM, svmbackups[0].Spec.VMBackupSpec.Type)
-		return werror.NewInvalidError(msg, fieldVMBackup)
+	// a VM can have one schedule per backup target, e.g. daily backups to a
+	// local target and weekly backups to an offsite one
+	for _, s := range svmbackups {
+		if s.Spec.VMBackupSpec.BackupTargetName == newSVMBackup.Spec.VMBackupSpec.BackupTargetName {
+			msg := fmt.Sprintf("VM %s already has %s schedule", srcVM, s.Spec.VMBackupSpec.Type)
+			return werror.NewInvalidError(msg, fieldVMBackup)
+		}
 	}
 
 	if newSVMBackup.Spec.VMBackupSpec.Type == v1beta1.Snapshot {
 		return nil
 	}
 
-	if err := v.checkTargetHealth(); err != nil {
+	if err := v.checkTargetHealth(newSVMBackup); err != nil {
 		return werror.NewInvalidError(err.Error(), fieldSuspend)
 	}
 
@@ -176,8 +307,8 @@ func (v *scheuldeVMBackupValidator) Update(_ *types.Request, oldObj runtime.Obje
 		return werror.NewInvalidError("source vm can't be changed", fieldVMBackup)
 	}
 
-	if newSVMBackup.Spec.MaxFailure >= newSVMBackup.Spec.Retain {
-		return werror.NewInvalidError("max failure should be less than retain", fieldMaxFailure)
+	if err := checkMaxFailure(newSVMBackup); err != nil {
+		return err
 	}
 
 	if _, err := cron.ParseStandard(newSVMBackup.Spec.Cron); err != nil {
@@ -190,6 +321,20 @@ func (v *scheuldeVMBackupValidator) Update(_ *types.Request, oldObj runtime.Obje
 		}
 	}
 
+	if !reflect.DeepEqual(oldSVMBackup.Spec.Copy, newSVMBackup.Spec.Copy) {
+		if err := v.checkCopy(newSVMBackup); err != nil {
+			return err
+		}
+	}
+
+	if err := checkRetentionPolicy(newSVMBackup); err != nil {
+		return err
+	}
+
+	if err := checkVerification(newSVMBackup); err != nil {
+		return err
+	}
+
 	//not updated to resume schedule
 	if !oldSVMBackup.Spec.Suspend || newSVMBackup.Spec.Suspend {
 		return nil
@@ -199,7 +344,7 @@ func (v *scheuldeVMBackupValidator) Update(_ *types.Request, oldObj runtime.Obje
 		return nil
 	}
 
-	if err := v.checkTargetHealth(); err != nil {
+	if err := v.checkTargetHealth(newSVMBackup); err != nil {
 		return werror.NewInvalidError(err.Error(), fieldSuspend)
 	}
 
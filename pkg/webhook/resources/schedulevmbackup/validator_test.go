@@ -0,0 +1,90 @@
+package schedulevmbackup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+func TestCreateWithHooksWithoutConsolePermission(t *testing.T) {
+	request := &types.Request{
+		Request: &webhook.Request{
+			Context: context.Background(),
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "demo"},
+			},
+		},
+	}
+	k8sclientset := corefake.NewClientset()
+	k8sclientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		sar.Status.Allowed = sar.Spec.ResourceAttributes.Subresource != "console"
+		return true, sar, nil
+	})
+	validator := &scheuldeVMBackupValidator{sar: k8sclientset.AuthorizationV1().SubjectAccessReviews()}
+
+	svmbackup := &v1beta1.ScheduleVMBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: "default", Annotations: map[string]string{util.AnnotationSVMBackupSkipCronCheck: "true"}},
+		Spec: v1beta1.ScheduleVMBackupSpec{
+			Cron:   "0 0 * * *",
+			Retain: 3,
+			VMBackupSpec: v1beta1.VirtualMachineBackupSpec{
+				Source: corev1.TypedLocalObjectReference{Name: "vm"},
+				Hooks: &v1beta1.BackupHooks{
+					PreSnapshot: []v1beta1.BackupHook{{Name: "freeze", Command: []string{"fsfreeze", "-f", "/data"}}},
+				},
+			},
+		},
+	}
+	err := validator.Create(request, svmbackup)
+	assert.ErrorContains(t, err, "user demo has no permission to open the console of VM default/vm")
+}
+
+func TestCheckMaxFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1beta1.ScheduleVMBackupSpec
+		wantErr string
+	}{
+		{
+			name:    "max failure not less than retain",
+			spec:    v1beta1.ScheduleVMBackupSpec{Retain: 3, MaxFailure: 3},
+			wantErr: "max failure should be less than retain",
+		},
+		{
+			name: "retention policy keeps more backups than retain",
+			spec: v1beta1.ScheduleVMBackupSpec{Retain: 3, MaxFailure: 4,
+				RetentionPolicy: &v1beta1.ScheduleVMBackupRetentionPolicy{Daily: 7, Weekly: 4}},
+		},
+		{
+			name: "max failure not less than the backups kept by the retention policy",
+			spec: v1beta1.ScheduleVMBackupSpec{Retain: 8, MaxFailure: 4,
+				RetentionPolicy: &v1beta1.ScheduleVMBackupRetentionPolicy{Daily: 2, Weekly: 2}},
+			wantErr: "max failure should be less than the backups kept by the retention policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMaxFailure(&v1beta1.ScheduleVMBackup{Spec: tt.spec})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
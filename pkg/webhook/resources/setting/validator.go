@@ -1,7 +1,6 @@
 package setting
 
 import (
-	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -24,7 +23,6 @@ import (
 	"github.com/harvester/go-common/ds"
 	networkv1 "github.com/harvester/harvester-network-controller/pkg/apis/network.harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester-network-controller/pkg/utils"
-	"github.com/longhorn/backupstore"
 	_ "github.com/longhorn/backupstore/nfs" //nolint
 	_ "github.com/longhorn/backupstore/s3"  //nolint
 	lhv1beta2 "github.com/longhorn/longhorn-manager/k8s/pkg/apis/longhorn/v1beta2"
@@ -115,6 +113,8 @@ var validateSettingFuncs = map[string]validateSettingFunc{
 	settings.MaxHotplugRatioSettingName:                        validateMaxHotplugRatio,
 	settings.LHIMResourcesSettingName:                          validateLHIMResources,
 	settings.LonghornV2DataEngineMemorySizeSettingName:         validateLonghornV2DataEngineMemorySize,
+	settings.ImageVerificationPolicySettingName:                validateImageVerificationPolicy,
+	settings.ImageRetentionPolicySettingName:                   validateImageRetentionPolicy,
 }
 
 type validateSettingUpdateFunc func(request *types.Request, oldSetting *v1beta1.Setting, newSetting *v1beta1.Setting) error
@@ -138,6 +138,8 @@ var validateSettingUpdateFuncs = map[string]validateSettingUpdateFunc{
 	settings.AdditionalGuestMemoryOverheadRatioName:            validateUpdateAdditionalGuestMemoryOverheadRatio,
 	settings.MaxHotplugRatioSettingName:                        validateUpdateMaxHotplugRatio,
 	settings.LonghornV2DataEngineMemorySizeSettingName:         validateUpdateLonghornV2DataEngineMemorySize,
+	settings.ImageVerificationPolicySettingName:                validateUpdateImageVerificationPolicy,
+	settings.ImageRetentionPolicySettingName:                   validateUpdateImageRetentionPolicy,
 }
 
 type validateSettingDeleteFunc func(setting *v1beta1.Setting) error
@@ -516,6 +518,18 @@ func (v *settingValidator) validateBackupTargetFields(target *settings.BackupTar
 	return nil
 }
 
+// validateBackupTargetEncryptionKey checks the secret holding the key to encrypt the backups.
+func (v *settingValidator) validateBackupTargetEncryptionKey(target *settings.BackupTarget) error {
+	if target.EncryptionKeySecret == "" {
+		return nil
+	}
+
+	if _, err := backuputil.GetEncryptionKey(v.secretCache, target); err != nil {
+		return werror.NewInvalidError(err.Error(), settings.KeywordValue)
+	}
+	return nil
+}
+
 func (v *settingValidator) validateUpdateBackupTarget(_ *types.Request, _ *v1beta1.Setting, newSetting *v1beta1.Setting) error {
 	return v.validateBackupTarget(newSetting)
 }
@@ -587,29 +601,37 @@ func (v *settingValidator) validateBackupTarget(setting *v1beta1.Setting) error
 		return err
 	}
 
+	if err = v.validateBackupTargetEncryptionKey(target); err != nil {
+		return err
+	}
+
+	// The S3 driver reads its credentials from the environment, they're only
+	// set while the driver of the backup target holds the environment lock.
+	var credentials map[string][]byte
 	if target.Type == settings.S3BackupType {
-		// Set OS environment variables for S3
-		os.Setenv(util.AWSAccessKey, target.AccessKeyID)
-		os.Setenv(util.AWSSecretKey, target.SecretAccessKey)
-		os.Setenv(util.AWSEndpoints, target.Endpoint)
 		if err := v.customizeTransport(); err != nil {
 			return err
 		}
+		credentials = map[string][]byte{
+			util.AWSAccessKey:       []byte(target.AccessKeyID),
+			util.AWSSecretKey:       []byte(target.SecretAccessKey),
+			util.AWSEndpoints:       []byte(target.Endpoint),
+			util.VirtualHostedStyle: []byte(strconv.FormatBool(target.VirtualHostedStyle)),
+		}
+		caSetting, err := v.settingCache.Get(settings.AdditionalCASettingName)
+		if err != nil {
+			return fmt.Errorf("failed to get additional CA setting: %v", err)
+		}
+		if caSetting.Value != "" {
+			credentials[util.AWSCERT] = []byte(caSetting.Value)
+		}
 	}
 
 	// GetBackupStoreDriver tests whether the driver can List objects, so we don't need to do it again here.
 	// S3: https://github.com/longhorn/backupstore/blob/56ddc538b85950b02c37432e4854e74f2647ca61/s3/s3.go#L38-L87
 	// NFS: https://github.com/longhorn/backupstore/blob/56ddc538b85950b02c37432e4854e74f2647ca61/nfs/nfs.go#L46-L81
-	endpoint := backuputil.ConstructEndpoint(target)
-
-	// There might be a goroutine leak if the driver doesn't end properly,
-	// Although we can pass ctx, but the underlying driver implementation doesn't support it.
-	// So we should be careful when using GetBackupStoreDriver function.
-	_, err = util.RunWithTimeoutAndResult(backuputil.ConnectBackupStoreTimeout, func(_ context.Context) (backupstore.BackupStoreDriver, error) {
-		return backupstore.GetBackupStoreDriver(endpoint)
-	})
-	if err != nil {
-		return werror.NewInvalidError("failed to connect to backup target, reason: "+err.Error(), settings.KeywordValue)
+	if _, err = backuputil.GetBackupStoreDriverWithCredentials(target, credentials); err != nil {
+		return werror.NewInvalidError(err.Error(), settings.KeywordValue)
 	}
 
 	return nil
@@ -637,7 +659,6 @@ func (v *settingValidator) customizeTransport() error {
 		return fmt.Errorf("failed to get additional CA setting: %v", err)
 	}
 	if caSetting.Value != "" {
-		os.Setenv(util.AWSCERT, caSetting.Value)
 		if ok := certs.AppendCertsFromPEM([]byte(caSetting.Value)); !ok {
 			return fmt.Errorf("failed to append custom certificates: %v", caSetting.Value)
 		}
@@ -2555,3 +2576,52 @@ func validateLonghornV2DataEngineMemorySize(newSetting *v1beta1.Setting) error {
 func validateUpdateLonghornV2DataEngineMemorySize(_ *types.Request, _ *v1beta1.Setting, newSetting *v1beta1.Setting) error {
 	return validateLonghornV2DataEngineMemorySize(newSetting)
 }
+
+func validateImageVerificationPolicy(setting *v1beta1.Setting) error {
+	if setting.Default != "" {
+		if _, err := settings.DecodeConfig[settings.ImageVerificationPolicyConfig](setting.Default); err != nil {
+			return werror.NewInvalidError(err.Error(), settings.KeywordDefault)
+		}
+	}
+
+	if setting.Value != "" {
+		if _, err := settings.DecodeConfig[settings.ImageVerificationPolicyConfig](setting.Value); err != nil {
+			return werror.NewInvalidError(err.Error(), settings.KeywordValue)
+		}
+	}
+	return nil
+}
+
+func validateUpdateImageVerificationPolicy(_ *types.Request, _ *v1beta1.Setting, newSetting *v1beta1.Setting) error {
+	return validateImageVerificationPolicy(newSetting)
+}
+
+func validateImageRetentionPolicy(setting *v1beta1.Setting) error {
+	if setting.Default != "" {
+		if err := validateImageRetentionPolicyValue(setting.Default); err != nil {
+			return werror.NewInvalidError(err.Error(), settings.KeywordDefault)
+		}
+	}
+
+	if setting.Value != "" {
+		if err := validateImageRetentionPolicyValue(setting.Value); err != nil {
+			return werror.NewInvalidError(err.Error(), settings.KeywordValue)
+		}
+	}
+	return nil
+}
+
+func validateImageRetentionPolicyValue(value string) error {
+	policy, err := settings.DecodeConfig[settings.ImageRetentionPolicyConfig](value)
+	if err != nil {
+		return err
+	}
+	if policy.Enabled && policy.UnusedDays < 1 {
+		return fmt.Errorf("unusedDays must be at least 1 when the policy is enabled")
+	}
+	return nil
+}
+
+func validateUpdateImageRetentionPolicy(_ *types.Request, _ *v1beta1.Setting, newSetting *v1beta1.Setting) error {
+	return validateImageRetentionPolicy(newSetting)
+}
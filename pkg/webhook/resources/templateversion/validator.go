@@ -12,6 +12,7 @@ import (
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	"github.com/harvester/harvester/pkg/ref"
 	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/util/vmtemplate"
 	werror "github.com/harvester/harvester/pkg/webhook/error"
 	"github.com/harvester/harvester/pkg/webhook/types"
 )
@@ -21,6 +22,7 @@ const (
 	fieldKeyPairIDs                     = "spec.keyPairIds"
 	fieldResourcesLimits                = "spec.vm.spec.template.spec.domain.resources.limits"
 	fieldVolumeClaimTemplatesAnnotation = "spec.vm.metadata.annotations[\"harvesterhci.io/volumeClaimTemplates\"]"
+	fieldParameters                     = "spec.parameters"
 )
 
 func NewValidator(templateCache ctlharvesterv1.VirtualMachineTemplateCache, templateVersionCache ctlharvesterv1.VirtualMachineTemplateVersionCache, keypairs ctlharvesterv1.KeyPairCache) types.Validator {
@@ -82,6 +84,10 @@ func (v *templateVersionValidator) Create(_ *types.Request, newObj runtime.Objec
 		return err
 	}
 
+	if err := vmtemplate.ValidateParameters(&vmTemplVersion.Spec); err != nil {
+		return werror.NewInvalidError(err.Error(), fieldParameters)
+	}
+
 	template := vmTemplVersion.Spec.VM.Spec.Template
 	if template != nil {
 		limits := template.Spec.Domain.Resources.Limits
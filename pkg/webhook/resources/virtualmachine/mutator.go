@@ -11,12 +11,14 @@ import (
 	"github.com/sirupsen/logrus"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/kubevirt/pkg/util/hardware"
 
+	harvesterv1 "github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
 	ctlcniv1 "github.com/harvester/harvester/pkg/generated/controllers/k8s.cni.cncf.io/v1"
 	ctlkubeovnv1 "github.com/harvester/harvester/pkg/generated/controllers/kubeovn.io/v1"
@@ -24,6 +26,7 @@ import (
 	"github.com/harvester/harvester/pkg/settings"
 	"github.com/harvester/harvester/pkg/util"
 	"github.com/harvester/harvester/pkg/util/network"
+	"github.com/harvester/harvester/pkg/util/placementpolicy"
 	"github.com/harvester/harvester/pkg/util/virtualmachine"
 	werror "github.com/harvester/harvester/pkg/webhook/error"
 	"github.com/harvester/harvester/pkg/webhook/types"
@@ -44,21 +47,24 @@ func NewMutator(
 	nad ctlcniv1.NetworkAttachmentDefinitionCache,
 	kubvirt ctlkubevirtv1.KubeVirtCache,
 	kubeovnSubnet ctlkubeovnv1.SubnetCache,
+	placementPolicy ctlharvesterv1.VMPlacementPolicyCache,
 ) types.Mutator {
 	return &vmMutator{
-		setting:       setting,
-		nad:           nad,
-		kubvirt:       kubvirt,
-		kubeovnSubnet: kubeovnSubnet,
+		setting:         setting,
+		nad:             nad,
+		kubvirt:         kubvirt,
+		kubeovnSubnet:   kubeovnSubnet,
+		placementPolicy: placementPolicy,
 	}
 }
 
 type vmMutator struct {
 	types.DefaultMutator
-	setting       ctlharvesterv1.SettingCache
-	nad           ctlcniv1.NetworkAttachmentDefinitionCache
-	kubvirt       ctlkubevirtv1.KubeVirtCache
-	kubeovnSubnet ctlkubeovnv1.SubnetCache
+	setting         ctlharvesterv1.SettingCache
+	nad             ctlcniv1.NetworkAttachmentDefinitionCache
+	kubvirt         ctlkubevirtv1.KubeVirtCache
+	kubeovnSubnet   ctlkubeovnv1.SubnetCache
+	placementPolicy ctlharvesterv1.VMPlacementPolicyCache
 }
 
 func (m *vmMutator) Resource() types.Resource {
@@ -96,6 +102,11 @@ func (m *vmMutator) Create(request *types.Request, newObj runtime.Object) (types
 		return nil, err
 	}
 
+	patchOps, err = m.patchPlacementPolicy(vm, patchOps)
+	if err != nil {
+		return nil, err
+	}
+
 	patchOps, err = m.patchTerminationGracePeriodSeconds(vm, patchOps)
 	if err != nil {
 		return nil, err
@@ -155,6 +166,11 @@ func (m *vmMutator) Update(request *types.Request, oldObj runtime.Object, newObj
 		return nil, err
 	}
 
+	patchOps, err = m.patchPlacementPolicy(newVM, patchOps)
+	if err != nil {
+		return nil, err
+	}
+
 	patchOps, err = m.patchTerminationGracePeriodSeconds(newVM, patchOps)
 	if err != nil {
 		return nil, err
@@ -469,6 +485,12 @@ func (m *vmMutator) patchAffinity(vm *kubevirtv1.VirtualMachine, patchOps types.
 		return patchOps, err
 	}
 
+	policy, err := m.getPlacementPolicy(vm)
+	if err != nil {
+		return patchOps, err
+	}
+	placementpolicy.ApplyToAffinity(affinity, policy)
+
 	// The .spec.affinity could not be like `{nodeAffinity:requireDuringSchedulingIgnoreDuringExecution:[]}` if there is not any rules.
 	if len(requiredNodeSelector.NodeSelectorTerms) == 0 {
 		if len(preferredNodeSelector) == 0 {
@@ -485,6 +507,64 @@ func (m *vmMutator) patchAffinity(vm *kubevirtv1.VirtualMachine, patchOps types.
 	return append(patchOps, fmt.Sprintf(`{"op":"replace","path":"/spec/template/spec/affinity","value":%s}`, string(bytes))), nil
 }
 
+// patchPlacementPolicy labels the VMI template with the placement policy of the VM, the pod affinity
+// terms of the policy select the virt-launcher pods of the members by this label. The topology spread
+// constraint of a Spread policy is added as well, the pod affinity terms are added by patchAffinity.
+func (m *vmMutator) patchPlacementPolicy(vm *kubevirtv1.VirtualMachine, patchOps types.PatchOps) (types.PatchOps, error) {
+	if vm == nil || vm.Spec.Template == nil {
+		return patchOps, nil
+	}
+
+	policy, err := m.getPlacementPolicy(vm)
+	if err != nil {
+		return patchOps, err
+	}
+
+	policyName := vm.Labels[util.LabelVMPlacementPolicy]
+	if vm.Spec.Template.ObjectMeta.Labels[util.LabelVMPlacementPolicy] != policyName {
+		metadata := vm.Spec.Template.ObjectMeta.DeepCopy()
+		if policyName == "" {
+			delete(metadata.Labels, util.LabelVMPlacementPolicy)
+		} else {
+			if metadata.Labels == nil {
+				metadata.Labels = map[string]string{}
+			}
+			metadata.Labels[util.LabelVMPlacementPolicy] = policyName
+		}
+		bytes, err := json.Marshal(metadata)
+		if err != nil {
+			return patchOps, err
+		}
+		patchOps = append(patchOps, fmt.Sprintf(`{"op":"replace","path":"/spec/template/metadata","value":%s}`, string(bytes)))
+	}
+
+	constraints := placementpolicy.ApplyToTopologySpreadConstraints(vm.Spec.Template.Spec.TopologySpreadConstraints, policy)
+	if !equality.Semantic.DeepEqual(constraints, vm.Spec.Template.Spec.TopologySpreadConstraints) {
+		bytes, err := json.Marshal(constraints)
+		if err != nil {
+			return patchOps, err
+		}
+		patchOps = append(patchOps, fmt.Sprintf(`{"op":"replace","path":"/spec/template/spec/topologySpreadConstraints","value":%s}`, string(bytes)))
+	}
+	return patchOps, nil
+}
+
+// getPlacementPolicy returns the placement policy the VM is a member of, or nil if the VM isn't a member of any policy.
+func (m *vmMutator) getPlacementPolicy(vm *kubevirtv1.VirtualMachine) (*harvesterv1.VMPlacementPolicy, error) {
+	name := vm.Labels[util.LabelVMPlacementPolicy]
+	if name == "" {
+		return nil, nil
+	}
+	policy, err := m.placementPolicy.Get(vm.Namespace, name)
+	if apierrors.IsNotFound(err) {
+		return nil, werror.NewBadRequest(fmt.Sprintf("placement policy %s/%s of the VM does not exist", vm.Namespace, name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
 func (m *vmMutator) getNodeSelectorRequirementFromNetwork(defaultNamespace string, network kubevirtv1.Network) (*v1.NodeSelectorRequirement, error) {
 	if network.Multus == nil || network.Multus.NetworkName == "" {
 		return nil, nil
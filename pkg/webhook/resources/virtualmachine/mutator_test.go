@@ -46,6 +46,7 @@ func setupTestMutator(clientset *fake.Clientset) types.Mutator {
 		fakeclients.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
 		fakeclients.KubeVirtCache(clientset.KubevirtV1().KubeVirts),
 		fakeclients.KubeovnSubnetCache(clientset.KubeovnV1().Subnets),
+		fakeclients.VMPlacementPolicyCache(clientset.HarvesterhciV1beta1().VMPlacementPolicies),
 	)
 }
 
@@ -55,6 +56,7 @@ func setupTestMutatorWithoutSubnetCache(clientset *fake.Clientset) types.Mutator
 		fakeclients.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
 		fakeclients.KubeVirtCache(clientset.KubevirtV1().KubeVirts),
 		nil,
+		fakeclients.VMPlacementPolicyCache(clientset.HarvesterhciV1beta1().VMPlacementPolicies),
 	)
 }
 
@@ -748,7 +750,8 @@ func TestPatchResourceOvercommitWithARMArchitecture(t *testing.T) {
 				fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings),
 				fakeclients.NetworkAttachmentDefinitionCache(clientset.K8sCniCncfIoV1().NetworkAttachmentDefinitions),
 				fakeclients.KubeVirtCache(clientset.KubevirtV1().KubeVirts),
-				fakeclients.KubeovnSubnetCache(clientset.KubeovnV1().Subnets))
+				fakeclients.KubeovnSubnetCache(clientset.KubeovnV1().Subnets),
+				fakeclients.VMPlacementPolicyCache(clientset.HarvesterhciV1beta1().VMPlacementPolicies))
 
 			actual, err := mutator.(*vmMutator).patchResourceOvercommit(vm)
 			assert.Nil(t, err, tc.name)
@@ -2366,3 +2369,124 @@ func Test_ForkliftPatch(t *testing.T) {
 		}
 	}
 }
+
+func TestPatchPlacementPolicy(t *testing.T) {
+	newPolicy := func(name string, policyType harvesterv1.VMPlacementPolicyType, enforcement harvesterv1.VMPlacementPolicyEnforcement) *harvesterv1.VMPlacementPolicy {
+		return &harvesterv1.VMPlacementPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       harvesterv1.VMPlacementPolicySpec{Type: policyType, Enforcement: enforcement},
+		}
+	}
+	newVM := func(policy string) *kubevirtv1.VirtualMachine {
+		vm := &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default", Labels: map[string]string{}},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{},
+			},
+		}
+		if policy != "" {
+			vm.Labels[util.LabelVMPlacementPolicy] = policy
+		}
+		return vm
+	}
+	policySelector := func(name string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchLabels: map[string]string{util.LabelVMPlacementPolicy: name}}
+	}
+
+	// a VM which left the "apart" policy still has its term and template label
+	formerMember := newVM("")
+	formerMember.Spec.Template.ObjectMeta.Labels = map[string]string{util.LabelVMPlacementPolicy: "apart", "app": "web"}
+	formerMember.Spec.Template.Spec.Affinity = &v1.Affinity{
+		PodAntiAffinity: &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{LabelSelector: policySelector("apart"), TopologyKey: v1.LabelHostname},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		vm                  *kubevirtv1.VirtualMachine
+		expectedErr         bool
+		expectedLabels      map[string]string
+		expectedAffinity    *v1.Affinity
+		expectedConstraints []v1.TopologySpreadConstraint
+	}{
+		{
+			name:           "hard anti-affinity",
+			vm:             newVM("apart"),
+			expectedLabels: map[string]string{util.LabelVMPlacementPolicy: "apart"},
+			expectedAffinity: &v1.Affinity{
+				PodAntiAffinity: &v1.PodAntiAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+						{LabelSelector: policySelector("apart"), TopologyKey: v1.LabelHostname},
+					},
+				},
+			},
+		},
+		{
+			name:           "soft affinity",
+			vm:             newVM("together"),
+			expectedLabels: map[string]string{util.LabelVMPlacementPolicy: "together"},
+			expectedAffinity: &v1.Affinity{
+				PodAffinity: &v1.PodAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+						{Weight: 100, PodAffinityTerm: v1.PodAffinityTerm{LabelSelector: policySelector("together"), TopologyKey: v1.LabelHostname}},
+					},
+				},
+			},
+		},
+		{
+			name:             "hard spread across zones",
+			vm:               newVM("zones"),
+			expectedLabels:   map[string]string{util.LabelVMPlacementPolicy: "zones"},
+			expectedAffinity: &v1.Affinity{},
+			expectedConstraints: []v1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: v1.LabelTopologyZone, WhenUnsatisfiable: v1.DoNotSchedule, LabelSelector: policySelector("zones")},
+			},
+		},
+		{
+			name:             "remove the terms of a former policy",
+			vm:               formerMember,
+			expectedLabels:   map[string]string{"app": "web"},
+			expectedAffinity: &v1.Affinity{},
+		},
+		{
+			name:        "policy does not exist",
+			vm:          newVM("missing"),
+			expectedErr: true,
+		},
+	}
+
+	clientset := fake.NewSimpleClientset(
+		newPolicy("apart", harvesterv1.VMPlacementPolicyAntiAffinity, harvesterv1.VMPlacementPolicyHard),
+		newPolicy("together", harvesterv1.VMPlacementPolicyAffinity, harvesterv1.VMPlacementPolicySoft),
+		newPolicy("zones", harvesterv1.VMPlacementPolicySpread, harvesterv1.VMPlacementPolicyHard),
+	)
+	createDefaultKubeVirt(clientset)
+	mutator := setupTestMutator(clientset).(*vmMutator)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patchOps, err := mutator.patchAffinity(tc.vm.DeepCopy(), nil)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			patchOps, err = mutator.patchPlacementPolicy(tc.vm, patchOps)
+			assert.NoError(t, err)
+
+			vmJSON, err := json.Marshal(tc.vm)
+			require.NoError(t, err)
+			patchedJSON, err := patch.Apply(vmJSON, []byte(fmt.Sprintf("[%s]", strings.Join(patchOps, ","))))
+			require.NoError(t, err)
+			patchedVM := &kubevirtv1.VirtualMachine{}
+			require.NoError(t, json.Unmarshal(patchedJSON, patchedVM))
+
+			assert.Equal(t, tc.expectedLabels, patchedVM.Spec.Template.ObjectMeta.Labels)
+			assert.Equal(t, tc.expectedAffinity, patchedVM.Spec.Template.Spec.Affinity)
+			assert.Equal(t, tc.expectedConstraints, patchedVM.Spec.Template.Spec.TopologySpreadConstraints)
+		})
+	}
+}
@@ -323,6 +323,9 @@ func (v *vmValidator) checkVMSpec(vm *kubevirtv1.VirtualMachine) error {
 	if err := v.checkOccupiedPVCs(vm); err != nil {
 		return err
 	}
+	if err := v.checkRekeyingPVCs(vm); err != nil {
+		return err
+	}
 	if err := v.checkTerminationGracePeriodSeconds(vm); err != nil {
 		return err
 	}
@@ -587,6 +590,59 @@ func (v *vmValidator) checkOccupiedPVCs(vm *kubevirtv1.VirtualMachine) error {
 	return nil
 }
 
+// checkRekeyingPVCs prevents the VM from being started while one of its volumes is being rekeyed,
+// the volume rekey controller switches the VM to the rekeyed volume once the data is copied.
+func (v *vmValidator) checkRekeyingPVCs(vm *kubevirtv1.VirtualMachine) error {
+	if !isStartRequested(vm) {
+		return nil
+	}
+	for _, volume := range vm.Spec.Template.Spec.Volumes {
+		var claimName string
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			claimName = volume.PersistentVolumeClaim.ClaimName
+		case volume.DataVolume != nil:
+			claimName = volume.DataVolume.Name
+		default:
+			continue
+		}
+		pvc, err := v.pvcCache.Get(vm.Namespace, claimName)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return werror.NewInternalError(err.Error())
+		}
+		if pvc.Annotations[util.AnnotationVolumeRekeyStatus] == util.VolumeRekeyInProgress {
+			return werror.NewInvalidError(
+				fmt.Sprintf("PVC %s/%s is being rekeyed, the VM can't be started until it's done", vm.Namespace, claimName),
+				"spec.runStrategy",
+			)
+		}
+	}
+	return nil
+}
+
+// isStartRequested returns true if the run strategy of the VM, or a pending state change request,
+// asks for the VM to run.
+func isStartRequested(vm *kubevirtv1.VirtualMachine) bool {
+	runStrategy, err := vm.RunStrategy()
+	if err != nil {
+		return false
+	}
+	switch runStrategy {
+	case kubevirtv1.RunStrategyHalted:
+		return false
+	case kubevirtv1.RunStrategyManual:
+		for _, request := range vm.Status.StateChangeRequests {
+			if request.Action == kubevirtv1.StartRequest {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
 func (v *vmValidator) checkVMBackup(vm *kubevirtv1.VirtualMachine) error {
 	exist, err := webhookutil.HasActiveBackup(v.vmBackupCache, v.vmbr, string(vm.UID))
 	if err != nil {
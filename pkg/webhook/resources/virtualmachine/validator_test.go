@@ -1806,3 +1806,74 @@ func TestCheckTargetVolumes(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckRekeyingPVCs(t *testing.T) {
+	newVM := func(runStrategy kubevirtv1.VirtualMachineRunStrategy, requests ...kubevirtv1.VirtualMachineStateChangeRequest) *kubevirtv1.VirtualMachine {
+		return &kubevirtv1.VirtualMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "default"},
+			Spec: kubevirtv1.VirtualMachineSpec{
+				RunStrategy: &runStrategy,
+				Template: &kubevirtv1.VirtualMachineInstanceTemplateSpec{
+					Spec: kubevirtv1.VirtualMachineInstanceSpec{
+						Volumes: []kubevirtv1.Volume{
+							{Name: "disk-0", VolumeSource: kubevirtv1.VolumeSource{PersistentVolumeClaim: &kubevirtv1.PersistentVolumeClaimVolumeSource{
+								PersistentVolumeClaimVolumeSource: corev1.PersistentVolumeClaimVolumeSource{ClaimName: "disk-0"},
+							}}},
+						},
+					},
+				},
+			},
+			Status: kubevirtv1.VirtualMachineStatus{StateChangeRequests: requests},
+		}
+	}
+	newPVC := func(status string) *corev1.PersistentVolumeClaim {
+		return &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "disk-0",
+				Namespace:   "default",
+				Annotations: map[string]string{util.AnnotationVolumeRekeyStatus: status},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		vm        *kubevirtv1.VirtualMachine
+		pvc       *corev1.PersistentVolumeClaim
+		expectErr bool
+	}{
+		{
+			name: "halted VM with a rekeying volume",
+			vm:   newVM(kubevirtv1.RunStrategyHalted),
+			pvc:  newPVC(util.VolumeRekeyInProgress),
+		},
+		{
+			name:      "started VM with a rekeying volume",
+			vm:        newVM(kubevirtv1.RunStrategyRerunOnFailure),
+			pvc:       newPVC(util.VolumeRekeyInProgress),
+			expectErr: true,
+		},
+		{
+			name:      "manual VM start requested with a rekeying volume",
+			vm:        newVM(kubevirtv1.RunStrategyManual, kubevirtv1.VirtualMachineStateChangeRequest{Action: kubevirtv1.StartRequest}),
+			pvc:       newPVC(util.VolumeRekeyInProgress),
+			expectErr: true,
+		},
+		{
+			name: "started VM with a volume failed to rekey",
+			vm:   newVM(kubevirtv1.RunStrategyAlways),
+			pvc:  newPVC(util.VolumeRekeyFailed),
+		},
+	}
+
+	for _, tc := range tests {
+		clientSet := fake.NewSimpleClientset(tc.pvc)
+		validator := &vmValidator{pvcCache: fakeclients.PersistentVolumeClaimCache(clientSet.CoreV1().PersistentVolumeClaims)}
+		err := validator.checkRekeyingPVCs(tc.vm)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
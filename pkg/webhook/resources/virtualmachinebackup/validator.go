@@ -6,8 +6,10 @@ import (
 	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	kubevirtv1 "kubevirt.io/api/core/v1"
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
@@ -28,6 +30,8 @@ const (
 	fieldSourceName       = "spec.source.name"
 	fieldTypeName         = "spec.type"
 	fieldFsFreezeDeadline = "spec.fsFreezeDeadline"
+	fieldHooks            = "spec.hooks"
+	fieldBackupTargetName = "spec.backupTargetName"
 )
 
 func NewValidator(
@@ -39,6 +43,8 @@ func NewValidator(
 	scCache ctlstoragev1.StorageClassCache,
 	resourceQuotaCache ctlharvesterv1.ResourceQuotaCache,
 	vmimCache ctlkubevirtv1.VirtualMachineInstanceMigrationCache,
+	btCache ctlharvesterv1.BackupTargetCache,
+	sar authorizationv1client.SubjectAccessReviewInterface,
 ) types.Validator {
 	return &virtualMachineBackupValidator{
 		vms:                vms,
@@ -49,6 +55,8 @@ func NewValidator(
 		scCache:            scCache,
 		resourceQuotaCache: resourceQuotaCache,
 		vmimCache:          vmimCache,
+		btCache:            btCache,
+		sar:                sar,
 		vmbr:               common.NewVMBackupReader(),
 		vmrr:               restorecommon.NewVMRestoreReader(),
 	}
@@ -65,6 +73,8 @@ type virtualMachineBackupValidator struct {
 	scCache            ctlstoragev1.StorageClassCache
 	resourceQuotaCache ctlharvesterv1.ResourceQuotaCache
 	vmimCache          ctlkubevirtv1.VirtualMachineInstanceMigrationCache
+	btCache            ctlharvesterv1.BackupTargetCache
+	sar                authorizationv1client.SubjectAccessReviewInterface
 	vmbr               common.VMBackupReader
 	vmrr               restorecommon.VMRestoreReader
 }
@@ -84,7 +94,7 @@ func (v *virtualMachineBackupValidator) Resource() types.Resource {
 	}
 }
 
-func (v *virtualMachineBackupValidator) Create(_ *types.Request, newObj runtime.Object) error {
+func (v *virtualMachineBackupValidator) Create(request *types.Request, newObj runtime.Object) error {
 	newVMBackup := newObj.(*v1beta1.VirtualMachineBackup)
 
 	sourceName := v.vmbr.GetSourceName(newVMBackup)
@@ -96,6 +106,16 @@ func (v *virtualMachineBackupValidator) Create(_ *types.Request, newObj runtime.
 		return werror.NewInvalidError("must not be negative", fieldFsFreezeDeadline)
 	}
 
+	hooks := v.vmbr.GetSpec(newVMBackup).Hooks
+	if err := webhookutil.ValidateBackupHooks(hooks, fieldHooks); err != nil {
+		return err
+	}
+	if webhookutil.HasBackupHooks(hooks) {
+		if err := webhookutil.CheckBackupHooksPermission(v.sar, request, v.vmbr.GetNamespace(newVMBackup), sourceName, fieldHooks); err != nil {
+			return err
+		}
+	}
+
 	validateFunc := v.validateStandardBackup
 	if !v.vmbr.IsMissingStatus(newVMBackup) {
 		validateFunc = v.validateVMBackupRecover
@@ -106,11 +126,13 @@ func (v *virtualMachineBackupValidator) Create(_ *types.Request, newObj runtime.
 		return err
 	}
 
+	// Additional check for backup type.
+	if backupTargetName := v.vmbr.GetBackupTargetName(newVMBackup); backupTargetName != "" {
+		return v.checkNamedBackupTarget(newVMBackup, backupTargetName)
+	}
 	if v.vmbr.GetType(newVMBackup) == v1beta1.Snapshot {
 		return nil
 	}
-
-	// Additional check for backup type.
 	if err := v.checkBackupTarget(); err != nil {
 		return werror.NewInvalidError(err.Error(), fieldTypeName)
 	}
@@ -141,6 +163,10 @@ func (v *virtualMachineBackupValidator) validateStandardBackup(vmb *v1beta1.Virt
 }
 
 func (v *virtualMachineBackupValidator) validateVMBackupRecover(vmb *v1beta1.VirtualMachineBackup) error {
+	// Exported volumes are recovered from the backup target by the export engine, not from LH backups.
+	if v.vmbr.GetType(vmb) == v1beta1.SnapshotExport {
+		return nil
+	}
 	// Perform LH backup specific validation.
 	return webhookutil.IsLHBackupRelated(vmb, v.vmbr)
 }
@@ -193,7 +219,28 @@ func (v *virtualMachineBackupValidator) checkBackupTarget() error {
 	return nil
 }
 
-func (v *virtualMachineBackupValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+// checkNamedBackupTarget checks the BackupTarget resource a backup is stored in.
+// Longhorn backups are written to the Longhorn BackupTarget the resource is
+// synced to, which can't encrypt the volume data with the key of the target.
+func (v *virtualMachineBackupValidator) checkNamedBackupTarget(vmb *v1beta1.VirtualMachineBackup, backupTargetName string) error {
+	backupType := v.vmbr.GetType(vmb)
+	if !backupType.UsesRemoteBackupTarget() {
+		return werror.NewInvalidError(fmt.Sprintf("%s backups can't be stored in a backup target", backupType), fieldBackupTargetName)
+	}
+	bt, err := v.btCache.Get(backupTargetName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return werror.NewInvalidError(fmt.Sprintf("backup target %s is not found", backupTargetName), fieldBackupTargetName)
+		}
+		return werror.NewInternalError(err.Error())
+	}
+	if backupType == v1beta1.Backup && bt.Spec.EncryptionKeySecret != "" {
+		return werror.NewInvalidError(fmt.Sprintf("backup target %s is encrypted, only %s backups can be stored in it", backupTargetName, v1beta1.SnapshotExport), fieldBackupTargetName)
+	}
+	return nil
+}
+
+func (v *virtualMachineBackupValidator) Update(request *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
 	newVMBackup := newObj.(*v1beta1.VirtualMachineBackup)
 	oldVMBackup := oldObj.(*v1beta1.VirtualMachineBackup)
 
@@ -203,6 +250,20 @@ func (v *virtualMachineBackupValidator) Update(_ *types.Request, oldObj runtime.
 		return werror.NewInvalidError("must not be negative", fieldFsFreezeDeadline)
 	}
 
+	hooks := v.vmbr.GetSpec(newVMBackup).Hooks
+	if err := webhookutil.ValidateBackupHooks(hooks, fieldHooks); err != nil {
+		return err
+	}
+	if webhookutil.HasBackupHooks(hooks) && !equality.Semantic.DeepEqual(v.vmbr.GetSpec(oldVMBackup).Hooks, hooks) {
+		if err := webhookutil.CheckBackupHooksPermission(v.sar, request, v.vmbr.GetNamespace(newVMBackup), v.vmbr.GetSourceName(newVMBackup), fieldHooks); err != nil {
+			return err
+		}
+	}
+
+	if v.vmbr.GetBackupTargetName(oldVMBackup) != v.vmbr.GetBackupTargetName(newVMBackup) {
+		return werror.NewInvalidError("backup target name can't be changed", fieldBackupTargetName)
+	}
+
 	oldAnnotations := oldVMBackup.GetAnnotations()
 	newAnnotations := newVMBackup.GetAnnotations()
 
@@ -0,0 +1,73 @@
+package virtualmachinebackup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/common"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+func TestCheckBackupHooksPermission(t *testing.T) {
+	request := &types.Request{
+		Request: &webhook.Request{
+			Context: context.Background(),
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "demo"},
+			},
+		},
+	}
+	newBackup := func(hooks []v1beta1.BackupHook) *v1beta1.VirtualMachineBackup {
+		return &v1beta1.VirtualMachineBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "default"},
+			Spec: v1beta1.VirtualMachineBackupSpec{
+				Source: corev1.TypedLocalObjectReference{Name: "vm"},
+				Hooks:  &v1beta1.BackupHooks{PreSnapshot: hooks},
+			},
+		}
+	}
+	freeze := []v1beta1.BackupHook{{Name: "freeze", Command: []string{"fsfreeze", "-f", "/data"}}}
+	flush := []v1beta1.BackupHook{{Name: "flush", Command: []string{"sync"}}}
+
+	k8sclientset := corefake.NewClientset()
+	var reviews []*authorizationv1.SubjectAccessReview
+	k8sclientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		reviews = append(reviews, sar)
+		return true, sar, nil
+	})
+	validator := &virtualMachineBackupValidator{
+		sar:  k8sclientset.AuthorizationV1().SubjectAccessReviews(),
+		vmbr: common.NewVMBackupReader(),
+	}
+
+	err := validator.Create(request, newBackup(freeze))
+	assert.ErrorContains(t, err, "user demo has no permission to open the console of VM default/vm")
+	if assert.Len(t, reviews, 1) {
+		attributes := reviews[0].Spec.ResourceAttributes
+		assert.Equal(t, "demo", reviews[0].Spec.User)
+		assert.Equal(t, "virtualmachineinstances", attributes.Resource)
+		assert.Equal(t, "console", attributes.Subresource)
+		assert.Equal(t, "vm", attributes.Name)
+	}
+
+	err = validator.Update(request, newBackup(freeze), newBackup(flush))
+	assert.ErrorContains(t, err, "has no permission to open the console")
+
+	// unchanged hooks are not checked again
+	reviews = nil
+	assert.NoError(t, validator.Update(request, newBackup(freeze), newBackup(freeze)))
+	assert.Empty(t, reviews)
+}
@@ -0,0 +1,111 @@
+package virtualmachinebackupcopy
+
+import (
+	"fmt"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/settings"
+	backuputil "github.com/harvester/harvester/pkg/util/backup"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldVMBackupName     = "spec.vmBackupName"
+	fieldBackupTargetName = "spec.backupTargetName"
+)
+
+func NewValidator(
+	settingCache ctlharvesterv1.SettingCache,
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache,
+	vmBackupCopyCache ctlharvesterv1.VirtualMachineBackupCopyCache,
+	btCache ctlharvesterv1.BackupTargetCache,
+) types.Validator {
+	return &vmBackupCopyValidator{
+		settingCache:      settingCache,
+		vmBackupCache:     vmBackupCache,
+		vmBackupCopyCache: vmBackupCopyCache,
+		btCache:           btCache,
+	}
+}
+
+type vmBackupCopyValidator struct {
+	types.DefaultValidator
+	settingCache      ctlharvesterv1.SettingCache
+	vmBackupCache     ctlharvesterv1.VirtualMachineBackupCache
+	vmBackupCopyCache ctlharvesterv1.VirtualMachineBackupCopyCache
+	btCache           ctlharvesterv1.BackupTargetCache
+}
+
+func (v *vmBackupCopyValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.VirtualMachineBackupCopyResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.VirtualMachineBackupCopy{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+		},
+	}
+}
+
+func (v *vmBackupCopyValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	vmBackupCopy := newObj.(*v1beta1.VirtualMachineBackupCopy)
+
+	if vmBackupCopy.Spec.VMBackupName == "" {
+		return werror.NewInvalidError("vm backup name is empty", fieldVMBackupName)
+	}
+	vmBackup, err := v.vmBackupCache.Get(vmBackupCopy.Namespace, vmBackupCopy.Spec.VMBackupName)
+	if err != nil {
+		return werror.NewInvalidError(err.Error(), fieldVMBackupName)
+	}
+	if !vmBackup.Spec.Type.UsesRemoteBackupTarget() {
+		return werror.NewInvalidError(fmt.Sprintf("vm backup of type %s isn't stored in a backup target and can't be copied", vmBackup.Spec.Type), fieldVMBackupName)
+	}
+	if vmBackup.Spec.BackupTargetName == vmBackupCopy.Spec.BackupTargetName {
+		return werror.NewInvalidError("vm backup can't be copied to the backup target it is stored in", fieldBackupTargetName)
+	}
+
+	if err := v.checkBackupTarget(vmBackupCopy.Spec.BackupTargetName); err != nil {
+		return werror.NewInvalidError(err.Error(), fieldBackupTargetName)
+	}
+
+	vmBackupCopies, err := v.vmBackupCopyCache.List(vmBackupCopy.Namespace, labels.Everything())
+	if err != nil {
+		return werror.NewInternalError(fmt.Sprintf("Can't list VM backup copies, err: %+v", err.Error()))
+	}
+	for _, c := range vmBackupCopies {
+		if c.Spec.VMBackupName == vmBackupCopy.Spec.VMBackupName && c.Spec.BackupTargetName == vmBackupCopy.Spec.BackupTargetName {
+			return werror.NewInvalidError(fmt.Sprintf("vm backup is already copied to the backup target by %s", c.Name), fieldBackupTargetName)
+		}
+	}
+	return nil
+}
+
+// checkBackupTarget checks the destination exists. The webhook doesn't sync
+// the settings, the backup-target setting is read from the cache.
+func (v *vmBackupCopyValidator) checkBackupTarget(backupTargetName string) error {
+	if backupTargetName != "" {
+		_, err := backuputil.GetBackupTarget(v.btCache, backupTargetName)
+		return err
+	}
+
+	backupTargetSetting, err := v.settingCache.Get(settings.BackupTargetSettingName)
+	if err != nil {
+		return fmt.Errorf("can't get backup target setting, err: %w", err)
+	}
+	target, err := settings.DecodeBackupTarget(backupTargetSetting.Value)
+	if err != nil {
+		return fmt.Errorf("unmarshal backup target failed, value: %s, err: %w", backupTargetSetting.Value, err)
+	}
+	if target.IsDefaultBackupTarget() {
+		return fmt.Errorf("backup target is not set")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package virtualmachinegroup
+
+import (
+	"fmt"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldMembers = "spec.members"
+	fieldTimeout = "spec.timeout"
+)
+
+func NewValidator(groupCache ctlharvesterv1.VirtualMachineGroupCache) types.Validator {
+	return &vmGroupValidator{
+		groupCache: groupCache,
+	}
+}
+
+type vmGroupValidator struct {
+	types.DefaultValidator
+	groupCache ctlharvesterv1.VirtualMachineGroupCache
+}
+
+func (v *vmGroupValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.VirtualMachineGroupResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.VirtualMachineGroup{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+func (v *vmGroupValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	return v.validate(newObj.(*v1beta1.VirtualMachineGroup))
+}
+
+func (v *vmGroupValidator) Update(_ *types.Request, _ runtime.Object, newObj runtime.Object) error {
+	return v.validate(newObj.(*v1beta1.VirtualMachineGroup))
+}
+
+func (v *vmGroupValidator) validate(group *v1beta1.VirtualMachineGroup) error {
+	if group.DeletionTimestamp != nil {
+		return nil
+	}
+	if group.Spec.Timeout != nil && group.Spec.Timeout.Duration <= 0 {
+		return werror.NewInvalidError("must be positive", fieldTimeout)
+	}
+
+	members := make(map[string]bool, len(group.Spec.Members))
+	for i, member := range group.Spec.Members {
+		field := fmt.Sprintf("%s[%d]", fieldMembers, i)
+		if member.Name == "" {
+			return werror.NewInvalidError("the VM name is empty", field+".name")
+		}
+		if members[member.Name] {
+			return werror.NewInvalidError(fmt.Sprintf("VM %s is listed more than once", member.Name), field+".name")
+		}
+		members[member.Name] = true
+		if member.StartDelay != nil && member.StartDelay.Duration < 0 {
+			return werror.NewInvalidError("must not be negative", field+".startDelay")
+		}
+		for j, gate := range member.ReadinessGates {
+			switch gate.Type {
+			case v1beta1.VirtualMachineGroupReadinessGuestAgent:
+			case v1beta1.VirtualMachineGroupReadinessTCPPort:
+				if gate.Port == 0 {
+					return werror.NewInvalidError("the port of a TCPPort readiness gate is empty", fmt.Sprintf("%s.readinessGates[%d].port", field, j))
+				}
+			default:
+				return werror.NewInvalidError(fmt.Sprintf("readiness gate %s is not supported", gate.Type), fmt.Sprintf("%s.readinessGates[%d].type", field, j))
+			}
+		}
+	}
+
+	// a VM is a member of one group at most, so its stop and start order is unambiguous
+	groups, err := v.groupCache.List(group.Namespace, labels.Everything())
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	for _, other := range groups {
+		if other.Name == group.Name {
+			continue
+		}
+		for _, member := range other.Spec.Members {
+			if members[member.Name] {
+				return werror.NewInvalidError(fmt.Sprintf("VM %s is a member of group %s", member.Name, other.Name), fieldMembers)
+			}
+		}
+	}
+	return nil
+}
@@ -24,9 +24,10 @@ func NewValidator(
 	ssar authorizationv1client.SelfSubjectAccessReviewInterface,
 	vmTemplateVersionCache ctlharvesterv1.VirtualMachineTemplateVersionCache,
 	scCache ctlstoragev1.StorageClassCache,
-	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache) types.Validator {
+	vmBackupCache ctlharvesterv1.VirtualMachineBackupCache,
+	settingCache ctlharvesterv1.SettingCache) types.Validator {
 
-	vmiv := common.GetVMIValidator(vmiCache, scCache, ssar, podCache, pvcCache, vmTemplateVersionCache, vmBackupCache)
+	vmiv := common.GetVMIValidator(vmiCache, scCache, ssar, podCache, pvcCache, vmTemplateVersionCache, vmBackupCache, settingCache)
 	validators := map[v1beta1.VMIBackend]backend.Validator{
 		v1beta1.VMIBackendBackingImage: backingimage.GetValidator(vmiv),
 		v1beta1.VMIBackendCDI:          cdi.GetValidator(vmiv),
@@ -0,0 +1,118 @@
+package virtualmachineimagedownloader
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	ctlcorev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/image/export"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldExport           = "spec.export"
+	fieldExportType       = "spec.export.type"
+	fieldExportURL        = "spec.export.url"
+	fieldExportBucket     = "spec.export.bucket"
+	fieldExportSecretName = "spec.export.secretName"
+
+	// resolving the export host must not hold the admission request for long
+	checkURLTimeout = 5 * time.Second
+)
+
+func NewValidator(clientset kubernetes.Interface, secretCache ctlcorev1.SecretCache) types.Validator {
+	return &virtualMachineImageDownloaderValidator{
+		clientset:   clientset,
+		secretCache: secretCache,
+	}
+}
+
+type virtualMachineImageDownloaderValidator struct {
+	types.DefaultValidator
+	clientset   kubernetes.Interface
+	secretCache ctlcorev1.SecretCache
+}
+
+func (v *virtualMachineImageDownloaderValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.VirtualMachineImageDownloaderResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.VirtualMachineImageDownloader{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+func (v *virtualMachineImageDownloaderValidator) Create(request *types.Request, newObj runtime.Object) error {
+	downloader := newObj.(*v1beta1.VirtualMachineImageDownloader)
+	if downloader.Spec.Export == nil {
+		return nil
+	}
+	return v.checkExportTarget(request.Context, downloader.Namespace, downloader.Spec.Export)
+}
+
+// Update refuses to change the export target, the export Job already runs against the old one.
+func (v *virtualMachineImageDownloaderValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+	oldDownloader := oldObj.(*v1beta1.VirtualMachineImageDownloader)
+	newDownloader := newObj.(*v1beta1.VirtualMachineImageDownloader)
+	if newDownloader.DeletionTimestamp != nil {
+		return nil
+	}
+	if !reflect.DeepEqual(oldDownloader.Spec.Export, newDownloader.Spec.Export) {
+		return werror.NewInvalidError("export target is immutable, create a new downloader instead", fieldExport)
+	}
+	return nil
+}
+
+// checkExportTarget makes sure the target does not point into the cluster or the host,
+// the export Job and its network policy block these addresses again at transfer time.
+func (v *virtualMachineImageDownloaderValidator) checkExportTarget(ctx context.Context, namespace string, target *v1beta1.VirtualMachineImageExportTarget) error {
+	switch target.Type {
+	case v1beta1.ImageExportTargetTypeHTTP:
+		if target.URL == "" {
+			return werror.NewInvalidError("url is required for the http export target", fieldExportURL)
+		}
+	case v1beta1.ImageExportTargetTypeS3:
+		if target.Bucket == "" {
+			return werror.NewInvalidError("bucket is required for the s3 export target", fieldExportBucket)
+		}
+	default:
+		return werror.NewInvalidError(fmt.Sprintf("unsupported export target type %q", target.Type), fieldExportType)
+	}
+
+	if target.SecretName != "" {
+		if _, err := v.secretCache.Get(namespace, target.SecretName); err != nil {
+			return werror.NewInvalidError(err.Error(), fieldExportSecretName)
+		}
+	}
+
+	// the s3 target defaults to AWS without an url
+	if target.URL == "" {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkURLTimeout)
+	defer cancel()
+	blocked, err := export.BlockedNetworks(ctx, v.clientset)
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	if err := export.CheckURL(ctx, target.URL, blocked); err != nil {
+		return werror.NewInvalidError(err.Error(), fieldExportURL)
+	}
+	return nil
+}
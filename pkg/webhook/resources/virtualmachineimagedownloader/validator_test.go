@@ -0,0 +1,122 @@
+package virtualmachineimagedownloader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rancher/wrangler/v3/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corefake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      *v1beta1.VirtualMachineImageExportTarget
+		errContains string
+	}{
+		{
+			name: "accepts downloader without export",
+		},
+		{
+			name:   "accepts public http target",
+			target: &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeHTTP, URL: "https://203.0.113.10/image.qcow2", SecretName: "credentials"},
+		},
+		{
+			name:   "accepts s3 target without endpoint",
+			target: &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeS3, Bucket: "images"},
+		},
+		{
+			name:        "rejects unknown type",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: "ftp", URL: "ftp://203.0.113.10/image.qcow2"},
+			errContains: "unsupported export target type",
+		},
+		{
+			name:        "rejects http target without url",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeHTTP},
+			errContains: "url is required",
+		},
+		{
+			name:        "rejects s3 target without bucket",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeS3, URL: "https://203.0.113.10"},
+			errContains: "bucket is required",
+		},
+		{
+			name:        "rejects missing secret",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeHTTP, URL: "https://203.0.113.10/image.qcow2", SecretName: "missing"},
+			errContains: "not found",
+		},
+		{
+			name:        "rejects non http scheme",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeS3, Bucket: "images", URL: "file:///etc/passwd"},
+			errContains: "url must be http or https",
+		},
+		{
+			name:        "rejects loopback",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeHTTP, URL: "http://127.0.0.1:6443/image.qcow2"},
+			errContains: "blocked network",
+		},
+		{
+			name:        "rejects link-local metadata service",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeS3, Bucket: "images", URL: "http://169.254.169.254"},
+			errContains: "blocked network",
+		},
+		{
+			name:        "rejects pod network",
+			target:      &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeHTTP, URL: "http://10.52.0.20/image.qcow2"},
+			errContains: "blocked network",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			k8sclientset := corefake.NewClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+				Spec:       corev1.NodeSpec{PodCIDRs: []string{"10.52.0.0/24"}},
+			})
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+			})
+			validator := NewValidator(k8sclientset, fakeclients.SecretCache(clientset.CoreV1().Secrets))
+
+			request := &types.Request{Request: &webhook.Request{Context: context.Background()}}
+			err := validator.Create(request, &v1beta1.VirtualMachineImageDownloader{
+				ObjectMeta: metav1.ObjectMeta{Name: "image-downloader", Namespace: "default"},
+				Spec: v1beta1.VirtualMachineImageDownloaderSpec{
+					ImageName: "image",
+					Export:    tc.target,
+				},
+			})
+			if tc.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.errContains)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	validator := NewValidator(corefake.NewClientset(), nil)
+	oldDownloader := &v1beta1.VirtualMachineImageDownloader{
+		ObjectMeta: metav1.ObjectMeta{Name: "image-downloader", Namespace: "default"},
+		Spec: v1beta1.VirtualMachineImageDownloaderSpec{
+			ImageName: "image",
+			Export:    &v1beta1.VirtualMachineImageExportTarget{Type: v1beta1.ImageExportTargetTypeS3, Bucket: "images"},
+		},
+	}
+
+	newDownloader := oldDownloader.DeepCopy()
+	newDownloader.Labels = map[string]string{"foo": "bar"}
+	assert.NoError(t, validator.Update(nil, oldDownloader, newDownloader))
+
+	newDownloader.Spec.Export.URL = "http://10.52.0.20"
+	assert.ErrorContains(t, validator.Update(nil, oldDownloader, newDownloader), "export target is immutable")
+}
@@ -38,11 +38,13 @@ const (
 	fieldVirtualMachineBackupName = "spec.virtualMachineBackupName"
 	fieldNewVM                    = "spec.newVM"
 	fieldKeepMacAddress           = "spec.keepMacAddress"
+	fieldVolumes                  = "spec.volumes"
 )
 
 func NewValidator(
 	nss ctlv1.NamespaceCache,
 	pods ctlv1.PodCache,
+	pvcCache ctlv1.PersistentVolumeClaimCache,
 	rqs ctlharvestercorev1.ResourceQuotaCache,
 	vms ctlkubevirtv1.VirtualMachineCache,
 	setting ctlharvesterv1.SettingCache,
@@ -52,15 +54,21 @@ func NewValidator(
 	vmims ctlkubevirtv1.VirtualMachineInstanceMigrationCache,
 	vscCache ctlsnapshotv1.VolumeSnapshotClassCache,
 	networkAttachmentDefinitionsCache ctlcniv1.NetworkAttachmentDefinitionCache,
+	btCache ctlharvesterv1.BackupTargetCache,
+	verificationCache ctlharvesterv1.BackupVerificationCache,
 ) types.Validator {
 	return &restoreValidator{
+		nss:                               nss,
 		vms:                               vms,
+		pvcCache:                          pvcCache,
 		setting:                           setting,
 		vmBackup:                          vmBackup,
 		vmRestore:                         vmRestore,
 		svmbackup:                         svmbackup,
 		vscCache:                          vscCache,
 		networkAttachmentDefinitionsCache: networkAttachmentDefinitionsCache,
+		btCache:                           btCache,
+		verificationCache:                 verificationCache,
 
 		vmrCalculator: resourcequota.NewCalculator(nss, pods, rqs, vmims, setting),
 		vmbr:          common.NewVMBackupReader(),
@@ -71,13 +79,17 @@ func NewValidator(
 type restoreValidator struct {
 	types.DefaultValidator
 
+	nss                               ctlv1.NamespaceCache
 	vms                               ctlkubevirtv1.VirtualMachineCache
+	pvcCache                          ctlv1.PersistentVolumeClaimCache
 	setting                           ctlharvesterv1.SettingCache
 	vmBackup                          ctlharvesterv1.VirtualMachineBackupCache
 	vmRestore                         ctlharvesterv1.VirtualMachineRestoreCache
 	svmbackup                         ctlharvesterv1.ScheduleVMBackupCache
 	vscCache                          ctlsnapshotv1.VolumeSnapshotClassCache
 	networkAttachmentDefinitionsCache ctlcniv1.NetworkAttachmentDefinitionCache
+	btCache                           ctlharvesterv1.BackupTargetCache
+	verificationCache                 ctlharvesterv1.BackupVerificationCache
 
 	vmrCalculator *resourcequota.Calculator
 	vmbr          common.VMBackupReader
@@ -112,7 +124,7 @@ func (v *restoreValidator) Create(_ *types.Request, newObj runtime.Object) error
 	}
 
 	svmbackup := util.ResolveSVMBackupRef(v.svmbackup, vmb)
-	if svmbackup != nil && !svmbackup.Spec.Suspend {
+	if svmbackup != nil && !svmbackup.Spec.Suspend && !v.isBackupVerification(vmr, vmb) {
 		return werror.NewInternalError(fmt.Sprintf("Source schedule %s/%s is running", svmbackup.Namespace, svmbackup.Name))
 	}
 
@@ -140,6 +152,25 @@ func (v *restoreValidator) Update(_ *types.Request, oldObj, newObj runtime.Objec
 	return nil
 }
 
+// isBackupVerification tells if the restore is made by a BackupVerification
+// in its sandbox namespace, those don't interfere with the schedule.
+func (v *restoreValidator) isBackupVerification(vmr *v1beta1.VirtualMachineRestore, vmb *v1beta1.VirtualMachineBackup) bool {
+	namespace, err := v.nss.Get(vmr.Namespace)
+	if err != nil || namespace.Annotations[util.AnnotationBackupVerificationID] == "" {
+		return false
+	}
+
+	verificationNamespace, verificationName := ref.Parse(namespace.Annotations[util.AnnotationBackupVerificationID])
+	verification, err := v.verificationCache.Get(verificationNamespace, verificationName)
+	if err != nil {
+		return false
+	}
+	return verification.Name == vmr.Name &&
+		verification.Namespace == vmb.Namespace &&
+		verification.Spec.VMBackupName == vmb.Name &&
+		verification.Status.SandboxNamespace == vmr.Namespace
+}
+
 func (v *restoreValidator) checkNewVMField(vmr *v1beta1.VirtualMachineRestore, vmb *v1beta1.VirtualMachineBackup) error {
 	targetNamespace := v.vmrr.GetNamespace(vmr)
 	targetName := v.vmrr.GetTargetName(vmr)
@@ -152,12 +183,91 @@ func (v *restoreValidator) checkNewVMField(vmr *v1beta1.VirtualMachineRestore, v
 	vmExists := err == nil && vm != nil
 
 	if v.vmrr.IsNewVM(vmr) {
+		if v.vmrr.IsPartialRestore(vmr) {
+			return werror.NewInvalidError("volumes can only be selected when restoring into an existing VM", fieldVolumes)
+		}
 		return v.validateNewVMRestore(vmExists, vm, vmr, vmb)
 	}
 
+	if v.vmrr.IsPartialRestore(vmr) {
+		return v.validatePartialRestore(vmExists, vm, vmr, vmb)
+	}
+
 	return v.validateExistingVMRestore(vmExists, vm, targetName)
 }
 
+// validatePartialRestore checks the selected volumes against the backup and
+// the target VM. Only replacing disks needs the VM to be stopped, hotplugging
+// works on a running VM.
+func (v *restoreValidator) validatePartialRestore(vmExists bool, vm *kubevirtv1.VirtualMachine, vmr *v1beta1.VirtualMachineRestore, vmb *v1beta1.VirtualMachineBackup) error {
+	if !vmExists {
+		return werror.NewInvalidError(fmt.Sprintf("can't restore volumes into nonexistent vm %s", v.vmrr.GetTargetName(vmr)), fieldTargetName)
+	}
+
+	backupVolumes := map[string]bool{}
+	for _, vb := range v.vmbr.GetVolBackups(vmb) {
+		backupVolumes[vb.VolumeName] = true
+	}
+	vmVolumes := map[string]*kubevirtv1.Volume{}
+	for i := range vm.Spec.Template.Spec.Volumes {
+		vmVolumes[vm.Spec.Template.Spec.Volumes[i].Name] = &vm.Spec.Template.Spec.Volumes[i]
+	}
+
+	selectedVolumes := map[string]bool{}
+	targetVolumes := map[string]bool{}
+	replace := false
+	for i := range vmr.Spec.Volumes {
+		sel := &vmr.Spec.Volumes[i]
+		if !backupVolumes[sel.VolumeName] {
+			return werror.NewInvalidError(fmt.Sprintf("volume %s isn't in the backup %s", sel.VolumeName, v.vmbr.GetName(vmb)), fieldVolumes)
+		}
+		if selectedVolumes[sel.VolumeName] {
+			return werror.NewInvalidError(fmt.Sprintf("volume %s is selected more than once", sel.VolumeName), fieldVolumes)
+		}
+		selectedVolumes[sel.VolumeName] = true
+
+		targetVolumeName := v.vmrr.GetSelectionTargetVolumeName(sel)
+		if targetVolumes[targetVolumeName] {
+			return werror.NewInvalidError(fmt.Sprintf("volume %s of the VM is targeted more than once", targetVolumeName), fieldVolumes)
+		}
+		targetVolumes[targetVolumeName] = true
+
+		switch sel.Mode {
+		case v1beta1.VolumeRestoreModeReplace:
+			volume, ok := vmVolumes[targetVolumeName]
+			if !ok || volume.PersistentVolumeClaim == nil {
+				return werror.NewInvalidError(fmt.Sprintf("VM %s has no PVC volume %s to replace", vm.Name, targetVolumeName), fieldVolumes)
+			}
+			replace = true
+		case v1beta1.VolumeRestoreModeHotplug:
+			if _, ok := vmVolumes[targetVolumeName]; ok {
+				return werror.NewInvalidError(fmt.Sprintf("VM %s already has a volume %s", vm.Name, targetVolumeName), fieldVolumes)
+			}
+			if errs := validationutil.IsDNS1123Label(targetVolumeName); len(errs) != 0 {
+				return werror.NewInvalidError(fmt.Sprintf("volume name %s is invalid, err: %v", targetVolumeName, errs), fieldVolumes)
+			}
+		default:
+			return werror.NewInvalidError(fmt.Sprintf("unsupported mode %q of volume %s", sel.Mode, sel.VolumeName), fieldVolumes)
+		}
+	}
+
+	if replace {
+		if err := v.validateVMNotRunning(vm); err != nil {
+			return err
+		}
+	}
+
+	if err := v.validateNoInProgressRestore(vm); err != nil {
+		return err
+	}
+
+	// a running VM keeps running with the hotplugged volumes
+	if vm.Status.Ready {
+		return nil
+	}
+	return v.validateResourceQuota(vm)
+}
+
 func (v *restoreValidator) validateNewVMRestore(vmExists bool, vm *kubevirtv1.VirtualMachine, vmr *v1beta1.VirtualMachineRestore, vmb *v1beta1.VirtualMachineBackup) error {
 	if vmExists {
 		return werror.NewInvalidError(fmt.Sprintf("VM %s is already exists", vm.Name), fieldNewVM)
@@ -320,6 +430,8 @@ func (v *restoreValidator) checkVMBackupType(vmr *v1beta1.VirtualMachineRestore,
 			// User may have VMBackups with non-LH source volume. We should prevent this VMBackup from restoring
 			err = webhookutil.IsLHBackupRelated(vmb, v.vmbr)
 		}
+	case v1beta1.SnapshotExport:
+		err = v.checkBackup(vmr, vmb)
 	case v1beta1.Snapshot:
 		err = v.checkSnapshot(vmr, vmb)
 	}
@@ -338,10 +450,13 @@ func (v *restoreValidator) checkBackup(vmr *v1beta1.VirtualMachineRestore, vmb *
 		return nil
 	}
 
+	pvcNamespaceAndNames, err := v.getReplacedPVCs(vmr, vmb)
+	if err != nil {
+		return err
+	}
+
 	// if deletion policy is delete, check whether there is snapshot using same pvc
-	vbs := v.vmbr.GetVolBackups(vmb)
-	for _, vb := range vbs {
-		pvcNamespaceAndName := fmt.Sprintf("%s/%s", v.vmbr.GetVolBackupPVCNameSpace(&vb), v.vmbr.GetVolBackupPVCName(&vb))
+	for _, pvcNamespaceAndName := range pvcNamespaceAndNames {
 		vss, err := v.vmBackup.GetByIndex(indexeres.VMBackupSnapshotByPVCNamespaceAndName, pvcNamespaceAndName)
 		if err != nil {
 			return err
@@ -359,18 +474,56 @@ func (v *restoreValidator) checkBackup(vmr *v1beta1.VirtualMachineRestore, vmb *
 	return nil
 }
 
-func (v *restoreValidator) checkBackupTarget(vmb *v1beta1.VirtualMachineBackup) error {
-	backupTargetSetting, err := v.setting.Get(settings.BackupTargetSettingName)
-	if err != nil {
-		return fmt.Errorf("can't get backup target setting, err: %w", err)
+// getReplacedPVCs returns the namespace/name of the PVCs the restore deletes.
+// A partial restore only deletes the PVCs of the target VM volumes it
+// replaces, resolved like the restore controller does, so those are checked
+// instead of the PVCs in the backup. A missing target VM or volume is left to
+// validatePartialRestore.
+func (v *restoreValidator) getReplacedPVCs(vmr *v1beta1.VirtualMachineRestore, vmb *v1beta1.VirtualMachineBackup) ([]string, error) {
+	var pvcNamespaceAndNames []string
+	if !v.vmrr.IsPartialRestore(vmr) {
+		for _, vb := range v.vmbr.GetVolBackups(vmb) {
+			pvcNamespaceAndNames = append(pvcNamespaceAndNames,
+				fmt.Sprintf("%s/%s", v.vmbr.GetVolBackupPVCNameSpace(&vb), v.vmbr.GetVolBackupPVCName(&vb)))
+		}
+		return pvcNamespaceAndNames, nil
+	}
+
+	namespace := v.vmrr.GetNamespace(vmr)
+	vm, err := v.vms.Get(namespace, v.vmrr.GetTargetName(vmr))
+	if apierrors.IsNotFound(err) {
+		return nil, nil
 	}
-	backupTarget, err := settings.DecodeBackupTarget(backupTargetSetting.Value)
 	if err != nil {
-		return fmt.Errorf("unmarshal backup target failed, value: %s, err: %w", backupTargetSetting.Value, err)
+		return nil, err
 	}
 
-	if backupTarget.IsDefaultBackupTarget() {
-		return fmt.Errorf("backup target is not set")
+	volumes := map[string]*kubevirtv1.Volume{}
+	for i := range vm.Spec.Template.Spec.Volumes {
+		volumes[vm.Spec.Template.Spec.Volumes[i].Name] = &vm.Spec.Template.Spec.Volumes[i]
+	}
+	for i := range vmr.Spec.Volumes {
+		sel := &vmr.Spec.Volumes[i]
+		if sel.Mode != v1beta1.VolumeRestoreModeReplace {
+			continue
+		}
+		volume, ok := volumes[v.vmrr.GetSelectionTargetVolumeName(sel)]
+		if !ok || volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		claimName := volume.PersistentVolumeClaim.ClaimName
+		if _, err := v.pvcCache.Get(namespace, claimName); err != nil {
+			return nil, fmt.Errorf("failed to get PVC %s/%s of volume %s to replace: %w", namespace, claimName, volume.Name, err)
+		}
+		pvcNamespaceAndNames = append(pvcNamespaceAndNames, fmt.Sprintf("%s/%s", namespace, claimName))
+	}
+	return pvcNamespaceAndNames, nil
+}
+
+func (v *restoreValidator) checkBackupTarget(vmb *v1beta1.VirtualMachineBackup) error {
+	backupTarget, err := v.getBackupTarget(vmb)
+	if err != nil {
+		return err
 	}
 
 	if !backuputil.IsBackupTargetSame(v.vmbr.GetBackupTarget(vmb), backupTarget) {
@@ -380,6 +533,27 @@ func (v *restoreValidator) checkBackupTarget(vmb *v1beta1.VirtualMachineBackup)
 	return nil
 }
 
+// getBackupTarget returns the BackupTarget the backup is stored in, or the backup-target setting.
+func (v *restoreValidator) getBackupTarget(vmb *v1beta1.VirtualMachineBackup) (*settings.BackupTarget, error) {
+	if backupTargetName := v.vmbr.GetBackupTargetName(vmb); backupTargetName != "" {
+		return backuputil.GetBackupTarget(v.btCache, backupTargetName)
+	}
+
+	backupTargetSetting, err := v.setting.Get(settings.BackupTargetSettingName)
+	if err != nil {
+		return nil, fmt.Errorf("can't get backup target setting, err: %w", err)
+	}
+	backupTarget, err := settings.DecodeBackupTarget(backupTargetSetting.Value)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal backup target failed, value: %s, err: %w", backupTargetSetting.Value, err)
+	}
+
+	if backupTarget.IsDefaultBackupTarget() {
+		return nil, fmt.Errorf("backup target is not set")
+	}
+	return backupTarget, nil
+}
+
 func (v *restoreValidator) checkVolumeSnapshotClass(vmBackup *v1beta1.VirtualMachineBackup) error {
 	for csiDriverName, vscName := range v.vmbr.GetCSIDriverVSCNames(vmBackup) {
 		if _, err := v.vscCache.Get(vscName); err != nil {
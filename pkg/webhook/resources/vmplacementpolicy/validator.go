@@ -0,0 +1,96 @@
+package vmplacementpolicy
+
+import (
+	"fmt"
+	"reflect"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlkubevirtv1 "github.com/harvester/harvester/pkg/generated/controllers/kubevirt.io/v1"
+	"github.com/harvester/harvester/pkg/util"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldSpec        = "spec"
+	fieldTopologyKey = "spec.topologyKey"
+	fieldWeight      = "spec.weight"
+	fieldMaxSkew     = "spec.maxSkew"
+)
+
+func NewValidator(vmCache ctlkubevirtv1.VirtualMachineCache) types.Validator {
+	return &vmPlacementPolicyValidator{
+		vmCache: vmCache,
+	}
+}
+
+type vmPlacementPolicyValidator struct {
+	types.DefaultValidator
+	vmCache ctlkubevirtv1.VirtualMachineCache
+}
+
+func (v *vmPlacementPolicyValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.VMPlacementPolicyResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.VMPlacementPolicy{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+			admissionregv1.Delete,
+		},
+	}
+}
+
+func (v *vmPlacementPolicyValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	policy := newObj.(*v1beta1.VMPlacementPolicy)
+
+	if policy.Spec.TopologyKey != "" {
+		if errs := validation.IsQualifiedName(policy.Spec.TopologyKey); len(errs) > 0 {
+			return werror.NewInvalidError(fmt.Sprintf("invalid topology key: %v", errs), fieldTopologyKey)
+		}
+	}
+	if policy.Spec.Weight != 0 && (policy.Spec.Type == v1beta1.VMPlacementPolicySpread || policy.Spec.Enforcement == v1beta1.VMPlacementPolicyHard) {
+		return werror.NewInvalidError("weight only applies to a Soft AntiAffinity or Affinity policy", fieldWeight)
+	}
+	if policy.Spec.MaxSkew != 0 && policy.Spec.Type != v1beta1.VMPlacementPolicySpread {
+		return werror.NewInvalidError("maxSkew only applies to a Spread policy", fieldMaxSkew)
+	}
+	return nil
+}
+
+func (v *vmPlacementPolicyValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+	oldPolicy := oldObj.(*v1beta1.VMPlacementPolicy)
+	newPolicy := newObj.(*v1beta1.VMPlacementPolicy)
+
+	// the policy is translated into the spec of the member VMs when they are created or updated,
+	// so a changed policy wouldn't apply to the existing members
+	if newPolicy.DeletionTimestamp == nil && !reflect.DeepEqual(oldPolicy.Spec, newPolicy.Spec) {
+		return werror.NewInvalidError("the spec of a placement policy is immutable, create a new policy instead", fieldSpec)
+	}
+	return nil
+}
+
+func (v *vmPlacementPolicyValidator) Delete(_ *types.Request, oldObj runtime.Object) error {
+	policy := oldObj.(*v1beta1.VMPlacementPolicy)
+
+	vms, err := v.vmCache.List(policy.Namespace, labels.SelectorFromSet(labels.Set{util.LabelVMPlacementPolicy: policy.Name}))
+	if err != nil {
+		return werror.NewInternalError(err.Error())
+	}
+	if len(vms) > 0 {
+		names := make([]string, 0, len(vms))
+		for _, vm := range vms {
+			names = append(names, vm.Name)
+		}
+		return werror.NewBadRequest(fmt.Sprintf("placement policy %s is used by VMs %v, remove the %s label from them first", policy.Name, names, util.LabelVMPlacementPolicy))
+	}
+	return nil
+}
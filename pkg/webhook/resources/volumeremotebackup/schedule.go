@@ -0,0 +1,116 @@
+package volumeremotebackup
+
+import (
+	"fmt"
+	"time"
+
+	ctlv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/util"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+const (
+	fieldCron               = "spec.cron"
+	fieldVolumeRemoteBackup = "spec.volumeRemoteBackup."
+
+	minCronGranularity = time.Hour
+)
+
+type scheduleValidator struct {
+	types.DefaultValidator
+	pvcCache ctlv1.PersistentVolumeClaimCache
+	bo       common.BackupOperator
+}
+
+func NewScheduleValidator(
+	pvcCache ctlv1.PersistentVolumeClaimCache,
+	remoteBackupClient ctlharvesterv1.VolumeRemoteBackupClient,
+	scCache ctlstoragev1.StorageClassCache,
+	settingCache ctlharvesterv1.SettingCache,
+) types.Validator {
+	return &scheduleValidator{
+		pvcCache: pvcCache,
+		bo:       common.NewBackupOperator(remoteBackupClient, pvcCache, scCache, settingCache),
+	}
+}
+
+func (v *scheduleValidator) Resource() types.Resource {
+	return types.Resource{
+		Names:      []string{v1beta1.ScheduleVolumeRemoteBackupResourceName},
+		Scope:      admissionregv1.NamespacedScope,
+		APIGroup:   v1beta1.SchemeGroupVersion.Group,
+		APIVersion: v1beta1.SchemeGroupVersion.Version,
+		ObjectType: &v1beta1.ScheduleVolumeRemoteBackup{},
+		OperationTypes: []admissionregv1.OperationType{
+			admissionregv1.Create,
+			admissionregv1.Update,
+		},
+	}
+}
+
+func checkCron(svrbackup *v1beta1.ScheduleVolumeRemoteBackup) error {
+	granularity, err := util.GetCronExpressionGranularity(svrbackup.Spec.Cron)
+	if err != nil {
+		return werror.NewInvalidError("invalid cron format", fieldCron)
+	}
+
+	if granularity < minCronGranularity {
+		return werror.NewInvalidError(fmt.Sprintf("schedule granularity %s less than %s",
+			granularity.String(), minCronGranularity.String()), fieldCron)
+	}
+	return nil
+}
+
+// scheduledBackup returns the VolumeRemoteBackup the schedule creates, to validate its spec.
+func scheduledBackup(svrbackup *v1beta1.ScheduleVolumeRemoteBackup) *v1beta1.VolumeRemoteBackup {
+	return &v1beta1.VolumeRemoteBackup{
+		ObjectMeta: svrbackup.ObjectMeta,
+		Spec:       svrbackup.Spec.VolumeRemoteBackupSpec,
+	}
+}
+
+func (v *scheduleValidator) Create(_ *types.Request, newObj runtime.Object) error {
+	svrbackup := newObj.(*v1beta1.ScheduleVolumeRemoteBackup)
+
+	if err := checkCron(svrbackup); err != nil {
+		return err
+	}
+
+	vrb := scheduledBackup(svrbackup)
+	if v.bo.GetSource(vrb) == "" {
+		return werror.NewInvalidError("source is required", fieldVolumeRemoteBackup+fieldSource)
+	}
+
+	if _, err := v.pvcCache.Get(v.bo.GetNamespace(vrb), v.bo.GetSource(vrb)); err != nil {
+		return werror.NewInvalidError(fmt.Sprintf("failed to get PVC %s/%s: %v", v.bo.GetNamespace(vrb), v.bo.GetSource(vrb), err),
+			fieldVolumeRemoteBackup+fieldSource)
+	}
+
+	return checkType(v.bo, vrb, fieldVolumeRemoteBackup)
+}
+
+func (v *scheduleValidator) Update(_ *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
+	oldSvrbackup := oldObj.(*v1beta1.ScheduleVolumeRemoteBackup)
+	newSvrbackup := newObj.(*v1beta1.ScheduleVolumeRemoteBackup)
+
+	if newSvrbackup.DeletionTimestamp != nil {
+		return nil
+	}
+
+	if oldSvrbackup.Spec.VolumeRemoteBackupSpec != newSvrbackup.Spec.VolumeRemoteBackupSpec {
+		return werror.NewInvalidError("volumeRemoteBackup cannot be changed", "spec.volumeRemoteBackup")
+	}
+
+	if oldSvrbackup.Spec.Cron == newSvrbackup.Spec.Cron {
+		return nil
+	}
+	return checkCron(newSvrbackup)
+}
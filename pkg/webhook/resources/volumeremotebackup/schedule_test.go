@@ -0,0 +1,124 @@
+package volumeremotebackup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+)
+
+func newSchedule(cron string) *v1beta1.ScheduleVolumeRemoteBackup {
+	return &v1beta1.ScheduleVolumeRemoteBackup{
+		ObjectMeta: metav1.ObjectMeta{Name: "schedule", Namespace: "default"},
+		Spec: v1beta1.ScheduleVolumeRemoteBackupSpec{
+			Cron: cron,
+			VolumeRemoteBackupSpec: v1beta1.VolumeRemoteBackupSpec{
+				Type:   v1beta1.VolumeRemoteBackupLH,
+				Source: "data",
+			},
+		},
+	}
+}
+
+func newTestScheduleValidator() *scheduleValidator {
+	clientset := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+	})
+	return NewScheduleValidator(
+		fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims),
+		fakeclients.VolumeRemoteBackupClient(clientset.HarvesterhciV1beta1().VolumeRemoteBackups),
+		fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses),
+		fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings),
+	).(*scheduleValidator)
+}
+
+func TestScheduleCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		schedule    func() *v1beta1.ScheduleVolumeRemoteBackup
+		errContains string
+	}{
+		{
+			name:     "hourly schedule",
+			schedule: func() *v1beta1.ScheduleVolumeRemoteBackup { return newSchedule("0 * * * *") },
+		},
+		{
+			name:     "daily schedule",
+			schedule: func() *v1beta1.ScheduleVolumeRemoteBackup { return newSchedule("30 2 * * *") },
+		},
+		{
+			name:        "invalid cron",
+			schedule:    func() *v1beta1.ScheduleVolumeRemoteBackup { return newSchedule("every hour") },
+			errContains: "invalid cron format",
+		},
+		{
+			name:        "schedule finer than an hour",
+			schedule:    func() *v1beta1.ScheduleVolumeRemoteBackup { return newSchedule("*/30 * * * *") },
+			errContains: "schedule granularity",
+		},
+		{
+			name: "missing source",
+			schedule: func() *v1beta1.ScheduleVolumeRemoteBackup {
+				svrbackup := newSchedule("0 * * * *")
+				svrbackup.Spec.VolumeRemoteBackupSpec.Source = ""
+				return svrbackup
+			},
+			errContains: "source is required",
+		},
+		{
+			name: "missing source PVC",
+			schedule: func() *v1beta1.ScheduleVolumeRemoteBackup {
+				svrbackup := newSchedule("0 * * * *")
+				svrbackup.Spec.VolumeRemoteBackupSpec.Source = "missing"
+				return svrbackup
+			},
+			errContains: "failed to get PVC default/missing",
+		},
+	}
+
+	validator := newTestScheduleValidator()
+	for _, tc := range tests {
+		err := validator.Create(nil, tc.schedule())
+		if tc.errContains == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.errContains, tc.name)
+		}
+	}
+}
+
+func TestScheduleUpdate(t *testing.T) {
+	validator := newTestScheduleValidator()
+	oldSchedule := newSchedule("0 * * * *")
+
+	newSchedule := oldSchedule.DeepCopy()
+	newSchedule.Spec.Suspend = true
+	assert.NoError(t, validator.Update(nil, oldSchedule, newSchedule))
+
+	newSchedule = oldSchedule.DeepCopy()
+	newSchedule.Spec.Cron = "0 0 * * *"
+	assert.NoError(t, validator.Update(nil, oldSchedule, newSchedule))
+
+	newSchedule = oldSchedule.DeepCopy()
+	newSchedule.Spec.Cron = "*/5 * * * *"
+	assert.ErrorContains(t, validator.Update(nil, oldSchedule, newSchedule), "schedule granularity")
+
+	newSchedule = oldSchedule.DeepCopy()
+	newSchedule.Spec.VolumeRemoteBackupSpec.Source = "other"
+	assert.ErrorContains(t, validator.Update(nil, oldSchedule, newSchedule), "volumeRemoteBackup cannot be changed")
+
+	newSchedule = oldSchedule.DeepCopy()
+	newSchedule.Spec.VolumeRemoteBackupSpec.Type = v1beta1.VolumeRemoteBackupCSI
+	assert.ErrorContains(t, validator.Update(nil, oldSchedule, newSchedule), "volumeRemoteBackup cannot be changed")
+
+	// a schedule being deleted is not validated
+	newSchedule = oldSchedule.DeepCopy()
+	newSchedule.Spec.Cron = "*/5 * * * *"
+	newSchedule.DeletionTimestamp = &metav1.Time{}
+	assert.NoError(t, validator.Update(nil, oldSchedule, newSchedule))
+}
@@ -2,14 +2,22 @@ package volumeremotebackup
 
 import (
 	"fmt"
+	"reflect"
 
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	ctlv1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	ctlstoragev1 "github.com/rancher/wrangler/v3/pkg/generated/controllers/storage/v1"
 	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 
 	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
 	ctlharvesterv1 "github.com/harvester/harvester/pkg/generated/controllers/harvesterhci.io/v1beta1"
+	ctlsnapshotv1 "github.com/harvester/harvester/pkg/generated/controllers/snapshot.storage.k8s.io/v1"
 	"github.com/harvester/harvester/pkg/ref"
 	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
 	werror "github.com/harvester/harvester/pkg/webhook/error"
@@ -17,11 +25,32 @@ import (
 )
 
 const (
-	fieldType   = "spec.type"
-	fieldSource = "spec.source"
-	fieldFrom   = "spec.from"
+	fieldType             = "spec.type"
+	fieldSource           = "spec.source"
+	fieldFrom             = "spec.from"
+	fieldTargetNamespace  = "spec.targetNamespace"
+	fieldTargetPVCName    = "spec.targetPVCName"
+	fieldStorageClassName = "spec.storageClassName"
+	fieldSize             = "spec.size"
 )
 
+// checkType validates the source PVC can be backed up by the type of the backup.
+func checkType(bo common.BackupOperator, vrb *v1beta1.VolumeRemoteBackup, fieldPrefix string) error {
+	if bo.GetType(vrb) != v1beta1.VolumeRemoteBackupCSI {
+		return nil
+	}
+
+	vsClassInfo, err := bo.GetSnapshotClassInfo(vrb)
+	if err != nil {
+		return werror.NewInvalidError(err.Error(), fieldPrefix+fieldSource)
+	}
+	if vsClassInfo.BackupVolumeSnapshotClassName == "" {
+		return werror.NewInvalidError(fmt.Sprintf("backupVolumeSnapshotClassName isn't configured for the CSI driver of PVC %s/%s",
+			bo.GetNamespace(vrb), bo.GetSource(vrb)), fieldPrefix+fieldType)
+	}
+	return nil
+}
+
 type remoteBackupValidator struct {
 	types.DefaultValidator
 	pvcCache ctlv1.PersistentVolumeClaimCache
@@ -67,7 +96,7 @@ func (v *remoteBackupValidator) Create(request *types.Request, newObj runtime.Ob
 		return werror.NewInternalError(fmt.Sprintf("failed to get PVC %s/%s: %v", v.bo.GetNamespace(vrb), v.bo.GetSource(vrb), err))
 	}
 
-	return nil
+	return checkType(v.bo, vrb, "")
 }
 
 func (v *remoteBackupValidator) Update(request *types.Request, oldObj runtime.Object, newObj runtime.Object) error {
@@ -88,6 +117,11 @@ func (v *remoteBackupValidator) Update(request *types.Request, oldObj runtime.Ob
 type remoteRestoreValidator struct {
 	types.DefaultValidator
 	remoteBackupCache ctlharvesterv1.VolumeRemoteBackupCache
+	pvcCache          ctlv1.PersistentVolumeClaimCache
+	scCache           ctlstoragev1.StorageClassCache
+	nsCache           ctlv1.NamespaceCache
+	vsCache           ctlsnapshotv1.VolumeSnapshotCache
+	sar               authorizationv1client.SubjectAccessReviewInterface
 	ro                common.RestoreOperator
 }
 
@@ -97,10 +131,18 @@ func NewRestoreValidator(
 	scCache ctlstoragev1.StorageClassCache,
 	settingCache ctlharvesterv1.SettingCache,
 	remoteBackupCache ctlharvesterv1.VolumeRemoteBackupCache,
+	nsCache ctlv1.NamespaceCache,
+	vsCache ctlsnapshotv1.VolumeSnapshotCache,
+	sar authorizationv1client.SubjectAccessReviewInterface,
 	bo common.BackupOperator,
 ) types.Validator {
 	return &remoteRestoreValidator{
 		remoteBackupCache: remoteBackupCache,
+		pvcCache:          pvcCache,
+		scCache:           scCache,
+		nsCache:           nsCache,
+		vsCache:           vsCache,
+		sar:               sar,
 		ro: common.NewRestoreOperator(
 			remoteRestoreClient,
 			pvcCache,
@@ -150,6 +192,10 @@ func (v *remoteRestoreValidator) Create(request *types.Request, newObj runtime.O
 		return werror.NewInvalidError(fmt.Sprintf("PVCBackup %s/%s is not ready", vrbNamespace, vrbName), fieldFrom)
 	}
 
+	if err := checkRestoreType(vrr, vrb); err != nil {
+		return err
+	}
+
 	// Check if a PVCBackup with the same name exists in the restore's namespace
 	_, err = v.remoteBackupCache.Get(v.ro.GetNamespace(vrr), v.ro.GetName(vrr))
 	if err == nil {
@@ -157,6 +203,106 @@ func (v *remoteRestoreValidator) Create(request *types.Request, newObj runtime.O
 			v.ro.GetName(vrr), v.ro.GetNamespace(vrr)), "metadata.name")
 	}
 
+	if err := v.checkTarget(request, vrr); err != nil {
+		return err
+	}
+
+	var sc *storagev1.StorageClass
+	if vrr.Spec.StorageClassName != "" {
+		if sc, err = v.scCache.Get(vrr.Spec.StorageClassName); err != nil {
+			return werror.NewInvalidError(fmt.Sprintf("failed to get storage class %s: %v", vrr.Spec.StorageClassName, err), fieldStorageClassName)
+		}
+	}
+	return checkTargetStorage(vrr, vrb, sc)
+}
+
+// checkTarget validates the PVC can be restored into the target namespace by the user, and
+// neither it nor the VolumeSnapshot it is restored from exist yet.
+func (v *remoteRestoreValidator) checkTarget(request *types.Request, vrr *v1beta1.VolumeRemoteRestore) error {
+	targetNamespace := v.ro.GetTargetNamespace(vrr)
+	targetPVCName := v.ro.GetTargetPVCName(vrr)
+
+	if v.ro.IsCrossNamespace(vrr) {
+		if _, err := v.nsCache.Get(targetNamespace); err != nil {
+			return werror.NewInvalidError(fmt.Sprintf("failed to get namespace %s: %v", targetNamespace, err), fieldTargetNamespace)
+		}
+		if err := v.checkCreatePermission(request, targetNamespace, "", "persistentvolumeclaims", "PVCs"); err != nil {
+			return err
+		}
+		if err := v.checkCreatePermission(request, targetNamespace, snapshotv1.GroupName, "volumesnapshots", "VolumeSnapshots"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := v.pvcCache.Get(targetNamespace, targetPVCName); err == nil {
+		return werror.NewInvalidError(fmt.Sprintf("PVC %s/%s already exists", targetNamespace, targetPVCName), fieldTargetPVCName)
+	}
+	// the PVC is restored from a VolumeSnapshot named after the restore
+	if _, err := v.vsCache.Get(targetNamespace, v.ro.GetName(vrr)); err == nil {
+		return werror.NewInvalidError(fmt.Sprintf("VolumeSnapshot %s/%s already exists", targetNamespace, v.ro.GetName(vrr)), "metadata.name")
+	}
+	return nil
+}
+
+// checkCreatePermission checks the user creating the restore can create the resource in the target
+// namespace, the PVC and its VolumeSnapshot are created by the controller on behalf of the user.
+func (v *remoteRestoreValidator) checkCreatePermission(request *types.Request, namespace, group, resource, kind string) error {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range request.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	sar, err := v.sar.Create(request.Context, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "create",
+				Group:     group,
+				Resource:  resource,
+			},
+			User:   request.UserInfo.Username,
+			Groups: request.UserInfo.Groups,
+			Extra:  extra,
+			UID:    request.UserInfo.UID,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return werror.NewInternalError(fmt.Sprintf("failed to check user permission, error: %s", err.Error()))
+	}
+
+	if !sar.Status.Allowed || sar.Status.Denied {
+		return werror.NewInvalidError(fmt.Sprintf("user %s has no permission to create %s in namespace %s",
+			request.UserInfo.Username, kind, namespace), fieldTargetNamespace)
+	}
+	return nil
+}
+
+// checkRestoreType validates the restore type matches the backup type, each type restores the
+// snapshot handle of its own backup.
+func checkRestoreType(vrr *v1beta1.VolumeRemoteRestore, vrb *v1beta1.VolumeRemoteBackup) error {
+	if string(vrr.Spec.Type) != string(vrb.Spec.Type) {
+		return werror.NewInvalidError(fmt.Sprintf("restore type %s can't restore the %s backup %s/%s",
+			vrr.Spec.Type, vrb.Spec.Type, vrb.Namespace, vrb.Name), fieldType)
+	}
+	return nil
+}
+
+// checkTargetStorage validates the PVC can be restored onto the storage class and with the size of
+// the restore, the storage class is nil if the restore doesn't change it.
+func checkTargetStorage(vrr *v1beta1.VolumeRemoteRestore, vrb *v1beta1.VolumeRemoteBackup, sc *storagev1.StorageClass) error {
+	if sc != nil && sc.Provisioner != vrb.Status.CSIProvider {
+		return werror.NewInvalidError(fmt.Sprintf("storage class %s is provisioned by %s, the backup is taken by %s",
+			sc.Name, sc.Provisioner, vrb.Status.CSIProvider), fieldStorageClassName)
+	}
+
+	if vrr.Spec.Size == nil {
+		return nil
+	}
+	sourceSize := vrb.Status.SourceSpec.Resources.Requests[corev1.ResourceStorage]
+	if vrr.Spec.Size.Cmp(sourceSize) < 0 {
+		return werror.NewInvalidError(fmt.Sprintf("size %s is smaller than the size %s of the source PVC",
+			vrr.Spec.Size.String(), sourceSize.String()), fieldSize)
+	}
 	return nil
 }
 
@@ -172,5 +318,21 @@ func (v *remoteRestoreValidator) Update(request *types.Request, oldObj runtime.O
 		return werror.NewInvalidError("spec.from cannot be changed", fieldFrom)
 	}
 
+	if v.ro.GetTargetNamespace(oldVrr) != v.ro.GetTargetNamespace(newVrr) {
+		return werror.NewInvalidError("spec.targetNamespace cannot be changed", fieldTargetNamespace)
+	}
+
+	if v.ro.GetTargetPVCName(oldVrr) != v.ro.GetTargetPVCName(newVrr) {
+		return werror.NewInvalidError("spec.targetPVCName cannot be changed", fieldTargetPVCName)
+	}
+
+	if oldVrr.Spec.StorageClassName != newVrr.Spec.StorageClassName {
+		return werror.NewInvalidError("spec.storageClassName cannot be changed", fieldStorageClassName)
+	}
+
+	if !reflect.DeepEqual(oldVrr.Spec.Size, newVrr.Spec.Size) {
+		return werror.NewInvalidError("spec.size cannot be changed", fieldSize)
+	}
+
 	return nil
 }
@@ -0,0 +1,240 @@
+package volumeremotebackup
+
+import (
+	"context"
+	"testing"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/rancher/wrangler/v3/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/generated/clientset/versioned/fake"
+	"github.com/harvester/harvester/pkg/util/fakeclients"
+	"github.com/harvester/harvester/pkg/volumeremotebackup/common"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+func TestCheckTargetStorage(t *testing.T) {
+	vrb := &v1beta1.VolumeRemoteBackup{
+		Status: v1beta1.VolumeRemoteBackupStatus{
+			CSIProvider: "driver.longhorn.io",
+			SourceSpec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: resource.MustParse("10Gi"),
+					},
+				},
+			},
+		},
+	}
+	newSC := func(provisioner string) *storagev1.StorageClass {
+		return &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "fast"},
+			Provisioner: provisioner,
+		}
+	}
+	newSize := func(size string) *resource.Quantity {
+		quantity := resource.MustParse(size)
+		return &quantity
+	}
+
+	tests := []struct {
+		name      string
+		sc        *storagev1.StorageClass
+		size      *resource.Quantity
+		expectErr bool
+	}{
+		{
+			name: "keep the source storage class and size",
+		},
+		{
+			name: "storage class of the driver of the backup",
+			sc:   newSC("driver.longhorn.io"),
+		},
+		{
+			name:      "storage class of another driver",
+			sc:        newSC("rbd.csi.ceph.com"),
+			expectErr: true,
+		},
+		{
+			name: "same size",
+			size: newSize("10Gi"),
+		},
+		{
+			name: "larger size",
+			size: newSize("20Gi"),
+		},
+		{
+			name:      "smaller size",
+			size:      newSize("5Gi"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		vrr := &v1beta1.VolumeRemoteRestore{
+			Spec: v1beta1.VolumeRemoteRestoreSpec{
+				Size: tc.size,
+			},
+		}
+		err := checkTargetStorage(vrr, vrb, tc.sc)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
+
+func TestCheckRestoreType(t *testing.T) {
+	tests := []struct {
+		name        string
+		restoreType v1beta1.VolumeRemoteRestoreType
+		backupType  v1beta1.VolumeRemoteBackupType
+		expectErr   bool
+	}{
+		{
+			name:        "lh restore of lh backup",
+			restoreType: v1beta1.VolumeRemoteRestoreLH,
+			backupType:  v1beta1.VolumeRemoteBackupLH,
+		},
+		{
+			name:        "csi restore of csi backup",
+			restoreType: v1beta1.VolumeRemoteRestoreCSI,
+			backupType:  v1beta1.VolumeRemoteBackupCSI,
+		},
+		{
+			name:        "lh restore of csi backup",
+			restoreType: v1beta1.VolumeRemoteRestoreLH,
+			backupType:  v1beta1.VolumeRemoteBackupCSI,
+			expectErr:   true,
+		},
+		{
+			name:        "csi restore of lh backup",
+			restoreType: v1beta1.VolumeRemoteRestoreCSI,
+			backupType:  v1beta1.VolumeRemoteBackupLH,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		vrr := &v1beta1.VolumeRemoteRestore{Spec: v1beta1.VolumeRemoteRestoreSpec{Type: tc.restoreType}}
+		vrb := &v1beta1.VolumeRemoteBackup{Spec: v1beta1.VolumeRemoteBackupSpec{Type: tc.backupType}}
+		err := checkRestoreType(vrr, vrb)
+		if tc.expectErr {
+			assert.Error(t, err, tc.name)
+		} else {
+			assert.NoError(t, err, tc.name)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	newRestore := func(targetNamespace string) *v1beta1.VolumeRemoteRestore {
+		return &v1beta1.VolumeRemoteRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "default"},
+			Spec: v1beta1.VolumeRemoteRestoreSpec{
+				From:            "backup",
+				TargetNamespace: targetNamespace,
+				TargetPVCName:   "data",
+			},
+		}
+	}
+	request := &types.Request{
+		Request: &webhook.Request{
+			Context: context.Background(),
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				UserInfo: authenticationv1.UserInfo{Username: "demo"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		restore     *v1beta1.VolumeRemoteRestore
+		objects     []runtime.Object
+		denied      string
+		errContains string
+	}{
+		{
+			name:    "same namespace",
+			restore: newRestore(""),
+		},
+		{
+			name:    "cross namespace",
+			restore: newRestore("restored"),
+		},
+		{
+			name:        "missing target namespace",
+			restore:     newRestore("missing"),
+			errContains: "failed to get namespace missing",
+		},
+		{
+			name:        "no permission to create PVCs",
+			restore:     newRestore("restored"),
+			denied:      "persistentvolumeclaims",
+			errContains: "user demo has no permission to create PVCs in namespace restored",
+		},
+		{
+			name:        "no permission to create VolumeSnapshots",
+			restore:     newRestore("restored"),
+			denied:      "volumesnapshots",
+			errContains: "user demo has no permission to create VolumeSnapshots in namespace restored",
+		},
+		{
+			name:    "existing PVC",
+			restore: newRestore("restored"),
+			objects: []runtime.Object{&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "restored"},
+			}},
+			errContains: "PVC restored/data already exists",
+		},
+		{
+			name:    "existing VolumeSnapshot",
+			restore: newRestore("restored"),
+			objects: []runtime.Object{&snapshotv1.VolumeSnapshot{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "restored"},
+			}},
+			errContains: "VolumeSnapshot restored/restore already exists",
+		},
+	}
+
+	for _, tc := range tests {
+		clientset := fake.NewSimpleClientset(tc.objects...)
+		k8sclientset := corefake.NewClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "restored"}})
+		k8sclientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			sar.Status.Allowed = sar.Spec.ResourceAttributes.Resource != tc.denied
+			return true, sar, nil
+		})
+		pvcCache := fakeclients.PersistentVolumeClaimCache(clientset.CoreV1().PersistentVolumeClaims)
+		scCache := fakeclients.StorageClassCache(clientset.StorageV1().StorageClasses)
+		settingCache := fakeclients.HarvesterSettingCache(clientset.HarvesterhciV1beta1().Settings)
+		vrbCache := fakeclients.VolumeRemoteBackupCache(clientset.HarvesterhciV1beta1().VolumeRemoteBackups)
+		validator := &remoteRestoreValidator{
+			pvcCache: pvcCache,
+			nsCache:  fakeclients.NamespaceCache(k8sclientset.CoreV1().Namespaces),
+			vsCache:  fakeclients.VolumeSnapshotCache(clientset.SnapshotV1().VolumeSnapshots),
+			sar:      k8sclientset.AuthorizationV1().SubjectAccessReviews(),
+			ro:       common.NewRestoreOperator(nil, pvcCache, scCache, settingCache, vrbCache, nil),
+		}
+
+		err := validator.checkTarget(request, tc.restore)
+		if tc.errContains == "" {
+			assert.NoError(t, err, tc.name)
+		} else {
+			assert.ErrorContains(t, err, tc.errContains, tc.name)
+		}
+	}
+}
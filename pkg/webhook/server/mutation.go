@@ -32,6 +32,7 @@ func Mutation(clients *clients.Clients, options *config.Options, crdExists bool)
 	vmImgCache := clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineImage().Cache()
 	vmCache := clients.KubevirtFactory.Kubevirt().V1().VirtualMachine().Cache()
 	kubevirtCache := clients.KubevirtFactory.Kubevirt().V1().KubeVirt().Cache()
+	vmPlacementPolicyCache := clients.HarvesterFactory.Harvesterhci().V1beta1().VMPlacementPolicy().Cache()
 	var kubeovnSubnetCache ctlkubeovnv1.SubnetCache
 	if crdExists {
 		kubeovnSubnetCache = clients.KubeovnFactory.Kubeovn().V1().Subnet().Cache()
@@ -41,7 +42,7 @@ func Mutation(clients *clients.Clients, options *config.Options, crdExists bool)
 		pod.NewMutator(settingCache),
 		templateversion.NewMutator(),
 		upgrade.NewMutator(nodeCache, settingCache),
-		virtualmachine.NewMutator(settingCache, nadCache, kubevirtCache, kubeovnSubnetCache),
+		virtualmachine.NewMutator(settingCache, nadCache, kubevirtCache, kubeovnSubnetCache, vmPlacementPolicyCache),
 		virtualmachineinstance.NewMutator(vmCache, nadCache),
 		virtualmachineimage.NewMutator(storageClassCache),
 		virtualmachinebackup.NewMutator(vmBackupCache),
@@ -13,16 +13,22 @@ import (
 	"github.com/harvester/harvester/pkg/webhook/clients"
 	"github.com/harvester/harvester/pkg/webhook/config"
 	"github.com/harvester/harvester/pkg/webhook/resources/addon"
+	"github.com/harvester/harvester/pkg/webhook/resources/backupbrowsesession"
+	"github.com/harvester/harvester/pkg/webhook/resources/backuptarget"
+	"github.com/harvester/harvester/pkg/webhook/resources/backupverification"
 	"github.com/harvester/harvester/pkg/webhook/resources/bundle"
 	"github.com/harvester/harvester/pkg/webhook/resources/bundledeployment"
 	"github.com/harvester/harvester/pkg/webhook/resources/datavolume"
 	"github.com/harvester/harvester/pkg/webhook/resources/deployment"
+	"github.com/harvester/harvester/pkg/webhook/resources/imagesyncpolicy"
 	"github.com/harvester/harvester/pkg/webhook/resources/keypair"
 	"github.com/harvester/harvester/pkg/webhook/resources/managedchart"
 	"github.com/harvester/harvester/pkg/webhook/resources/namespace"
 	"github.com/harvester/harvester/pkg/webhook/resources/networkattachmentdefinition"
 	"github.com/harvester/harvester/pkg/webhook/resources/node"
+	"github.com/harvester/harvester/pkg/webhook/resources/nodemaintenancecampaign"
 	"github.com/harvester/harvester/pkg/webhook/resources/persistentvolumeclaim"
+	"github.com/harvester/harvester/pkg/webhook/resources/rebalancepolicy"
 	"github.com/harvester/harvester/pkg/webhook/resources/resourcequota"
 	"github.com/harvester/harvester/pkg/webhook/resources/schedulevmbackup"
 	"github.com/harvester/harvester/pkg/webhook/resources/secret"
@@ -35,8 +41,12 @@ import (
 	"github.com/harvester/harvester/pkg/webhook/resources/version"
 	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachine"
 	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachinebackup"
+	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachinebackupcopy"
+	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachinegroup"
 	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachineimage"
+	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachineimagedownloader"
 	"github.com/harvester/harvester/pkg/webhook/resources/virtualmachinerestore"
+	"github.com/harvester/harvester/pkg/webhook/resources/vmplacementpolicy"
 	"github.com/harvester/harvester/pkg/webhook/resources/volumeremotebackup"
 	"github.com/harvester/harvester/pkg/webhook/resources/volumesnapshot"
 	"github.com/harvester/harvester/pkg/webhook/types"
@@ -82,12 +92,20 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 			clients.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteBackup(),
 			clients.StorageFactory.Storage().V1().StorageClass().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache()),
+		volumeremotebackup.NewScheduleValidator(
+			clients.Core.PersistentVolumeClaim().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteBackup(),
+			clients.StorageFactory.Storage().V1().StorageClass().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache()),
 		volumeremotebackup.NewRestoreValidator(
 			clients.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteRestore(),
 			clients.Core.PersistentVolumeClaim().Cache(),
 			clients.StorageFactory.Storage().V1().StorageClass().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteBackup().Cache(),
+			clients.Core.Namespace().Cache(),
+			clients.SnapshotFactory.Snapshot().V1().VolumeSnapshot().Cache(),
+			clients.K8s.AuthorizationV1().SubjectAccessReviews(),
 			common.NewBackupOperator(
 				clients.HarvesterFactory.Harvesterhci().V1beta1().VolumeRemoteBackup(),
 				clients.Core.PersistentVolumeClaim().Cache(),
@@ -115,7 +133,8 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 			clients.K8s.AuthorizationV1().SelfSubjectAccessReviews(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineTemplateVersion().Cache(),
 			clients.StorageFactory.Storage().V1().StorageClass().Cache(),
-			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache()),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache()),
 		upgrade.NewValidator(
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Upgrade().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Addon().Cache(),
@@ -149,10 +168,13 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 			clients.StorageFactory.Storage().V1().StorageClass().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().ResourceQuota().Cache(),
 			clients.KubevirtFactory.Kubevirt().V1().VirtualMachineInstanceMigration().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget().Cache(),
+			clients.K8s.AuthorizationV1().SubjectAccessReviews(),
 		),
 		virtualmachinerestore.NewValidator(
 			clients.Core.Namespace().Cache(),
 			clients.Core.Pod().Cache(),
+			clients.Core.PersistentVolumeClaim().Cache(),
 			clients.HarvesterCoreFactory.Core().V1().ResourceQuota().Cache(),
 			clients.KubevirtFactory.Kubevirt().V1().VirtualMachine().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
@@ -162,6 +184,8 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 			clients.KubevirtFactory.Kubevirt().V1().VirtualMachineInstanceMigration().Cache(),
 			clients.SnapshotFactory.Snapshot().V1().VolumeSnapshotClass().Cache(),
 			clients.CNIFactory.K8s().V1().NetworkAttachmentDefinition().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().BackupVerification().Cache(),
 		),
 		setting.NewValidator(
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
@@ -223,10 +247,30 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 			clients.KubevirtFactory.Kubevirt().V1().VirtualMachine().Cache(),
 		),
 		resourcequota.NewValidator(),
+		backuptarget.NewValidator(
+			clients.Core.Secret().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().ScheduleVMBackup().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackupCopy().Cache(),
+		),
+		virtualmachinebackupcopy.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackupCopy().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget().Cache(),
+		),
+		backupverification.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache(),
+		),
+		backupbrowsesession.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineBackup().Cache(),
+		),
 		schedulevmbackup.NewValidator(
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
 			clients.Core.Secret().Cache(),
 			clients.HarvesterFactory.Harvesterhci().V1beta1().ScheduleVMBackup().Cache(),
+			clients.HarvesterFactory.Harvesterhci().V1beta1().BackupTarget().Cache(),
+			clients.K8s.AuthorizationV1().SubjectAccessReviews(),
 		),
 		secret.NewValidator(clients.StorageFactory.Storage().V1().StorageClass().Cache()),
 		supportbundle.NewValidator(clients.Core.Namespace().Cache()),
@@ -241,6 +285,25 @@ func Validation(clients *clients.Clients, options *config.Options, crdExists boo
 		networkattachmentdefinition.NewValidator(
 			clients.HarvesterFactory.Harvesterhci().V1beta1().Setting().Cache(),
 		),
+		rebalancepolicy.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().RebalancePolicy().Cache(),
+		),
+		imagesyncpolicy.NewValidator(
+			clients.Core.Secret().Cache(),
+		),
+		nodemaintenancecampaign.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().NodeMaintenanceCampaign().Cache(),
+		),
+		virtualmachinegroup.NewValidator(
+			clients.HarvesterFactory.Harvesterhci().V1beta1().VirtualMachineGroup().Cache(),
+		),
+		vmplacementpolicy.NewValidator(
+			clients.KubevirtFactory.Kubevirt().V1().VirtualMachine().Cache(),
+		),
+		virtualmachineimagedownloader.NewValidator(
+			clients.K8s,
+			clients.Core.Secret().Cache(),
+		),
 	}
 
 	router := webhook.NewRouter()
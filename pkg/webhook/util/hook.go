@@ -0,0 +1,93 @@
+package util
+
+import (
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+
+	"github.com/harvester/harvester/pkg/apis/harvesterhci.io/v1beta1"
+	"github.com/harvester/harvester/pkg/backup/hook"
+	werror "github.com/harvester/harvester/pkg/webhook/error"
+	"github.com/harvester/harvester/pkg/webhook/types"
+)
+
+// ValidateBackupHooks checks the pre/post snapshot hooks of a VMBackup spec, field is the path of the hooks.
+func ValidateBackupHooks(hooks *v1beta1.BackupHooks, field string) error {
+	if hooks == nil {
+		return nil
+	}
+	if err := validateBackupHookList(hooks.PreSnapshot, field+".preSnapshot"); err != nil {
+		return err
+	}
+	return validateBackupHookList(hooks.PostSnapshot, field+".postSnapshot")
+}
+
+func validateBackupHookList(hooks []v1beta1.BackupHook, field string) error {
+	names := make(map[string]struct{}, len(hooks))
+	for i, h := range hooks {
+		hookField := fmt.Sprintf("%s[%d]", field, i)
+		if h.Name == "" {
+			return werror.NewInvalidError("hook name is empty", hookField+".name")
+		}
+		if _, ok := names[h.Name]; ok {
+			return werror.NewInvalidError(fmt.Sprintf("hook name %s is duplicated", h.Name), hookField+".name")
+		}
+		names[h.Name] = struct{}{}
+
+		if len(h.Command) == 0 || h.Command[0] == "" {
+			return werror.NewInvalidError("hook command is empty", hookField+".command")
+		}
+		if h.Timeout != nil && (h.Timeout.Duration <= 0 || h.Timeout.Duration > hook.MaxTimeout) {
+			return werror.NewInvalidError(fmt.Sprintf("timeout must be positive and no more than %s", hook.MaxTimeout), hookField+".timeout")
+		}
+		switch h.OnFailure {
+		case "", v1beta1.HookFailurePolicyAbort, v1beta1.HookFailurePolicyContinue:
+		default:
+			return werror.NewInvalidError(fmt.Sprintf("unknown failure policy %s", h.OnFailure), hookField+".onFailure")
+		}
+	}
+	return nil
+}
+
+// HasBackupHooks reports whether any hook is set.
+func HasBackupHooks(hooks *v1beta1.BackupHooks) bool {
+	return hooks != nil && (len(hooks.PreSnapshot) > 0 || len(hooks.PostSnapshot) > 0)
+}
+
+// CheckBackupHooksPermission checks the user setting backup hooks may open
+// the console of the VM. The hooks run as root in the guest through the
+// guest agent, so setting them must not grant more than the console does.
+func CheckBackupHooksPermission(sar authorizationv1client.SubjectAccessReviewInterface, request *types.Request, namespace, vmName, field string) error {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for key, value := range request.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review, err := sar.Create(request.Context, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "get",
+				Group:       "subresources.kubevirt.io",
+				Resource:    "virtualmachineinstances",
+				Subresource: "console",
+				Name:        vmName,
+			},
+			User:   request.UserInfo.Username,
+			Groups: request.UserInfo.Groups,
+			Extra:  extra,
+			UID:    request.UserInfo.UID,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return werror.NewInternalError(fmt.Sprintf("failed to check user permission, error: %s", err.Error()))
+	}
+
+	if !review.Status.Allowed || review.Status.Denied {
+		return werror.NewInvalidError(fmt.Sprintf("user %s has no permission to open the console of VM %s/%s, which backup hooks require",
+			request.UserInfo.Username, namespace, vmName), field)
+	}
+	return nil
+}
@@ -140,10 +140,11 @@ func ValidateProvisionerAndConfig(pvc *corev1.PersistentVolumeClaim,
 
 	var requiredValue string
 	switch {
+	case bt == v1beta1.Snapshot, bt == v1beta1.SnapshotExport:
+		// SnapshotExport moves the data by itself, it only needs an in-cluster snapshot.
+		requiredValue = c.VolumeSnapshotClassName
 	case bt.UsesRemoteBackupTarget():
 		requiredValue = c.BackupVolumeSnapshotClassName
-	case bt == v1beta1.Snapshot:
-		requiredValue = c.VolumeSnapshotClassName
 	}
 	if requiredValue == "" {
 		return fmt.Errorf("%s's snapshot class is not configured for provisioner %s in the %s setting",